@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"0xnetworth/backend/internal/auth"
 	"0xnetworth/backend/internal/handlers"
 	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/integrations/plaid"
+	"0xnetworth/backend/internal/liveprices"
+	"0xnetworth/backend/internal/metrics"
 	workflowclient "0xnetworth/backend/internal/integrations/workflow"
+	"0xnetworth/backend/internal/integrations/youtube"
+	"0xnetworth/backend/internal/pricehistory"
 	"0xnetworth/backend/internal/store"
 	"0xnetworth/backend/internal/workflow"
 
@@ -17,9 +27,43 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout bounds how long in-flight requests may run after a shutdown signal
+const shutdownTimeout = 10 * time.Second
+
+// coinbaseValidateTimeout bounds the startup check of the Coinbase credentials, retries included
+const coinbaseValidateTimeout = 30 * time.Second
+
+// newHTTPTransport returns the transport outbound API requests share, keeping enough idle
+// connections per host that a sync's concurrent requests reuse them rather than redial
+func newHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	return transport
+}
+
 func main() {
-	// Initialize store - use PostgreSQL if DATABASE_URL is set, otherwise fall back to in-memory
+	// Registered first so it runs last, after the deferred cleanup below
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// Cancelled on SIGINT or SIGTERM, which abandons startup or shuts the server down
+	shutdownSignal, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Initialize store - use SQLite if DB_DRIVER=sqlite, PostgreSQL if DATABASE_URL is set,
+	// otherwise fall back to in-memory, saved to STORE_FILE if that is set
 	var storeInstance store.Store
+	dbDriver := os.Getenv("DB_DRIVER")
+	if dbDriver != "" && dbDriver != "sqlite" && dbDriver != "postgres" {
+		log.Fatalf("Unsupported DB_DRIVER %q (expected sqlite or postgres)", dbDriver)
+	}
 	databaseURL := os.Getenv("DATABASE_URL")
 	
 	// Build DATABASE_URL from individual components if not provided
@@ -43,56 +87,66 @@ func main() {
 		}
 	}
 
-	if databaseURL != "" {
+	if dbDriver == "sqlite" {
+		// DB_PATH is the database file; it and the schema are created on first run
+		dbPath := os.Getenv("DB_PATH")
+		if dbPath == "" {
+			dbPath = "networth.db"
+		}
+		log.Printf("Initializing SQLite store at %s...", dbPath)
+		sqliteStore, err := store.NewSQLiteStore(dbPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize SQLite store: %v", err)
+		}
+		defer sqliteStore.Close()
+
+		storeInstance = sqliteStore
+		log.Println("SQLite store initialized successfully")
+	} else if databaseURL != "" {
 		log.Println("Initializing PostgreSQL store...")
-		postgresStore, err := store.NewPostgresStore(databaseURL)
+		postgresStore, err := store.NewPostgresStore(shutdownSignal, databaseURL)
 		if err != nil {
+			if shutdownSignal.Err() != nil {
+				log.Println("Shutdown requested while waiting for PostgreSQL")
+				return
+			}
 			log.Fatalf("Failed to initialize PostgreSQL store: %v", err)
 		}
 		defer postgresStore.Close()
 
-		// Read and execute schema
-		// Try multiple paths to find schema.sql
-		var schemaSQL []byte
-		var schemaErr error
-		schemaPaths := []string{
-			"/internal/store/schema.sql",                      // Container path (absolute)
-			filepath.Join("internal", "store", "schema.sql"),  // Development
-			filepath.Join(".", "internal", "store", "schema.sql"),
-		}
-		
-		// Try executable-relative path
-		if execPath, err := os.Executable(); err == nil {
-			schemaPaths = append([]string{
-				filepath.Join(filepath.Dir(execPath), "..", "internal", "store", "schema.sql"),
-				filepath.Join(filepath.Dir(execPath), "internal", "store", "schema.sql"),
-			}, schemaPaths...)
-		}
-		
-		for _, schemaPath := range schemaPaths {
-			schemaSQL, schemaErr = os.ReadFile(schemaPath)
-			if schemaErr == nil {
-				break
+		// Bring the schema up to date. With DB_MIGRATIONS_PLAN=true the pending migrations are
+		// listed and the server exits without applying them.
+		if os.Getenv("DB_MIGRATIONS_PLAN") == "true" {
+			pending, err := postgresStore.PendingMigrations(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to plan database migrations: %v", err)
 			}
-		}
-		
-		if schemaErr != nil {
-			log.Printf("Warning: Failed to read schema file from any path: %v. Schema may need to be initialized manually.", schemaErr)
-		} else {
-			// Check if FORCE_SCHEMA_INIT is set to fail fast on schema errors
-			forceInit := os.Getenv("FORCE_SCHEMA_INIT") == "true"
-			if err := postgresStore.InitSchema(string(schemaSQL)); err != nil {
-				if forceInit {
-					log.Fatalf("Failed to initialize schema (FORCE_SCHEMA_INIT=true): %v", err)
-				}
-				log.Printf("Warning: Failed to initialize schema (may already exist): %v", err)
-			} else {
-				log.Println("Database schema initialized successfully")
+			if len(pending) == 0 {
+				log.Println("No pending database migrations")
+			}
+			for _, m := range pending {
+				log.Printf("Pending migration %04d_%s", m.Version, m.Name)
 			}
+			postgresStore.Close()
+			os.Exit(0)
 		}
+		if err := postgresStore.ApplyMigrations(context.Background()); err != nil {
+			log.Fatalf("Failed to apply database migrations: %v", err)
+		}
+		log.Println("Database schema is up to date")
 
 		storeInstance = postgresStore
 		log.Println("PostgreSQL store initialized successfully")
+	} else if storeFile := os.Getenv("STORE_FILE"); storeFile != "" {
+		log.Printf("Initializing in-memory store persisted to %s...", storeFile)
+		memoryStore := store.NewFileStore(storeFile)
+		// The store writes changes every few seconds; save the rest before exiting
+		defer func() {
+			if err := memoryStore.Close(); err != nil {
+				log.Printf("Error saving store file: %v", err)
+			}
+		}()
+		storeInstance = memoryStore
 	} else {
 		log.Println("Warning: DATABASE_URL not set, using in-memory store (data will not persist)")
 		storeInstance = store.NewStore()
@@ -103,7 +157,14 @@ func main() {
 	// API Key Name can be UUID format (xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx)
 	// or full path format (organizations/{org_id}/apiKeys/{key_id})
 	// See: https://docs.cdp.coinbase.com/api-reference/v2/authentication
-	var coinbaseClient *coinbase.Client
+	// Left nil unless configured, since an interface holding a nil *coinbase.Client is not nil
+	var coinbaseSyncer handlers.CoinbaseSyncer
+	var coinbaseCredentials handlers.CoinbaseCredentials
+	// One connection pool for the Coinbase and Plaid clients' requests, REST and public alike
+	httpTransport := newHTTPTransport()
+	defer httpTransport.CloseIdleConnections()
+	var priceHistory *pricehistory.Service
+	var livePrices *liveprices.Updater
 	coinbaseAPIKeyName := os.Getenv("COINBASE_API_KEY_NAME")
 	coinbaseAPIPrivateKey := os.Getenv("COINBASE_API_PRIVATE_KEY")
 	// Support legacy environment variable names for backward compatibility
@@ -114,16 +175,64 @@ func main() {
 		coinbaseAPIPrivateKey = os.Getenv("COINBASE_API_SECRET")
 	}
 	if coinbaseAPIKeyName != "" && coinbaseAPIPrivateKey != "" {
-		var err error
-		coinbaseClient, err = coinbase.NewClient(coinbaseAPIKeyName, coinbaseAPIPrivateKey)
+		coinbaseClient, err := coinbase.NewClient(coinbaseAPIKeyName, coinbaseAPIPrivateKey, coinbase.WithTransport(httpTransport))
 		if err != nil {
 			log.Fatalf("Failed to initialize Coinbase client: %v", err)
 		}
+		coinbaseSyncer = coinbaseClient
+		coinbaseCredentials = coinbaseClient
+		priceHistory = pricehistory.New(coinbaseClient)
 		log.Println("Coinbase client initialized")
+		// Check the key in the background so an unreachable Coinbase does not delay startup;
+		// a bad key is logged rather than fatal, since stored data can still be served
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), coinbaseValidateTimeout)
+			defer cancel()
+			status := coinbaseClient.Validate(ctx)
+			if status.OK() {
+				log.Printf("Coinbase: %s", status.Summary)
+			}
+			if status.Message != "" {
+				log.Printf("Warning: Coinbase credential check (%s): %s", status.State, status.Message)
+			}
+		}()
+		if os.Getenv("COINBASE_WS_ENABLED") == "true" {
+			livePrices = liveprices.NewUpdater(storeInstance, coinbaseClient)
+		}
 	} else {
 		log.Println("Warning: Coinbase API keys not configured. Sync functionality will be limited.")
 	}
 
+	// Initialize Plaid client if credentials are provided, for linking M1 Finance accounts.
+	// Access tokens are stored encrypted with PLAID_TOKEN_KEY, so Plaid is not started without it.
+	var plaidClient handlers.PlaidAPI
+	var plaidSandbox handlers.PlaidSandboxAPI
+	var plaidTokens *plaid.TokenCipher
+	plaidClientID := os.Getenv("PLAID_CLIENT_ID")
+	plaidSecret := os.Getenv("PLAID_SECRET")
+	if plaidClientID != "" && plaidSecret != "" {
+		plaidEnv := os.Getenv("PLAID_ENV")
+		if plaidEnv == "" {
+			plaidEnv = "sandbox"
+		}
+		plaidOpts := []plaid.Option{plaid.WithTransport(httpTransport)}
+		// Items linked while this is set have Plaid post their changes to /api/plaid/webhook
+		if webhookURL := os.Getenv("PLAID_WEBHOOK_URL"); webhookURL != "" {
+			plaidOpts = append(plaidOpts, plaid.WithWebhookURL(webhookURL))
+		}
+		client, err := plaid.NewClient(plaidClientID, plaidSecret, plaidEnv, plaidOpts...)
+		if err != nil {
+			log.Fatalf("Failed to initialize Plaid client: %v", err)
+		}
+		plaidTokens, err = plaid.NewTokenCipher(os.Getenv("PLAID_TOKEN_KEY"))
+		if err != nil {
+			log.Fatalf("Failed to initialize Plaid token encryption: %v", err)
+		}
+		plaidClient = client
+		plaidSandbox = client
+		log.Printf("Plaid client initialized (%s)", plaidEnv)
+	}
+
 	// Initialize workflow service client
 	workflowServiceURL := os.Getenv("WORKFLOW_SERVICE_URL")
 	if workflowServiceURL == "" {
@@ -131,16 +240,55 @@ func main() {
 	}
 	workflowClient := workflowclient.NewClient(workflowServiceURL)
 
+	// Initialize the YouTube client if an API key is provided, for polling channels. Its
+	// estimated quota usage is kept in the store and capped at YOUTUBE_DAILY_QUOTA_BUDGET.
+	youtubeBudget, err := youtube.QuotaBudgetFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid YouTube configuration: %v", err)
+	}
+	youtubeClient := youtube.NewClient(os.Getenv("YOUTUBE_API_KEY"),
+		youtube.WithQuota(youtube.NewQuota(storeInstance, youtubeBudget)))
+	if youtubeClient != nil {
+		log.Printf("YouTube API client initialized (daily quota budget %d units)", youtubeBudget)
+	} else {
+		log.Println("Warning: YOUTUBE_API_KEY not set. Channel polling will be disabled.")
+	}
+
 	// Initialize workflow engine and scheduler
-	workflowEngine := workflow.NewEngine(storeInstance, workflowClient)
-	workflowScheduler := workflow.NewScheduler(storeInstance, workflowEngine)
+	workflowEngine := workflow.NewEngine(storeInstance, workflowClient, youtubeClient)
+	workflowScheduler := workflow.NewScheduler(storeInstance, workflowEngine, youtubeClient)
+	retentionWorker := workflow.NewRetentionWorker(storeInstance)
 
 	// Initialize handlers
+	healthHandler := handlers.NewHealthHandler(storeInstance, coinbaseCredentials, youtubeClient)
+	statsHandler := handlers.NewStatsHandler(storeInstance)
+	metricsHandler := handlers.NewMetricsHandler(metrics.Default)
+	exportHandler := handlers.NewExportHandler(storeInstance)
+	importHandler := handlers.NewImportHandler(storeInstance)
 	portfoliosHandler := handlers.NewPortfoliosHandler(storeInstance)
+	accountsHandler := handlers.NewAccountsHandler(storeInstance)
 	investmentsHandler := handlers.NewInvestmentsHandler(storeInstance)
 	networthHandler := handlers.NewNetWorthHandler(storeInstance)
-	syncHandler := handlers.NewSyncHandler(storeInstance, coinbaseClient)
-	workflowHandler := handlers.NewWorkflowHandler(storeInstance, workflowEngine, workflowScheduler)
+	transactionsHandler := handlers.NewTransactionsHandler(storeInstance)
+	syncHandler := handlers.NewSyncHandler(storeInstance, coinbaseSyncer, plaidClient, plaidTokens)
+	plaidRefresher := handlers.NewPlaidRefresher(syncHandler)
+	plaidHandler := handlers.NewPlaidHandler(storeInstance, plaidClient, plaidTokens, plaidRefresher)
+	pricesHandler := handlers.NewPricesHandler(priceHistory)
+	if livePrices != nil {
+		syncHandler.OnSynced(livePrices.HoldingsChanged)
+	}
+	workflowHandler := handlers.NewWorkflowHandler(storeInstance, workflowEngine, workflowScheduler, youtubeClient)
+
+	// Provision API users; once any token is configured every request must carry one
+	userCount, err := auth.LoadUsersFromEnv(context.Background(), storeInstance)
+	if err != nil {
+		log.Fatalf("Failed to provision API users: %v", err)
+	}
+	if userCount > 0 {
+		log.Printf("Provisioned %d API users, requiring bearer tokens", userCount)
+	} else {
+		log.Println("Warning: API_USERS not set, serving all requests as the default user")
+	}
 
 	// Setup router
 	router := gin.Default()
@@ -176,62 +324,114 @@ func main() {
 			config.AllowAllOrigins = true
 		}
 	}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"}
+	config.ExposeHeaders = []string{"X-Total-Count", "X-Request-ID"}
 	router.Use(cors.New(config))
 
 	// Health check endpoint
-	router.GET("/api/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"service": "0xnetworth-backend",
-		})
-	})
+	router.GET("/api/health", healthHandler.GetHealth)
+
+	// Plaid webhooks carry no API token; the handler verifies Plaid signed them
+	router.POST("/api/plaid/webhook", plaidHandler.Webhook)
 
 	// API routes
 	api := router.Group("/api")
+	api.Use(auth.Middleware(storeInstance, userCount > 0))
 	{
 		// Portfolio routes
 		api.GET("/portfolios", portfoliosHandler.GetPortfolios)
 		api.GET("/portfolios/platform/:platform", portfoliosHandler.GetPortfoliosByPlatform)
 		api.GET("/portfolios/:id", portfoliosHandler.GetPortfolio)
+		api.GET("/portfolios/:id/value", portfoliosHandler.GetPortfolioValue)
+		api.PUT("/portfolios/:id", portfoliosHandler.UpdatePortfolio)
+		api.PATCH("/portfolios/:id", portfoliosHandler.UpdatePortfolio)
+
+		// Account routes
+		api.GET("/accounts", accountsHandler.GetAccounts)
+		api.GET("/accounts/platform/:platform", accountsHandler.GetAccountsByPlatform)
+		api.GET("/accounts/:id", accountsHandler.GetAccount)
 
 		// Investment routes
 		api.GET("/investments", investmentsHandler.GetInvestments)
+		api.GET("/investments/performance", investmentsHandler.GetPerformance)
 		api.GET("/investments/portfolio/:portfolioId", investmentsHandler.GetInvestmentsByPortfolio)
 		api.GET("/investments/platform/:platform", investmentsHandler.GetInvestmentsByPlatform)
+		api.GET("/investments/symbol/:symbol", investmentsHandler.GetInvestmentsBySymbol)
+		api.GET("/investments/symbol/:symbol/history", investmentsHandler.GetInvestmentHistory)
+
+		// Transaction routes
+		api.GET("/transactions", transactionsHandler.GetTransactions)
+		api.GET("/transactions/summary", transactionsHandler.GetTransactionSummary)
+
+		// Stats routes
+		api.GET("/stats", statsHandler.GetStats)
+		api.GET("/metrics", metricsHandler.GetMetrics)
+
+		// Export routes
+		api.GET("/export", exportHandler.GetExport)
+		api.POST("/import", importHandler.PostImport)
 
 		// Net worth routes
 		api.GET("/networth", networthHandler.GetNetWorth)
 		api.GET("/networth/breakdown", networthHandler.GetNetWorthBreakdown)
 
 		// Sync routes
+		api.GET("/sync/status", syncHandler.GetSyncStatus)
 		api.POST("/sync", syncHandler.SyncAll)
 		api.POST("/sync/:platform", syncHandler.SyncPlatform)
 
+		// Plaid routes
+		api.POST("/plaid/link-token", plaidHandler.CreateLinkToken)
+		api.POST("/plaid/exchange", plaidHandler.ExchangePublicToken)
+		api.GET("/plaid/items", plaidHandler.GetItems)
+		api.DELETE("/plaid/items/:id", plaidHandler.DeleteItem)
+		api.POST("/plaid/items/:id/sync", syncHandler.SyncPlaidItem)
+		// Sandbox helpers exist only with PLAID_ENV=sandbox, and respond 404 otherwise
+		if handlers.RegisterPlaidSandboxRoutes(api, plaidSandbox, plaidHandler, syncHandler) {
+			log.Println("Plaid sandbox helpers enabled at /api/plaid/sandbox")
+		}
+
+		// Price routes
+		api.GET("/prices/:productId/candles", pricesHandler.GetCandles)
+
 		// Workflow routes
 		api.POST("/workflow/execute", workflowHandler.ExecuteWorkflow)
 		api.GET("/workflow/executions", workflowHandler.GetWorkflowExecutions)
 		api.GET("/workflow/executions/:id", workflowHandler.GetWorkflowExecution)
+		api.DELETE("/workflow/executions/:id", workflowHandler.DeleteWorkflowExecution)
+		api.POST("/workflow/executions/prune", workflowHandler.PruneWorkflowExecutions)
 		api.GET("/workflow/executions/:id/details", workflowHandler.GetWorkflowExecutionDetails)
+		api.GET("/workflow/executions/:id/events", workflowHandler.GetWorkflowExecutionEvents)
+		api.GET("/workflow/transcripts/search", workflowHandler.SearchTranscripts)
 		api.GET("/workflow/transcripts/:id", workflowHandler.GetTranscript)
 		api.GET("/workflow/analyses/:id", workflowHandler.GetMarketAnalysis)
 		api.GET("/workflow/recommendations/:id", workflowHandler.GetRecommendation)
 		api.GET("/workflow/recommendations/summary", workflowHandler.GetRecommendationsSummary)
 		api.POST("/workflow/recommendations/aggregate", workflowHandler.GenerateAggregatedRecommendation)
+		api.GET("/workflow/recommendations/aggregated", workflowHandler.GetAggregatedRecommendationHistory)
 		api.POST("/workflow/sources", workflowHandler.CreateYouTubeSource)
 		api.GET("/workflow/sources", workflowHandler.GetYouTubeSources)
 		api.GET("/workflow/sources/:id", workflowHandler.GetYouTubeSource)
 		api.PUT("/workflow/sources/:id", workflowHandler.UpdateYouTubeSource)
 		api.DELETE("/workflow/sources/:id", workflowHandler.DeleteYouTubeSource)
 		api.POST("/workflow/sources/:id/schedule", workflowHandler.UpdateSourceSchedule)
+		api.GET("/workflow/sources/:id/transcripts", workflowHandler.GetSourceTranscripts)
 		api.POST("/workflow/sources/test", workflowHandler.TestYouTubeSource)
 		api.POST("/workflow/sources/trigger-all", workflowHandler.TriggerAllSources)
 	}
 
-	// Start workflow scheduler
+	// Start workflow scheduler and transcript retention
 	workflowScheduler.Start()
 	defer workflowScheduler.Stop()
+	retentionWorker.Start()
+	defer retentionWorker.Stop()
+	plaidRefresher.Start()
+	defer plaidRefresher.Stop()
+	if livePrices != nil {
+		livePrices.Start()
+		defer livePrices.Stop()
+	}
 
 	// Get port from environment or default to 8080
 	port := os.Getenv("PORT")
@@ -239,10 +439,28 @@ func main() {
 		port = "8080"
 	}
 
-	// Start server
-	log.Printf("Server starting on :%s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	// Start server. On SIGINT or SIGTERM, stop accepting requests and let the ones in flight
+	// finish, so the deferred calls above stop the scheduler and close the store.
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on :%s", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Printf("Failed to start server: %v", err)
+		exitCode = 1
+		return
+	case <-shutdownSignal.Done():
+	}
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
 	}
 }
 
@@ -0,0 +1,127 @@
+// Package auth resolves API bearer tokens to users and scopes requests to them.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userIDKey is the gin context key holding the authenticated user ID
+const userIDKey = "user_id"
+
+// HashToken returns the SHA-256 hex digest stored for an API token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Middleware resolves the bearer token on each request to a user and records the user ID
+// on the context. When required is false, requests without a token are served as the
+// default user so single-user deployments keep working unchanged.
+func Middleware(s store.Store, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			if required {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "missing API token",
+				})
+				return
+			}
+			c.Set(userIDKey, models.DefaultUserID)
+			c.Next()
+			return
+		}
+
+		user, err := s.GetUserByTokenHash(c.Request.Context(), HashToken(token))
+		if errors.Is(err, store.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid API token",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to verify API token: " + err.Error(),
+			})
+			return
+		}
+
+		c.Set(userIDKey, user.ID)
+		c.Next()
+	}
+}
+
+// UserID returns the user ID resolved by Middleware, or the default user if none was set
+func UserID(c *gin.Context) string {
+	if userID := c.GetString(userIDKey); userID != "" {
+		return userID
+	}
+	return models.DefaultUserID
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header value
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+// LoadUsersFromEnv provisions users from API_USERS, a comma-separated list of
+// "user_id:token" or "user_id:name:token" entries. Use "default" as the user ID to give
+// the bootstrap user (which owns pre-existing data) a token. It returns the number of users
+// provisioned; when it is non-zero the API should require tokens.
+func LoadUsersFromEnv(ctx context.Context, s store.Store) (int, error) {
+	raw := os.Getenv("API_USERS")
+	if strings.TrimSpace(raw) == "" {
+		return 0, nil
+	}
+
+	count := 0
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		var user models.User
+		var token string
+		switch len(parts) {
+		case 2:
+			user = models.User{ID: parts[0], Name: parts[0]}
+			token = parts[1]
+		case 3:
+			user = models.User{ID: parts[0], Name: parts[1]}
+			token = parts[2]
+		default:
+			return count, fmt.Errorf("invalid API_USERS entry %q: expected user_id:token or user_id:name:token", entry)
+		}
+
+		user.ID = strings.TrimSpace(user.ID)
+		token = strings.TrimSpace(token)
+		if user.ID == "" || token == "" {
+			return count, fmt.Errorf("invalid API_USERS entry %q: user ID and token are required", entry)
+		}
+		user.TokenHash = HashToken(token)
+
+		if err := s.CreateOrUpdateUser(ctx, &user); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
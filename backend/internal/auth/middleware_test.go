@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveAs resolves the request's token with Middleware and returns the status and the user
+// the request was scoped to
+func serveAs(t *testing.T, s store.Store, required bool, authorization string) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(s, required))
+	var userID string
+	router.GET("/", func(c *gin.Context) {
+		userID = UserID(c)
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code, userID
+}
+
+func TestMiddlewareScopesRequestsToTokenOwner(t *testing.T) {
+	s := store.NewStore()
+	for _, user := range []models.User{
+		{ID: models.DefaultUserID, Name: "Owner", TokenHash: HashToken("owner-token")},
+		{ID: "alice", Name: "Alice", TokenHash: HashToken("alice-token")},
+	} {
+		if err := s.CreateOrUpdateUser(context.Background(), &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		required      bool
+		authorization string
+		wantStatus    int
+		wantUser      string
+	}{
+		{"owner token", true, "Bearer owner-token", http.StatusNoContent, models.DefaultUserID},
+		{"other user's token", true, "Bearer alice-token", http.StatusNoContent, "alice"},
+		{"scheme is case-insensitive", true, "bearer alice-token", http.StatusNoContent, "alice"},
+		{"unknown token", true, "Bearer guess", http.StatusUnauthorized, ""},
+		{"unknown token when optional", false, "Bearer guess", http.StatusUnauthorized, ""},
+		{"missing token", true, "", http.StatusUnauthorized, ""},
+		{"missing token when optional", false, "", http.StatusNoContent, models.DefaultUserID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, userID := serveAs(t, s, tt.required, tt.authorization)
+			if status != tt.wantStatus || userID != tt.wantUser {
+				t.Fatalf("status %d as user %q, want %d as %q", status, userID, tt.wantStatus, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package currency provides currency code validation, normalization and display precision.
+package currency
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Commonly referenced currency codes
+const (
+	USD = "USD"
+	EUR = "EUR"
+	GBP = "GBP"
+	BTC = "BTC"
+	ETH = "ETH"
+)
+
+// Reporting is the currency net worth is reported in. Holdings valued in another currency are
+// not converted yet, so they are counted apart from it.
+const Reporting = USD
+
+// Info describes a supported currency
+type Info struct {
+	Code      string `json:"code"`
+	Precision int    `json:"precision"` // Decimal places used for display and rounding
+	Crypto    bool   `json:"crypto"`
+}
+
+// fiatPrecision lists ISO-4217 codes whose minor unit is not the usual 2 decimals
+var fiatPrecision = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// iso4217 is the set of active ISO-4217 currency codes
+var iso4217 = map[string]bool{}
+
+func init() {
+	codes := `AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL
+		BSD BTN BWP BYN BZD CAD CDF CHF CLF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD
+		EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS
+		INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD
+		LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK
+		NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK
+		SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH
+		UGX USD UYI UYU UYW UZS VED VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL`
+	for _, code := range strings.Fields(codes) {
+		iso4217[code] = true
+	}
+}
+
+// cryptoCurrencies are non-ISO codes accepted as settlement currencies (e.g. Coinbase
+// accounts denominated in BTC), with their display precision
+var cryptoCurrencies = map[string]int{
+	BTC:    8,
+	ETH:    8,
+	"USDC": 6,
+	"USDT": 6,
+}
+
+// Lookup returns metadata for a currency code after normalizing it
+func Lookup(code string) (Info, bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	if precision, ok := cryptoCurrencies[normalized]; ok {
+		return Info{Code: normalized, Precision: precision, Crypto: true}, true
+	}
+	if iso4217[normalized] {
+		precision, ok := fiatPrecision[normalized]
+		if !ok {
+			precision = 2
+		}
+		return Info{Code: normalized, Precision: precision}, true
+	}
+	return Info{}, false
+}
+
+// IsReporting reports whether code is the reporting currency; an empty code is taken to be
+func IsReporting(code string) bool {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	return normalized == "" || normalized == Reporting
+}
+
+// IsValid reports whether code is a known ISO-4217 or allowed crypto currency code
+func IsValid(code string) bool {
+	_, ok := Lookup(code)
+	return ok
+}
+
+// Normalize trims and uppercases a currency code, returning an error if it is not known
+func Normalize(code string) (string, error) {
+	info, ok := Lookup(code)
+	if !ok {
+		return "", fmt.Errorf("invalid currency code %q: must be an ISO-4217 code or a supported crypto currency", code)
+	}
+	return info.Code, nil
+}
+
+// Precision returns the display precision for a currency, defaulting to 2 for unknown codes
+func Precision(code string) int {
+	if info, ok := Lookup(code); ok {
+		return info.Precision
+	}
+	return 2
+}
+
+// Round rounds an amount to the display precision of its currency
+func Round(amount float64, code string) float64 {
+	factor := math.Pow(10, float64(Precision(code)))
+	return math.Round(amount*factor) / factor
+}
+
+// Format renders an amount with the display precision of its currency, without symbols
+func Format(amount float64, code string) string {
+	return strconv.FormatFloat(amount, 'f', Precision(code), 64)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountsHandler handles account-related HTTP requests
+type AccountsHandler struct {
+	store store.Store
+}
+
+// NewAccountsHandler creates a new accounts handler
+func NewAccountsHandler(store store.Store) *AccountsHandler {
+	return &AccountsHandler{
+		store: store,
+	}
+}
+
+// GetAccounts returns all accounts with their balances
+func (h *AccountsHandler) GetAccounts(c *gin.Context) {
+	accounts, err := userStore(c, h.store).GetAllAccounts(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "get accounts", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+	})
+}
+
+// GetAccountsByPlatform returns accounts for a specific platform
+func (h *AccountsHandler) GetAccountsByPlatform(c *gin.Context) {
+	platform, err := models.ParsePlatform(c.Param("platform"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	accounts, err := userStore(c, h.store).GetAccountsByPlatform(c.Request.Context(), platform)
+	if err != nil {
+		respondStoreError(c, err, "get accounts", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"platform": platform,
+		"accounts": accounts,
+	})
+}
+
+// GetAccount returns an account by ID
+func (h *AccountsHandler) GetAccount(c *gin.Context) {
+	account, err := userStore(c, h.store).GetAccountByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondStoreError(c, err, "get account", "account not found")
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
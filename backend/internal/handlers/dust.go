@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+
+	"0xnetworth/backend/internal/currency"
+	"0xnetworth/backend/internal/models"
+)
+
+// dustPolicy is how a sync treats holdings worth less than a threshold, such as the leftovers
+// of staking and earn rewards
+type dustPolicy struct {
+	threshold float64 // In the reporting currency; 0 disables the policy
+	skip      bool    // Leave dust out of the sync rather than flag it
+}
+
+// syncDustPolicy reads SYNC_MIN_HOLDING_VALUE_USD and SYNC_DUST_MODE once. An invalid value is
+// logged and the default used: no threshold, and dust flagged rather than skipped.
+var syncDustPolicy = sync.OnceValue(func() dustPolicy {
+	var policy dustPolicy
+	if value := os.Getenv("SYNC_MIN_HOLDING_VALUE_USD"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 || math.IsInf(threshold, 0) {
+			log.Printf("Warning: SYNC_MIN_HOLDING_VALUE_USD must be a non-negative number, got %q, disabling the dust threshold", value)
+			return dustPolicy{}
+		}
+		policy.threshold = threshold
+	}
+	switch mode := os.Getenv("SYNC_DUST_MODE"); mode {
+	case "", "flag":
+	case "skip":
+		policy.skip = true
+	default:
+		log.Printf("Warning: SYNC_DUST_MODE must be flag or skip, got %q, using flag", mode)
+	}
+	return policy
+})
+
+// apply flags the synced investments worth less than the threshold as dust, or with skip
+// removes them from the result, so that holdings of fully synced portfolios are deactivated
+// like sold ones. It runs on priced values; holdings valued in another currency are not
+// compared, nor derivative positions, whose value is their profit or loss. It returns how many
+// holdings were dust.
+func (p dustPolicy) apply(result *models.SyncResult) int {
+	if p.threshold == 0 {
+		return 0
+	}
+	dust := 0
+	kept := result.Investments[:0]
+	for _, investment := range result.Investments {
+		investment.IsDust = currency.IsReporting(investment.Currency) &&
+			investment.AssetType != models.AssetTypeDerivative &&
+			math.Abs(investment.Value) < p.threshold
+		if investment.IsDust {
+			dust++
+			if p.skip {
+				continue
+			}
+		}
+		kept = append(kept, investment)
+	}
+	result.Investments = kept
+	if dust > 0 {
+		action := "Flagged"
+		if p.skip {
+			action = "Skipped"
+		}
+		log.Printf("Info: %s %d %s holdings worth less than %.2f %s as dust", action, dust, result.Platform, p.threshold, currency.Reporting)
+	}
+	return dust
+}
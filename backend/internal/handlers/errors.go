@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondStoreError writes the response for a failed store call. Missing records map to
+// 404 with notFoundMessage when one is given; anything else is a 500 naming the action.
+func respondStoreError(c *gin.Context, err error, action string, notFoundMessage string) {
+	if notFoundMessage != "" && errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": notFoundMessage,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "failed to " + action + ": " + err.Error(),
+	})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportPageSize is how many records of a type are read from the store at a time while exporting
+const exportPageSize = 500
+
+// ExportHandler serves full data exports
+type ExportHandler struct {
+	store store.Store
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(store store.Store) *ExportHandler {
+	return &ExportHandler{
+		store: store,
+	}
+}
+
+// exportSection writes the records of one type by passing each to emit
+type exportSection struct {
+	name  string
+	write func(emit func(v any) error) error
+}
+
+// GetExport streams the user's data as a models.Export document. Records are read from the
+// store a page at a time and encoded as they arrive, so the document is never held in memory.
+// Once streaming has started the status can no longer change, so a failure part way through
+// ends the response early; the document is then invalid JSON and lacks "complete": true.
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	ctx := c.Request.Context()
+	scoped := userStore(c, h.store)
+	exportedAt := models.Now()
+
+	sections := []exportSection{
+		{"portfolios", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.Portfolio, error) {
+				portfolios, _, err := scoped.GetAllPortfolios(ctx, opts, store.SortOption{})
+				return portfolios, err
+			})
+		}},
+		{"accounts", func(emit func(v any) error) error {
+			accounts, err := scoped.GetAllAccounts(ctx)
+			return exportAll(emit, accounts, err)
+		}},
+		{"investments", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.Investment, error) {
+				investments, _, err := scoped.GetAllInvestments(ctx, opts, store.SortOption{}, store.InvestmentFilter{IncludeInactive: true})
+				return investments, err
+			})
+		}},
+		{"transactions", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.Transaction, error) {
+				transactions, _, err := scoped.ListTransactions(ctx, store.TransactionFilter{ListOptions: opts})
+				return transactions, err
+			})
+		}},
+		{"networth_snapshots", func(emit func(v any) error) error {
+			snapshots, err := scoped.GetNetWorthSnapshots(ctx, time.Time{}, time.Time{})
+			return exportAll(emit, snapshots, err)
+		}},
+	}
+	sections = append(sections, workflowExportSections(ctx, scoped)...)
+
+	filename := fmt.Sprintf("0xnetworth-export-%s.json", exportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if err := writeExport(c.Writer, exportedAt, sections); err != nil {
+		log.Printf("Export failed part way through: %v", err)
+	}
+}
+
+// workflowExportSections returns the sections of the workflow records
+func workflowExportSections(ctx context.Context, s store.Store) []exportSection {
+	return []exportSection{
+		{"youtube_sources", func(emit func(v any) error) error {
+			sources, err := s.GetAllYouTubeSources(ctx)
+			return exportAll(emit, sources, err)
+		}},
+		{"transcripts", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.VideoTranscript, error) {
+				return s.ListTranscripts(ctx, opts)
+			})
+		}},
+		{"market_analyses", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.MarketAnalysis, error) {
+				return s.ListMarketAnalyses(ctx, opts)
+			})
+		}},
+		{"recommendations", func(emit func(v any) error) error {
+			return exportPages(emit, func(opts store.ListOptions) ([]*models.Recommendation, error) {
+				return s.ListRecommendations(ctx, opts)
+			})
+		}},
+		{"workflow_executions", func(emit func(v any) error) error {
+			executions, err := s.GetAllWorkflowExecutions(ctx)
+			return exportAll(emit, executions, err)
+		}},
+		{"aggregated_recommendations", func(emit func(v any) error) error {
+			recs, err := s.GetAggregatedRecommendations(ctx, 0)
+			return exportAll(emit, recs, err)
+		}},
+	}
+}
+
+// writeExport writes the document header, each section as a JSON array and the trailing
+// completion marker
+func writeExport(w io.Writer, exportedAt time.Time, sections []exportSection) error {
+	enc := json.NewEncoder(w)
+	field := func(prefix, name string, v any) error {
+		if _, err := fmt.Fprintf(w, "%s%q:", prefix, name); err != nil {
+			return err
+		}
+		return enc.Encode(v)
+	}
+
+	if err := field("{", "schema", models.ExportSchema); err != nil {
+		return err
+	}
+	if err := field(",", "version", models.ExportVersion); err != nil {
+		return err
+	}
+	if err := field(",", "exported_at", exportedAt); err != nil {
+		return err
+	}
+	for _, section := range sections {
+		if _, err := fmt.Fprintf(w, ",%q:[", section.name); err != nil {
+			return err
+		}
+		first := true
+		emit := func(v any) error {
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			return enc.Encode(v)
+		}
+		if err := section.write(emit); err != nil {
+			return fmt.Errorf("failed to export %s: %w", section.name, err)
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+	}
+	if err := field(",", "complete", true); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// exportPages emits every record returned by fetch, reading exportPageSize records at a time
+func exportPages[T any](emit func(v any) error, fetch func(opts store.ListOptions) ([]T, error)) error {
+	for offset := 0; ; offset += exportPageSize {
+		page, err := fetch(store.ListOptions{Limit: exportPageSize, Offset: offset})
+		if err := exportAll(emit, page, err); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// exportAll emits each record, or returns err if loading them failed
+func exportAll[T any](emit func(v any) error, records []T, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := emit(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"0xnetworth/backend/internal/auth"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestRouter returns a router that requires API tokens and resolves them against s
+func newTestRouter(s store.Store) *gin.Engine {
+	router := gin.New()
+	router.Use(auth.Middleware(s, true))
+	return router
+}
+
+// addTestUser provisions a user in s and returns its API token
+func addTestUser(t *testing.T, s store.Store, userID string) string {
+	t.Helper()
+	token := "token-" + userID
+	err := s.CreateOrUpdateUser(context.Background(), &models.User{
+		ID:        userID,
+		Name:      userID,
+		TokenHash: auth.HashToken(token),
+	})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateUser(%s): %v", userID, err)
+	}
+	return token
+}
+
+// doRequest serves a request with an optional JSON body as the user holding token
+func doRequest(t *testing.T, router http.Handler, method, target, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeJSON decodes a response body into v
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+}
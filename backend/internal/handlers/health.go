@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/integrations/youtube"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout bounds the store check so a hung database fails the probe quickly
+const healthCheckTimeout = 2 * time.Second
+
+// CoinbaseCredentials reports the result of the latest Coinbase credential check.
+// *coinbase.Client implements it.
+type CoinbaseCredentials interface {
+	Credentials() coinbase.CredentialStatus
+}
+
+var _ CoinbaseCredentials = (*coinbase.Client)(nil)
+
+// YouTubeQuota reports the estimated YouTube API quota used today, or false if it is not
+// tracked. *youtube.Client implements it, including a nil one.
+type YouTubeQuota interface {
+	QuotaUsage(ctx context.Context) (youtube.QuotaUsage, bool)
+}
+
+var _ YouTubeQuota = (*youtube.Client)(nil)
+
+// HealthHandler reports whether the service can serve requests
+type HealthHandler struct {
+	store    store.Store
+	coinbase CoinbaseCredentials // nil when Coinbase is not configured
+	youtube  YouTubeQuota
+}
+
+// NewHealthHandler creates a new health handler. coinbaseCredentials is nil when Coinbase is
+// not configured.
+func NewHealthHandler(store store.Store, coinbaseCredentials CoinbaseCredentials, youtubeQuota YouTubeQuota) *HealthHandler {
+	return &HealthHandler{
+		store:    store,
+		coinbase: coinbaseCredentials,
+		youtube:  youtubeQuota,
+	}
+}
+
+// GetHealth checks the store and returns 503 if it is unavailable, so container health
+// checks and readiness probes can act on it. Pool statistics are included for stores that
+// have a connection pool. The Coinbase credential check is reported under "coinbase" but does
+// not affect the status code, since the service can serve stored data without Coinbase. The
+// YouTube quota used today is reported under "youtube_quota", so a budget running out is seen
+// before polling stops.
+func (h *HealthHandler) GetHealth(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	status, storeStatus, code := "ok", "ok", http.StatusOK
+	if err := h.store.HealthCheck(ctx); err != nil {
+		status, storeStatus, code = "unavailable", "error: "+err.Error(), http.StatusServiceUnavailable
+	}
+
+	response := gin.H{
+		"status":  status,
+		"service": "0xnetworth-backend",
+		"store":   storeStatus,
+	}
+	if provider, ok := h.store.(store.PoolStatsProvider); ok {
+		response["pool"] = provider.PoolStats()
+	}
+	if h.coinbase != nil {
+		response["coinbase"] = h.coinbase.Credentials()
+	} else {
+		response["coinbase"] = coinbase.CredentialStatus{State: coinbase.CredentialsNotConfigured, Summary: "not configured"}
+	}
+	if h.youtube != nil {
+		if usage, ok := h.youtube.QuotaUsage(ctx); ok {
+			response["youtube_quota"] = usage
+		} else {
+			response["youtube_quota"] = "not configured"
+		}
+	}
+	c.JSON(code, response)
+}
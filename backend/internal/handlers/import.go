@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Import modes accepted by PostImport
+const (
+	importModeMerge   = "merge"
+	importModeReplace = "replace"
+)
+
+// ImportHandler restores data from an export document
+type ImportHandler struct {
+	store store.Store
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(store store.Store) *ImportHandler {
+	return &ImportHandler{
+		store: store,
+	}
+}
+
+// importSection writes the records of one type from an export document
+type importSection struct {
+	kind store.RecordKind
+	// present is true when the document contains the section, even if it is empty
+	present bool
+	write   func(ctx context.Context, tx store.Store) (int, error)
+}
+
+// PostImport restores a models.Export document. In merge mode (the default) records are
+// upserted over the existing data; in replace mode every record type present in the document
+// is deleted first. Everything is written in one transaction, so a failure imports nothing.
+func (h *ImportHandler) PostImport(c *gin.Context) {
+	mode := c.DefaultQuery("mode", importModeMerge)
+	if mode != importModeMerge && mode != importModeReplace {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "mode must be merge or replace",
+		})
+		return
+	}
+
+	var doc models.Export
+	if err := json.NewDecoder(c.Request.Body).Decode(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid export document: " + err.Error(),
+		})
+		return
+	}
+	if problems := validateImport(&doc); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "export document cannot be imported",
+			"problems": problems,
+		})
+		return
+	}
+
+	sections := importSections(&doc)
+
+	deleted := make(map[store.RecordKind]int)
+	imported := make(map[store.RecordKind]int)
+	var networth *models.NetWorth
+	err := userStore(c, h.store).WithTransaction(c.Request.Context(), func(tx store.Store) error {
+		ctx := c.Request.Context()
+		if mode == importModeReplace {
+			// Delete dependent records before the records they reference
+			for i := len(sections) - 1; i >= 0; i-- {
+				if !sections[i].present {
+					continue
+				}
+				n, err := tx.DeleteRecords(ctx, sections[i].kind)
+				if err != nil {
+					return err
+				}
+				deleted[sections[i].kind] = n
+			}
+		}
+		for _, section := range sections {
+			if !section.present {
+				continue
+			}
+			n, err := section.write(ctx, tx)
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %w", section.kind, err)
+			}
+			imported[section.kind] = n
+		}
+		var err error
+		networth, err = tx.RecalculateNetWorth(ctx)
+		return err
+	})
+	if err != nil {
+		log.Printf("Import failed and was rolled back: %v", err)
+		respondStoreError(c, err, "import data", "")
+		return
+	}
+
+	response := gin.H{
+		"mode":     mode,
+		"imported": imported,
+		"networth": networth,
+	}
+	if mode == importModeReplace {
+		response["deleted"] = deleted
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// validateImport lists the reasons doc cannot be imported
+func validateImport(doc *models.Export) []string {
+	var problems []string
+	if doc.Schema != models.ExportSchema {
+		problems = append(problems, fmt.Sprintf("schema is %q, expected %q", doc.Schema, models.ExportSchema))
+	}
+	if doc.Version != models.ExportVersion {
+		problems = append(problems, fmt.Sprintf("unsupported version %d, expected %d", doc.Version, models.ExportVersion))
+	}
+	if !doc.Complete {
+		problems = append(problems, "document is incomplete; the export that wrote it failed part way through")
+	}
+
+	checkIDs := func(kind store.RecordKind, ids []string) {
+		seen := make(map[string]bool, len(ids))
+		for i, id := range ids {
+			switch {
+			case id == "":
+				problems = append(problems, fmt.Sprintf("%s[%d] has no id", kind, i))
+			case seen[id]:
+				problems = append(problems, fmt.Sprintf("%s[%d] repeats id %q", kind, i, id))
+			}
+			seen[id] = true
+		}
+	}
+	checkIDs(store.RecordPortfolios, recordIDs(doc.Portfolios, func(p *models.Portfolio) string { return p.ID }))
+	checkIDs(store.RecordAccounts, recordIDs(doc.Accounts, func(a *models.Account) string { return a.ID }))
+	checkIDs(store.RecordInvestments, recordIDs(doc.Investments, func(inv *models.Investment) string { return inv.ID }))
+	checkIDs(store.RecordTransactions, recordIDs(doc.Transactions, func(t *models.Transaction) string { return t.ID }))
+	checkIDs(store.RecordYouTubeSources, recordIDs(doc.YouTubeSources, func(s *models.YouTubeSource) string { return s.ID }))
+	checkIDs(store.RecordTranscripts, recordIDs(doc.Transcripts, func(t *models.VideoTranscript) string { return t.ID }))
+	checkIDs(store.RecordMarketAnalyses, recordIDs(doc.MarketAnalyses, func(a *models.MarketAnalysis) string { return a.ID }))
+	checkIDs(store.RecordRecommendations, recordIDs(doc.Recommendations, func(r *models.Recommendation) string { return r.ID }))
+	checkIDs(store.RecordWorkflowExecutions, recordIDs(doc.WorkflowExecutions, func(e *models.WorkflowExecution) string { return e.ID }))
+	checkIDs(store.RecordAggregatedRecommendations, recordIDs(doc.AggregatedRecommendations, func(r *models.AggregatedRecommendation) string { return r.ID }))
+
+	for i, p := range doc.Portfolios {
+		if p != nil && p.TaxTreatment != "" && !p.TaxTreatment.IsValid() {
+			problems = append(problems, fmt.Sprintf("portfolios[%d] has unknown tax treatment %q", i, p.TaxTreatment))
+		}
+	}
+	for i, source := range doc.YouTubeSources {
+		if source == nil {
+			continue
+		}
+		if _, err := source.TitleFilter(); err != nil {
+			problems = append(problems, fmt.Sprintf("youtube_sources[%d] %v", i, err))
+		}
+	}
+	for i, snapshot := range doc.NetWorthSnapshots {
+		if snapshot == nil || snapshot.Timestamp.IsZero() {
+			problems = append(problems, fmt.Sprintf("networth_snapshots[%d] has no timestamp", i))
+		}
+	}
+	return problems
+}
+
+// recordIDs returns the ID of each record, or "" for null entries
+func recordIDs[T any](records []*T, id func(*T) string) []string {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		if record != nil {
+			ids[i] = id(record)
+		}
+	}
+	return ids
+}
+
+// importSections lists the document's record types in the order they must be written, so
+// that each record's references already exist
+func importSections(doc *models.Export) []importSection {
+	return []importSection{
+		{store.RecordPortfolios, doc.Portfolios != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.Portfolios, func(ctx context.Context, p *models.Portfolio) error {
+				_, err := tx.CreateOrUpdatePortfolio(ctx, p)
+				return err
+			})
+		}},
+		{store.RecordAccounts, doc.Accounts != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.Accounts, func(ctx context.Context, a *models.Account) error {
+				_, err := tx.CreateOrUpdateAccount(ctx, a)
+				return err
+			})
+		}},
+		{store.RecordInvestments, doc.Investments != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importInvestments(ctx, tx, doc.Investments)
+		}},
+		{store.RecordTransactions, doc.Transactions != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.Transactions, tx.CreateOrUpdateTransaction)
+		}},
+		{store.RecordNetWorthSnapshots, doc.NetWorthSnapshots != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.NetWorthSnapshots, func(ctx context.Context, snapshot *models.NetWorthSnapshot) error {
+				return tx.SaveNetWorthSnapshot(ctx, &models.NetWorth{
+					TotalValue:     snapshot.TotalValue,
+					Currency:       snapshot.Currency,
+					ByPlatform:     snapshot.ByPlatform,
+					ByAssetType:    snapshot.ByAssetType,
+					ByTaxTreatment: snapshot.ByTaxTreatment,
+					AccountCount:   snapshot.AccountCount,
+					LastCalculated: snapshot.Timestamp,
+				})
+			})
+		}},
+		{store.RecordYouTubeSources, doc.YouTubeSources != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.YouTubeSources, tx.CreateOrUpdateYouTubeSource)
+		}},
+		{store.RecordTranscripts, doc.Transcripts != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.Transcripts, tx.CreateOrUpdateTranscript)
+		}},
+		{store.RecordMarketAnalyses, doc.MarketAnalyses != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.MarketAnalyses, tx.CreateOrUpdateMarketAnalysis)
+		}},
+		{store.RecordRecommendations, doc.Recommendations != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.Recommendations, tx.CreateOrUpdateRecommendation)
+		}},
+		{store.RecordWorkflowExecutions, doc.WorkflowExecutions != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.WorkflowExecutions, tx.CreateOrUpdateWorkflowExecution)
+		}},
+		{store.RecordAggregatedRecommendations, doc.AggregatedRecommendations != nil, func(ctx context.Context, tx store.Store) (int, error) {
+			return importEach(ctx, doc.AggregatedRecommendations, tx.CreateOrUpdateAggregatedRecommendation)
+		}},
+	}
+}
+
+// importEach writes each non-null record, returning how many were written
+func importEach[T any](ctx context.Context, records []*T, write func(context.Context, *T) error) (int, error) {
+	written := 0
+	for _, record := range records {
+		if record == nil {
+			continue
+		}
+		if err := write(ctx, record); err != nil {
+			return written, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// importInvestments writes the investments as one batch. Writing reactivates every
+// investment, so those exported as inactive are then deactivated again as of their
+// original deactivation time.
+func importInvestments(ctx context.Context, tx store.Store, investments []*models.Investment) (int, error) {
+	batch := make([]*models.Investment, 0, len(investments))
+	inactive := make(map[time.Time][]string)
+	for _, inv := range investments {
+		if inv == nil {
+			continue
+		}
+		batch = append(batch, inv)
+		if !inv.Active {
+			at := models.Now()
+			if inv.DeactivatedAt != nil {
+				at = inv.DeactivatedAt.UTC()
+			}
+			inactive[at] = append(inactive[at], inv.ID)
+		}
+	}
+	if _, err := tx.CreateOrUpdateInvestments(ctx, batch); err != nil {
+		return 0, err
+	}
+	for at, ids := range inactive {
+		if _, err := tx.DeactivateInvestments(ctx, ids, at); err != nil {
+			return 0, err
+		}
+	}
+	return len(batch), nil
+}
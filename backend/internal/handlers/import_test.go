@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+func newImportExportRouter(s store.Store) http.Handler {
+	router := newTestRouter(s)
+	router.GET("/api/export", NewExportHandler(s).GetExport)
+	router.POST("/api/import", NewImportHandler(s).PostImport)
+	return router
+}
+
+// importDocument returns an export document holding the given sections
+func importDocument(sections string) string {
+	return fmt.Sprintf(`{"schema":%q,"version":%d,"exported_at":"2024-05-01T00:00:00Z",%s,"complete":true}`,
+		models.ExportSchema, models.ExportVersion, sections)
+}
+
+func TestImportWorkflowSectionsOnlyChangeOwnRecords(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	otherToken := addTestUser(t, s, "alice")
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "src-1", Type: models.YouTubeSourceTypeChannel, Name: "Owner's channel"}); err != nil {
+		t.Fatal(err)
+	}
+	router := newImportExportRouter(s)
+
+	rec := doRequest(t, router, http.MethodPost, "/api/import?mode=replace", otherToken,
+		importDocument(`"youtube_sources":[{"id":"src-2","type":"channel","name":"Alice's channel","url":"https://www.youtube.com/@alice"}]`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("other user's replace import: status %d: %s", rec.Code, rec.Body)
+	}
+	sources, err := s.GetAllYouTubeSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 || sources[0].ID != "src-1" {
+		t.Fatalf("owner's YouTube sources after another user's import = %+v, want only src-1", sources)
+	}
+	sources, err = s.ForUser("alice").GetAllYouTubeSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 || sources[0].ID != "src-2" {
+		t.Fatalf("other user's YouTube sources after import = %+v, want only src-2", sources)
+	}
+}
+
+func TestExportHoldsOnlyOwnWorkflowRecords(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	ownerToken := addTestUser(t, s, models.DefaultUserID)
+	otherToken := addTestUser(t, s, "alice")
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "src-1", Type: models.YouTubeSourceTypeChannel, Name: "Owner's channel"}); err != nil {
+		t.Fatal(err)
+	}
+	router := newImportExportRouter(s)
+
+	var doc models.Export
+	rec := doRequest(t, router, http.MethodGet, "/api/export", otherToken, "")
+	decodeJSON(t, rec, &doc)
+	if !doc.Complete {
+		t.Fatalf("other user's export is incomplete: %s", rec.Body)
+	}
+	if len(doc.YouTubeSources) != 0 {
+		t.Fatalf("other user's export has %d YouTube sources, want 0", len(doc.YouTubeSources))
+	}
+
+	// The export imports back cleanly and leaves the owner's records alone
+	rec = doRequest(t, router, http.MethodPost, "/api/import?mode=replace", otherToken, rec.Body.String())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("re-importing other user's export: status %d: %s", rec.Code, rec.Body)
+	}
+
+	doc = models.Export{}
+	rec = doRequest(t, router, http.MethodGet, "/api/export", ownerToken, "")
+	decodeJSON(t, rec, &doc)
+	if len(doc.YouTubeSources) != 1 {
+		t.Fatalf("default user's export has %d YouTube sources, want 1", len(doc.YouTubeSources))
+	}
+}
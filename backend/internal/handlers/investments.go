@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"0xnetworth/backend/internal/models"
 	"0xnetworth/backend/internal/store"
@@ -21,18 +26,107 @@ func NewInvestmentsHandler(store store.Store) *InvestmentsHandler {
 	}
 }
 
-// GetInvestments returns all investments
+// parseInvestmentFilter reads ?include_inactive, which adds holdings that a sync no longer
+// reports to an investment listing, and ?include_dust, which adds holdings flagged as dust
+func parseInvestmentFilter(c *gin.Context) (store.InvestmentFilter, error) {
+	filter := store.InvestmentFilter{ExcludeDust: true}
+	if value := c.Query("include_inactive"); value != "" {
+		includeInactive, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("include_inactive must be true or false")
+		}
+		filter.IncludeInactive = includeInactive
+	}
+	if value := c.Query("include_dust"); value != "" {
+		includeDust, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("include_dust must be true or false")
+		}
+		filter.ExcludeDust = !includeDust
+	}
+	return filter, nil
+}
+
+// labelPortfolios sets the portfolio name of each investment, so listings can be grouped by
+// portfolio
+func labelPortfolios(ctx context.Context, s store.Store, investments []*models.Investment) error {
+	portfolios, _, err := s.GetAllPortfolios(ctx, store.ListOptions{}, store.SortOption{})
+	if err != nil {
+		return err
+	}
+	accounts, err := s.GetAllAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	models.LabelPortfolios(investments, portfolios, accounts)
+	return nil
+}
+
+// GetInvestments returns a page of investments (see parseListOptions and parseSortOption)
 func (h *InvestmentsHandler) GetInvestments(c *gin.Context) {
-	investments := h.store.GetAllInvestments()
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	order, err := parseSortOption(c, store.InvestmentSortFields())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	filter, err := parseInvestmentFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	investments, total, err := userStore(c, h.store).GetAllInvestments(c.Request.Context(), opts, order, filter)
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+	models.FlagUnconverted(investments)
+	models.SetUnrealizedPLPercent(investments)
+	if err := labelPortfolios(c.Request.Context(), userStore(c, h.store), investments); err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"investments": investments,
+		"total_count": total,
+		"limit":       opts.Limit,
+		"offset":      opts.Offset,
 	})
 }
 
 // GetInvestmentsByPortfolio returns investments for a specific portfolio
 func (h *InvestmentsHandler) GetInvestmentsByPortfolio(c *gin.Context) {
 	portfolioID := c.Param("portfolioId")
-	investments := h.store.GetInvestmentsByAccount(portfolioID) // AccountID field is actually portfolio ID
+	filter, err := parseInvestmentFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	investments, err := userStore(c, h.store).GetInvestmentsByAccount(c.Request.Context(), portfolioID, filter) // AccountID field is actually portfolio ID
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+	models.FlagUnconverted(investments)
+	models.SetUnrealizedPLPercent(investments)
+	if err := labelPortfolios(c.Request.Context(), userStore(c, h.store), investments); err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"portfolio_id": portfolioID,
 		"investments": investments,
@@ -41,21 +135,132 @@ func (h *InvestmentsHandler) GetInvestmentsByPortfolio(c *gin.Context) {
 
 // GetInvestmentsByPlatform returns investments for a specific platform
 func (h *InvestmentsHandler) GetInvestmentsByPlatform(c *gin.Context) {
-	platformStr := c.Param("platform")
-	platform := models.Platform(platformStr)
+	platform, err := models.ParsePlatform(c.Param("platform"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	// Validate platform
-	if platform != models.PlatformCoinbase {
+	filter, err := parseInvestmentFilter(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid platform. Must be 'coinbase'",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	investments := h.store.GetInvestmentsByPlatform(platform)
+	investments, err := userStore(c, h.store).GetInvestmentsByPlatform(c.Request.Context(), platform, filter)
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+	models.FlagUnconverted(investments)
+	models.SetUnrealizedPLPercent(investments)
+	if err := labelPortfolios(c.Request.Context(), userStore(c, h.store), investments); err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"platform": platform,
 		"investments": investments,
 	})
 }
 
+
+// GetInvestmentsBySymbol returns every holding of a symbol across accounts and platforms,
+// with the total quantity and value. An unknown symbol returns an empty list, not a 404.
+func (h *InvestmentsHandler) GetInvestmentsBySymbol(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "symbol is required",
+		})
+		return
+	}
+
+	filter, err := parseInvestmentFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	investments, err := userStore(c, h.store).GetInvestmentsBySymbol(c.Request.Context(), symbol, filter)
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+
+	// Values in other currencies cannot be summed with the rest until they are converted
+	models.FlagUnconverted(investments)
+	models.SetUnrealizedPLPercent(investments)
+	if err := labelPortfolios(c.Request.Context(), userStore(c, h.store), investments); err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
+	totalQuantity, totalValue := 0.0, 0.0
+	for _, inv := range investments {
+		totalQuantity += inv.Quantity
+		if !inv.Unconverted {
+			totalValue += inv.Value
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":         symbol,
+		"investments":    investments,
+		"total_quantity": totalQuantity,
+		"total_value":    totalValue,
+	})
+}
+
+// GetInvestmentHistory handles GET /api/investments/symbol/:symbol/history
+// Returns the quantity, price and value of the symbol on each platform at every sync, oldest
+// first. Optional query parameters: from and to (RFC3339 or YYYY-MM-DD), bounding the range
+// [from, to).
+func (h *InvestmentsHandler) GetInvestmentHistory(c *gin.Context) {
+	symbol := strings.ToUpper(strings.TrimSpace(c.Param("symbol")))
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "symbol is required",
+		})
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if fromStr := c.Query("from"); fromStr != "" {
+		if from, err = parseDateParam(fromStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid from parameter: " + err.Error(),
+			})
+			return
+		}
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		if to, err = parseDateParam(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid to parameter: " + err.Error(),
+			})
+			return
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "from must not be after to",
+		})
+		return
+	}
+
+	history, err := userStore(c, h.store).GetInvestmentHistory(c.Request.Context(), symbol, from, to)
+	if err != nil {
+		respondStoreError(c, err, "get investment history", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"symbol":  symbol,
+		"history": history,
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+func TestGetInvestmentsCostBasisNullVsZero(t *testing.T) {
+	s := store.NewStore()
+	token := addTestUser(t, s, models.DefaultUserID)
+	router := newTestRouter(s)
+	router.GET("/api/investments", NewInvestmentsHandler(s).GetInvestments)
+
+	zero, cost, gain := 0.0, 40.0, 20.0
+	if _, err := s.ForUser(models.DefaultUserID).CreateOrUpdateInvestments(context.Background(), []*models.Investment{
+		{ID: "unknown", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60, Price: 60, Currency: "USD"},
+		{ID: "free", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 1, Value: 30, Price: 30, Currency: "USD", CostBasis: &zero},
+		{ID: "held", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "SOL", Quantity: 1, Value: 60, Price: 60, Currency: "USD", CostBasis: &cost, UnrealizedGain: &gain},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := doRequest(t, router, http.MethodGet, "/api/investments", token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Investments []map[string]json.RawMessage `json:"investments"`
+	}
+	decodeJSON(t, rec, &body)
+	byID := make(map[string]map[string]json.RawMessage)
+	for _, investment := range body.Investments {
+		var id string
+		if err := json.Unmarshal(investment["id"], &id); err != nil {
+			t.Fatal(err)
+		}
+		byID[id] = investment
+	}
+
+	if got := string(byID["unknown"]["cost_basis"]); got != "null" {
+		t.Errorf("unknown cost_basis = %s, want null", got)
+	}
+	if got := string(byID["unknown"]["unrealized_gain"]); got != "null" {
+		t.Errorf("unknown unrealized_gain = %s, want null", got)
+	}
+	if got := string(byID["free"]["cost_basis"]); got != "0" {
+		t.Errorf("zero cost_basis = %s, want 0", got)
+	}
+	for id, want := range map[string]string{"unknown": "null", "free": "null", "held": "50"} {
+		if got := string(byID[id]["unrealized_pl_percent"]); got != want {
+			t.Errorf("%s unrealized_pl_percent = %s, want %s", id, got, want)
+		}
+	}
+}
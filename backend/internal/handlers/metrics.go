@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"0xnetworth/backend/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler serves the process's operation metrics, such as Coinbase request and
+// PostgreSQL query latency
+type MetricsHandler struct {
+	registry *metrics.Registry
+}
+
+// NewMetricsHandler creates a metrics handler serving registry
+func NewMetricsHandler(registry *metrics.Registry) *MetricsHandler {
+	return &MetricsHandler{
+		registry: registry,
+	}
+}
+
+// GetMetrics returns the count, errors and latency of every operation recorded since startup,
+// only those whose name starts with ?prefix= if it is set (e.g. "coinbase")
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	prefix := c.Query("prefix")
+	operations := make([]metrics.OperationStats, 0)
+	for _, stats := range h.registry.Snapshot() {
+		if strings.HasPrefix(stats.Name, prefix) {
+			operations = append(operations, stats)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"operations": operations,
+	})
+}
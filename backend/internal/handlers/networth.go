@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"0xnetworth/backend/internal/models"
 	"0xnetworth/backend/internal/store"
 
 	"github.com/gin-gonic/gin"
@@ -23,23 +24,53 @@ func NewNetWorthHandler(store store.Store) *NetWorthHandler {
 // GetNetWorth returns the current net worth
 func (h *NetWorthHandler) GetNetWorth(c *gin.Context) {
 	// Recalculate before returning to ensure accuracy
-	h.store.RecalculateNetWorth()
-	networth := h.store.GetNetWorth()
+	networth, err := userStore(c, h.store).RecalculateNetWorth(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "calculate net worth", "")
+		return
+	}
 	c.JSON(http.StatusOK, networth)
 }
 
 // GetNetWorthBreakdown returns detailed breakdown of net worth
 func (h *NetWorthHandler) GetNetWorthBreakdown(c *gin.Context) {
+	s := userStore(c, h.store)
 	// Recalculate before returning
-	h.store.RecalculateNetWorth()
-	networth := h.store.GetNetWorth()
-	portfolios := h.store.GetAllPortfolios()
-	investments := h.store.GetAllInvestments()
+	networth, err := s.RecalculateNetWorth(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "calculate net worth", "")
+		return
+	}
+	portfolios, _, err := s.GetAllPortfolios(c.Request.Context(), store.ListOptions{}, store.SortOption{})
+	if err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
+	investments, _, err := s.GetAllInvestments(c.Request.Context(), store.ListOptions{}, store.SortOption{}, store.InvestmentFilter{})
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+	byPortfolio, err := s.GetPortfolioValues(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "get portfolio values", "")
+		return
+	}
+
+	accounts, err := s.GetAllAccounts(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "get accounts", "")
+		return
+	}
 
+	models.FlagUnconverted(investments)
+	models.SetUnrealizedPLPercent(investments)
+	models.LabelPortfolios(investments, portfolios, accounts)
 	c.JSON(http.StatusOK, gin.H{
 		"networth":   networth,
 		"portfolios": portfolios,
 		"investments": investments,
+		"by_portfolio": byPortfolio,
 	})
 }
 
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// parseListOptions reads ?limit= and ?offset= from the query string. The limit defaults
+// to defaultListLimit and is capped at maxListLimit.
+func parseListOptions(c *gin.Context) (store.ListOptions, error) {
+	opts := store.ListOptions{Limit: defaultListLimit}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("invalid limit parameter")
+		}
+		if limit > maxListLimit {
+			limit = maxListLimit
+		}
+		opts.Limit = limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("invalid offset parameter")
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
+}
+
+// parseSortOption reads ?sort= and ?order= from the query string. The sort field must be one
+// of allowed; order is asc (the default) or desc. Without ?sort= the listing keeps its
+// default order.
+func parseSortOption(c *gin.Context, allowed []string) (store.SortOption, error) {
+	var opt store.SortOption
+
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		opt.Desc = true
+	default:
+		return opt, fmt.Errorf("invalid order %q; must be asc or desc", order)
+	}
+
+	field := c.Query("sort")
+	if field == "" {
+		return store.SortOption{}, nil
+	}
+	if !slices.Contains(allowed, field) {
+		return opt, fmt.Errorf("invalid sort field %q; allowed fields: %s", field, strings.Join(allowed, ", "))
+	}
+	opt.Field = field
+	return opt, nil
+}
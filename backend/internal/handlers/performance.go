@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/currency"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCostBasisMethod reads COST_BASIS_METHOD once, falling back to FIFO when it is unset
+// or invalid
+var defaultCostBasisMethod = sync.OnceValue(func() models.CostBasisMethod {
+	value := os.Getenv("COST_BASIS_METHOD")
+	if value == "" {
+		return models.CostBasisFIFO
+	}
+	method, err := models.ParseCostBasisMethod(value)
+	if err != nil {
+		log.Printf("Warning: %v, using %s", err, models.CostBasisFIFO)
+		return models.CostBasisFIFO
+	}
+	return method
+})
+
+// computeCostBasis walks every stored transaction of the user with method. Units deposited or
+// transferred in without a USD amount are valued at the symbol's recorded price on that date.
+func computeCostBasis(ctx context.Context, s store.Store, method models.CostBasisMethod) (map[string]*models.SymbolCostBasis, error) {
+	transactions, err := s.GetTransactionsBetween(ctx, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return models.ComputeCostBasis(transactions, method, historyPriceAt(ctx, s)), nil
+}
+
+// historyPriceAt returns a lookup of the latest USD price recorded in the investment history
+// at or before a time. Each symbol's history is read once; a symbol whose history cannot be
+// read has no prices, leaving its units without a known cost.
+func historyPriceAt(ctx context.Context, s store.Store) func(symbol string, at time.Time) (float64, bool) {
+	histories := make(map[string][]*models.InvestmentHistoryPoint)
+	return func(symbol string, at time.Time) (float64, bool) {
+		symbol = strings.ToUpper(symbol)
+		history, loaded := histories[symbol]
+		if !loaded {
+			var err error
+			history, err = s.GetInvestmentHistory(ctx, symbol, time.Time{}, time.Time{})
+			if err != nil {
+				log.Printf("Failed to read %s history for cost basis: %v", symbol, err)
+			}
+			histories[symbol] = history
+		}
+		// History is oldest first; find the last point not after at
+		i := sort.Search(len(history), func(i int) bool { return history[i].Timestamp.After(at) })
+		for ; i > 0; i-- {
+			point := history[i-1]
+			if point.Price > 0 && strings.EqualFold(point.Currency, currency.USD) {
+				return point.Price, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// applyCostBasis sets the cost basis and unrealized gain of synced investments from the stored
+// transactions. Cost basis is derived data, so failing to compute it is logged and the
+// investments keep their stored values.
+func applyCostBasis(ctx context.Context, s store.Store, investments []*models.Investment) {
+	costs, err := computeCostBasis(ctx, s, defaultCostBasisMethod())
+	if err != nil {
+		log.Printf("Failed to compute cost basis of synced investments: %v", err)
+		return
+	}
+	models.ApplyCostBasis(investments, costs)
+}
+
+// GetPerformance handles GET /api/investments/performance
+// Returns the realized and unrealized profit of each symbol and in total, from the active
+// investments and the cost of their units in the stored transactions. Optional query parameter:
+// method (fifo or average), defaulting to COST_BASIS_METHOD.
+func (h *InvestmentsHandler) GetPerformance(c *gin.Context) {
+	method := defaultCostBasisMethod()
+	if value := c.Query("method"); value != "" {
+		var err error
+		if method, err = models.ParseCostBasisMethod(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	scoped := userStore(c, h.store)
+	investments, _, err := scoped.GetAllInvestments(c.Request.Context(), store.ListOptions{}, store.SortOption{}, store.InvestmentFilter{})
+	if err != nil {
+		respondStoreError(c, err, "get investments", "")
+		return
+	}
+	costs, err := computeCostBasis(c.Request.Context(), scoped, method)
+	if err != nil {
+		respondStoreError(c, err, "get transactions", "")
+		return
+	}
+	c.JSON(http.StatusOK, models.NewPerformanceSummary(investments, costs, method))
+}
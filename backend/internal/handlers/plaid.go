@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"0xnetworth/backend/internal/auth"
+	"0xnetworth/backend/internal/integrations/plaid"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// What unlinking a Plaid item does with the data its syncs stored, as given by DELETE
+// /api/plaid/items/:id?data=
+const (
+	plaidItemDataKeep       = "keep"
+	plaidItemDataDeactivate = "deactivate"
+	plaidItemDataDelete     = "delete"
+)
+
+// maxWebhookBodySize bounds the body of a Plaid webhook, which is read whole to verify it
+const maxWebhookBodySize = 1 << 20
+
+// PlaidAPI is everything the handlers call Plaid for: linking and unlinking items, reading
+// their accounts, holdings and investment transactions, and verifying the webhooks Plaid sends
+// about them. *plaid.Client implements it; *plaidtest.Fake stands in for it to exercise the
+// Plaid flows without the network.
+type PlaidAPI interface {
+	plaid.ItemReader
+	CreateLinkToken(ctx context.Context, userID string) (*plaid.LinkToken, error)
+	ExchangePublicToken(ctx context.Context, publicToken string) (*plaid.Exchange, error)
+	GetInstitution(ctx context.Context, accessToken string) (*plaid.Institution, error)
+	RemoveItem(ctx context.Context, accessToken string) error
+	VerifyWebhook(ctx context.Context, body []byte, verification string) error
+}
+
+var _ PlaidAPI = (*plaid.Client)(nil)
+
+// PlaidItemRefresher queues a sync of one linked item, returning false if it cannot take more.
+// *PlaidRefresher implements it.
+type PlaidItemRefresher interface {
+	Enqueue(userID, itemID string) bool
+}
+
+var _ PlaidItemRefresher = (*PlaidRefresher)(nil)
+
+// PlaidHandler handles linking Plaid items and Plaid's webhooks about them
+type PlaidHandler struct {
+	store     store.Store
+	client    PlaidAPI
+	tokens    *plaid.TokenCipher
+	refresher PlaidItemRefresher
+}
+
+// NewPlaidHandler creates a new Plaid handler. client is nil when Plaid is not configured,
+// which makes its endpoints respond 503; tokens encrypts the access tokens stored, and
+// refresher syncs the items webhooks report changes of.
+func NewPlaidHandler(store store.Store, client PlaidAPI, tokens *plaid.TokenCipher, refresher PlaidItemRefresher) *PlaidHandler {
+	return &PlaidHandler{
+		store:     store,
+		client:    client,
+		tokens:    tokens,
+		refresher: refresher,
+	}
+}
+
+// configured writes an error response and returns false if Plaid is not configured
+func (h *PlaidHandler) configured(c *gin.Context) bool {
+	if h.client == nil || h.tokens == nil || h.refresher == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Plaid client not configured",
+		})
+		return false
+	}
+	return true
+}
+
+// CreateLinkToken handles POST /api/plaid/link-token
+// Returns a token for opening Plaid Link in the frontend
+func (h *PlaidHandler) CreateLinkToken(c *gin.Context) {
+	if !h.configured(c) {
+		return
+	}
+	linkToken, err := h.client.CreateLinkToken(c.Request.Context(), auth.UserID(c))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, linkToken)
+}
+
+// plaidExchangeRequest is the body of POST /api/plaid/exchange, the public token and
+// institution Plaid Link's onSuccess callback receives
+type plaidExchangeRequest struct {
+	PublicToken     string          `json:"public_token" binding:"required"`
+	InstitutionID   string          `json:"institution_id"`
+	InstitutionName string          `json:"institution_name"`
+	Platform        models.Platform `json:"platform"`
+}
+
+// ExchangePublicToken handles POST /api/plaid/exchange
+// Exchanges a Plaid Link public token and stores the item with its access token encrypted.
+// The access token is never part of the response.
+func (h *PlaidHandler) ExchangePublicToken(c *gin.Context) {
+	if !h.configured(c) {
+		return
+	}
+	var req plaidExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "public_token is required",
+		})
+		return
+	}
+	if req.Platform == "" {
+		req.Platform = models.PlatformM1Finance
+	}
+	if req.Platform != models.PlatformM1Finance {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid platform. Plaid items can only be linked for 'm1_finance'",
+		})
+		return
+	}
+
+	item, ok := h.linkItem(c, req.PublicToken, req.InstitutionID, req.InstitutionName, req.Platform)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// linkItem exchanges publicToken and stores the item of the requesting user with its access
+// token encrypted, looking up its institution if institutionName is empty. It returns the
+// stored item, or writes an error response and returns false.
+func (h *PlaidHandler) linkItem(c *gin.Context, publicToken, institutionID, institutionName string, platform models.Platform) (*models.PlaidItem, bool) {
+	ctx := c.Request.Context()
+	exchange, err := h.client.ExchangePublicToken(ctx, publicToken)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	sealed, err := h.tokens.Seal(exchange.ItemID, exchange.AccessToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to encrypt access token: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	item := &models.PlaidItem{
+		ID:                   exchange.ItemID,
+		InstitutionID:        institutionID,
+		InstitutionName:      institutionName,
+		Platform:             platform,
+		EncryptedAccessToken: sealed,
+	}
+	if item.InstitutionName == "" {
+		// The item is usable without a name, so a failed lookup only leaves it unnamed
+		if institution, err := h.client.GetInstitution(ctx, exchange.AccessToken); err != nil {
+			log.Printf("Warning: Failed to look up the institution of Plaid item %s: %v", item.ID, err)
+		} else {
+			item.InstitutionID = institution.ID
+			item.InstitutionName = institution.Name
+		}
+	}
+
+	scoped := userStore(c, h.store)
+	if err := scoped.CreateOrUpdatePlaidItem(ctx, item); err != nil {
+		respondStoreError(c, err, "store Plaid item", "")
+		return nil, false
+	}
+	log.Printf("Linked Plaid item %s (%s)", item.ID, item.InstitutionName)
+	// Read back for the timestamps the store assigned
+	stored, err := scoped.GetPlaidItem(ctx, item.ID)
+	if err != nil {
+		respondStoreError(c, err, "get Plaid item", "")
+		return nil, false
+	}
+	return stored, true
+}
+
+// GetItems handles GET /api/plaid/items
+// Returns the user's linked Plaid items with their status, last sync and number of accounts,
+// without their access tokens
+func (h *PlaidHandler) GetItems(c *gin.Context) {
+	items, err := userStore(c, h.store).GetPlaidItems(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "get Plaid items", "")
+		return
+	}
+	for _, item := range items {
+		item.AccountCount = len(item.AccountIDs)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+	})
+}
+
+// DeleteItem handles DELETE /api/plaid/items/:id
+// Unlinks a Plaid item: deletes it with its access token, then removes it at Plaid. The data
+// its syncs stored is kept unless ?data=deactivate marks its accounts and holdings inactive or
+// ?data=delete deletes them with their portfolios and transactions. Responds 204, also for an
+// item that is already unlinked.
+func (h *PlaidHandler) DeleteItem(c *gin.Context) {
+	if !h.configured(c) {
+		return
+	}
+	data := c.DefaultQuery("data", plaidItemDataKeep)
+	if data != plaidItemDataKeep && data != plaidItemDataDeactivate && data != plaidItemDataDelete {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid data. Must be 'keep', 'deactivate' or 'delete'",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	scoped := userStore(c, h.store)
+	item, err := scoped.GetPlaidItem(ctx, c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondStoreError(c, err, "get Plaid item", "")
+		return
+	}
+	accessToken, err := h.tokens.Open(item.ID, item.EncryptedAccessToken)
+	if err != nil {
+		// Without its token the item cannot be synced either, so it is still unlinked here
+		log.Printf("Warning: Cannot decrypt the access token of Plaid item %s, so it is only unlinked here and must be removed in the Plaid dashboard: %v", item.ID, err)
+	}
+	accountIDs := item.AccountIDs
+	if data != plaidItemDataKeep && len(accountIDs) == 0 && accessToken != "" {
+		// An item not synced since its accounts were recorded; Plaid still knows them
+		if accounts, err := h.client.GetAccounts(ctx, accessToken); err != nil {
+			log.Printf("Warning: Failed to get the accounts of Plaid item %s, leaving their data: %v", item.ID, err)
+		} else {
+			for _, account := range accounts {
+				accountIDs = append(accountIDs, account.AccountID)
+			}
+		}
+	}
+
+	// The item and its token are deleted before Plaid is asked to remove it, so a failure
+	// between the two cannot leave the token of a removed item stored. Once under way the
+	// unlinking is finished even if the client goes away.
+	writeCtx := context.WithoutCancel(ctx)
+	err = scoped.WithTransaction(writeCtx, func(tx store.Store) error {
+		if err := removeItemData(writeCtx, tx, item.Platform, accountIDs, data); err != nil {
+			return err
+		}
+		return tx.DeletePlaidItem(writeCtx, item.ID)
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		// Unlinked by a concurrent request
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		respondStoreError(c, err, "delete Plaid item", "")
+		return
+	}
+
+	if accessToken != "" {
+		if err := h.client.RemoveItem(writeCtx, accessToken); err != nil && !plaid.IsItemGone(err) {
+			// Put the item back so unlinking can be retried; its data stays as unlinking left it
+			if restoreErr := restorePlaidItem(writeCtx, scoped, item); restoreErr != nil {
+				log.Printf("Error: Plaid item %s was unlinked but not removed at Plaid, and could not be restored to retry: %v", item.ID, restoreErr)
+			}
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	log.Printf("Unlinked Plaid item %s (%s), data: %s", item.ID, item.InstitutionName, data)
+	c.Status(http.StatusNoContent)
+}
+
+// removeItemData deactivates or deletes the data stored for the accounts of an unlinked Plaid
+// item on platform, as data says, and updates net worth to leave them out. Accounts on other
+// platforms are left alone.
+func removeItemData(ctx context.Context, tx store.Store, platform models.Platform, accountIDs []string, data string) error {
+	if data == plaidItemDataKeep || len(accountIDs) == 0 {
+		return nil
+	}
+	now := models.Now()
+	for _, accountID := range accountIDs {
+		account, err := tx.GetAccountByID(ctx, accountID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if account.Platform != platform {
+			continue
+		}
+		investments, err := tx.GetInvestmentsByAccount(ctx, accountID, store.InvestmentFilter{IncludeInactive: data == plaidItemDataDelete})
+		if err != nil {
+			return err
+		}
+
+		if data == plaidItemDataDeactivate {
+			ids := make([]string, 0, len(investments))
+			for _, investment := range investments {
+				ids = append(ids, investment.ID)
+			}
+			if _, err := tx.DeactivateInvestments(ctx, ids, now); err != nil {
+				return err
+			}
+			account.Active = false
+			if _, err := tx.CreateOrUpdateAccount(ctx, account); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, investment := range investments {
+			if err := tx.DeleteInvestment(ctx, investment.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+		if err := tx.DeleteAccount(ctx, accountID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		// Each Plaid account has a portfolio of the same ID, whose deletion takes its
+		// transactions with it
+		if err := tx.DeletePortfolio(ctx, accountID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	networth, err := tx.RecalculateNetWorth(ctx)
+	if err != nil {
+		return err
+	}
+	return tx.SaveNetWorthSnapshot(ctx, networth)
+}
+
+// restorePlaidItem stores an unlinked item again, with the accounts, status and last sync it had
+func restorePlaidItem(ctx context.Context, s store.Store, item *models.PlaidItem) error {
+	if err := s.CreateOrUpdatePlaidItem(ctx, item); err != nil {
+		return err
+	}
+	if err := s.SetPlaidItemAccounts(ctx, item.ID, item.AccountIDs); err != nil {
+		return err
+	}
+	status := item.Status
+	if status == "" {
+		status = models.PlaidItemStatusHealthy
+	}
+	return s.SetPlaidItemStatus(ctx, item.ID, status, item.LastSyncedAt)
+}
+
+// Webhook handles POST /api/plaid/webhook
+// Receives Plaid's webhooks, which carry no API token; each is verified by its signed
+// Plaid-Verification header instead. One reporting new holdings or investment transactions of
+// a linked item queues a sync of just that item. Plaid retries a webhook until it is answered
+// with 200, so one about an unknown item or of another type is logged and acknowledged.
+func (h *PlaidHandler) Webhook(c *gin.Context) {
+	if !h.configured(c) {
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "failed to read webhook: " + err.Error(),
+		})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.client.VerifyWebhook(ctx, body, c.GetHeader("Plaid-Verification")); err != nil {
+		log.Printf("Rejected Plaid webhook: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "webhook verification failed",
+		})
+		return
+	}
+
+	var webhook plaid.Webhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		log.Printf("Ignoring Plaid webhook that is not valid JSON: %v", err)
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+	if webhook.Error != nil {
+		log.Printf("Plaid webhook %s/%s for item %s reports an error: %s/%s",
+			webhook.WebhookType, webhook.WebhookCode, webhook.ItemID, webhook.Error.ErrorType, webhook.Error.ErrorCode)
+	}
+	if !webhook.RefreshesInvestments() {
+		log.Printf("Ignoring Plaid webhook %s/%s for item %s", webhook.WebhookType, webhook.WebhookCode, webhook.ItemID)
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	userID, err := h.store.GetPlaidItemUserID(ctx, webhook.ItemID)
+	if errors.Is(err, store.ErrNotFound) {
+		log.Printf("Ignoring Plaid webhook %s/%s for unknown item %s", webhook.WebhookType, webhook.WebhookCode, webhook.ItemID)
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+	if err != nil {
+		// Plaid retries the webhook, so the item is synced once the store is back
+		log.Printf("Failed to look up Plaid item %s for webhook %s/%s: %v", webhook.ItemID, webhook.WebhookType, webhook.WebhookCode, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "failed to look up item",
+		})
+		return
+	}
+	if !h.refresher.Enqueue(userID, webhook.ItemID) {
+		log.Printf("Plaid refresh queue is full, leaving webhook %s/%s for item %s to be retried", webhook.WebhookType, webhook.WebhookCode, webhook.ItemID)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "refresh queue is full",
+		})
+		return
+	}
+	log.Printf("Queued a refresh of Plaid item %s for webhook %s/%s", webhook.ItemID, webhook.WebhookType, webhook.WebhookCode)
+	c.JSON(http.StatusOK, gin.H{"status": "queued"})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// plaidRefreshQueueSize is how many item syncs can wait for the refresher before webhooks are
+// turned away to be retried
+const plaidRefreshQueueSize = 64
+
+// plaidRefreshTimeout bounds the sync of one item, which unlike a requested sync has no client
+// to give up on it
+const plaidRefreshTimeout = 2 * time.Minute
+
+// plaidRefresh is a queued sync of one user's Plaid item
+type plaidRefresh struct {
+	userID string
+	itemID string
+}
+
+// PlaidRefresher syncs single Plaid items in the background, one at a time, as webhooks report
+// that their holdings or investment transactions changed
+type PlaidRefresher struct {
+	sync  *SyncHandler
+	queue chan plaidRefresh
+	// pending holds the queued items, so a burst of webhooks about one item syncs it once
+	mu       sync.Mutex
+	pending  map[plaidRefresh]bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPlaidRefresher creates a refresher that syncs and stores items as sync does for a
+// requested sync
+func NewPlaidRefresher(sync *SyncHandler) *PlaidRefresher {
+	return &PlaidRefresher{
+		sync:    sync,
+		queue:   make(chan plaidRefresh, plaidRefreshQueueSize),
+		pending: make(map[plaidRefresh]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Enqueue queues a sync of the item itemID of userID, returning false if the queue is full. An
+// item already queued is not queued again.
+func (r *PlaidRefresher) Enqueue(userID, itemID string) bool {
+	job := plaidRefresh{userID: userID, itemID: itemID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[job] {
+		return true
+	}
+	select {
+	case r.queue <- job:
+		r.pending[job] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Start syncs queued items until Stop is called. Without a Plaid syncer nothing is synced.
+func (r *PlaidRefresher) Start() {
+	if r.sync.plaidSyncer == nil {
+		close(r.done)
+		return
+	}
+	go func() {
+		defer close(r.done)
+		for {
+			select {
+			case job := <-r.queue:
+				r.mu.Lock()
+				delete(r.pending, job)
+				r.mu.Unlock()
+				r.refresh(job)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the refresher, waiting for a sync in progress to finish. Queued items are dropped;
+// the next sync of each user fetches their changes.
+func (r *PlaidRefresher) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+}
+
+// refresh syncs and stores one item. A failure is only logged: the other items of the user
+// may be fine, so it is not recorded as a failed sync of the platform, and the user's next
+// sync reports it.
+func (r *PlaidRefresher) refresh(job plaidRefresh) {
+	ctx, cancel := context.WithTimeout(context.Background(), plaidRefreshTimeout)
+	defer cancel()
+	h := r.sync
+	scoped := h.store.ForUser(job.userID)
+	item, err := scoped.GetPlaidItem(ctx, job.itemID)
+	if err != nil {
+		log.Printf("Skipping the refresh of Plaid item %s: %v", job.itemID, err)
+		return
+	}
+
+	start := time.Now()
+	result, err := h.syncPlaidItems(ctx, scoped, []*models.PlaidItem{item})
+	if err != nil {
+		reason := err.Error()
+		// A failed item leaves its reason in the report rather than the error
+		if result != nil && len(result.Report.Items) > 0 {
+			reason = result.Report.Items[0].Error
+		}
+		log.Printf("Error refreshing Plaid item %s (%s): %s", item.ID, item.InstitutionName, reason)
+		return
+	}
+	// The write is not cut short by the timeout, as with a requested sync
+	if _, _, err := saveSyncResults(context.WithoutCancel(ctx), scoped, result, models.Now()); err != nil {
+		log.Printf("Error storing the refresh of Plaid item %s: %v", item.ID, err)
+		return
+	}
+	for _, fn := range h.onSynced {
+		fn()
+	}
+	log.Printf("Refreshed Plaid item %s (%s) in %s: %d accounts, %d investments, %d transactions",
+		item.ID, item.InstitutionName, time.Since(start).Round(time.Millisecond),
+		len(result.Accounts), len(result.Investments), len(result.Transactions))
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"0xnetworth/backend/internal/integrations/plaid"
+	"0xnetworth/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaidSandboxAPI is the test shortcuts of Plaid's sandbox. *plaid.Client implements it, and
+// reports through Sandbox whether it calls the sandbox at all.
+type PlaidSandboxAPI interface {
+	Sandbox() bool
+	SandboxCreatePublicToken(ctx context.Context, institutionID string) (string, error)
+	SandboxFireWebhook(ctx context.Context, accessToken, webhookType, webhookCode string) error
+}
+
+var _ PlaidSandboxAPI = (*plaid.Client)(nil)
+
+// PlaidSandboxHandler handles the sandbox helpers for developing the Plaid flows without real
+// credentials: creating an item without Plaid Link, and having Plaid fire a webhook about one
+type PlaidSandboxHandler struct {
+	client PlaidSandboxAPI
+	plaid  *PlaidHandler
+	sync   *SyncHandler
+}
+
+// RegisterPlaidSandboxRoutes registers the sandbox helpers on api if PLAID_ENV is set to
+// sandbox and client calls Plaid's sandbox, and reports whether it did. Otherwise the routes do
+// not exist, so they respond 404. The client defaults to the sandbox when PLAID_ENV is unset,
+// but the helpers, which link items without Plaid Link, are only enabled by asking for them.
+func RegisterPlaidSandboxRoutes(api gin.IRoutes, client PlaidSandboxAPI, plaidHandler *PlaidHandler, syncHandler *SyncHandler) bool {
+	if !strings.EqualFold(os.Getenv("PLAID_ENV"), "sandbox") || client == nil || !client.Sandbox() {
+		return false
+	}
+	h := &PlaidSandboxHandler{
+		client: client,
+		plaid:  plaidHandler,
+		sync:   syncHandler,
+	}
+	api.POST("/plaid/sandbox/create-item", h.CreateItem)
+	api.POST("/plaid/sandbox/items/:id/fire-webhook", h.FireWebhook)
+	return true
+}
+
+// plaidSandboxItemRequest is the optional body of POST /api/plaid/sandbox/create-item
+type plaidSandboxItemRequest struct {
+	InstitutionID string `json:"institution_id"`
+}
+
+// CreateItem handles POST /api/plaid/sandbox/create-item
+// Creates an item at a sandbox institution for the investments product, links it as Plaid
+// Link and POST /api/plaid/exchange would, and syncs it, responding as
+// POST /api/plaid/items/:id/sync does. An item whose sync fails stays linked.
+func (h *PlaidSandboxHandler) CreateItem(c *gin.Context) {
+	if !h.plaid.configured(c) {
+		return
+	}
+	var req plaidSandboxItemRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+	if req.InstitutionID == "" {
+		req.InstitutionID = plaid.DefaultSandboxInstitution
+	}
+
+	publicToken, err := h.client.SandboxCreatePublicToken(c.Request.Context(), req.InstitutionID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	item, ok := h.plaid.linkItem(c, publicToken, "", "", models.PlatformM1Finance)
+	if !ok {
+		return
+	}
+	log.Printf("Created sandbox Plaid item %s at %s", item.ID, req.InstitutionID)
+	h.sync.syncAndSavePlaidItems(c, syncContext(c), userStore(c, h.sync.store), item.Platform, []*models.PlaidItem{item}, false)
+}
+
+// plaidSandboxWebhookRequest is the optional body of
+// POST /api/plaid/sandbox/items/:id/fire-webhook
+type plaidSandboxWebhookRequest struct {
+	WebhookType string `json:"webhook_type"`
+	WebhookCode string `json:"webhook_code"`
+}
+
+// FireWebhook handles POST /api/plaid/sandbox/items/:id/fire-webhook
+// Has Plaid send a webhook about one of the requesting user's sandbox items, HOLDINGS
+// DEFAULT_UPDATE unless the body names another, to the webhook URL the item was created with
+func (h *PlaidSandboxHandler) FireWebhook(c *gin.Context) {
+	if !h.plaid.configured(c) {
+		return
+	}
+	req := plaidSandboxWebhookRequest{
+		WebhookType: plaid.WebhookTypeHoldings,
+		WebhookCode: plaid.WebhookCodeDefaultUpdate,
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	item, err := userStore(c, h.plaid.store).GetPlaidItem(ctx, c.Param("id"))
+	if err != nil {
+		respondStoreError(c, err, "get Plaid item", "Plaid item not found")
+		return
+	}
+	accessToken, err := h.plaid.tokens.Open(item.ID, item.EncryptedAccessToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to decrypt access token: " + err.Error(),
+		})
+		return
+	}
+	if err := h.client.SandboxFireWebhook(ctx, accessToken, req.WebhookType, req.WebhookCode); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	log.Printf("Fired sandbox Plaid webhook %s/%s for item %s", req.WebhookType, req.WebhookCode, item.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"webhook_fired": true,
+		"webhook_type":  req.WebhookType,
+		"webhook_code":  req.WebhookCode,
+	})
+}
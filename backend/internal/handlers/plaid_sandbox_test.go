@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"0xnetworth/backend/internal/integrations/plaid"
+	"0xnetworth/backend/internal/integrations/plaid/plaidtest"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+// plaidSandboxTargets are the sandbox helpers' routes
+var plaidSandboxTargets = []string{"/api/plaid/sandbox/create-item", "/api/plaid/sandbox/items/item-m1/fire-webhook"}
+
+func TestPlaidSandboxRoutesOnlyInSandbox(t *testing.T) {
+	t.Setenv("PLAID_ENV", "sandbox")
+	e := newPlaidTestEnv(t, plaidtest.NewFake())
+	for _, target := range plaidSandboxTargets {
+		if rec := doRequest(t, e.router, http.MethodPost, target, e.token, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s outside the sandbox = %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestPlaidSandboxRoutesNeedPlaidEnv(t *testing.T) {
+	// The client calls the sandbox, as it does by default when PLAID_ENV is unset
+	t.Setenv("PLAID_ENV", "")
+	os.Unsetenv("PLAID_ENV")
+	e := newPlaidTestEnv(t, plaidtest.NewSandboxFake())
+	for _, target := range plaidSandboxTargets {
+		if rec := doRequest(t, e.router, http.MethodPost, target, e.token, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s with PLAID_ENV unset = %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestPlaidSandboxCreateItem(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("PLAID_ENV", "sandbox")
+	fake := plaidtest.NewSandboxFake()
+	fake.AddSandboxInstitution(plaid.DefaultSandboxInstitution, m1Item())
+	e := newPlaidTestEnv(t, fake)
+
+	rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/sandbox/create-item", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create-item status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	scoped := e.store.ForUser("alice")
+	items, err := scoped.GetPlaidItems(ctx)
+	if err != nil || len(items) != 1 || items[0].ID != "item-m1" || items[0].InstitutionName != "M1 Finance" {
+		t.Fatalf("linked items = %+v (%v), want the sandbox item", items, err)
+	}
+	investments, err := scoped.GetInvestmentsByPlatform(ctx, models.PlatformM1Finance, store.InvestmentFilter{})
+	if err != nil || len(investments) != 1 {
+		t.Fatalf("synced investments = %d (%v), want 1", len(investments), err)
+	}
+
+	rec = doRequest(t, e.router, http.MethodPost, "/api/plaid/sandbox/create-item", e.token, `{"institution_id":"ins_unknown"}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("create-item at an unknown institution = %d, want 502: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPlaidSandboxFireWebhook(t *testing.T) {
+	t.Setenv("PLAID_ENV", "sandbox")
+	fake := plaidtest.NewSandboxFake()
+	fake.AddSandboxInstitution(plaid.DefaultSandboxInstitution, m1Item())
+	e := newPlaidTestEnv(t, fake)
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/sandbox/create-item", e.token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("create-item status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/sandbox/items/item-m1/fire-webhook", e.token, `{"webhook_type":"INVESTMENTS_TRANSACTIONS"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("fire-webhook status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	want := plaidtest.FiredWebhook{ItemID: "item-m1", WebhookType: "INVESTMENTS_TRANSACTIONS", WebhookCode: plaid.WebhookCodeDefaultUpdate}
+	if fired := fake.FiredWebhooks(); len(fired) != 1 || fired[0] != want {
+		t.Fatalf("fired webhooks = %+v, want %+v", fired, want)
+	}
+
+	bob := addTestUser(t, e.store, "bob")
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/sandbox/items/item-m1/fire-webhook", bob, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("fire-webhook for another user's item = %d, want 404: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"0xnetworth/backend/internal/integrations/plaid"
+	"0xnetworth/backend/internal/integrations/plaid/plaidtest"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// plaidTestEnv serves the Plaid and sync routes against a fake Plaid
+type plaidTestEnv struct {
+	store  store.Store
+	fake   *plaidtest.Fake
+	router *gin.Engine
+	token  string
+}
+
+// newPlaidTestEnv serves the routes against fake, along with the sandbox helpers if it is a
+// sandbox fake
+func newPlaidTestEnv(t *testing.T, fake *plaidtest.Fake) *plaidTestEnv {
+	t.Helper()
+	tokens, err := plaid.NewTokenCipher(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewStore()
+	syncHandler := NewSyncHandler(s, nil, fake, tokens)
+	plaidHandler := NewPlaidHandler(s, fake, tokens, NewPlaidRefresher(syncHandler))
+	router := newTestRouter(s)
+	router.POST("/api/plaid/exchange", plaidHandler.ExchangePublicToken)
+	router.GET("/api/plaid/items", plaidHandler.GetItems)
+	router.POST("/api/plaid/items/:id/sync", syncHandler.SyncPlaidItem)
+	router.POST("/api/sync/:platform", syncHandler.SyncPlatform)
+	RegisterPlaidSandboxRoutes(router.Group("/api"), fake, plaidHandler, syncHandler)
+	return &plaidTestEnv{store: s, fake: fake, router: router, token: addTestUser(t, s, "alice")}
+}
+
+// m1Item returns an M1 item with one brokerage account holding 10 VTI
+func m1Item() *plaidtest.Item {
+	balance := 2500.0
+	return &plaidtest.Item{
+		ItemID:      "item-m1",
+		Institution: plaid.Institution{ID: "ins_m1", Name: "M1 Finance"},
+		Accounts: []plaid.Account{{
+			AccountID: "acc-brokerage", Name: "Brokerage", Type: "investment", Subtype: "brokerage",
+			Balances: plaid.Balances{Current: &balance, IsoCurrencyCode: "USD"},
+		}},
+		Holdings: []plaid.Holding{{
+			AccountID: "acc-brokerage", SecurityID: "sec-vti", Quantity: 10,
+			InstitutionPrice: 250, InstitutionValue: 2500, IsoCurrencyCode: "USD",
+			Security: &plaid.Security{SecurityID: "sec-vti", Name: "Vanguard Total Stock Market ETF", TickerSymbol: "VTI", Type: "etf"},
+		}},
+	}
+}
+
+// linkM1Item links the item of m1Item
+func (e *plaidTestEnv) linkM1Item(t *testing.T) {
+	t.Helper()
+	e.fake.AddItem("public-m1", m1Item())
+	rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/exchange", e.token, `{"public_token":"public-m1"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("exchange status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPlaidLinkAndSync(t *testing.T) {
+	ctx := context.Background()
+	e := newPlaidTestEnv(t, plaidtest.NewFake())
+	e.linkM1Item(t)
+
+	rec := doRequest(t, e.router, http.MethodGet, "/api/plaid/items", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("items status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"M1 Finance"`) || strings.Contains(body, "access-fake") {
+		t.Fatalf("items = %s, want the M1 item named and no access token", body)
+	}
+
+	rec = doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sync status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	investments, err := e.store.ForUser("alice").GetInvestmentsByPlatform(ctx, models.PlatformM1Finance, store.InvestmentFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(investments) != 1 || investments[0].Symbol != "VTI" || investments[0].Value != 2500 {
+		t.Fatalf("synced investments = %+v, want 10 VTI worth 2500", investments)
+	}
+	item, err := e.store.ForUser("alice").GetPlaidItem(ctx, "item-m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(item.AccountIDs) != 1 || item.AccountIDs[0] != "acc-brokerage" {
+		t.Fatalf("item accounts = %v, want the synced brokerage account", item.AccountIDs)
+	}
+}
+
+func TestPlaidSyncItemLoginRequired(t *testing.T) {
+	e := newPlaidTestEnv(t, plaidtest.NewFake())
+	e.linkM1Item(t)
+	e.fake.SetItemError("item-m1", plaidtest.ItemLoginRequired())
+
+	rec := doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("sync status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Error  string            `json:"error"`
+		Report models.SyncReport `json:"report"`
+	}
+	decodeJSON(t, rec, &body)
+	if !strings.Contains(body.Error, "re-link") {
+		t.Errorf("error = %q, want it to tell the user to re-link", body.Error)
+	}
+	if len(body.Report.Items) != 1 || body.Report.Items[0].ErrorCode != plaid.ErrorCodeItemLoginRequired {
+		t.Errorf("report items = %+v, want the item failing with %s", body.Report.Items, plaid.ErrorCodeItemLoginRequired)
+	}
+}
+
+// listedItems returns the items GET /api/plaid/items lists, by ID
+func (e *plaidTestEnv) listedItems(t *testing.T) map[string]models.PlaidItem {
+	t.Helper()
+	rec := doRequest(t, e.router, http.MethodGet, "/api/plaid/items", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("items status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Items []models.PlaidItem `json:"items"`
+	}
+	decodeJSON(t, rec, &body)
+	items := make(map[string]models.PlaidItem, len(body.Items))
+	for _, item := range body.Items {
+		items[item.ID] = item
+	}
+	return items
+}
+
+func TestPlaidSyncSkipsItemsNeedingLogin(t *testing.T) {
+	e := newPlaidTestEnv(t, plaidtest.NewFake())
+	e.linkM1Item(t)
+	ira := m1Item()
+	ira.ItemID = "item-ira"
+	ira.Accounts[0].AccountID = "acc-ira"
+	ira.Holdings[0].AccountID = "acc-ira"
+	e.fake.AddItem("public-ira", ira)
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/exchange", e.token, `{"public_token":"public-ira"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("exchange status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	items := e.listedItems(t)
+	if item := items["item-m1"]; item.Status != models.PlaidItemStatusHealthy || item.LastSyncedAt != nil || item.AccountCount != 0 {
+		t.Fatalf("new item = %+v, want it healthy and never synced", item)
+	}
+
+	// The first sync finds that the M1 item needs the user to log in again
+	e.fake.SetItemError("item-m1", plaidtest.ItemLoginRequired())
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("sync status = %d, want 200 with the IRA synced: %s", rec.Code, rec.Body)
+	}
+	items = e.listedItems(t)
+	if item := items["item-m1"]; item.Status != models.PlaidItemStatusLoginRequired || item.LastSyncedAt != nil {
+		t.Fatalf("failed item = %+v, want it login_required and never synced", item)
+	}
+	if item := items["item-ira"]; item.Status != models.PlaidItemStatusHealthy || item.LastSyncedAt == nil || item.AccountCount != 1 {
+		t.Fatalf("synced item = %+v, want it healthy with its last sync and one account", item)
+	}
+
+	// Later syncs leave it alone, even once Plaid would answer again, until it is re-linked
+	e.fake.SetItemError("item-m1", nil)
+	calls := e.fake.Calls("item-m1")
+	rec := doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("second sync status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got := e.fake.Calls("item-m1"); got != calls {
+		t.Fatalf("second sync made %d Plaid calls for the item needing login, want none", got-calls)
+	}
+	var body struct {
+		Message string            `json:"message"`
+		Report  models.SyncReport `json:"report"`
+	}
+	decodeJSON(t, rec, &body)
+	var skipped []string
+	for _, item := range body.Report.Items {
+		if item.Status == models.SyncStatusSkipped {
+			skipped = append(skipped, item.ID)
+		}
+	}
+	if len(body.Report.Items) != 2 || len(skipped) != 1 || skipped[0] != "item-m1" {
+		t.Fatalf("report items = %+v, want item-m1 skipped and the IRA synced", body.Report.Items)
+	}
+	if !strings.Contains(body.Message, "skipped") {
+		t.Errorf("message = %q, want it to mention the skipped item", body.Message)
+	}
+
+	// With only the item needing login left, the sync conflicts without calling Plaid
+	e.fake.SetItemError("item-ira", plaidtest.ItemLoginRequired())
+	doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, "")
+	calls = e.fake.Calls("item-m1") + e.fake.Calls("item-ira")
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("sync of items all needing login status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	if got := e.fake.Calls("item-m1") + e.fake.Calls("item-ira"); got != calls {
+		t.Fatalf("sync of items all needing login made %d Plaid calls, want none", got-calls)
+	}
+
+	// Re-linking the item makes it healthy, and the next sync brings in its data
+	e.fake.AddItem("public-m1-again", m1Item())
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/exchange", e.token, `{"public_token":"public-m1-again"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("re-link status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	if item := e.listedItems(t)["item-m1"]; item.Status != models.PlaidItemStatusHealthy {
+		t.Fatalf("re-linked item = %+v, want it healthy", item)
+	}
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/sync/m1_finance", e.token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("sync after re-linking status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if item := e.listedItems(t)["item-m1"]; item.LastSyncedAt == nil || item.AccountCount != 1 {
+		t.Fatalf("re-linked item after its sync = %+v, want its last sync and account", item)
+	}
+}
+
+func TestPlaidSyncItem(t *testing.T) {
+	ctx := context.Background()
+	e := newPlaidTestEnv(t, plaidtest.NewFake())
+	e.linkM1Item(t)
+
+	rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/items/item-m1/sync", e.token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("item sync status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	investments, err := e.store.ForUser("alice").GetInvestmentsByPlatform(ctx, models.PlatformM1Finance, store.InvestmentFilter{})
+	if err != nil || len(investments) != 1 {
+		t.Fatalf("synced investments = %d (%v), want 1", len(investments), err)
+	}
+
+	// Another user cannot sync the item, which is not theirs
+	bob := addTestUser(t, e.store, "bob")
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/items/item-m1/sync", bob, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("another user's item sync status = %d, want 404: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/items/missing/sync", e.token, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown item sync status = %d, want 404: %s", rec.Code, rec.Body)
+	}
+
+	// A failing item is reported, but not recorded as a failed sync of the platform
+	e.fake.SetItemError("item-m1", plaidtest.ItemLoginRequired())
+	if rec := doRequest(t, e.router, http.MethodPost, "/api/plaid/items/item-m1/sync", e.token, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("failing item sync status = %d, want 409: %s", rec.Code, rec.Body)
+	}
+	record, err := e.store.ForUser("alice").GetSyncRecord(ctx, models.PlatformM1Finance)
+	if err != nil || record.Status != models.SyncStatusSuccess {
+		t.Fatalf("sync record = %+v (%v), want the earlier success", record, err)
+	}
+}
@@ -21,28 +21,52 @@ func NewPortfoliosHandler(store store.Store) *PortfoliosHandler {
 	}
 }
 
-// GetPortfolios returns all portfolios
+// GetPortfolios returns a page of portfolios (see parseListOptions and parseSortOption)
 func (h *PortfoliosHandler) GetPortfolios(c *gin.Context) {
-	portfolios := h.store.GetAllPortfolios()
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	order, err := parseSortOption(c, store.PortfolioSortFields())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	portfolios, total, err := userStore(c, h.store).GetAllPortfolios(c.Request.Context(), opts, order)
+	if err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"portfolios": portfolios,
+		"portfolios":  portfolios,
+		"total_count": total,
+		"limit":       opts.Limit,
+		"offset":      opts.Offset,
 	})
 }
 
 // GetPortfoliosByPlatform returns portfolios for a specific platform
 func (h *PortfoliosHandler) GetPortfoliosByPlatform(c *gin.Context) {
-	platformStr := c.Param("platform")
-	platform := models.Platform(platformStr)
-
-	// Validate platform
-	if platform != models.PlatformCoinbase {
+	platform, err := models.ParsePlatform(c.Param("platform"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid platform. Must be 'coinbase'",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	portfolios := h.store.GetPortfoliosByPlatform(platform)
+	portfolios, err := userStore(c, h.store).GetPortfoliosByPlatform(c.Request.Context(), platform)
+	if err != nil {
+		respondStoreError(c, err, "get portfolios", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"platform": platform,
 		"portfolios": portfolios,
@@ -52,15 +76,61 @@ func (h *PortfoliosHandler) GetPortfoliosByPlatform(c *gin.Context) {
 // GetPortfolio returns a portfolio by ID
 func (h *PortfoliosHandler) GetPortfolio(c *gin.Context) {
 	portfolioID := c.Param("id")
-	portfolio, exists := h.store.GetPortfolioByID(portfolioID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "portfolio not found",
-		})
+	portfolio, err := userStore(c, h.store).GetPortfolioByID(c.Request.Context(), portfolioID)
+	if err != nil {
+		respondStoreError(c, err, "get portfolio", "portfolio not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, portfolio)
 }
 
+// GetPortfolioValue handles GET /api/portfolios/:id/value
+// Returns the portfolio's number of active holdings and their combined value
+func (h *PortfoliosHandler) GetPortfolioValue(c *gin.Context) {
+	portfolioID := c.Param("id")
+	values, err := userStore(c, h.store).GetPortfolioValues(c.Request.Context())
+	if err != nil {
+		respondStoreError(c, err, "get portfolio value", "")
+		return
+	}
+	for _, value := range values {
+		if value.PortfolioID == portfolioID {
+			c.JSON(http.StatusOK, value)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "portfolio not found",
+	})
+}
+
+
+
+// UpdatePortfolio updates user-managed portfolio metadata (tax treatment, custodian, display order)
+func (h *PortfoliosHandler) UpdatePortfolio(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	var update models.PortfolioMetadataUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
 
+	if update.TaxTreatment != nil && *update.TaxTreatment != "" && !update.TaxTreatment.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid tax_treatment. Must be one of: 'taxable', 'traditional', 'roth', 'other'",
+		})
+		return
+	}
+
+	portfolio, err := userStore(c, h.store).UpdatePortfolioMetadata(c.Request.Context(), portfolioID, update)
+	if err != nil {
+		respondStoreError(c, err, "update portfolio", "portfolio not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, portfolio)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/pricehistory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PricesHandler serves historical market prices
+type PricesHandler struct {
+	history *pricehistory.Service
+}
+
+// NewPricesHandler creates a new prices handler. history is nil when Coinbase is not
+// configured, which makes requests respond 503.
+func NewPricesHandler(history *pricehistory.Service) *PricesHandler {
+	return &PricesHandler{history: history}
+}
+
+// GetCandles returns the candles of a product such as BTC-USD. The optional start and end
+// query parameters take an RFC3339 timestamp or a YYYY-MM-DD date; end defaults to now and start
+// to one request's worth of candles before end. granularity defaults to ONE_DAY.
+func (h *PricesHandler) GetCandles(c *gin.Context) {
+	if h.history == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Coinbase client not configured",
+		})
+		return
+	}
+
+	productID := strings.ToUpper(c.Param("productId"))
+	granularity := strings.ToUpper(c.DefaultQuery("granularity", pricehistory.DefaultGranularity))
+	span, ok := coinbase.CandleGranularity(granularity)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid granularity %q", granularity)})
+		return
+	}
+
+	end := time.Now().UTC()
+	if endStr := c.Query("end"); endStr != "" {
+		parsed, err := parseDateParam(endStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end parameter: " + err.Error()})
+			return
+		}
+		end = parsed
+	}
+	start := end.Add(-span * coinbase.MaxCandlesPerRequest)
+	if startStr := c.Query("start"); startStr != "" {
+		parsed, err := parseDateParam(startStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start parameter: " + err.Error()})
+			return
+		}
+		start = parsed
+	}
+	if !start.Before(end) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be before end"})
+		return
+	}
+
+	candles, err := h.history.Candles(c.Request.Context(), productID, start, end, granularity)
+	if err != nil {
+		log.Printf("Error fetching %s candles: %v", productID, err)
+		var apiErr *coinbase.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("product %s not found", productID)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch candles: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product_id":  productID,
+		"granularity": granularity,
+		"start":       start.Format(time.RFC3339),
+		"end":         end.Format(time.RFC3339),
+		"candles":     candles,
+	})
+}
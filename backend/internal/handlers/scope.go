@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"0xnetworth/backend/internal/auth"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userStore returns the store scoped to the user authenticated on the request
+func userStore(c *gin.Context, s store.Store) store.Store {
+	return s.ForUser(auth.UserID(c))
+}
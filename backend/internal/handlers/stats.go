@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsHandler serves entity counts
+type StatsHandler struct {
+	store store.Store
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(store store.Store) *StatsHandler {
+	return &StatsHandler{
+		store: store,
+	}
+}
+
+// GetStats returns how many portfolios, active investments and workflow executions the user
+// has, without loading the records
+func (h *StatsHandler) GetStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	scoped := userStore(c, h.store)
+
+	portfolios, err := scoped.CountPortfolios(ctx)
+	if err != nil {
+		respondStoreError(c, err, "count portfolios", "")
+		return
+	}
+	investments, err := scoped.CountInvestments(ctx, "")
+	if err != nil {
+		respondStoreError(c, err, "count investments", "")
+		return
+	}
+	executions, err := scoped.CountWorkflowExecutions(ctx, store.ExecutionFilter{})
+	if err != nil {
+		respondStoreError(c, err, "count workflow executions", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"portfolios":          portfolios,
+		"investments":         investments,
+		"workflow_executions": executions,
+	})
+}
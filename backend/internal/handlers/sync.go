@@ -1,140 +1,837 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"0xnetworth/backend/internal/auth"
 	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/integrations/plaid"
 	"0xnetworth/backend/internal/models"
 	"0xnetworth/backend/internal/store"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
+// CoinbaseSyncer fetches everything a Coinbase sync stores. *coinbase.Client implements it; a
+// fake can stand in for it to exercise the sync flow without API keys.
+type CoinbaseSyncer interface {
+	SyncAll(ctx context.Context, opts coinbase.SyncOptions) (*models.SyncResult, error)
+}
+
+var _ CoinbaseSyncer = (*coinbase.Client)(nil)
+
 // SyncHandler handles data synchronization requests
 type SyncHandler struct {
 	store         store.Store
-	coinbaseClient *coinbase.Client
+	coinbaseClient CoinbaseSyncer
+	// coinbaseUserID owns the Coinbase credentials configured through the environment.
+	// Other users cannot sync, or they would import that user's holdings into their own scope.
+	coinbaseUserID string
+	// plaidSyncer syncs each user's own linked items, so unlike Coinbase it serves every user
+	plaidSyncer *plaid.Syncer
+	// onSynced is called after each sync whose results were stored
+	onSynced []func()
 }
 
-// NewSyncHandler creates a new sync handler
-func NewSyncHandler(store store.Store, coinbaseClient *coinbase.Client) *SyncHandler {
-	return &SyncHandler{
+// NewSyncHandler creates a new sync handler. coinbaseClient and plaidClient are nil when
+// Coinbase or Plaid is not configured, which makes syncs of their platforms respond 503;
+// plaidTokens decrypts the access tokens of the items synced through plaidClient.
+func NewSyncHandler(store store.Store, coinbaseClient CoinbaseSyncer, plaidClient PlaidAPI, plaidTokens *plaid.TokenCipher) *SyncHandler {
+	h := &SyncHandler{
 		store:          store,
 		coinbaseClient: coinbaseClient,
+		coinbaseUserID: models.DefaultUserID,
+	}
+	if plaidClient != nil && plaidTokens != nil {
+		h.plaidSyncer = plaid.NewSyncer(plaidClient, plaidTokens)
 	}
+	return h
 }
 
-// SyncAll triggers synchronization from all platforms
-func (h *SyncHandler) SyncAll(c *gin.Context) {
+// OnSynced registers fn to be called after every sync whose results were stored, such as to
+// follow the holdings it changed
+func (h *SyncHandler) OnSynced(fn func()) {
+	h.onSynced = append(h.onSynced, fn)
+}
+
+// coinbaseStore returns the requesting user's store, or writes an error response and
+// returns false if Coinbase is unavailable to that user
+func (h *SyncHandler) coinbaseStore(c *gin.Context) (store.Store, bool) {
 	if h.coinbaseClient == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error": "Coinbase client not configured",
 		})
-		return
+		return nil, false
+	}
+	if auth.UserID(c) != h.coinbaseUserID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Coinbase credentials are not configured for this user",
+		})
+		return nil, false
 	}
+	return userStore(c, h.store), true
+}
 
-	// Sync from Coinbase
-	portfolios, investments, err := h.coinbaseClient.SyncAll()
-	if err != nil {
-		log.Printf("Error syncing from Coinbase: %v", err)
-		// Check if it's a 403 error from Coinbase API
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "403") || strings.Contains(errMsg, "forbidden") {
-			log.Printf("Coinbase API returned 403 Forbidden: %s", errMsg)
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Coinbase API access forbidden: " + errMsg,
-			})
+// SyncAll triggers synchronization from all platforms the requesting user can sync: Coinbase
+// for the user owning its credentials, and M1 Finance once they have linked it through Plaid.
+// Each platform is saved and recorded on its own, so one that fails leaves the others synced
+// and the sync partial; only if every platform fails does the sync fail.
+func (h *SyncHandler) SyncAll(c *gin.Context) {
+	scoped := userStore(c, h.store)
+	ctx := syncContext(c)
+	syncCoinbase := h.coinbaseClient != nil && auth.UserID(c) == h.coinbaseUserID
+	var items []*models.PlaidItem
+	if h.plaidSyncer != nil {
+		var err error
+		items, err = linkedItems(ctx, scoped, models.PlatformM1Finance)
+		if err != nil {
+			respondStoreError(c, err, "get Plaid items", "")
 			return
 		}
+	}
+	if !syncCoinbase && len(items) == 0 {
+		// Nothing to sync, so explain why Coinbase cannot be
+		h.coinbaseStore(c)
+		return
+	}
+
+	// Once the data is fetched it is written in full even if the client goes away, so a
+	// cancelled request cannot leave some records of the sync saved and others not
+	writeCtx := context.WithoutCancel(c.Request.Context())
+	var results []*models.SyncResult
+	var failures []platformFailure
+	reports := make(map[models.Platform]models.SyncReport)
+	if syncCoinbase {
+		result, err := h.coinbaseClient.SyncAll(ctx, coinbaseSyncOptions(ctx, scoped))
+		if err != nil {
+			log.Printf("Error syncing from Coinbase: %v", err)
+			recordSyncFailure(writeCtx, scoped, models.PlatformCoinbase, err)
+			failures = append(failures, platformFailure{models.PlatformCoinbase, err})
+		} else {
+			results = append(results, result)
+		}
+	}
+	var plaidResult *models.SyncResult
+	if len(items) > 0 {
+		var err error
+		plaidResult, err = h.syncPlaidItems(ctx, scoped, items)
+		if err != nil {
+			log.Printf("Error syncing from Plaid: %v", err)
+			recordSyncFailure(writeCtx, scoped, models.PlatformM1Finance, err)
+			failures = append(failures, platformFailure{models.PlatformM1Finance, err})
+			if plaidResult != nil {
+				reports[models.PlatformM1Finance] = plaidResult.Report
+			}
+		} else {
+			results = append(results, plaidResult)
+		}
+	}
+	if len(results) == 0 {
+		// Coinbase's failure says more, such as how long to wait after a rate limit
+		if failures[0].platform == models.PlatformCoinbase {
+			respondCoinbaseSyncError(c, failures[0].err)
+		} else {
+			respondPlaidSyncError(c, failures[0].err, plaidResult)
+		}
+		return
+	}
+
+	syncTime := models.Now()
+	var saved savedSync
+	var synced []*models.SyncResult
+	totalErrors := 0
+	for _, result := range results {
+		platformSaved, errorCount, err := saveSyncResults(writeCtx, scoped, result, syncTime)
+		if err != nil {
+			recordSyncFailure(writeCtx, scoped, result.Platform, err)
+			failures = append(failures, platformFailure{result.Platform, fmt.Errorf("failed to store synced data: %w", err)})
+			totalErrors += errorCount
+			continue
+		}
+		synced = append(synced, result)
+		saved.Changes.Merge(platformSaved.Changes)
+		saved.Deactivated += platformSaved.Deactivated
+		saved.Dust += platformSaved.Dust
+	}
+	if len(synced) == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to sync from Coinbase: " + err.Error(),
+			"error":       failures[len(failures)-1].err.Error(),
+			"error_count": totalErrors,
 		})
 		return
 	}
+	for _, fn := range h.onSynced {
+		fn()
+	}
+
+	response := gin.H{
+		"last_sync": syncTime.Format(time.RFC3339),
+		"platforms": syncStatusesOrNil(writeCtx, scoped),
+		"investments_deactivated": saved.Deactivated,
+		"dust_holdings": saved.Dust,
+		"created": saved.Changes.Created,
+		"updated": saved.Changes.Updated,
+		"unchanged": saved.Changes.Unchanged,
+	}
+	status := models.SyncStatusSuccess
+	messages := make([]string, 0, len(synced)+len(failures))
+	var portfolios, investments, accounts, transactions int
+	staleAssets := make([]string, 0)
+	for _, result := range synced {
+		portfolios += len(result.Portfolios)
+		investments += len(result.Investments)
+		accounts += len(result.Accounts)
+		transactions += len(result.Transactions)
+		staleAssets = append(staleAssets, result.StaleAssets...)
+		reports[result.Platform] = result.Report
+		if result.Report.Status != models.SyncStatusSuccess {
+			status = models.SyncStatusPartial
+		}
+		messages = append(messages, syncMessage(result.Report, result.Platform))
+		if result.Platform == models.PlatformCoinbase {
+			// Kept for clients written when Coinbase was the only platform
+			response["report"] = result.Report
+		}
+	}
+	if len(failures) > 0 {
+		status = models.SyncStatusPartial
+		errs := make(map[models.Platform]string, len(failures))
+		for _, failure := range failures {
+			errs[failure.platform] = failure.err.Error()
+			messages = append(messages, fmt.Sprintf("%s sync failed: %v", failure.platform, failure.err))
+		}
+		response["errors"] = errs
+	}
+	response["message"] = strings.Join(messages, "; ")
+	if len(synced) == 1 && len(failures) == 0 {
+		response["message"] = syncMessage(synced[0].Report, "")
+	}
+	response["status"] = status
+	response["portfolios_synced"] = portfolios
+	response["investments_synced"] = investments
+	response["accounts_synced"] = accounts
+	response["transactions_synced"] = transactions
+	response["stale_assets"] = staleAssets
+	response["reports"] = reports
+	c.JSON(http.StatusOK, response)
+}
 
-	// Store portfolios
-	for _, portfolio := range portfolios {
-		h.store.CreateOrUpdatePortfolio(portfolio)
+// platformFailure is a platform whose part of a sync of all platforms failed
+type platformFailure struct {
+	platform models.Platform
+	err      error
+}
+
+// SyncPlatform triggers synchronization for a specific platform
+func (h *SyncHandler) SyncPlatform(c *gin.Context) {
+	platform, err := models.ParsePlatform(c.Param("platform"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	// Store investments
-	for _, investment := range investments {
-		h.store.CreateOrUpdateInvestment(investment)
+	if platform == models.PlatformM1Finance {
+		h.syncPlaid(c, platform)
+		return
 	}
 
-	// Recalculate net worth
-	h.store.RecalculateNetWorth()
-	h.store.SetLastSyncTime(time.Now())
+	scoped, ok := h.coinbaseStore(c)
+	if !ok {
+		return
+	}
+
+	// Sync from Coinbase
+	ctx := syncContext(c)
+	result, err := h.coinbaseClient.SyncAll(ctx, coinbaseSyncOptions(ctx, scoped))
+	// Once the data is fetched it is written in full even if the client goes away, so a
+	// cancelled request cannot leave some records of the sync saved and others not
+	writeCtx := context.WithoutCancel(c.Request.Context())
+	if err != nil {
+		log.Printf("Error syncing from Coinbase: %v", err)
+		recordSyncFailure(writeCtx, scoped, models.PlatformCoinbase, err)
+		respondCoinbaseSyncError(c, err)
+		return
+	}
+
+	syncTime := models.Now()
+	saved, errorCount, err := saveSyncResults(writeCtx, scoped, result, syncTime)
+	if err != nil {
+		recordSyncFailure(writeCtx, scoped, result.Platform, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":       "failed to store synced data: " + err.Error(),
+			"error_count": errorCount,
+		})
+		return
+	}
+	for _, fn := range h.onSynced {
+		fn()
+	}
+
+	if result.Platform != platform {
+		log.Printf("Requested a %s sync but the client synced %s", platform, result.Platform)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "sync completed successfully",
-		"last_sync": h.store.GetLastSyncTime().Format(time.RFC3339),
-		"portfolios_synced": len(portfolios),
-		"investments_synced": len(investments),
+		"message":   syncMessage(result.Report, result.Platform),
+		"status":    result.Report.Status,
+		"platform":  result.Platform,
+		"last_sync": syncTime.Format(time.RFC3339),
+		"platforms": syncStatusesOrNil(writeCtx, scoped),
+		"portfolios_synced": len(result.Portfolios),
+		"investments_synced": len(result.Investments),
+		"accounts_synced": len(result.Accounts),
+		"transactions_synced": len(result.Transactions),
+		"stale_assets": result.StaleAssets,
+		"report": result.Report,
+		"investments_deactivated": saved.Deactivated,
+		"dust_holdings": saved.Dust,
+		"created": saved.Changes.Created,
+		"updated": saved.Changes.Updated,
+		"unchanged": saved.Changes.Unchanged,
 	})
 }
 
-// SyncPlatform triggers synchronization for a specific platform
-func (h *SyncHandler) SyncPlatform(c *gin.Context) {
-	platformStr := c.Param("platform")
-	platform := models.Platform(platformStr)
-
-	if platform != models.PlatformCoinbase {
+// syncPlaid syncs every Plaid item the requesting user linked for platform. Items that fail
+// are reported in the response's report.items without failing the others; only when every
+// item fails is the sync recorded as failed, and then nothing is saved.
+func (h *SyncHandler) syncPlaid(c *gin.Context, platform models.Platform) {
+	if h.plaidSyncer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Plaid client not configured",
+		})
+		return
+	}
+	scoped := userStore(c, h.store)
+	ctx := syncContext(c)
+	items, err := linkedItems(ctx, scoped, platform)
+	if err != nil {
+		respondStoreError(c, err, "get Plaid items", "")
+		return
+	}
+	if len(items) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "invalid platform. Only 'coinbase' is supported",
+			"error": fmt.Sprintf("no %s accounts are linked; link one through Plaid first", platform),
 		})
 		return
 	}
 
-	if h.coinbaseClient == nil {
+	h.syncAndSavePlaidItems(c, ctx, scoped, platform, items, true)
+}
+
+// SyncPlaidItem handles POST /api/plaid/items/:id/sync
+// Syncs one of the requesting user's linked Plaid items and stores its data, as syncing its
+// platform does for all of them. A failure concerns the item alone, so unlike a failed sync of
+// the platform it is not recorded in the sync status; the item's report gives the reason.
+func (h *SyncHandler) SyncPlaidItem(c *gin.Context) {
+	if h.plaidSyncer == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Coinbase client not configured",
+			"error": "Plaid client not configured",
 		})
 		return
 	}
+	scoped := userStore(c, h.store)
+	ctx := syncContext(c)
+	item, err := scoped.GetPlaidItem(ctx, c.Param("id"))
+	if err != nil {
+		respondStoreError(c, err, "get Plaid item", "Plaid item not found")
+		return
+	}
+	h.syncAndSavePlaidItems(c, ctx, scoped, item.Platform, []*models.PlaidItem{item}, false)
+}
 
-	// Sync from Coinbase
-	portfolios, investments, err := h.coinbaseClient.SyncAll()
+// syncAndSavePlaidItems syncs items of platform with the sync's context ctx, stores their data
+// and writes the response. recordFailure records a sync that fails as a failed sync of platform.
+func (h *SyncHandler) syncAndSavePlaidItems(c *gin.Context, ctx context.Context, scoped store.Store, platform models.Platform, items []*models.PlaidItem, recordFailure bool) {
+	result, err := h.syncPlaidItems(ctx, scoped, items)
+	// As with Coinbase, fetched data is written in full even if the client goes away
+	writeCtx := context.WithoutCancel(c.Request.Context())
 	if err != nil {
-		log.Printf("Error syncing from Coinbase: %v", err)
-		// Check if it's a 403 error from Coinbase API
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "403") || strings.Contains(errMsg, "forbidden") {
-			log.Printf("Coinbase API returned 403 Forbidden: %s", errMsg)
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Coinbase API access forbidden: " + errMsg,
-			})
-			return
+		log.Printf("Error syncing from Plaid: %v", err)
+		if recordFailure {
+			recordSyncFailure(writeCtx, scoped, platform, err)
+		}
+		respondPlaidSyncError(c, err, result)
+		return
+	}
+
+	syncTime := models.Now()
+	saved, errorCount, err := saveSyncResults(writeCtx, scoped, result, syncTime)
+	if err != nil {
+		if recordFailure {
+			recordSyncFailure(writeCtx, scoped, platform, err)
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to sync from Coinbase: " + err.Error(),
+			"error":       "failed to store synced data: " + err.Error(),
+			"error_count": errorCount,
 		})
 		return
 	}
+	for _, fn := range h.onSynced {
+		fn()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 syncMessage(result.Report, platform),
+		"status":                  result.Report.Status,
+		"platform":                platform,
+		"last_sync":               syncTime.Format(time.RFC3339),
+		"platforms":               syncStatusesOrNil(writeCtx, scoped),
+		"portfolios_synced":       len(result.Portfolios),
+		"investments_synced":      len(result.Investments),
+		"accounts_synced":         len(result.Accounts),
+		"transactions_synced":     len(result.Transactions),
+		"report":                  result.Report,
+		"investments_deactivated": saved.Deactivated,
+		"dust_holdings":           saved.Dust,
+		"created":                 saved.Changes.Created,
+		"updated":                 saved.Changes.Updated,
+		"unchanged":               saved.Changes.Unchanged,
+	})
+}
+
+// errPlaidItemsFailed is the error of a Plaid sync in which every linked item failed
+var errPlaidItemsFailed = errors.New("every linked item failed to sync")
 
-	// Store portfolios
-	for _, portfolio := range portfolios {
-		h.store.CreateOrUpdatePortfolio(portfolio)
+// linkedItems returns the Plaid items the user of s linked for platform
+func linkedItems(ctx context.Context, s store.Store, platform models.Platform) ([]*models.PlaidItem, error) {
+	items, err := s.GetPlaidItems(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return slices.DeleteFunc(items, func(item *models.PlaidItem) bool {
+		return item.Platform != platform
+	}), nil
+}
 
-	// Store investments
-	for _, investment := range investments {
-		h.store.CreateOrUpdateInvestment(investment)
+// syncPlaidItems syncs the healthy items of the user of s. The others are not sent to Plaid but
+// reported as skipped, since they cannot sync until they are re-linked. An item that fails
+// because its institution needs the user to log in again is recorded as such. If none of them
+// synced it fails with errPlaidItemsFailed along with the result, whose report gives each
+// item's error.
+func (h *SyncHandler) syncPlaidItems(ctx context.Context, s store.Store, items []*models.PlaidItem) (*models.SyncResult, error) {
+	healthy := make([]*models.PlaidItem, 0, len(items))
+	var skipped []models.ItemSyncReport
+	for _, item := range items {
+		if item.Healthy() {
+			healthy = append(healthy, item)
+			continue
+		}
+		skipped = append(skipped, models.ItemSyncReport{
+			ID:              item.ID,
+			InstitutionName: item.InstitutionName,
+			Status:          models.SyncStatusSkipped,
+			Error:           "the item has to be re-linked through Plaid Link before it can sync",
+			ErrorCode:       plaid.ErrorCodeItemLoginRequired,
+		})
 	}
 
-	// Recalculate net worth
-	h.store.RecalculateNetWorth()
-	h.store.SetLastSyncTime(time.Now())
+	result := &models.SyncResult{
+		Platform: models.PlatformM1Finance,
+		Report: models.SyncReport{
+			Status:        models.SyncStatusSuccess,
+			Portfolios:    []models.PortfolioSyncReport{},
+			SkippedAssets: []models.SkippedAsset{},
+			Items:         []models.ItemSyncReport{},
+		},
+	}
+	if len(healthy) > 0 {
+		var err error
+		result, err = h.plaidSyncer.SyncItems(ctx, healthy, plaidSyncOptions(ctx, s, healthy))
+		if err != nil {
+			return nil, err
+		}
+		recordItemsNeedingLogin(ctx, s, result.Report.Items)
+	}
+	if len(skipped) > 0 {
+		result.Report.Items = append(result.Report.Items, skipped...)
+		result.Report.Status = models.SyncStatusPartial
+	}
+	if result.Report.FailedItems()+result.Report.SkippedItems() == len(result.Report.Items) {
+		return result, errPlaidItemsFailed
+	}
+	return result, nil
+}
+
+// recordItemsNeedingLogin marks the items that failed because Plaid needs the user to log in
+// again, so later syncs skip them. A status that cannot be recorded is only logged, since the
+// item then fails again on the next sync.
+func recordItemsNeedingLogin(ctx context.Context, s store.Store, items []models.ItemSyncReport) {
+	ctx = context.WithoutCancel(ctx)
+	for _, item := range items {
+		if item.Status != models.SyncStatusFailed || item.ErrorCode != plaid.ErrorCodeItemLoginRequired {
+			continue
+		}
+		if err := s.SetPlaidItemStatus(ctx, item.ID, models.PlaidItemStatusLoginRequired, nil); err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Failed to record that Plaid item %s needs the user to log in again: %v", item.ID, err)
+		}
+	}
+}
+
+// plaidSyncOptions fetches only the investment transactions dated from the newest stored one of
+// each account of items, so a sync after the first one fetches just the recent ones. An account
+// whose newest transaction cannot be read has the whole lookback window fetched again, which
+// rewrites the same transactions.
+func plaidSyncOptions(ctx context.Context, s store.Store, items []*models.PlaidItem) plaid.SyncOptions {
+	opts := plaid.SyncOptions{TransactionsSince: make(map[string]time.Time)}
+	platforms := make([]models.Platform, 0, 1)
+	for _, item := range items {
+		if !slices.Contains(platforms, item.Platform) {
+			platforms = append(platforms, item.Platform)
+		}
+	}
+	for _, platform := range platforms {
+		accounts, err := s.GetAccountsByPlatform(ctx, platform)
+		if err != nil {
+			log.Printf("Failed to read stored %s accounts, fetching every transaction: %v", platform, err)
+			continue
+		}
+		for _, account := range accounts {
+			latest, _, err := s.ListTransactions(ctx, store.TransactionFilter{
+				Platform:    platform,
+				AccountID:   account.ID,
+				ListOptions: store.ListOptions{Limit: 1},
+			})
+			if err != nil {
+				log.Printf("Failed to read the latest transaction of account %s, fetching all of them: %v", account.ID, err)
+			} else if len(latest) > 0 {
+				opts.TransactionsSince[account.ID] = latest[0].Timestamp
+			}
+		}
+	}
+	return opts
+}
+
+// respondPlaidSyncError writes the response for a Plaid sync that failed before anything was
+// saved. result, if any, reports why each item failed. If every item failed because its
+// institution wants the user to log in again, syncing again cannot help until they re-link it,
+// so the sync conflicts with the state of their items rather than failing upstream.
+func respondPlaidSyncError(c *gin.Context, err error, result *models.SyncResult) {
+	switch {
+	case errors.Is(err, errPlaidItemsFailed) && itemsNeedLogin(result.Report.Items):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Plaid needs you to log in to your linked institution again; re-link it through Plaid Link, then sync",
+			"report": result.Report,
+		})
+	case errors.Is(err, errPlaidItemsFailed):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "Failed to sync from Plaid: " + err.Error(),
+			"report": result.Report,
+		})
+	case errors.Is(err, context.DeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": "Plaid sync timed out: " + err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to sync from Plaid: " + err.Error(),
+		})
+	}
+}
+
+// itemsNeedLogin reports whether every failed or skipped item of a sync needs the user to log
+// in again
+func itemsNeedLogin(items []models.ItemSyncReport) bool {
+	failed := 0
+	for _, item := range items {
+		if item.Status != models.SyncStatusFailed && item.Status != models.SyncStatusSkipped {
+			continue
+		}
+		if item.ErrorCode != plaid.ErrorCodeItemLoginRequired {
+			return false
+		}
+		failed++
+	}
+	return failed > 0
+}
+
+// syncContext returns the request's context with a correlation ID for the Coinbase requests of
+// a sync: the caller's X-Request-ID if it is a plain token, or a new one. The ID is returned in
+// the X-Request-ID response header so the sync's log lines can be found.
+func syncContext(c *gin.Context) context.Context {
+	id := c.GetHeader("X-Request-ID")
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	c.Header("X-Request-ID", id)
+	return coinbase.WithCorrelationID(c.Request.Context(), id)
+}
+
+// validRequestID reports whether a caller's request ID is safe to log: up to 64 letters,
+// digits, dashes, underscores and dots
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// syncMessage summarizes a completed sync, of platform if one was requested
+func syncMessage(report models.SyncReport, platform models.Platform) string {
+	message := "sync completed successfully"
+	if failed := report.FailedItems(); failed > 0 {
+		message = fmt.Sprintf("sync completed with %d of %d linked items failing", failed, len(report.Items))
+	} else if skipped := report.SkippedItems(); skipped > 0 {
+		message = fmt.Sprintf("sync completed with %d of %d linked items skipped until they are re-linked", skipped, len(report.Items))
+	} else if report.Status == models.SyncStatusPartial {
+		message = fmt.Sprintf("sync completed with %d of %d holdings skipped", report.Holdings.Skipped, report.Holdings.Fetched)
+	}
+	if platform != "" {
+		message += " for " + string(platform)
+	}
+	if len(report.SkippedPortfolios) > 0 {
+		skipped := make([]string, 0, len(report.SkippedPortfolios))
+		for _, portfolio := range report.SkippedPortfolios {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", portfolio.Name, portfolio.Type))
+		}
+		message += fmt.Sprintf(", skipping %d portfolios by type: %s", len(skipped), strings.Join(skipped, ", "))
+	}
+	return message
+}
+
+// coinbaseSyncOptions fetches only the fills executed since the latest stored Coinbase
+// transaction, so a sync after the first one fetches just the new trades. If that cannot be
+// read every fill is fetched again, which rewrites the same transactions. It also passes the
+// stored price of each Coinbase investment, so a holding that cannot be priced keeps it; if
+// those cannot be read such holdings are skipped.
+func coinbaseSyncOptions(ctx context.Context, s store.Store) coinbase.SyncOptions {
+	var opts coinbase.SyncOptions
+	latest, _, err := s.ListTransactions(ctx, store.TransactionFilter{
+		Platform:    models.PlatformCoinbase,
+		ListOptions: store.ListOptions{Limit: 1},
+	})
+	if err != nil {
+		log.Printf("Failed to read the latest Coinbase transaction, fetching every fill: %v", err)
+	} else if len(latest) > 0 {
+		opts.FillsSince = latest[0].Timestamp
+	}
+
+	stored, err := s.GetInvestmentsByPlatform(ctx, models.PlatformCoinbase, store.InvestmentFilter{IncludeInactive: true})
+	if err != nil {
+		log.Printf("Failed to read stored Coinbase prices, unpriced holdings will be skipped: %v", err)
+		return opts
+	}
+	opts.LastPrices = make(map[string]float64, len(stored))
+	for _, investment := range stored {
+		if investment.Price > 0 {
+			opts.LastPrices[investment.ID] = investment.Price
+		}
+	}
+	return opts
+}
+
+// respondCoinbaseSyncError writes the response for a Coinbase sync that failed before anything
+// was saved
+func respondCoinbaseSyncError(c *gin.Context, err error) {
+	var limited *coinbase.RateLimitedError
+	if errors.As(err, &limited) {
+		retryAfter := int(math.Ceil(limited.RetryAfter.Seconds()))
+		if retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":               "Coinbase rate limited the sync: " + err.Error(),
+			"retry_after_seconds": retryAfter,
+		})
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": "Coinbase sync timed out: " + err.Error(),
+		})
+		return
+	}
+	// Check if it's a 403 error from Coinbase API
+	errMsg := err.Error()
+	var apiErr *coinbase.APIError
+	if (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden) ||
+		strings.Contains(errMsg, "403") || strings.Contains(errMsg, "forbidden") {
+		log.Printf("Coinbase API returned 403 Forbidden: %s", errMsg)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Coinbase API access forbidden: " + errMsg,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to sync from Coinbase: " + err.Error(),
+	})
+}
 
+// GetSyncStatus handles GET /api/sync/status
+// Returns the latest sync attempt on each supported platform for the requesting user: whether
+// it succeeded, the error if it failed, how many records it wrote, and the last successful sync.
+func (h *SyncHandler) GetSyncStatus(c *gin.Context) {
+	statuses, err := syncStatuses(c.Request.Context(), userStore(c, h.store))
+	if err != nil {
+		respondStoreError(c, err, "get sync status", "")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "sync completed successfully for " + platformStr,
-		"platform":  platformStr,
-		"last_sync": h.store.GetLastSyncTime().Format(time.RFC3339),
-		"portfolios_synced": len(portfolios),
-		"investments_synced": len(investments),
+		"platforms": statuses,
 	})
 }
 
+// syncStatuses returns the latest sync attempt on every supported platform, with status
+// "never" for platforms that have not been synced
+func syncStatuses(ctx context.Context, s store.Store) ([]*models.SyncRecord, error) {
+	platforms := models.ValidPlatforms()
+	statuses := make([]*models.SyncRecord, len(platforms))
+	for i, platform := range platforms {
+		record, err := s.GetSyncRecord(ctx, platform)
+		if errors.Is(err, store.ErrNotFound) {
+			record, err = &models.SyncRecord{Platform: platform, Status: models.SyncStatusNever}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		statuses[i] = record
+	}
+	return statuses, nil
+}
+
+// syncStatusesOrNil returns the platforms' sync status for a sync response. The sync itself
+// has already succeeded, so a failure to read the status is logged rather than reported.
+func syncStatusesOrNil(ctx context.Context, s store.Store) []*models.SyncRecord {
+	statuses, err := syncStatuses(ctx, s)
+	if err != nil {
+		log.Printf("Failed to read sync status: %v", err)
+		return nil
+	}
+	return statuses
+}
+
+// recordSyncFailure records a failed sync attempt on platform so the status endpoint can
+// report it. The sync has already failed, so a failure to record it is only logged.
+func recordSyncFailure(ctx context.Context, s store.Store, platform models.Platform, syncErr error) {
+	if err := s.RecordSyncResult(ctx, platform, models.SyncStatusFailed, syncErr.Error(), models.SyncCounts{}, models.Now()); err != nil {
+		log.Printf("Failed to record failed %s sync: %v", platform, err)
+	}
+}
+
+// savedSync summarizes what saveSyncResults wrote
+type savedSync struct {
+	// Changes counts the portfolios, accounts and investments created, updated or left unchanged
+	Changes     store.UpsertCounts
+	Deactivated int
+	// Dust counts the holdings worth less than the dust threshold, flagged or skipped
+	Dust int
+}
+
+// saveSyncResults stores synced portfolios, accounts, transactions and investments, costed from
+// the stored transactions and with the dust policy applied (see syncDustPolicy), deactivates
+// holdings that fully synced portfolios no longer report, then recalculates net worth, snapshots
+// it, records the synced positions in the investment history and records a successful sync of the
+// platform. It returns what was written and how many investments were deactivated. Everything is
+// written in one transaction, so a sync is either stored whole and recorded as successful or not
+// stored at all: on a failure it returns how many records failed along with the error, and
+// nothing is kept. Callers record the failure.
+func saveSyncResults(ctx context.Context, s store.Store, result *models.SyncResult, syncTime time.Time) (savedSync, int, error) {
+	saved := savedSync{Dust: syncDustPolicy().apply(result)}
+	total := len(result.Portfolios) + len(result.Accounts) + len(result.Investments) + len(result.Transactions)
+	// How many records the failure that rolled the sync back accounts for
+	errorCount := 0
+	recordFailure := func(records int, err error) error {
+		log.Printf("Error storing synced data: %v", err)
+		errorCount = records
+		return fmt.Errorf("%d of %d records failed to save: %w", records, total, err)
+	}
+	err := s.WithTransaction(ctx, func(tx store.Store) error {
+		for _, portfolio := range result.Portfolios {
+			upsert, err := tx.CreateOrUpdatePortfolio(ctx, portfolio)
+			if err != nil {
+				return recordFailure(1, err)
+			}
+			saved.Changes.Add(upsert)
+		}
+		for _, account := range result.Accounts {
+			upsert, err := tx.CreateOrUpdateAccount(ctx, account)
+			if err != nil {
+				return recordFailure(1, err)
+			}
+			saved.Changes.Add(upsert)
+		}
+		// Transactions are written by ID without telling created from updated, so they are not counted
+		for _, transaction := range result.Transactions {
+			if err := tx.CreateOrUpdateTransaction(ctx, transaction); err != nil {
+				return recordFailure(1, err)
+			}
+		}
+		// Cost basis is derived from every stored transaction, including the fills just written
+		applyCostBasis(ctx, tx, result.Investments)
+		investments, err := tx.CreateOrUpdateInvestments(ctx, result.Investments)
+		if err != nil {
+			// The batch is all-or-nothing, so none of the investments were saved
+			return recordFailure(len(result.Investments), err)
+		}
+		saved.Changes.Merge(investments)
+
+		// A holding missing from a portfolio whose holdings were all fetched has been sold.
+		// Portfolios whose fetch failed are left out, so their holdings stay active until a sync
+		// succeeds.
+		keepIDs := make([]string, 0, len(result.Investments))
+		for _, investment := range result.Investments {
+			keepIDs = append(keepIDs, investment.ID)
+		}
+		saved.Deactivated, err = tx.DeactivateMissingInvestments(ctx, result.Platform, result.CompletePortfolios, keepIDs, syncTime)
+		if err != nil {
+			return err
+		}
+
+		// Recalculate net worth and record it and each synced position in the history
+		networth, err := tx.RecalculateNetWorth(ctx)
+		if err != nil {
+			return err
+		}
+		if err := tx.SaveNetWorthSnapshot(ctx, networth); err != nil {
+			return err
+		}
+		if err := tx.SaveInvestmentHistory(ctx, models.NewInvestmentHistoryPoints(result.Investments, syncTime)); err != nil {
+			return err
+		}
+		// Each synced Plaid item keeps its accounts, so unlinking it can find their data, and
+		// is healthy as of this sync. An item unlinked while it synced has nothing to keep them on.
+		for _, item := range result.Report.Items {
+			if item.Status != models.SyncStatusSuccess {
+				continue
+			}
+			if err := tx.SetPlaidItemAccounts(ctx, item.ID, item.AccountIDs); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+			if err := tx.SetPlaidItemStatus(ctx, item.ID, models.PlaidItemStatusHealthy, &syncTime); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+		counts := models.SyncCounts{
+			Portfolios:  len(result.Portfolios),
+			Accounts:    len(result.Accounts),
+			Investments: len(result.Investments),
+		}
+		return tx.RecordSyncResult(ctx, result.Platform, models.SyncStatusSuccess, "", counts, syncTime)
+	})
+	if err != nil {
+		return savedSync{}, max(errorCount, 1), err
+	}
+	if saved.Changes.Skipped > 0 {
+		log.Printf("Skipped %d synced records that belong to another user", saved.Changes.Skipped)
+	}
+	if saved.Deactivated > 0 {
+		log.Printf("Deactivated %d %s investments no longer reported by the platform", saved.Deactivated, result.Platform)
+	}
+	return saved, 0, nil
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingInvestmentsStore fails every investment batch, in and out of transactions
+type failingInvestmentsStore struct {
+	store.Store
+	err error
+}
+
+func (s failingInvestmentsStore) WithTransaction(ctx context.Context, fn func(tx store.Store) error) error {
+	return s.Store.WithTransaction(ctx, func(tx store.Store) error {
+		return fn(failingInvestmentsStore{Store: tx, err: s.err})
+	})
+}
+
+func (s failingInvestmentsStore) ForUser(userID string) store.Store {
+	return failingInvestmentsStore{Store: s.Store.ForUser(userID), err: s.err}
+}
+
+func (s failingInvestmentsStore) CreateOrUpdateInvestments(context.Context, []*models.Investment) (store.UpsertCounts, error) {
+	return store.UpsertCounts{}, s.err
+}
+
+// testSyncResult returns a Coinbase sync of one portfolio holding one BTC worth price
+func testSyncResult(price float64) *models.SyncResult {
+	return &models.SyncResult{
+		Platform:   models.PlatformCoinbase,
+		Portfolios: []*models.Portfolio{{ID: "p1", Platform: models.PlatformCoinbase, Name: "Default"}},
+		Accounts: []*models.Account{{
+			ID: "a1", Platform: models.PlatformCoinbase, PortfolioID: "p1", Name: "BTC Wallet", Currency: "BTC", Active: true,
+		}},
+		Investments: []*models.Investment{{
+			ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", AssetType: models.AssetTypeCrypto,
+			Quantity: 1, Price: price, Value: price, Currency: "USD",
+		}},
+		Transactions: []*models.Transaction{{
+			ID: "t1", AccountID: "a1", Platform: models.PlatformCoinbase, Type: models.TransactionTypeBuy, Symbol: "BTC",
+			Quantity: 1, Amount: price, Currency: "USD", Timestamp: models.Now(),
+		}},
+		CompletePortfolios: []string{"p1"},
+		Report:             models.SyncReport{Status: models.SyncStatusSuccess},
+	}
+}
+
+func TestSaveSyncResultsKeepsNothingOnFailure(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	errWrite := errors.New("disk full")
+
+	_, errorCount, err := saveSyncResults(ctx, failingInvestmentsStore{Store: s, err: errWrite}, testSyncResult(60000), models.Now())
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("saveSyncResults error = %v, want %v", err, errWrite)
+	}
+	if errorCount != 1 {
+		t.Fatalf("error count = %d, want the 1 investment of the failed batch", errorCount)
+	}
+
+	// The portfolio, account and transaction written before the investments failed are rolled back
+	if _, err := s.GetPortfolioByID(ctx, "p1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("portfolio after a failed sync: %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetAccountByID(ctx, "a1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("account after a failed sync: %v, want ErrNotFound", err)
+	}
+	if transactions, err := s.GetTransactionsByAccount(ctx, "a1"); err != nil || len(transactions) != 0 {
+		t.Errorf("transactions after a failed sync = %d (%v), want none", len(transactions), err)
+	}
+	if _, err := s.GetSyncRecord(ctx, models.PlatformCoinbase); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("sync record after a failed sync: %v, want none", err)
+	}
+}
+
+// fakeCoinbase returns a fixed sync result or error in place of the Coinbase API
+type fakeCoinbase struct {
+	result *models.SyncResult
+	err    error
+}
+
+func (f *fakeCoinbase) SyncAll(context.Context, coinbase.SyncOptions) (*models.SyncResult, error) {
+	return f.result, f.err
+}
+
+// newSyncRouter serves the sync routes of a handler syncing Coinbase through client
+func newSyncRouter(t *testing.T, s store.Store, client CoinbaseSyncer) (*gin.Engine, string) {
+	t.Helper()
+	token := addTestUser(t, s, models.DefaultUserID)
+	h := NewSyncHandler(s, client, nil, nil)
+	router := newTestRouter(s)
+	router.POST("/api/sync/:platform", h.SyncPlatform)
+	return router, token
+}
+
+func TestSyncPlatformStoresResults(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	router, token := newSyncRouter(t, s, &fakeCoinbase{result: testSyncResult(60000)})
+
+	rec := doRequest(t, router, http.MethodPost, "/api/sync/coinbase", token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Status            models.SyncStatus `json:"status"`
+		InvestmentsSynced int               `json:"investments_synced"`
+		Created           int               `json:"created"`
+	}
+	decodeJSON(t, rec, &body)
+	if body.Status != models.SyncStatusSuccess || body.InvestmentsSynced != 1 || body.Created != 3 {
+		t.Fatalf("response = %+v, want a successful sync creating a portfolio, account and investment", body)
+	}
+
+	scoped := s.ForUser(models.DefaultUserID)
+	if _, err := scoped.GetPortfolioByID(ctx, "p1"); err != nil {
+		t.Errorf("synced portfolio: %v", err)
+	}
+	investments, err := scoped.GetInvestmentsByPlatform(ctx, models.PlatformCoinbase, store.InvestmentFilter{})
+	if err != nil || len(investments) != 1 || investments[0].Value != 60000 {
+		t.Errorf("synced investments = %v (%v), want the BTC holding worth 60000", investments, err)
+	}
+	snapshots, err := scoped.GetNetWorthSnapshots(ctx, time.Time{}, time.Time{})
+	if err != nil || len(snapshots) != 1 {
+		t.Errorf("snapshots = %d (%v), want 1", len(snapshots), err)
+	}
+	record, err := scoped.GetSyncRecord(ctx, models.PlatformCoinbase)
+	if err != nil || record.Status != models.SyncStatusSuccess {
+		t.Errorf("sync record = %+v (%v), want a success", record, err)
+	}
+}
+
+func TestSyncPlatformReportsPartialSync(t *testing.T) {
+	result := testSyncResult(60000)
+	result.Report.Status = models.SyncStatusPartial
+	result.Report.Holdings.Fetched, result.Report.Holdings.Skipped = 2, 1
+	router, token := newSyncRouter(t, store.NewStore(), &fakeCoinbase{result: result})
+
+	rec := doRequest(t, router, http.MethodPost, "/api/sync/coinbase", token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Status  models.SyncStatus `json:"status"`
+		Message string            `json:"message"`
+	}
+	decodeJSON(t, rec, &body)
+	if body.Status != models.SyncStatusPartial || !strings.Contains(body.Message, "1 of 2 holdings skipped") {
+		t.Fatalf("response = %+v, want a partial sync skipping 1 of 2 holdings", body)
+	}
+}
+
+func TestSyncPlatformForbidden(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	router, token := newSyncRouter(t, s, &fakeCoinbase{err: &coinbase.APIError{StatusCode: http.StatusForbidden, Message: "missing scope"}})
+
+	rec := doRequest(t, router, http.MethodPost, "/api/sync/coinbase", token, "")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403: %s", rec.Code, rec.Body)
+	}
+	record, err := s.ForUser(models.DefaultUserID).GetSyncRecord(ctx, models.PlatformCoinbase)
+	if err != nil || record.Status != models.SyncStatusFailed {
+		t.Errorf("sync record = %+v (%v), want a failure", record, err)
+	}
+}
+
+func TestSyncPlatformStoreFailure(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	failing := failingInvestmentsStore{Store: s, err: errors.New("disk full")}
+	router, token := newSyncRouter(t, failing, &fakeCoinbase{result: testSyncResult(60000)})
+
+	rec := doRequest(t, router, http.MethodPost, "/api/sync/coinbase", token, "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", rec.Code, rec.Body)
+	}
+	scoped := s.ForUser(models.DefaultUserID)
+	snapshots, err := scoped.GetNetWorthSnapshots(ctx, time.Time{}, time.Time{})
+	if err != nil || len(snapshots) != 0 {
+		t.Errorf("snapshots = %d (%v), want none after a failed save", len(snapshots), err)
+	}
+	record, err := scoped.GetSyncRecord(ctx, models.PlatformCoinbase)
+	if err != nil || record.Status != models.SyncStatusFailed || record.LastSync != nil {
+		t.Errorf("sync record = %+v (%v), want a failure and no successful sync", record, err)
+	}
+}
+
+func TestSyncPlatformTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	timedOut := fmt.Errorf("sync cancelled: %w", context.DeadlineExceeded)
+	router, token := newSyncRouter(t, s, &fakeCoinbase{err: timedOut})
+
+	rec := doRequest(t, router, http.MethodPost, "/api/sync/coinbase", token, "")
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504: %s", rec.Code, rec.Body)
+	}
+	scoped := s.ForUser(models.DefaultUserID)
+	record, err := scoped.GetSyncRecord(ctx, models.PlatformCoinbase)
+	if err != nil || record.Status != models.SyncStatusFailed {
+		t.Errorf("sync record = %+v (%v), want a failure", record, err)
+	}
+	if _, err := scoped.GetPortfolioByID(ctx, "p1"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("portfolio after a timed out sync: %v, want ErrNotFound", err)
+	}
+}
+
+// disconnectingCoinbase cancels the request it syncs for once the data is fetched, as a client
+// going away mid-sync does
+type disconnectingCoinbase struct {
+	fakeCoinbase
+	cancel context.CancelFunc
+}
+
+func (d *disconnectingCoinbase) SyncAll(ctx context.Context, opts coinbase.SyncOptions) (*models.SyncResult, error) {
+	d.cancel()
+	return d.fakeCoinbase.SyncAll(ctx, opts)
+}
+
+func TestSyncPlatformSavesAfterClientGoesAway(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	router, token := newSyncRouter(t, s, &disconnectingCoinbase{fakeCoinbase: fakeCoinbase{result: testSyncResult(60000)}, cancel: cancel})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/sync/coinbase", nil).WithContext(reqCtx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The fetched sync is saved in full despite the cancelled request
+	scoped := s.ForUser(models.DefaultUserID)
+	if _, err := scoped.GetPortfolioByID(ctx, "p1"); err != nil {
+		t.Errorf("synced portfolio: %v", err)
+	}
+	investments, err := scoped.GetInvestmentsByPlatform(ctx, models.PlatformCoinbase, store.InvestmentFilter{})
+	if err != nil || len(investments) != 1 {
+		t.Errorf("synced investments = %d (%v), want 1", len(investments), err)
+	}
+	if transactions, err := scoped.GetTransactionsByAccount(ctx, "a1"); err != nil || len(transactions) != 1 {
+		t.Errorf("synced transactions = %d (%v), want 1", len(transactions), err)
+	}
+	record, err := scoped.GetSyncRecord(ctx, models.PlatformCoinbase)
+	if err != nil || record.Status != models.SyncStatusSuccess {
+		t.Errorf("sync record = %+v (%v), want a success", record, err)
+	}
+}
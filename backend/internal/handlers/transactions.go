@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransactionsHandler handles transaction-related HTTP requests
+type TransactionsHandler struct {
+	store store.Store
+}
+
+// NewTransactionsHandler creates a new transactions handler
+func NewTransactionsHandler(store store.Store) *TransactionsHandler {
+	return &TransactionsHandler{
+		store: store,
+	}
+}
+
+// GetTransactions returns transactions matching the query filters, newest first
+func (h *TransactionsHandler) GetTransactions(c *gin.Context) {
+	filter, err := parseTransactionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	transactions, total, err := userStore(c, h.store).ListTransactions(c.Request.Context(), filter)
+	if err != nil {
+		respondStoreError(c, err, "list transactions", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": transactions,
+		"total_count":  total,
+		"limit":        filter.Limit,
+		"offset":       filter.Offset,
+	})
+}
+
+// GetTransactionSummary returns per-currency totals by transaction type for a calendar year
+func (h *TransactionsHandler) GetTransactionSummary(c *gin.Context) {
+	year := time.Now().UTC().Year()
+	if yearStr := c.Query("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid year parameter",
+			})
+			return
+		}
+		year = parsed
+	}
+
+	summary, err := userStore(c, h.store).GetTransactionSummary(c.Request.Context(), year)
+	if err != nil {
+		respondStoreError(c, err, "summarize transactions", "")
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// parseTransactionFilter builds a store filter from the request query string
+func parseTransactionFilter(c *gin.Context) (store.TransactionFilter, error) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return store.TransactionFilter{}, err
+	}
+	filter := store.TransactionFilter{
+		AccountID:   c.Query("account_id"),
+		Symbol:      c.Query("symbol"),
+		ListOptions: opts,
+	}
+
+	if platformStr := c.Query("platform"); platformStr != "" {
+		platform, err := models.ParsePlatform(platformStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.Platform = platform
+	}
+
+	if typeStr := c.Query("type"); typeStr != "" {
+		txType := models.TransactionType(typeStr)
+		if !txType.IsValid() {
+			return filter, fmt.Errorf("invalid transaction type %q", typeStr)
+		}
+		filter.Type = txType
+	}
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err := parseDateParam(fromStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from parameter: %w", err)
+		}
+		filter.From = from
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		to, err := parseEndDateParam(toStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to parameter: %w", err)
+		}
+		filter.To = to
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+
+	return filter, nil
+}
+
+// parseDateParam accepts either an RFC3339 timestamp or a YYYY-MM-DD date (midnight UTC)
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 timestamp or YYYY-MM-DD date")
+	}
+	return t, nil
+}
+
+// parseEndDateParam parses the exclusive end of a range as parseDateParam does, except that a
+// YYYY-MM-DD date includes that whole day: it ends at the following midnight UTC
+func parseEndDateParam(value string) (time.Time, error) {
+	t, err := parseDateParam(value)
+	if err != nil {
+		return t, err
+	}
+	if _, dateErr := time.Parse("2006-01-02", value); dateErr == nil {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
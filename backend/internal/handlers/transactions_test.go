@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// transactionsPage is the body of GET /api/transactions
+type transactionsPage struct {
+	Transactions []*models.Transaction `json:"transactions"`
+	TotalCount   int                   `json:"total_count"`
+	Limit        int                   `json:"limit"`
+	Offset       int                   `json:"offset"`
+}
+
+// newTransactionsRouter serves the transaction routes of s for a user, whose token it returns
+func newTransactionsRouter(t *testing.T, s store.Store) (*gin.Engine, string) {
+	t.Helper()
+	token := addTestUser(t, s, models.DefaultUserID)
+	h := NewTransactionsHandler(s)
+	router := newTestRouter(s)
+	router.GET("/api/transactions", h.GetTransactions)
+	return router, token
+}
+
+// seedTransactions stores n Coinbase buys of BTC, t0 to tn-1, one day apart from 2024-05-01
+func seedTransactions(t *testing.T, s store.Store, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := s.ForUser(models.DefaultUserID).CreateOrUpdateTransaction(context.Background(), &models.Transaction{
+			ID:        fmt.Sprintf("t%d", i),
+			AccountID: "a1",
+			Platform:  models.PlatformCoinbase,
+			Type:      models.TransactionTypeBuy,
+			Symbol:    "BTC",
+			Quantity:  1,
+			Amount:    100,
+			Currency:  "USD",
+			Timestamp: time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func pageIDs(transactions []*models.Transaction) []string {
+	ids := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		ids = append(ids, transaction.ID)
+	}
+	return ids
+}
+
+func TestGetTransactionsPages(t *testing.T) {
+	s := store.NewStore()
+	router, token := newTransactionsRouter(t, s)
+	seedTransactions(t, s, 5)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"limit=2", []string{"t4", "t3"}},
+		{"limit=2&offset=2", []string{"t2", "t1"}},
+		{"limit=2&offset=4", []string{"t0"}}, // the last page is short
+		{"limit=2&offset=5", []string{}},     // past the end
+		{"", []string{"t4", "t3", "t2", "t1", "t0"}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, router, http.MethodGet, "/api/transactions?"+tt.query, token, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want 200: %s", tt.query, rec.Code, rec.Body)
+		}
+		var page transactionsPage
+		decodeJSON(t, rec, &page)
+		if got := pageIDs(page.Transactions); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: transactions = %v, want %v", tt.query, got, tt.want)
+		}
+		if page.TotalCount != 5 {
+			t.Errorf("%q: total_count = %d, want 5", tt.query, page.TotalCount)
+		}
+	}
+}
+
+func TestGetTransactionsInvalidPage(t *testing.T) {
+	router, token := newTransactionsRouter(t, store.NewStore())
+	for _, query := range []string{"limit=0", "limit=-1", "limit=ten", "offset=-1", "offset=x"} {
+		if rec := doRequest(t, router, http.MethodGet, "/api/transactions?"+query, token, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	s := store.NewStore()
+	router, token := newTransactionsRouter(t, s)
+	scoped := s.ForUser(models.DefaultUserID)
+	for _, transaction := range []*models.Transaction{
+		{ID: "cb-buy", AccountID: "cb", Platform: models.PlatformCoinbase, Type: models.TransactionTypeBuy, Symbol: "BTC", Timestamp: time.Date(2024, 5, 31, 23, 30, 0, 0, time.UTC)},
+		{ID: "cb-sell", AccountID: "cb", Platform: models.PlatformCoinbase, Type: models.TransactionTypeSell, Symbol: "BTC", Timestamp: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "cb-eth", AccountID: "cb", Platform: models.PlatformCoinbase, Type: models.TransactionTypeBuy, Symbol: "ETH", Timestamp: time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)},
+		{ID: "m1-buy", AccountID: "m1", Platform: models.PlatformM1Finance, Type: models.TransactionTypeBuy, Symbol: "VTI", Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		transaction.Currency = "USD"
+		if err := scoped.CreateOrUpdateTransaction(context.Background(), transaction); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"platform=coinbase", []string{"cb-sell", "cb-buy", "cb-eth"}},
+		{"platform=coinbase&type=buy", []string{"cb-buy", "cb-eth"}},
+		{"platform=coinbase&type=buy&symbol=BTC", []string{"cb-buy"}},
+		{"account_id=m1&type=buy", []string{"m1-buy"}},
+		// A to date includes the whole day, and a from date starts at its midnight
+		{"from=2024-05-01&to=2024-05-31", []string{"cb-buy", "cb-eth", "m1-buy"}},
+		{"from=2024-05-31&to=2024-05-31", []string{"cb-buy"}},
+		// A to timestamp is exclusive
+		{"to=2024-05-31T23:30:00Z", []string{"cb-eth", "m1-buy"}},
+		{"from=2024-06-01T00:00:00Z&type=sell&platform=coinbase", []string{"cb-sell"}},
+		// Empty ranges and filters that match nothing
+		{"from=2024-05-31T23:30:00Z&to=2024-05-31T23:30:00Z", []string{}},
+		{"from=2024-07-01", []string{}},
+		{"platform=m1_finance&symbol=BTC", []string{}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, router, http.MethodGet, "/api/transactions?"+tt.query, token, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, want 200: %s", tt.query, rec.Code, rec.Body)
+		}
+		var page transactionsPage
+		decodeJSON(t, rec, &page)
+		if got := pageIDs(page.Transactions); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: transactions = %v, want %v", tt.query, got, tt.want)
+		}
+		if page.TotalCount != len(tt.want) {
+			t.Errorf("%q: total_count = %d, want %d", tt.query, page.TotalCount, len(tt.want))
+		}
+	}
+
+	for _, query := range []string{"platform=kraken", "type=gift", "from=May", "to=2024-13-01", "from=2024-06-01&to=2024-05-01"} {
+		if rec := doRequest(t, router, http.MethodGet, "/api/transactions?"+query, token, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
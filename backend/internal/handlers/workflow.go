@@ -1,17 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gin-gonic/gin"
 
+	"0xnetworth/backend/internal/auth"
 	"0xnetworth/backend/internal/integrations/youtube"
 	"0xnetworth/backend/internal/models"
 	"0xnetworth/backend/internal/store"
@@ -28,14 +30,17 @@ type WorkflowHandler struct {
 	store    store.Store
 	engine   *workflow.Engine
 	scheduler *workflow.Scheduler
+	youtubeClient *youtube.Client // nil when YOUTUBE_API_KEY is not set
 }
 
-// NewWorkflowHandler creates a new workflow handler
-func NewWorkflowHandler(store store.Store, engine *workflow.Engine, scheduler *workflow.Scheduler) *WorkflowHandler {
+// NewWorkflowHandler creates a new workflow handler. youtubeClient is nil when YouTube is not
+// configured.
+func NewWorkflowHandler(store store.Store, engine *workflow.Engine, scheduler *workflow.Scheduler, youtubeClient *youtube.Client) *WorkflowHandler {
 	return &WorkflowHandler{
-		store:     store,
-		engine:    engine,
-		scheduler: scheduler,
+		store:         store,
+		engine:        engine,
+		scheduler:     scheduler,
+		youtubeClient: youtubeClient,
 	}
 }
 
@@ -53,7 +58,7 @@ func (h *WorkflowHandler) ExecuteWorkflow(c *gin.Context) {
 		return
 	}
 
-	execution, err := h.engine.ExecuteWorkflow(req.YouTubeURL, req.SourceID)
+	execution, err := h.engine.ForUser(auth.UserID(c)).ExecuteWorkflow(c.Request.Context(), req.YouTubeURL, req.SourceID)
 	if err != nil {
 		log.Printf("Error executing workflow: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -66,24 +71,227 @@ func (h *WorkflowHandler) ExecuteWorkflow(c *gin.Context) {
 }
 
 // GetWorkflowExecutions handles GET /api/workflow/executions
+// Optional ?status=, ?source_id=, ?video_id=, ?completed_after= and ?limit= parameters narrow the results,
+// and ?sort= and ?order= change their order (see parseSortOption).
+// The X-Total-Count header gives the number of matching executions regardless of the limit.
+// With ?paginated=true or a ?cursor= the response is a page instead (see getWorkflowExecutionPage).
 func (h *WorkflowHandler) GetWorkflowExecutions(c *gin.Context) {
-	executions := h.store.GetAllWorkflowExecutions()
+	scoped := userStore(c, h.store)
+	filter, err := parseExecutionFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	paginated := c.Query("cursor") != ""
+	if paginatedStr := c.Query("paginated"); paginatedStr != "" {
+		value, err := strconv.ParseBool(paginatedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "paginated must be true or false"})
+			return
+		}
+		paginated = paginated || value
+	}
+	if paginated {
+		h.getWorkflowExecutionPage(c, filter)
+		return
+	}
+
+	executions, err := scoped.ListWorkflowExecutions(c.Request.Context(), filter)
+	if err != nil {
+		respondStoreError(c, err, "get workflow executions", "")
+		return
+	}
+
+	// The body stays a bare array, so the total goes in a header. Only a full page can
+	// have more matches beyond it.
+	total := len(executions)
+	if filter.Limit > 0 && total == filter.Limit {
+		total, err = scoped.CountWorkflowExecutions(c.Request.Context(), filter)
+		if err != nil {
+			respondStoreError(c, err, "count workflow executions", "")
+			return
+		}
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, executions)
 }
 
+// getWorkflowExecutionPage responds with {items, next_cursor}: up to ?limit= executions (default
+// defaultListLimit), newest first, after ?cursor=. Pass next_cursor back as ?cursor= for the
+// next page; it is null on the last one. Pages are found by keyset, so they cannot be sorted.
+func (h *WorkflowHandler) getWorkflowExecutionPage(c *gin.Context, filter store.ExecutionFilter) {
+	if filter.Sort.Field != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort cannot be combined with pagination"})
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+
+	executions, next, err := userStore(c, h.store).PageWorkflowExecutions(c.Request.Context(), filter, c.Query("cursor"))
+	if errors.Is(err, store.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		respondStoreError(c, err, "get workflow executions", "")
+		return
+	}
+	var nextCursor *string
+	if next != "" {
+		nextCursor = &next
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       executions,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseExecutionFilter builds a store filter from the request query string.
+// Without ?limit= every matching execution is returned.
+func parseExecutionFilter(c *gin.Context) (store.ExecutionFilter, error) {
+	filter := store.ExecutionFilter{
+		SourceID: c.Query("source_id"),
+		VideoID:  c.Query("video_id"),
+	}
+
+	if statusStr := c.Query("status"); statusStr != "" {
+		status := models.WorkflowExecutionStatus(statusStr)
+		if !status.IsValid() {
+			return filter, fmt.Errorf("invalid status %q", statusStr)
+		}
+		filter.Status = status
+	}
+
+	if afterStr := c.Query("completed_after"); afterStr != "" {
+		after, err := parseDateParam(afterStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid completed_after parameter: %w", err)
+		}
+		filter.CompletedAfter = after
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return filter, fmt.Errorf("invalid limit parameter")
+		}
+		if limit > maxListLimit {
+			limit = maxListLimit
+		}
+		filter.Limit = limit
+	}
+
+	order, err := parseSortOption(c, store.ExecutionSortFields())
+	if err != nil {
+		return filter, err
+	}
+	filter.Sort = order
+
+	return filter, nil
+}
+
 // GetWorkflowExecution handles GET /api/workflow/executions/:id
 func (h *WorkflowHandler) GetWorkflowExecution(c *gin.Context) {
 	id := c.Param("id")
 	
-	execution, exists := h.store.GetWorkflowExecutionByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+	execution, err := userStore(c, h.store).GetWorkflowExecutionByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get workflow execution", "execution not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, execution)
 }
 
+// DeleteWorkflowExecution handles DELETE /api/workflow/executions/:id
+// With ?cascade=true the execution's transcript, analysis and recommendation are deleted too.
+// Executions that are still pending or processing cannot be deleted.
+func (h *WorkflowHandler) DeleteWorkflowExecution(c *gin.Context) {
+	scoped := userStore(c, h.store)
+	id := c.Param("id")
+
+	cascade := false
+	if cascadeStr := c.Query("cascade"); cascadeStr != "" {
+		parsed, err := strconv.ParseBool(cascadeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cascade must be true or false"})
+			return
+		}
+		cascade = parsed
+	}
+
+	execution, err := scoped.GetWorkflowExecutionByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get workflow execution", "execution not found")
+		return
+	}
+	// The engine would recreate a running execution when it next saves progress
+	if execution.Status == models.WorkflowStatusPending || execution.Status == models.WorkflowStatusProcessing {
+		c.JSON(http.StatusConflict, gin.H{"error": "execution is still " + string(execution.Status)})
+		return
+	}
+
+	if err := scoped.DeleteWorkflowExecution(c.Request.Context(), id, cascade); err != nil {
+		respondStoreError(c, err, "delete workflow execution", "execution not found")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// PruneWorkflowExecutions handles POST /api/workflow/executions/prune
+// Deletes executions with ?status= (default failed) that started more than ?older_than_days= ago
+// (default 0, any age), keeping the ?keep= (default 50) most recent of each source. Their
+// transcripts, analyses and recommendations are deleted too unless another execution uses them.
+func (h *WorkflowHandler) PruneWorkflowExecutions(c *gin.Context) {
+	status := models.WorkflowExecutionStatus(c.DefaultQuery("status", string(models.WorkflowStatusFailed)))
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status: " + string(status)})
+		return
+	}
+	// The engine would recreate a running execution when it next saves progress
+	if status == models.WorkflowStatusPending || status == models.WorkflowStatusProcessing {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot prune executions that are still " + string(status)})
+		return
+	}
+
+	keep := workflow.DefaultKeepFailedExecutions
+	if keepStr := c.Query("keep"); keepStr != "" {
+		k, err := strconv.Atoi(keepStr)
+		if err != nil || k < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keep must be a non-negative integer"})
+			return
+		}
+		keep = k
+	}
+
+	var olderThan time.Time
+	if daysStr := c.Query("older_than_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "older_than_days must be a non-negative integer"})
+			return
+		}
+		if days > 0 {
+			olderThan = models.Now().AddDate(0, 0, -days)
+		}
+	}
+
+	deleted, err := userStore(c, h.store).PruneWorkflowExecutions(c.Request.Context(), status, olderThan, keep)
+	if err != nil {
+		respondStoreError(c, err, "prune workflow executions", "")
+		return
+	}
+	log.Printf("Pruned %d %s workflow executions", deleted, status)
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+		"status":  status,
+		"keep":    keep,
+	})
+}
+
 // CreateYouTubeSourceRequest represents the request body for creating a YouTube source
 type CreateYouTubeSourceRequest struct {
 	Type     models.YouTubeSourceType `json:"type" binding:"required"`
@@ -91,6 +299,26 @@ type CreateYouTubeSourceRequest struct {
 	Name     string                   `json:"name" binding:"required"`
 	Enabled  bool                     `json:"enabled"`
 	Schedule string                   `json:"schedule,omitempty"`
+	// Title filters, see models.YouTubeSource. An update leaves a filter that is left out
+	// unchanged; an empty list clears it.
+	TitleIncludes []string `json:"title_includes,omitempty"`
+	TitleExcludes []string `json:"title_excludes,omitempty"`
+	RecordSkipped *bool    `json:"record_skipped,omitempty"`
+}
+
+// applyTitleFilters sets the title filters of req on source and validates them
+func (req *CreateYouTubeSourceRequest) applyTitleFilters(source *models.YouTubeSource) error {
+	if req.TitleIncludes != nil {
+		source.TitleIncludes = req.TitleIncludes
+	}
+	if req.TitleExcludes != nil {
+		source.TitleExcludes = req.TitleExcludes
+	}
+	if req.RecordSkipped != nil {
+		source.RecordSkipped = *req.RecordSkipped
+	}
+	_, err := source.TitleFilter()
+	return err
 }
 
 // CreateYouTubeSource handles POST /api/workflow/sources
@@ -108,14 +336,22 @@ func (h *WorkflowHandler) CreateYouTubeSource(c *gin.Context) {
 		Name:      req.Name,
 		Enabled:   req.Enabled,
 		Schedule:  req.Schedule,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		CreatedAt: models.Now(),
 	}
+	if err := req.applyTitleFilters(source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.resolveChannelID(c.Request.Context(), source)
 
-	h.store.CreateOrUpdateYouTubeSource(source)
+	if err := userStore(c, h.store).CreateOrUpdateYouTubeSource(c.Request.Context(), source); err != nil {
+		respondStoreError(c, err, "create source", "")
+		return
+	}
 	
 	// Schedule the source if it's enabled
 	if h.scheduler != nil && source.Enabled {
-		if err := h.scheduler.ReloadSourceSchedule(source.ID); err != nil {
+		if err := h.scheduler.ReloadSourceSchedule(c.Request.Context(), auth.UserID(c), source.ID); err != nil {
 			log.Printf("Failed to schedule newly created source %s: %v", source.ID, err)
 			// Don't fail the request, just log the error
 		}
@@ -125,8 +361,24 @@ func (h *WorkflowHandler) CreateYouTubeSource(c *gin.Context) {
 }
 
 // GetYouTubeSources handles GET /api/workflow/sources
+// Optional query parameter: type (channel or playlist)
 func (h *WorkflowHandler) GetYouTubeSources(c *gin.Context) {
-	sources := h.store.GetAllYouTubeSources()
+	scoped := userStore(c, h.store)
+	var sources []*models.YouTubeSource
+	var err error
+	switch sourceType := models.YouTubeSourceType(c.Query("type")); sourceType {
+	case "":
+		sources, err = scoped.GetAllYouTubeSources(c.Request.Context())
+	case models.YouTubeSourceTypeChannel, models.YouTubeSourceTypePlaylist:
+		sources, err = scoped.GetYouTubeSourcesByType(c.Request.Context(), sourceType)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be channel or playlist"})
+		return
+	}
+	if err != nil {
+		respondStoreError(c, err, "get sources", "")
+		return
+	}
 	c.JSON(http.StatusOK, sources)
 }
 
@@ -134,9 +386,9 @@ func (h *WorkflowHandler) GetYouTubeSources(c *gin.Context) {
 func (h *WorkflowHandler) GetYouTubeSource(c *gin.Context) {
 	id := c.Param("id")
 	
-	source, exists := h.store.GetYouTubeSourceByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "source not found"})
+	source, err := userStore(c, h.store).GetYouTubeSourceByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get source", "source not found")
 		return
 	}
 
@@ -147,8 +399,8 @@ func (h *WorkflowHandler) GetYouTubeSource(c *gin.Context) {
 func (h *WorkflowHandler) DeleteYouTubeSource(c *gin.Context) {
 	id := c.Param("id")
 	
-	if !h.store.DeleteYouTubeSource(id) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "source not found"})
+	if err := userStore(c, h.store).DeleteYouTubeSource(c.Request.Context(), id); err != nil {
+		respondStoreError(c, err, "delete source", "source not found")
 		return
 	}
 
@@ -162,11 +414,12 @@ type UpdateSourceScheduleRequest struct {
 
 // UpdateSourceSchedule handles POST /api/workflow/sources/:id/schedule
 func (h *WorkflowHandler) UpdateSourceSchedule(c *gin.Context) {
+	scoped := userStore(c, h.store)
 	id := c.Param("id")
 	
-	source, exists := h.store.GetYouTubeSourceByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "source not found"})
+	source, err := scoped.GetYouTubeSourceByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get source", "source not found")
 		return
 	}
 
@@ -177,11 +430,14 @@ func (h *WorkflowHandler) UpdateSourceSchedule(c *gin.Context) {
 	}
 
 	source.Schedule = req.Schedule
-	h.store.CreateOrUpdateYouTubeSource(source)
+	if err := scoped.CreateOrUpdateYouTubeSource(c.Request.Context(), source); err != nil {
+		respondStoreError(c, err, "update source", "")
+		return
+	}
 
 	// Reload the schedule in the scheduler
 	if h.scheduler != nil {
-		if err := h.scheduler.ReloadSourceSchedule(id); err != nil {
+		if err := h.scheduler.ReloadSourceSchedule(c.Request.Context(), auth.UserID(c), id); err != nil {
 			log.Printf("Failed to reload schedule for source %s: %v", id, err)
 			// Don't fail the request, just log the error
 		}
@@ -192,11 +448,12 @@ func (h *WorkflowHandler) UpdateSourceSchedule(c *gin.Context) {
 
 // UpdateYouTubeSource handles PUT /api/workflow/sources/:id
 func (h *WorkflowHandler) UpdateYouTubeSource(c *gin.Context) {
+	scoped := userStore(c, h.store)
 	id := c.Param("id")
 	
-	source, exists := h.store.GetYouTubeSourceByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "source not found"})
+	source, err := scoped.GetYouTubeSourceByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get source", "source not found")
 		return
 	}
 
@@ -206,6 +463,11 @@ func (h *WorkflowHandler) UpdateYouTubeSource(c *gin.Context) {
 		return
 	}
 
+	// The resolved channel ID belongs to the old URL
+	if req.Type != source.Type || req.URL != source.URL {
+		source.ChannelID = ""
+	}
+
 	// Update source fields
 	source.Type = req.Type
 	source.URL = req.URL
@@ -214,12 +476,20 @@ func (h *WorkflowHandler) UpdateYouTubeSource(c *gin.Context) {
 	if req.Schedule != "" {
 		source.Schedule = req.Schedule
 	}
+	if err := req.applyTitleFilters(source); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.resolveChannelID(c.Request.Context(), source)
 
-	h.store.CreateOrUpdateYouTubeSource(source)
+	if err := scoped.CreateOrUpdateYouTubeSource(c.Request.Context(), source); err != nil {
+		respondStoreError(c, err, "update source", "")
+		return
+	}
 	
 	// Reload the schedule in the scheduler if schedule or enabled status changed
 	if h.scheduler != nil && (req.Schedule != "" || req.Enabled != source.Enabled) {
-		if err := h.scheduler.ReloadSourceSchedule(id); err != nil {
+		if err := h.scheduler.ReloadSourceSchedule(c.Request.Context(), auth.UserID(c), id); err != nil {
 			log.Printf("Failed to reload schedule for source %s: %v", id, err)
 			// Don't fail the request, just log the error
 		}
@@ -228,6 +498,40 @@ func (h *WorkflowHandler) UpdateYouTubeSource(c *gin.Context) {
 	c.JSON(http.StatusOK, source)
 }
 
+// resolveChannelID sets the channel ID of a channel source that has none yet, so the scheduler
+// does not resolve its URL on every run. A failure is only logged; the scheduler tries again.
+func (h *WorkflowHandler) resolveChannelID(ctx context.Context, source *models.YouTubeSource) {
+	if h.youtubeClient == nil || source.Type != models.YouTubeSourceTypeChannel || source.ChannelID != "" {
+		return
+	}
+	channelID, err := h.youtubeClient.ExtractChannelID(ctx, source.URL)
+	if err != nil {
+		log.Printf("Could not resolve the channel ID of source %s from %s: %v", source.ID, source.URL, err)
+		return
+	}
+	source.ChannelID = channelID
+}
+
+// saveResolvedChannelID sets channelID on userID's channel sources of channelURL that have no
+// channel ID yet. A failure is only logged, since the ID can be resolved again.
+func (h *WorkflowHandler) saveResolvedChannelID(ctx context.Context, userID, channelURL, channelID string) {
+	scoped := h.store.ForUser(userID)
+	sources, err := scoped.GetYouTubeSourcesByType(ctx, models.YouTubeSourceTypeChannel)
+	if err != nil {
+		log.Printf("Failed to load sources to save channel ID %s: %v", channelID, err)
+		return
+	}
+	for _, source := range sources {
+		if source.URL != channelURL || source.ChannelID != "" {
+			continue
+		}
+		source.ChannelID = channelID
+		if err := scoped.CreateOrUpdateYouTubeSource(ctx, source); err != nil {
+			log.Printf("Failed to save channel ID of source %s: %v", source.ID, err)
+		}
+	}
+}
+
 // TestYouTubeSourceRequest represents the request body for testing a YouTube source
 type TestYouTubeSourceRequest struct {
 	URL string `json:"url" binding:"required"`
@@ -241,35 +545,35 @@ func (h *WorkflowHandler) TestYouTubeSource(c *gin.Context) {
 		return
 	}
 
-	// Get YouTube client from scheduler (if available)
-	// We need to access the scheduler's youtubeClient
-	// For now, we'll create a temporary client
-	youtubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
-	if youtubeAPIKey == "" {
+	// The shared client, so the test counts against the same quota budget as polling
+	youtubeClient := h.youtubeClient
+	if youtubeClient == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "YouTube API key not configured"})
 		return
 	}
 
-	// Import youtube client
-	youtubeClient := youtube.NewClient(youtubeAPIKey)
-	if youtubeClient == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize YouTube client"})
+	// Try to extract/resolve channel ID
+	channelID, err := youtubeClient.ExtractChannelID(c.Request.Context(), req.URL)
+	if youtube.IsQuotaExceeded(err) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Try to extract/resolve channel ID
-	channelID, err := youtubeClient.ExtractChannelID(req.URL)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Verify the channel exists by trying to fetch videos
-	_, err = youtubeClient.GetChannelVideos(channelID, 1, nil)
+	_, err = youtubeClient.GetChannelUploads(c.Request.Context(), channelID, 1, nil)
+	if youtube.IsQuotaExceeded(err) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Channel not found or inaccessible: %v", err)})
 		return
 	}
+	h.saveResolvedChannelID(c.Request.Context(), auth.UserID(c), req.URL, channelID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -285,7 +589,11 @@ func (h *WorkflowHandler) TriggerAllSources(c *gin.Context) {
 		return
 	}
 	
-	triggered := h.scheduler.TriggerAllSources()
+	triggered, err := h.scheduler.TriggerAllSources(c.Request.Context(), auth.UserID(c))
+	if err != nil {
+		respondStoreError(c, err, "load sources", "")
+		return
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -299,22 +607,118 @@ func (h *WorkflowHandler) TriggerAllSources(c *gin.Context) {
 func (h *WorkflowHandler) GetTranscript(c *gin.Context) {
 	id := c.Param("id")
 	
-	transcript, exists := h.store.GetTranscriptByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "transcript not found"})
+	transcript, err := userStore(c, h.store).GetTranscriptByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get transcript", "transcript not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, transcript)
 }
 
+// defaultSearchLimit is how many transcripts a search returns without ?limit=
+const defaultSearchLimit = 20
+
+// SearchTranscripts handles GET /api/workflow/transcripts/search?q=
+// Returns the transcripts whose title or text contains every word of q, each with a snippet of
+// the text around the first match and the character offsets of the matches within it.
+// Supports ?limit= (default 20, capped at maxListLimit).
+func (h *WorkflowHandler) SearchTranscripts(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(l, maxListLimit)
+	}
+
+	results, err := userStore(c, h.store).SearchTranscripts(c.Request.Context(), query, limit)
+	if err != nil {
+		respondStoreError(c, err, "search transcripts", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query":   query,
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// transcriptWithoutText is a transcript listing entry with the text left out. Its empty Text
+// field shadows the embedded one so it is omitted from the JSON.
+type transcriptWithoutText struct {
+	*models.VideoTranscript
+	Text string `json:"text,omitempty"`
+}
+
+// GetSourceTranscripts handles GET /api/workflow/sources/:id/transcripts
+// Returns the source's transcripts newest first. Supports ?limit= (capped at maxListLimit)
+// and ?include_text=false to leave out the transcript text.
+func (h *WorkflowHandler) GetSourceTranscripts(c *gin.Context) {
+	scoped := userStore(c, h.store)
+	id := c.Param("id")
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(l, maxListLimit)
+	}
+
+	includeText := true
+	if includeStr := c.Query("include_text"); includeStr != "" {
+		parsed, err := strconv.ParseBool(includeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "include_text must be true or false"})
+			return
+		}
+		includeText = parsed
+	}
+
+	if _, err := scoped.GetYouTubeSourceByID(c.Request.Context(), id); err != nil {
+		respondStoreError(c, err, "get source", "source not found")
+		return
+	}
+
+	transcripts, err := scoped.GetTranscriptsBySourceID(c.Request.Context(), id, limit)
+	if err != nil {
+		respondStoreError(c, err, "get transcripts", "")
+		return
+	}
+
+	var response interface{} = transcripts
+	if !includeText {
+		entries := make([]transcriptWithoutText, len(transcripts))
+		for i, t := range transcripts {
+			entries[i] = transcriptWithoutText{VideoTranscript: t}
+		}
+		response = entries
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"source_id":   id,
+		"transcripts": response,
+		"count":       len(transcripts),
+	})
+}
+
 // GetMarketAnalysis handles GET /api/workflow/analyses/:id
 func (h *WorkflowHandler) GetMarketAnalysis(c *gin.Context) {
 	id := c.Param("id")
 	
-	analysis, exists := h.store.GetMarketAnalysisByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "analysis not found"})
+	analysis, err := userStore(c, h.store).GetMarketAnalysisByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get analysis", "analysis not found")
 		return
 	}
 
@@ -325,9 +729,9 @@ func (h *WorkflowHandler) GetMarketAnalysis(c *gin.Context) {
 func (h *WorkflowHandler) GetRecommendation(c *gin.Context) {
 	id := c.Param("id")
 	
-	recommendation, exists := h.store.GetRecommendationByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "recommendation not found"})
+	recommendation, err := userStore(c, h.store).GetRecommendationByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get recommendation", "recommendation not found")
 		return
 	}
 
@@ -337,11 +741,12 @@ func (h *WorkflowHandler) GetRecommendation(c *gin.Context) {
 // GetWorkflowExecutionDetails handles GET /api/workflow/executions/:id/details
 // Returns the full execution with all related data (transcript, analysis, recommendation)
 func (h *WorkflowHandler) GetWorkflowExecutionDetails(c *gin.Context) {
+	scoped := userStore(c, h.store)
 	id := c.Param("id")
 	
-	execution, exists := h.store.GetWorkflowExecutionByID(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+	execution, err := scoped.GetWorkflowExecutionByID(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get workflow execution", "execution not found")
 		return
 	}
 
@@ -352,24 +757,36 @@ func (h *WorkflowHandler) GetWorkflowExecutionDetails(c *gin.Context) {
 
 	// Add transcript if available
 	if execution.TranscriptID != "" {
-		transcript, exists := h.store.GetTranscriptByID(execution.TranscriptID)
-		if exists {
+		transcript, err := scoped.GetTranscriptByID(c.Request.Context(), execution.TranscriptID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			respondStoreError(c, err, "get transcript", "")
+			return
+		}
+		if err == nil {
 			response["transcript"] = transcript
 		}
 	}
 
 	// Add market analysis if available
 	if execution.AnalysisID != "" {
-		analysis, exists := h.store.GetMarketAnalysisByID(execution.AnalysisID)
-		if exists {
+		analysis, err := scoped.GetMarketAnalysisByID(c.Request.Context(), execution.AnalysisID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			respondStoreError(c, err, "get analysis", "")
+			return
+		}
+		if err == nil {
 			response["market_analysis"] = analysis
 		}
 	}
 
 	// Add recommendation if available
 	if execution.RecommendationID != "" {
-		recommendation, exists := h.store.GetRecommendationByID(execution.RecommendationID)
-		if exists {
+		recommendation, err := scoped.GetRecommendationByID(c.Request.Context(), execution.RecommendationID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			respondStoreError(c, err, "get recommendation", "")
+			return
+		}
+		if err == nil {
 			response["recommendation"] = recommendation
 		}
 	}
@@ -377,6 +794,28 @@ func (h *WorkflowHandler) GetWorkflowExecutionDetails(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetWorkflowExecutionEvents handles GET /api/workflow/executions/:id/events
+// Returns the execution's step-level event log, oldest first
+func (h *WorkflowHandler) GetWorkflowExecutionEvents(c *gin.Context) {
+	scoped := userStore(c, h.store)
+	id := c.Param("id")
+
+	if _, err := scoped.GetWorkflowExecutionByID(c.Request.Context(), id); err != nil {
+		respondStoreError(c, err, "get workflow execution", "execution not found")
+		return
+	}
+	events, err := scoped.GetWorkflowExecutionEvents(c.Request.Context(), id)
+	if err != nil {
+		respondStoreError(c, err, "get workflow execution events", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution_id": id,
+		"events":       events,
+	})
+}
+
 // RecommendationsSummary represents aggregated recommendation data
 type RecommendationsSummary struct {
 	TotalCount           int                `json:"total_count"`
@@ -394,6 +833,8 @@ type AggregatedRecommendationResponse struct {
 	SuggestedActions []SuggestedActionResponse `json:"suggested_actions"`
 	Summary          string           `json:"summary"`
 	KeyInsights      []string         `json:"key_insights"`
+	ExecutionIDs     []string         `json:"execution_ids"` // Workflow executions that fed this recommendation
+	GeneratedAt      time.Time        `json:"generated_at,omitzero"`
 }
 
 // SuggestedActionResponse represents a suggested action in the aggregated recommendation
@@ -411,11 +852,12 @@ type RecommendationSummaryItem struct {
 	Action        string  `json:"action"`
 	Confidence    float64 `json:"confidence"`
 	Condition     string  `json:"condition"`
-	CompletedAt   string  `json:"completed_at"`
+	CompletedAt   time.Time `json:"completed_at"`
 }
 
 // GetRecommendationsSummary handles GET /api/workflow/recommendations/summary
 func (h *WorkflowHandler) GetRecommendationsSummary(c *gin.Context) {
+	scoped := userStore(c, h.store)
 	// Get days parameter (default 7)
 	daysStr := c.DefaultQuery("days", "7")
 	days := 7
@@ -426,104 +868,54 @@ func (h *WorkflowHandler) GetRecommendationsSummary(c *gin.Context) {
 	// Calculate cutoff time
 	cutoffTime := time.Now().UTC().AddDate(0, 0, -days)
 	
-	// Get all executions
-	allExecutions := h.store.GetAllWorkflowExecutions()
-	
-	// Filter executions from the past N days with recommendations
-	recentExecutions := make([]*models.WorkflowExecution, 0)
-	// Also collect all completed executions for aggregated summary (regardless of days)
-	allCompletedExecutions := make([]*models.WorkflowExecution, 0)
-	
-	for _, exec := range allExecutions {
-		if exec.Status != models.WorkflowStatusCompleted {
-			continue
-		}
-		if exec.RecommendationID == "" {
-			continue
-		}
-		if exec.CompletedAt == "" {
-			continue
-		}
-		
-		completedAt, err := time.Parse(time.RFC3339, exec.CompletedAt)
-		if err != nil {
-			log.Printf("Warning: Invalid CompletedAt timestamp for execution %s: %v", exec.ID, err)
-			continue
-		}
-		
-		// Add to all completed for aggregated summary
-		allCompletedExecutions = append(allCompletedExecutions, exec)
-		
-		if completedAt.After(cutoffTime) {
-			recentExecutions = append(recentExecutions, exec)
-		}
+	// Completed executions from the past N days, joined with their recommendation and analysis
+	rows, err := scoped.GetRecommendationSummaryData(c.Request.Context(), cutoffTime)
+	if err != nil {
+		respondStoreError(c, err, "get recommendation summary data", "")
+		return
 	}
 	
 	// Build summary
 	summary := RecommendationsSummary{
-		TotalCount:          len(recentExecutions),
+		TotalCount:          len(rows),
 		ActionDistribution:  make(map[string]int),
 		ConditionDistribution: make(map[string]int),
-		RecentRecommendations: make([]RecommendationSummaryItem, 0, len(recentExecutions)),
 	}
 	
 	totalConfidence := 0.0
 	validConfidenceCount := 0
 	
-	// Collect all recommendation items first
-	allRecommendationItems := make([]RecommendationSummaryItem, 0, len(recentExecutions))
-	
-	// Process each execution
-	for _, exec := range recentExecutions {
-		// Get recommendation
-		rec, exists := h.store.GetRecommendationByID(exec.RecommendationID)
-		if !exists {
-			continue
-		}
-		
-		// Get market analysis for condition
+	// Rows arrive newest first, so the first few are the recent recommendations
+	recentItems := make([]RecommendationSummaryItem, 0, min(len(rows), RecentRecommendationsLimit))
+	for _, row := range rows {
 		condition := "unknown"
-		if exec.AnalysisID != "" {
-			analysis, exists := h.store.GetMarketAnalysisByID(exec.AnalysisID)
-			if exists {
-				condition = analysis.Conditions
-				summary.ConditionDistribution[condition]++
-			}
+		if row.HasAnalysis {
+			condition = row.Condition
+			summary.ConditionDistribution[condition]++
 		}
 		
 		// Track action distribution
-		summary.ActionDistribution[rec.Action]++
+		summary.ActionDistribution[row.Action]++
 		
 		// Track confidence
-		if rec.Confidence > 0 {
-			totalConfidence += rec.Confidence
+		if row.Confidence > 0 {
+			totalConfidence += row.Confidence
 			validConfidenceCount++
 		}
 		
-		// Collect all items for sorting
-		item := RecommendationSummaryItem{
-			ExecutionID: exec.ID,
-			VideoTitle:   exec.VideoTitle,
-			VideoID:      exec.VideoID,
-			Action:       rec.Action,
-			Confidence:   rec.Confidence,
-			Condition:   condition,
-			CompletedAt:  exec.CompletedAt,
-		}
-		allRecommendationItems = append(allRecommendationItems, item)
-	}
-	
-	// Sort by completed_at (newest first), then take top N
-	sort.Slice(allRecommendationItems, func(i, j int) bool {
-		return allRecommendationItems[i].CompletedAt > allRecommendationItems[j].CompletedAt
-	})
-	
-	// Take top N most recent
-	limit := RecentRecommendationsLimit
-	if len(allRecommendationItems) < limit {
-		limit = len(allRecommendationItems)
+		if len(recentItems) < RecentRecommendationsLimit {
+			recentItems = append(recentItems, RecommendationSummaryItem{
+				ExecutionID: row.ExecutionID,
+				VideoTitle:   row.VideoTitle,
+				VideoID:      row.VideoID,
+				Action:       row.Action,
+				Confidence:   row.Confidence,
+				Condition:   condition,
+				CompletedAt:  row.CompletedAt,
+			})
+		}
 	}
-	summary.RecentRecommendations = allRecommendationItems[:limit]
+	summary.RecentRecommendations = recentItems
 	
 	// Calculate average confidence
 	if validConfidenceCount > 0 {
@@ -531,8 +923,12 @@ func (h *WorkflowHandler) GetRecommendationsSummary(c *gin.Context) {
 	}
 	
 	// Get cached aggregated recommendation if it exists (don't auto-generate)
-	cachedRec, exists := h.store.GetLatestAggregatedRecommendation()
-	if exists {
+	cachedRec, err := scoped.GetLatestAggregatedRecommendation(c.Request.Context())
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		respondStoreError(c, err, "get aggregated recommendation", "")
+		return
+	}
+	if err == nil {
 		// Convert to response format
 		suggestedActions := make([]SuggestedActionResponse, len(cachedRec.SuggestedActions))
 		for i, sa := range cachedRec.SuggestedActions {
@@ -549,6 +945,8 @@ func (h *WorkflowHandler) GetRecommendationsSummary(c *gin.Context) {
 			SuggestedActions: suggestedActions,
 			Summary:          cachedRec.Summary,
 			KeyInsights:      cachedRec.KeyInsights,
+			ExecutionIDs:     cachedRec.ExecutionIDs,
+			GeneratedAt:      cachedRec.GeneratedAt,
 		}
 	}
 	
@@ -556,28 +954,39 @@ func (h *WorkflowHandler) GetRecommendationsSummary(c *gin.Context) {
 }
 
 // GenerateAggregatedRecommendation handles POST /api/workflow/recommendations/aggregate
-// Manually triggers generation of aggregated recommendation from the last 10 videos
+// Manually triggers generation of aggregated recommendation from the last 10 videos.
+// An optional ?days= parameter restricts the input to executions completed within that window.
 func (h *WorkflowHandler) GenerateAggregatedRecommendation(c *gin.Context) {
-	// Get all completed workflow executions
-	allExecutions := h.store.GetAllWorkflowExecutions()
-	
-	// Filter to only completed executions
-	allCompletedExecutions := make([]*models.WorkflowExecution, 0)
-	for _, exec := range allExecutions {
-		if exec.Status == models.WorkflowStatusCompleted {
-			allCompletedExecutions = append(allCompletedExecutions, exec)
+	windowDays := 0
+	if daysStr := c.Query("days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive integer"})
+			return
 		}
+		windowDays = d
+	}
+
+	// Get the most recent completed executions (within the window, if one was requested)
+	var since time.Time
+	if windowDays > 0 {
+		since = time.Now().UTC().AddDate(0, 0, -windowDays)
+	}
+	recentExecutions, err := userStore(c, h.store).GetCompletedWorkflowExecutionsSince(c.Request.Context(), since, aggregateExecutionLimit)
+	if err != nil {
+		respondStoreError(c, err, "get workflow executions", "")
+		return
 	}
 	
-	if len(allCompletedExecutions) == 0 {
+	if len(recentExecutions) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No completed workflow executions found. Process some videos first.",
+			"error": "No completed workflow executions with a recommendation found. Process some videos first.",
 		})
 		return
 	}
 	
 	// Generate aggregated recommendation
-	aggregatedRec, err := h.generateAggregatedRecommendation(allCompletedExecutions)
+	aggregatedRec, err := h.generateAggregatedRecommendation(c.Request.Context(), auth.UserID(c), recentExecutions, windowDays)
 	if err != nil {
 		log.Printf("Failed to generate aggregated recommendation: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -590,52 +999,49 @@ func (h *WorkflowHandler) GenerateAggregatedRecommendation(c *gin.Context) {
 	c.JSON(http.StatusOK, aggregatedRec)
 }
 
-// generateAggregatedRecommendation creates an AI-powered consolidated recommendation from the most recent 10 completed workflow executions
-func (h *WorkflowHandler) generateAggregatedRecommendation(executions []*models.WorkflowExecution) (*AggregatedRecommendationResponse, error) {
-	if len(executions) == 0 {
+// aggregateExecutionLimit is how many of the most recent completed executions an aggregated
+// recommendation is generated from
+const aggregateExecutionLimit = 10
+
+// generateAggregatedRecommendation creates an AI-powered consolidated recommendation from userID's recent completed workflow executions
+func (h *WorkflowHandler) generateAggregatedRecommendation(ctx context.Context, userID string, recentExecutions []*models.WorkflowExecution, windowDays int) (*AggregatedRecommendationResponse, error) {
+	if len(recentExecutions) == 0 {
 		return nil, fmt.Errorf("no workflow executions provided")
 	}
 	
-	// Sort by completed_at (newest first)
-	sort.Slice(executions, func(i, j int) bool {
-		if executions[i].CompletedAt == "" || executions[j].CompletedAt == "" {
-			return false
-		}
-		return executions[i].CompletedAt > executions[j].CompletedAt
-	})
-	
-	// Take the most recent 10
-	limit := 10
-	if len(executions) < limit {
-		limit = len(executions)
-	}
-	recentExecutions := executions[:limit]
-	
 	// Build portfolio context
-	portfolioContext := h.engine.BuildPortfolioContext()
+	engine := h.engine.ForUser(userID)
+	portfolioContext := engine.BuildPortfolioContext(ctx)
 	
 	// Call engine to generate aggregated recommendation
-	aggregatedRec, err := h.engine.GenerateAggregatedRecommendation(recentExecutions, portfolioContext)
+	aggregatedRec, err := engine.GenerateAggregatedRecommendation(ctx, recentExecutions, portfolioContext)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate aggregated recommendation: %w", err)
 	}
 	
-	// Get execution IDs for storage
+	// Get execution IDs and distinct sources for storage
 	currentExecutionIDs := make([]string, len(recentExecutions))
+	sourceIDs := make(map[string]bool)
 	for i, exec := range recentExecutions {
 		currentExecutionIDs[i] = exec.ID
+		if exec.SourceID != "" {
+			sourceIDs[exec.SourceID] = true
+		}
 	}
 	
-	// Store the aggregated recommendation with execution IDs
-	recID := "latest" // Use a fixed ID so we always update the same record
+	// Store each generation as a new record so the history is preserved
+	generatedAt := models.Now()
 	storedRec := &models.AggregatedRecommendation{
-		ID:              recID,
-		Action:          aggregatedRec.Action,
-		Confidence:      aggregatedRec.Confidence,
+		ID:               uuid.New().String(),
+		Action:           aggregatedRec.Action,
+		Confidence:       aggregatedRec.Confidence,
 		SuggestedActions: make([]models.SuggestedAction, len(aggregatedRec.SuggestedActions)),
-		Summary:         aggregatedRec.Summary,
-		KeyInsights:     aggregatedRec.KeyInsights,
-		ExecutionIDs:   currentExecutionIDs,
+		Summary:          aggregatedRec.Summary,
+		KeyInsights:      aggregatedRec.KeyInsights,
+		ExecutionIDs:     currentExecutionIDs,
+		GeneratedAt:      generatedAt,
+		WindowDays:       windowDays,
+		SourceCount:      len(sourceIDs),
 	}
 	
 	for i, sa := range aggregatedRec.SuggestedActions {
@@ -646,9 +1052,8 @@ func (h *WorkflowHandler) generateAggregatedRecommendation(executions []*models.
 		}
 	}
 	
-	if err := h.store.CreateOrUpdateAggregatedRecommendation(storedRec); err != nil {
-		log.Printf("Failed to store aggregated recommendation: %v", err)
-		// Continue anyway, we still return the recommendation
+	if err := h.store.ForUser(userID).CreateOrUpdateAggregatedRecommendation(ctx, storedRec); err != nil {
+		return nil, fmt.Errorf("failed to store aggregated recommendation: %w", err)
 	}
 	
 	// Convert to response format
@@ -667,9 +1072,35 @@ func (h *WorkflowHandler) generateAggregatedRecommendation(executions []*models.
 		SuggestedActions: suggestedActions,
 		Summary:          aggregatedRec.Summary,
 		KeyInsights:      aggregatedRec.KeyInsights,
+		ExecutionIDs:     currentExecutionIDs,
+		GeneratedAt:      generatedAt,
 	}, nil
 }
 
+// GetAggregatedRecommendationHistory handles GET /api/workflow/recommendations/aggregated
+// Returns previously generated aggregated recommendations, newest first (default limit 20)
+func (h *WorkflowHandler) GetAggregatedRecommendationHistory(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = l
+	}
+
+	recs, err := userStore(c, h.store).GetAggregatedRecommendations(c.Request.Context(), limit)
+	if err != nil {
+		respondStoreError(c, err, "get aggregated recommendations", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"aggregated_recommendations": recs,
+		"count":                      len(recs),
+	})
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/youtube"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+	"0xnetworth/backend/internal/workflow"
+
+	"github.com/gin-gonic/gin"
+)
+
+// executionsPage is the body of GET /api/workflow/executions?paginated=true
+type executionsPage struct {
+	Items      []*models.WorkflowExecution `json:"items"`
+	NextCursor *string                     `json:"next_cursor"`
+}
+
+func TestGetWorkflowExecutionsPages(t *testing.T) {
+	s := store.NewStore()
+	token := addTestUser(t, s, models.DefaultUserID)
+	h := NewWorkflowHandler(s, nil, nil, nil)
+	router := newTestRouter(s)
+	router.GET("/api/workflow/executions", h.GetWorkflowExecutions)
+
+	// Pairs of executions share a second, so pages must break ties by ID
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		err := s.CreateOrUpdateWorkflowExecution(context.Background(), &models.WorkflowExecution{
+			ID:        fmt.Sprintf("e%d", i),
+			Status:    models.WorkflowStatusCompleted,
+			VideoURL:  fmt.Sprintf("https://youtu.be/e%d", i),
+			CreatedAt: start.Add(time.Duration(i/2) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	target := "/api/workflow/executions?paginated=true&limit=2"
+	pages := 0
+	for ; pages < 10; pages++ {
+		rec := doRequest(t, router, http.MethodGet, target, token, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: status = %d, want 200: %s", pages, rec.Code, rec.Body)
+		}
+		var page executionsPage
+		decodeJSON(t, rec, &page)
+		for _, execution := range page.Items {
+			got = append(got, execution.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		if len(page.Items) != 2 {
+			t.Fatalf("page %d has %d executions and a next cursor, want 2", pages, len(page.Items))
+		}
+		target = "/api/workflow/executions?limit=2&cursor=" + *page.NextCursor
+	}
+	if want := []string{"e4", "e3", "e2", "e1", "e0"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("paged executions = %v, want %v", got, want)
+	}
+	if pages != 2 {
+		t.Fatalf("the last page was page %d, want the third, holding e0 alone", pages)
+	}
+
+	for _, query := range []string{"cursor=not-a-cursor", "paginated=true&sort=created_at", "paginated=maybe"} {
+		if rec := doRequest(t, router, http.MethodGet, "/api/workflow/executions?"+query, token, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400: %s", query, rec.Code, rec.Body)
+		}
+	}
+}
+
+// fakeYouTube serves the YouTube Data API calls of the source handlers and the scheduler. The
+// handles "known" and "other" resolve to UCknown and UCother, every channel's uploads playlist
+// is empty, and the channels.list requests are counted.
+type fakeYouTube struct {
+	mu sync.Mutex
+	// channelRequests counts every channels.list request, and resolutions those resolving a
+	// handle or username to a channel ID
+	channelRequests, resolutions int
+}
+
+func (f *fakeYouTube) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch r.URL.Path {
+	case "/channels":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.channelRequests++
+		if id := query.Get("id"); id != "" {
+			fmt.Fprintf(w, `{"items":[{"contentDetails":{"relatedPlaylists":{"uploads":"UU%s"}}}]}`, strings.TrimPrefix(id, "UC"))
+			return
+		}
+		f.resolutions++
+		switch name := query.Get("forHandle") + query.Get("forUsername"); name {
+		case "known", "other":
+			fmt.Fprintf(w, `{"items":[{"id":"UC%s"}]}`, name)
+		default:
+			w.Write([]byte(`{"items":[]}`))
+		}
+	case "/playlistItems":
+		w.Write([]byte(`{"items":[]}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeYouTube) counts() (channelRequests, resolutions int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.channelRequests, f.resolutions
+}
+
+// newSourceTestRouter serves the source routes of a workflow handler whose YouTube client calls
+// fake. Each call has a client of its own, so nothing is cached from an earlier one.
+func newSourceTestRouter(t *testing.T, s store.Store, fake *fakeYouTube) (*gin.Engine, *youtube.Client) {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := youtube.NewClient("test-key", youtube.WithBaseURL(server.URL))
+	h := NewWorkflowHandler(s, nil, nil, client)
+	router := newTestRouter(s)
+	router.POST("/api/workflow/sources", h.CreateYouTubeSource)
+	router.PUT("/api/workflow/sources/:id", h.UpdateYouTubeSource)
+	router.POST("/api/workflow/sources/test", h.TestYouTubeSource)
+	return router, client
+}
+
+func TestSourceChannelIDResolvedOnSave(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	token := addTestUser(t, s, models.DefaultUserID)
+	fake := &fakeYouTube{}
+	router, _ := newSourceTestRouter(t, s, fake)
+
+	// Creating a channel source resolves its handle and stores the channel ID with it
+	rec := doRequest(t, router, http.MethodPost, "/api/workflow/sources", token,
+		`{"type":"channel","url":"https://www.youtube.com/@known","name":"Known","enabled":false}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, want 201: %s", rec.Code, rec.Body)
+	}
+	var created models.YouTubeSource
+	decodeJSON(t, rec, &created)
+	stored, err := s.GetYouTubeSourceByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ChannelID != "UCknown" {
+		t.Fatalf("created source channel ID = %q, want UCknown", stored.ChannelID)
+	}
+
+	// Testing a URL writes its channel ID back to the sources of that URL that have none
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{
+		ID: "unresolved", Type: models.YouTubeSourceTypeChannel, URL: "https://www.youtube.com/@other", Name: "Other",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rec = doRequest(t, router, http.MethodPost, "/api/workflow/sources/test", token, `{"url":"https://www.youtube.com/@other"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("test status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	stored, err = s.GetYouTubeSourceByID(ctx, "unresolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ChannelID != "UCother" {
+		t.Fatalf("tested source channel ID = %q, want UCother written back", stored.ChannelID)
+	}
+}
+
+func TestSourceStoredChannelIDSkipsResolution(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("WORKFLOW_SCHEDULE_ENABLED", "false")
+	s := store.NewStore()
+	token := addTestUser(t, s, models.DefaultUserID)
+	// The stored ID is not what the handle resolves to, so a resolution would show
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{
+		ID: "resolved", Type: models.YouTubeSourceTypeChannel, URL: "https://www.youtube.com/@known", Name: "Known",
+		Enabled: true, ChannelID: "UCstored",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving the source with its URL unchanged keeps the stored ID without calling YouTube
+	fake := &fakeYouTube{}
+	router, _ := newSourceTestRouter(t, s, fake)
+	rec := doRequest(t, router, http.MethodPut, "/api/workflow/sources/resolved", token,
+		`{"type":"channel","url":"https://www.youtube.com/@known","name":"Renamed","enabled":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if channelRequests, _ := fake.counts(); channelRequests != 0 {
+		t.Fatalf("update made %d channels.list requests, want none", channelRequests)
+	}
+	stored, err := s.GetYouTubeSourceByID(ctx, "resolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ChannelID != "UCstored" || stored.Name != "Renamed" {
+		t.Fatalf("updated source = %q with channel ID %q, want Renamed keeping UCstored", stored.Name, stored.ChannelID)
+	}
+
+	// Polling it lists the stored channel's uploads without resolving the handle
+	fake = &fakeYouTube{}
+	_, client := newSourceTestRouter(t, s, fake)
+	scheduler := workflow.NewScheduler(s, nil, client)
+	t.Cleanup(scheduler.Stop)
+	if _, err := scheduler.TriggerAllSources(ctx, models.DefaultUserID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stored, err = s.GetYouTubeSourceByID(ctx, "resolved")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.LastProcessed.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the triggered run did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	channelRequests, resolutions := fake.counts()
+	if resolutions != 0 {
+		t.Fatalf("polling made %d channel resolutions, want the stored ID used", resolutions)
+	}
+	if channelRequests != 1 || stored.ChannelID != "UCstored" {
+		t.Fatalf("polling made %d channels.list requests for channel %q, want just the uploads lookup of UCstored", channelRequests, stored.ChannelID)
+	}
+
+	// Changing the URL clears the stored ID, so the new one is resolved
+	fake = &fakeYouTube{}
+	router, _ = newSourceTestRouter(t, s, fake)
+	rec = doRequest(t, router, http.MethodPut, "/api/workflow/sources/resolved", token,
+		`{"type":"channel","url":"https://www.youtube.com/@other","name":"Other","enabled":true}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update of the URL status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	stored, err = s.GetYouTubeSourceByID(ctx, "resolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, resolutions := fake.counts(); resolutions != 1 || stored.ChannelID != "UCother" {
+		t.Fatalf("source with a new URL has channel ID %q after %d resolutions, want UCother after 1", stored.ChannelID, resolutions)
+	}
+}
+
+func TestWorkflowRecordsAreScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	ownerToken := addTestUser(t, s, models.DefaultUserID)
+	otherToken := addTestUser(t, s, "alice")
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "src-1", Type: models.YouTubeSourceTypeChannel, URL: "https://www.youtube.com/@known", Name: "Owner's channel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{ID: "e1", Status: models.WorkflowStatusCompleted, VideoURL: "https://youtu.be/e1", SourceID: "src-1"}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewWorkflowHandler(s, nil, nil, nil)
+	router := newTestRouter(s)
+	router.GET("/api/workflow/sources", h.GetYouTubeSources)
+	router.GET("/api/workflow/sources/:id", h.GetYouTubeSource)
+	router.DELETE("/api/workflow/sources/:id", h.DeleteYouTubeSource)
+	router.GET("/api/workflow/executions/:id", h.GetWorkflowExecution)
+	router.DELETE("/api/workflow/executions/:id", h.DeleteWorkflowExecution)
+
+	var sources []*models.YouTubeSource
+	decodeJSON(t, doRequest(t, router, http.MethodGet, "/api/workflow/sources", otherToken, ""), &sources)
+	if len(sources) != 0 {
+		t.Fatalf("other user lists %d YouTube sources, want none", len(sources))
+	}
+	for _, req := range []struct{ method, target string }{
+		{http.MethodGet, "/api/workflow/sources/src-1"},
+		{http.MethodDelete, "/api/workflow/sources/src-1"},
+		{http.MethodGet, "/api/workflow/executions/e1"},
+		{http.MethodDelete, "/api/workflow/executions/e1"},
+	} {
+		if rec := doRequest(t, router, req.method, req.target, otherToken, ""); rec.Code != http.StatusNotFound {
+			t.Fatalf("other user's %s %s: status %d, want 404: %s", req.method, req.target, rec.Code, rec.Body)
+		}
+	}
+	for _, target := range []string{"/api/workflow/sources/src-1", "/api/workflow/executions/e1"} {
+		if rec := doRequest(t, router, http.MethodGet, target, ownerToken, ""); rec.Code != http.StatusOK {
+			t.Fatalf("owner's GET %s after other user's requests: status %d, want 200: %s", target, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestSchedulerRunsSourceAsOwner(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("WORKFLOW_SCHEDULE_ENABLED", "false")
+	s := store.NewStore()
+	addTestUser(t, s, "alice")
+	owner := s.ForUser("alice")
+	if err := owner.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{
+		ID: "alice-src", Type: models.YouTubeSourceTypeChannel, URL: "https://www.youtube.com/@known", Name: "Alice's channel",
+		Enabled: true, ChannelID: "UCknown",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, client := newSourceTestRouter(t, s, &fakeYouTube{})
+	scheduler := workflow.NewScheduler(s, nil, client)
+	t.Cleanup(scheduler.Stop)
+	if triggered, err := scheduler.TriggerAllSources(ctx, models.DefaultUserID); err != nil || len(triggered) != 0 {
+		t.Fatalf("default user's trigger-all = %v, %v; want no sources", triggered, err)
+	}
+	if triggered, err := scheduler.TriggerAllSources(ctx, "alice"); err != nil || len(triggered) != 1 {
+		t.Fatalf("alice's trigger-all = %v, %v; want alice-src", triggered, err)
+	}
+
+	// The run saves its progress to the owner's source
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stored, err := owner.GetYouTubeSourceByID(ctx, "alice-src")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.LastProcessed.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the triggered run did not update alice's source")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sources, err := s.GetAllYouTubeSources(ctx); err != nil || len(sources) != 0 {
+		t.Fatalf("default user's YouTube sources after alice's run = %d, %v; want none", len(sources), err)
+	}
+}
@@ -0,0 +1,159 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxCandlesPerRequest is the most candles requested at once; Coinbase rejects ranges spanning
+// more than its limit
+const MaxCandlesPerRequest = 300
+
+// candleGranularities maps the granularities Coinbase accepts onto the span of one candle
+var candleGranularities = map[string]time.Duration{
+	"ONE_MINUTE":     time.Minute,
+	"FIVE_MINUTE":    5 * time.Minute,
+	"FIFTEEN_MINUTE": 15 * time.Minute,
+	"THIRTY_MINUTE":  30 * time.Minute,
+	"ONE_HOUR":       time.Hour,
+	"TWO_HOUR":       2 * time.Hour,
+	"SIX_HOUR":       6 * time.Hour,
+	"ONE_DAY":        24 * time.Hour,
+}
+
+// Candle is the trading of a product over one period starting at Start
+type Candle struct {
+	Start  time.Time `json:"start"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+type coinbaseCandle struct {
+	Start  string `json:"start"`
+	Low    string `json:"low"`
+	High   string `json:"high"`
+	Open   string `json:"open"`
+	Close  string `json:"close"`
+	Volume string `json:"volume"`
+}
+
+type coinbaseCandlesResponse struct {
+	Candles []coinbaseCandle `json:"candles"`
+}
+
+// CandleGranularity returns the span of one candle of granularity, case-insensitively. ok is
+// false if Coinbase does not offer it.
+func CandleGranularity(granularity string) (time.Duration, bool) {
+	span, ok := candleGranularities[strings.ToUpper(granularity)]
+	return span, ok
+}
+
+// GetProductCandles fetches the candles of productID starting in [start, end), oldest first.
+// Ranges longer than MaxCandlesPerRequest candles are fetched in several requests. Periods
+// without trades have no candle.
+func (c *Client) GetProductCandles(ctx context.Context, productID string, start, end time.Time, granularity string) ([]Candle, error) {
+	span, ok := CandleGranularity(granularity)
+	if !ok {
+		return nil, fmt.Errorf("invalid candle granularity %q", granularity)
+	}
+	granularity = strings.ToUpper(granularity)
+	if !start.Before(end) {
+		return nil, fmt.Errorf("candle range start %s is not before end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	candles := make([]Candle, 0)
+	seen := make(map[int64]bool)
+	for chunkStart := start; chunkStart.Before(end); {
+		chunkEnd := chunkStart.Add(span * MaxCandlesPerRequest)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		chunk, err := c.fetchCandles(ctx, productID, chunkStart, chunkEnd, granularity)
+		if err != nil {
+			return nil, err
+		}
+		for _, candle := range chunk {
+			// Coinbase includes a candle starting exactly at the end of a range, which is also
+			// the first of the next chunk
+			if candle.Start.Before(start) || !candle.Start.Before(end) || seen[candle.Start.Unix()] {
+				continue
+			}
+			seen[candle.Start.Unix()] = true
+			candles = append(candles, candle)
+		}
+		chunkStart = chunkEnd
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Start.Before(candles[j].Start) })
+	return candles, nil
+}
+
+// fetchCandles fetches one range of at most MaxCandlesPerRequest candles
+func (c *Client) fetchCandles(ctx context.Context, productID string, start, end time.Time, granularity string) ([]Candle, error) {
+	query := url.Values{}
+	query.Set("start", strconv.FormatInt(start.Unix(), 10))
+	query.Set("end", strconv.FormatInt(end.Unix(), 10))
+	query.Set("granularity", granularity)
+	path := fmt.Sprintf("/brokerage/products/%s/candles?%s", url.PathEscape(productID), query.Encode())
+	resp, err := c.makeRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candles: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(bodyBytes),
+		}
+	}
+
+	var apiResp coinbaseCandlesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode candles response: %w", err)
+	}
+	candles := make([]Candle, 0, len(apiResp.Candles))
+	for _, raw := range apiResp.Candles {
+		candle, err := parseCandle(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s candle: %w", productID, err)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// parseCandle converts a candle's string fields
+func parseCandle(raw coinbaseCandle) (Candle, error) {
+	startUnix, err := strconv.ParseInt(raw.Start, 10, 64)
+	if err != nil {
+		return Candle{}, fmt.Errorf("invalid start %q", raw.Start)
+	}
+	candle := Candle{Start: time.Unix(startUnix, 0).UTC()}
+	for _, field := range []struct {
+		name, value string
+		dest        *float64
+	}{
+		{"open", raw.Open, &candle.Open},
+		{"high", raw.High, &candle.High},
+		{"low", raw.Low, &candle.Low},
+		{"close", raw.Close, &candle.Close},
+		{"volume", raw.Volume, &candle.Volume},
+	} {
+		if *field.dest, err = strconv.ParseFloat(field.value, 64); err != nil {
+			return Candle{}, fmt.Errorf("invalid %s %q", field.name, field.value)
+		}
+	}
+	return candle, nil
+}
@@ -0,0 +1,96 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// cashInvestments turns the fiat and stablecoin balances of accounts into cash holdings valued
+// in the reporting currency, so cash kept on the exchange counts towards net worth. A cash
+// holding belongs to its Coinbase account rather than a portfolio.
+//
+// reported holds portfolio UUID + "/" + asset for every position the portfolio breakdowns
+// returned, so a stablecoin already synced as a holding is not counted twice, and unfetched
+// the portfolios whose breakdown failed. complete lists the accounts whose cash holding is
+// settled by this sync; a stored cash holding of one of them that is not returned has been
+// emptied. A balance whose currency has no rate is kept at its holding's price in lastPrices,
+// flagged stale, and otherwise skipped and listed in skipped. Only a done ctx is returned as an
+// error.
+func (c *Client) cashInvestments(ctx context.Context, accounts []*models.Account, reported, unfetched map[string]bool, lastPrices map[string]float64) (cash []*models.Investment, complete []string, skipped []models.SkippedAsset, err error) {
+	held := make([]*models.Account, 0)
+	codes := make([]string, 0)
+	for _, account := range accounts {
+		code := strings.ToUpper(account.Currency)
+		if stablecoins[code] {
+			// Whether the breakdown counts it is unknown until its portfolio is fetched
+			if unfetched[account.PortfolioID] {
+				continue
+			}
+			if reported[account.PortfolioID+"/"+code] {
+				complete = append(complete, account.ID)
+				continue
+			}
+		} else if !account.IsCash() {
+			// Crypto balances are synced from the portfolio breakdown
+			continue
+		}
+		if !account.Active || account.Balance() == 0 {
+			complete = append(complete, account.ID)
+			continue
+		}
+		held = append(held, account)
+		codes = append(codes, code)
+	}
+
+	rates, err := c.reportingRates(ctx, codes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	syncedAt := models.Now()
+	for _, account := range held {
+		code := strings.ToUpper(account.Currency)
+		balance := account.Balance()
+		id := "cash-" + account.ID
+		value, ok := toReporting(balance, code, rates)
+		stale := false
+		if !ok {
+			price, known := lastPrices[id]
+			if !known {
+				log.Printf("Warning: No %s rate available for %s cash in account %s, skipping", reportingCurrency, code, account.ID)
+				skipped = append(skipped, models.SkippedAsset{
+					ProductID: code + "-" + reportingCurrency,
+					AccountID: account.ID,
+					Reason:    fmt.Sprintf("no %s rate for %s", reportingCurrency, code),
+				})
+				continue
+			}
+			log.Printf("Warning: No %s rate available for %s cash in account %s, keeping its last price", reportingCurrency, code, account.ID)
+			value, stale = price*balance, true
+		}
+		investment := &models.Investment{
+			ID:          id,
+			AccountID:   account.ID,
+			Platform:    models.PlatformCoinbase,
+			Symbol:      code,
+			Name:        account.Name,
+			Quantity:    balance,
+			Value:       value,
+			Price:       value / balance,
+			Currency:    reportingCurrency,
+			AssetType:   models.AssetTypeCash,
+			StalePrice:  stale,
+			LastUpdated: syncedAt,
+		}
+		if code != reportingCurrency && !stale {
+			investment.NativeValue = &balance
+			investment.NativeCurrency = code
+		}
+		cash = append(cash, investment)
+		complete = append(complete, account.ID)
+	}
+	return cash, complete, skipped, nil
+}
@@ -2,20 +2,64 @@ package coinbase
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/coinbase/cdp-sdk/go/auth"
+	"golang.org/x/sync/errgroup"
 	"0xnetworth/backend/internal/models"
 )
 
 const (
 	coinbaseAPIBaseURL = "https://api.coinbase.com/api/v3"
+
+	// accountsPageSize is the number of accounts requested per page, the most Coinbase returns
+	accountsPageSize = 250
+	// maxListPages bounds the pages fetched of any listing, so an API that keeps reporting
+	// has_next with a fresh cursor cannot loop forever
+	maxListPages = 100
+
+	// Retry defaults for transient failures of GET requests; see makeRequest
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryDelay       = 30 * time.Second
+
+	// defaultRateLimit is the requests per second sent unless COINBASE_RATE_LIMIT says otherwise,
+	// well within Coinbase's per-second limits
+	defaultRateLimit = 10
+
+	// defaultPriceCacheTTL is how long a product price is reused unless COINBASE_PRICE_CACHE_TTL
+	// says otherwise
+	defaultPriceCacheTTL = 30 * time.Second
+
+	// priceBatchSize is the number of products quoted per best_bid_ask request
+	priceBatchSize = 100
+
+	// defaultSyncTimeout bounds a whole SyncAll unless COINBASE_SYNC_TIMEOUT says otherwise
+	defaultSyncTimeout = 2 * time.Minute
+
+	// holdingsConcurrency is the number of portfolios whose holdings are fetched at once
+	holdingsConcurrency = 4
+
+	// jwtLifetime is how long a signed request token is valid
+	jwtLifetime = 120 * time.Second
+	// defaultJWTExpiryMargin is how long before expiry a cached token stops being reused unless
+	// COINBASE_JWT_EXPIRY_MARGIN says otherwise
+	defaultJWTExpiryMargin = 10 * time.Second
 )
 
 // APIError represents an error from the Coinbase API with status code
@@ -36,31 +80,167 @@ func (e *APIError) Error() string {
 // See: https://docs.cdp.coinbase.com/api-reference/v2/authentication
 type Client struct {
 	apiKeyName   string // CDP API Key ID (UUID or full path format)
-	apiKeySecret string // API Key Secret (PEM or base64-encoded DER format)
+	apiKeySecret string // API Key Secret, normalized for the CDP SDK (see normalizeKeySecret)
+	keyAlgorithm string // ES256 for ECDSA keys, EdDSA for Ed25519 keys
 	httpClient   *http.Client
+	maxRetries   int           // Retries of a failed GET request (COINBASE_MAX_RETRIES)
+	retryBackoff time.Duration // Wait before the first retry, doubled for each one after
+	limiter      *rateLimiter  // Shared by all requests; nil when rate limiting is disabled
+	prices       *priceCache   // Recently fetched product prices; nil when caching is disabled
+	jwts         *jwtCache     // Signed tokens still valid for reuse; nil when reuse is disabled
+	syncTimeout  time.Duration // Bound on a whole SyncAll (COINBASE_SYNC_TIMEOUT); 0 for none
+	fx           FXSource      // Converts balances in other currencies into the reporting currency
+	wsURL        string        // Websocket endpoint of StreamTicker
+	debug        bool          // Log every request with its status and timing (COINBASE_DEBUG)
+	credentials  atomic.Pointer[CredentialStatus] // Result of the latest Validate
+	// stablecoinParity values USDC and USDT at one dollar without looking up a rate
+	// (COINBASE_STABLECOIN_PARITY)
+	stablecoinParity bool
+	// portfolioTypes are the Coinbase portfolio types synced (COINBASE_PORTFOLIO_TYPES); nil
+	// syncs every type but INTX
+	portfolioTypes map[string]bool
+	// intxEnabled syncs INTX portfolios from their perpetual positions and collateral
+	// (COINBASE_INTX_ENABLED); keys without INTX access are refused by those endpoints
+	intxEnabled bool
 }
 
 // NewClient creates a new Coinbase API client using CDP API v2 authentication
 // apiKeyName: The CDP API Key ID (UUID or full path format: organizations/{org_id}/apiKeys/{key_id})
 // apiKeySecret: The Private Key - can be in PEM format or base64-encoded DER (as provided in JSON file from CDP Portal)
-// The CDP SDK handles parsing of the private key in various formats (ES256 or Ed25519)
+// ECDSA keys sign with ES256 and Ed25519 keys with EdDSA; see normalizeKeySecret for the formats accepted
 // See: https://docs.cdp.coinbase.com/api-reference/v2/authentication#creating-secret-api-keys
-func NewClient(apiKeyName, apiKeySecret string) (*Client, error) {
+// opts tune the HTTP client requests are sent with; see WithTimeout, WithHTTPClient and
+// WithTransport
+func NewClient(apiKeyName, apiKeySecret string, opts ...Option) (*Client, error) {
 	if apiKeyName == "" {
 		return nil, fmt.Errorf("apiKeyName cannot be empty")
 	}
 	if apiKeySecret == "" {
 		return nil, fmt.Errorf("apiKeySecret cannot be empty")
 	}
+	apiKeySecret, keyAlgorithm, err := normalizeKeySecret(apiKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apiKeySecret: %w", err)
+	}
+	maxRetries := defaultMaxRetries
+	if val := os.Getenv("COINBASE_MAX_RETRIES"); val != "" {
+		retries, err := strconv.Atoi(val)
+		if err != nil || retries < 0 {
+			return nil, fmt.Errorf("COINBASE_MAX_RETRIES must be a non-negative integer, got %q", val)
+		}
+		maxRetries = retries
+	}
+	var limiter *rateLimiter
+	rate := float64(defaultRateLimit)
+	if val := os.Getenv("COINBASE_RATE_LIMIT"); val != "" {
+		parsed, err := strconv.ParseFloat(val, 64)
+		if err != nil || parsed < 0 || math.IsInf(parsed, 0) {
+			return nil, fmt.Errorf("COINBASE_RATE_LIMIT must be a non-negative number of requests per second, got %q", val)
+		}
+		rate = parsed
+	}
+	if rate > 0 {
+		limiter = newRateLimiter(rate)
+	}
+	var prices *priceCache
+	priceTTL := defaultPriceCacheTTL
+	if val := os.Getenv("COINBASE_PRICE_CACHE_TTL"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("COINBASE_PRICE_CACHE_TTL must be a non-negative duration such as 30s, got %q", val)
+		}
+		priceTTL = parsed
+	}
+	if priceTTL > 0 {
+		prices = newPriceCache(priceTTL)
+	}
+	syncTimeout := defaultSyncTimeout
+	if val := os.Getenv("COINBASE_SYNC_TIMEOUT"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("COINBASE_SYNC_TIMEOUT must be a non-negative duration such as 2m, got %q", val)
+		}
+		syncTimeout = parsed
+	}
+	var jwts *jwtCache
+	jwtMargin := defaultJWTExpiryMargin
+	if val := os.Getenv("COINBASE_JWT_EXPIRY_MARGIN"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("COINBASE_JWT_EXPIRY_MARGIN must be a non-negative duration such as 10s, got %q", val)
+		}
+		jwtMargin = parsed
+	}
+	// A margin of the whole lifetime or more would never reuse a token
+	if jwtMargin < jwtLifetime {
+		jwts = newJWTCache(jwtLifetime, jwtMargin)
+	}
+	stablecoinParity := false
+	if val := os.Getenv("COINBASE_STABLECOIN_PARITY"); val != "" {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("COINBASE_STABLECOIN_PARITY must be true or false, got %q", val)
+		}
+		stablecoinParity = parsed
+	}
+	intxEnabled := false
+	if val := os.Getenv("COINBASE_INTX_ENABLED"); val != "" {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("COINBASE_INTX_ENABLED must be true or false, got %q", val)
+		}
+		intxEnabled = parsed
+	}
+	var portfolioTypes map[string]bool
+	if val := os.Getenv("COINBASE_PORTFOLIO_TYPES"); val != "" {
+		portfolioTypes, err = parsePortfolioTypes(val)
+		if err != nil {
+			return nil, err
+		}
+	}
+	httpClient, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Client{
+	client := &Client{
 		apiKeyName:   apiKeyName,
 		apiKeySecret: apiKeySecret,
-		httpClient:   &http.Client{Timeout: 30 * time.Second},
-	}, nil
+		keyAlgorithm: keyAlgorithm,
+		httpClient:   httpClient,
+		maxRetries:   maxRetries,
+		retryBackoff: defaultRetryBackoff,
+		limiter:      limiter,
+		prices:       prices,
+		jwts:         jwts,
+		syncTimeout:  syncTimeout,
+		wsURL:        coinbaseWebsocketURL,
+		stablecoinParity: stablecoinParity,
+		portfolioTypes:   portfolioTypes,
+		intxEnabled:      intxEnabled,
+		debug:            os.Getenv("COINBASE_DEBUG") == "true",
+	}
+	client.fx = productFXSource{client: client}
+	return client, nil
 }
 
 // Coinbase API Response Types
+
+// coinbasePage is the pagination metadata of a Coinbase list response. Some listings, such as
+// fills, return only a cursor, which is empty on the last page.
+type coinbasePage struct {
+	HasNext *bool  `json:"has_next"`
+	Cursor  string `json:"cursor"`
+}
+
+// more reports whether another page follows the one fetched with cursor
+func (p coinbasePage) more(cursor string) bool {
+	if p.HasNext != nil && !*p.HasNext {
+		return false
+	}
+	return p.Cursor != "" && p.Cursor != cursor
+}
+
 type coinbasePortfolio struct {
 	UUID     string `json:"uuid"`
 	Name     string `json:"name"`
@@ -98,6 +278,22 @@ type coinbaseProduct struct {
 	TradingDisabled bool   `json:"trading_disabled"`
 }
 
+// Best bid/ask response types, quoting several products at once
+type coinbasePriceLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+type coinbasePricebook struct {
+	ProductID string               `json:"product_id"`
+	Bids      []coinbasePriceLevel `json:"bids"`
+	Asks      []coinbasePriceLevel `json:"asks"`
+}
+
+type coinbaseBestBidAskResponse struct {
+	Pricebooks []coinbasePricebook `json:"pricebooks"`
+}
+
 type coinbasePortfolioHoldings struct {
 	PortfolioID string `json:"portfolio_id"`
 	ProductID   string `json:"product_id"`
@@ -112,6 +308,27 @@ type coinbasePortfolioHoldingsResponse struct {
 	Data []coinbasePortfolioHoldings `json:"data"`
 }
 
+// Accounts Response Types
+type coinbaseBalance struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+type coinbaseAccount struct {
+	UUID              string          `json:"uuid"`
+	Name              string          `json:"name"`
+	Currency          string          `json:"currency"`
+	AvailableBalance  coinbaseBalance `json:"available_balance"`
+	Hold              coinbaseBalance `json:"hold"`
+	Active            bool            `json:"active"`
+	Type              string          `json:"type"`
+	RetailPortfolioID string          `json:"retail_portfolio_id"`
+}
+
+type coinbaseAccountsResponse struct {
+	Accounts []coinbaseAccount `json:"accounts"`
+}
+
 // Portfolio Breakdown Response Types (using the correct API endpoint)
 type coinbaseSpotPosition struct {
 	Asset                    string  `json:"asset"`
@@ -126,6 +343,22 @@ type coinbaseSpotPosition struct {
 	} `json:"average_entry_price"`
 	AssetUUID string `json:"asset_uuid"`
 	IsCash    bool   `json:"is_cash"`
+	// StakedBalance is the quantity locked in staking or earn, which the other balances leave out
+	StakedBalance coinbaseAmount `json:"staked_balance"`
+	// FiatCurrency is the currency of the fiat balances, that of the portfolio breakdown
+	FiatCurrency string `json:"-"`
+	// MarketPriced marks a position built from an account balance rather than a breakdown,
+	// which has no fiat balance and is valued at its asset's market price instead
+	MarketPriced bool `json:"-"`
+}
+
+// Price returns the price of the position's asset in its fiat currency, its fiat balance per
+// unit, as the breakdown reports it. ok is false for an empty or market priced position.
+func (p coinbaseSpotPosition) Price() (float64, bool) {
+	if p.MarketPriced || p.TotalBalanceCrypto <= 0 {
+		return 0, false
+	}
+	return p.TotalBalanceFiat / p.TotalBalanceCrypto, true
 }
 
 type coinbasePortfolioBreakdown struct {
@@ -134,6 +367,9 @@ type coinbasePortfolioBreakdown struct {
 		UUID string `json:"uuid"`
 		Type string `json:"type"`
 	} `json:"portfolio"`
+	PortfolioBalances struct {
+		TotalBalance coinbaseBalance `json:"total_balance"`
+	} `json:"portfolio_balances"`
 	SpotPositions []coinbaseSpotPosition `json:"spot_positions"`
 	PerpPositions []interface{}          `json:"perp_positions"`
 	FuturesPositions []interface{}       `json:"futures_positions"`
@@ -166,7 +402,7 @@ func (c *Client) generateJWT(method, path string) (string, error) {
 		RequestMethod: method,
 		RequestHost:   "api.coinbase.com",
 		RequestPath:   path,
-		ExpiresIn:     120, // 2 minutes, default
+		ExpiresIn:     int64(jwtLifetime / time.Second),
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate JWT using CDP SDK: %w", err)
@@ -175,43 +411,144 @@ func (c *Client) generateJWT(method, path string) (string, error) {
 	return jwt, nil
 }
 
-// makeRequest makes an authenticated request to Coinbase API using JWT
-func (c *Client) makeRequest(method, path string, body io.Reader) (*http.Response, error) {
+// authToken returns a token for a request to method and path, reusing a cached one until it
+// nears expiry; cached reports whether it was reused. A newly signed token has its own nonce.
+func (c *Client) authToken(method, path string) (token string, cached bool, err error) {
+	if c.jwts == nil {
+		token, err = c.generateJWT(method, path)
+		return token, false, err
+	}
+	if token, ok := c.jwts.get(method, path); ok {
+		return token, true, nil
+	}
+	// exp is whole seconds after the signing time, so counting from the second it starts in
+	// never overestimates the token's life
+	signedAt := time.Now().Truncate(time.Second)
+	token, err = c.generateJWT(method, path)
+	if err != nil {
+		return "", false, err
+	}
+	c.jwts.put(method, path, token, signedAt)
+	log.Printf("Generated JWT for [%s %s], token length: %d", method, path, len(token))
+	return token, false, nil
+}
+
+// makeRequest makes an authenticated request to Coinbase API using JWT.
+// GET requests that fail with a network error or a retryable status (see retryableStatus) are
+// retried up to maxRetries times with exponential backoff; other requests are not idempotent
+// and are sent once. Every attempt carries a JWT that is not about to expire (see authToken).
+// Each attempt first waits its turn with the rate limiter; once ctx is done
+// no further attempts are made. A 429 waits as long as its Retry-After asks; one that cannot be
+// retried in time is returned as a *RateLimitedError. Every request is recorded by observeRequest.
+func (c *Client) makeRequest(ctx context.Context, method, path string, body io.Reader) (resp *http.Response, err error) {
 	url := coinbaseAPIBaseURL + path
+	start, retried := time.Now(), 0
+	defer func() {
+		c.observeRequest(ctx, method, path, time.Since(start), retried, resp, err)
+	}()
 	
 	var bodyBytes []byte
 	if body != nil {
-		var err error
 		bodyBytes, err = io.ReadAll(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read body: %w", err)
 		}
-		body = bytes.NewReader(bodyBytes)
 	}
 
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	retries := 0
+	if method == http.MethodGet {
+		retries = c.maxRetries
 	}
+	for attempt := 0; ; attempt++ {
+		retried = attempt
+		if c.limiter != nil {
+			if err := c.limiter.wait(ctx); err != nil {
+				return nil, fmt.Errorf("failed to make request: %w", err)
+			}
+		}
+		resp, err := c.sendRequest(ctx, method, url, path, bodyBytes)
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			if limited := rateLimitedError(ctx, method, path, resp, attempt == retries); limited != nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				log.Printf("Coinbase request [%s %s] rate limited after %d retries, giving up: %v", method, path, attempt, limited)
+				return nil, limited
+			}
+		}
+		retryable := (err != nil && ctx.Err() == nil) || (err == nil && retryableStatus(resp.StatusCode))
+		if !retryable || attempt == retries {
+			if attempt > 0 {
+				log.Printf("Coinbase request [%s %s] finished after %d retries", method, path, attempt)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
 
-	// Generate JWT token for this request
-	// JWT path must include /api/v3 to match the actual request URL
-	fullPath := "/api/v3" + path
-	jwtToken, err := c.generateJWT(method, fullPath)
-	if err != nil {
-		log.Printf("Failed to generate JWT: %v", err)
-		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+		delay := c.retryDelay(attempt, resp)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		log.Printf("Coinbase request [%s %s] failed (%s), retry %d of %d in %s", method, path, reason, attempt+1, retries, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to make request: %w", ctx.Err())
+		}
 	}
-	log.Printf("Generated JWT for [%s %s], token length: %d", method, fullPath, len(jwtToken))
+}
 
-	// Set headers
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+// sendRequest makes a single attempt at a request, signed with a JWT for its method and path.
+// If Coinbase rejects a reused token it is dropped and the request is sent again, once, with a
+// newly signed one.
+func (c *Client) sendRequest(ctx context.Context, method, requestURL, path string, bodyBytes []byte) (*http.Response, error) {
+	// JWT path must include /api/v3 to match the actual request URL, but not the query string
+	fullPath, _, _ := strings.Cut("/api/v3"+path, "?")
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	var resp *http.Response
+	for {
+		var body io.Reader
+		if bodyBytes != nil {
+			body = bytes.NewReader(bodyBytes)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		jwtToken, cached, err := c.authToken(method, fullPath)
+		if err != nil {
+			log.Printf("Failed to generate JWT: %v", err)
+			return nil, fmt.Errorf("failed to generate JWT: %w", err)
+		}
+
+		// Set headers
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make request: %w", err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || c.jwts == nil {
+			break
+		}
+		c.jwts.invalidate(method, fullPath)
+		if !cached {
+			break
+		}
+		log.Printf("Coinbase rejected a reused JWT for [%s %s], signing a new one", method, fullPath)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
 	}
 
 	// Log non-2xx responses for debugging
@@ -221,78 +558,348 @@ func (c *Client) makeRequest(method, path string, body io.Reader) (*http.Respons
 		// Create a new reader for the body since we consumed it
 		resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 		log.Printf("Coinbase API error [%s %s]: %d - %s", method, path, resp.StatusCode, string(bodyBytes))
-		log.Printf("Request URL: %s", url)
-		log.Printf("API Key Name: %s", c.apiKeyName)
 	}
 
 	return resp, nil
 }
 
-// GetPortfolios fetches portfolios from Coinbase
+// retryableStatus reports whether a response status is likely transient: rate limiting or a
+// server-side failure. Client errors such as 401, 403 and 404 will not change on retry.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before retry number attempt+1: retryBackoff doubled for
+// each earlier retry, plus up to half again of random jitter so that concurrent syncs spread
+// out, capped at maxRetryDelay. A Retry-After header takes precedence.
+func (c *Client) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(wait, maxRetryDelay)
+		}
+	}
+	delay := maxRetryDelay
+	if attempt < 20 { // beyond this the shift could overflow, and the cap applies anyway
+		delay = min(c.retryBackoff<<attempt, maxRetryDelay)
+	}
+	delay += time.Duration(rand.Int64N(int64(delay)/2 + 1))
+	return min(delay, maxRetryDelay)
+}
+
+// getAllPages GETs path and then each following page, passing every response body to handle.
+// Coinbase list responses carry a cursor for the next page, and usually has_next; an API that keeps
+// reporting more pages is cut off with an error after maxListPages pages rather than
+// returning a partial listing. It returns the number of pages fetched.
+func (c *Client) getAllPages(ctx context.Context, path string, handle func(body []byte) error) (int, error) {
+	cursor := ""
+	for page := 1; page <= maxListPages; page++ {
+		pagePath := path
+		if cursor != "" {
+			separator := "?"
+			if strings.Contains(path, "?") {
+				separator = "&"
+			}
+			pagePath += separator + "cursor=" + url.QueryEscape(cursor)
+		}
+		resp, err := c.makeRequest(ctx, "GET", pagePath, nil)
+		if err != nil {
+			return page - 1, err
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return page - 1, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return page - 1, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    string(bodyBytes),
+			}
+		}
+
+		var next coinbasePage
+		if err := json.Unmarshal(bodyBytes, &next); err != nil {
+			return page - 1, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if err := handle(bodyBytes); err != nil {
+			return page, err
+		}
+		if !next.more(cursor) {
+			return page, nil
+		}
+		cursor = next.Cursor
+	}
+	return maxListPages, fmt.Errorf("listing still had more pages after %d pages", maxListPages)
+}
+
+// GetPortfolios fetches portfolios from Coinbase, following pagination
 // IMPORTANT: This endpoint only returns portfolios that the API key has access to.
 // If your API key is scoped to a specific portfolio, only that portfolio will be returned.
 // To see all portfolios, ensure your API key has "Portfolio primary view access" 
 // or is not scoped to a specific portfolio in Coinbase Developer Platform.
-func (c *Client) GetPortfolios() ([]coinbasePortfolio, error) {
-	resp, err := c.makeRequest("GET", "/brokerage/portfolios", nil)
+func (c *Client) GetPortfolios(ctx context.Context) ([]coinbasePortfolio, error) {
+	portfolios := []coinbasePortfolio{}
+	pages, err := c.getAllPages(ctx, "/brokerage/portfolios", func(bodyBytes []byte) error {
+		// Log the raw response for debugging
+		log.Printf("GetPortfolios: Raw API response: %s", string(bodyBytes))
+
+		var apiResp coinbasePortfoliosResponse
+		if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		// Log what we found
+		log.Printf("GetPortfolios: Found %d portfolios in 'Portfolios' field, %d in 'Data' field", 
+			len(apiResp.Portfolios), len(apiResp.Data))
+		
+		// Log portfolio details
+		if len(apiResp.Portfolios) > 0 {
+			for i, p := range apiResp.Portfolios {
+				log.Printf("GetPortfolios: Portfolio[%d]: UUID=%s, Name=%s, Type=%s", i, p.UUID, p.Name, p.Type)
+			}
+		}
+		if len(apiResp.Data) > 0 {
+			for i, p := range apiResp.Data {
+				log.Printf("GetPortfolios: Data[%d]: UUID=%s, Name=%s, Type=%s", i, p.UUID, p.Name, p.Type)
+			}
+		}
+
+		// Handle different response formats
+		if len(apiResp.Portfolios) > 0 {
+			portfolios = append(portfolios, apiResp.Portfolios...)
+		} else {
+			portfolios = append(portfolios, apiResp.Data...)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch portfolios: %w", err)
 	}
+	log.Printf("GetPortfolios: Fetched %d portfolios in %d pages", len(portfolios), pages)
+	return portfolios, nil
+}
+
+// GetPortfolioBreakdown fetches a portfolio's breakdown, whose spot positions carry each asset's
+// balance and its value in the portfolio's fiat currency, so holdings can be valued without
+// asking for prices. Every page is fetched, since a missing page would silently drop positions
+// from net worth; the positions of all pages are returned together in one breakdown.
+func (c *Client) GetPortfolioBreakdown(ctx context.Context, portfolioID string) (*coinbasePortfolioBreakdown, error) {
+	// GET /api/v3/brokerage/portfolios/{portfolio_uuid}
+	path := fmt.Sprintf("/brokerage/portfolios/%s", portfolioID)
+
+	var breakdown *coinbasePortfolioBreakdown
+	pages, err := c.getAllPages(ctx, path, func(bodyBytes []byte) error {
+		var apiResp coinbasePortfolioBreakdownResponse
+		if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+			return fmt.Errorf("failed to decode portfolio breakdown response: %w", err)
+		}
+		page := apiResp.Breakdown
+		for i := range page.SpotPositions {
+			page.SpotPositions[i].FiatCurrency = page.PortfolioBalances.TotalBalance.Currency
+		}
+		if breakdown == nil {
+			breakdown = &page
+		} else {
+			breakdown.SpotPositions = append(breakdown.SpotPositions, page.SpotPositions...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolio breakdown: %w", err)
+	}
+	log.Printf("GetPortfolioBreakdown: Fetched %d positions for portfolio %s in %d pages", len(breakdown.SpotPositions), portfolioID, pages)
+	return breakdown, nil
+}
+
+// GetPortfolioHoldings fetches the holdings of a portfolio from its breakdown, leaving out
+// cash positions
+func (c *Client) GetPortfolioHoldings(ctx context.Context, portfolioID string) ([]coinbaseSpotPosition, error) {
+	breakdown, err := c.GetPortfolioBreakdown(ctx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	positions := []coinbaseSpotPosition{}
+	for _, pos := range breakdown.SpotPositions {
+		if !pos.IsCash {
+			positions = append(positions, pos)
+		}
+	}
+	return positions, nil
+}
+
+// GetAccounts fetches every account (one per currency) visible to the API key, following
+// pagination until Coinbase reports no more pages
+func (c *Client) GetAccounts(ctx context.Context) ([]*models.Account, error) {
+	accounts := make([]*models.Account, 0)
+	path := fmt.Sprintf("/brokerage/accounts?limit=%d", accountsPageSize)
+	_, err := c.getAllPages(ctx, path, func(bodyBytes []byte) error {
+		var apiResp coinbaseAccountsResponse
+		if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+			return fmt.Errorf("failed to decode accounts response: %w", err)
+		}
+
+		syncedAt := models.Now()
+		for _, a := range apiResp.Accounts {
+			// Balances are decimal strings; an unparseable one is reported as zero
+			available, _ := strconv.ParseFloat(a.AvailableBalance.Value, 64)
+			hold, _ := strconv.ParseFloat(a.Hold.Value, 64)
+			accounts = append(accounts, &models.Account{
+				ID:          a.UUID,
+				Platform:    models.PlatformCoinbase,
+				PortfolioID: a.RetailPortfolioID,
+				Name:        a.Name,
+				Currency:    a.Currency,
+				Type:        a.Type,
+				Available:   available,
+				Hold:        hold,
+				Active:      a.Active,
+				LastSynced:  syncedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// PriceOptions adjusts a product price lookup
+type PriceOptions struct {
+	// ForceRefresh fetches a live quote instead of reusing a cached price
+	ForceRefresh bool
+}
+
+// GetProductPrice returns the current price of a product, reusing a price fetched within the
+// cache TTL unless opts.ForceRefresh is set. If the brokerage cannot quote the product its
+// public spot price is used.
+func (c *Client) GetProductPrice(ctx context.Context, productID string, opts PriceOptions) (float64, error) {
+	if c.prices != nil {
+		if price, ok := c.prices.get(productID, opts.ForceRefresh); ok {
+			return price, nil
+		}
+	}
+	price, err := c.productPrice(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+	if c.prices != nil {
+		c.prices.put(productID, price)
+	}
+	return price, nil
+}
+
+// PriceCacheStats reports the product price cache's hits and misses so far. It is zero when
+// caching is disabled.
+func (c *Client) PriceCacheStats() PriceCacheStats {
+	if c.prices == nil {
+		return PriceCacheStats{}
+	}
+	return c.prices.stats()
+}
+
+// fetchProductPrice fetches the current price of a product from Coinbase
+func (c *Client) fetchProductPrice(ctx context.Context, productID string) (float64, error) {
+	path := fmt.Sprintf("/brokerage/products/%s", productID)
+	resp, err := c.makeRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch product: %w", err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, &APIError{
+		return 0, &APIError{
 			StatusCode: resp.StatusCode,
 			Message:    string(bodyBytes),
 		}
 	}
 
-	// Read the full response body for logging
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	var product coinbaseProduct
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
 	}
-	
-	// Log the raw response for debugging
-	log.Printf("GetPortfolios: Raw API response: %s", string(bodyBytes))
 
-	var apiResp coinbasePortfoliosResponse
-	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	price, err := strconv.ParseFloat(product.Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse price: %w", err)
 	}
 
-	// Log what we found
-	log.Printf("GetPortfolios: Found %d portfolios in 'Portfolios' field, %d in 'Data' field", 
-		len(apiResp.Portfolios), len(apiResp.Data))
-	
-	// Log portfolio details
-	if len(apiResp.Portfolios) > 0 {
-		for i, p := range apiResp.Portfolios {
-			log.Printf("GetPortfolios: Portfolio[%d]: UUID=%s, Name=%s, Type=%s", i, p.UUID, p.Name, p.Type)
+	return price, nil
+}
+
+// GetPrices returns the current price of each product, taking cached prices where it can and
+// quoting the rest with as few best_bid_ask requests as possible. A product the batch response
+// leaves out is fetched on its own, or else its public spot price is used; one that cannot be priced either way is left out of the
+// result, which only fails if ctx is done.
+func (c *Client) GetPrices(ctx context.Context, productIDs []string) (map[string]float64, error) {
+	prices, _, err := c.getPrices(ctx, productIDs)
+	return prices, err
+}
+
+// getPrices is GetPrices, also returning why each product left out could not be priced
+func (c *Client) getPrices(ctx context.Context, productIDs []string) (map[string]float64, map[string]error, error) {
+	prices := make(map[string]float64, len(productIDs))
+	failures := make(map[string]error)
+	pending := make([]string, 0, len(productIDs))
+	seen := make(map[string]bool, len(productIDs))
+	for _, id := range productIDs {
+		if seen[id] {
+			continue
 		}
-	}
-	if len(apiResp.Data) > 0 {
-		for i, p := range apiResp.Data {
-			log.Printf("GetPortfolios: Data[%d]: UUID=%s, Name=%s, Type=%s", i, p.UUID, p.Name, p.Type)
+		seen[id] = true
+		if c.prices != nil {
+			if price, ok := c.prices.get(id, false); ok {
+				prices[id] = price
+				continue
+			}
 		}
+		pending = append(pending, id)
 	}
 
-	// Handle different response formats
-	if len(apiResp.Portfolios) > 0 {
-		return apiResp.Portfolios, nil
+	for chunk := range slices.Chunk(pending, priceBatchSize) {
+		quotes, err := c.fetchBestBidAsk(ctx, chunk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, err
+			}
+			log.Printf("Warning: Batch price request for %d products failed, fetching them one by one: %v", len(chunk), err)
+		}
+		for _, id := range chunk {
+			price, ok := quotes[id]
+			if !ok {
+				price, err = c.productPrice(ctx, id)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, nil, err
+					}
+					log.Printf("Warning: Failed to get price for %s: %v", id, err)
+					failures[id] = err
+					continue
+				}
+			}
+			prices[id] = price
+			if c.prices != nil {
+				c.prices.put(id, price)
+			}
+		}
 	}
-	return apiResp.Data, nil
+	return prices, failures, nil
 }
 
-// GetPortfolioHoldings fetches holdings for a specific portfolio using the Portfolio Breakdown endpoint
-// This endpoint returns spot_positions which contain the actual holdings/assets in the portfolio
-func (c *Client) GetPortfolioHoldings(portfolioID string) ([]coinbaseSpotPosition, error) {
-	// Use the correct endpoint: GET /api/v3/brokerage/portfolios/{portfolio_uuid}
-	path := fmt.Sprintf("/brokerage/portfolios/%s", portfolioID)
-	resp, err := c.makeRequest("GET", path, nil)
+// fetchBestBidAsk quotes productIDs in one request, pricing each at the midpoint of its best
+// bid and ask, or at whichever of the two it has. Products without either are left out.
+func (c *Client) fetchBestBidAsk(ctx context.Context, productIDs []string) (map[string]float64, error) {
+	query := make(url.Values)
+	query["product_ids"] = productIDs
+	resp, err := c.makeRequest(ctx, "GET", "/brokerage/best_bid_ask?"+query.Encode(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch portfolio breakdown: %w", err)
+		return nil, fmt.Errorf("failed to fetch best bid/ask: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -304,117 +911,353 @@ func (c *Client) GetPortfolioHoldings(portfolioID string) ([]coinbaseSpotPositio
 		}
 	}
 
-	var apiResp coinbasePortfolioBreakdownResponse
+	var apiResp coinbaseBestBidAskResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("failed to decode portfolio breakdown response: %w", err)
+		return nil, fmt.Errorf("failed to decode best bid/ask response: %w", err)
 	}
 
-	// Return spot positions (filter out cash positions)
+	quotes := make(map[string]float64, len(apiResp.Pricebooks))
+	for _, book := range apiResp.Pricebooks {
+		var bid, ask float64
+		if len(book.Bids) > 0 {
+			bid, _ = strconv.ParseFloat(book.Bids[0].Price, 64)
+		}
+		if len(book.Asks) > 0 {
+			ask, _ = strconv.ParseFloat(book.Asks[0].Price, 64)
+		}
+		switch {
+		case bid > 0 && ask > 0:
+			quotes[book.ProductID] = (bid + ask) / 2
+		case bid > 0:
+			quotes[book.ProductID] = bid
+		case ask > 0:
+			quotes[book.ProductID] = ask
+		}
+	}
+	return quotes, nil
+}
+
+// usdProductID returns the product that prices asset in US dollars, the currency portfolio
+// breakdowns report values in
+func usdProductID(asset string) string {
+	return strings.ToUpper(asset) + "-USD"
+}
+
+// positionProductID returns the product that prices a position's asset, taking a staked asset
+// as the asset it stands for
+func positionProductID(position coinbaseSpotPosition) string {
+	return usdProductID(underlyingAsset(position.Asset))
+}
+
+// fetchHoldings fetches the holdings of every portfolio from its breakdown, up to
+// holdingsConcurrency at a time. holdings[i], fetched[i] and fallback[i] belong to portfolios[i]
+// whatever order the requests finish in. A portfolio whose breakdown Coinbase does not serve, or
+// the API key may not read, is marked for fallback so its holdings can be built from its accounts
+// instead (see accountHoldings). Any other portfolio that fails is logged and left unfetched,
+// with its error in failures[i]; only a done ctx is returned as an error.
+func (c *Client) fetchHoldings(ctx context.Context, portfolios []coinbasePortfolio) (holdings [][]coinbaseSpotPosition, fetched, fallback []bool, failures []error, err error) {
+	holdings = make([][]coinbaseSpotPosition, len(portfolios))
+	fetched = make([]bool, len(portfolios))
+	fallback = make([]bool, len(portfolios))
+	failures = make([]error, len(portfolios))
+
+	var g errgroup.Group
+	g.SetLimit(holdingsConcurrency)
+	for i, portfolio := range portfolios {
+		g.Go(func() error {
+			// A cancelled sync skips the portfolios still waiting for a worker
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			log.Printf("Info: Fetching holdings for portfolio %s (%s)", portfolio.UUID, portfolio.Name)
+			positions, err := c.GetPortfolioHoldings(ctx, portfolio.UUID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				var apiErr *APIError
+				if errors.As(err, &apiErr) && breakdownUnavailable(apiErr.StatusCode) {
+					log.Printf("Warning: Breakdown of portfolio %s unavailable (%d), falling back to its accounts", portfolio.UUID, apiErr.StatusCode)
+					fallback[i] = true
+					return nil
+				}
+				// Log but continue with other portfolios
+				log.Printf("Warning: Failed to get holdings for portfolio %s: %v", portfolio.UUID, err)
+				failures[i] = err
+				return nil
+			}
+			log.Printf("Info: Found %d spot positions in portfolio %s", len(positions), portfolio.UUID)
+			holdings[i] = positions
+			fetched[i] = true
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return holdings, fetched, fallback, failures, nil
+}
+
+// breakdownUnavailable reports whether a portfolio breakdown failed with a status that will not
+// change on retry: the endpoint is not served, or the API key lacks the permission
+func breakdownUnavailable(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusForbidden
+}
+
+// accountHoldings builds the crypto positions of a portfolio from its accounts, for portfolios
+// whose breakdown is unavailable. They are market priced, having no fiat balance, and stand in
+// the account for the asset UUID accounts do not report, so their investment IDs differ from
+// those synced from a breakdown. Cash and stablecoin balances are left to cashInvestments.
+func accountHoldings(portfolioID string, accounts []*models.Account) []coinbaseSpotPosition {
 	positions := []coinbaseSpotPosition{}
-	for _, pos := range apiResp.Breakdown.SpotPositions {
-		if !pos.IsCash {
-			positions = append(positions, pos)
+	for _, account := range accounts {
+		code := strings.ToUpper(account.Currency)
+		if account.PortfolioID != portfolioID || account.IsCash() || stablecoins[code] {
+			continue
 		}
+		if !account.Active || account.Balance() == 0 {
+			continue
+		}
+		positions = append(positions, coinbaseSpotPosition{
+			Asset:              code,
+			AccountUUID:        account.ID,
+			TotalBalanceCrypto: account.Balance(),
+			AssetUUID:          account.ID,
+			FiatCurrency:       reportingCurrency,
+			MarketPriced:       true,
+		})
 	}
+	return positions
+}
 
-	return positions, nil
+// fillFromAccounts gives every portfolio marked for fallback its holdings from accounts, marking
+// it fetched. Without accounts they stay unfetched.
+func fillFromAccounts(portfolios []coinbasePortfolio, accounts []*models.Account, holdings [][]coinbaseSpotPosition, fetched, fallback []bool) {
+	if accounts == nil {
+		return
+	}
+	for i, portfolio := range portfolios {
+		if fallback[i] {
+			holdings[i] = accountHoldings(portfolio.UUID, accounts)
+			fetched[i] = true
+			log.Printf("Info: Built %d positions for portfolio %s from its accounts", len(holdings[i]), portfolio.UUID)
+		}
+	}
 }
 
-// GetProductPrice fetches current price for a product
-func (c *Client) GetProductPrice(productID string) (float64, error) {
-	path := fmt.Sprintf("/brokerage/products/%s", productID)
-	resp, err := c.makeRequest("GET", path, nil)
+// priceHoldings fetches the current price of the asset of every position in holdings that needs
+// a market price (see needsMarketPrice), keyed by asset; other breakdown positions carry their
+// own. A staked asset is priced as the asset it stands for. A failure is logged and leaves the
+// prices it affects out, so those positions are skipped; failures holds why, by product ID. Only
+// a done ctx is returned as an error.
+func (c *Client) priceHoldings(ctx context.Context, holdings ...[]coinbaseSpotPosition) (prices map[string]float64, failures map[string]error, err error) {
+	productIDs := make([]string, 0)
+	for _, positions := range holdings {
+		for _, position := range positions {
+			if !needsMarketPrice(position) {
+				continue
+			}
+			if id := positionProductID(position); !slices.Contains(productIDs, id) {
+				productIDs = append(productIDs, id)
+			}
+		}
+	}
+	if len(productIDs) == 0 {
+		return nil, nil, nil
+	}
+
+	quotes, failures, err := c.getPrices(ctx, productIDs)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch product: %w", err)
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
+	log.Printf("Info: Priced %d of %d held products", len(quotes), len(productIDs))
+	prices = make(map[string]float64, len(quotes))
+	for _, positions := range holdings {
+		for _, position := range positions {
+			if price, ok := quotes[positionProductID(position)]; ok {
+				prices[position.Asset] = price
+			}
+		}
+	}
+	return prices, failures, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return 0, &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(bodyBytes),
+// positionPrice returns the price of a spot position in the reporting currency: for a market
+// priced position its asset's price in prices, otherwise the price the breakdown reports,
+// converted with rates, else for a position without liquid units its market price if prices has
+// one or its average entry price. ok is false if none of these is known.
+func positionPrice(position coinbaseSpotPosition, prices, rates map[string]float64) (float64, bool) {
+	if position.MarketPriced {
+		price, ok := prices[position.Asset]
+		return price, ok
+	}
+	if price, ok := position.Price(); ok {
+		return toReporting(price, position.FiatCurrency, rates)
+	}
+	if price, ok := prices[position.Asset]; ok {
+		return price, true
+	}
+	if position.AverageEntryPrice.Value != "" {
+		price, _ := strconv.ParseFloat(position.AverageEntryPrice.Value, 64)
+		code := position.AverageEntryPrice.Currency
+		if code == "" {
+			code = position.FiatCurrency
 		}
+		return toReporting(price, code, rates)
 	}
+	return 0, false
+}
 
-	var product coinbaseProduct
-	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+// positionValue returns the price and value of a spot position in the reporting currency. ok is
+// false if the position has no price, or its balance's currency no rate.
+func positionValue(position coinbaseSpotPosition, prices, rates map[string]float64) (price, value float64, ok bool) {
+	price, ok = positionPrice(position, prices, rates)
+	if !ok {
+		return 0, 0, false
+	}
+	if position.MarketPriced {
+		return price, price * position.TotalBalanceCrypto, true
 	}
+	value, ok = toReporting(position.TotalBalanceFiat, position.FiatCurrency, rates)
+	return price, value, ok
+}
 
-	price, err := strconv.ParseFloat(product.Price, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse price: %w", err)
+// positionInvestment converts a spot position in portfolioID into an investment valued in the
+// reporting currency, recording the balance Coinbase reported when it was in another currency.
+// A position that cannot be valued is kept at its investment's price in lastPrices, flagged
+// stale; ok is false if it has none.
+func positionInvestment(portfolioID string, position coinbaseSpotPosition, prices, rates, lastPrices map[string]float64) (*models.Investment, bool) {
+	id := fmt.Sprintf("%s-%s", portfolioID, position.AssetUUID)
+	price, value, ok := positionValue(position, prices, rates)
+	stale := false
+	if !ok {
+		if price, ok = lastPrices[id]; !ok {
+			return nil, false
+		}
+		value, stale = price*position.TotalBalanceCrypto, true
 	}
 
-	return price, nil
+	// Stablecoins are counted as cash, like the stablecoin balances of accounts
+	assetType := models.AssetTypeCrypto
+	if stablecoins[strings.ToUpper(position.Asset)] {
+		assetType = models.AssetTypeCash
+	}
+	// An asset standing for staked units is held as the asset staked
+	symbol, staked := position.Asset, false
+	if underlying, ok := stakedAssets[strings.ToUpper(position.Asset)]; ok {
+		symbol, staked = underlying, true
+	}
+
+	// Use the asset symbol (e.g., "BTC", "ETH") and total balance in crypto as quantity
+	investment := &models.Investment{
+		ID:          id,
+		AccountID:   portfolioID,
+		Platform:    models.PlatformCoinbase,
+		Symbol:      symbol,
+		Name:        position.Asset,
+		Quantity:    position.TotalBalanceCrypto,
+		Value:       value,
+		Price:       price,
+		Currency:    reportingCurrency,
+		AssetType:   assetType,
+		Staked:      staked,
+		StalePrice:  stale,
+		LastUpdated: models.Now(),
+	}
+	if native := strings.ToUpper(position.FiatCurrency); native != "" && native != reportingCurrency && !stale {
+		nativeValue := position.TotalBalanceFiat
+		investment.NativeValue = &nativeValue
+		investment.NativeCurrency = native
+	}
+	return investment, true
 }
 
 // GetInvestments fetches investment holdings from Coinbase
-func (c *Client) GetInvestments(accountID string) ([]*models.Investment, error) {
+func (c *Client) GetInvestments(ctx context.Context, accountID string) ([]*models.Investment, error) {
 	// First, get all portfolios
-	portfolios, err := c.GetPortfolios()
+	portfolios, err := c.GetPortfolios(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get portfolios: %w", err)
 	}
 
 	investments := make([]*models.Investment, 0)
 
-	// For each portfolio, get holdings
-	for _, portfolio := range portfolios {
-		holdings, err := c.GetPortfolioHoldings(portfolio.UUID)
+	// Get the holdings of several portfolios at once
+	portfolioHoldings, fetched, fallback, _, err := c.fetchHoldings(ctx, portfolios)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(fallback, true) {
+		accounts, err := c.GetAccounts(ctx)
 		if err != nil {
-			// Log error but continue with other portfolios
-			continue
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Warning: Failed to get accounts for portfolios without a breakdown: %v", err)
 		}
+		fillFromAccounts(portfolios, accounts, portfolioHoldings, fetched, fallback)
+	}
 
-		for _, position := range holdings {
-			// Use the asset symbol (e.g., "BTC", "ETH")
-			symbol := position.Asset
-			
-			// Calculate price from average entry price if available
-			var price float64
-			if position.AverageEntryPrice.Value != "" {
-				price, _ = strconv.ParseFloat(position.AverageEntryPrice.Value, 64)
-			} else if position.TotalBalanceCrypto > 0 {
-				price = position.TotalBalanceFiat / position.TotalBalanceCrypto
-			} else {
-				continue
-			}
+	// Price the assets held without a breakdown together rather than one request per holding
+	prices, _, err := c.priceHoldings(ctx, portfolioHoldings...)
+	if err != nil {
+		return nil, err
+	}
 
-			quantity := position.TotalBalanceCrypto
-			value := position.TotalBalanceFiat
+	// Balances in other currencies are converted into the reporting currency
+	rates, err := c.holdingRates(ctx, portfolioHoldings...)
+	if err != nil {
+		return nil, err
+	}
 
-			investment := &models.Investment{
-				ID:          fmt.Sprintf("%s-%s", portfolio.UUID, position.AssetUUID),
-				AccountID:   portfolio.UUID,
-				Platform:    models.PlatformCoinbase,
-				Symbol:      symbol,
-				Name:        symbol,
-				Quantity:    quantity,
-				Value:       value,
-				Price:       price,
-				Currency:    "USD",
-				AssetType:    "crypto",
-				LastUpdated: time.Now().UTC().Format(time.RFC3339),
+	for i, portfolio := range portfolios {
+		for _, position := range portfolioHoldings[i] {
+			investment, ok := positionInvestment(portfolio.UUID, position, prices, rates, nil)
+			if !ok {
+				continue
 			}
 			investments = append(investments, investment)
+			if staked, _, priced := stakedInvestment(portfolio.UUID, position, prices, rates, nil); priced {
+				investments = append(investments, staked)
+			}
 		}
 	}
 
 	return investments, nil
 }
 
-// SyncAll syncs all portfolios and investments from Coinbase
-// Uses Portfolio primary view access which is the standard for Coinbase Advanced Trade
-func (c *Client) SyncAll() ([]*models.Portfolio, []*models.Investment, error) {
-	log.Printf("SyncAll: Starting sync with API key: %s", c.apiKeyName)
+// SyncOptions adjusts what SyncAll fetches
+type SyncOptions struct {
+	// FillsSince limits the fills fetched to those executed at or after it; zero fetches them all
+	FillsSince time.Time
+	// LastPrices holds the last known price of stored investments by ID. A holding that cannot
+	// be priced is kept at it and flagged stale rather than being left out.
+	LastPrices map[string]float64
+}
+
+// SyncAll syncs all portfolios, investments, accounts and fills from Coinbase
+// Uses Portfolio primary view access which is the standard for Coinbase Advanced Trade.
+// Holdings are valued from each portfolio's breakdown; a portfolio whose breakdown is not served or
+// not permitted falls back to its account balances priced at market.
+// Accounts and fills are best effort: if they cannot be fetched the sync still returns portfolios and investments.
+// Fiat and stablecoin account balances are returned as cash investments belonging to their account.
+// A portfolio is listed in CompletePortfolios only if all of its holdings were fetched and converted,
+// and an account only if its cash balance was.
+// The sync stops when ctx is done or the client's sync timeout passes; it only reads from Coinbase,
+// so a sync that stops early has nothing to undo.
+func (c *Client) SyncAll(ctx context.Context, opts SyncOptions) (*models.SyncResult, error) {
+	if c.syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.syncTimeout)
+		defer cancel()
+	}
+	log.Printf("SyncAll: Starting sync [%s] with a %s key", correlationID(ctx), c.keyAlgorithm)
 
 	// Get portfolios and investments
 	// This works with "Portfolio primary view access"
 	investments := make([]*models.Investment, 0)
 	log.Printf("SyncAll: Attempting to fetch portfolios...")
-	portfolios, err := c.GetPortfolios()
+	portfolios, err := c.GetPortfolios(ctx)
 	if err != nil {
 		// If we can't get portfolios either, return what we have
 		if apiErr, ok := err.(*APIError); ok {
@@ -422,11 +1265,26 @@ func (c *Client) SyncAll() ([]*models.Portfolio, []*models.Investment, error) {
 		} else {
 			log.Printf("Error: Failed to get portfolios: %v", err)
 		}
-		return nil, investments, fmt.Errorf("failed to get portfolios: %w", err)
+		return nil, fmt.Errorf("failed to get portfolios: %w", err)
 	}
 
 	log.Printf("Info: Found %d portfolios", len(portfolios))
 
+	// Portfolios of excluded types are neither stored nor fetched. Their holdings from earlier
+	// syncs are deactivated, since they no longer count towards net worth.
+	var report models.SyncReport
+	completePortfolios := make([]string, 0, len(portfolios))
+	portfolios, excluded := c.selectPortfolios(portfolios)
+	for _, p := range excluded {
+		log.Printf("Info: Skipping portfolio %s (%s) of type %s", p.UUID, p.Name, p.Type)
+		report.SkippedPortfolios = append(report.SkippedPortfolios, models.SkippedPortfolio{
+			ID:   p.UUID,
+			Name: p.Name,
+			Type: portfolioType(p.Type),
+		})
+		completePortfolios = append(completePortfolios, p.UUID)
+	}
+
 	// Convert portfolios to models
 	portfolioModels := make([]*models.Portfolio, 0, len(portfolios))
 	for _, p := range portfolios {
@@ -434,69 +1292,235 @@ func (c *Client) SyncAll() ([]*models.Portfolio, []*models.Investment, error) {
 			ID:         p.UUID,
 			Platform:   models.PlatformCoinbase,
 			Name:       p.Name,
-			Type:       p.Type,
-			LastSynced: time.Now().UTC().Format(time.RFC3339),
+			Type:       portfolioType(p.Type),
+			LastSynced: models.Now(),
 		})
 	}
 
-	// For each portfolio, get holdings directly
-	for _, portfolio := range portfolios {
-		log.Printf("Info: Fetching holdings for portfolio %s (%s)", portfolio.UUID, portfolio.Name)
-		holdings, err := c.GetPortfolioHoldings(portfolio.UUID)
-		if err != nil {
-			// Log but continue with other portfolios
-			if apiErr, ok := err.(*APIError); ok {
-				log.Printf("Warning: Failed to get holdings for portfolio %s: %d - %s", portfolio.UUID, apiErr.StatusCode, apiErr.Message)
-			} else {
-				log.Printf("Warning: Failed to get holdings for portfolio %s: %v", portfolio.UUID, err)
+	// INTX portfolios hold perpetual positions rather than a spot breakdown
+	portfolios, intxPortfolios := c.splitIntx(portfolios)
+
+	// Get the holdings of several portfolios at once; a portfolio that fails is logged and skipped
+	portfolioHoldings, fetched, fallback, failures, err := c.fetchHoldings(ctx, portfolios)
+	if err != nil {
+		return nil, fmt.Errorf("sync cancelled: %w", err)
+	}
+	// Rate limiting outlasting the sync would fail every request after it, so the sync ends
+	// rather than saving whatever got through
+	if limited := asRateLimited(failures...); limited != nil {
+		return nil, fmt.Errorf("sync rate limited: %w", limited)
+	}
+
+	// Accounts carry the cash balances, which the portfolio breakdown leaves out, and the
+	// holdings of portfolios without a breakdown
+	log.Printf("SyncAll: Attempting to fetch accounts...")
+	accounts, err := c.GetAccounts(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("sync cancelled: %w", ctx.Err())
+		}
+		if limited := asRateLimited(err); limited != nil {
+			return nil, fmt.Errorf("sync rate limited: %w", limited)
+		}
+		log.Printf("Warning: Failed to get accounts: %v", err)
+		report.Warnings = append(report.Warnings, "failed to get accounts, so cash balances were not synced: "+err.Error())
+		accounts = nil
+	}
+	fillFromAccounts(portfolios, accounts, portfolioHoldings, fetched, fallback)
+
+	// Breakdown positions carry their value; only holdings built from accounts are priced, with
+	// one or two batch requests rather than one per holding
+	prices, priceFailures, err := c.priceHoldings(ctx, portfolioHoldings...)
+	if err != nil {
+		return nil, fmt.Errorf("sync cancelled: %w", err)
+	}
+	// Portfolios that report balances in another currency are converted into the reporting
+	// currency; a portfolio whose rate is missing is left incomplete rather than mixing currencies
+	rates, err := c.holdingRates(ctx, portfolioHoldings...)
+	if err != nil {
+		return nil, fmt.Errorf("sync cancelled: %w", err)
+	}
+
+	reported := make(map[string]bool)
+	unfetched := make(map[string]bool)
+	for i, portfolio := range portfolios {
+		portfolioReport := models.PortfolioSyncReport{ID: portfolio.UUID, Name: portfolio.Name}
+		if !fetched[i] {
+			unfetched[portfolio.UUID] = true
+			portfolioReport.Status = models.SyncStatusFailed
+			switch {
+			case failures[i] != nil:
+				portfolioReport.Error = failures[i].Error()
+			case fallback[i]:
+				portfolioReport.Error = "breakdown unavailable and accounts could not be fetched"
 			}
+			report.Portfolios = append(report.Portfolios, portfolioReport)
 			continue
 		}
-
-		log.Printf("Info: Found %d spot positions in portfolio %s", len(holdings), portfolio.UUID)
+		holdings := portfolioHoldings[i]
+		for _, position := range holdings {
+			reported[portfolio.UUID+"/"+strings.ToUpper(position.Asset)] = true
+		}
 
 		// Convert spot positions to investments
 		for _, position := range holdings {
-			// Use the asset symbol as the symbol (e.g., "BTC", "ETH")
-			symbol := position.Asset
-			
-			// Calculate price from average entry price if available, otherwise use current balance
-			var price float64
-			if position.AverageEntryPrice.Value != "" {
-				price, _ = strconv.ParseFloat(position.AverageEntryPrice.Value, 64)
-			} else if position.TotalBalanceCrypto > 0 {
-				// Fallback: calculate price from fiat balance / crypto balance
-				price = position.TotalBalanceFiat / position.TotalBalanceCrypto
+			// Use the breakdown's value, or the market price of a holding built from an account,
+			// and convert balances in other currencies. One that cannot be valued keeps its last
+			// known price.
+			investment, ok := positionInvestment(portfolio.UUID, position, prices, rates, opts.LastPrices)
+			if ok {
+				investments = append(investments, investment)
+				portfolioReport.Holdings.Add(investment.StalePrice)
+				log.Printf("Info: Added investment: %s - Quantity: %f, Value: $%.2f", investment.Symbol, investment.Quantity, investment.Value)
 			} else {
-				// If no price available, skip this position
-				log.Printf("Warning: No price available for asset %s, skipping", symbol)
+				// If no price or exchange rate available, skip this position
+				log.Printf("Warning: No price or %s rate available for asset %s, skipping", reportingCurrency, position.Asset)
+				portfolioReport.Holdings.Skip()
+				report.SkippedAssets = append(report.SkippedAssets, models.SkippedAsset{
+					ProductID:   positionProductID(position),
+					PortfolioID: portfolio.UUID,
+					Reason:      skipReason(position, rates, priceFailures),
+				})
+			}
+
+			// Staked units are a holding of their own beside the liquid one
+			staked, hasStaked, priced := stakedInvestment(portfolio.UUID, position, prices, rates, opts.LastPrices)
+			if hasStaked && !priced {
+				log.Printf("Warning: No price available for staked %s, skipping", position.Asset)
+				portfolioReport.Holdings.Skip()
+				report.SkippedAssets = append(report.SkippedAssets, models.SkippedAsset{
+					ProductID:   positionProductID(position),
+					PortfolioID: portfolio.UUID,
+					Staked:      true,
+					Reason:      skipReason(position, rates, priceFailures),
+				})
 				continue
 			}
+			if priced {
+				investments = append(investments, staked)
+				portfolioReport.Holdings.Add(staked.StalePrice)
+				log.Printf("Info: Added staked investment: %s - Quantity: %f, Value: $%.2f", staked.Symbol, staked.Quantity, staked.Value)
+			}
+		}
 
-			// Use total balance in crypto as quantity
-			quantity := position.TotalBalanceCrypto
-			value := position.TotalBalanceFiat
+		log.Printf("Info: Converted %d spot positions to investments from portfolio %s", len(holdings), portfolio.UUID)
+		portfolioReport.Status = portfolioStatus(portfolioReport.Holdings)
+		if portfolioReport.Status == models.SyncStatusSuccess {
+			completePortfolios = append(completePortfolios, portfolio.UUID)
+		}
+		report.Holdings.Merge(portfolioReport.Holdings)
+		report.Portfolios = append(report.Portfolios, portfolioReport)
+	}
 
-			investment := &models.Investment{
-				ID:          fmt.Sprintf("%s-%s", portfolio.UUID, position.AssetUUID),
-				AccountID:   portfolio.UUID,
-				Platform:    models.PlatformCoinbase,
-				Symbol:      symbol,
-				Name:        symbol,
-				Quantity:    quantity,
-				Value:       value,
-				Price:       price,
-				Currency:    "USD", // Portfolio breakdown returns values in USD
-				AssetType:    "crypto",
-				LastUpdated: time.Now().UTC().Format(time.RFC3339),
+	// INTX portfolios count their collateral and the unrealized profit or loss of their
+	// positions. A portfolio whose key lacks INTX access fails, keeping its earlier holdings.
+	intxPortfolioIDs := make(map[string]bool, len(intxPortfolios))
+	for _, portfolio := range intxPortfolios {
+		intxPortfolioIDs[portfolio.UUID] = true
+		portfolioReport := models.PortfolioSyncReport{ID: portfolio.UUID, Name: portfolio.Name}
+		positions, skipped, err := c.intxInvestments(ctx, portfolio.UUID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("sync cancelled: %w", ctx.Err())
 			}
-			investments = append(investments, investment)
-			log.Printf("Info: Added investment: %s - Quantity: %f, Value: $%.2f", symbol, quantity, value)
+			if limited := asRateLimited(err); limited != nil {
+				return nil, fmt.Errorf("sync rate limited: %w", limited)
+			}
+			log.Printf("Warning: Failed to sync INTX portfolio %s: %v", portfolio.UUID, err)
+			portfolioReport.Status = models.SyncStatusFailed
+			portfolioReport.Error = err.Error()
+			report.Portfolios = append(report.Portfolios, portfolioReport)
+			continue
+		}
+		investments = append(investments, positions...)
+		for range positions {
+			portfolioReport.Holdings.Add(false)
+		}
+		for range skipped {
+			portfolioReport.Holdings.Skip()
 		}
+		report.SkippedAssets = append(report.SkippedAssets, skipped...)
+		log.Printf("Info: Converted %d INTX positions and balances to investments from portfolio %s", len(positions), portfolio.UUID)
+		portfolioReport.Status = portfolioStatus(portfolioReport.Holdings)
+		if portfolioReport.Status == models.SyncStatusSuccess {
+			completePortfolios = append(completePortfolios, portfolio.UUID)
+		}
+		report.Holdings.Merge(portfolioReport.Holdings)
+		report.Portfolios = append(report.Portfolios, portfolioReport)
+	}
 
-		log.Printf("Info: Converted %d spot positions to investments from portfolio %s", len(holdings), portfolio.UUID)
+	// Fiat and stablecoin balances count towards net worth as cash holdings. Without accounts
+	// no account is complete, so cash holdings from earlier syncs stay active. The balances of
+	// INTX portfolios are their collateral, already counted above.
+	cashSources := make([]*models.Account, 0, len(accounts))
+	for _, account := range accounts {
+		if !intxPortfolioIDs[account.PortfolioID] {
+			cashSources = append(cashSources, account)
+		}
+	}
+	cash, cashAccounts, skippedCash, err := c.cashInvestments(ctx, cashSources, reported, unfetched, opts.LastPrices)
+	if err != nil {
+		return nil, fmt.Errorf("sync cancelled: %w", err)
+	}
+	log.Printf("Info: Added %d cash balances from accounts", len(cash))
+	investments = append(investments, cash...)
+	completePortfolios = append(completePortfolios, cashAccounts...)
+	for _, investment := range cash {
+		report.Holdings.Add(investment.StalePrice)
+	}
+	for range skippedCash {
+		report.Holdings.Skip()
+	}
+	report.SkippedAssets = append(report.SkippedAssets, skippedCash...)
+
+	// The 24 hour price moves only inform the net worth change; without them it is unknown
+	if err := c.applyPriceChanges(ctx, investments); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("sync cancelled: %w", ctx.Err())
+		}
+		log.Printf("Warning: Failed to get 24h price changes: %v", err)
+		report.Warnings = append(report.Warnings, "failed to get 24h price changes: "+err.Error())
 	}
 
-	log.Printf("Info: SyncAll completed - %d portfolios, %d investments", len(portfolioModels), len(investments))
-	return portfolioModels, investments, nil
+	var stale []string
+	for _, investment := range investments {
+		if investment.StalePrice {
+			stale = append(stale, fmt.Sprintf("%s (%s)", investment.Symbol, investment.ID))
+		}
+	}
+	if len(stale) > 0 {
+		log.Printf("Warning: Kept %d holdings at their last known price: %s", len(stale), strings.Join(stale, ", "))
+	}
+
+	// Fills record the trades behind the holdings; they are stored by trade ID, so fetching
+	// from the last synced fill again is harmless
+	log.Printf("SyncAll: Attempting to fetch fills...")
+	transactions, err := c.GetFills(ctx, "", opts.FillsSince)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("sync cancelled: %w", ctx.Err())
+		}
+		if limited := asRateLimited(err); limited != nil {
+			return nil, fmt.Errorf("sync rate limited: %w", limited)
+		}
+		log.Printf("Warning: Failed to get fills: %v", err)
+		report.Warnings = append(report.Warnings, "failed to get fills: "+err.Error())
+		transactions = nil
+	}
+
+	finishReport(&report)
+	if report.Status != models.SyncStatusSuccess {
+		log.Printf("Warning: SyncAll partially completed - %d of %d holdings skipped, %d warnings", report.Holdings.Skipped, report.Holdings.Fetched, len(report.Warnings))
+	}
+	log.Printf("Info: SyncAll completed - %d portfolios, %d investments, %d accounts, %d fills", len(portfolioModels), len(investments), len(accounts), len(transactions))
+	return &models.SyncResult{
+		Platform:           models.PlatformCoinbase,
+		Portfolios:         portfolioModels,
+		Accounts:           accounts,
+		Investments:        investments,
+		Transactions:       transactions,
+		CompletePortfolios: completePortfolios,
+		StaleAssets:        stale,
+		Report:             report,
+	}, nil
 }
@@ -0,0 +1,268 @@
+package coinbase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// redirectTransport sends every request to a test server instead of Coinbase
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// testKeySecret returns a new ECDSA P-256 private key in PEM, as CDP issues them
+func testKeySecret(t testing.TB) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+// newTestClient returns a client whose requests handler serves, without rate limiting
+func newTestClient(t testing.TB, handler http.Handler, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COINBASE_RATE_LIMIT", "0")
+	client, err := NewClient("organizations/test/apiKeys/test", testKeySecret(t),
+		append([]Option{WithTransport(redirectTransport{target: target})}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestGetAccountsFollowsCursor(t *testing.T) {
+	var cursors []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/brokerage/accounts" {
+			http.NotFound(w, r)
+			return
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "250" {
+			t.Errorf("limit = %q, want 250", limit)
+		}
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		switch cursor {
+		case "":
+			w.Write([]byte(`{"accounts":[
+				{"uuid":"btc","currency":"BTC","active":true,"available_balance":{"value":"0.5","currency":"BTC"}},
+				{"uuid":"eth","currency":"ETH","active":true,"available_balance":{"value":"2","currency":"ETH"}}
+			],"has_next":true,"cursor":"page-2"}`))
+		case "page-2":
+			w.Write([]byte(`{"accounts":[
+				{"uuid":"usd","currency":"USD","active":true,"available_balance":{"value":"1500.25","currency":"USD"}}
+			],"has_next":false,"cursor":""}`))
+		default:
+			t.Errorf("unexpected cursor %q", cursor)
+			http.NotFound(w, r)
+		}
+	}))
+
+	accounts, err := client.GetAccounts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cursors) != 2 {
+		t.Fatalf("fetched pages with cursors %q, want the first page and page-2", cursors)
+	}
+	if len(accounts) != 3 {
+		t.Fatalf("got %d accounts, want 3 across both pages", len(accounts))
+	}
+	if usd := accounts[2]; usd.ID != "usd" || usd.Available != 1500.25 || !usd.Active {
+		t.Fatalf("second page account = %+v, want the active USD account with 1500.25", usd)
+	}
+}
+
+func TestGetAccountsStopsRepeatingCursor(t *testing.T) {
+	pages := 0
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		// An API that keeps reporting more pages with the same cursor
+		w.Write([]byte(`{"accounts":[{"uuid":"btc","currency":"BTC","active":true}],"has_next":true,"cursor":"same"}`))
+	}))
+
+	if _, err := client.GetAccounts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if pages != 2 {
+		t.Fatalf("fetched %d pages, want to stop once the cursor repeats", pages)
+	}
+}
+
+func TestGetPortfoliosFollowsCursorInBothShapes(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"portfolios":[{"uuid":"p1","name":"Default","type":"DEFAULT"}],"has_next":true,"cursor":"page-2"}`))
+		case "page-2":
+			// Some API versions list portfolios under data instead
+			w.Write([]byte(`{"data":[{"uuid":"p2","name":"Savings","type":"CONSUMER"}],"has_next":false}`))
+		}
+	}))
+
+	portfolios, err := client.GetPortfolios(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(portfolios) != 2 || portfolios[0].UUID != "p1" || portfolios[1].UUID != "p2" {
+		t.Fatalf("portfolios = %+v, want p1 from the first page and p2 from the second", portfolios)
+	}
+}
+
+func TestGetPortfolioHoldingsFollowsCursor(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/brokerage/portfolios/p1" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Write([]byte(`{"breakdown":{"portfolio_balances":{"total_balance":{"value":"70150","currency":"USD"}},"spot_positions":[
+				{"asset":"USD","total_balance_fiat":50,"total_balance_crypto":50,"is_cash":true},
+				{"asset":"DOGE","total_balance_fiat":100,"total_balance_crypto":1000}
+			]},"has_next":true,"cursor":"page-2"}`))
+		case "page-2":
+			// The most valuable holding is on the last page
+			w.Write([]byte(`{"breakdown":{"portfolio_balances":{"total_balance":{"value":"70150","currency":"USD"}},"spot_positions":[
+				{"asset":"BTC","total_balance_fiat":70000,"total_balance_crypto":1}
+			]},"has_next":false}`))
+		}
+	}))
+
+	holdings, err := client.GetPortfolioHoldings(context.Background(), "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holdings) != 2 {
+		t.Fatalf("got %d holdings, want DOGE and BTC without the cash position", len(holdings))
+	}
+	var total float64
+	for _, holding := range holdings {
+		total += holding.TotalBalanceFiat
+		if holding.FiatCurrency != "USD" {
+			t.Errorf("%s fiat currency = %q, want USD", holding.Asset, holding.FiatCurrency)
+		}
+	}
+	if holdings[1].Asset != "BTC" || total != 70100 {
+		t.Fatalf("holdings = %+v worth %v, want BTC from the second page and 70100 in all", holdings, total)
+	}
+}
+
+func TestFetchHoldingsConcurrently(t *testing.T) {
+	const latency = 100 * time.Millisecond
+	var inFlight, maxInFlight atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(latency)
+
+		uuid := strings.TrimPrefix(r.URL.Path, "/api/v3/brokerage/portfolios/")
+		if uuid == "p3" {
+			http.Error(w, `{"message":"bad portfolio"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"breakdown":{"spot_positions":[{"asset":"` + strings.ToUpper(uuid) + `","total_balance_fiat":1,"total_balance_crypto":1}]}}`))
+	}))
+
+	portfolios := make([]coinbasePortfolio, 8)
+	for i := range portfolios {
+		portfolios[i] = coinbasePortfolio{UUID: fmt.Sprintf("p%d", i)}
+	}
+	start := time.Now()
+	holdings, fetched, _, failures, err := client.fetchHoldings(context.Background(), portfolios)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Eight portfolios four at a time take two round trips rather than eight
+	if elapsed >= 4*latency {
+		t.Errorf("fetching 8 portfolios took %v, want about 2 x %v", elapsed, latency)
+	}
+	if peak := maxInFlight.Load(); peak > holdingsConcurrency {
+		t.Errorf("%d requests in flight at once, want at most %d", peak, holdingsConcurrency)
+	}
+	for i, portfolio := range portfolios {
+		if i == 3 {
+			if fetched[i] || failures[i] == nil {
+				t.Errorf("failing portfolio p3 fetched = %v, failure = %v, want it skipped with its error", fetched[i], failures[i])
+			}
+			continue
+		}
+		if !fetched[i] || len(holdings[i]) != 1 || holdings[i][0].Asset != strings.ToUpper(portfolio.UUID) {
+			t.Errorf("portfolio %s holdings = %+v, want its own position", portfolio.UUID, holdings[i])
+		}
+	}
+}
+
+// hangingHandler never answers, until the client gives up
+func hangingHandler(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+func TestSyncAllTimeout(t *testing.T) {
+	t.Setenv("COINBASE_SYNC_TIMEOUT", "200ms")
+	client := newTestClient(t, http.HandlerFunc(hangingHandler))
+
+	start := time.Now()
+	_, err := client.SyncAll(context.Background(), SyncOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SyncAll against a server that never answers: %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("SyncAll took %v, want it to stop at the 200ms sync timeout", elapsed)
+	}
+}
+
+func TestSyncAllCancelled(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(hangingHandler))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.SyncAll(ctx, SyncOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled SyncAll: %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("SyncAll took %v after being cancelled at 100ms", elapsed)
+	}
+}
@@ -0,0 +1,132 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// fillsPageSize is the number of fills requested per page
+const fillsPageSize = 100
+
+// Historical fills response types
+type coinbaseFill struct {
+	EntryID           string `json:"entry_id"`
+	TradeID           string `json:"trade_id"`
+	OrderID           string `json:"order_id"`
+	TradeTime         string `json:"trade_time"`
+	Price             string `json:"price"`
+	Size              string `json:"size"`
+	Commission        string `json:"commission"`
+	ProductID         string `json:"product_id"`
+	SizeInQuote       bool   `json:"size_in_quote"`
+	Side              string `json:"side"`
+	RetailPortfolioID string `json:"retail_portfolio_id"`
+}
+
+type coinbaseFillsResponse struct {
+	Fills []coinbaseFill `json:"fills"`
+}
+
+// GetFills fetches the buy and sell fills of productID, or of every product if it is empty,
+// that executed at or after since, following pagination. A zero since fetches the whole history.
+// Each fill becomes a transaction whose ID is derived from the Coinbase trade ID, so fetching a
+// fill again rewrites the same transaction. A fill that cannot be read is logged and skipped.
+func (c *Client) GetFills(ctx context.Context, productID string, since time.Time) ([]*models.Transaction, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(fillsPageSize))
+	if productID != "" {
+		query.Set("product_ids", productID)
+	}
+	if !since.IsZero() {
+		query.Set("start_sequence_timestamp", since.UTC().Format(time.RFC3339))
+	}
+
+	transactions := make([]*models.Transaction, 0)
+	pages, err := c.getAllPages(ctx, "/brokerage/orders/historical/fills?"+query.Encode(), func(bodyBytes []byte) error {
+		var apiResp coinbaseFillsResponse
+		if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+			return fmt.Errorf("failed to decode fills response: %w", err)
+		}
+		for _, fill := range apiResp.Fills {
+			// Perpetual fills open and close positions rather than trade the base asset, so
+			// they would corrupt its cost basis
+			if isPerpetualProduct(fill.ProductID) {
+				continue
+			}
+			transaction, err := fillTransaction(fill)
+			if err != nil {
+				log.Printf("Warning: Skipping Coinbase fill %s: %v", fill.TradeID, err)
+				continue
+			}
+			transactions = append(transactions, transaction)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch fills: %w", err)
+	}
+	log.Printf("GetFills: Fetched %d fills in %d pages", len(transactions), pages)
+	return transactions, nil
+}
+
+// fillTransaction converts a fill into a buy or sell of the product's base asset, with the
+// amount and fee in its quote currency
+func fillTransaction(fill coinbaseFill) (*models.Transaction, error) {
+	if fill.TradeID == "" {
+		return nil, fmt.Errorf("fill has no trade ID")
+	}
+	var txType models.TransactionType
+	switch strings.ToUpper(fill.Side) {
+	case "BUY":
+		txType = models.TransactionTypeBuy
+	case "SELL":
+		txType = models.TransactionTypeSell
+	default:
+		return nil, fmt.Errorf("unknown side %q", fill.Side)
+	}
+	base, quote, ok := strings.Cut(fill.ProductID, "-")
+	if !ok || base == "" || quote == "" {
+		return nil, fmt.Errorf("invalid product ID %q", fill.ProductID)
+	}
+	price, err := strconv.ParseFloat(fill.Price, 64)
+	if err != nil || price <= 0 {
+		return nil, fmt.Errorf("invalid price %q", fill.Price)
+	}
+	size, err := strconv.ParseFloat(fill.Size, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size %q", fill.Size)
+	}
+	// Commission is empty on fills that paid none
+	fee, _ := strconv.ParseFloat(fill.Commission, 64)
+	executed, err := time.Parse(time.RFC3339Nano, fill.TradeTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trade time %q", fill.TradeTime)
+	}
+
+	// A fill's size is in the base asset unless it says it is in the quote currency
+	quantity, amount := size, size*price
+	if fill.SizeInQuote {
+		quantity, amount = size/price, size
+	}
+	return &models.Transaction{
+		ID:          "coinbase-fill-" + fill.TradeID,
+		AccountID:   fill.RetailPortfolioID,
+		Platform:    models.PlatformCoinbase,
+		Type:        txType,
+		Symbol:      strings.ToUpper(base),
+		Quantity:    quantity,
+		Amount:      amount,
+		Currency:    strings.ToUpper(quote),
+		Fee:         fee,
+		Timestamp:   executed,
+		Description: fmt.Sprintf("Coinbase %s fill of order %s", fill.ProductID, fill.OrderID),
+	}, nil
+}
@@ -0,0 +1,105 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"0xnetworth/backend/internal/currency"
+)
+
+// reportingCurrency is the currency investments are valued in, whatever currency a portfolio
+// reports its balances in
+const reportingCurrency = currency.Reporting
+
+// stablecoins are the USD stablecoins valued at one dollar when stablecoin parity is enabled
+var stablecoins = map[string]bool{"USDC": true, "USDT": true}
+
+// FXSource provides exchange rates for converting portfolio balances into the reporting currency
+type FXSource interface {
+	// Rate returns how many units of to one unit of from is worth
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// SetFXSource replaces the exchange rate source, which by default prices currencies with
+// Coinbase products
+func (c *Client) SetFXSource(source FXSource) {
+	c.fx = source
+}
+
+// productFXSource prices one currency in another with the Coinbase product trading them,
+// FROM-TO or else the inverse of TO-FROM
+type productFXSource struct {
+	client *Client
+}
+
+func (p productFXSource) Rate(ctx context.Context, from, to string) (float64, error) {
+	direct, inverse := from+"-"+to, to+"-"+from
+	prices, err := p.client.GetPrices(ctx, []string{direct, inverse})
+	if err != nil {
+		return 0, err
+	}
+	if price, ok := prices[direct]; ok && price > 0 {
+		return price, nil
+	}
+	if price, ok := prices[inverse]; ok && price > 0 {
+		return 1 / price, nil
+	}
+	return 0, fmt.Errorf("no Coinbase product prices %s in %s", from, to)
+}
+
+// holdingRates returns the rate into the reporting currency of every other currency the
+// balances and entry prices of holdings are in; see reportingRates
+func (c *Client) holdingRates(ctx context.Context, holdings ...[]coinbaseSpotPosition) (map[string]float64, error) {
+	var codes []string
+	for _, positions := range holdings {
+		for _, position := range positions {
+			codes = append(codes, position.FiatCurrency, position.AverageEntryPrice.Currency)
+		}
+	}
+	return c.reportingRates(ctx, codes)
+}
+
+// reportingRates returns the rate into the reporting currency of each of codes other than the
+// reporting currency itself. A rate that cannot be found is logged and left out, so the
+// balances it affects are skipped; only a done ctx is returned as an error.
+func (c *Client) reportingRates(ctx context.Context, codes []string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	missing := make(map[string]bool)
+	for _, code := range codes {
+		code = strings.ToUpper(code)
+		if code == "" || code == reportingCurrency {
+			continue
+		}
+		if _, ok := rates[code]; ok || missing[code] {
+			continue
+		}
+		if c.stablecoinParity && stablecoins[code] {
+			rates[code] = 1
+			continue
+		}
+		rate, err := c.fx.Rate(ctx, code, reportingCurrency)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Warning: No %s rate for %s, skipping balances in it: %v", reportingCurrency, code, err)
+			missing[code] = true
+			continue
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
+// toReporting converts amount in code into the reporting currency. An empty code means the
+// reporting currency. ok is false if rates has no rate for code.
+func toReporting(amount float64, code string, rates map[string]float64) (float64, bool) {
+	code = strings.ToUpper(code)
+	if code == "" || code == reportingCurrency {
+		return amount, true
+	}
+	rate, ok := rates[code]
+	return amount * rate, ok
+}
@@ -0,0 +1,264 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// float returns the balance's value, 0 if it is empty or malformed
+func (b coinbaseBalance) float() float64 {
+	value, _ := strconv.ParseFloat(b.Value, 64)
+	return value
+}
+
+// coinbaseIntxPosition is a perpetual futures position in an INTX portfolio
+type coinbaseIntxPosition struct {
+	ProductID        string          `json:"product_id"`
+	Symbol           string          `json:"symbol"`
+	PositionSide     string          `json:"position_side"` // POSITION_SIDE_LONG or POSITION_SIDE_SHORT
+	MarginType       string          `json:"margin_type"`
+	NetSize          string          `json:"net_size"`
+	Leverage         string          `json:"leverage"`
+	EntryVWAP        coinbaseBalance `json:"entry_vwap"`
+	MarkPrice        coinbaseBalance `json:"mark_price"`
+	UnrealizedPnL    coinbaseBalance `json:"unrealized_pnl"`
+	IMNotional       coinbaseBalance `json:"im_notional"` // Initial margin held for the position
+	PositionNotional coinbaseBalance `json:"position_notional"`
+	LiquidationPrice coinbaseBalance `json:"liquidation_price"`
+}
+
+type coinbaseIntxPositionsResponse struct {
+	Positions []coinbaseIntxPosition `json:"positions"`
+}
+
+// coinbaseIntxBalance is an asset held as collateral in an INTX portfolio
+type coinbaseIntxBalance struct {
+	Asset struct {
+		AssetName string `json:"asset_name"`
+	} `json:"asset"`
+	Quantity        string `json:"quantity"`
+	Hold            string `json:"hold"`
+	CollateralValue string `json:"collateral_value"`
+}
+
+type coinbaseIntxBalancesResponse struct {
+	PortfolioBalances []struct {
+		PortfolioUUID string                `json:"portfolio_uuid"`
+		Balances      []coinbaseIntxBalance `json:"balances"`
+	} `json:"portfolio_balances"`
+}
+
+// short reports whether the position is short, so its size counts against the holder
+func (p coinbaseIntxPosition) short() bool {
+	return strings.EqualFold(p.PositionSide, "POSITION_SIDE_SHORT")
+}
+
+// size returns the position's size, negative for a short position
+func (p coinbaseIntxPosition) size() float64 {
+	size, _ := strconv.ParseFloat(p.NetSize, 64)
+	size = math.Abs(size)
+	if p.short() {
+		return -size
+	}
+	return size
+}
+
+// GetIntxPositions fetches the perpetual futures positions of an INTX portfolio. API keys
+// without INTX access are refused with a 403.
+func (c *Client) GetIntxPositions(ctx context.Context, portfolioID string) ([]coinbaseIntxPosition, error) {
+	// GET /api/v3/brokerage/intx/positions/{portfolio_uuid}
+	var apiResp coinbaseIntxPositionsResponse
+	if err := c.getJSON(ctx, "/brokerage/intx/positions/"+portfolioID, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch INTX positions: %w", err)
+	}
+	return apiResp.Positions, nil
+}
+
+// GetIntxBalances fetches the collateral balances of an INTX portfolio
+func (c *Client) GetIntxBalances(ctx context.Context, portfolioID string) ([]coinbaseIntxBalance, error) {
+	// GET /api/v3/brokerage/intx/balances/{portfolio_uuid}
+	var apiResp coinbaseIntxBalancesResponse
+	if err := c.getJSON(ctx, "/brokerage/intx/balances/"+portfolioID, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to fetch INTX balances: %w", err)
+	}
+	balances := make([]coinbaseIntxBalance, 0)
+	for _, portfolio := range apiResp.PortfolioBalances {
+		balances = append(balances, portfolio.Balances...)
+	}
+	return balances, nil
+}
+
+// getJSON makes a GET request and decodes a successful response into v; an error status is
+// returned as an *APIError
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	resp, err := c.makeRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
+	}
+	if err := json.Unmarshal(bodyBytes, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// intxInvestments values an INTX portfolio as its collateral plus the unrealized profit or loss
+// of its positions, never their notional. Each position is a derivative holding whose quantity
+// is negative when short, priced at the mark price and valued at its unrealized PnL; each
+// collateral balance is a holding of its asset. Amounts without a rate into the reporting
+// currency are skipped. A 403 is explained as the key lacking INTX access; only a failed
+// request or a done ctx is returned as an error.
+func (c *Client) intxInvestments(ctx context.Context, portfolioID string) (investments []*models.Investment, skipped []models.SkippedAsset, err error) {
+	positions, err := c.GetIntxPositions(ctx, portfolioID)
+	if err != nil {
+		return nil, nil, intxError(err)
+	}
+	balances, err := c.GetIntxBalances(ctx, portfolioID)
+	if err != nil {
+		return nil, nil, intxError(err)
+	}
+
+	codes := make([]string, 0, 2*len(positions)+len(balances))
+	for _, position := range positions {
+		codes = append(codes, position.UnrealizedPnL.Currency, position.MarkPrice.Currency)
+	}
+	for _, balance := range balances {
+		codes = append(codes, balance.Asset.AssetName)
+	}
+	rates, err := c.reportingRates(ctx, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncedAt := models.Now()
+	for _, position := range positions {
+		size := position.size()
+		if size == 0 {
+			continue
+		}
+		symbol := strings.ToUpper(position.Symbol)
+		if symbol == "" {
+			symbol = strings.ToUpper(position.ProductID)
+		}
+		pnl, pnlOK := toReporting(position.UnrealizedPnL.float(), position.UnrealizedPnL.Currency, rates)
+		mark, markOK := toReporting(position.MarkPrice.float(), position.MarkPrice.Currency, rates)
+		if !pnlOK || !markOK {
+			log.Printf("Warning: No %s rate available for INTX position %s, skipping", reportingCurrency, symbol)
+			skipped = append(skipped, models.SkippedAsset{
+				ProductID:   symbol,
+				PortfolioID: portfolioID,
+				Reason:      fmt.Sprintf("no %s rate for %s", reportingCurrency, strings.ToUpper(position.UnrealizedPnL.Currency)),
+			})
+			continue
+		}
+		side := "long"
+		if position.short() {
+			side = "short"
+		}
+		investment := &models.Investment{
+			ID:             fmt.Sprintf("%s-%s", portfolioID, symbol),
+			AccountID:      portfolioID,
+			Platform:       models.PlatformCoinbase,
+			Symbol:         symbol,
+			Name:           fmt.Sprintf("%s %s", symbol, side),
+			Quantity:       size,
+			Value:          pnl,
+			Price:          mark,
+			Currency:       reportingCurrency,
+			AssetType:      models.AssetTypeDerivative,
+			UnrealizedGain: &pnl,
+			LastUpdated:    syncedAt,
+		}
+		if entry, ok := toReporting(position.EntryVWAP.float(), position.EntryVWAP.Currency, rates); ok && entry > 0 {
+			investment.AverageBuyPrice = &entry
+		}
+		investments = append(investments, investment)
+	}
+
+	for _, balance := range balances {
+		code := strings.ToUpper(balance.Asset.AssetName)
+		quantity, _ := strconv.ParseFloat(balance.Quantity, 64)
+		if code == "" || quantity == 0 {
+			continue
+		}
+		value, ok := toReporting(quantity, code, rates)
+		if !ok {
+			log.Printf("Warning: No %s rate available for %s INTX collateral, skipping", reportingCurrency, code)
+			skipped = append(skipped, models.SkippedAsset{
+				ProductID:   code + "-" + reportingCurrency,
+				PortfolioID: portfolioID,
+				Reason:      fmt.Sprintf("no %s rate for %s", reportingCurrency, code),
+			})
+			continue
+		}
+		assetType := models.AssetTypeCrypto
+		if code == reportingCurrency || stablecoins[code] {
+			assetType = models.AssetTypeCash
+		}
+		investment := &models.Investment{
+			ID:          fmt.Sprintf("%s-collateral-%s", portfolioID, code),
+			AccountID:   portfolioID,
+			Platform:    models.PlatformCoinbase,
+			Symbol:      code,
+			Name:        code + " collateral",
+			Quantity:    quantity,
+			Value:       value,
+			Price:       value / quantity,
+			Currency:    reportingCurrency,
+			AssetType:   assetType,
+			LastUpdated: syncedAt,
+		}
+		if code != reportingCurrency {
+			investment.NativeValue = &quantity
+			investment.NativeCurrency = code
+		}
+		investments = append(investments, investment)
+	}
+	return investments, skipped, nil
+}
+
+// intxError explains a 403 from an INTX endpoint, which is how Coinbase answers keys of users
+// without INTX access
+func intxError(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("the API key has no INTX access (set COINBASE_INTX_ENABLED=false if the account has none): %w", err)
+	}
+	return err
+}
+
+// isPerpetualProduct reports whether a product is a perpetual future, such as BTC-PERP-INTX,
+// whose fills are not trades of its base asset
+func isPerpetualProduct(productID string) bool {
+	return strings.Contains(strings.ToUpper(productID), "-PERP")
+}
+
+// splitIntx separates the INTX portfolios synced from their positions, when INTX support is
+// enabled, from those synced from their breakdown
+func (c *Client) splitIntx(portfolios []coinbasePortfolio) (spot, intx []coinbasePortfolio) {
+	for _, portfolio := range portfolios {
+		if c.intxEnabled && strings.EqualFold(portfolio.Type, portfolioTypeINTX) {
+			intx = append(intx, portfolio)
+		} else {
+			spot = append(spot, portfolio)
+		}
+	}
+	return spot, intx
+}
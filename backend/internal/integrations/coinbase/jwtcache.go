@@ -0,0 +1,65 @@
+package coinbase
+
+import (
+	"sync"
+	"time"
+)
+
+// jwtKey identifies what a token is signed for; Coinbase tokens are scoped to one method and path
+type jwtKey struct {
+	method, path string
+}
+
+type cachedJWT struct {
+	token     string
+	expiresAt time.Time
+}
+
+// jwtCache reuses signed tokens for their method and path until margin before they expire, so a
+// paginated or batched sync signs a token per endpoint rather than per request. It is safe for
+// concurrent use.
+type jwtCache struct {
+	lifetime time.Duration
+	margin   time.Duration
+	mu       sync.Mutex
+	tokens   map[jwtKey]cachedJWT
+}
+
+func newJWTCache(lifetime, margin time.Duration) *jwtCache {
+	return &jwtCache{
+		lifetime: lifetime,
+		margin:   margin,
+		tokens:   make(map[jwtKey]cachedJWT),
+	}
+}
+
+// get returns the cached token for method and path if it is not within the margin of expiring
+func (j *jwtCache) get(method, path string) (string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cached, ok := j.tokens[jwtKey{method, path}]
+	if !ok || !time.Now().Before(cached.expiresAt.Add(-j.margin)) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+// put caches a token signed at signedAt, dropping the tokens that can no longer be reused
+func (j *jwtCache) put(method, path, token string, signedAt time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for key, cached := range j.tokens {
+		if !now.Before(cached.expiresAt.Add(-j.margin)) {
+			delete(j.tokens, key)
+		}
+	}
+	j.tokens[jwtKey{method, path}] = cachedJWT{token: token, expiresAt: signedAt.Add(j.lifetime)}
+}
+
+// invalidate forgets the token for method and path, after Coinbase rejected it
+func (j *jwtCache) invalidate(method, path string) {
+	j.mu.Lock()
+	delete(j.tokens, jwtKey{method, path})
+	j.mu.Unlock()
+}
@@ -0,0 +1,144 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenRecorder serves every request, recording the distinct tokens it was sent. Each distinct
+// token is one signing, since every new token has its own nonce.
+type tokenRecorder struct {
+	mu     sync.Mutex
+	tokens map[string]int
+	// reject401 is how many more requests carrying an already seen token get a 401
+	reject401 int
+}
+
+func newTokenRecorder() *tokenRecorder {
+	return &tokenRecorder{tokens: make(map[string]int)}
+}
+
+func (r *tokenRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token := req.Header.Get("Authorization")
+	seen := r.tokens[token] > 0
+	r.tokens[token]++
+	if seen && r.reject401 > 0 {
+		r.reject401--
+		http.Error(w, `{"message":"token expired"}`, http.StatusUnauthorized)
+		return
+	}
+	w.Write([]byte(`{}`))
+}
+
+func (r *tokenRecorder) signings() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tokens)
+}
+
+// simulateSync sends 100 GET requests spread across 5 endpoints, as a paginated sync does
+func simulateSync(tb testing.TB, client *Client) {
+	tb.Helper()
+	for i := 0; i < 100; i++ {
+		resp, err := client.makeRequest(context.Background(), http.MethodGet, fmt.Sprintf("/brokerage/endpoint-%d", i%5), nil)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func TestJWTReusedPerEndpoint(t *testing.T) {
+	recorder := newTokenRecorder()
+	simulateSync(t, newTestClient(t, recorder))
+	if got := recorder.signings(); got != 5 {
+		t.Fatalf("signed %d tokens for 100 requests to 5 endpoints, want 5", got)
+	}
+}
+
+func TestJWTReuseDisabledByMargin(t *testing.T) {
+	t.Setenv("COINBASE_JWT_EXPIRY_MARGIN", "2m")
+	recorder := newTokenRecorder()
+	simulateSync(t, newTestClient(t, recorder))
+	if got := recorder.signings(); got != 100 {
+		t.Fatalf("signed %d tokens with reuse disabled, want one per request", got)
+	}
+}
+
+func TestJWTResignedAfterUnauthorized(t *testing.T) {
+	recorder := newTokenRecorder()
+	client := newTestClient(t, recorder)
+	for i := 0; i < 2; i++ {
+		resp, err := client.makeRequest(context.Background(), http.MethodGet, "/brokerage/accounts", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d status = %d, want 200", i, resp.StatusCode)
+		}
+		// The reused token is rejected once
+		recorder.reject401 = 1
+	}
+	if got := recorder.signings(); got != 2 {
+		t.Fatalf("signed %d tokens, want a new one after the reused token was rejected", got)
+	}
+}
+
+func TestJWTCacheExpiry(t *testing.T) {
+	cache := newJWTCache(jwtLifetime, 10*time.Second)
+	now := time.Now()
+	cache.put("GET", "/fresh", "fresh", now)
+	cache.put("GET", "/near-expiry", "near-expiry", now.Add(-jwtLifetime+5*time.Second))
+
+	if token, ok := cache.get("GET", "/fresh"); !ok || token != "fresh" {
+		t.Errorf("fresh token = %q, %v, want it reused", token, ok)
+	}
+	if _, ok := cache.get("POST", "/fresh"); ok {
+		t.Error("token reused for another method")
+	}
+	if _, ok := cache.get("GET", "/near-expiry"); ok {
+		t.Error("token within the margin of expiring was reused")
+	}
+	cache.invalidate("GET", "/fresh")
+	if _, ok := cache.get("GET", "/fresh"); ok {
+		t.Error("invalidated token was reused")
+	}
+}
+
+// BenchmarkSync100Requests compares a simulated 100-request sync with token reuse and with
+// a token signed per request, reporting the signings each takes
+func BenchmarkSync100Requests(b *testing.B) {
+	for _, bench := range []struct {
+		name   string
+		margin string
+	}{
+		{"reused", ""},
+		{"signed per request", "2m"},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			if bench.margin != "" {
+				b.Setenv("COINBASE_JWT_EXPIRY_MARGIN", bench.margin)
+			}
+			recorder := newTokenRecorder()
+			client := newTestClient(b, recorder)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// A sync starts with no token cached
+				if client.jwts != nil {
+					client.jwts = newJWTCache(client.jwts.lifetime, client.jwts.margin)
+				}
+				simulateSync(b, client)
+			}
+			b.ReportMetric(float64(recorder.signings())/float64(b.N), "signings/op")
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package coinbase
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// Signing algorithms of the keys CDP issues
+const (
+	algorithmES256 = "ES256" // ECDSA P-256 keys
+	algorithmEdDSA = "EdDSA" // Ed25519 keys
+)
+
+// normalizeKeySecret converts a CDP private key into one of the two forms the CDP SDK signs
+// with: a SEC1 PEM EC key for ES256, or a base64 Ed25519 private key (seed and public key) for
+// EdDSA. It accepts those forms, PKCS#8 keys of either type in PEM or base64 DER, SEC1 EC keys
+// in base64 DER and base64 Ed25519 seeds, and returns the algorithm the key signs with.
+// Newlines escaped as \n, as keys pasted into environment variables often are, are unescaped.
+func normalizeKeySecret(secret string) (normalized, algorithm string, err error) {
+	secret = strings.TrimSpace(strings.ReplaceAll(secret, `\n`, "\n"))
+	if block, _ := pem.Decode([]byte(secret)); block != nil {
+		return normalizeDERKey(block.Bytes)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("private key is neither PEM nor base64")
+	}
+	switch len(decoded) {
+	case ed25519.PrivateKeySize:
+		return secret, algorithmEdDSA, nil
+	case ed25519.SeedSize:
+		return base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(decoded)), algorithmEdDSA, nil
+	}
+	return normalizeDERKey(decoded)
+}
+
+// normalizeDERKey normalizes a SEC1 EC or PKCS#8 private key in DER
+func normalizeDERKey(der []byte) (normalized, algorithm string, err error) {
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return ecKeyPEM(key)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return "", "", fmt.Errorf("private key is not a SEC1 EC or PKCS#8 key: %w", err)
+	}
+	switch key := parsed.(type) {
+	case *ecdsa.PrivateKey:
+		return ecKeyPEM(key)
+	case ed25519.PrivateKey:
+		return base64.StdEncoding.EncodeToString(key), algorithmEdDSA, nil
+	default:
+		return "", "", fmt.Errorf("unsupported private key type %T; CDP keys are ECDSA or Ed25519", parsed)
+	}
+}
+
+// ecKeyPEM encodes an EC key as SEC1 PEM
+func ecKeyPEM(key *ecdsa.PrivateKey) (string, string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid EC private key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), algorithmES256, nil
+}
@@ -0,0 +1,154 @@
+package coinbase
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKey is a generated CDP key in one of the formats NewClient accepts
+type testKey struct {
+	name      string
+	secret    string
+	public    crypto.PublicKey
+	algorithm string
+}
+
+// testKeys generates an ECDSA and an Ed25519 key in each format NewClient accepts
+func testKeys(t *testing.T) []testKey {
+	t.Helper()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec1PEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	return []testKey{
+		{"SEC1 PEM", sec1PEM, &ecKey.PublicKey, algorithmES256},
+		{"SEC1 PEM with escaped newlines", strings.ReplaceAll(sec1PEM, "\n", `\n`), &ecKey.PublicKey, algorithmES256},
+		{"EC PKCS#8 PEM", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8})), &ecKey.PublicKey, algorithmES256},
+		{"SEC1 base64 DER", base64.StdEncoding.EncodeToString(sec1), &ecKey.PublicKey, algorithmES256},
+		{"Ed25519 PKCS#8 PEM", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edPKCS8})), edPublic, algorithmEdDSA},
+		{"Ed25519 base64", base64.StdEncoding.EncodeToString(edKey), edPublic, algorithmEdDSA},
+		{"Ed25519 base64 seed", base64.StdEncoding.EncodeToString(edKey.Seed()), edPublic, algorithmEdDSA},
+	}
+}
+
+// parseJWT verifies token against public, accepting only algorithm
+func parseJWT(token string, public crypto.PublicKey, algorithm string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(*jwt.Token) (any, error) {
+		return public, nil
+	}, jwt.WithValidMethods([]string{algorithm}))
+}
+
+func TestJWTSignedWithKeyAlgorithm(t *testing.T) {
+	for _, key := range testKeys(t) {
+		t.Run(key.name, func(t *testing.T) {
+			client, err := NewClient("organizations/test/apiKeys/test", key.secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.keyAlgorithm != key.algorithm {
+				t.Fatalf("key algorithm = %s, want %s", client.keyAlgorithm, key.algorithm)
+			}
+			token, err := client.GenerateJWT("GET", "/api/v3/brokerage/accounts")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := parseJWT(token, key.public, key.algorithm)
+			if err != nil {
+				t.Fatalf("JWT does not validate against the public key: %v", err)
+			}
+			if alg := parsed.Header["alg"]; alg != key.algorithm {
+				t.Errorf("header alg = %v, want %s", alg, key.algorithm)
+			}
+			if kid := parsed.Header["kid"]; kid != "organizations/test/apiKeys/test" {
+				t.Errorf("header kid = %v, want the key name", kid)
+			}
+		})
+	}
+}
+
+func TestJWTRejectedByOtherKey(t *testing.T) {
+	keys := testKeys(t)
+	ecKey, edKey := keys[0], keys[len(keys)-1]
+	otherKeys := testKeys(t)
+	otherEC, otherEd := otherKeys[0], otherKeys[len(otherKeys)-1]
+
+	for _, tt := range []struct{ key, other testKey }{{ecKey, otherEC}, {edKey, otherEd}} {
+		client, err := NewClient("organizations/test/apiKeys/test", tt.key.secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := client.GenerateJWT("GET", "/api/v3/brokerage/accounts")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseJWT(token, tt.other.public, tt.key.algorithm); err == nil {
+			t.Errorf("%s JWT validates against another key", tt.key.algorithm)
+		}
+
+		// Tampering with the claims invalidates the signature
+		parts := strings.Split(token, ".")
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := strings.Replace(string(claims), "/api/v3/brokerage/accounts", "/api/v3/brokerage/orders", 1)
+		if tampered == string(claims) {
+			t.Fatalf("claims %s do not name the request path", claims)
+		}
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(tampered))
+		if _, err := parseJWT(strings.Join(parts, "."), tt.key.public, tt.key.algorithm); err == nil {
+			t.Errorf("tampered %s JWT validates", tt.key.algorithm)
+		}
+	}
+}
+
+func TestNewClientRejectsUnsupportedKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, secret := range map[string]string{
+		"RSA PKCS#8":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaPKCS8})),
+		"not a key":   "not-a-key",
+		"short bytes": base64.StdEncoding.EncodeToString([]byte("too short")),
+	} {
+		if _, err := NewClient("organizations/test/apiKeys/test", secret); err == nil {
+			t.Errorf("%s: NewClient accepted the key", name)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package coinbase
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultHTTPTimeout bounds a single request, including reading its response, unless
+// COINBASE_HTTP_TIMEOUT or WithTimeout says otherwise
+const defaultHTTPTimeout = 30 * time.Second
+
+// Option configures how NewClient builds a Client
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	timeout    *time.Duration
+	httpClient *http.Client
+	transport  http.RoundTripper
+}
+
+// WithTimeout bounds each request to Coinbase, overriding COINBASE_HTTP_TIMEOUT; 0 means no
+// bound beyond the caller's context. It does not change how long a signed JWT is valid.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = &timeout
+	}
+}
+
+// WithHTTPClient sends requests through a copy of client, keeping its timeout unless
+// WithTimeout is also given
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport sends requests through transport, such as one whose connection pool is shared
+// with other clients
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// newHTTPClient builds the http.Client of a Client from its options and COINBASE_HTTP_TIMEOUT
+func newHTTPClient(opts []Option) (*http.Client, error) {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var client http.Client
+	if o.httpClient != nil {
+		// A copy, so the options do not change a client the caller also uses
+		client = *o.httpClient
+	} else {
+		client.Timeout = defaultHTTPTimeout
+		if val := os.Getenv("COINBASE_HTTP_TIMEOUT"); val != "" {
+			parsed, err := time.ParseDuration(val)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("COINBASE_HTTP_TIMEOUT must be a non-negative duration such as 30s, got %q", val)
+			}
+			client.Timeout = parsed
+		}
+	}
+	if o.timeout != nil {
+		if *o.timeout < 0 {
+			return nil, fmt.Errorf("coinbase HTTP timeout must not be negative, got %s", *o.timeout)
+		}
+		client.Timeout = *o.timeout
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	return &client, nil
+}
@@ -0,0 +1,93 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowHandler answers after delay, or gives up once the client does
+func slowHandler(delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			w.Write([]byte(`{}`))
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// timedRequest sends one GET without retries, returning its error and how long it took
+func timedRequest(t *testing.T, client *Client) (time.Duration, error) {
+	t.Helper()
+	start := time.Now()
+	resp, err := client.makeRequest(context.Background(), http.MethodGet, "/brokerage/accounts", nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return time.Since(start), err
+}
+
+func TestWithTimeoutBoundsSlowRequests(t *testing.T) {
+	t.Setenv("COINBASE_MAX_RETRIES", "0")
+	client := newTestClient(t, slowHandler(2*time.Second), WithTimeout(100*time.Millisecond))
+	elapsed, err := timedRequest(t, client)
+	if err == nil {
+		t.Fatal("request to a server slower than the timeout succeeded")
+	}
+	if elapsed > time.Second {
+		t.Fatalf("request took %v, want it abandoned after the 100ms timeout", elapsed)
+	}
+
+	// A request within the timeout succeeds
+	client = newTestClient(t, slowHandler(10*time.Millisecond), WithTimeout(time.Second))
+	if _, err := timedRequest(t, client); err != nil {
+		t.Fatalf("request within the timeout: %v", err)
+	}
+}
+
+func TestHTTPTimeoutFromEnvironment(t *testing.T) {
+	t.Setenv("COINBASE_MAX_RETRIES", "0")
+	t.Setenv("COINBASE_HTTP_TIMEOUT", "100ms")
+	client := newTestClient(t, slowHandler(2*time.Second))
+	if elapsed, err := timedRequest(t, client); err == nil || elapsed > time.Second {
+		t.Fatalf("request took %v (%v), want it abandoned after COINBASE_HTTP_TIMEOUT", elapsed, err)
+	}
+
+	// WithTimeout overrides the environment
+	client = newTestClient(t, slowHandler(300*time.Millisecond), WithTimeout(time.Second))
+	if _, err := timedRequest(t, client); err != nil {
+		t.Fatalf("request within WithTimeout: %v", err)
+	}
+
+	t.Setenv("COINBASE_HTTP_TIMEOUT", "soon")
+	if _, err := NewClient("organizations/test/apiKeys/test", testKeySecret(t)); err == nil || !strings.Contains(err.Error(), "COINBASE_HTTP_TIMEOUT") {
+		t.Fatalf("NewClient with an invalid COINBASE_HTTP_TIMEOUT: %v, want an error naming it", err)
+	}
+}
+
+func TestTimeoutDoesNotChangeJWTLifetime(t *testing.T) {
+	client := newTestClient(t, slowHandler(0), WithTimeout(time.Second))
+	token, err := client.GenerateJWT(http.MethodGet, "/api/v3/brokerage/accounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		NotBefore int64 `json:"nbf"`
+		Expires   int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Duration(claims.Expires-claims.NotBefore) * time.Second; lifetime != jwtLifetime {
+		t.Fatalf("JWT lifetime = %v, want %v whatever the HTTP timeout", lifetime, jwtLifetime)
+	}
+}
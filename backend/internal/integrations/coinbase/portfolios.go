@@ -0,0 +1,83 @@
+package coinbase
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Portfolio types reported by Coinbase
+const (
+	portfolioTypeDefault  = "DEFAULT"
+	portfolioTypeConsumer = "CONSUMER"
+	portfolioTypeINTX     = "INTX" // International Exchange perpetual futures
+)
+
+// portfolioTypeNames maps the Coinbase portfolio types to the Portfolio.Type stored
+var portfolioTypeNames = map[string]string{
+	portfolioTypeDefault:  "default",
+	portfolioTypeConsumer: "consumer",
+	portfolioTypeINTX:     "perpetuals",
+}
+
+// portfolioType returns the Portfolio.Type of a Coinbase portfolio type; types without a
+// friendly name are stored in lower case, and UNDEFINED not at all
+func portfolioType(coinbaseType string) string {
+	coinbaseType = strings.ToUpper(coinbaseType)
+	if name, ok := portfolioTypeNames[coinbaseType]; ok {
+		return name
+	}
+	if coinbaseType == "UNDEFINED" {
+		return ""
+	}
+	return strings.ToLower(coinbaseType)
+}
+
+// parsePortfolioTypes reads COINBASE_PORTFOLIO_TYPES, a comma-separated list of the Coinbase
+// portfolio types to sync, such as "DEFAULT,CONSUMER"
+func parsePortfolioTypes(val string) (map[string]bool, error) {
+	types := make(map[string]bool)
+	for _, field := range strings.Split(val, ",") {
+		field = strings.ToUpper(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if _, ok := portfolioTypeNames[field]; !ok {
+			known := make([]string, 0, len(portfolioTypeNames))
+			for name := range portfolioTypeNames {
+				known = append(known, name)
+			}
+			slices.Sort(known)
+			return nil, fmt.Errorf("COINBASE_PORTFOLIO_TYPES lists unknown portfolio type %q; use %s", field, strings.Join(known, ", "))
+		}
+		types[field] = true
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("COINBASE_PORTFOLIO_TYPES must list at least one portfolio type, got %q", val)
+	}
+	return types, nil
+}
+
+// syncsPortfolio reports whether portfolios of a Coinbase type are synced. Unless
+// COINBASE_PORTFOLIO_TYPES says otherwise, every type is but INTX, whose perpetual positions are
+// not holdings the price logic can value, unless COINBASE_INTX_ENABLED syncs them from the
+// INTX endpoints.
+func (c *Client) syncsPortfolio(coinbaseType string) bool {
+	coinbaseType = strings.ToUpper(coinbaseType)
+	if c.portfolioTypes == nil {
+		return coinbaseType != portfolioTypeINTX || c.intxEnabled
+	}
+	return c.portfolioTypes[coinbaseType]
+}
+
+// selectPortfolios splits portfolios into those synced and those skipped by their type
+func (c *Client) selectPortfolios(portfolios []coinbasePortfolio) (synced, skipped []coinbasePortfolio) {
+	for _, portfolio := range portfolios {
+		if c.syncsPortfolio(portfolio.Type) {
+			synced = append(synced, portfolio)
+		} else {
+			skipped = append(skipped, portfolio)
+		}
+	}
+	return synced, skipped
+}
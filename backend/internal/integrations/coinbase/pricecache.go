@@ -0,0 +1,62 @@
+package coinbase
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PriceCacheStats counts the product price lookups answered from the cache and those that
+// had to ask Coinbase, including forced refreshes
+type PriceCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+type cachedPrice struct {
+	price     float64
+	fetchedAt time.Time
+}
+
+// priceCache remembers product prices for ttl, so holdings of the same asset in several
+// portfolios, and syncs in quick succession, share one price request. It is safe for
+// concurrent use.
+type priceCache struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	prices map[string]cachedPrice
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func newPriceCache(ttl time.Duration) *priceCache {
+	return &priceCache{
+		ttl:    ttl,
+		prices: make(map[string]cachedPrice),
+	}
+}
+
+// get returns the cached price of productID if it is younger than the TTL and a refresh is not
+// forced, counting the lookup as a hit or a miss
+func (p *priceCache) get(productID string, forceRefresh bool) (float64, bool) {
+	p.mu.Lock()
+	cached, ok := p.prices[productID]
+	p.mu.Unlock()
+	if forceRefresh || !ok || time.Since(cached.fetchedAt) >= p.ttl {
+		p.misses.Add(1)
+		return 0, false
+	}
+	p.hits.Add(1)
+	return cached.price, true
+}
+
+// put caches a freshly fetched price
+func (p *priceCache) put(productID string, price float64) {
+	p.mu.Lock()
+	p.prices[productID] = cachedPrice{price: price, fetchedAt: time.Now()}
+	p.mu.Unlock()
+}
+
+func (p *priceCache) stats() PriceCacheStats {
+	return PriceCacheStats{Hits: p.hits.Load(), Misses: p.misses.Load()}
+}
@@ -0,0 +1,89 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+type coinbaseProductsResponse struct {
+	Products []coinbaseProduct `json:"products"`
+}
+
+// GetPriceChanges returns the percentage each product's price moved over the last 24 hours,
+// listing the products priceBatchSize at a time. A product Coinbase does not list, or lists
+// without a move, is left out of the result.
+func (c *Client) GetPriceChanges(ctx context.Context, productIDs []string) (map[string]float64, error) {
+	changes := make(map[string]float64, len(productIDs))
+	for start := 0; start < len(productIDs); start += priceBatchSize {
+		query := url.Values{}
+		for _, productID := range productIDs[start:min(start+priceBatchSize, len(productIDs))] {
+			query.Add("product_ids", productID)
+		}
+		// GET /api/v3/brokerage/products?product_ids=BTC-USD&product_ids=ETH-USD
+		var apiResp coinbaseProductsResponse
+		if err := c.getJSON(ctx, "/brokerage/products?"+query.Encode(), &apiResp); err != nil {
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
+		for _, product := range apiResp.Products {
+			if product.Price24h == "" {
+				continue
+			}
+			pct, err := strconv.ParseFloat(product.Price24h, 64)
+			if err != nil {
+				continue
+			}
+			changes[strings.ToUpper(product.ProductID)] = pct
+		}
+	}
+	return changes, nil
+}
+
+// applyPriceChanges records the 24 hour price move of the crypto holdings among investments,
+// and a flat move for cash in the reporting currency. Holdings whose product has no known move,
+// derivatives, holdings kept at a stale price and those valued in another currency are left
+// unknown.
+func (c *Client) applyPriceChanges(ctx context.Context, investments []*models.Investment) error {
+	productIDs := make([]string, 0)
+	for _, investment := range investments {
+		if id, ok := priceChangeProduct(investment); ok && !slices.Contains(productIDs, id) {
+			productIDs = append(productIDs, id)
+		}
+	}
+	changes, err := c.GetPriceChanges(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+	log.Printf("Info: Found the 24h price change of %d of %d held products", len(changes), len(productIDs))
+	for _, investment := range investments {
+		if investment.Currency == reportingCurrency && strings.EqualFold(investment.Symbol, reportingCurrency) {
+			investment.SetPriceChange24h(0)
+			continue
+		}
+		id, ok := priceChangeProduct(investment)
+		if !ok {
+			continue
+		}
+		if pct, ok := changes[id]; ok {
+			investment.SetPriceChange24h(pct)
+		}
+	}
+	return nil
+}
+
+// priceChangeProduct returns the product whose 24 hour move applies to an investment, one
+// valued in the reporting currency at a current price that is not a derivative or reporting
+// currency cash
+func priceChangeProduct(investment *models.Investment) (string, bool) {
+	if investment.Currency != reportingCurrency || investment.AssetType == models.AssetTypeDerivative || investment.StalePrice ||
+		investment.Symbol == "" || strings.EqualFold(investment.Symbol, reportingCurrency) {
+		return "", false
+	}
+	return usdProductID(underlyingAsset(investment.Symbol)), true
+}
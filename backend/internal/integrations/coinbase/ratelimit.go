@@ -0,0 +1,129 @@
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket that every request of a Client passes through, so concurrent
+// callers share one request budget. Tokens accrue at rate per second up to burst, and each
+// request takes one, waiting for it if the bucket is empty.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64 // negative while callers are waiting for tokens they have reserved
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing rate requests per second, starting with a full
+// bucket of one second's worth of requests
+func newRateLimiter(rate float64) *rateLimiter {
+	burst := math.Max(1, math.Floor(rate))
+	return &rateLimiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, blocking until it is due. If ctx is done first the token is given back
+// and ctx's error returned, so a cancelled caller does not hold up the others.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// RateLimitedError is returned for a request Coinbase keeps answering with 429 Too Many
+// Requests when it is given up rather than retried: its retries are spent, or the wait Coinbase
+// asks for is longer than maxRetryDelay or would outlast the request's deadline.
+type RateLimitedError struct {
+	Method     string
+	Path       string
+	RetryAfter time.Duration // How long Coinbase asked to wait; 0 if it did not say
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("coinbase rate limited [%s %s], retry after %s", e.Method, e.Path, e.RetryAfter)
+	}
+	return fmt.Sprintf("coinbase rate limited [%s %s]", e.Method, e.Path)
+}
+
+// asRateLimited returns the first of errs that is or wraps a RateLimitedError, or nil
+func asRateLimited(errs ...error) *RateLimitedError {
+	for _, err := range errs {
+		var limited *RateLimitedError
+		if errors.As(err, &limited) {
+			return limited
+		}
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header, which is either a number of seconds or an HTTP date,
+// into how long to wait from now. ok is false if the header is missing or malformed.
+func retryAfter(header string, now time.Time) (wait time.Duration, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// rateLimitedError returns the error to give up a 429 response with instead of retrying it, or
+// nil if it should be retried: waiting is pointless when no retries are left, and a wait longer
+// than maxRetryDelay or past ctx's deadline would only end the request later.
+func rateLimitedError(ctx context.Context, method, path string, resp *http.Response, lastAttempt bool) error {
+	wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+	limited := &RateLimitedError{Method: method, Path: path, RetryAfter: wait}
+	if lastAttempt || (ok && wait > maxRetryDelay) {
+		return limited
+	}
+	if deadline, has := ctx.Deadline(); has && ok && time.Until(deadline) < wait {
+		return limited
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package coinbase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSharedAcrossGoroutines(t *testing.T) {
+	// A burst of 10, then 10 a second: 25 requests take about 1.5s
+	limiter := newRateLimiter(10)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 25; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := limiter.wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 1400*time.Millisecond || elapsed > 2500*time.Millisecond {
+		t.Fatalf("25 requests at 10 rps took %v, want about 1.5s", elapsed)
+	}
+}
+
+func TestRateLimiterCancelledWaitReturnsToken(t *testing.T) {
+	limiter := newRateLimiter(1)
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bucket is empty, so this waits and is cancelled first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait with an expiring context: %v, want context.DeadlineExceeded", err)
+	}
+
+	// The cancelled caller gave its token back, so the next is due a second after the first
+	start := time.Now()
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Fatalf("next wait took %v, want the cancelled caller's token back", elapsed)
+	}
+}
+
+func TestCancelledRequestSkipsRateLimitAndServer(t *testing.T) {
+	var requests atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{}`))
+	}))
+	// newTestClient turns rate limiting off; this test needs it on
+	client.limiter = newRateLimiter(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.makeRequest(ctx, http.MethodGet, "/brokerage/accounts", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("request with a cancelled context: %v, want context.Canceled", err)
+	}
+	if requests.Load() != 0 {
+		t.Fatalf("server got %d requests, want none", requests.Load())
+	}
+	if client.limiter.tokens != client.limiter.burst {
+		t.Fatalf("limiter has %v tokens, want the full burst of %v", client.limiter.tokens, client.limiter.burst)
+	}
+}
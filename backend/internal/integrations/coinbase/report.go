@@ -0,0 +1,42 @@
+package coinbase
+
+import (
+	"fmt"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// skipReason explains why a position could not be valued: the request for its market price
+// failed, its balance is in a currency without a rate, or no price was found for it
+func skipReason(position coinbaseSpotPosition, rates map[string]float64, failures map[string]error) string {
+	if err, failed := failures[positionProductID(position)]; failed {
+		return "price request failed: " + err.Error()
+	}
+	if _, ok := toReporting(1, position.FiatCurrency, rates); !ok {
+		return fmt.Sprintf("no %s rate for %s", reportingCurrency, strings.ToUpper(position.FiatCurrency))
+	}
+	return "no price available"
+}
+
+// portfolioStatus returns the status of a portfolio whose holdings were fetched
+func portfolioStatus(counts models.HoldingCounts) models.SyncStatus {
+	if counts.Skipped > 0 {
+		return models.SyncStatusPartial
+	}
+	return models.SyncStatusSuccess
+}
+
+// finishReport sets the overall status of report: partial if any portfolio was not fully synced,
+// a holding was skipped or some data could not be fetched
+func finishReport(report *models.SyncReport) {
+	report.Status = models.SyncStatusSuccess
+	if report.Holdings.Skipped > 0 || len(report.Warnings) > 0 {
+		report.Status = models.SyncStatusPartial
+	}
+	for _, portfolio := range report.Portfolios {
+		if portfolio.Status != models.SyncStatusSuccess {
+			report.Status = models.SyncStatusPartial
+		}
+	}
+}
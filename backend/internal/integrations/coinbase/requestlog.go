@@ -0,0 +1,68 @@
+package coinbase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"0xnetworth/backend/internal/metrics"
+)
+
+// correlationIDKey is the context key of the ID tying Coinbase requests to the sync that made them
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose Coinbase requests are logged with id, such as the ID
+// of the incoming request that started a sync
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationID returns the ID set by WithCorrelationID, or "-" if there is none
+func correlationID(ctx context.Context) string {
+	if id, ok := ctx.Value(correlationIDKey{}).(string); ok && id != "" {
+		return id
+	}
+	return "-"
+}
+
+// endpointName names the endpoint of a request for metrics: its method and path without the
+// query string, with identifiers such as portfolio UUIDs and product IDs replaced by ":id" so
+// each endpoint is counted once, e.g. "coinbase GET /brokerage/portfolios/:id"
+func endpointName(method, path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.ContainsFunc(segment, func(r rune) bool { return r == '-' || unicode.IsDigit(r) }) {
+			segments[i] = ":id"
+		}
+	}
+	return "coinbase " + method + " " + strings.Join(segments, "/")
+}
+
+// observeRequest records a finished request, retries included, in metrics.Default under its
+// endpoint, counting network errors and error statuses as errors. With COINBASE_DEBUG it also
+// logs the request. Only the method, path, status, timing and correlation ID are logged, never
+// the token or the key.
+func (c *Client) observeRequest(ctx context.Context, method, path string, duration time.Duration, retries int, resp *http.Response, err error) {
+	recorded := err
+	status := "error"
+	if err == nil {
+		status = fmt.Sprint(resp.StatusCode)
+		if resp.StatusCode >= http.StatusBadRequest {
+			recorded = fmt.Errorf("status %d", resp.StatusCode)
+		}
+	}
+	metrics.Default.Observe(endpointName(method, path), duration, recorded)
+	if !c.debug {
+		return
+	}
+	if err != nil {
+		log.Printf("Coinbase request [%s] %s %s failed after %s (%d retries): %v", correlationID(ctx), method, path, duration.Round(time.Millisecond), retries, err)
+		return
+	}
+	log.Printf("Coinbase request [%s] %s %s -> %s in %s (%d retries)", correlationID(ctx), method, path, status, duration.Round(time.Millisecond), retries)
+}
@@ -0,0 +1,80 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// coinbasePublicBaseURL serves Coinbase's public, unauthenticated price data
+const coinbasePublicBaseURL = "https://api.coinbase.com/v2"
+
+type coinbaseSpotPriceResponse struct {
+	Data struct {
+		Amount   string `json:"amount"`
+		Base     string `json:"base"`
+		Currency string `json:"currency"`
+	} `json:"data"`
+}
+
+// productPrice fetches the current price of a product from the brokerage API, falling back to
+// the public spot price of the pair when the brokerage cannot quote it, as with delisted or
+// regionally restricted products
+func (c *Client) productPrice(ctx context.Context, productID string) (float64, error) {
+	price, err := c.fetchProductPrice(ctx, productID)
+	if err == nil {
+		return price, nil
+	}
+	if ctx.Err() != nil {
+		return 0, err
+	}
+	spot, spotErr := c.fetchSpotPrice(ctx, productID)
+	if spotErr != nil {
+		return 0, fmt.Errorf("%w; spot price fallback also failed: %v", err, spotErr)
+	}
+	log.Printf("Info: Priced %s with the public spot price after the brokerage failed: %v", productID, err)
+	return spot, nil
+}
+
+// fetchSpotPrice fetches the public spot price of a pair such as BTC-USD. The endpoint needs no
+// JWT, but the request still waits its turn with the rate limiter.
+func (c *Client) fetchSpotPrice(ctx context.Context, pair string) (float64, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, coinbasePublicBaseURL+"/prices/"+url.PathEscape(pair)+"/spot", nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch spot price: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(bodyBytes),
+		}
+	}
+
+	var apiResp coinbaseSpotPriceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return 0, fmt.Errorf("failed to decode spot price response: %w", err)
+	}
+	price, err := strconv.ParseFloat(apiResp.Data.Amount, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("invalid spot price %q", apiResp.Data.Amount)
+	}
+	return price, nil
+}
@@ -0,0 +1,117 @@
+package coinbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// stakedAssets maps the Coinbase assets that stand for staked units, such as the ETH2 of
+// Ethereum staked before withdrawals were enabled, onto the asset they are staked in
+var stakedAssets = map[string]string{"ETH2": "ETH"}
+
+// underlyingAsset returns the asset a staked asset stands for, or asset itself
+func underlyingAsset(asset string) string {
+	if underlying, ok := stakedAssets[strings.ToUpper(asset)]; ok {
+		return underlying
+	}
+	return asset
+}
+
+// coinbaseAmount is a quantity Coinbase may send as a number, a numeric string or a balance
+// object with a value
+type coinbaseAmount float64
+
+func (a *coinbaseAmount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		*a = 0
+		return nil
+	}
+	var value string
+	switch data[0] {
+	case '"':
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	case '{':
+		var balance coinbaseBalance
+		if err := json.Unmarshal(data, &balance); err != nil {
+			return err
+		}
+		value = balance.Value
+	default:
+		value = string(data)
+	}
+	if value == "" {
+		*a = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	*a = coinbaseAmount(f)
+	return nil
+}
+
+// stakedPrice returns the price in the reporting currency of the staked units of a position:
+// the breakdown's price of the liquid units, or the market price in prices when it has none
+func stakedPrice(position coinbaseSpotPosition, prices, rates map[string]float64) (float64, bool) {
+	if price, ok := position.Price(); ok {
+		return toReporting(price, position.FiatCurrency, rates)
+	}
+	price, ok := prices[position.Asset]
+	return price, ok
+}
+
+// needsMarketPrice reports whether valuing a position takes its asset's market price: it was
+// built from an account, or it has staked units but no liquid ones to price them by
+func needsMarketPrice(position coinbaseSpotPosition) bool {
+	if position.MarketPriced {
+		return true
+	}
+	_, priced := position.Price()
+	return position.StakedBalance > 0 && !priced
+}
+
+// stakedInvestment converts the staked units of a spot position in portfolioID into a holding
+// of their own, flagged staked, so they count towards net worth without being merged into the
+// liquid holding. Staked units without a price are kept at their investment's price in
+// lastPrices, flagged stale. ok is false if the position has nothing staked; priced is false if
+// it has but no price is known.
+func stakedInvestment(portfolioID string, position coinbaseSpotPosition, prices, rates, lastPrices map[string]float64) (investment *models.Investment, ok, priced bool) {
+	quantity := float64(position.StakedBalance)
+	if quantity <= 0 {
+		return nil, false, false
+	}
+	id := fmt.Sprintf("%s-%s-staked", portfolioID, position.AssetUUID)
+	price, priced := stakedPrice(position, prices, rates)
+	stale := false
+	if !priced {
+		if price, priced = lastPrices[id]; !priced {
+			return nil, true, false
+		}
+		stale = true
+	}
+	symbol := strings.ToUpper(underlyingAsset(position.Asset))
+	return &models.Investment{
+		ID:          id,
+		AccountID:   portfolioID,
+		Platform:    models.PlatformCoinbase,
+		Symbol:      symbol,
+		Name:        symbol + " (staked)",
+		Quantity:    quantity,
+		Value:       price * quantity,
+		Price:       price,
+		Currency:    reportingCurrency,
+		AssetType:   models.AssetTypeCrypto,
+		Staked:      true,
+		StalePrice:  stale,
+		LastUpdated: models.Now(),
+	}, true, true
+}
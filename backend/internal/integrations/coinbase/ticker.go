@@ -0,0 +1,118 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coinbase/cdp-sdk/go/auth"
+	"golang.org/x/net/websocket"
+)
+
+// coinbaseWebsocketURL serves the Advanced Trade market data channels
+const coinbaseWebsocketURL = "wss://advanced-trade-ws.coinbase.com"
+
+// tickerReadTimeout bounds the wait for any message. The heartbeats channel sends one every
+// second, so a connection silent for this long has dropped.
+const tickerReadTimeout = 30 * time.Second
+
+// Tick is a trade price reported by the ticker channel
+type Tick struct {
+	ProductID string
+	Price     float64
+	Time      time.Time
+}
+
+type coinbaseSubscribe struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids,omitempty"`
+	Channel    string   `json:"channel"`
+	JWT        string   `json:"jwt,omitempty"`
+}
+
+type coinbaseTickerMessage struct {
+	Type      string `json:"type"`
+	Message   string `json:"message"`
+	Channel   string `json:"channel"`
+	Timestamp string `json:"timestamp"`
+	Events    []struct {
+		Tickers []struct {
+			ProductID string `json:"product_id"`
+			Price     string `json:"price"`
+		} `json:"tickers"`
+	} `json:"events"`
+}
+
+// StreamTicker subscribes to the ticker channel for productIDs and calls onTick with every price
+// update, starting with the current price of each, until ctx is done or the connection fails.
+// It returns the error that ended the stream; the caller decides whether to reconnect.
+func (c *Client) StreamTicker(ctx context.Context, productIDs []string, onTick func(Tick)) error {
+	config, err := websocket.NewConfig(c.wsURL, "https://api.coinbase.com")
+	if err != nil {
+		return fmt.Errorf("invalid websocket URL: %w", err)
+	}
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to ticker: %w", err)
+	}
+	// Closing the connection unblocks the read loop once ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	// Websocket tokens are signed for no request in particular
+	token, err := auth.GenerateJWT(auth.JwtOptions{
+		KeyID:     c.apiKeyName,
+		KeySecret: c.apiKeySecret,
+		ExpiresIn: int64(jwtLifetime / time.Second),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate websocket JWT: %w", err)
+	}
+	for _, channel := range []string{"heartbeats", "ticker"} {
+		subscribe := coinbaseSubscribe{Type: "subscribe", Channel: channel, JWT: token}
+		if channel == "ticker" {
+			subscribe.ProductIDs = productIDs
+		}
+		if err := websocket.JSON.Send(conn, subscribe); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", channel, err)
+		}
+	}
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(tickerReadTimeout)); err != nil {
+			return err
+		}
+		var msg coinbaseTickerMessage
+		if err := websocket.JSON.Receive(conn, &msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, ok := err.(*json.SyntaxError); ok {
+				continue
+			}
+			return fmt.Errorf("ticker connection failed: %w", err)
+		}
+		if msg.Type == "error" {
+			return fmt.Errorf("ticker subscription failed: %s", msg.Message)
+		}
+		if msg.Channel != "ticker" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+		if err != nil {
+			at = time.Now()
+		}
+		for _, event := range msg.Events {
+			for _, ticker := range event.Tickers {
+				price, err := strconv.ParseFloat(ticker.Price, 64)
+				if err != nil || price <= 0 {
+					continue
+				}
+				onTick(Tick{ProductID: ticker.ProductID, Price: price, Time: at.UTC()})
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CredentialState classifies the outcome of checking the Coinbase credentials
+type CredentialState string
+
+const (
+	// CredentialsNotConfigured means no Coinbase credentials were provided
+	CredentialsNotConfigured CredentialState = "not_configured"
+	// CredentialsUnchecked means Validate has not finished yet
+	CredentialsUnchecked CredentialState = "unchecked"
+	// CredentialsOK means the key authenticated and can view portfolios
+	CredentialsOK CredentialState = "ok"
+	// CredentialsInvalidKey means Coinbase rejected the key itself: a wrong key name, a
+	// mismatched private key or a deleted key
+	CredentialsInvalidKey CredentialState = "invalid_key"
+	// CredentialsInsufficientPermission means the key authenticated but lacks the View permission
+	CredentialsInsufficientPermission CredentialState = "insufficient_permission"
+	// CredentialsNetworkError means Coinbase could not be reached, or failed, so the key is
+	// neither known to work nor known to be wrong
+	CredentialsNetworkError CredentialState = "network_error"
+)
+
+// CredentialStatus is the result of a credential check, shown by the health endpoint
+type CredentialStatus struct {
+	State CredentialState `json:"state"`
+	// Summary is a short description for display, such as "connected (read-only)"
+	Summary string `json:"summary"`
+	// Message tells an operator what to do about a failed check
+	Message     string     `json:"message,omitempty"`
+	CanView     bool       `json:"can_view"`
+	CanTrade    bool       `json:"can_trade"`
+	CanTransfer bool       `json:"can_transfer"`
+	CheckedAt   *time.Time `json:"checked_at"`
+}
+
+// OK reports whether the credentials can be used to sync
+func (s CredentialStatus) OK() bool {
+	return s.State == CredentialsOK
+}
+
+type coinbaseKeyPermissions struct {
+	CanView       bool   `json:"can_view"`
+	CanTrade      bool   `json:"can_trade"`
+	CanTransfer   bool   `json:"can_transfer"`
+	PortfolioUUID string `json:"portfolio_uuid"`
+	PortfolioType string `json:"portfolio_type"`
+}
+
+// Validate checks the credentials with the cheapest authenticated request, the key's own
+// permissions, and classifies the result. The result is also kept for Credentials. A failed
+// check is reported in the status rather than as an error, since it is not fatal: a network
+// error at startup may clear up before the first sync.
+func (c *Client) Validate(ctx context.Context) CredentialStatus {
+	status := c.checkCredentials(ctx)
+	checkedAt := time.Now().UTC()
+	status.CheckedAt = &checkedAt
+	c.credentials.Store(&status)
+	return status
+}
+
+// Credentials returns the result of the latest Validate, or an unchecked status if there has
+// been none
+func (c *Client) Credentials() CredentialStatus {
+	if status := c.credentials.Load(); status != nil {
+		return *status
+	}
+	return CredentialStatus{State: CredentialsUnchecked, Summary: "checking"}
+}
+
+func (c *Client) checkCredentials(ctx context.Context) CredentialStatus {
+	// GET /api/v3/brokerage/key_permissions
+	resp, err := c.makeRequest(ctx, http.MethodGet, "/brokerage/key_permissions", nil)
+	if err != nil {
+		return CredentialStatus{
+			State:   CredentialsNetworkError,
+			Summary: "unreachable",
+			Message: fmt.Sprintf("Could not reach Coinbase to check the API key (%v); syncs will fail until it is reachable", err),
+		}
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return CredentialStatus{
+			State:   CredentialsNetworkError,
+			Summary: "unreachable",
+			Message: fmt.Sprintf("Failed to read the Coinbase key permissions: %v", err),
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return CredentialStatus{
+			State:   CredentialsInvalidKey,
+			Summary: "invalid API key",
+			Message: "Coinbase rejected the API key: check that COINBASE_API_KEY_NAME and COINBASE_API_PRIVATE_KEY belong to the same CDP key and that the key has not been deleted",
+		}
+	case resp.StatusCode == http.StatusForbidden:
+		return CredentialStatus{
+			State:   CredentialsInsufficientPermission,
+			Summary: "missing View permission",
+			Message: "Coinbase refused the API key: grant it the View permission in the Coinbase Developer Platform, and allow this server's IP address if the key has an allowlist",
+		}
+	case resp.StatusCode != http.StatusOK:
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
+		return CredentialStatus{
+			State:   CredentialsNetworkError,
+			Summary: "unreachable",
+			Message: fmt.Sprintf("Coinbase could not check the API key (%v); syncs will fail until it recovers", apiErr),
+		}
+	}
+
+	var permissions coinbaseKeyPermissions
+	if err := json.Unmarshal(bodyBytes, &permissions); err != nil {
+		return CredentialStatus{
+			State:   CredentialsNetworkError,
+			Summary: "unreachable",
+			Message: fmt.Sprintf("Failed to decode the Coinbase key permissions: %v", err),
+		}
+	}
+	status := CredentialStatus{
+		CanView:     permissions.CanView,
+		CanTrade:    permissions.CanTrade,
+		CanTransfer: permissions.CanTransfer,
+	}
+	if !permissions.CanView {
+		status.State = CredentialsInsufficientPermission
+		status.Summary = "missing View permission"
+		status.Message = "The Coinbase API key lacks the View permission, which syncs need to list portfolios and balances: enable View on the key in the Coinbase Developer Platform"
+		return status
+	}
+	status.State = CredentialsOK
+	if permissions.CanTrade || permissions.CanTransfer {
+		status.Summary = "connected (trading enabled)"
+		status.Message = "The Coinbase API key can trade or transfer funds, which syncs never need; a View-only key is safer"
+	} else {
+		status.Summary = "connected (read-only)"
+	}
+	return status
+}
@@ -0,0 +1,41 @@
+package plaid
+
+import (
+	"context"
+	"fmt"
+)
+
+// Account is an account at a linked institution
+type Account struct {
+	AccountID    string   `json:"account_id"`
+	Name         string   `json:"name"`
+	OfficialName string   `json:"official_name"`
+	Mask         string   `json:"mask"`
+	Type         string   `json:"type"`    // investment, depository, credit, loan or other
+	Subtype      string   `json:"subtype"` // such as brokerage, ira or roth
+	Balances     Balances `json:"balances"`
+}
+
+// Balances are an account's balances as of the request. Plaid leaves a balance null when the
+// institution does not report it.
+type Balances struct {
+	// Available is what can be withdrawn or spent, which for an investment account is its
+	// settled cash at most
+	Available *float64 `json:"available"`
+	// Current is the total balance; for an investment account, its holdings and cash
+	Current                *float64 `json:"current"`
+	IsoCurrencyCode        string   `json:"iso_currency_code"`
+	UnofficialCurrencyCode string   `json:"unofficial_currency_code"`
+}
+
+// GetAccounts returns the accounts of the item accessToken belongs to, with their balances as
+// Plaid last refreshed them
+func (c *Client) GetAccounts(ctx context.Context, accessToken string) ([]Account, error) {
+	var response struct {
+		Accounts []Account `json:"accounts"`
+	}
+	if err := c.post(ctx, "/accounts/get", map[string]string{"access_token": accessToken}, &response); err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+	return response.Accounts, nil
+}
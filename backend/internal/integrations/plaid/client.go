@@ -0,0 +1,262 @@
+package plaid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// environmentURLs are the Plaid API hosts of each PLAID_ENV
+var environmentURLs = map[string]string{
+	"sandbox":     "https://sandbox.plaid.com",
+	"development": "https://development.plaid.com",
+	"production":  "https://production.plaid.com",
+}
+
+// clientName is shown to the user in Plaid Link
+const clientName = "0xNetworth"
+
+// APIError is an error response from Plaid, which names the error by type and code
+type APIError struct {
+	StatusCode   int
+	ErrorType    string `json:"error_type"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	RequestID    string `json:"request_id"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("plaid API error: %d %s/%s - %s (request %s)", e.StatusCode, e.ErrorType, e.ErrorCode, e.ErrorMessage, e.RequestID)
+}
+
+// Client calls the Plaid API with the server's client ID and secret
+type Client struct {
+	clientID   string
+	secret     string
+	env        string
+	baseURL    string
+	httpClient *http.Client
+	// transactionsLookback is how far back an account's first sync fetches its investment
+	// transactions
+	transactionsLookback time.Duration
+	// webhookURL is where Plaid posts webhooks about the items linked, empty for none
+	webhookURL string
+	// webhookKeys caches the keys webhooks are signed with, by key ID
+	webhookKeysMu sync.Mutex
+	webhookKeys   map[string]cachedWebhookKey
+}
+
+// Option configures how NewClient builds a Client
+type Option func(*Client)
+
+// WithTransport sends requests through transport, such as one whose connection pool is shared
+// with other clients
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithBaseURL sends requests to url instead of the host of the client's environment, such as
+// to an httptest server
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(url, "/")
+	}
+}
+
+// WithWebhookURL has Plaid post webhooks about the items linked from now on to url, so their
+// changes are synced as they happen
+func WithWebhookURL(url string) Option {
+	return func(c *Client) {
+		c.webhookURL = url
+	}
+}
+
+// NewClient creates a Plaid client for env, one of sandbox, development or production
+func NewClient(clientID, secret, env string, opts ...Option) (*Client, error) {
+	if clientID == "" {
+		return nil, fmt.Errorf("clientID cannot be empty")
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("secret cannot be empty")
+	}
+	env = strings.ToLower(env)
+	baseURL, ok := environmentURLs[env]
+	if !ok {
+		return nil, fmt.Errorf("PLAID_ENV must be sandbox, development or production, got %q", env)
+	}
+	lookback, err := transactionsLookback()
+	if err != nil {
+		return nil, err
+	}
+	client := &Client{
+		clientID:             clientID,
+		secret:               secret,
+		env:                  env,
+		baseURL:              baseURL,
+		httpClient:           &http.Client{Timeout: 30 * time.Second},
+		transactionsLookback: lookback,
+		webhookKeys:          make(map[string]cachedWebhookKey),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client, nil
+}
+
+// post sends request to a Plaid endpoint and decodes the response into response. Plaid takes
+// every call as a POST authenticated by the client ID and secret headers.
+func (c *Client) post(ctx context.Context, path string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PLAID-CLIENT-ID", c.clientID)
+	req.Header.Set("PLAID-SECRET", c.secret)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(bodyBytes, apiErr); err != nil {
+			apiErr.ErrorMessage = string(bodyBytes)
+		}
+		// The response never carries tokens on error, so its codes are safe to log
+		log.Printf("Plaid request [POST %s] failed in %s: %s/%s", path, time.Since(start).Round(time.Millisecond), apiErr.ErrorType, apiErr.ErrorCode)
+		return apiErr
+	}
+	if err := json.Unmarshal(bodyBytes, response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// LinkToken starts a Plaid Link session in the frontend
+type LinkToken struct {
+	LinkToken  string    `json:"link_token"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// CreateLinkToken creates a Link token for linking an investment account of userID
+func (c *Client) CreateLinkToken(ctx context.Context, userID string) (*LinkToken, error) {
+	request := map[string]any{
+		"client_name":   clientName,
+		"language":      "en",
+		"country_codes": []string{"US"},
+		"products":      []string{"investments"},
+		"user":          map[string]string{"client_user_id": userID},
+	}
+	if c.webhookURL != "" {
+		request["webhook"] = c.webhookURL
+	}
+	var response LinkToken
+	if err := c.post(ctx, "/link/token/create", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to create link token: %w", err)
+	}
+	return &response, nil
+}
+
+// Exchange is the item a public token was exchanged for
+type Exchange struct {
+	AccessToken string `json:"access_token"`
+	ItemID      string `json:"item_id"`
+}
+
+// ExchangePublicToken exchanges the public token Plaid Link returns for the item's access token.
+// A public token expires 30 minutes after Link completes and can be exchanged only once.
+func (c *Client) ExchangePublicToken(ctx context.Context, publicToken string) (*Exchange, error) {
+	var response Exchange
+	err := c.post(ctx, "/item/public_token/exchange", map[string]string{"public_token": publicToken}, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange public token: %w", err)
+	}
+	if response.AccessToken == "" || response.ItemID == "" {
+		return nil, errors.New("failed to exchange public token: response has no access token or item ID")
+	}
+	return &response, nil
+}
+
+// Institution is the financial institution of an item
+type Institution struct {
+	ID   string `json:"institution_id"`
+	Name string `json:"name"`
+}
+
+// GetInstitution returns the institution of the item accessToken belongs to
+func (c *Client) GetInstitution(ctx context.Context, accessToken string) (*Institution, error) {
+	var item struct {
+		Item struct {
+			InstitutionID string `json:"institution_id"`
+		} `json:"item"`
+	}
+	if err := c.post(ctx, "/item/get", map[string]string{"access_token": accessToken}, &item); err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item.Item.InstitutionID == "" {
+		return nil, errors.New("failed to get item: it has no institution")
+	}
+
+	var institution struct {
+		Institution Institution `json:"institution"`
+	}
+	request := map[string]any{
+		"institution_id": item.Item.InstitutionID,
+		"country_codes":  []string{"US"},
+	}
+	if err := c.post(ctx, "/institutions/get_by_id", request, &institution); err != nil {
+		return nil, fmt.Errorf("failed to get institution %s: %w", item.Item.InstitutionID, err)
+	}
+	return &institution.Institution, nil
+}
+
+// RemoveItem removes the item accessToken belongs to, which invalidates the token and stops
+// Plaid billing for the item
+func (c *Client) RemoveItem(ctx context.Context, accessToken string) error {
+	var response struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := c.post(ctx, "/item/remove", map[string]string{"access_token": accessToken}, &response); err != nil {
+		return fmt.Errorf("failed to remove item: %w", err)
+	}
+	return nil
+}
+
+// ErrorCodeItemLoginRequired is the Plaid error of an item whose institution needs the user to
+// log in again, such as after a password change; the item works again once it is re-linked
+// through Plaid Link
+const ErrorCodeItemLoginRequired = "ITEM_LOGIN_REQUIRED"
+
+// IsLoginRequired reports whether err is Plaid saying the item needs the user to log in again
+func IsLoginRequired(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode == ErrorCodeItemLoginRequired
+}
+
+// IsItemGone reports whether err is Plaid saying an access token's item no longer exists, such
+// as after it was removed
+func IsItemGone(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode == "ITEM_NOT_FOUND" || apiErr.ErrorCode == "INVALID_ACCESS_TOKEN")
+}
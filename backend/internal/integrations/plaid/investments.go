@@ -0,0 +1,123 @@
+package plaid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// Holding is a position in an investment account, joined with the security it holds
+type Holding struct {
+	AccountID  string  `json:"account_id"`
+	SecurityID string  `json:"security_id"`
+	Quantity   float64 `json:"quantity"`
+	// InstitutionPrice and InstitutionValue are the price and value the institution reports,
+	// as of its last pricing rather than the request
+	InstitutionPrice       float64  `json:"institution_price"`
+	InstitutionValue       float64  `json:"institution_value"`
+	CostBasis              *float64 `json:"cost_basis"`
+	IsoCurrencyCode        string   `json:"iso_currency_code"`
+	UnofficialCurrencyCode string   `json:"unofficial_currency_code"`
+	// Security is the held security, nil if Plaid did not list it
+	Security *Security `json:"-"`
+}
+
+// Security is a security held in a linked account
+type Security struct {
+	SecurityID       string `json:"security_id"`
+	Name             string `json:"name"`
+	TickerSymbol     string `json:"ticker_symbol"`
+	Type             string `json:"type"` // such as equity, etf, mutual fund or cash
+	IsCashEquivalent bool   `json:"is_cash_equivalent"`
+}
+
+// GetInvestments returns the holdings of the item accessToken belongs to, each joined with its
+// security, in the accounts accountIDs or in every investment account if none are given
+func (c *Client) GetInvestments(ctx context.Context, accessToken string, accountIDs ...string) ([]Holding, error) {
+	request := map[string]any{"access_token": accessToken}
+	if len(accountIDs) > 0 {
+		request["options"] = map[string]any{"account_ids": accountIDs}
+	}
+	var response struct {
+		Holdings   []Holding  `json:"holdings"`
+		Securities []Security `json:"securities"`
+	}
+	if err := c.post(ctx, "/investments/holdings/get", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to get holdings: %w", err)
+	}
+
+	securities := make(map[string]*Security, len(response.Securities))
+	for i := range response.Securities {
+		securities[response.Securities[i].SecurityID] = &response.Securities[i]
+	}
+	for i := range response.Holdings {
+		response.Holdings[i].Security = securities[response.Holdings[i].SecurityID]
+	}
+	return response.Holdings, nil
+}
+
+// securityAssetTypes maps Plaid security types onto asset types. Mutual funds are pooled funds
+// like ETFs, so they share that class.
+var securityAssetTypes = map[string]models.AssetType{
+	"equity":         models.AssetTypeStock,
+	"etf":            models.AssetTypeETF,
+	"mutual fund":    models.AssetTypeETF,
+	"fixed income":   models.AssetTypeBond,
+	"cash":           models.AssetTypeCash,
+	"cryptocurrency": models.AssetTypeCrypto,
+	"derivative":     models.AssetTypeDerivative,
+}
+
+// securitySymbol returns the symbol of a security: its ticker, or its name if it has none,
+// such as some funds, or as a last resort its Plaid ID. security may be nil.
+func securitySymbol(security *Security, securityID string) string {
+	if security != nil && security.TickerSymbol != "" {
+		return strings.ToUpper(security.TickerSymbol)
+	}
+	if security != nil && security.Name != "" {
+		return security.Name
+	}
+	return securityID
+}
+
+// holdingInvestment maps a holding of an account of platform into an investment. A security
+// without a ticker goes by its name instead (see securitySymbol), so it is still counted.
+func holdingInvestment(holding Holding, platform models.Platform) *models.Investment {
+	security := holding.Security
+	if security == nil {
+		security = &Security{SecurityID: holding.SecurityID}
+	}
+	symbol := securitySymbol(security, holding.SecurityID)
+	name := security.Name
+	if name == "" {
+		name = symbol
+	}
+
+	assetType, ok := securityAssetTypes[strings.ToLower(security.Type)]
+	if !ok {
+		assetType = models.AssetTypeOther
+	}
+	if security.IsCashEquivalent {
+		assetType = models.AssetTypeCash
+	}
+
+	value := holding.InstitutionValue
+	if value == 0 {
+		value = holding.Quantity * holding.InstitutionPrice
+	}
+	return &models.Investment{
+		ID:          fmt.Sprintf("%s-%s", holding.AccountID, holding.SecurityID),
+		AccountID:   holding.AccountID,
+		Platform:    platform,
+		Symbol:      symbol,
+		Name:        name,
+		Quantity:    holding.Quantity,
+		Value:       value,
+		Price:       holding.InstitutionPrice,
+		Currency:    currencyCode(holding.IsoCurrencyCode, holding.UnofficialCurrencyCode),
+		AssetType:   assetType,
+		LastUpdated: models.Now(),
+	}
+}
@@ -0,0 +1,329 @@
+// Package plaidtest provides a fake of the Plaid API, so the link, sync, unlink and webhook
+// flows can be exercised against the handlers without credentials or the network.
+package plaidtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/plaid"
+)
+
+// Item is an item the fake serves once it is linked
+type Item struct {
+	ItemID       string
+	Institution  plaid.Institution
+	Accounts     []plaid.Account
+	Holdings     []plaid.Holding
+	Transactions []plaid.InvestmentTransaction
+	// Err, if set, fails every call for the item after it is linked, such as
+	// ItemLoginRequired() once the user changed their password at the institution
+	Err error
+}
+
+// Fake implements the Plaid calls of the handlers from items added to it. Its methods are safe
+// for concurrent use.
+type Fake struct {
+	mu sync.Mutex
+	// linkable holds the items not yet exchanged, by the public token Plaid Link gives for them
+	linkable map[string]*Item
+	// linked holds the exchanged items, by access token
+	linked  map[string]*Item
+	removed []string
+	// calls counts the calls made with each item's access token, by item ID
+	calls map[string]int
+	// webhookVerification is the only Plaid-Verification header VerifyWebhook accepts
+	webhookVerification string
+	next                int
+	// sandbox makes the fake a sandbox client, creating items from sandboxItems by institution
+	sandbox      bool
+	sandboxItems map[string]*Item
+	fired        []FiredWebhook
+}
+
+// FiredWebhook is a webhook the fake was asked to fire through its sandbox
+type FiredWebhook struct {
+	ItemID      string
+	WebhookType string
+	WebhookCode string
+}
+
+var _ plaid.ItemReader = (*Fake)(nil)
+
+// NewFake creates a fake without items
+func NewFake() *Fake {
+	return &Fake{
+		linkable:     make(map[string]*Item),
+		linked:       make(map[string]*Item),
+		calls:        make(map[string]int),
+		sandboxItems: make(map[string]*Item),
+	}
+}
+
+// NewSandboxFake creates a fake of the Plaid sandbox without items, whose sandbox calls create
+// items from those added with AddSandboxInstitution
+func NewSandboxFake() *Fake {
+	f := NewFake()
+	f.sandbox = true
+	return f
+}
+
+// AddSandboxInstitution makes SandboxCreatePublicToken create item at institutionID
+func (f *Fake) AddSandboxInstitution(institutionID string, item *Item) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sandboxItems[institutionID] = item
+}
+
+// FiredWebhooks returns the webhooks fired through the sandbox, in the order they were fired
+func (f *Fake) FiredWebhooks() []FiredWebhook {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.fired)
+}
+
+// AddItem makes item linkable: exchanging publicToken links it and returns its access token
+func (f *Fake) AddItem(publicToken string, item *Item) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.linkable[publicToken] = item
+}
+
+// SetItemError fails every later call for the linked item itemID with err, or lets them
+// succeed again if err is nil
+func (f *Fake) SetItemError(itemID string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range f.linked {
+		if item.ItemID == itemID {
+			item.Err = err
+		}
+	}
+}
+
+// SetWebhookVerification sets the Plaid-Verification header VerifyWebhook accepts
+func (f *Fake) SetWebhookVerification(verification string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.webhookVerification = verification
+}
+
+// Removed returns the IDs of the items removed, in the order they were removed
+func (f *Fake) Removed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.removed)
+}
+
+// Calls returns how many calls were made with an access token of the item itemID, failed ones
+// included
+func (f *Fake) Calls(itemID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[itemID]
+}
+
+// ItemLoginRequired returns the error Plaid answers with for an item whose institution needs
+// the user to log in again
+func ItemLoginRequired() error {
+	return &plaid.APIError{
+		StatusCode:   http.StatusBadRequest,
+		ErrorType:    "ITEM_ERROR",
+		ErrorCode:    plaid.ErrorCodeItemLoginRequired,
+		ErrorMessage: "the login details of this item have changed (credentials, MFA, or required user action) and a user login is required to update this information",
+		RequestID:    "fake",
+	}
+}
+
+// invalidAccessToken is the error Plaid answers with for an access token it does not know
+func invalidAccessToken() error {
+	return &plaid.APIError{
+		StatusCode:   http.StatusBadRequest,
+		ErrorType:    "INVALID_INPUT",
+		ErrorCode:    "INVALID_ACCESS_TOKEN",
+		ErrorMessage: "provided access token is in an invalid format",
+		RequestID:    "fake",
+	}
+}
+
+// item returns the linked item accessToken belongs to, or the error Plaid would answer with
+func (f *Fake) item(accessToken string) (*Item, error) {
+	item, ok := f.linked[accessToken]
+	if !ok {
+		return nil, invalidAccessToken()
+	}
+	f.calls[item.ItemID]++
+	if item.Err != nil {
+		return nil, item.Err
+	}
+	return item, nil
+}
+
+// CreateLinkToken returns a Link token for userID
+func (f *Fake) CreateLinkToken(ctx context.Context, userID string) (*plaid.LinkToken, error) {
+	return &plaid.LinkToken{
+		LinkToken:  "link-fake-" + userID,
+		Expiration: time.Now().Add(4 * time.Hour),
+	}, nil
+}
+
+// ExchangePublicToken links the item added under publicToken. As with Plaid, a public token
+// can be exchanged only once.
+func (f *Fake) ExchangePublicToken(ctx context.Context, publicToken string) (*plaid.Exchange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, ok := f.linkable[publicToken]
+	if !ok {
+		return nil, fmt.Errorf("failed to exchange public token: %w", &plaid.APIError{
+			StatusCode:   http.StatusBadRequest,
+			ErrorType:    "INVALID_INPUT",
+			ErrorCode:    "INVALID_PUBLIC_TOKEN",
+			ErrorMessage: "provided public token is expired or has already been exchanged",
+			RequestID:    "fake",
+		})
+	}
+	delete(f.linkable, publicToken)
+	f.next++
+	accessToken := fmt.Sprintf("access-fake-%d", f.next)
+	f.linked[accessToken] = item
+	return &plaid.Exchange{AccessToken: accessToken, ItemID: item.ItemID}, nil
+}
+
+// GetInstitution returns the institution of the item accessToken belongs to
+func (f *Fake) GetInstitution(ctx context.Context, accessToken string) (*plaid.Institution, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, err := f.item(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	institution := item.Institution
+	return &institution, nil
+}
+
+// GetAccounts returns the accounts of the item accessToken belongs to
+func (f *Fake) GetAccounts(ctx context.Context, accessToken string) ([]plaid.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, err := f.item(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+	return slices.Clone(item.Accounts), nil
+}
+
+// GetInvestments returns the holdings of the item accessToken belongs to in the accounts
+// accountIDs, or in all of its accounts if none are given
+func (f *Fake) GetInvestments(ctx context.Context, accessToken string, accountIDs ...string) ([]plaid.Holding, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, err := f.item(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holdings: %w", err)
+	}
+	holdings := make([]plaid.Holding, 0, len(item.Holdings))
+	for _, holding := range item.Holdings {
+		if len(accountIDs) == 0 || slices.Contains(accountIDs, holding.AccountID) {
+			holdings = append(holdings, holding)
+		}
+	}
+	return holdings, nil
+}
+
+// GetInvestmentTransactions returns the investment transactions of the item accessToken
+// belongs to dated from since, all of them if since is zero, in the accounts accountIDs or in
+// all of its accounts if none are given
+func (f *Fake) GetInvestmentTransactions(ctx context.Context, accessToken string, since time.Time, accountIDs ...string) ([]plaid.InvestmentTransaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, err := f.item(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investment transactions: %w", err)
+	}
+	from := since.Format("2006-01-02")
+	transactions := make([]plaid.InvestmentTransaction, 0, len(item.Transactions))
+	for _, transaction := range item.Transactions {
+		if len(accountIDs) > 0 && !slices.Contains(accountIDs, transaction.AccountID) {
+			continue
+		}
+		if !since.IsZero() && transaction.Date < from {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// RemoveItem removes the item accessToken belongs to, after which its access token is invalid.
+// Unlike the other calls it succeeds for an item failing with Err, as Plaid's does.
+func (f *Fake) RemoveItem(ctx context.Context, accessToken string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	item, ok := f.linked[accessToken]
+	if !ok {
+		return fmt.Errorf("failed to remove item: %w", invalidAccessToken())
+	}
+	delete(f.linked, accessToken)
+	f.removed = append(f.removed, item.ItemID)
+	return nil
+}
+
+// VerifyWebhook accepts a webhook whose Plaid-Verification header is the one set with
+// SetWebhookVerification, whatever its body
+func (f *Fake) VerifyWebhook(ctx context.Context, body []byte, verification string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.webhookVerification == "" || verification != f.webhookVerification {
+		return errors.New("invalid webhook verification token")
+	}
+	return nil
+}
+
+// Sandbox reports whether the fake was created with NewSandboxFake
+func (f *Fake) Sandbox() bool {
+	return f.sandbox
+}
+
+// SandboxCreatePublicToken makes the item added for institutionID linkable, as Plaid's sandbox
+// creates one, and returns its public token
+func (f *Fake) SandboxCreatePublicToken(ctx context.Context, institutionID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.sandbox {
+		return "", plaid.ErrNotSandbox
+	}
+	item, ok := f.sandboxItems[institutionID]
+	if !ok {
+		return "", fmt.Errorf("failed to create sandbox public token: %w", &plaid.APIError{
+			StatusCode:   http.StatusBadRequest,
+			ErrorType:    "INVALID_INPUT",
+			ErrorCode:    "INVALID_INSTITUTION",
+			ErrorMessage: "invalid institution_id provided",
+			RequestID:    "fake",
+		})
+	}
+	f.next++
+	publicToken := fmt.Sprintf("public-sandbox-%d", f.next)
+	f.linkable[publicToken] = item
+	return publicToken, nil
+}
+
+// SandboxFireWebhook records the webhook as fired about the item accessToken belongs to
+func (f *Fake) SandboxFireWebhook(ctx context.Context, accessToken, webhookType, webhookCode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.sandbox {
+		return plaid.ErrNotSandbox
+	}
+	item, err := f.item(accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to fire sandbox webhook: %w", err)
+	}
+	f.fired = append(f.fired, FiredWebhook{ItemID: item.ItemID, WebhookType: webhookType, WebhookCode: webhookCode})
+	return nil
+}
@@ -0,0 +1,70 @@
+package plaid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultSandboxInstitution is the sandbox institution items are created at unless another is
+// chosen: First Platypus Bank, whose test user has investment accounts with holdings and
+// transactions
+const DefaultSandboxInstitution = "ins_109508"
+
+// ErrNotSandbox is returned by the sandbox calls of a client for another environment, which
+// Plaid would refuse
+var ErrNotSandbox = errors.New("plaid sandbox calls need PLAID_ENV=sandbox")
+
+// Sandbox reports whether the client calls Plaid's sandbox, where its test shortcuts are
+// available
+func (c *Client) Sandbox() bool {
+	return c.env == "sandbox"
+}
+
+// SandboxCreatePublicToken creates an item at the sandbox institution institutionID for the
+// investments product, skipping Plaid Link, and returns its public token to exchange
+func (c *Client) SandboxCreatePublicToken(ctx context.Context, institutionID string) (string, error) {
+	if !c.Sandbox() {
+		return "", ErrNotSandbox
+	}
+	request := map[string]any{
+		"institution_id":   institutionID,
+		"initial_products": []string{"investments"},
+	}
+	if c.webhookURL != "" {
+		request["options"] = map[string]string{"webhook": c.webhookURL}
+	}
+	var response struct {
+		PublicToken string `json:"public_token"`
+	}
+	if err := c.post(ctx, "/sandbox/public_token/create", request, &response); err != nil {
+		return "", fmt.Errorf("failed to create sandbox public token: %w", err)
+	}
+	if response.PublicToken == "" {
+		return "", errors.New("failed to create sandbox public token: response has no public token")
+	}
+	return response.PublicToken, nil
+}
+
+// SandboxFireWebhook has Plaid send the webhook webhookType/webhookCode about the sandbox item
+// accessToken belongs to, to the webhook URL it was created with
+func (c *Client) SandboxFireWebhook(ctx context.Context, accessToken, webhookType, webhookCode string) error {
+	if !c.Sandbox() {
+		return ErrNotSandbox
+	}
+	request := map[string]string{
+		"access_token": accessToken,
+		"webhook_type": webhookType,
+		"webhook_code": webhookCode,
+	}
+	var response struct {
+		WebhookFired bool `json:"webhook_fired"`
+	}
+	if err := c.post(ctx, "/sandbox/item/fire_webhook", request, &response); err != nil {
+		return fmt.Errorf("failed to fire sandbox webhook: %w", err)
+	}
+	if !response.WebhookFired {
+		return errors.New("failed to fire sandbox webhook: Plaid did not fire it")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package plaid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSandboxCallsOnlyInSandbox(t *testing.T) {
+	client, err := NewClient("client", "secret", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Sandbox() {
+		t.Fatal("a production client reports the sandbox")
+	}
+	if _, err := client.SandboxCreatePublicToken(context.Background(), DefaultSandboxInstitution); !errors.Is(err, ErrNotSandbox) {
+		t.Fatalf("SandboxCreatePublicToken in production: %v, want ErrNotSandbox", err)
+	}
+	if err := client.SandboxFireWebhook(context.Background(), "access", WebhookTypeHoldings, WebhookCodeDefaultUpdate); !errors.Is(err, ErrNotSandbox) {
+		t.Fatalf("SandboxFireWebhook in production: %v, want ErrNotSandbox", err)
+	}
+}
+
+func TestSandboxCreatePublicToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sandbox/public_token/create" {
+			http.NotFound(w, r)
+			return
+		}
+		var request struct {
+			InstitutionID   string            `json:"institution_id"`
+			InitialProducts []string          `json:"initial_products"`
+			Options         map[string]string `json:"options"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if request.InstitutionID != "ins_1" || len(request.InitialProducts) != 1 || request.InitialProducts[0] != "investments" ||
+			request.Options["webhook"] != "https://example.com/api/plaid/webhook" {
+			t.Errorf("request = %+v, want ins_1 for investments with the webhook URL", request)
+		}
+		w.Write([]byte(`{"public_token":"public-sandbox-1","request_id":"r"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient("client", "secret", "Sandbox", WithBaseURL(server.URL), WithWebhookURL("https://example.com/api/plaid/webhook"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicToken, err := client.SandboxCreatePublicToken(context.Background(), "ins_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicToken != "public-sandbox-1" {
+		t.Fatalf("public token = %q, want public-sandbox-1", publicToken)
+	}
+}
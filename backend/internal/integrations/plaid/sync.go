@@ -0,0 +1,251 @@
+package plaid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// accountTypeInvestment is the Plaid type of accounts that hold securities
+const accountTypeInvestment = "investment"
+
+// ItemReader reads the data of linked items. *Client implements it; a fake can stand in for it
+// to sync without the network.
+type ItemReader interface {
+	GetAccounts(ctx context.Context, accessToken string) ([]Account, error)
+	GetInvestments(ctx context.Context, accessToken string, accountIDs ...string) ([]Holding, error)
+	GetInvestmentTransactions(ctx context.Context, accessToken string, since time.Time, accountIDs ...string) ([]InvestmentTransaction, error)
+}
+
+var _ ItemReader = (*Client)(nil)
+
+// Syncer syncs linked Plaid items, decrypting each item's access token as it goes
+type Syncer struct {
+	client ItemReader
+	tokens *TokenCipher
+}
+
+// NewSyncer creates a syncer that reads items through client with the access tokens tokens
+// decrypts
+func NewSyncer(client ItemReader, tokens *TokenCipher) *Syncer {
+	return &Syncer{
+		client: client,
+		tokens: tokens,
+	}
+}
+
+// SyncOptions narrows what SyncItems fetches
+type SyncOptions struct {
+	// TransactionsSince is the date of the newest stored transaction of each account, by
+	// account ID. Its investment transactions are fetched from that date, so the day's are
+	// fetched again and rewritten, rather than over the whole lookback window.
+	TransactionsSince map[string]time.Time
+}
+
+// SyncItems fetches the accounts, holdings and investment transactions of every item. Each
+// Plaid account becomes a portfolio, so its tax treatment can be set on its own, and an account
+// of that portfolio holding its balance; each holding of an investment account becomes an
+// investment of that portfolio. An item that fails, such as one whose consent was revoked, is
+// reported in the result's items with its error and leaves the others to sync, and its stored
+// holdings are kept. Transactions that cannot be fetched are a warning that leaves the item
+// synced. Only a done ctx is returned as an error.
+func (s *Syncer) SyncItems(ctx context.Context, items []*models.PlaidItem, opts SyncOptions) (*models.SyncResult, error) {
+	result := &models.SyncResult{
+		Platform: models.PlatformM1Finance,
+		Report: models.SyncReport{
+			Status:        models.SyncStatusSuccess,
+			Portfolios:    []models.PortfolioSyncReport{},
+			SkippedAssets: []models.SkippedAsset{},
+			Items:         make([]models.ItemSyncReport, 0, len(items)),
+		},
+	}
+	for _, item := range items {
+		report := models.ItemSyncReport{
+			ID:              item.ID,
+			InstitutionName: item.InstitutionName,
+			Status:          models.SyncStatusSuccess,
+		}
+		synced, err := s.syncItem(ctx, item, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Warning: Failed to sync Plaid item %s (%s): %v", item.ID, item.InstitutionName, err)
+			report.Status = models.SyncStatusFailed
+			report.Error = err.Error()
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				report.ErrorCode = apiErr.ErrorCode
+			}
+			result.Report.Status = models.SyncStatusPartial
+		} else {
+			report.Accounts = len(synced.Accounts)
+			for _, account := range synced.Accounts {
+				report.AccountIDs = append(report.AccountIDs, account.ID)
+			}
+			result.Portfolios = append(result.Portfolios, synced.Portfolios...)
+			result.Accounts = append(result.Accounts, synced.Accounts...)
+			result.Investments = append(result.Investments, synced.Investments...)
+			result.Transactions = append(result.Transactions, synced.Transactions...)
+			result.CompletePortfolios = append(result.CompletePortfolios, synced.CompletePortfolios...)
+			result.Report.Portfolios = append(result.Report.Portfolios, synced.Report.Portfolios...)
+			result.Report.Holdings.Merge(synced.Report.Holdings)
+			if len(synced.Report.Warnings) > 0 {
+				result.Report.Warnings = append(result.Report.Warnings, synced.Report.Warnings...)
+				result.Report.Status = models.SyncStatusPartial
+			}
+		}
+		result.Report.Items = append(result.Report.Items, report)
+	}
+	return result, nil
+}
+
+// syncItem fetches the accounts of one item as portfolios and their accounts, and the holdings
+// and transactions of its investment accounts
+func (s *Syncer) syncItem(ctx context.Context, item *models.PlaidItem, opts SyncOptions) (*models.SyncResult, error) {
+	accessToken, err := s.tokens.Open(item.ID, item.EncryptedAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	plaidAccounts, err := s.client.GetAccounts(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	synced := &models.SyncResult{Platform: item.Platform}
+	syncedAt := models.Now()
+	reports := make(map[string]*models.PortfolioSyncReport)
+	for _, plaidAccount := range plaidAccounts {
+		name := accountName(plaidAccount)
+		synced.Portfolios = append(synced.Portfolios, &models.Portfolio{
+			ID:         plaidAccount.AccountID,
+			Platform:   item.Platform,
+			Name:       name,
+			Type:       plaidAccount.Subtype,
+			LastSynced: syncedAt,
+		})
+		synced.Accounts = append(synced.Accounts, &models.Account{
+			ID:          plaidAccount.AccountID,
+			Platform:    item.Platform,
+			PortfolioID: plaidAccount.AccountID,
+			Name:        name,
+			Currency:    currencyCode(plaidAccount.Balances.IsoCurrencyCode, plaidAccount.Balances.UnofficialCurrencyCode),
+			Type:        plaidAccount.Type,
+			Available:   accountBalance(plaidAccount.Balances),
+			Active:      true,
+			LastSynced:  syncedAt,
+		})
+		if plaidAccount.Type == accountTypeInvestment {
+			reports[plaidAccount.AccountID] = &models.PortfolioSyncReport{
+				ID:     plaidAccount.AccountID,
+				Name:   name,
+				Status: models.SyncStatusSuccess,
+			}
+		}
+	}
+	// Only investment accounts have holdings; Plaid refuses the call for an item without any
+	if len(reports) == 0 {
+		return synced, nil
+	}
+
+	holdings, err := s.client.GetInvestments(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	for _, holding := range holdings {
+		report, ok := reports[holding.AccountID]
+		if !ok || holding.Quantity == 0 {
+			continue
+		}
+		synced.Investments = append(synced.Investments, holdingInvestment(holding, item.Platform))
+		report.Holdings.Add(false)
+	}
+	// Every holding of these accounts was fetched, so a stored one missing from them was sold
+	for _, plaidAccount := range plaidAccounts {
+		if report, ok := reports[plaidAccount.AccountID]; ok {
+			synced.CompletePortfolios = append(synced.CompletePortfolios, report.ID)
+			synced.Report.Portfolios = append(synced.Report.Portfolios, *report)
+			synced.Report.Holdings.Merge(report.Holdings)
+		}
+	}
+
+	for _, accountID := range synced.CompletePortfolios {
+		transactions, err := s.accountTransactions(ctx, accessToken, item.Platform, accountID, opts.TransactionsSince[accountID])
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Warning: Failed to sync the transactions of Plaid account %s: %v", accountID, err)
+			synced.Report.Warnings = append(synced.Report.Warnings, fmt.Sprintf("transactions of account %s: %v", accountID, err))
+			continue
+		}
+		synced.Transactions = append(synced.Transactions, transactions...)
+	}
+	return synced, nil
+}
+
+// accountTransactions fetches the investment transactions of an account from since, or over
+// the client's lookback window if since is zero or earlier
+func (s *Syncer) accountTransactions(ctx context.Context, accessToken string, platform models.Platform, accountID string, since time.Time) ([]*models.Transaction, error) {
+	plaidTransactions, err := s.client.GetInvestmentTransactions(ctx, accessToken, since, accountID)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]*models.Transaction, 0, len(plaidTransactions))
+	for _, plaidTransaction := range plaidTransactions {
+		transaction, ok, err := investmentTransaction(plaidTransaction, platform)
+		if err != nil {
+			log.Printf("Warning: Skipping Plaid investment transaction: %v", err)
+			continue
+		}
+		if ok {
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions, nil
+}
+
+// accountBalance returns the balance an account is stored with. Unlike a Coinbase account,
+// whose available balance is what it holds, Plaid's available balance of an investment account
+// is only the cash that could be withdrawn and is often null. The current balance is what the
+// account is worth, which is what M1 shows, so it is used; available is the fallback when the
+// institution reports no current balance.
+func accountBalance(balances Balances) float64 {
+	if balances.Current != nil {
+		return *balances.Current
+	}
+	if balances.Available != nil {
+		return *balances.Available
+	}
+	return 0
+}
+
+// currencyCode returns the currency Plaid gives as an ISO code, or for currencies without one
+// as an unofficial code, USD if it gives neither
+func currencyCode(iso, unofficial string) string {
+	if iso != "" {
+		return strings.ToUpper(iso)
+	}
+	if unofficial != "" {
+		return strings.ToUpper(unofficial)
+	}
+	return "USD"
+}
+
+// accountName names an account as the institution does, with the last digits of its number
+// to tell apart accounts of the same name
+func accountName(account Account) string {
+	name := account.Name
+	if name == "" {
+		name = account.OfficialName
+	}
+	if account.Mask != "" {
+		name = fmt.Sprintf("%s (%s)", name, account.Mask)
+	}
+	return name
+}
@@ -0,0 +1,73 @@
+package plaid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks the format of a sealed token, so the key or cipher can change later
+const sealedPrefix = "v1:"
+
+// TokenCipher encrypts Plaid access tokens for storage with AES-256-GCM. Each token is bound to
+// its item ID, so a stored token cannot be swapped onto another item.
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher creates a cipher from key, 32 bytes encoded in standard base64 such as the
+// output of `openssl rand -base64 32`
+func NewTokenCipher(key string) (*TokenCipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("PLAID_TOKEN_KEY must be base64: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("PLAID_TOKEN_KEY must be 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// Seal encrypts the access token of item itemID
+func (t *TokenCipher) Seal(itemID, accessToken string) (string, error) {
+	nonce := make([]byte, t.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := t.aead.Seal(nonce, nonce, []byte(accessToken), []byte(itemID))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts the access token of item itemID sealed by Seal. It fails if the token was
+// sealed with another key or for another item.
+func (t *TokenCipher) Open(itemID, sealed string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", errors.New("access token is not in a known sealed format")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("access token is corrupt: %w", err)
+	}
+	if len(raw) < t.aead.NonceSize() {
+		return "", errors.New("access token is corrupt: too short")
+	}
+	nonce, ciphertext := raw[:t.aead.NonceSize()], raw[t.aead.NonceSize():]
+	plaintext, err := t.aead.Open(nil, nonce, ciphertext, []byte(itemID))
+	if err != nil {
+		return "", errors.New("access token cannot be decrypted with PLAID_TOKEN_KEY")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,107 @@
+package plaid
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// newTestCipher returns a cipher whose key is 32 copies of b
+func newTestCipher(t *testing.T, b byte) *TokenCipher {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+	tokens, err := NewTokenCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestTokenCipherRoundTrip(t *testing.T) {
+	tokens := newTestCipher(t, 'k')
+	sealed, err := tokens.Seal("item-1", "access-sandbox-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, "access-sandbox-123") {
+		t.Fatalf("sealed token = %q, want it versioned and not in plaintext", sealed)
+	}
+	opened, err := tokens.Open("item-1", sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != "access-sandbox-123" {
+		t.Fatalf("opened token = %q, want access-sandbox-123", opened)
+	}
+
+	// Each seal has its own nonce
+	again, err := tokens.Seal("item-1", "access-sandbox-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Fatal("sealing the same token twice gave the same ciphertext")
+	}
+}
+
+func TestTokenCipherRejectsWrongKeyAndItem(t *testing.T) {
+	tokens := newTestCipher(t, 'k')
+	sealed, err := tokens.Seal("item-1", "access-sandbox-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestCipher(t, 'x').Open("item-1", sealed); err == nil {
+		t.Error("opened a token with another key")
+	}
+	if _, err := tokens.Open("item-2", sealed); err == nil {
+		t.Error("opened a token sealed for another item")
+	}
+}
+
+func TestTokenCipherRejectsTamperedTokens(t *testing.T) {
+	tokens := newTestCipher(t, 'k')
+	sealed, err := tokens.Seal("item-1", "access-sandbox-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 1
+	flipped := sealedPrefix + base64.StdEncoding.EncodeToString(raw)
+
+	for name, token := range map[string]string{
+		"flipped bit": flipped,
+		"truncated":   sealedPrefix + base64.StdEncoding.EncodeToString(raw[:4]),
+		"not base64":  sealedPrefix + "!!!",
+		"plaintext":   "access-sandbox-123",
+		"unknown":     "v2:" + strings.TrimPrefix(sealed, sealedPrefix),
+	} {
+		opened, err := tokens.Open("item-1", token)
+		if err == nil {
+			t.Errorf("%s: opened %q", name, opened)
+			continue
+		}
+		if strings.Contains(err.Error(), "access-sandbox-123") {
+			t.Errorf("%s: error %q reveals the token", name, err)
+		}
+	}
+}
+
+func TestNewTokenCipherValidatesKey(t *testing.T) {
+	for name, key := range map[string]string{
+		"not base64": "not a key!",
+		"too short":  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 16))),
+		"empty":      "",
+	} {
+		if _, err := NewTokenCipher(key); err == nil {
+			t.Errorf("%s: NewTokenCipher accepted the key", name)
+		}
+	}
+	// Surrounding whitespace, as in a key read from a file, is ignored
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	if _, err := NewTokenCipher(" " + key + "\n"); err != nil {
+		t.Errorf("key with surrounding whitespace: %v", err)
+	}
+}
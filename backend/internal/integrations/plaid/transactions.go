@@ -0,0 +1,179 @@
+package plaid
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// transactionsPageSize is how many investment transactions are requested per page, the most
+// Plaid returns at once
+const transactionsPageSize = 500
+
+// defaultTransactionsLookback is how far back the first sync of an account fetches its
+// investment transactions unless PLAID_TRANSACTIONS_LOOKBACK_DAYS says otherwise. Plaid keeps
+// 24 months of them.
+const defaultTransactionsLookback = 730 * 24 * time.Hour
+
+// plaidDate is the date format of Plaid requests and responses
+const plaidDate = "2006-01-02"
+
+// InvestmentTransaction is a trade or cash movement in an investment account, joined with the
+// security it concerns
+type InvestmentTransaction struct {
+	InvestmentTransactionID string  `json:"investment_transaction_id"`
+	AccountID               string  `json:"account_id"`
+	SecurityID              string  `json:"security_id"`
+	Date                    string  `json:"date"`
+	Name                    string  `json:"name"`
+	Quantity                float64 `json:"quantity"`
+	// Amount is positive when cash leaves the account, such as for a buy, and negative when it
+	// comes in, such as for a sale or dividend. It includes fees.
+	Amount                 float64 `json:"amount"`
+	Price                  float64 `json:"price"`
+	Fees                   float64 `json:"fees"`
+	Type                   string  `json:"type"`    // buy, sell, cancel, cash, fee or transfer
+	Subtype                string  `json:"subtype"` // such as dividend, deposit or withdrawal
+	IsoCurrencyCode        string  `json:"iso_currency_code"`
+	UnofficialCurrencyCode string  `json:"unofficial_currency_code"`
+	// Security is the security traded, nil for cash movements or if Plaid did not list it
+	Security *Security `json:"-"`
+}
+
+// transactionsLookback reads PLAID_TRANSACTIONS_LOOKBACK_DAYS
+func transactionsLookback() (time.Duration, error) {
+	val := os.Getenv("PLAID_TRANSACTIONS_LOOKBACK_DAYS")
+	if val == "" {
+		return defaultTransactionsLookback, nil
+	}
+	days, err := strconv.Atoi(val)
+	if err != nil || days < 1 || days > 730 {
+		return 0, fmt.Errorf("PLAID_TRANSACTIONS_LOOKBACK_DAYS must be a number of days from 1 to 730, got %q", val)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// GetInvestmentTransactions returns the investment transactions of the item accessToken
+// belongs to dated from since through today, each joined with its security. A zero since, or
+// one before the client's lookback window, fetches the whole window. They are fetched a page at
+// a time until Plaid's total is reached; accountIDs limits them to those accounts.
+func (c *Client) GetInvestmentTransactions(ctx context.Context, accessToken string, since time.Time, accountIDs ...string) ([]InvestmentTransaction, error) {
+	end := time.Now().UTC()
+	start := end.Add(-c.transactionsLookback)
+	if since.After(start) {
+		start = since
+	}
+	securities := make(map[string]*Security)
+	transactions := make([]InvestmentTransaction, 0)
+	for {
+		options := map[string]any{
+			"count":  transactionsPageSize,
+			"offset": len(transactions),
+		}
+		if len(accountIDs) > 0 {
+			options["account_ids"] = accountIDs
+		}
+		request := map[string]any{
+			"access_token": accessToken,
+			"start_date":   start.Format(plaidDate),
+			"end_date":     end.Format(plaidDate),
+			"options":      options,
+		}
+		var response struct {
+			InvestmentTransactions      []InvestmentTransaction `json:"investment_transactions"`
+			Securities                  []Security              `json:"securities"`
+			TotalInvestmentTransactions int                     `json:"total_investment_transactions"`
+		}
+		if err := c.post(ctx, "/investments/transactions/get", request, &response); err != nil {
+			return nil, fmt.Errorf("failed to get investment transactions: %w", err)
+		}
+		for i := range response.Securities {
+			securities[response.Securities[i].SecurityID] = &response.Securities[i]
+		}
+		transactions = append(transactions, response.InvestmentTransactions...)
+		// An empty page ends the listing even if the total says otherwise, so a total that
+		// shrinks between pages cannot loop forever
+		if len(response.InvestmentTransactions) == 0 || len(transactions) >= response.TotalInvestmentTransactions {
+			break
+		}
+	}
+	for i := range transactions {
+		transactions[i].Security = securities[transactions[i].SecurityID]
+	}
+	return transactions, nil
+}
+
+// transactionType maps a Plaid investment transaction onto a transaction type. Cancellations,
+// cash movements other than deposits, withdrawals and dividends, and types Plaid adds later are
+// not stored.
+func transactionType(transaction InvestmentTransaction) (models.TransactionType, bool) {
+	subtype := strings.ToLower(transaction.Subtype)
+	switch strings.ToLower(transaction.Type) {
+	case "buy":
+		return models.TransactionTypeBuy, true
+	case "sell":
+		return models.TransactionTypeSell, true
+	case "fee":
+		return models.TransactionTypeFee, true
+	case "transfer":
+		return models.TransactionTypeTransfer, true
+	case "cash":
+		switch {
+		case strings.Contains(subtype, "dividend"):
+			return models.TransactionTypeDividend, true
+		case subtype == "deposit" || subtype == "contribution":
+			return models.TransactionTypeDeposit, true
+		case subtype == "withdrawal" || subtype == "distribution":
+			return models.TransactionTypeWithdraw, true
+		}
+	}
+	return "", false
+}
+
+// investmentTransaction maps a Plaid investment transaction of platform into a transaction,
+// keyed by Plaid's ID so syncing it again rewrites it. Amounts and quantities are unsigned, as
+// for Coinbase fills, except a transfer's quantity, which is negative when units leave.
+func investmentTransaction(transaction InvestmentTransaction, platform models.Platform) (*models.Transaction, bool, error) {
+	txType, ok := transactionType(transaction)
+	if !ok {
+		return nil, false, nil
+	}
+	date, err := time.Parse(plaidDate, transaction.Date)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid date %q of investment transaction %s", transaction.Date, transaction.InvestmentTransactionID)
+	}
+
+	quantity := math.Abs(transaction.Quantity)
+	if txType == models.TransactionTypeTransfer {
+		quantity = transaction.Quantity
+	}
+	// Cash movements, dividends included, and fees move cash rather than units
+	if plaidType := strings.ToLower(transaction.Type); plaidType == "cash" || plaidType == "fee" {
+		quantity = 0
+	}
+	// A trade's amount is its units at their price, with the fees kept apart as for Coinbase
+	amount := math.Abs(transaction.Amount)
+	if (txType == models.TransactionTypeBuy || txType == models.TransactionTypeSell) && transaction.Price > 0 && quantity > 0 {
+		amount = quantity * transaction.Price
+	}
+
+	return &models.Transaction{
+		ID:          "plaid-" + transaction.InvestmentTransactionID,
+		AccountID:   transaction.AccountID,
+		Platform:    platform,
+		Type:        txType,
+		Symbol:      securitySymbol(transaction.Security, transaction.SecurityID),
+		Quantity:    quantity,
+		Amount:      amount,
+		Currency:    currencyCode(transaction.IsoCurrencyCode, transaction.UnofficialCurrencyCode),
+		Fee:         math.Abs(transaction.Fees),
+		Timestamp:   date,
+		Description: transaction.Name,
+	}, true, nil
+}
@@ -0,0 +1,157 @@
+package plaid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Webhook types and codes that report new investment data
+const (
+	WebhookTypeHoldings                = "HOLDINGS"
+	WebhookTypeInvestmentsTransactions = "INVESTMENTS_TRANSACTIONS"
+	WebhookCodeDefaultUpdate           = "DEFAULT_UPDATE"
+	WebhookCodeHistoricalUpdate        = "HISTORICAL_UPDATE"
+)
+
+// webhookMaxAge is how old a webhook's verification token may be, as Plaid recommends, so a
+// captured webhook cannot be replayed later
+const webhookMaxAge = 5 * time.Minute
+
+// webhookKeyTTL is how long a webhook verification key is cached before Plaid is asked again
+// whether it has expired
+const webhookKeyTTL = 24 * time.Hour
+
+// Webhook is a notification Plaid posts about an item
+type Webhook struct {
+	WebhookType string    `json:"webhook_type"`
+	WebhookCode string    `json:"webhook_code"`
+	ItemID      string    `json:"item_id"`
+	Error       *APIError `json:"error"`
+}
+
+// RefreshesInvestments reports whether the webhook says an item's holdings or investment
+// transactions changed, so the item should be synced again
+func (w Webhook) RefreshesInvestments() bool {
+	switch w.WebhookType {
+	case WebhookTypeHoldings:
+		return w.WebhookCode == WebhookCodeDefaultUpdate
+	case WebhookTypeInvestmentsTransactions:
+		return w.WebhookCode == WebhookCodeDefaultUpdate || w.WebhookCode == WebhookCodeHistoricalUpdate
+	}
+	return false
+}
+
+// webhookKey is a key Plaid signs webhooks with, as a JSON Web Key
+type webhookKey struct {
+	Alg       string `json:"alg"`
+	Crv       string `json:"crv"`
+	Kid       string `json:"kid"`
+	Kty       string `json:"kty"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	ExpiredAt *int64 `json:"expired_at"`
+}
+
+// VerifyWebhook checks that body was posted by Plaid, given the JWT in its Plaid-Verification
+// header: the token must be signed with ES256 by a current Plaid key, be at most five minutes
+// old and carry the SHA-256 of body.
+func (c *Client) VerifyWebhook(ctx context.Context, body []byte, verification string) error {
+	if verification == "" {
+		return errors.New("webhook has no Plaid-Verification header")
+	}
+	var claims struct {
+		jwt.RegisteredClaims
+		RequestBodySHA256 string `json:"request_body_sha256"`
+	}
+	_, err := jwt.ParseWithClaims(verification, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no key ID")
+		}
+		return c.webhookVerificationKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}), jwt.WithIssuedAt())
+	if err != nil {
+		return fmt.Errorf("invalid webhook verification token: %w", err)
+	}
+	if claims.IssuedAt == nil || time.Since(claims.IssuedAt.Time) > webhookMaxAge {
+		return errors.New("webhook verification token is too old")
+	}
+	sum := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(claims.RequestBodySHA256)) != 1 {
+		return errors.New("webhook body does not match its verification token")
+	}
+	return nil
+}
+
+// cachedWebhookKey is a webhook verification key and when it was fetched
+type cachedWebhookKey struct {
+	key       *ecdsa.PublicKey
+	fetchedAt time.Time
+}
+
+// webhookVerificationKey returns the public key kid names. Plaid rotates keys under new key
+// IDs, so a key is cached for webhookKeyTTL rather than fetched for every webhook; one Plaid
+// reports expired is refused.
+func (c *Client) webhookVerificationKey(ctx context.Context, kid string) (*ecdsa.PublicKey, error) {
+	c.webhookKeysMu.Lock()
+	cached, ok := c.webhookKeys[kid]
+	c.webhookKeysMu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < webhookKeyTTL {
+		return cached.key, nil
+	}
+
+	var response struct {
+		Key webhookKey `json:"key"`
+	}
+	if err := c.post(ctx, "/webhook_verification_key/get", map[string]string{"key_id": kid}, &response); err != nil {
+		return nil, fmt.Errorf("failed to get webhook verification key: %w", err)
+	}
+	if response.Key.ExpiredAt != nil {
+		c.webhookKeysMu.Lock()
+		delete(c.webhookKeys, kid)
+		c.webhookKeysMu.Unlock()
+		return nil, fmt.Errorf("webhook verification key %s expired", kid)
+	}
+	key, err := response.Key.publicKey()
+	if err != nil {
+		return nil, err
+	}
+	c.webhookKeysMu.Lock()
+	c.webhookKeys[kid] = cachedWebhookKey{key: key, fetchedAt: time.Now()}
+	c.webhookKeysMu.Unlock()
+	return key, nil
+}
+
+// publicKey decodes a P-256 JSON Web Key
+func (k webhookKey) publicKey() (*ecdsa.PublicKey, error) {
+	if k.Kty != "EC" || k.Crv != "P-256" {
+		return nil, fmt.Errorf("webhook verification key %s is %s/%s, not an EC P-256 key", k.Kid, k.Kty, k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x of webhook verification key %s: %w", k.Kid, err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y of webhook verification key %s: %w", k.Kid, err)
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("webhook verification key %s is not a P-256 point", k.Kid)
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
@@ -0,0 +1,74 @@
+package youtube
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ChannelIDCacheTTL is how long a handle or username resolved to a channel ID is reused.
+	// A channel keeps its ID for good; only its handle can move to another channel.
+	ChannelIDCacheTTL = 24 * time.Hour
+	// ChannelNotFoundCacheTTL is how long a handle or username that matched no channel is
+	// answered from the cache, so a mistyped one is not looked up on every test or poll
+	ChannelNotFoundCacheTTL = 10 * time.Minute
+)
+
+// ErrChannelNotFound is returned when a handle or username matches no channel
+var ErrChannelNotFound = errors.New("channel not found")
+
+// channelCacheEntry is a resolved channel ID, or an empty one for a name that matched no channel
+type channelCacheEntry struct {
+	channelID string
+	expires   time.Time
+}
+
+// channelCache remembers what handles and usernames resolved to. Its methods are safe for
+// concurrent use.
+type channelCache struct {
+	mu      sync.Mutex
+	entries map[string]channelCacheEntry
+	now     func() time.Time
+}
+
+func newChannelCache() *channelCache {
+	return &channelCache{
+		entries: make(map[string]channelCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// channelCacheKey returns the key of a handle or username. Both are matched without regard to
+// case by YouTube, so they are cached that way.
+func channelCacheKey(kind, name string) string {
+	return kind + ":" + strings.ToLower(name)
+}
+
+// get returns the cached channel ID of key, empty if the name matched no channel, and false if
+// nothing unexpired is cached
+func (cc *channelCache) get(key string) (string, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	entry, ok := cc.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !cc.now().Before(entry.expires) {
+		delete(cc.entries, key)
+		return "", false
+	}
+	return entry.channelID, true
+}
+
+// put caches channelID for key, or that the name matched no channel if channelID is empty
+func (cc *channelCache) put(key, channelID string) {
+	ttl := ChannelIDCacheTTL
+	if channelID == "" {
+		ttl = ChannelNotFoundCacheTTL
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.entries[key] = channelCacheEntry{channelID: channelID, expires: cc.now().Add(ttl)}
+}
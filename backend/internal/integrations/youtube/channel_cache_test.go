@@ -0,0 +1,123 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChannels resolves the handle and username "known" to UCknown and matches nothing else,
+// counting the lookups
+type fakeChannels struct {
+	mu      sync.Mutex
+	lookups int
+}
+
+func (f *fakeChannels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/channels" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	f.lookups++
+	f.mu.Unlock()
+	query := r.URL.Query()
+	name := query.Get("forHandle") + query.Get("forUsername")
+	if strings.EqualFold(name, "known") {
+		w.Write([]byte(`{"items":[{"id":"UCknown"}]}`))
+		return
+	}
+	w.Write([]byte(`{"items":[]}`))
+}
+
+// newCacheTestClient returns a client resolving names through fake, with its cache on a clock
+// the returned function advances
+func newCacheTestClient(t *testing.T, fake *fakeChannels) (*Client, func(time.Duration)) {
+	t.Helper()
+	client := newTestClient(t, fake)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client.channelIDs.now = func() time.Time { return now }
+	return client, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestChannelCacheHitAndMiss(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeChannels{}
+	client, _ := newCacheTestClient(t, fake)
+
+	for _, url := range []string{"https://www.youtube.com/@known", "https://www.youtube.com/@Known/videos"} {
+		channelID, err := client.ExtractChannelID(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if channelID != "UCknown" {
+			t.Fatalf("%s resolved to %q, want UCknown", url, channelID)
+		}
+	}
+	if fake.lookups != 1 {
+		t.Fatalf("%d lookups for one handle in two cases, want 1", fake.lookups)
+	}
+
+	// Usernames are cached apart from handles
+	if _, err := client.ExtractChannelID(ctx, "https://www.youtube.com/c/known"); err != nil {
+		t.Fatal(err)
+	}
+	if fake.lookups != 2 {
+		t.Fatalf("%d lookups after a username, want 2", fake.lookups)
+	}
+
+	// A name that matches no channel is cached as not found
+	for i := 0; i < 2; i++ {
+		if _, err := client.ExtractChannelID(ctx, "https://www.youtube.com/@typo"); !errors.Is(err, ErrChannelNotFound) {
+			t.Fatalf("mistyped handle: %v, want ErrChannelNotFound", err)
+		}
+	}
+	if fake.lookups != 3 {
+		t.Fatalf("%d lookups after a mistyped handle twice, want 3", fake.lookups)
+	}
+
+	// Channel URLs carry their ID and are never looked up
+	if channelID, err := client.ExtractChannelID(ctx, "https://www.youtube.com/channel/UCabcdefghijklmnopqrstuv"); err != nil || channelID != "UCabcdefghijklmnopqrstuv" {
+		t.Fatalf("channel URL resolved to %q (%v)", channelID, err)
+	}
+	if fake.lookups != 3 {
+		t.Fatalf("%d lookups after a channel URL, want still 3", fake.lookups)
+	}
+}
+
+func TestChannelCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeChannels{}
+	client, advance := newCacheTestClient(t, fake)
+	resolve := func(url string) {
+		t.Helper()
+		if _, err := client.ExtractChannelID(ctx, url); err != nil && !errors.Is(err, ErrChannelNotFound) {
+			t.Fatal(err)
+		}
+	}
+
+	resolve("https://www.youtube.com/@known")
+	resolve("https://www.youtube.com/@typo")
+	if fake.lookups != 2 {
+		t.Fatalf("%d lookups, want 2", fake.lookups)
+	}
+
+	// Past the not-found TTL the mistyped handle is looked up again, the known one is not
+	advance(ChannelNotFoundCacheTTL)
+	resolve("https://www.youtube.com/@known")
+	resolve("https://www.youtube.com/@typo")
+	if fake.lookups != 3 {
+		t.Fatalf("%d lookups after %v, want only the mistyped handle again", fake.lookups, ChannelNotFoundCacheTTL)
+	}
+
+	// Past its own TTL the known handle is looked up again
+	advance(ChannelIDCacheTTL)
+	resolve("https://www.youtube.com/@known")
+	if fake.lookups != 4 {
+		t.Fatalf("%d lookups after %v, want the known handle again", fake.lookups, ChannelIDCacheTTL)
+	}
+}
@@ -1,12 +1,14 @@
 package youtube
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +19,9 @@ const (
 	MaxResultsMax = 50
 	// MaxErrorMessageSize limits error message size to prevent memory issues
 	MaxErrorMessageSize = 500
+	// MaxChannelPages bounds how many pages of search results GetChannelVideos reads; each
+	// search costs 100 of the API's 10,000 daily quota units
+	MaxChannelPages = 10
 )
 
 // Client handles communication with YouTube Data API v3
@@ -24,6 +29,13 @@ type Client struct {
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+	// uploadsPlaylists caches the uploads playlist ID of each channel, which never changes
+	uploadsMu        sync.Mutex
+	uploadsPlaylists map[string]string
+	// quota, if set, refuses requests once the day's estimated usage reaches its budget
+	quota *Quota
+	// channelIDs caches what handles and usernames resolved to
+	channelIDs *channelCache
 }
 
 // Video represents a YouTube video from the API
@@ -38,7 +50,8 @@ type Video struct {
 
 // SearchResponse represents the response from YouTube Data API search endpoint
 type SearchResponse struct {
-	Items []SearchItem `json:"items"`
+	Items         []SearchItem `json:"items"`
+	NextPageToken string       `json:"nextPageToken"`
 }
 
 // SearchItem represents a single item in the search response
@@ -71,26 +84,78 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("YouTube API error: %d - %s", e.StatusCode, e.Message)
 }
 
+// Option configures how NewClient builds a Client
+type Option func(*Client)
+
+// WithQuota counts the estimated cost of every request against quota and refuses requests with
+// a QuotaExceededError once its daily budget is reached
+func WithQuota(quota *Quota) Option {
+	return func(c *Client) {
+		c.quota = quota
+	}
+}
+
+// WithBaseURL sends the API requests to baseURL instead of the YouTube Data API, such as to a
+// fake of it in tests
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
 // NewClient creates a new YouTube Data API client
-func NewClient(apiKey string) *Client {
+func NewClient(apiKey string, opts ...Option) *Client {
 	if apiKey == "" {
 		return nil
 	}
 
-	return &Client{
+	client := &Client{
 		apiKey: apiKey,
 		baseURL: "https://www.googleapis.com/youtube/v3",
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		uploadsPlaylists: make(map[string]string),
+		channelIDs:       newChannelCache(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
+// QuotaUsage returns the estimated quota usage of the current day, or false if the client
+// does not track its quota
+func (c *Client) QuotaUsage(ctx context.Context) (QuotaUsage, bool) {
+	if c == nil || c.quota == nil {
+		return QuotaUsage{}, false
+	}
+	return c.quota.Usage(ctx), true
+}
+
+// get sends a GET request costing cost quota units, which is abandoned if ctx is cancelled
+// before the response arrives. It is refused with a QuotaExceededError, unsent, if the cost
+// would take the day's usage past the budget.
+func (c *Client) get(ctx context.Context, reqURL string, cost int) (*http.Response, error) {
+	if c.quota != nil {
+		if err := c.quota.Reserve(ctx, cost); err != nil {
+			return nil, err
+		}
 	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.httpClient.Do(req)
 }
 
-// GetChannelVideos fetches recent videos from a YouTube channel
+// GetChannelVideos fetches recent videos from a YouTube channel, newest first
 // channelID: The YouTube channel ID (not the custom URL)
-// maxResults: Maximum number of videos to return (1-50)
-// publishedAfter: Only return videos published after this time (optional)
-func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAfter *time.Time) ([]Video, error) {
+// maxResults: Maximum number of videos to return. The API returns at most 50 per request, so
+// more are fetched by following nextPageToken, up to MaxChannelPages pages.
+// publishedAfter: Only return videos published after this time (optional). Paging stops at the
+// first video that predates it.
+func (c *Client) GetChannelVideos(ctx context.Context, channelID string, maxResults int, publishedAfter *time.Time) ([]Video, error) {
 	if c == nil {
 		return nil, fmt.Errorf("YouTube client not initialized (API key not set)")
 	}
@@ -98,10 +163,51 @@ func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAft
 	if maxResults < 1 {
 		maxResults = 10
 	}
-	if maxResults > 50 {
-		maxResults = 50
+
+	videos := make([]Video, 0, min(maxResults, MaxResultsMax))
+	pageToken := ""
+	for page := 0; page < MaxChannelPages; page++ {
+		searchResp, err := c.searchChannelVideos(ctx, channelID, min(maxResults-len(videos), MaxResultsMax), publishedAfter, pageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		// Convert to Video structs
+		for _, item := range searchResp.Items {
+			publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+			if err != nil {
+				// Skip videos with invalid timestamps
+				continue
+			}
+			// Results are newest first, so the rest are older too
+			if publishedAfter != nil && !publishedAt.After(*publishedAfter) {
+				return videos, nil
+			}
+
+			videos = append(videos, Video{
+				ID:           item.ID.VideoID,
+				Title:        item.Snippet.Title,
+				Description:  item.Snippet.Description,
+				PublishedAt:  publishedAt,
+				ChannelID:    item.Snippet.ChannelID,
+				ChannelTitle: item.Snippet.ChannelTitle,
+			})
+			if len(videos) == maxResults {
+				return videos, nil
+			}
+		}
+
+		if searchResp.NextPageToken == "" {
+			break
+		}
+		pageToken = searchResp.NextPageToken
 	}
 
+	return videos, nil
+}
+
+// searchChannelVideos fetches one page of up to maxResults of a channel's videos, newest first
+func (c *Client) searchChannelVideos(ctx context.Context, channelID string, maxResults int, publishedAfter *time.Time, pageToken string) (*SearchResponse, error) {
 	// Build request URL
 	reqURL := fmt.Sprintf("%s/search", c.baseURL)
 	params := url.Values{}
@@ -115,11 +221,14 @@ func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAft
 	if publishedAfter != nil {
 		params.Set("publishedAfter", publishedAfter.Format(time.RFC3339))
 	}
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
 
 	reqURL += "?" + params.Encode()
 
 	// Make request
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL, CostSearch)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
@@ -133,26 +242,7 @@ func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAft
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		errorMsg := string(bodyBytes)
-		// Limit error message size
-		if len(errorMsg) > MaxErrorMessageSize {
-			errorMsg = errorMsg[:MaxErrorMessageSize] + "..."
-		}
-		
-		// Provide user-friendly error messages for common cases
-		switch resp.StatusCode {
-		case http.StatusForbidden:
-			errorMsg = "YouTube API quota exceeded or API key invalid"
-		case http.StatusBadRequest:
-			errorMsg = "Invalid YouTube API request: " + errorMsg
-		case http.StatusUnauthorized:
-			errorMsg = "YouTube API key is invalid or missing"
-		}
-		
-		return nil, &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    errorMsg,
-		}
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
 	}
 
 	// Parse response
@@ -160,27 +250,7 @@ func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAft
 	if err := json.Unmarshal(bodyBytes, &searchResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
-	// Convert to Video structs
-	videos := make([]Video, 0, len(searchResp.Items))
-	for _, item := range searchResp.Items {
-		publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
-		if err != nil {
-			// Skip videos with invalid timestamps
-			continue
-		}
-
-		videos = append(videos, Video{
-			ID:           item.ID.VideoID,
-			Title:        item.Snippet.Title,
-			Description:  item.Snippet.Description,
-			PublishedAt:  publishedAt,
-			ChannelID:    item.Snippet.ChannelID,
-			ChannelTitle: item.Snippet.ChannelTitle,
-		})
-	}
-
-	return videos, nil
+	return &searchResp, nil
 }
 
 // ExtractChannelID extracts channel ID from various YouTube URL formats
@@ -188,7 +258,9 @@ func (c *Client) GetChannelVideos(channelID string, maxResults int, publishedAft
 // - https://www.youtube.com/channel/UC... (standard channel ID format)
 // - https://www.youtube.com/@username (custom handle format)
 // - https://www.youtube.com/c/ChannelName (custom URL format)
-func (c *Client) ExtractChannelID(channelURL string) (string, error) {
+// Handles and usernames are resolved with the API and cached for ChannelIDCacheTTL; one that
+// matches no channel fails with ErrChannelNotFound, cached for ChannelNotFoundCacheTTL.
+func (c *Client) ExtractChannelID(ctx context.Context, channelURL string) (string, error) {
 	if c == nil {
 		return "", fmt.Errorf("YouTube client not initialized (API key not set)")
 	}
@@ -214,7 +286,7 @@ func (c *Client) ExtractChannelID(channelURL string) (string, error) {
 			handle = strings.Split(handle, "?")[0]
 			if handle != "" {
 				// Use YouTube API to resolve handle to channel ID
-				return c.resolveHandleToChannelID(handle)
+				return c.resolveHandleToChannelID(ctx, handle)
 			}
 		}
 	}
@@ -227,7 +299,7 @@ func (c *Client) ExtractChannelID(channelURL string) (string, error) {
 			username = strings.Split(username, "?")[0]
 			if username != "" {
 				// Use YouTube API to resolve username to channel ID
-				return c.resolveUsernameToChannelID(username)
+				return c.resolveUsernameToChannelID(ctx, username)
 			}
 		}
 	}
@@ -236,7 +308,15 @@ func (c *Client) ExtractChannelID(channelURL string) (string, error) {
 }
 
 // resolveHandleToChannelID resolves a YouTube handle (@username) to a channel ID
-func (c *Client) resolveHandleToChannelID(handle string) (string, error) {
+func (c *Client) resolveHandleToChannelID(ctx context.Context, handle string) (string, error) {
+	cacheKey := channelCacheKey("handle", handle)
+	if channelID, ok := c.channelIDs.get(cacheKey); ok {
+		if channelID == "" {
+			return "", fmt.Errorf("%w for handle: %s", ErrChannelNotFound, handle)
+		}
+		return channelID, nil
+	}
+
 	reqURL := fmt.Sprintf("%s/channels", c.baseURL)
 	params := url.Values{}
 	params.Set("key", c.apiKey)
@@ -245,7 +325,7 @@ func (c *Client) resolveHandleToChannelID(handle string) (string, error) {
 	
 	reqURL += "?" + params.Encode()
 	
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL, CostChannels)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve handle: %w", err)
 	}
@@ -271,14 +351,24 @@ func (c *Client) resolveHandleToChannelID(handle string) (string, error) {
 	}
 	
 	if len(channelResp.Items) == 0 {
-		return "", fmt.Errorf("channel not found for handle: %s", handle)
+		c.channelIDs.put(cacheKey, "")
+		return "", fmt.Errorf("%w for handle: %s", ErrChannelNotFound, handle)
 	}
 	
+	c.channelIDs.put(cacheKey, channelResp.Items[0].ID)
 	return channelResp.Items[0].ID, nil
 }
 
 // resolveUsernameToChannelID resolves a YouTube username (/c/ChannelName) to a channel ID
-func (c *Client) resolveUsernameToChannelID(username string) (string, error) {
+func (c *Client) resolveUsernameToChannelID(ctx context.Context, username string) (string, error) {
+	cacheKey := channelCacheKey("username", username)
+	if channelID, ok := c.channelIDs.get(cacheKey); ok {
+		if channelID == "" {
+			return "", fmt.Errorf("%w for username: %s", ErrChannelNotFound, username)
+		}
+		return channelID, nil
+	}
+
 	reqURL := fmt.Sprintf("%s/channels", c.baseURL)
 	params := url.Values{}
 	params.Set("key", c.apiKey)
@@ -287,7 +377,7 @@ func (c *Client) resolveUsernameToChannelID(username string) (string, error) {
 	
 	reqURL += "?" + params.Encode()
 	
-	resp, err := c.httpClient.Get(reqURL)
+	resp, err := c.get(ctx, reqURL, CostChannels)
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve username: %w", err)
 	}
@@ -313,9 +403,11 @@ func (c *Client) resolveUsernameToChannelID(username string) (string, error) {
 	}
 	
 	if len(channelResp.Items) == 0 {
-		return "", fmt.Errorf("channel not found for username: %s", username)
+		c.channelIDs.put(cacheKey, "")
+		return "", fmt.Errorf("%w for username: %s", ErrChannelNotFound, username)
 	}
 	
+	c.channelIDs.put(cacheKey, channelResp.Items[0].ID)
 	return channelResp.Items[0].ID, nil
 }
 
@@ -0,0 +1,120 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client whose API requests handler serves
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewClient("test-key")
+	client.baseURL = server.URL
+	return client
+}
+
+// searchRequest is what one search request asked the fake API for
+type searchRequest struct {
+	pageToken, maxResults, publishedAfter string
+}
+
+// fakeSearch serves three pages of a channel's videos, newest first, two videos a page: v1 and
+// v2 published on 2024-05-06 and 05, then v3 and v4, then v5 and v6 on 2024-05-01
+type fakeSearch struct {
+	mu       sync.Mutex
+	requests []searchRequest
+}
+
+func (f *fakeSearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/search" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	f.mu.Lock()
+	f.requests = append(f.requests, searchRequest{query.Get("pageToken"), query.Get("maxResults"), query.Get("publishedAfter")})
+	f.mu.Unlock()
+
+	page, next := 0, "page-2"
+	switch query.Get("pageToken") {
+	case "page-2":
+		page, next = 1, "page-3"
+	case "page-3":
+		page, next = 2, ""
+	}
+	item := func(n int) string {
+		return fmt.Sprintf(`{"id":{"videoId":"v%d"},"snippet":{"publishedAt":"2024-05-%02dT12:00:00Z","channelId":"UC1","title":"Video %d"}}`, n, 7-n, n)
+	}
+	fmt.Fprintf(w, `{"items":[%s,%s],"nextPageToken":%q}`, item(2*page+1), item(2*page+2), next)
+}
+
+func videoIDs(videos []Video) []string {
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	return ids
+}
+
+func TestGetChannelVideosFollowsPages(t *testing.T) {
+	fake := &fakeSearch{}
+	client := newTestClient(t, fake)
+
+	videos, err := client.GetChannelVideos(context.Background(), "UC1", 100, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(videoIDs(videos)); got != "[v1 v2 v3 v4 v5 v6]" {
+		t.Fatalf("videos = %s, want all six across the three pages", got)
+	}
+	if len(fake.requests) != 3 || fake.requests[1].pageToken != "page-2" || fake.requests[2].pageToken != "page-3" {
+		t.Fatalf("requests = %+v, want the three pages in order", fake.requests)
+	}
+}
+
+func TestGetChannelVideosStopsAtMaxResults(t *testing.T) {
+	fake := &fakeSearch{}
+	client := newTestClient(t, fake)
+
+	videos, err := client.GetChannelVideos(context.Background(), "UC1", 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(videoIDs(videos)); got != "[v1 v2 v3]" {
+		t.Fatalf("videos = %s, want the newest three", got)
+	}
+	// The second page asks only for the video still wanted, and the third is never fetched
+	if len(fake.requests) != 2 || fake.requests[0].maxResults != "3" || fake.requests[1].maxResults != "1" {
+		t.Fatalf("requests = %+v, want two pages asking for 3 then 1", fake.requests)
+	}
+}
+
+func TestGetChannelVideosStopsAtPublishedAfter(t *testing.T) {
+	fake := &fakeSearch{}
+	client := newTestClient(t, fake)
+
+	// v4 was published on 2024-05-03, so paging stops there
+	after := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	videos, err := client.GetChannelVideos(context.Background(), "UC1", 100, &after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(videoIDs(videos)); got != "[v1 v2 v3]" {
+		t.Fatalf("videos = %s, want those published after %s", got, after)
+	}
+	if len(fake.requests) != 2 {
+		t.Fatalf("fetched %d pages, want to stop on the page reaching older videos", len(fake.requests))
+	}
+	for _, request := range fake.requests {
+		if request.publishedAfter != "2024-05-03T12:00:00Z" {
+			t.Errorf("page %q publishedAfter = %q, want it on every page", request.pageToken, request.publishedAfter)
+		}
+	}
+}
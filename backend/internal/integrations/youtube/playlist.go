@@ -0,0 +1,238 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxPlaylistPages bounds how many pages of a playlist GetPlaylistVideos reads, so a long
+// playlist whose videos are mostly older than publishedAfter cannot use up the API quota
+const MaxPlaylistPages = 20
+
+// PlaylistItemsResponse represents the response from YouTube Data API playlistItems endpoint
+type PlaylistItemsResponse struct {
+	Items         []PlaylistItem `json:"items"`
+	NextPageToken string         `json:"nextPageToken"`
+}
+
+// PlaylistItem represents a single video in a playlist
+type PlaylistItem struct {
+	Snippet        PlaylistItemSnippet        `json:"snippet"`
+	ContentDetails PlaylistItemContentDetails `json:"contentDetails"`
+	Status         PlaylistItemStatus         `json:"status"`
+}
+
+// PlaylistItemSnippet represents the metadata of a playlist item. PublishedAt is when the video
+// was added to the playlist; the channel fields name the playlist's owner and the videoOwner
+// fields the video's.
+type PlaylistItemSnippet struct {
+	PublishedAt            string `json:"publishedAt"`
+	ChannelID              string `json:"channelId"`
+	ChannelTitle           string `json:"channelTitle"`
+	Title                  string `json:"title"`
+	Description            string `json:"description"`
+	VideoOwnerChannelID    string `json:"videoOwnerChannelId"`
+	VideoOwnerChannelTitle string `json:"videoOwnerChannelTitle"`
+}
+
+// PlaylistItemContentDetails identifies the video of a playlist item and when it was
+// published. VideoPublishedAt is empty for a deleted or private video.
+type PlaylistItemContentDetails struct {
+	VideoID          string `json:"videoId"`
+	VideoPublishedAt string `json:"videoPublishedAt"`
+}
+
+// PlaylistItemStatus represents the privacy status of a playlist item
+type PlaylistItemStatus struct {
+	PrivacyStatus string `json:"privacyStatus"` // public, unlisted, private or privacyStatusUnspecified
+}
+
+// GetPlaylistVideos fetches videos from a YouTube playlist, in playlist order, a page at a time
+// playlistID: The YouTube playlist ID (the list= parameter of its URL)
+// maxResults: Maximum number of videos to return
+// publishedAfter: Only return videos published after this time (optional). Playlists are not
+// ordered by date, so every page up to MaxPlaylistPages is read to find them.
+// Private and deleted videos are skipped.
+func (c *Client) GetPlaylistVideos(ctx context.Context, playlistID string, maxResults int, publishedAfter *time.Time) ([]Video, error) {
+	if c == nil {
+		return nil, fmt.Errorf("YouTube client not initialized (API key not set)")
+	}
+	if playlistID == "" {
+		return nil, fmt.Errorf("playlist ID cannot be empty")
+	}
+	if maxResults < 1 {
+		maxResults = 10
+	}
+	return c.playlistVideos(ctx, playlistID, maxResults, publishedAfter, false)
+}
+
+// playlistVideos fetches up to maxResults videos of a playlist published after publishedAfter,
+// if set. If the playlist is newestFirst, as a channel's uploads are, paging stops at the first
+// video that predates publishedAfter.
+func (c *Client) playlistVideos(ctx context.Context, playlistID string, maxResults int, publishedAfter *time.Time, newestFirst bool) ([]Video, error) {
+	videos := make([]Video, 0, min(maxResults, MaxResultsMax))
+	pageToken := ""
+	for page := 0; page < MaxPlaylistPages && len(videos) < maxResults; page++ {
+		resp, err := c.getPlaylistItems(ctx, playlistID, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			video, ok := playlistVideo(item)
+			if !ok {
+				continue
+			}
+			if publishedAfter != nil && !video.PublishedAt.After(*publishedAfter) {
+				if newestFirst {
+					return videos, nil
+				}
+				continue
+			}
+			videos = append(videos, video)
+			if len(videos) == maxResults {
+				break
+			}
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		pageToken = resp.NextPageToken
+	}
+
+	return videos, nil
+}
+
+// getPlaylistItems fetches one page of a playlist's items
+func (c *Client) getPlaylistItems(ctx context.Context, playlistID, pageToken string) (*PlaylistItemsResponse, error) {
+	reqURL := fmt.Sprintf("%s/playlistItems", c.baseURL)
+	params := url.Values{}
+	params.Set("key", c.apiKey)
+	params.Set("playlistId", playlistID)
+	params.Set("part", "snippet,contentDetails,status")
+	params.Set("maxResults", fmt.Sprintf("%d", MaxResultsMax))
+	if pageToken != "" {
+		params.Set("pageToken", pageToken)
+	}
+
+	reqURL += "?" + params.Encode()
+
+	resp, err := c.get(ctx, reqURL, CostPlaylistItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    fmt.Sprintf("YouTube playlist %s not found or not public", playlistID),
+			}
+		}
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
+	}
+
+	var itemsResp PlaylistItemsResponse
+	if err := json.Unmarshal(bodyBytes, &itemsResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &itemsResp, nil
+}
+
+// playlistVideo converts a playlist item to a Video, returning false for a private or deleted
+// video, which the API lists without a publish date, or an item with an invalid timestamp
+func playlistVideo(item PlaylistItem) (Video, bool) {
+	if item.ContentDetails.VideoID == "" || item.ContentDetails.VideoPublishedAt == "" {
+		return Video{}, false
+	}
+	switch item.Status.PrivacyStatus {
+	case "private", "privacyStatusUnspecified":
+		return Video{}, false
+	}
+	publishedAt, err := time.Parse(time.RFC3339, item.ContentDetails.VideoPublishedAt)
+	if err != nil {
+		return Video{}, false
+	}
+
+	// The video's own channel, which for a playlist curated by someone else is not the
+	// playlist's
+	channelID, channelTitle := item.Snippet.VideoOwnerChannelID, item.Snippet.VideoOwnerChannelTitle
+	if channelID == "" {
+		channelID, channelTitle = item.Snippet.ChannelID, item.Snippet.ChannelTitle
+	}
+	return Video{
+		ID:           item.ContentDetails.VideoID,
+		Title:        item.Snippet.Title,
+		Description:  item.Snippet.Description,
+		PublishedAt:  publishedAt,
+		ChannelID:    channelID,
+		ChannelTitle: channelTitle,
+	}, true
+}
+
+// ExtractPlaylistID extracts the playlist ID from the list= query parameter of a YouTube URL
+// Supports:
+// - https://www.youtube.com/playlist?list=PL...
+// - https://www.youtube.com/watch?v=...&list=PL... (a video played within a playlist)
+// - https://youtu.be/...?list=PL...
+// Watch Later, Liked videos and auto-generated mixes are personal to the viewer and cannot be
+// listed with an API key, so they are refused.
+func ExtractPlaylistID(playlistURL string) (string, error) {
+	raw := strings.TrimSpace(playlistURL)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid playlist URL %s: %w", playlistURL, err)
+	}
+
+	playlistID := parsed.Query().Get("list")
+	if playlistID == "" {
+		return "", fmt.Errorf("unable to extract playlist ID from URL: %s (no list parameter)", playlistURL)
+	}
+	for _, r := range playlistID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("invalid playlist ID %q in URL: %s", playlistID, playlistURL)
+		}
+	}
+	if playlistID == "WL" || playlistID == "LL" || strings.HasPrefix(playlistID, "RD") {
+		return "", fmt.Errorf("playlist %s is private to its viewer and cannot be fetched", playlistID)
+	}
+	return playlistID, nil
+}
+
+// newAPIError builds the error of a failed YouTube API request from its status and body, with
+// user-friendly messages for common cases
+func newAPIError(statusCode int, body []byte) *APIError {
+	errorMsg := string(body)
+	// Limit error message size
+	if len(errorMsg) > MaxErrorMessageSize {
+		errorMsg = errorMsg[:MaxErrorMessageSize] + "..."
+	}
+
+	switch statusCode {
+	case http.StatusForbidden:
+		errorMsg = "YouTube API quota exceeded or API key invalid"
+	case http.StatusBadRequest:
+		errorMsg = "Invalid YouTube API request: " + errorMsg
+	case http.StatusUnauthorized:
+		errorMsg = "YouTube API key is invalid or missing"
+	}
+
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    errorMsg,
+	}
+}
@@ -0,0 +1,179 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	_ "time/tzdata" // the quota day is Pacific time, whatever zone database the host has
+)
+
+// Estimated quota cost of each request the client makes, in API units
+const (
+	CostSearch        = 100
+	CostChannels      = 1
+	CostPlaylistItems = 1
+	CostVideos        = 1
+)
+
+// DefaultDailyQuotaBudget is the daily quota Google grants a project unless it asks for more
+const DefaultDailyQuotaBudget = 10000
+
+// quotaDayFormat is the format of the quota day the store keeps usage under
+const quotaDayFormat = "2006-01-02"
+
+// quotaLocation is where Google's quota day runs: it resets at midnight Pacific time
+var quotaLocation = mustLoadLocation("America/Los_Angeles")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load time zone %s: %v", name, err))
+	}
+	return loc
+}
+
+// QuotaStore persists the units used each quota day, so restarts do not forget them.
+// store.Store implements it.
+type QuotaStore interface {
+	GetYouTubeQuotaUsage(ctx context.Context, day string) (int, error)
+	AddYouTubeQuotaUsage(ctx context.Context, day string, units int) (int, error)
+}
+
+// QuotaExceededError is returned instead of making a request that would take the day's usage
+// past the budget
+type QuotaExceededError struct {
+	Used     int
+	Budget   int
+	Cost     int
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("YouTube daily quota budget reached: %d of %d units used, request needs %d; resets at %s",
+		e.Used, e.Budget, e.Cost, e.ResetsAt.Format(time.RFC3339))
+}
+
+// IsQuotaExceeded reports whether err is or wraps a QuotaExceededError
+func IsQuotaExceeded(err error) bool {
+	var quotaErr *QuotaExceededError
+	return errors.As(err, &quotaErr)
+}
+
+// QuotaUsage is the estimated usage of the current quota day
+type QuotaUsage struct {
+	Day       string    `json:"day"`
+	Used      int       `json:"used"`
+	Budget    int       `json:"budget"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Quota tracks the estimated units the client uses each day against a budget. Its methods are
+// safe for concurrent use.
+type Quota struct {
+	store  QuotaStore
+	budget int
+	now    func() time.Time
+
+	mu sync.Mutex
+	// day and used cache the usage of the current quota day; used is -1 until it is loaded
+	day  string
+	used int
+}
+
+// NewQuota creates a quota tracker that refuses requests past budget units a day, keeping its
+// usage in store
+func NewQuota(store QuotaStore, budget int) *Quota {
+	return &Quota{
+		store:  store,
+		budget: budget,
+		now:    time.Now,
+		used:   -1,
+	}
+}
+
+// QuotaBudgetFromEnv returns the budget set with YOUTUBE_DAILY_QUOTA_BUDGET, or
+// DefaultDailyQuotaBudget if it is not set
+func QuotaBudgetFromEnv() (int, error) {
+	value := os.Getenv("YOUTUBE_DAILY_QUOTA_BUDGET")
+	if value == "" {
+		return DefaultDailyQuotaBudget, nil
+	}
+	budget, err := strconv.Atoi(value)
+	if err != nil || budget < 1 {
+		return 0, fmt.Errorf("YOUTUBE_DAILY_QUOTA_BUDGET must be a positive number of units, got %q", value)
+	}
+	return budget, nil
+}
+
+// Budget returns the daily budget in units
+func (q *Quota) Budget() int {
+	return q.budget
+}
+
+// quotaDay returns the quota day t falls in and when it ends
+func quotaDay(t time.Time) (string, time.Time) {
+	local := t.In(quotaLocation)
+	y, m, d := local.Date()
+	return local.Format(quotaDayFormat), time.Date(y, m, d+1, 0, 0, 0, 0, quotaLocation)
+}
+
+// load makes the cache hold the usage of day, reading it from the store when the day
+// changes. It must be called with q.mu held.
+func (q *Quota) load(ctx context.Context, day string) {
+	if q.day == day && q.used >= 0 {
+		return
+	}
+	used, err := q.store.GetYouTubeQuotaUsage(ctx, day)
+	if err != nil {
+		// Count from zero rather than stop polling; the store is retried next day
+		log.Printf("Failed to load YouTube quota usage of %s, counting from zero: %v", day, err)
+		used = 0
+	}
+	q.day, q.used = day, used
+}
+
+// Reserve records cost units against the current day before a request is made, or returns a
+// QuotaExceededError without recording them if the day's usage would pass the budget
+func (q *Quota) Reserve(ctx context.Context, cost int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	day, resetsAt := quotaDay(q.now())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load(ctx, day)
+	if q.used+cost > q.budget {
+		return &QuotaExceededError{Used: q.used, Budget: q.budget, Cost: cost, ResetsAt: resetsAt}
+	}
+
+	used, err := q.store.AddYouTubeQuotaUsage(context.WithoutCancel(ctx), day, cost)
+	if err != nil {
+		log.Printf("Failed to record YouTube quota usage of %s: %v", day, err)
+		used = q.used + cost
+	}
+	q.used = used
+	return nil
+}
+
+// Usage returns the estimated usage of the current quota day
+func (q *Quota) Usage(ctx context.Context) QuotaUsage {
+	day, resetsAt := quotaDay(q.now())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.load(ctx, day)
+	return QuotaUsage{
+		Day:       day,
+		Used:      q.used,
+		Budget:    q.budget,
+		Remaining: max(q.budget-q.used, 0),
+		ResetsAt:  resetsAt,
+	}
+}
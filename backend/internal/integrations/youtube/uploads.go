@@ -0,0 +1,112 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GetChannelUploads fetches recent videos from a YouTube channel through its uploads playlist,
+// newest first. Listing a playlist costs 1 quota unit per page where a search costs 100, so
+// this is the way to poll channels; it takes the same arguments as GetChannelVideos. The
+// uploads playlist is resolved once per channel and cached. If it cannot be resolved, or the
+// channel has none, the videos are searched for with GetChannelVideos instead, unless the
+// quota budget is what refused the request.
+func (c *Client) GetChannelUploads(ctx context.Context, channelID string, maxResults int, publishedAfter *time.Time) ([]Video, error) {
+	if c == nil {
+		return nil, fmt.Errorf("YouTube client not initialized (API key not set)")
+	}
+
+	if maxResults < 1 {
+		maxResults = 10
+	}
+
+	playlistID, err := c.uploadsPlaylistID(ctx, channelID)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	// Searching costs a hundred times more, so a spent budget is not worked around
+	if IsQuotaExceeded(err) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("Could not resolve the uploads playlist of channel %s, searching instead: %v", channelID, err)
+		return c.GetChannelVideos(ctx, channelID, maxResults, publishedAfter)
+	}
+
+	videos, err := c.playlistVideos(ctx, playlistID, maxResults, publishedAfter, true)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		log.Printf("Uploads playlist %s of channel %s not found, searching instead", playlistID, channelID)
+		c.uploadsMu.Lock()
+		delete(c.uploadsPlaylists, channelID)
+		c.uploadsMu.Unlock()
+		return c.GetChannelVideos(ctx, channelID, maxResults, publishedAfter)
+	}
+	return videos, err
+}
+
+// uploadsPlaylistID returns the ID of the playlist holding every upload of a channel
+func (c *Client) uploadsPlaylistID(ctx context.Context, channelID string) (string, error) {
+	c.uploadsMu.Lock()
+	playlistID, ok := c.uploadsPlaylists[channelID]
+	c.uploadsMu.Unlock()
+	if ok {
+		return playlistID, nil
+	}
+
+	reqURL := fmt.Sprintf("%s/channels", c.baseURL)
+	params := url.Values{}
+	params.Set("key", c.apiKey)
+	params.Set("part", "contentDetails")
+	params.Set("id", channelID)
+
+	reqURL += "?" + params.Encode()
+
+	resp, err := c.get(ctx, reqURL, CostChannels)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError(resp.StatusCode, bodyBytes)
+	}
+
+	var channelResp struct {
+		Items []struct {
+			ContentDetails struct {
+				RelatedPlaylists struct {
+					Uploads string `json:"uploads"`
+				} `json:"relatedPlaylists"`
+			} `json:"contentDetails"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(bodyBytes, &channelResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(channelResp.Items) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrChannelNotFound, channelID)
+	}
+	playlistID = channelResp.Items[0].ContentDetails.RelatedPlaylists.Uploads
+	if playlistID == "" {
+		return "", fmt.Errorf("channel %s has no uploads playlist", channelID)
+	}
+
+	c.uploadsMu.Lock()
+	c.uploadsPlaylists[channelID] = playlistID
+	c.uploadsMu.Unlock()
+	return playlistID, nil
+}
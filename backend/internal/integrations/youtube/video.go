@@ -0,0 +1,210 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VideoDetails represents the metadata, duration and statistics of a video
+type VideoDetails struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	PublishedAt  time.Time `json:"publishedAt"`
+	ChannelID    string    `json:"channelId"`
+	ChannelTitle string    `json:"channelTitle"`
+	// DurationSeconds is 0 for a live stream or premiere that has not ended
+	DurationSeconds int `json:"durationSeconds"`
+	// LiveBroadcastContent is none for a regular video, live or upcoming otherwise
+	LiveBroadcastContent string `json:"liveBroadcastContent"`
+	ViewCount            int64  `json:"viewCount"`
+	// LikeCount and CommentCount are 0 when the owner hides them
+	LikeCount    int64 `json:"likeCount"`
+	CommentCount int64 `json:"commentCount"`
+}
+
+// VideosResponse represents the response from YouTube Data API videos endpoint
+type VideosResponse struct {
+	Items []VideoItem `json:"items"`
+}
+
+// VideoItem represents a single video in the videos response
+type VideoItem struct {
+	ID             string              `json:"id"`
+	Snippet        VideoItemSnippet    `json:"snippet"`
+	ContentDetails VideoContentDetails `json:"contentDetails"`
+	Statistics     VideoStatistics     `json:"statistics"`
+}
+
+// VideoItemSnippet represents video metadata in the videos response
+type VideoItemSnippet struct {
+	VideoSnippet
+	LiveBroadcastContent string `json:"liveBroadcastContent"`
+}
+
+// VideoContentDetails represents the content details of a video
+type VideoContentDetails struct {
+	Duration string `json:"duration"` // ISO 8601, such as PT1H2M10S
+}
+
+// VideoStatistics represents the statistics of a video. The API returns the counts as strings.
+type VideoStatistics struct {
+	ViewCount    string `json:"viewCount"`
+	LikeCount    string `json:"likeCount"`
+	CommentCount string `json:"commentCount"`
+}
+
+// GetVideoDetails fetches the details of videos by ID, up to MaxResultsMax per request
+// Videos that are private, deleted or otherwise unavailable are left out of the result, as are
+// any with an invalid publish time or duration. The result is in the order the API returns
+// videos rather than the order of videoIDs.
+func (c *Client) GetVideoDetails(ctx context.Context, videoIDs []string) ([]VideoDetails, error) {
+	if c == nil {
+		return nil, fmt.Errorf("YouTube client not initialized (API key not set)")
+	}
+
+	details := make([]VideoDetails, 0, len(videoIDs))
+	for start := 0; start < len(videoIDs); start += MaxResultsMax {
+		batch := videoIDs[start:min(start+MaxResultsMax, len(videoIDs))]
+		items, err := c.getVideos(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			video, err := videoDetails(item)
+			if err != nil {
+				// Skip videos with invalid timestamps or durations
+				continue
+			}
+			details = append(details, video)
+		}
+	}
+	return details, nil
+}
+
+// getVideos fetches one batch of videos
+func (c *Client) getVideos(ctx context.Context, videoIDs []string) ([]VideoItem, error) {
+	reqURL := fmt.Sprintf("%s/videos", c.baseURL)
+	params := url.Values{}
+	params.Set("key", c.apiKey)
+	params.Set("id", strings.Join(videoIDs, ","))
+	params.Set("part", "contentDetails,snippet,statistics")
+	params.Set("maxResults", fmt.Sprintf("%d", MaxResultsMax))
+
+	reqURL += "?" + params.Encode()
+
+	resp, err := c.get(ctx, reqURL, CostVideos)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
+	}
+
+	var videosResp VideosResponse
+	if err := json.Unmarshal(bodyBytes, &videosResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return videosResp.Items, nil
+}
+
+// videoDetails converts a video of the videos response to VideoDetails
+func videoDetails(item VideoItem) (VideoDetails, error) {
+	publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+	if err != nil {
+		return VideoDetails{}, fmt.Errorf("invalid publish time %q of video %s", item.Snippet.PublishedAt, item.ID)
+	}
+	duration, err := ParseDuration(item.ContentDetails.Duration)
+	if err != nil {
+		return VideoDetails{}, fmt.Errorf("invalid duration of video %s: %w", item.ID, err)
+	}
+	return VideoDetails{
+		ID:                   item.ID,
+		Title:                item.Snippet.Title,
+		Description:          item.Snippet.Description,
+		PublishedAt:          publishedAt,
+		ChannelID:            item.Snippet.ChannelID,
+		ChannelTitle:         item.Snippet.ChannelTitle,
+		DurationSeconds:      duration,
+		LiveBroadcastContent: item.Snippet.LiveBroadcastContent,
+		ViewCount:            statisticCount(item.Statistics.ViewCount),
+		LikeCount:            statisticCount(item.Statistics.LikeCount),
+		CommentCount:         statisticCount(item.Statistics.CommentCount),
+	}, nil
+}
+
+// statisticCount parses a count of the statistics part, 0 if it is hidden
+func statisticCount(count string) int64 {
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ParseDuration parses an ISO 8601 duration as the API gives video lengths, such as PT1H2M10S
+// or P1DT2H for a long stream, into seconds. An empty duration, as of a live stream that has
+// not ended, is 0.
+func ParseDuration(duration string) (int, error) {
+	if duration == "" {
+		return 0, nil
+	}
+	rest, ok := strings.CutPrefix(duration, "P")
+	if !ok {
+		return 0, fmt.Errorf("duration %q does not start with P", duration)
+	}
+
+	seconds := 0
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime {
+				return 0, fmt.Errorf("duration %q has more than one T", duration)
+			}
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", duration)
+		}
+		n, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", duration, err)
+		}
+
+		var unit int
+		switch designator := rest[end]; {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * 3600
+		case !inTime && designator == 'D':
+			unit = 24 * 3600
+		case inTime && designator == 'H':
+			unit = 3600
+		case inTime && designator == 'M':
+			unit = 60
+		case inTime && designator == 'S':
+			unit = 1
+		default:
+			return 0, fmt.Errorf("invalid duration %q: unexpected %q", duration, designator)
+		}
+		seconds += n * unit
+		rest = rest[end+1:]
+	}
+	return seconds, nil
+}
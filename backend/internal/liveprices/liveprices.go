@@ -0,0 +1,266 @@
+// Package liveprices keeps the prices of Coinbase holdings current between syncs, from the
+// Coinbase websocket ticker. Syncs remain the source of truth for quantities; ticks only move
+// the price and value of the holdings already stored.
+package liveprices
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+// writeInterval is how often ticked prices are written, so a product is repriced at most once
+// per interval however often it trades
+const writeInterval = 5 * time.Second
+
+// Reconnect delays after the ticker drops, doubled after each failed attempt. A connection that
+// lasted maxReconnectDelay resets the delay.
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// TickerStreamer streams price ticks for a set of products until ctx is done or the stream
+// fails; *coinbase.Client implements it
+type TickerStreamer interface {
+	StreamTicker(ctx context.Context, productIDs []string, onTick func(coinbase.Tick)) error
+}
+
+var _ TickerStreamer = (*coinbase.Client)(nil)
+
+// Updater reprices the stored Coinbase holdings of the user owning the Coinbase credentials as
+// their products trade, and recalculates that user's net worth
+type Updater struct {
+	store   store.Store
+	ticker  TickerStreamer
+	changed chan struct{} // Signals that the held products may have changed
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]float64 // Latest ticked price by product ID, not yet written
+}
+
+// NewUpdater creates an updater reading ticks from ticker and writing to the default user's
+// holdings in s
+func NewUpdater(s store.Store, ticker TickerStreamer) *Updater {
+	return &Updater{
+		store:   s.ForUser(models.DefaultUserID),
+		ticker:  ticker,
+		changed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		pending: make(map[string]float64),
+	}
+}
+
+// Start streams the ticker for the held products in the background until Stop is called
+func (u *Updater) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	u.cancel = cancel
+	log.Printf("Starting live Coinbase prices (writing at most every %s)", writeInterval)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		u.stream(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(writeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				u.flush(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(u.done)
+	}()
+}
+
+// Stop closes the ticker connection and waits for the updater to finish
+func (u *Updater) Stop() {
+	if u.cancel == nil {
+		return
+	}
+	u.cancel()
+	<-u.done
+	log.Println("Live Coinbase prices stopped")
+}
+
+// HoldingsChanged resubscribes the ticker to the products held, after a sync may have changed
+// them. It does not block.
+func (u *Updater) HoldingsChanged() {
+	select {
+	case u.changed <- struct{}{}:
+	default:
+	}
+}
+
+// stream keeps a ticker subscription for the held products open until ctx is done, reconnecting
+// with backoff when it drops and resubscribing when the holdings change
+func (u *Updater) stream(ctx context.Context) {
+	delay := minReconnectDelay
+	for ctx.Err() == nil {
+		products, err := u.heldProducts(ctx)
+		if err != nil {
+			log.Printf("Warning: Failed to load Coinbase holdings for live prices: %v", err)
+		} else if len(products) == 0 {
+			// Nothing to stream until a sync stores some holdings
+			select {
+			case <-u.changed:
+			case <-ctx.Done():
+			}
+			continue
+		} else {
+			resubscribe, lasted, err := u.subscribe(ctx, products)
+			if ctx.Err() != nil {
+				return
+			}
+			if resubscribe {
+				delay = minReconnectDelay
+				continue
+			}
+			if lasted >= maxReconnectDelay {
+				delay = minReconnectDelay
+			}
+			log.Printf("Warning: Coinbase ticker dropped, reconnecting in %s: %v", delay, err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-u.changed:
+		case <-ctx.Done():
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// subscribe streams ticks for products until the stream fails, ctx is done or the holdings
+// change, which is reported by resubscribe. lasted is how long the stream ran.
+func (u *Updater) subscribe(ctx context.Context, products []string) (resubscribe bool, lasted time.Duration, err error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Reports whether the holdings changed, once the stream has ended
+	watched := make(chan bool, 1)
+	go func() {
+		select {
+		case <-u.changed:
+			cancel()
+			watched <- true
+		case <-streamCtx.Done():
+			watched <- false
+		}
+	}()
+
+	log.Printf("Info: Subscribing to the Coinbase ticker for %d products", len(products))
+	started := time.Now()
+	err = u.ticker.StreamTicker(streamCtx, products, u.record)
+	lasted = time.Since(started)
+	cancel()
+	if <-watched {
+		return true, lasted, nil
+	}
+	return false, lasted, err
+}
+
+// record keeps the latest price of a tick until the next write
+func (u *Updater) record(tick coinbase.Tick) {
+	u.mu.Lock()
+	u.pending[tick.ProductID] = tick.Price
+	u.mu.Unlock()
+}
+
+// heldProducts returns the products pricing the active Coinbase crypto holdings
+func (u *Updater) heldProducts(ctx context.Context) ([]string, error) {
+	investments, err := u.store.GetInvestmentsByPlatform(ctx, models.PlatformCoinbase, store.InvestmentFilter{})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	products := make([]string, 0)
+	for _, investment := range investments {
+		if investment.AssetType != models.AssetTypeCrypto {
+			continue
+		}
+		if id := productID(investment.Symbol); !seen[id] {
+			seen[id] = true
+			products = append(products, id)
+		}
+	}
+	return products, nil
+}
+
+// productID returns the product pricing symbol in US dollars, the currency of Coinbase holdings
+func productID(symbol string) string {
+	return strings.ToUpper(symbol) + "-USD"
+}
+
+// flush writes the prices ticked since the last write to the holdings they price and
+// recalculates net worth, in one transaction. Only prices are written, valued at the quantity
+// stored when the write lands, so a sync committing meanwhile keeps its quantities and
+// deactivations.
+func (u *Updater) flush(ctx context.Context) {
+	u.mu.Lock()
+	prices := u.pending
+	u.pending = make(map[string]float64)
+	u.mu.Unlock()
+	if len(prices) == 0 {
+		return
+	}
+
+	repriced := 0
+	err := u.store.WithTransaction(ctx, func(tx store.Store) error {
+		repriced = 0
+		investments, err := tx.GetInvestmentsByPlatform(ctx, models.PlatformCoinbase, store.InvestmentFilter{})
+		if err != nil {
+			return err
+		}
+		at := models.Now()
+		for _, investment := range investments {
+			if investment.AssetType != models.AssetTypeCrypto {
+				continue
+			}
+			price, ok := prices[productID(investment.Symbol)]
+			if !ok || (price == investment.Price && !investment.StalePrice) {
+				continue
+			}
+			err := tx.UpdateInvestmentPrice(ctx, investment.ID, price, at)
+			if errors.Is(err, store.ErrNotFound) {
+				// Deactivated or deleted by a sync since it was listed
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			repriced++
+		}
+		if repriced == 0 {
+			return nil
+		}
+		_, err = tx.RecalculateNetWorth(ctx)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Warning: Failed to write live Coinbase prices: %v", err)
+		}
+		return
+	}
+	if repriced > 0 {
+		log.Printf("Info: Repriced %d Coinbase holdings from the ticker", repriced)
+	}
+}
@@ -0,0 +1,122 @@
+package liveprices
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+func cryptoHolding(id, symbol string, quantity, price float64) *models.Investment {
+	return &models.Investment{
+		ID:        id,
+		AccountID: "coinbase-account",
+		Platform:  models.PlatformCoinbase,
+		Symbol:    symbol,
+		AssetType: models.AssetTypeCrypto,
+		Quantity:  quantity,
+		Price:     price,
+		Value:     quantity * price,
+		Currency:  "USD",
+	}
+}
+
+func holdingsByID(t *testing.T, s store.Store) map[string]*models.Investment {
+	t.Helper()
+	investments, _, err := s.GetAllInvestments(context.Background(), store.ListOptions{}, store.SortOption{}, store.InvestmentFilter{IncludeInactive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]*models.Investment)
+	for _, investment := range investments {
+		byID[investment.ID] = investment
+	}
+	return byID
+}
+
+func TestFlushRepricesAtStoredQuantity(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+		cryptoHolding("btc", "BTC", 1, 60000),
+		cryptoHolding("eth", "ETH", 10, 3000),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	u := NewUpdater(s, nil)
+	u.record(coinbase.Tick{ProductID: "BTC-USD", Price: 70000})
+	u.record(coinbase.Tick{ProductID: "ETH-USD", Price: 3500})
+
+	// A sync lands after the ticks: more BTC, and the ETH holding is gone
+	if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{cryptoHolding("btc", "BTC", 2, 60000)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeactivateInvestments(ctx, []string{"eth"}, models.Now()); err != nil {
+		t.Fatal(err)
+	}
+	u.flush(ctx)
+
+	holdings := holdingsByID(t, s)
+	if btc := holdings["btc"]; btc.Quantity != 2 || btc.Price != 70000 || btc.Value != 140000 {
+		t.Fatalf("BTC = %v at %v worth %v, want 2 at 70000 worth 140000", btc.Quantity, btc.Price, btc.Value)
+	}
+	if eth := holdings["eth"]; eth.Active || eth.Price != 3000 {
+		t.Fatalf("ETH = active %v at %v, want left inactive at 3000", eth.Active, eth.Price)
+	}
+}
+
+// TestFlushConcurrentWithSync is meant for go test -race: ticks are flushed while syncs rewrite
+// the holdings, and every holding must end up at the last synced quantity
+func TestFlushConcurrentWithSync(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewStore()
+	const holdings = 5
+	initial := make([]*models.Investment, 0, holdings)
+	for i := 0; i < holdings; i++ {
+		initial = append(initial, cryptoHolding(fmt.Sprintf("h%d", i), fmt.Sprintf("C%d", i), 1, 100))
+	}
+	if _, err := s.CreateOrUpdateInvestments(ctx, initial); err != nil {
+		t.Fatal(err)
+	}
+	u := NewUpdater(s, nil)
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for round := 1; round <= rounds; round++ {
+			for i := 0; i < holdings; i++ {
+				u.record(coinbase.Tick{ProductID: fmt.Sprintf("C%d-USD", i), Price: float64(100 + round)})
+			}
+			u.flush(ctx)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for round := 1; round <= rounds; round++ {
+			synced := make([]*models.Investment, 0, holdings)
+			for i := 0; i < holdings; i++ {
+				synced = append(synced, cryptoHolding(fmt.Sprintf("h%d", i), fmt.Sprintf("C%d", i), float64(round), 100))
+			}
+			if _, err := s.CreateOrUpdateInvestments(ctx, synced); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	for id, investment := range holdingsByID(t, s) {
+		if investment.Quantity != rounds {
+			t.Errorf("%s quantity = %v, want the last synced %d", id, investment.Quantity, rounds)
+		}
+		if investment.Value != investment.Quantity*investment.Price {
+			t.Errorf("%s value = %v, want %v × %v", id, investment.Value, investment.Quantity, investment.Price)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package metrics keeps in-process operation counters and latency histograms, so they can be
+// logged or served without an external metrics library.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// Default is the registry shared by the whole process
+var Default = NewRegistry()
+
+// Registry records the count, errors and latency of named operations. It is safe for
+// concurrent use.
+type Registry struct {
+	mu         sync.Mutex
+	operations map[string]*operation
+}
+
+// operation holds the running totals for one operation name
+type operation struct {
+	count   int64
+	errors  int64
+	total   time.Duration
+	max     time.Duration
+	buckets []int64 // per bucket, not cumulative; the last entry counts observations above every bound
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{operations: make(map[string]*operation)}
+}
+
+// Observe records one run of the named operation
+func (r *Registry) Observe(name string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op, exists := r.operations[name]
+	if !exists {
+		op = &operation{buckets: make([]int64, len(latencyBuckets)+1)}
+		r.operations[name] = op
+	}
+	op.count++
+	if err != nil {
+		op.errors++
+	}
+	op.total += duration
+	op.max = max(op.max, duration)
+	op.buckets[sort.Search(len(latencyBuckets), func(i int) bool { return duration <= latencyBuckets[i] })]++
+}
+
+// OperationStats is a snapshot of one operation's totals. Durations are in seconds.
+type OperationStats struct {
+	Name         string  `json:"name"`
+	Count        int64   `json:"count"`
+	Errors       int64   `json:"errors"`
+	TotalSeconds float64 `json:"total_seconds"`
+	MaxSeconds   float64 `json:"max_seconds"`
+	// P50Seconds and P95Seconds are the median and 95th percentile latency, estimated from the
+	// histogram (see quantile)
+	P50Seconds float64  `json:"p50_seconds"`
+	P95Seconds float64  `json:"p95_seconds"`
+	Buckets    []Bucket `json:"buckets"`
+}
+
+// Bucket counts the observations that took at most UpperBound seconds. Counts are cumulative,
+// as in a Prometheus histogram, and observations above every bound are only in Count.
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
+
+// Snapshot returns the current totals of every operation, ordered by name
+func (r *Registry) Snapshot() []OperationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]OperationStats, 0, len(r.operations))
+	for name, op := range r.operations {
+		buckets := make([]Bucket, len(latencyBuckets))
+		var cumulative int64
+		for i, bound := range latencyBuckets {
+			cumulative += op.buckets[i]
+			buckets[i] = Bucket{UpperBound: bound.Seconds(), Count: cumulative}
+		}
+		stats = append(stats, OperationStats{
+			Name:         name,
+			Count:        op.count,
+			Errors:       op.errors,
+			TotalSeconds: op.total.Seconds(),
+			MaxSeconds:   op.max.Seconds(),
+			P50Seconds:   op.quantile(0.5).Seconds(),
+			P95Seconds:   op.quantile(0.95).Seconds(),
+			Buckets:      buckets,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// quantile estimates the latency below which fraction q of the observations fell, interpolating
+// linearly within the histogram bucket it lands in, as Prometheus does. The estimate never
+// exceeds the slowest observation; it is 0 without observations.
+func (op *operation) quantile(q float64) time.Duration {
+	if op.count == 0 {
+		return 0
+	}
+	rank := q * float64(op.count)
+	var cumulative int64
+	lower := time.Duration(0)
+	for i, bound := range latencyBuckets {
+		if op.buckets[i] > 0 && float64(cumulative+op.buckets[i]) >= rank {
+			fraction := (rank - float64(cumulative)) / float64(op.buckets[i])
+			return min(lower+time.Duration(fraction*float64(bound-lower)), op.max)
+		}
+		cumulative += op.buckets[i]
+		lower = bound
+	}
+	return op.max
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AccountTypeFiat is the Coinbase type of accounts holding a fiat currency
+const AccountTypeFiat = "ACCOUNT_TYPE_FIAT"
+
+// Account is a single-currency balance held on a platform, such as a Coinbase USD or BTC wallet
+type Account struct {
+	ID          string    `json:"id"`
+	Platform    Platform  `json:"platform"`
+	PortfolioID string    `json:"portfolio_id,omitempty"` // Portfolio the account belongs to, if known
+	Name        string    `json:"name"`
+	Currency    string    `json:"currency"`
+	Type        string    `json:"type,omitempty"` // e.g., "ACCOUNT_TYPE_FIAT", "ACCOUNT_TYPE_CRYPTO"
+	Available   float64   `json:"available_balance"`
+	Hold        float64   `json:"hold"` // Balance reserved for open orders
+	Active      bool      `json:"active"`
+	LastSynced  time.Time `json:"last_synced,omitzero"`
+}
+
+// Balance returns the account's total balance, including funds on hold
+func (a *Account) Balance() float64 {
+	return a.Available + a.Hold
+}
+
+// IsCash reports whether the account holds a fiat currency
+func (a *Account) IsCash() bool {
+	return a.Type == AccountTypeFiat
+}
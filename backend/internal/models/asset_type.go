@@ -0,0 +1,94 @@
+package models
+
+import "strings"
+
+// AssetType represents the canonical class of an investment
+type AssetType string
+
+const (
+	AssetTypeCrypto     AssetType = "crypto"
+	AssetTypeStablecoin AssetType = "stablecoin"
+	AssetTypeCash       AssetType = "cash"
+	AssetTypeStock      AssetType = "stock"
+	AssetTypeETF        AssetType = "etf"
+	AssetTypeBond       AssetType = "bond"
+	AssetTypeDerivative AssetType = "derivative"
+	AssetTypeRealEstate AssetType = "real_estate"
+	AssetTypeOther      AssetType = "other"
+)
+
+// ValidAssetTypes returns all canonical asset types
+func ValidAssetTypes() []AssetType {
+	return []AssetType{
+		AssetTypeCrypto,
+		AssetTypeStablecoin,
+		AssetTypeCash,
+		AssetTypeStock,
+		AssetTypeETF,
+		AssetTypeBond,
+		AssetTypeDerivative,
+		AssetTypeRealEstate,
+		AssetTypeOther,
+	}
+}
+
+// IsValid reports whether the asset type is one of the canonical values
+func (a AssetType) IsValid() bool {
+	for _, valid := range ValidAssetTypes() {
+		if a == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// assetTypeSynonyms maps common spellings onto canonical asset types.
+// Keys are lowercase with spaces and hyphens collapsed to underscores.
+var assetTypeSynonyms = map[string]AssetType{
+	"cryptocurrency":       AssetTypeCrypto,
+	"coin":                 AssetTypeCrypto,
+	"token":                AssetTypeCrypto,
+	"stable":               AssetTypeStablecoin,
+	"stable_coin":          AssetTypeStablecoin,
+	"fiat":                 AssetTypeCash,
+	"currency":             AssetTypeCash,
+	"money_market":         AssetTypeCash,
+	"equity":               AssetTypeStock,
+	"equities":             AssetTypeStock,
+	"stocks":               AssetTypeStock,
+	"share":                AssetTypeStock,
+	"shares":               AssetTypeStock,
+	"etfs":                 AssetTypeETF,
+	"fund":                 AssetTypeETF,
+	"exchange_traded_fund": AssetTypeETF,
+	"bonds":                AssetTypeBond,
+	"fixed_income":         AssetTypeBond,
+	"treasury":             AssetTypeBond,
+	"option":               AssetTypeDerivative,
+	"options":              AssetTypeDerivative,
+	"future":               AssetTypeDerivative,
+	"futures":              AssetTypeDerivative,
+	"derivatives":          AssetTypeDerivative,
+	"realestate":           AssetTypeRealEstate,
+	"reit":                 AssetTypeRealEstate,
+	"property":             AssetTypeRealEstate,
+}
+
+// NormalizeAssetType maps a free-form asset type onto its canonical value.
+// It is case-insensitive and understands common synonyms ("Equity", "ETFs", "real estate").
+// Unrecognized input returns AssetTypeOther and false; empty input is treated as unrecognized.
+func NormalizeAssetType(s string) (AssetType, bool) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	if key == "" {
+		return AssetTypeOther, false
+	}
+
+	if assetType := AssetType(key); assetType.IsValid() {
+		return assetType, true
+	}
+	if assetType, ok := assetTypeSynonyms[key]; ok {
+		return assetType, true
+	}
+	return AssetTypeOther, false
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/currency"
+)
+
+// CostBasisMethod selects which acquisitions a sale or withdrawal is taken from
+type CostBasisMethod string
+
+const (
+	// CostBasisFIFO takes disposals from the oldest acquisitions first
+	CostBasisFIFO CostBasisMethod = "fifo"
+	// CostBasisAverage pools acquisitions at their average cost
+	CostBasisAverage CostBasisMethod = "average"
+)
+
+// ParseCostBasisMethod validates a cost basis method name, case-insensitively
+func ParseCostBasisMethod(s string) (CostBasisMethod, error) {
+	switch method := CostBasisMethod(strings.ToLower(strings.TrimSpace(s))); method {
+	case CostBasisFIFO, CostBasisAverage:
+		return method, nil
+	default:
+		return "", fmt.Errorf("invalid cost basis method %q (must be %s or %s)", s, CostBasisFIFO, CostBasisAverage)
+	}
+}
+
+// basisCurrencies are the currencies transaction amounts must be in to count towards cost basis,
+// which is in USD. USD stablecoins are taken at parity.
+var basisCurrencies = map[string]bool{currency.USD: true, "USDC": true, "USDT": true}
+
+// quantityEpsilon absorbs floating point residue when lots are used up
+const quantityEpsilon = 1e-9
+
+// SymbolCostBasis is what the transactions in one symbol say about its holding: the quantity
+// still held, what it cost and the gain realized by selling the rest
+type SymbolCostBasis struct {
+	Symbol   string
+	Quantity float64
+	// CostBasis is the USD cost of Quantity, including fees; it is only meaningful if KnownBasis
+	CostBasis float64
+	// KnownBasis is false if some of the quantity held was acquired without a known cost
+	KnownBasis      bool
+	FirstAcquiredAt time.Time
+	// RealizedGain is the proceeds of sales less their cost. Sales of units without a known
+	// cost, or for a currency other than USD, are left out and clear RealizedComplete.
+	RealizedGain     float64
+	RealizedComplete bool
+}
+
+// UnitCost returns the average cost of each unit held, if it is known
+func (b *SymbolCostBasis) UnitCost() (float64, bool) {
+	if !b.KnownBasis || b.Quantity <= quantityEpsilon {
+		return 0, false
+	}
+	return b.CostBasis / b.Quantity, true
+}
+
+// costLot is a quantity acquired together, or with the average method every quantity held
+type costLot struct {
+	quantity float64
+	cost     float64 // USD; meaningless unless known
+	known    bool
+	acquired time.Time
+}
+
+// costTracker follows the lots of one symbol through its transactions
+type costTracker struct {
+	method CostBasisMethod
+	lots   []costLot
+	basis  SymbolCostBasis
+}
+
+func (t *costTracker) add(lot costLot) {
+	if t.method == CostBasisAverage && len(t.lots) > 0 {
+		pool := &t.lots[0]
+		pool.quantity += lot.quantity
+		pool.cost += lot.cost
+		pool.known = pool.known && lot.known
+		return
+	}
+	t.lots = append(t.lots, lot)
+}
+
+// remove takes quantity from the oldest lots, returning its cost and whether all of that cost
+// is known. Taking more than the lots hold, as when earlier transactions are missing, makes
+// the cost unknown.
+func (t *costTracker) remove(quantity float64) (float64, bool) {
+	cost, known := 0.0, true
+	for quantity > quantityEpsilon && len(t.lots) > 0 {
+		lot := &t.lots[0]
+		taken := math.Min(quantity, lot.quantity)
+		share := lot.cost * taken / lot.quantity
+		cost += share
+		known = known && lot.known
+		lot.quantity -= taken
+		lot.cost -= share
+		quantity -= taken
+		if lot.quantity <= quantityEpsilon {
+			t.lots = t.lots[1:]
+		}
+	}
+	if quantity > quantityEpsilon {
+		known = false
+	}
+	return cost, known
+}
+
+// acquisitionCost returns the USD cost of transaction's units: its amount, plus fees for a buy,
+// when that is in USD, or else their price at the time from priceAt
+func acquisitionCost(transaction *Transaction, quantity float64, priceAt func(symbol string, at time.Time) (float64, bool)) (float64, bool) {
+	if transaction.Amount > 0 && basisCurrencies[strings.ToUpper(transaction.Currency)] {
+		cost := transaction.Amount
+		if transaction.Type == TransactionTypeBuy {
+			cost += transaction.Fee
+		}
+		return cost, true
+	}
+	if transaction.Type != TransactionTypeBuy && priceAt != nil {
+		if price, ok := priceAt(transaction.Symbol, transaction.Timestamp); ok {
+			return price * quantity, true
+		}
+	}
+	return 0, false
+}
+
+// ComputeCostBasis walks transactions in time order and returns the cost basis of each symbol
+// they trade, keyed by upper-case symbol. Buys, deposits and incoming transfers (positive
+// quantity) add units, sales remove them and realize a gain, and withdrawals and outgoing
+// transfers (negative quantity) remove them without one. Units deposited or transferred in
+// without a USD amount are valued with priceAt, which may be nil; if it has no price their
+// basis is unknown. Transactions in fiat currencies themselves, such as cash deposits, and
+// dividends and fees, which move cash rather than units, are ignored.
+func ComputeCostBasis(transactions []*Transaction, method CostBasisMethod, priceAt func(symbol string, at time.Time) (float64, bool)) map[string]*SymbolCostBasis {
+	ordered := make([]*Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		symbol := strings.ToUpper(transaction.Symbol)
+		if symbol == "" || transaction.Quantity == 0 ||
+			transaction.Type == TransactionTypeDividend || transaction.Type == TransactionTypeFee {
+			continue
+		}
+		if info, ok := currency.Lookup(symbol); ok && !info.Crypto {
+			continue
+		}
+		ordered = append(ordered, transaction)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].Timestamp.Equal(ordered[j].Timestamp) {
+			return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	trackers := make(map[string]*costTracker)
+	for _, transaction := range ordered {
+		symbol := strings.ToUpper(transaction.Symbol)
+		tracker, exists := trackers[symbol]
+		if !exists {
+			tracker = &costTracker{method: method, basis: SymbolCostBasis{Symbol: symbol, RealizedComplete: true}}
+			trackers[symbol] = tracker
+		}
+
+		quantity := math.Abs(transaction.Quantity)
+		incoming := transaction.Type == TransactionTypeBuy || transaction.Type == TransactionTypeDeposit ||
+			(transaction.Type == TransactionTypeTransfer && transaction.Quantity > 0)
+		switch {
+		case incoming:
+			cost, known := acquisitionCost(transaction, quantity, priceAt)
+			tracker.add(costLot{quantity: quantity, cost: cost, known: known, acquired: transaction.Timestamp})
+		case transaction.Type == TransactionTypeSell:
+			cost, known := tracker.remove(quantity)
+			if known && basisCurrencies[strings.ToUpper(transaction.Currency)] {
+				tracker.basis.RealizedGain += transaction.Amount - transaction.Fee - cost
+			} else {
+				tracker.basis.RealizedComplete = false
+			}
+		default:
+			tracker.remove(quantity)
+		}
+	}
+
+	result := make(map[string]*SymbolCostBasis, len(trackers))
+	for symbol, tracker := range trackers {
+		basis := tracker.basis
+		basis.KnownBasis = true
+		for _, lot := range tracker.lots {
+			basis.Quantity += lot.quantity
+			basis.CostBasis += lot.cost
+			basis.KnownBasis = basis.KnownBasis && lot.known
+			if basis.FirstAcquiredAt.IsZero() || lot.acquired.Before(basis.FirstAcquiredAt) {
+				basis.FirstAcquiredAt = lot.acquired
+			}
+		}
+		result[symbol] = &basis
+	}
+	return result
+}
+
+// ApplyCostBasis fills in the cost basis fields of investments from costs, keyed by upper-case
+// symbol. Each unit held is costed at its symbol's average unit cost, so holdings of a symbol in
+// several accounts share it. Investments whose symbol has no known cost are left unchanged.
+func ApplyCostBasis(investments []*Investment, costs map[string]*SymbolCostBasis) {
+	for _, investment := range investments {
+		basis, ok := costs[strings.ToUpper(investment.Symbol)]
+		if !ok {
+			continue
+		}
+		unitCost, ok := basis.UnitCost()
+		if !ok {
+			continue
+		}
+		costBasis := unitCost * investment.Quantity
+		unrealizedGain := investment.Value - costBasis
+		firstAcquiredAt := basis.FirstAcquiredAt
+		investment.CostBasis = &costBasis
+		investment.AverageBuyPrice = &unitCost
+		investment.UnrealizedGain = &unrealizedGain
+		investment.UnrealizedPLPercent = gainPercent(unrealizedGain, costBasis)
+		investment.FirstAcquiredAt = &firstAcquiredAt
+	}
+}
+
+// SetUnrealizedPLPercent sets UnrealizedPLPercent on the investments from their stored cost
+// basis and unrealized gain, which the store keeps current as prices change
+func SetUnrealizedPLPercent(investments []*Investment) {
+	for _, investment := range investments {
+		investment.UnrealizedPLPercent = nil
+		if investment.CostBasis != nil && investment.UnrealizedGain != nil {
+			investment.UnrealizedPLPercent = gainPercent(*investment.UnrealizedGain, *investment.CostBasis)
+		}
+	}
+}
+
+// SymbolPerformance is the profit on one symbol: unrealized on the holdings, valued at their
+// current value, and realized by past sales. The cost and unrealized fields are null when some
+// of the holding has no known cost.
+type SymbolPerformance struct {
+	Symbol                string   `json:"symbol"`
+	Quantity              float64  `json:"quantity"`
+	Value                 float64  `json:"value"`
+	CostBasis             *float64 `json:"cost_basis"`
+	UnrealizedGain        *float64 `json:"unrealized_gain"`
+	UnrealizedGainPercent *float64 `json:"unrealized_gain_percent"`
+	RealizedGain          float64  `json:"realized_gain"`
+	// UnknownBasis flags a symbol with units acquired, or sold, without a known cost
+	UnknownBasis bool `json:"unknown_basis"`
+}
+
+// PerformanceSummary is the profit on every symbol with transactions or holdings, and in
+// total. Totals of cost, value and unrealized gain cover only holdings with a known cost.
+type PerformanceSummary struct {
+	Method                CostBasisMethod      `json:"method"`
+	Currency              string               `json:"currency"`
+	Symbols               []*SymbolPerformance `json:"symbols"`
+	CostBasis             float64              `json:"cost_basis"`
+	Value                 float64              `json:"value"`
+	UnrealizedGain        float64              `json:"unrealized_gain"`
+	UnrealizedGainPercent *float64             `json:"unrealized_gain_percent"`
+	RealizedGain          float64              `json:"realized_gain"`
+}
+
+// NewPerformanceSummary combines the current investments with the cost basis of each symbol.
+// Cash holdings without transactions have no profit and are left out.
+func NewPerformanceSummary(investments []*Investment, costs map[string]*SymbolCostBasis, method CostBasisMethod) *PerformanceSummary {
+	bySymbol := make(map[string]*SymbolPerformance)
+	symbol := func(name string) *SymbolPerformance {
+		performance, exists := bySymbol[name]
+		if !exists {
+			performance = &SymbolPerformance{Symbol: name}
+			bySymbol[name] = performance
+		}
+		return performance
+	}
+	for _, investment := range investments {
+		name := strings.ToUpper(investment.Symbol)
+		if _, traded := costs[name]; !traded && investment.AssetType == AssetTypeCash {
+			continue
+		}
+		performance := symbol(name)
+		performance.Quantity += investment.Quantity
+		performance.Value += investment.Value
+	}
+	for name := range costs {
+		symbol(name)
+	}
+
+	summary := &PerformanceSummary{Method: method, Currency: currency.USD, Symbols: make([]*SymbolPerformance, 0, len(bySymbol))}
+	for name, performance := range bySymbol {
+		basis, traded := costs[name]
+		if traded {
+			performance.RealizedGain = basis.RealizedGain
+			summary.RealizedGain += basis.RealizedGain
+			performance.UnknownBasis = !basis.RealizedComplete
+		}
+		if performance.Quantity > quantityEpsilon {
+			unitCost, known := 0.0, false
+			if traded {
+				unitCost, known = basis.UnitCost()
+			}
+			if known {
+				costBasis := unitCost * performance.Quantity
+				unrealizedGain := performance.Value - costBasis
+				performance.CostBasis = &costBasis
+				performance.UnrealizedGain = &unrealizedGain
+				performance.UnrealizedGainPercent = gainPercent(unrealizedGain, costBasis)
+				summary.CostBasis += costBasis
+				summary.Value += performance.Value
+				summary.UnrealizedGain += unrealizedGain
+			} else {
+				performance.UnknownBasis = true
+			}
+		}
+		summary.Symbols = append(summary.Symbols, performance)
+	}
+	sort.Slice(summary.Symbols, func(i, j int) bool { return summary.Symbols[i].Symbol < summary.Symbols[j].Symbol })
+	summary.UnrealizedGainPercent = gainPercent(summary.UnrealizedGain, summary.CostBasis)
+	return summary
+}
+
+// gainPercent returns gain as a percentage of cost, or nil when there is no cost to divide by
+func gainPercent(gain, cost float64) *float64 {
+	if cost <= 0 {
+		return nil
+	}
+	percent := gain / cost * 100
+	return &percent
+}
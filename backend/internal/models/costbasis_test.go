@@ -0,0 +1,169 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// day returns noon UTC on day n of January 2024
+func day(n int) time.Time {
+	return time.Date(2024, 1, n, 12, 0, 0, 0, time.UTC)
+}
+
+// btc returns a BTC transaction of quantity units for amount USD on day n
+func btc(id string, txType TransactionType, n int, quantity, amount, fee float64) *Transaction {
+	return &Transaction{ID: id, AccountID: "a1", Type: txType, Symbol: "BTC", Quantity: quantity, Amount: amount, Currency: "USD", Fee: fee, Timestamp: day(n)}
+}
+
+func TestComputeCostBasis(t *testing.T) {
+	priceOnDay2 := func(symbol string, at time.Time) (float64, bool) {
+		return 250, at.Equal(day(2))
+	}
+	for _, test := range []struct {
+		name         string
+		transactions []*Transaction
+		method       CostBasisMethod
+		priceAt      func(string, time.Time) (float64, bool)
+		want         SymbolCostBasis
+	}{
+		{
+			name:         "fifo sells the oldest lot",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeBuy, 2, 1, 300, 0), btc("t3", TransactionTypeSell, 3, -1, 400, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1, CostBasis: 300, KnownBasis: true, FirstAcquiredAt: day(2), RealizedGain: 300, RealizedComplete: true},
+		},
+		{
+			name:         "average sells at the pooled cost",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeBuy, 2, 1, 300, 0), btc("t3", TransactionTypeSell, 3, -1, 400, 0)},
+			method:       CostBasisAverage,
+			want:         SymbolCostBasis{Quantity: 1, CostBasis: 200, KnownBasis: true, FirstAcquiredAt: day(1), RealizedGain: 200, RealizedComplete: true},
+		},
+		{
+			name:         "transactions are taken in time order",
+			transactions: []*Transaction{btc("t3", TransactionTypeSell, 3, -1, 400, 0), btc("t2", TransactionTypeBuy, 2, 1, 300, 0), btc("t1", TransactionTypeBuy, 1, 1, 100, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1, CostBasis: 300, KnownBasis: true, FirstAcquiredAt: day(2), RealizedGain: 300, RealizedComplete: true},
+		},
+		{
+			name:         "partial sell of a lot splits its cost, fees included",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 2, 200, 2), btc("t2", TransactionTypeSell, 2, -0.5, 80, 1)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1.5, CostBasis: 151.5, KnownBasis: true, FirstAcquiredAt: day(1), RealizedGain: 28.5, RealizedComplete: true},
+		},
+		{
+			name:         "partial sell across lots",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeBuy, 2, 1, 300, 0), btc("t3", TransactionTypeSell, 3, -1.5, 600, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 0.5, CostBasis: 150, KnownBasis: true, FirstAcquiredAt: day(2), RealizedGain: 350, RealizedComplete: true},
+		},
+		{
+			name:         "overselling leaves the realized gain incomplete",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeSell, 2, -2, 500, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 0, CostBasis: 0, KnownBasis: true, RealizedGain: 0, RealizedComplete: false},
+		},
+		{
+			name:         "withdrawal removes units without realizing a gain",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 2, 200, 0), btc("t2", TransactionTypeTransfer, 2, -1, 0, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1, CostBasis: 100, KnownBasis: true, FirstAcquiredAt: day(1), RealizedComplete: true},
+		},
+		{
+			name:         "transfer in without a price has unknown basis",
+			transactions: []*Transaction{btc("t1", TransactionTypeTransfer, 1, 1, 0, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1, KnownBasis: false, FirstAcquiredAt: day(1), RealizedComplete: true},
+		},
+		{
+			name:         "transfer in is costed at the price on its date",
+			transactions: []*Transaction{btc("t1", TransactionTypeTransfer, 2, 2, 0, 0)},
+			method:       CostBasisFIFO,
+			priceAt:      priceOnDay2,
+			want:         SymbolCostBasis{Quantity: 2, CostBasis: 500, KnownBasis: true, FirstAcquiredAt: day(2), RealizedComplete: true},
+		},
+		{
+			name:         "transfer in without a price on its date has unknown basis",
+			transactions: []*Transaction{btc("t1", TransactionTypeTransfer, 3, 1, 0, 0)},
+			method:       CostBasisFIFO,
+			priceAt:      priceOnDay2,
+			want:         SymbolCostBasis{Quantity: 1, KnownBasis: false, FirstAcquiredAt: day(3), RealizedComplete: true},
+		},
+		{
+			name:         "fifo sells the known lot before an unknown one",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeTransfer, 2, 1, 0, 0), btc("t3", TransactionTypeSell, 3, -1, 150, 0)},
+			method:       CostBasisFIFO,
+			want:         SymbolCostBasis{Quantity: 1, KnownBasis: false, FirstAcquiredAt: day(2), RealizedGain: 50, RealizedComplete: true},
+		},
+		{
+			name:         "average pools an unknown lot into an unknown basis",
+			transactions: []*Transaction{btc("t1", TransactionTypeBuy, 1, 1, 100, 0), btc("t2", TransactionTypeTransfer, 2, 1, 0, 0), btc("t3", TransactionTypeSell, 3, -1, 150, 0)},
+			method:       CostBasisAverage,
+			want:         SymbolCostBasis{Quantity: 1, KnownBasis: false, FirstAcquiredAt: day(1), RealizedComplete: false},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			costs := ComputeCostBasis(test.transactions, test.method, test.priceAt)
+			got, ok := costs["BTC"]
+			if !ok || len(costs) != 1 {
+				t.Fatalf("cost basis = %v, want BTC alone", costs)
+			}
+			want := test.want
+			if math.Abs(got.Quantity-want.Quantity) > 1e-9 || got.KnownBasis != want.KnownBasis ||
+				(want.KnownBasis && math.Abs(got.CostBasis-want.CostBasis) > 1e-9) {
+				t.Errorf("holding = %v costing %v (known %v), want %v costing %v (known %v)",
+					got.Quantity, got.CostBasis, got.KnownBasis, want.Quantity, want.CostBasis, want.KnownBasis)
+			}
+			if math.Abs(got.RealizedGain-want.RealizedGain) > 1e-9 || got.RealizedComplete != want.RealizedComplete {
+				t.Errorf("realized gain = %v (complete %v), want %v (complete %v)",
+					got.RealizedGain, got.RealizedComplete, want.RealizedGain, want.RealizedComplete)
+			}
+			if want.Quantity > 0 && !got.FirstAcquiredAt.Equal(want.FirstAcquiredAt) {
+				t.Errorf("first acquired at %s, want %s", got.FirstAcquiredAt, want.FirstAcquiredAt)
+			}
+			if unitCost, ok := got.UnitCost(); ok != (want.KnownBasis && want.Quantity > 0) || math.IsNaN(unitCost) {
+				t.Errorf("unit cost = %v, %v; want it known only for a held quantity of known cost", unitCost, ok)
+			}
+		})
+	}
+}
+
+func TestComputeCostBasisIgnoresCashMovements(t *testing.T) {
+	costs := ComputeCostBasis([]*Transaction{
+		{ID: "t1", Type: TransactionTypeDeposit, Symbol: "USD", Quantity: 100, Amount: 100, Currency: "USD", Timestamp: day(1)},
+		{ID: "t2", Type: TransactionTypeDividend, Symbol: "VTI", Quantity: 1, Amount: 5, Currency: "USD", Timestamp: day(1)},
+		{ID: "t3", Type: TransactionTypeFee, Symbol: "BTC", Quantity: 1, Amount: 1, Currency: "USD", Timestamp: day(1)},
+		{ID: "t4", Type: TransactionTypeBuy, Symbol: "ETH", Amount: 100, Currency: "USD", Timestamp: day(1)},
+	}, CostBasisFIFO, nil)
+	if len(costs) != 0 {
+		t.Fatalf("cost basis = %v, want none from cash deposits, dividends, fees or trades without a quantity", costs)
+	}
+}
+
+func TestApplyCostBasisUnrealizedPLPercent(t *testing.T) {
+	costs := ComputeCostBasis([]*Transaction{
+		btc("t1", TransactionTypeBuy, 1, 2, 400, 0),
+		{ID: "t2", Type: TransactionTypeTransfer, Symbol: "ETH", Quantity: 1, Currency: "USD", Timestamp: day(1)},
+	}, CostBasisFIFO, nil)
+	held := &Investment{ID: "i1", Symbol: "btc", Quantity: 1, Value: 300}
+	unknown := &Investment{ID: "i2", Symbol: "ETH", Quantity: 1, Value: 30}
+	ApplyCostBasis([]*Investment{held, unknown}, costs)
+
+	if held.CostBasis == nil || *held.CostBasis != 200 || held.UnrealizedGain == nil || *held.UnrealizedGain != 100 {
+		t.Fatalf("BTC cost basis %v, unrealized gain %v; want 200 and 100", held.CostBasis, held.UnrealizedGain)
+	}
+	if held.UnrealizedPLPercent == nil || *held.UnrealizedPLPercent != 50 {
+		t.Fatalf("BTC unrealized P&L percent = %v, want 50", held.UnrealizedPLPercent)
+	}
+	if unknown.CostBasis != nil || unknown.UnrealizedPLPercent != nil {
+		t.Fatalf("ETH of unknown basis = %v, %v%%; want both nil", unknown.CostBasis, unknown.UnrealizedPLPercent)
+	}
+
+	// Listings derive it from the stored fields, and leave it unset for a free holding
+	zero, gain := 0.0, 5.0
+	free := &Investment{ID: "i3", Symbol: "SOL", CostBasis: &zero, UnrealizedGain: &gain}
+	SetUnrealizedPLPercent([]*Investment{held, unknown, free})
+	if held.UnrealizedPLPercent == nil || *held.UnrealizedPLPercent != 50 || unknown.UnrealizedPLPercent != nil || free.UnrealizedPLPercent != nil {
+		t.Fatalf("listed percents = %v, %v, %v; want 50, nil and nil", held.UnrealizedPLPercent, unknown.UnrealizedPLPercent, free.UnrealizedPLPercent)
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ExportSchema identifies a 0xNetworth data export
+const ExportSchema = "0xnetworth-export"
+
+// ExportVersion is the version of the export format written by this build. Bump it whenever a
+// change to the document or to a model it contains would break importing older files.
+const ExportVersion = 1
+
+// Export is a full copy of a user's data, financial and workflow records alike
+type Export struct {
+	Schema                    string                      `json:"schema"`
+	Version                   int                         `json:"version"`
+	ExportedAt                time.Time                   `json:"exported_at"`
+	Portfolios                []*Portfolio                `json:"portfolios"`
+	Accounts                  []*Account                  `json:"accounts"`
+	Investments               []*Investment               `json:"investments"`
+	Transactions              []*Transaction              `json:"transactions"`
+	NetWorthSnapshots         []*NetWorthSnapshot         `json:"networth_snapshots"`
+	YouTubeSources            []*YouTubeSource            `json:"youtube_sources"`
+	Transcripts               []*VideoTranscript          `json:"transcripts"`
+	MarketAnalyses            []*MarketAnalysis           `json:"market_analyses"`
+	Recommendations           []*Recommendation           `json:"recommendations"`
+	WorkflowExecutions        []*WorkflowExecution        `json:"workflow_executions"`
+	AggregatedRecommendations []*AggregatedRecommendation `json:"aggregated_recommendations"`
+	// Complete is written last, so a document cut short by a failed export is detectable
+	Complete bool `json:"complete"`
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/currency"
+)
+
 // Investment represents an investment holding
 type Investment struct {
 	ID          string   `json:"id"`
@@ -11,7 +18,132 @@ type Investment struct {
 	Value       float64  `json:"value"`        // Current value in account currency
 	Price       float64  `json:"price"`        // Current price per unit
 	Currency    string   `json:"currency"`     // Currency of the investment
-	AssetType   string   `json:"asset_type"`  // e.g., "crypto", "stock", "etf", "bond"
-	LastUpdated string   `json:"last_updated,omitempty"` // ISO 8601 timestamp
+	// NativeValue and NativeCurrency are the value as the platform reported it, set when it was
+	// in another currency and Value holds its conversion into Currency
+	NativeValue    *float64 `json:"native_value"`
+	NativeCurrency string   `json:"native_currency,omitempty"`
+	AssetType   AssetType `json:"asset_type"` // Canonical asset class, see ValidAssetTypes
+	// Staked marks units locked in staking or an earn program. They count towards net worth
+	// but are kept apart from the liquid holding of the same asset.
+	Staked      bool      `json:"staked"`
+	// StalePrice marks a holding the last sync could not price, kept at its last known price
+	StalePrice  bool      `json:"stale_price"`
+	// IsDust marks a holding worth less than the sync's dust threshold. It counts towards net
+	// worth but is left out of investment listings unless asked for.
+	IsDust      bool      `json:"is_dust"`
+	// Unconverted marks, in API responses, a holding valued in a currency other than the
+	// reporting currency, which net worth leaves out. It is not stored.
+	Unconverted bool      `json:"unconverted,omitempty"`
+	// PortfolioName is, in API responses, the name of the portfolio owning the holding, so
+	// holdings can be grouped by portfolio. It is not stored.
+	PortfolioName string  `json:"portfolio_name,omitempty"`
+	// PriceChange24hPct is the percentage the asset's price moved over the last 24 hours as of
+	// the last sync, and ValueChange24h what that move changed the holding's value by. They are
+	// nil when the platform did not report the move, so unknown is distinguishable from flat.
+	PriceChange24hPct *float64 `json:"price_change_24h_pct"`
+	ValueChange24h    *float64 `json:"value_change_24h"`
+	LastUpdated time.Time `json:"last_updated,omitzero"`
+
+	// Active is false once a sync no longer reports the holding. Inactive holdings are kept
+	// for auditing but left out of net worth and default listings; writing one reactivates it.
+	Active        bool       `json:"active"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+
+	// Cost basis fields are computed rather than synced from platforms.
+	// They are pointers so that "unknown" (null) is distinguishable from zero.
+	CostBasis       *float64 `json:"cost_basis"`        // Total amount paid for the current quantity
+	AverageBuyPrice *float64 `json:"average_buy_price"` // Average price paid per unit
+	FirstAcquiredAt *time.Time `json:"first_acquired_at"` // When the first unit was acquired
+	UnrealizedGain  *float64 `json:"unrealized_gain"`   // Value minus cost basis
+	// UnrealizedPLPercent is, in API responses, UnrealizedGain as a percentage of CostBasis,
+	// nil when either is unknown or nothing was paid. It is not stored.
+	UnrealizedPLPercent *float64 `json:"unrealized_pl_percent"`
 }
 
+// FlagUnconverted sets Unconverted on the investments whose currency is not the reporting
+// currency
+func FlagUnconverted(investments []*Investment) {
+	for _, investment := range investments {
+		investment.Unconverted = !currency.IsReporting(investment.Currency)
+	}
+}
+
+// LabelPortfolios sets PortfolioName on the investments. A holding's AccountID is its portfolio,
+// or for a cash balance its account, which is looked up in accounts for the portfolio.
+func LabelPortfolios(investments []*Investment, portfolios []*Portfolio, accounts []*Account) {
+	names := make(map[string]string, len(portfolios))
+	for _, portfolio := range portfolios {
+		names[portfolio.ID] = portfolio.Name
+	}
+	accountPortfolios := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		accountPortfolios[account.ID] = account.PortfolioID
+	}
+	for _, investment := range investments {
+		name, ok := names[investment.AccountID]
+		if !ok {
+			name = names[accountPortfolios[investment.AccountID]]
+		}
+		investment.PortfolioName = name
+	}
+}
+
+// InvestmentHistoryPoint is the position in one symbol on one platform at a sync, kept to chart
+// its value over time. Timestamp is the sync time in UTC truncated to the minute; a later point
+// for the same platform and symbol within the same minute replaces the earlier one.
+type InvestmentHistoryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Platform  Platform  `json:"platform"`
+	Symbol    string    `json:"symbol"`
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Value     float64   `json:"value"`
+	Currency  string    `json:"currency"`
+}
+
+// NewInvestmentHistoryPoints captures synced investments as history points, one per platform
+// and symbol. Holdings of a symbol in several accounts are summed and priced at their combined
+// value per unit.
+func NewInvestmentHistoryPoints(investments []*Investment, syncTime time.Time) []*InvestmentHistoryPoint {
+	type key struct {
+		platform Platform
+		symbol   string
+	}
+	timestamp := syncTime.UTC().Truncate(time.Minute)
+	points := make([]*InvestmentHistoryPoint, 0, len(investments))
+	byKey := make(map[key]*InvestmentHistoryPoint, len(investments))
+	for _, investment := range investments {
+		k := key{investment.Platform, strings.ToUpper(investment.Symbol)}
+		point, exists := byKey[k]
+		if !exists {
+			point = &InvestmentHistoryPoint{
+				Timestamp: timestamp,
+				Platform:  k.platform,
+				Symbol:    k.symbol,
+				Price:     investment.Price,
+				Currency:  investment.Currency,
+			}
+			byKey[k] = point
+			points = append(points, point)
+		}
+		point.Quantity += investment.Quantity
+		point.Value += investment.Value
+	}
+	for _, point := range points {
+		if point.Quantity != 0 {
+			point.Price = point.Value / point.Quantity
+		}
+	}
+	return points
+}
+
+// SetPriceChange24h records that the investment's price moved pct percent over the last 24
+// hours, and the part of its value that move accounts for
+func (i *Investment) SetPriceChange24h(pct float64) {
+	i.PriceChange24hPct = &pct
+	change := 0.0
+	if pct != -100 {
+		change = i.Value * pct / (100 + pct)
+	}
+	i.ValueChange24h = &change
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestInvestmentCostBasisNullVsZero(t *testing.T) {
+	zero := 0.0
+	acquired := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	unknown := &Investment{ID: "unknown", Symbol: "BTC"}
+	free := &Investment{
+		ID: "free", Symbol: "BTC", CostBasis: &zero, AverageBuyPrice: &zero, FirstAcquiredAt: &acquired, UnrealizedGain: &zero,
+	}
+
+	unknownFields := jsonFields(t, mustMarshal(t, unknown))
+	freeFields := jsonFields(t, mustMarshal(t, free))
+	for _, key := range []string{"cost_basis", "average_buy_price", "first_acquired_at", "unrealized_gain"} {
+		if value, ok := unknownFields[key]; !ok || value != nil {
+			t.Errorf("unknown %s = %v (present %v), want null", key, value, ok)
+		}
+	}
+	for _, key := range []string{"cost_basis", "average_buy_price", "unrealized_gain"} {
+		if value := freeFields[key]; value != 0.0 {
+			t.Errorf("zero %s = %v, want 0", key, value)
+		}
+	}
+	if value := freeFields["first_acquired_at"]; value != "2024-01-15T09:30:00Z" {
+		t.Errorf("first_acquired_at = %v, want 2024-01-15T09:30:00Z", value)
+	}
+
+	// Decoding keeps them apart as well
+	var decoded Investment
+	if err := json.Unmarshal([]byte(`{"cost_basis":0,"average_buy_price":null}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.CostBasis == nil || *decoded.CostBasis != 0 {
+		t.Errorf("decoded cost_basis 0 = %v, want a zero", decoded.CostBasis)
+	}
+	if decoded.AverageBuyPrice != nil || decoded.UnrealizedGain != nil {
+		t.Errorf("decoded null and missing fields = %v, %v, want nil", decoded.AverageBuyPrice, decoded.UnrealizedGain)
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // MarketAnalysis represents market condition analysis results
 type MarketAnalysis struct {
 	ID          string   `json:"id"`
@@ -8,7 +10,7 @@ type MarketAnalysis struct {
 	Trends      []string `json:"trends"`
 	RiskFactors []string `json:"risk_factors"`
 	Summary     string   `json:"summary"`
-	CreatedAt   string   `json:"created_at,omitempty"` // ISO 8601 timestamp
+	CreatedAt   time.Time `json:"created_at,omitzero"`
 }
 
 // SuggestedAction represents an individual suggested action
@@ -26,20 +28,33 @@ type Recommendation struct {
 	Confidence     float64          `json:"confidence"` // 0.0 to 1.0
 	SuggestedActions []SuggestedAction `json:"suggested_actions"`
 	Summary        string           `json:"summary,omitempty"`
-	CreatedAt      string           `json:"created_at,omitempty"` // ISO 8601 timestamp
+	CreatedAt      time.Time        `json:"created_at,omitzero"`
+}
+
+// RecommendationSummaryRow is a completed workflow execution joined with its recommendation
+// and, when one is stored, its market analysis
+type RecommendationSummaryRow struct {
+	ExecutionID string    `json:"execution_id"`
+	VideoID     string    `json:"video_id"`
+	VideoTitle  string    `json:"video_title"`
+	CompletedAt time.Time `json:"completed_at"`
+	Action      string    `json:"action"`
+	Confidence  float64   `json:"confidence"`
+	Condition   string    `json:"condition"`
+	HasAnalysis bool      `json:"has_analysis"` // False when the execution has no stored market analysis
 }
 
-// AggregatedRecommendation represents a consolidated recommendation from multiple videos
+// AggregatedRecommendation represents a consolidated recommendation from multiple videos.
+// Each generation is stored as its own record so the history can be reviewed over time.
 type AggregatedRecommendation struct {
-	ID              string           `json:"id"`
-	Action          string           `json:"action"`
-	Confidence      float64          `json:"confidence"`
+	ID               string            `json:"id"`
+	Action           string            `json:"action"`
+	Confidence       float64           `json:"confidence"`
 	SuggestedActions []SuggestedAction `json:"suggested_actions"`
-	Summary         string           `json:"summary"`
-	KeyInsights     []string         `json:"key_insights"`
-	ExecutionIDs    []string         `json:"execution_ids"` // IDs of executions used to generate this
-	CreatedAt       string           `json:"created_at,omitempty"`
-	UpdatedAt       string           `json:"updated_at,omitempty"`
+	Summary          string            `json:"summary"`
+	KeyInsights      []string          `json:"key_insights"`
+	ExecutionIDs     []string          `json:"execution_ids"`          // IDs of executions used to generate this
+	GeneratedAt      time.Time         `json:"generated_at,omitzero"`
+	WindowDays       int               `json:"window_days"`            // Lookback window in days (0 = most recent executions regardless of age)
+	SourceCount      int               `json:"source_count"`           // Number of distinct YouTube sources that fed this recommendation
 }
-
-
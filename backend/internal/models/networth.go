@@ -1,13 +1,61 @@
 package models
 
+import (
+	"strings"
+	"time"
+)
+
 // NetWorth represents aggregated net worth information
 type NetWorth struct {
 	TotalValue    float64            `json:"total_value"`
 	Currency      string             `json:"currency"`
 	ByPlatform    map[Platform]float64 `json:"by_platform"`    // Value per platform
-	ByAssetType   map[string]float64   `json:"by_asset_type"`  // Value per asset type
+	ByAssetType   map[AssetType]float64 `json:"by_asset_type"` // Value per canonical asset type
+	ByTaxTreatment map[TaxTreatment]float64 `json:"by_tax_treatment"` // Value per portfolio tax treatment
 	AccountCount  int                `json:"account_count"`
-	LastCalculated string            `json:"last_calculated"`  // ISO 8601 timestamp
+	LastCalculated time.Time         `json:"last_calculated"`
+	// Unconverted sums by currency the holdings valued in a currency other than Currency, which
+	// are left out of the total and breakdowns until they can be converted
+	Unconverted map[string]float64 `json:"unconverted,omitempty"`
+	// PriceChange24h is how much of the total the last 24 hours of price movement account for,
+	// summed over the holdings whose move is known; nil if none is
+	PriceChange24h *float64 `json:"price_change_24h"`
+}
+
+// AddHolding counts value, held on platform as assetType in a portfolio with taxTreatment and
+// valued in code, into the total and breakdowns. A value in another currency than the net
+// worth's is counted in Unconverted instead, as it cannot be summed with the others. An empty
+// code is taken to be the net worth's currency, and an empty tax treatment unassigned.
+func (n *NetWorth) AddHolding(platform Platform, assetType string, taxTreatment TaxTreatment, code string, value float64) {
+	if code = strings.ToUpper(strings.TrimSpace(code)); code != "" && code != n.Currency {
+		if n.Unconverted == nil {
+			n.Unconverted = make(map[string]float64)
+		}
+		n.Unconverted[code] += value
+		return
+	}
+	n.TotalValue += value
+	n.ByPlatform[platform] += value
+	// Rows are normalized on write, but fold any stragglers so the breakdown only ever
+	// contains canonical values
+	canonical, _ := NormalizeAssetType(assetType)
+	n.ByAssetType[canonical] += value
+	if taxTreatment == "" {
+		taxTreatment = TaxTreatmentUnassigned
+	}
+	n.ByTaxTreatment[taxTreatment] += value
+}
+
+// AddPriceChange counts change, the 24 hour price movement of holdings valued in code, into
+// PriceChange24h. Like AddHolding it leaves out values in another currency.
+func (n *NetWorth) AddPriceChange(code string, change float64) {
+	if code = strings.ToUpper(strings.TrimSpace(code)); code != "" && code != n.Currency {
+		return
+	}
+	if n.PriceChange24h == nil {
+		n.PriceChange24h = new(float64)
+	}
+	*n.PriceChange24h += change
 }
 
 // NetWorthBreakdown provides detailed breakdown of net worth
@@ -17,3 +65,33 @@ type NetWorthBreakdown struct {
 	Investments []*Investment `json:"investments"`
 }
 
+
+// NetWorthSnapshot is a point-in-time record of net worth, kept to chart its history.
+// Timestamp is the calculation time in UTC truncated to the minute; a later snapshot
+// within the same minute replaces the earlier one.
+type NetWorthSnapshot struct {
+	Timestamp      time.Time                `json:"timestamp"`
+	TotalValue     float64                  `json:"total_value"`
+	Currency       string                   `json:"currency"`
+	ByPlatform     map[Platform]float64     `json:"by_platform"`
+	ByAssetType    map[AssetType]float64    `json:"by_asset_type"`
+	ByTaxTreatment map[TaxTreatment]float64 `json:"by_tax_treatment"`
+	AccountCount   int                      `json:"account_count"`
+}
+
+// NewNetWorthSnapshot captures a calculated net worth as a snapshot
+func NewNetWorthSnapshot(networth *NetWorth) *NetWorthSnapshot {
+	calculated := networth.LastCalculated
+	if calculated.IsZero() {
+		calculated = Now()
+	}
+	return &NetWorthSnapshot{
+		Timestamp:      calculated.UTC().Truncate(time.Minute),
+		TotalValue:     networth.TotalValue,
+		Currency:       networth.Currency,
+		ByPlatform:     networth.ByPlatform,
+		ByAssetType:    networth.ByAssetType,
+		ByTaxTreatment: networth.ByTaxTreatment,
+		AccountCount:   networth.AccountCount,
+	}
+}
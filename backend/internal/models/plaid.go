@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// PlaidItemStatus is whether a Plaid item can be synced
+type PlaidItemStatus string
+
+const (
+	PlaidItemStatusHealthy PlaidItemStatus = "healthy"
+	// PlaidItemStatusLoginRequired is the status of an item whose institution needs the user
+	// to log in again; syncs skip it until it is re-linked through Plaid Link
+	PlaidItemStatusLoginRequired PlaidItemStatus = "login_required"
+)
+
+// PlaidItem is a login at a financial institution linked through Plaid, such as an M1 Finance
+// account. Its access token reads the institution's data on the user's behalf, so it is stored
+// encrypted and never returned by the API.
+type PlaidItem struct {
+	ID              string   `json:"id"` // Plaid's item_id
+	InstitutionID   string   `json:"institution_id,omitempty"`
+	InstitutionName string   `json:"institution_name"`
+	Platform        Platform `json:"platform"` // Platform the item's accounts are synced as
+	// EncryptedAccessToken is the access token sealed with the server's token key (see
+	// plaid.TokenCipher)
+	EncryptedAccessToken string `json:"-"`
+	// AccountIDs are the accounts the item's last sync stored, so unlinking it can find the
+	// data it brought in
+	AccountIDs []string `json:"account_ids,omitempty"`
+	// AccountCount is the number of AccountIDs, set on the items the API lists
+	AccountCount int             `json:"account_count"`
+	Status       PlaidItemStatus `json:"status"`
+	// LastSyncedAt is when the item last synced successfully, nil if it never has
+	LastSyncedAt *time.Time `json:"last_synced_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at,omitzero"`
+	UpdatedAt    time.Time  `json:"updated_at,omitzero"`
+}
+
+// Healthy reports whether the item can be synced. Items stored before statuses were recorded
+// have none and are healthy.
+func (item *PlaidItem) Healthy() bool {
+	return item.Status == "" || item.Status == PlaidItemStatusHealthy
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform represents the investment platform
+type Platform string
+
+const (
+	PlatformCoinbase Platform = "coinbase"
+	// PlatformM1Finance is M1 Finance, linked through Plaid
+	PlatformM1Finance Platform = "m1_finance"
+)
+
+// ValidPlatforms returns all supported platforms
+func ValidPlatforms() []Platform {
+	return []Platform{
+		PlatformCoinbase,
+		PlatformM1Finance,
+	}
+}
+
+// IsValid reports whether the platform is one of the supported platforms
+func (p Platform) IsValid() bool {
+	for _, valid := range ValidPlatforms() {
+		if p == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePlatform converts a string (case-insensitive) into a supported Platform
+func ParsePlatform(s string) (Platform, error) {
+	platform := Platform(strings.ToLower(strings.TrimSpace(s)))
+	if !platform.IsValid() {
+		return "", fmt.Errorf("invalid platform %q. Must be one of: %s", s, validPlatformList())
+	}
+	return platform, nil
+}
+
+// validPlatformList returns the supported platforms as a quoted, comma-separated list
+func validPlatformList() string {
+	platforms := ValidPlatforms()
+	names := make([]string, len(platforms))
+	for i, p := range platforms {
+		names[i] = "'" + string(p) + "'"
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,18 +1,82 @@
 package models
 
-// Platform represents the investment platform
-type Platform string
+import "time"
+
+// TaxTreatment describes how gains in a portfolio are taxed
+type TaxTreatment string
 
 const (
-	PlatformCoinbase Platform = "coinbase"
+	TaxTreatmentTaxable     TaxTreatment = "taxable"
+	TaxTreatmentTraditional TaxTreatment = "traditional"
+	TaxTreatmentRoth        TaxTreatment = "roth"
+	TaxTreatmentOther       TaxTreatment = "other"
+
+	// TaxTreatmentUnassigned is the breakdown bucket for portfolios without a tax treatment.
+	// It is not accepted as an input value.
+	TaxTreatmentUnassigned TaxTreatment = "unassigned"
 )
 
+// ValidTaxTreatments returns all tax treatments that can be assigned to a portfolio
+func ValidTaxTreatments() []TaxTreatment {
+	return []TaxTreatment{
+		TaxTreatmentTaxable,
+		TaxTreatmentTraditional,
+		TaxTreatmentRoth,
+		TaxTreatmentOther,
+	}
+}
+
+// IsValid reports whether the tax treatment can be assigned to a portfolio
+func (t TaxTreatment) IsValid() bool {
+	for _, valid := range ValidTaxTreatments() {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
 // Portfolio represents a portfolio/account from an investment platform
 type Portfolio struct {
 	ID          string   `json:"id"`
 	Platform    Platform `json:"platform"`
 	Name        string   `json:"name"`
-	Type        string   `json:"type,omitempty"` // e.g., "default", "main"
-	LastSynced  string   `json:"last_synced,omitempty"` // ISO 8601 timestamp
+	Type        string   `json:"type,omitempty"` // e.g., "default", "consumer", "perpetuals"
+	LastSynced  time.Time `json:"last_synced,omitzero"`
+
+	// User-managed metadata. Sync never sets these, so upserts preserve existing values.
+	TaxTreatment TaxTreatment `json:"tax_treatment,omitempty"`
+	Custodian    string       `json:"custodian,omitempty"`
+	DisplayOrder *int         `json:"display_order,omitempty"` // Lower values sort first; nil sorts last
+}
+
+// PortfolioValue is the combined value of the active holdings in a portfolio
+type PortfolioValue struct {
+	PortfolioID  string   `json:"portfolio_id"`
+	Name         string   `json:"name"`
+	Platform     Platform `json:"platform"`
+	HoldingCount int      `json:"holding_count"`
+	Value        float64  `json:"value"`
 }
 
+// PortfolioMetadataUpdate is a partial update of user-managed portfolio metadata.
+// Nil fields are left unchanged; an empty string clears tax treatment or custodian.
+type PortfolioMetadataUpdate struct {
+	TaxTreatment *TaxTreatment `json:"tax_treatment"`
+	Custodian    *string       `json:"custodian"`
+	DisplayOrder *int          `json:"display_order"`
+}
+
+// Apply copies the set fields of the update onto the portfolio
+func (u PortfolioMetadataUpdate) Apply(p *Portfolio) {
+	if u.TaxTreatment != nil {
+		p.TaxTreatment = *u.TaxTreatment
+	}
+	if u.Custodian != nil {
+		p.Custodian = *u.Custodian
+	}
+	if u.DisplayOrder != nil {
+		order := *u.DisplayOrder
+		p.DisplayOrder = &order
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import "time"
+
+// SyncResult is the data fetched from a platform by one sync
+type SyncResult struct {
+	Platform    Platform
+	Portfolios  []*Portfolio
+	Accounts    []*Account
+	Investments []*Investment
+	// Transactions are the trades fetched by the sync; a sync that could not fetch them has none
+	Transactions []*Transaction
+	// CompletePortfolios lists the portfolios whose holdings were all fetched, along with the
+	// accounts whose cash balance was, since cash holdings belong to an account. A stored holding
+	// missing from one of these has been sold; others may be missing holdings because a request
+	// failed, so their stored holdings must be left alone.
+	CompletePortfolios []string
+	// StaleAssets describes the holdings kept at their last known price because they could not
+	// be priced
+	StaleAssets []string
+	// Report says how completely the holdings were fetched and priced
+	Report SyncReport
+}
+
+// SyncReport says how completely a sync fetched and priced holdings, so a sync that skipped some
+// can be told apart from one that synced everything
+type SyncReport struct {
+	// Status is SyncStatusPartial if a portfolio or holding was skipped, or part of the data
+	// could not be fetched, and SyncStatusSuccess otherwise
+	Status     SyncStatus            `json:"status"`
+	Portfolios []PortfolioSyncReport `json:"portfolios"`
+	// Holdings counts every holding found, cash balances included
+	Holdings HoldingCounts `json:"holdings"`
+	// SkippedAssets lists the holdings left out, with the reason
+	SkippedAssets []SkippedAsset `json:"skipped_assets"`
+	// SkippedPortfolios lists the portfolios left out because their type is not synced. They do
+	// not make a sync partial.
+	SkippedPortfolios []SkippedPortfolio `json:"skipped_portfolios,omitempty"`
+	// Warnings describes the data other than holdings that could not be fetched
+	Warnings []string `json:"warnings,omitempty"`
+	// Items is how each linked Plaid item synced, on platforms synced through Plaid
+	Items []ItemSyncReport `json:"items,omitempty"`
+}
+
+// ItemSyncReport is how one linked Plaid item synced
+type ItemSyncReport struct {
+	ID              string `json:"id"`
+	InstitutionName string `json:"institution_name"`
+	// Status is SyncStatusFailed if the item could not be synced, such as when its consent was
+	// revoked, SyncStatusSkipped if it was not synced because it has to be re-linked, and
+	// SyncStatusSuccess otherwise
+	Status SyncStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	// ErrorCode is Plaid's code for Error, such as ITEM_LOGIN_REQUIRED when the item has to be
+	// re-linked
+	ErrorCode string `json:"error_code,omitempty"`
+	Accounts  int    `json:"accounts"`
+	// AccountIDs are the IDs of those accounts, which the item is stored with rather than
+	// reported
+	AccountIDs []string `json:"-"`
+}
+
+// FailedItems counts the Plaid items that could not be synced
+func (r SyncReport) FailedItems() int {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Status == SyncStatusFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// SkippedItems counts the Plaid items that were not synced because they have to be re-linked
+func (r SyncReport) SkippedItems() int {
+	skipped := 0
+	for _, item := range r.Items {
+		if item.Status == SyncStatusSkipped {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// PortfolioSyncReport is how completely the holdings of one portfolio were synced
+type PortfolioSyncReport struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Status is SyncStatusFailed if the holdings could not be fetched, SyncStatusPartial if
+	// some were skipped, and SyncStatusSuccess otherwise
+	Status   SyncStatus    `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Holdings HoldingCounts `json:"holdings"`
+}
+
+// HoldingCounts tallies holdings by how they were priced. Fetched is the sum of the others.
+type HoldingCounts struct {
+	Fetched int `json:"fetched"`
+	Priced  int `json:"priced"`
+	Stale   int `json:"stale"` // Kept at their last known price
+	Skipped int `json:"skipped"`
+}
+
+// SkippedAsset is a holding a sync left out
+type SkippedAsset struct {
+	ProductID   string `json:"product_id"` // The product that prices it, such as BTC-USD
+	PortfolioID string `json:"portfolio_id,omitempty"`
+	AccountID   string `json:"account_id,omitempty"` // Set instead of PortfolioID for cash balances
+	Staked      bool   `json:"staked,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// SkippedPortfolio is a portfolio a sync left out because of its type
+type SkippedPortfolio struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // As stored in Portfolio.Type, such as "perpetuals"
+}
+
+// Add counts one holding, stale if it was kept at its last known price
+func (c *HoldingCounts) Add(stale bool) {
+	c.Fetched++
+	if stale {
+		c.Stale++
+	} else {
+		c.Priced++
+	}
+}
+
+// Skip counts one holding left out
+func (c *HoldingCounts) Skip() {
+	c.Fetched++
+	c.Skipped++
+}
+
+// Merge adds other's counts to c
+func (c *HoldingCounts) Merge(other HoldingCounts) {
+	c.Fetched += other.Fetched
+	c.Priced += other.Priced
+	c.Stale += other.Stale
+	c.Skipped += other.Skipped
+}
+
+// SyncStatus is the outcome of a sync attempt
+type SyncStatus string
+
+const (
+	SyncStatusSuccess SyncStatus = "success"
+	SyncStatusFailed  SyncStatus = "failed"
+	// SyncStatusPartial is reported for a sync that completed but left out some of the data
+	SyncStatusPartial SyncStatus = "partial"
+	// SyncStatusSkipped is reported for a Plaid item a sync left alone because it is not healthy
+	SyncStatusSkipped SyncStatus = "skipped"
+	// SyncStatusNever is reported for platforms that have no recorded sync attempt
+	SyncStatusNever SyncStatus = "never"
+)
+
+// SyncCounts is how many records of each type a sync wrote
+type SyncCounts struct {
+	Portfolios  int `json:"portfolios"`
+	Accounts    int `json:"accounts"`
+	Investments int `json:"investments"`
+}
+
+// SyncRecord describes the latest sync attempt on a platform
+type SyncRecord struct {
+	Platform Platform   `json:"platform"`
+	Status   SyncStatus `json:"status"`
+	Error    string     `json:"error,omitempty"` // Why the attempt failed
+	Counts   SyncCounts `json:"counts"`
+	// LastAttempt is when the latest sync ran; LastSync is the latest one that succeeded.
+	// Either is null if there has been no such sync.
+	LastAttempt *time.Time `json:"last_attempt"`
+	LastSync    *time.Time `json:"last_sync"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Timestamps are stored as time.Time in UTC with whole-second precision so that they
+// marshal to the same RFC3339 strings ("2006-01-02T15:04:05Z") the API has always returned.
+// Fields that may be unset use the omitzero JSON option, which omits the zero time just as
+// omitempty omitted an empty string.
+
+// Now returns the current time normalized for storage in a model
+func Now() time.Time {
+	return NormalizeTime(time.Now())
+}
+
+// NormalizeTime converts t to UTC and truncates it to whole seconds
+func NormalizeTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC().Truncate(time.Second)
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// jsonFields unmarshals a JSON object into its fields
+func jsonFields(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+// TestTimestampsReadOldPayloads decodes payloads as the API wrote them when timestamps were
+// RFC3339 strings, and checks they encode back to the same timestamps
+func TestTimestampsReadOldPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		value   any
+		// present are the timestamp fields of payload, absent the unset ones old payloads omitted
+		present, absent []string
+	}{
+		{
+			name:    "pending execution",
+			payload: `{"id":"e1","status":"pending","video_id":"v1","video_url":"https://youtu.be/v1","created_at":"2024-05-01T12:00:00Z"}`,
+			value:   &WorkflowExecution{},
+			present: []string{"created_at"},
+			absent:  []string{"started_at", "completed_at"},
+		},
+		{
+			name:    "completed execution",
+			payload: `{"id":"e2","status":"completed","video_id":"v2","video_url":"https://youtu.be/v2","created_at":"2024-05-01T12:00:00Z","started_at":"2024-05-01T12:00:05Z","completed_at":"2024-05-01T12:03:00Z"}`,
+			value:   &WorkflowExecution{},
+			present: []string{"created_at", "started_at", "completed_at"},
+		},
+		{
+			name:    "investment",
+			payload: `{"id":"i1","account_id":"a1","platform":"coinbase","symbol":"BTC","name":"Bitcoin","quantity":1,"value":60000,"price":60000,"currency":"USD","last_updated":"2024-05-01T12:00:00Z","first_acquired_at":"2023-01-15T09:30:00Z"}`,
+			value:   &Investment{},
+			present: []string{"last_updated", "first_acquired_at"},
+		},
+		{
+			name:    "investment never synced",
+			payload: `{"id":"i2","account_id":"a1","platform":"coinbase","symbol":"ETH","quantity":0,"value":0,"price":0,"currency":"USD","first_acquired_at":null}`,
+			value:   &Investment{},
+			absent:  []string{"last_updated"},
+		},
+		{
+			name:    "account",
+			payload: `{"id":"a1","portfolio_id":"p1","platform":"coinbase","name":"BTC Wallet","currency":"BTC","last_synced":"2024-05-01T12:00:00Z"}`,
+			value:   &Account{},
+			present: []string{"last_synced"},
+		},
+		{
+			name:    "portfolio never synced",
+			payload: `{"id":"p1","platform":"coinbase","name":"Default"}`,
+			value:   &Portfolio{},
+			absent:  []string{"last_synced"},
+		},
+		{
+			name:    "transaction",
+			payload: `{"id":"t1","account_id":"a1","platform":"coinbase","type":"buy","symbol":"BTC","amount":60000,"currency":"USD","timestamp":"2024-05-01T12:00:00Z"}`,
+			value:   &Transaction{},
+			present: []string{"timestamp"},
+		},
+		{
+			name:    "net worth",
+			payload: `{"total_value":60000,"currency":"USD","by_platform":{"coinbase":60000},"account_count":1,"last_calculated":"2024-05-01T12:00:00Z"}`,
+			value:   &NetWorth{},
+			present: []string{"last_calculated"},
+		},
+		{
+			name:    "youtube source",
+			payload: `{"id":"s1","name":"Channel","channel_id":"UC1","enabled":true,"last_processed":"2024-05-01T06:00:00Z","created_at":"2024-01-01T00:00:00Z"}`,
+			value:   &YouTubeSource{},
+			present: []string{"last_processed", "created_at"},
+		},
+		{
+			name:    "aggregated recommendation",
+			payload: `{"id":"r1","action":"hold","confidence":0.8,"summary":"Hold","generated_at":"2024-05-01T12:00:00Z","window_days":7,"source_count":2}`,
+			value:   &AggregatedRecommendation{},
+			present: []string{"generated_at"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := json.Unmarshal([]byte(tt.payload), tt.value); err != nil {
+				t.Fatalf("decode old payload: %v", err)
+			}
+			encoded, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			old, fields := jsonFields(t, []byte(tt.payload)), jsonFields(t, encoded)
+			for _, key := range tt.present {
+				if fields[key] != old[key] {
+					t.Errorf("%s = %v, want %v as before", key, fields[key], old[key])
+				}
+			}
+			for _, key := range tt.absent {
+				if value, ok := fields[key]; ok {
+					t.Errorf("%s = %v, want it omitted as before", key, value)
+				}
+			}
+		})
+	}
+}
+
+func TestTimestampsReadOffsets(t *testing.T) {
+	var execution WorkflowExecution
+	payload := `{"id":"e1","created_at":"2024-05-01T14:00:00+02:00","completed_at":"2024-05-01T12:03:00.250Z"}`
+	if err := json.Unmarshal([]byte(payload), &execution); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC); !execution.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", execution.CreatedAt, want)
+	}
+	// Normalized for storage, timestamps encode in UTC to the second as the API always wrote them
+	execution.CreatedAt = NormalizeTime(execution.CreatedAt)
+	execution.CompletedAt = NormalizeTime(execution.CompletedAt)
+	fields := jsonFields(t, mustMarshal(t, execution))
+	if fields["created_at"] != "2024-05-01T12:00:00Z" || fields["completed_at"] != "2024-05-01T12:03:00Z" {
+		t.Errorf("normalized timestamps = %v, %v, want UTC whole seconds", fields["created_at"], fields["completed_at"])
+	}
+}
+
+func TestTimestampsOrderChronologically(t *testing.T) {
+	// Strings with different offsets sort out of order; the times they decode to do not
+	var earlier, later Transaction
+	if err := json.Unmarshal([]byte(`{"timestamp":"2024-05-01T23:00:00-05:00"}`), &later); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"timestamp":"2024-05-02T01:00:00Z"}`), &earlier); err != nil {
+		t.Fatal(err)
+	}
+	if !earlier.Timestamp.Before(later.Timestamp) {
+		t.Errorf("%v is not before %v", earlier.Timestamp, later.Timestamp)
+	}
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // TransactionType represents the type of transaction
 type TransactionType string
 
@@ -9,8 +11,35 @@ const (
 	TransactionTypeDeposit TransactionType = "deposit"
 	TransactionTypeWithdraw TransactionType = "withdraw"
 	TransactionTypeTransfer TransactionType = "transfer"
+	// TransactionTypeDividend is a cash distribution paid on a held security
+	TransactionTypeDividend TransactionType = "dividend"
+	// TransactionTypeFee is a fee charged to the account rather than to a trade
+	TransactionTypeFee TransactionType = "fee"
 )
 
+// ValidTransactionTypes returns all supported transaction types
+func ValidTransactionTypes() []TransactionType {
+	return []TransactionType{
+		TransactionTypeBuy,
+		TransactionTypeSell,
+		TransactionTypeDeposit,
+		TransactionTypeWithdraw,
+		TransactionTypeTransfer,
+		TransactionTypeDividend,
+		TransactionTypeFee,
+	}
+}
+
+// IsValid reports whether the transaction type is one of the supported types
+func (t TransactionType) IsValid() bool {
+	for _, valid := range ValidTransactionTypes() {
+		if t == valid {
+			return true
+		}
+	}
+	return false
+}
+
 // Transaction represents a financial transaction
 type Transaction struct {
 	ID          string          `json:"id"`
@@ -22,7 +51,42 @@ type Transaction struct {
 	Amount      float64         `json:"amount"`                // Transaction amount
 	Currency    string          `json:"currency"`
 	Fee         float64         `json:"fee,omitempty"`        // Transaction fee
-	Timestamp   string          `json:"timestamp"`            // ISO 8601 timestamp
+	Timestamp   time.Time       `json:"timestamp"`
 	Description string          `json:"description,omitempty"`
 }
 
+// TransactionTotals holds summed transaction amounts for a single currency
+type TransactionTotals struct {
+	Bought    float64 `json:"bought"`
+	Sold      float64 `json:"sold"`
+	Deposited float64 `json:"deposited"`
+	Withdrawn float64 `json:"withdrawn"`
+	Dividends float64 `json:"dividends"`
+	Fees      float64 `json:"fees"`
+}
+
+// Add adds a transaction amount and fee to the totals for its type. A fee transaction's amount
+// counts towards the fees.
+func (t *TransactionTotals) Add(txType TransactionType, amount, fee float64) {
+	switch txType {
+	case TransactionTypeBuy:
+		t.Bought += amount
+	case TransactionTypeSell:
+		t.Sold += amount
+	case TransactionTypeDeposit:
+		t.Deposited += amount
+	case TransactionTypeWithdraw:
+		t.Withdrawn += amount
+	case TransactionTypeDividend:
+		t.Dividends += amount
+	case TransactionTypeFee:
+		t.Fees += amount
+	}
+	t.Fees += fee
+}
+
+// TransactionSummary summarizes a year of transactions per currency
+type TransactionSummary struct {
+	Year       int                           `json:"year"`
+	ByCurrency map[string]*TransactionTotals `json:"by_currency"`
+}
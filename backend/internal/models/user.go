@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// DefaultUserID is the bootstrap user that owns all data created before multi-user
+// support existed, and every request when no API tokens are configured.
+const DefaultUserID = "default"
+
+// User represents a person whose financial data is isolated from other users
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"-"` // SHA-256 hex digest of the user's API token; never serialized
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // VideoTranscript represents a YouTube video transcript
 type VideoTranscript struct {
 	ID          string `json:"id"`
@@ -9,7 +11,27 @@ type VideoTranscript struct {
 	Text        string `json:"text"`
 	Duration    *int   `json:"duration,omitempty"` // Duration in seconds
 	SourceID    string `json:"source_id,omitempty"` // Reference to YouTubeSource
-	CreatedAt   string `json:"created_at,omitempty"` // ISO 8601 timestamp
+	CreatedAt   time.Time `json:"created_at,omitzero"`
 }
 
 
+
+// TextRange is a span of a string given as character offsets, End exclusive
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// TranscriptSearchResult is a transcript matching a search, with an excerpt of its text
+// around the first match. Highlights locate the matched terms within Snippet.
+type TranscriptSearchResult struct {
+	TranscriptID string      `json:"transcript_id"`
+	VideoID      string      `json:"video_id"`
+	VideoTitle   string      `json:"video_title"`
+	VideoURL     string      `json:"video_url"`
+	SourceID     string      `json:"source_id,omitempty"`
+	SourceName   string      `json:"source_name,omitempty"`
+	Snippet      string      `json:"snippet"`
+	Highlights   []TextRange `json:"highlights"`
+	CreatedAt    time.Time   `json:"created_at,omitzero"`
+}
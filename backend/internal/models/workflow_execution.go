@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // WorkflowExecutionStatus represents the status of a workflow execution
 type WorkflowExecutionStatus string
 
@@ -8,8 +10,20 @@ const (
 	WorkflowStatusProcessing WorkflowExecutionStatus = "processing"
 	WorkflowStatusCompleted  WorkflowExecutionStatus = "completed"
 	WorkflowStatusFailed     WorkflowExecutionStatus = "failed"
+	// WorkflowStatusSkipped records a video a source's title filters left out. It never holds
+	// the video, so the video is processed if the filters later let it through.
+	WorkflowStatusSkipped WorkflowExecutionStatus = "skipped"
 )
 
+// IsValid reports whether the status is one of the known workflow execution statuses
+func (s WorkflowExecutionStatus) IsValid() bool {
+	switch s {
+	case WorkflowStatusPending, WorkflowStatusProcessing, WorkflowStatusCompleted, WorkflowStatusFailed, WorkflowStatusSkipped:
+		return true
+	}
+	return false
+}
+
 // WorkflowExecution represents a workflow execution record
 type WorkflowExecution struct {
 	ID             string                  `json:"id"`
@@ -22,9 +36,33 @@ type WorkflowExecution struct {
 	AnalysisID     string                  `json:"analysis_id,omitempty"`
 	RecommendationID string                `json:"recommendation_id,omitempty"`
 	Error          string                  `json:"error,omitempty"`
-	CreatedAt      string                  `json:"created_at,omitempty"` // ISO 8601 timestamp
-	StartedAt      string                  `json:"started_at,omitempty"` // ISO 8601 timestamp
-	CompletedAt    string                  `json:"completed_at,omitempty"` // ISO 8601 timestamp
+	CreatedAt      time.Time               `json:"created_at,omitzero"`
+	StartedAt      time.Time               `json:"started_at,omitzero"`
+	CompletedAt    time.Time               `json:"completed_at,omitzero"`
 }
 
 
+
+
+// WorkflowEventType identifies a step in a workflow execution's event log
+type WorkflowEventType string
+
+const (
+	WorkflowEventStarted              WorkflowEventType = "started"
+	WorkflowEventServiceCalled        WorkflowEventType = "workflow_service_called"
+	WorkflowEventTranscriptStored     WorkflowEventType = "transcript_stored"
+	WorkflowEventAnalysisStored       WorkflowEventType = "analysis_stored"
+	WorkflowEventRecommendationStored WorkflowEventType = "recommendation_stored"
+	WorkflowEventCompleted            WorkflowEventType = "completed"
+	WorkflowEventFailed               WorkflowEventType = "failed"
+	WorkflowEventSkipped              WorkflowEventType = "skipped"
+)
+
+// WorkflowExecutionEvent records a step of a workflow execution as it completes, so a failed
+// execution shows how far it got
+type WorkflowExecutionEvent struct {
+	ExecutionID string            `json:"execution_id"`
+	Type        WorkflowEventType `json:"type"`
+	Detail      string            `json:"detail,omitempty"`
+	Timestamp   time.Time         `json:"timestamp"`
+}
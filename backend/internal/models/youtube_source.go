@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
 // YouTubeSourceType represents the type of YouTube source
 type YouTubeSourceType string
 
@@ -18,8 +24,72 @@ type YouTubeSource struct {
 	PlaylistID  string            `json:"playlist_id,omitempty"`
 	Enabled     bool              `json:"enabled"`
 	Schedule    string            `json:"schedule,omitempty"` // Cron expression
-	LastProcessed string          `json:"last_processed,omitempty"` // ISO 8601 timestamp
-	CreatedAt   string            `json:"created_at,omitempty"` // ISO 8601 timestamp
+	// TitleIncludes and TitleExcludes are regular expressions matched against video titles
+	// without regard to case; a plain keyword matches titles containing it. Only videos whose
+	// title matches one of TitleIncludes, if any are set, and none of TitleExcludes are processed.
+	TitleIncludes []string        `json:"title_includes,omitempty"`
+	TitleExcludes []string        `json:"title_excludes,omitempty"`
+	// RecordSkipped records each video the title filters leave out as a skipped execution
+	RecordSkipped bool            `json:"record_skipped,omitempty"`
+	LastProcessed time.Time       `json:"last_processed,omitzero"`
+	CreatedAt   time.Time         `json:"created_at,omitzero"`
+}
+
+// TitleFilter decides from their titles which videos of a source are processed
+type TitleFilter struct {
+	includes []*regexp.Regexp
+	excludes []*regexp.Regexp
+}
+
+// TitleFilter compiles the source's title filters, failing on the first invalid pattern
+func (s *YouTubeSource) TitleFilter() (*TitleFilter, error) {
+	includes, err := compileTitlePatterns("title_includes", s.TitleIncludes)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := compileTitlePatterns("title_excludes", s.TitleExcludes)
+	if err != nil {
+		return nil, err
+	}
+	return &TitleFilter{includes: includes, excludes: excludes}, nil
+}
+
+// compileTitlePatterns compiles the patterns of a title filter field, ignoring case
+func compileTitlePatterns(field string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("%s cannot contain an empty pattern", field)
+		}
+		// Checked as given first, so an error quotes the pattern rather than the flagged one
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", field, pattern, err)
+		}
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", field, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Match reports whether a video titled title is processed, and if not, why
+func (f *TitleFilter) Match(title string) (bool, string) {
+	for _, re := range f.excludes {
+		if re.MatchString(title) {
+			return false, fmt.Sprintf("title matches excluded pattern %q", re.String()[len("(?i)"):])
+		}
+	}
+	if len(f.includes) == 0 {
+		return true, ""
+	}
+	for _, re := range f.includes {
+		if re.MatchString(title) {
+			return true, ""
+		}
+	}
+	return false, "title matches none of the included patterns"
 }
 
 
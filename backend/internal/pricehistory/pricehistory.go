@@ -0,0 +1,69 @@
+// Package pricehistory serves the historical prices of products, for backfilling net worth
+// history and measuring performance since purchase.
+package pricehistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/integrations/coinbase"
+)
+
+// DefaultGranularity is the candle granularity used when none is given
+const DefaultGranularity = "ONE_DAY"
+
+// priceLookback is how far before a time PriceAt looks for a candle, covering products that
+// did not trade for a few days
+const priceLookback = 7 * 24 * time.Hour
+
+// ErrNoPrice is returned by PriceAt when the product has no candle near the time
+var ErrNoPrice = errors.New("no price available")
+
+// CandleSource fetches the candles of a product; *coinbase.Client implements it
+type CandleSource interface {
+	GetProductCandles(ctx context.Context, productID string, start, end time.Time, granularity string) ([]coinbase.Candle, error)
+}
+
+var _ CandleSource = (*coinbase.Client)(nil)
+
+// Service answers historical price questions from a candle source
+type Service struct {
+	source CandleSource
+}
+
+// New creates a price history service reading candles from source
+func New(source CandleSource) *Service {
+	return &Service{source: source}
+}
+
+// Candles returns the candles of productID starting in [start, end), oldest first. An empty
+// granularity means DefaultGranularity.
+func (s *Service) Candles(ctx context.Context, productID string, start, end time.Time, granularity string) ([]coinbase.Candle, error) {
+	productID = strings.ToUpper(strings.TrimSpace(productID))
+	if productID == "" {
+		return nil, fmt.Errorf("product ID is required")
+	}
+	if granularity == "" {
+		granularity = DefaultGranularity
+	}
+	return s.source.GetProductCandles(ctx, productID, start, end, granularity)
+}
+
+// PriceAt returns the price of productID at a past time: the close of the latest daily candle
+// starting at or before it, looking back up to a week. It returns ErrNoPrice if there is none.
+func (s *Service) PriceAt(ctx context.Context, productID string, at time.Time) (float64, error) {
+	day := at.UTC().Truncate(24 * time.Hour)
+	candles, err := s.Candles(ctx, productID, day.Add(-priceLookback), day.Add(24*time.Hour), DefaultGranularity)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(candles) - 1; i >= 0; i-- {
+		if !candles[i].Start.After(at) && candles[i].Close > 0 {
+			return candles[i].Close, nil
+		}
+	}
+	return 0, fmt.Errorf("%w for %s at %s", ErrNoPrice, productID, at.Format(time.RFC3339))
+}
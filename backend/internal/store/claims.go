@@ -0,0 +1,31 @@
+package store
+
+import "0xnetworth/backend/internal/models"
+
+// Video claims are taken with the same statements in SQLite and PostgreSQL; see
+// TryClaimVideoExecution.
+
+// videoClaimHolderQuery finds the execution of user $2 holding a video: the one that claimed
+// it, or failing that one that completed it without a claim, such as before claims were recorded
+const videoClaimHolderQuery = `SELECT id FROM workflow_executions
+		 WHERE user_id = $2 AND (claimed_video_id = $1 OR (video_id = $1 AND status = 'completed'))
+		 ORDER BY claimed_video_id IS NULL, id LIMIT 1`
+
+// claimVideoSQL inserts an execution that claims its video, doing nothing if another execution
+// of the same user already holds the claim; see claimVideoArgs
+const claimVideoSQL = `INSERT INTO workflow_executions (id, status, video_id, video_url, video_title, source_id, error, created_at, started_at, user_id, claimed_video_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $3)
+		 ON CONFLICT (user_id, claimed_video_id) WHERE claimed_video_id IS NOT NULL DO NOTHING`
+
+// claimVideoArgs returns the arguments of claimVideoSQL for an execution of userID
+func claimVideoArgs(execution *models.WorkflowExecution, userID string) []interface{} {
+	return []interface{}{
+		execution.ID, execution.Status, execution.VideoID, execution.VideoURL, execution.VideoTitle,
+		execution.SourceID, execution.Error, executionCreatedAt(execution), nullableTime(execution.StartedAt),
+		userID,
+	}
+}
+
+// maxClaimAttempts bounds how often TryClaimVideoExecution retries when the execution holding
+// a video releases it between the failed claim and the lookup of the holder
+const maxClaimAttempts = 3
@@ -0,0 +1,632 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// conformanceStore opens an empty store of one backend for a test
+type conformanceStore struct {
+	name string
+	open func(t *testing.T) Store
+}
+
+// conformanceStores lists the backends the conformance suite runs against. PostgreSQL is
+// only tested when TEST_DATABASE_URL names a scratch database; every table in it is emptied
+// before each test. It is tested both with pgx's default prepared statements and with the
+// simple protocol used behind pgbouncer (see DB_QUERY_EXEC_MODE).
+func conformanceStores() []conformanceStore {
+	stores := []conformanceStore{
+		{"memory", func(t *testing.T) Store { return NewStore() }},
+		{"sqlite", openTestSQLiteStore},
+	}
+	if os.Getenv("TEST_DATABASE_URL") != "" {
+		stores = append(stores,
+			conformanceStore{"postgres", openTestPostgresStore},
+			conformanceStore{"postgres_simple_protocol", func(t *testing.T) Store {
+				t.Setenv("DB_QUERY_EXEC_MODE", "simple_protocol")
+				return openTestPostgresStore(t)
+			}},
+		)
+	}
+	return stores
+}
+
+func openTestSQLiteStore(t *testing.T) Store {
+	t.Helper()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "networth.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func openTestPostgresStore(t *testing.T) Store {
+	t.Helper()
+	ctx := context.Background()
+	s, err := NewPostgresStore(ctx, os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatalf("NewPostgresStore: %v", err)
+	}
+	t.Cleanup(s.Close)
+	if err := s.ApplyMigrations(ctx); err != nil {
+		t.Fatalf("ApplyMigrations: %v", err)
+	}
+	_, err = s.pool.Exec(ctx, `DO $$ DECLARE t text; BEGIN
+		FOR t IN SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename NOT IN ('schema_migrations', 'users') LOOP
+			EXECUTE format('TRUNCATE TABLE %I CASCADE', t);
+		END LOOP;
+		DELETE FROM users WHERE id <> 'default';
+	END $$`)
+	if err != nil {
+		t.Fatalf("failed to empty the test database: %v", err)
+	}
+	return s
+}
+
+// forEachStore runs test against an empty store of every backend
+func forEachStore(t *testing.T, test func(t *testing.T, s Store)) {
+	for _, backend := range conformanceStores() {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+// testTime returns a whole-second UTC time offset from a fixed base, as the stores keep it
+func testTime(offset time.Duration) time.Time {
+	return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Add(offset)
+}
+
+func TestConformancePortfolioUpsertResults(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		portfolio := &models.Portfolio{ID: "p1", Platform: models.PlatformCoinbase, Name: "Main", LastSynced: testTime(0)}
+
+		for _, step := range []struct {
+			name string
+			edit func()
+			want UpsertResult
+		}{
+			{"create", func() {}, UpsertCreated},
+			{"same values", func() { portfolio.LastSynced = testTime(time.Minute) }, UpsertUnchanged},
+			{"new name", func() { portfolio.Name = "Renamed" }, UpsertUpdated},
+		} {
+			step.edit()
+			got, err := s.CreateOrUpdatePortfolio(ctx, portfolio)
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if got != step.want {
+				t.Fatalf("%s: result %s, want %s", step.name, got, step.want)
+			}
+		}
+
+		stored, err := s.GetPortfolioByID(ctx, "p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Name != "Renamed" {
+			t.Fatalf("stored name %q, want Renamed", stored.Name)
+		}
+		if err := s.DeletePortfolio(ctx, "p1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetPortfolioByID(ctx, "p1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetPortfolioByID after delete: %v, want ErrNotFound", err)
+		}
+		if err := s.DeletePortfolio(ctx, "p1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("second DeletePortfolio: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestConformanceInvestmentBatchAndDeactivation(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		investments := []*models.Investment{
+			{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60000, Price: 60000, Currency: "USD"},
+			{ID: "i2", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 2, Value: 6000, Price: 3000, Currency: "USD"},
+		}
+		counts, err := s.CreateOrUpdateInvestments(ctx, investments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts != (UpsertCounts{Created: 2}) {
+			t.Fatalf("first batch counts %+v, want 2 created", counts)
+		}
+
+		investments[1].Quantity = 3
+		counts, err = s.CreateOrUpdateInvestments(ctx, investments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts != (UpsertCounts{Updated: 1, Unchanged: 1}) {
+			t.Fatalf("second batch counts %+v, want 1 updated and 1 unchanged", counts)
+		}
+
+		deactivated, err := s.DeactivateMissingInvestments(ctx, models.PlatformCoinbase, []string{"a1"}, []string{"i1"}, testTime(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deactivated != 1 {
+			t.Fatalf("deactivated %d investments, want 1", deactivated)
+		}
+		active, err := s.GetInvestmentsBySymbol(ctx, "eth", InvestmentFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 0 {
+			t.Fatalf("active ETH holdings %d, want 0 after deactivation", len(active))
+		}
+		all, err := s.GetInvestmentsBySymbol(ctx, "eth", InvestmentFilter{IncludeInactive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].Active || all[0].DeactivatedAt == nil || !all[0].DeactivatedAt.Equal(testTime(0)) {
+			t.Fatalf("inactive ETH holding %+v, want one deactivated at %s", all, testTime(0))
+		}
+		if n, err := s.CountInvestments(ctx, ""); err != nil || n != 1 {
+			t.Fatalf("CountInvestments = %d, %v; want 1 active", n, err)
+		}
+	})
+}
+
+func TestConformanceAnalysisJSONColumns(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		transcript := &models.VideoTranscript{ID: "t1", VideoID: "v1", VideoTitle: "Weekly outlook", Text: "text", CreatedAt: testTime(0)}
+		if err := s.CreateOrUpdateTranscript(ctx, transcript); err != nil {
+			t.Fatal(err)
+		}
+		analysis := &models.MarketAnalysis{
+			ID:           "ma1",
+			TranscriptID: "t1",
+			Conditions:   "bullish",
+			Trends:       []string{"rates falling", "AI capex"},
+			RiskFactors:  []string{"recession"},
+			Summary:      "summary",
+			CreatedAt:    testTime(time.Minute),
+		}
+		if err := s.CreateOrUpdateMarketAnalysis(ctx, analysis); err != nil {
+			t.Fatal(err)
+		}
+		recommendation := &models.Recommendation{
+			ID:         "r1",
+			AnalysisID: "ma1",
+			Action:     "rebalance",
+			Confidence: 0.75,
+			SuggestedActions: []models.SuggestedAction{
+				{Type: "increase", Symbol: "VTI", Rationale: "broad exposure"},
+			},
+			CreatedAt: testTime(2 * time.Minute),
+		}
+		if err := s.CreateOrUpdateRecommendation(ctx, recommendation); err != nil {
+			t.Fatal(err)
+		}
+
+		gotAnalysis, err := s.GetMarketAnalysisByID(ctx, "ma1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gotAnalysis, analysis) {
+			t.Fatalf("stored analysis %+v, want %+v", gotAnalysis, analysis)
+		}
+		gotRecommendation, err := s.GetRecommendationByID(ctx, "r1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gotRecommendation, recommendation) {
+			t.Fatalf("stored recommendation %+v, want %+v", gotRecommendation, recommendation)
+		}
+	})
+}
+
+func TestConformanceTranscriptsNewestFirst(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		for i, id := range []string{"t-old", "t-new", "t-mid"} {
+			offsets := []time.Duration{0, 2 * time.Hour, time.Hour}
+			err := s.CreateOrUpdateTranscript(ctx, &models.VideoTranscript{
+				ID: id, VideoID: "v1", VideoTitle: "title", Text: "text", CreatedAt: testTime(offsets[i]),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		transcripts, err := s.GetTranscriptsByVideoID(ctx, "v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := transcriptIDs(transcripts); !reflect.DeepEqual(got, []string{"t-new", "t-mid", "t-old"}) {
+			t.Fatalf("transcripts in order %v, want newest first", got)
+		}
+		latest, err := s.GetLatestTranscriptByVideoID(ctx, "v1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest.ID != "t-new" {
+			t.Fatalf("latest transcript %s, want t-new", latest.ID)
+		}
+		if _, err := s.GetLatestTranscriptByVideoID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetLatestTranscriptByVideoID of unknown video: %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestConformanceTransactionsBetween(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		for i, id := range []string{"tx1", "tx2", "tx3"} {
+			err := s.CreateOrUpdateTransaction(ctx, &models.Transaction{
+				ID: id, AccountID: "a1", Platform: models.PlatformCoinbase, Type: models.TransactionTypeBuy,
+				Amount: 100, Currency: "USD", Timestamp: testTime(time.Duration(i) * 24 * time.Hour),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// [from, to) includes tx2 at from and excludes tx3 at to
+		transactions, err := s.GetTransactionsBetween(ctx, testTime(24*time.Hour), testTime(48*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != 1 || transactions[0].ID != "tx2" {
+			t.Fatalf("transactions between got %d, want only tx2", len(transactions))
+		}
+		transactions, err = s.GetTransactionsBetween(ctx, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := transactionIDs(transactions); !reflect.DeepEqual(got, []string{"tx3", "tx2", "tx1"}) {
+			t.Fatalf("transactions in order %v, want newest first", got)
+		}
+	})
+}
+
+func transcriptIDs(transcripts []*models.VideoTranscript) []string {
+	ids := make([]string, len(transcripts))
+	for i, transcript := range transcripts {
+		ids[i] = transcript.ID
+	}
+	return ids
+}
+
+func transactionIDs(transactions []*models.Transaction) []string {
+	ids := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		ids[i] = transaction.ID
+	}
+	return ids
+}
+
+func TestConformanceWorkflowExecutionsNewestFirst(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		// IDs sort opposite to creation, so ordering by ID instead of creation time shows up
+		for i, id := range []string{"e-c", "e-b", "e-a"} {
+			err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{
+				ID: id, Status: models.WorkflowStatusCompleted, VideoURL: "https://youtu.be/" + id,
+				CreatedAt: testTime(time.Duration(i) * time.Hour),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// An execution saved without a creation time is created now, so it is the newest
+		if err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{
+			ID: "e-0", Status: models.WorkflowStatusPending, VideoURL: "https://youtu.be/e-0",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"e-0", "e-a", "e-b", "e-c"}
+
+		all, err := s.GetAllWorkflowExecutions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := executionIDs(all); !reflect.DeepEqual(got, want) {
+			t.Fatalf("GetAllWorkflowExecutions order %v, want %v", got, want)
+		}
+		listed, err := s.ListWorkflowExecutions(ctx, ExecutionFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := executionIDs(listed); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ListWorkflowExecutions order %v, want %v", got, want)
+		}
+
+		// Updating an execution keeps its creation time
+		if err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{
+			ID: "e-c", Status: models.WorkflowStatusFailed, VideoURL: "https://youtu.be/e-c", Error: "boom",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := s.GetWorkflowExecutionByID(ctx, "e-c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.CreatedAt.Equal(testTime(0)) || updated.Status != models.WorkflowStatusFailed {
+			t.Fatalf("updated execution created at %s with status %s, want %s and failed",
+				updated.CreatedAt, updated.Status, testTime(0))
+		}
+	})
+}
+
+func TestConformanceVideoClaims(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		first := &models.WorkflowExecution{ID: "e1", Status: models.WorkflowStatusProcessing, VideoID: "v1", VideoURL: "https://youtu.be/v1"}
+		holder, claimed, err := s.TryClaimVideoExecution(ctx, first)
+		if err != nil || !claimed || holder != "e1" {
+			t.Fatalf("first claim = %q, %v, %v; want e1 claimed", holder, claimed, err)
+		}
+		stored, err := s.GetWorkflowExecutionByID(ctx, "e1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.CreatedAt.IsZero() {
+			t.Fatal("claimed execution has no creation time")
+		}
+
+		second := &models.WorkflowExecution{ID: "e2", Status: models.WorkflowStatusProcessing, VideoID: "v1", VideoURL: "https://youtu.be/v1"}
+		holder, claimed, err = s.TryClaimVideoExecution(ctx, second)
+		if err != nil || claimed || holder != "e1" {
+			t.Fatalf("second claim = %q, %v, %v; want held by e1", holder, claimed, err)
+		}
+
+		// Failing the holder releases the video
+		first.Status = models.WorkflowStatusFailed
+		if err := s.CreateOrUpdateWorkflowExecution(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+		holder, claimed, err = s.TryClaimVideoExecution(ctx, second)
+		if err != nil || !claimed || holder != "e2" {
+			t.Fatalf("claim after failure = %q, %v, %v; want e2 claimed", holder, claimed, err)
+		}
+	})
+}
+
+func executionIDs(executions []*models.WorkflowExecution) []string {
+	ids := make([]string, len(executions))
+	for i, execution := range executions {
+		ids[i] = execution.ID
+	}
+	return ids
+}
+
+func TestConformanceExecutionPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		// Several executions share a second, and some were given sub-second creation times,
+		// so a cursor that loses precision skips rows
+		for i := 0; i < 7; i++ {
+			id := fmt.Sprintf("e%d", i)
+			createdAt := testTime(time.Duration(i/3)*time.Second + time.Duration(i)*123*time.Millisecond)
+			err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{
+				ID: id, Status: models.WorkflowStatusCompleted, VideoURL: "https://youtu.be/" + id, CreatedAt: createdAt,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Pages run newest first, and by descending ID within a second
+		want := []string{"e6", "e5", "e4", "e3", "e2", "e1", "e0"}
+
+		var got []string
+		cursor := ""
+		for page := 0; page < 10; page++ {
+			executions, next, err := s.PageWorkflowExecutions(ctx, ExecutionFilter{Limit: 2}, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, executionIDs(executions)...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("paged executions %v, want %v", got, want)
+		}
+
+		if _, _, err := s.PageWorkflowExecutions(ctx, ExecutionFilter{Limit: 2}, "not a cursor"); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("PageWorkflowExecutions with a bad cursor: %v, want ErrInvalidCursor", err)
+		}
+	})
+}
+
+func TestConformancePruneTranscripts(t *testing.T) {
+	for _, keepMetadata := range []bool{true, false} {
+		t.Run(fmt.Sprintf("keepMetadata=%v", keepMetadata), func(t *testing.T) {
+			forEachStore(t, func(t *testing.T, s Store) {
+				ctx := context.Background()
+				for id, createdAt := range map[string]time.Time{"t-old": testTime(0), "t-used": testTime(0), "t-new": testTime(48 * time.Hour)} {
+					err := s.CreateOrUpdateTranscript(ctx, &models.VideoTranscript{ID: id, VideoID: id, VideoTitle: id, Text: "text", CreatedAt: createdAt})
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				// An execution completed after the cutoff keeps its old transcript
+				if err := s.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{
+					ID: "e1", Status: models.WorkflowStatusCompleted, VideoURL: "https://youtu.be/t-used",
+					TranscriptID: "t-used", CreatedAt: testTime(0), CompletedAt: testTime(36 * time.Hour),
+				}); err != nil {
+					t.Fatal(err)
+				}
+
+				pruned, err := s.PruneTranscriptsOlderThan(ctx, testTime(24*time.Hour), keepMetadata)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pruned != 1 {
+					t.Fatalf("pruned %d transcripts, want 1", pruned)
+				}
+				old, err := s.GetTranscriptByID(ctx, "t-old")
+				if keepMetadata {
+					if err != nil || old.Text != "" {
+						t.Fatalf("old transcript = %+v, %v; want kept without text", old, err)
+					}
+				} else if !errors.Is(err, ErrNotFound) {
+					t.Fatalf("old transcript lookup: %v, want ErrNotFound", err)
+				}
+				for _, id := range []string{"t-used", "t-new"} {
+					if kept, err := s.GetTranscriptByID(ctx, id); err != nil || kept.Text != "text" {
+						t.Fatalf("transcript %s = %+v, %v; want kept with text", id, kept, err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestConformanceUpdateInvestmentPrice(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		costBasis := 50.0
+		if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+			{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60, Price: 60, Currency: "USD", CostBasis: &costBasis, StalePrice: true},
+			{ID: "i2", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 1, Value: 30, Price: 30, Currency: "USD"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// A sync changes the quantity after the price was read and before it is written
+		if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+			{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 2, Value: 120, Price: 60, Currency: "USD"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.DeactivateInvestments(ctx, []string{"i2"}, testTime(0)); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.UpdateInvestmentPrice(ctx, "i1", 100, testTime(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.UpdateInvestmentPrice(ctx, "i2", 100, testTime(time.Minute)); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("repricing an inactive investment: %v, want ErrNotFound", err)
+		}
+		if err := s.UpdateInvestmentPrice(ctx, "missing", 100, testTime(time.Minute)); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("repricing a missing investment: %v, want ErrNotFound", err)
+		}
+
+		investments, _, err := s.GetAllInvestments(ctx, ListOptions{}, SortOption{}, InvestmentFilter{IncludeInactive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := make(map[string]*models.Investment)
+		for _, inv := range investments {
+			byID[inv.ID] = inv
+		}
+		btc := byID["i1"]
+		if btc.Quantity != 2 || btc.Price != 100 || btc.Value != 200 || btc.StalePrice || !btc.Active {
+			t.Fatalf("repriced BTC = quantity %v, price %v, value %v, stale %v, active %v; want 2 at 100 = 200, fresh and active",
+				btc.Quantity, btc.Price, btc.Value, btc.StalePrice, btc.Active)
+		}
+		if btc.UnrealizedGain == nil || *btc.UnrealizedGain != 150 {
+			t.Fatalf("repriced BTC unrealized gain %v, want 150", btc.UnrealizedGain)
+		}
+		if !btc.LastUpdated.Equal(testTime(time.Minute)) {
+			t.Fatalf("repriced BTC last updated %s, want %s", btc.LastUpdated, testTime(time.Minute))
+		}
+		if eth := byID["i2"]; eth.Active || eth.Price != 30 {
+			t.Fatalf("inactive ETH = active %v at %v; want left inactive at 30", eth.Active, eth.Price)
+		}
+	})
+}
+
+func TestConformanceCostBasisNullVsZero(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		zero := 0.0
+		if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+			{ID: "unknown", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60, Price: 60, Currency: "USD"},
+			{ID: "free", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 1, Value: 30, Price: 30, Currency: "USD", CostBasis: &zero, AverageBuyPrice: &zero},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		// A sync, which leaves cost basis unset, does not blank the stored zero
+		if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+			{ID: "free", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 1, Value: 35, Price: 35, Currency: "USD"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		investments, err := s.GetInvestmentsByAccount(ctx, "a1", InvestmentFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := make(map[string]*models.Investment)
+		for _, inv := range investments {
+			byID[inv.ID] = inv
+		}
+		unknown, free := byID["unknown"], byID["free"]
+		if unknown == nil || free == nil {
+			t.Fatalf("investments = %v, want unknown and free", investments)
+		}
+		if unknown.CostBasis != nil || unknown.AverageBuyPrice != nil || unknown.UnrealizedGain != nil {
+			t.Errorf("unknown cost basis = %v, %v, %v, want nil", unknown.CostBasis, unknown.AverageBuyPrice, unknown.UnrealizedGain)
+		}
+		if free.CostBasis == nil || *free.CostBasis != 0 || free.AverageBuyPrice == nil || *free.AverageBuyPrice != 0 {
+			t.Errorf("zero cost basis = %v, %v, want zeros", free.CostBasis, free.AverageBuyPrice)
+		}
+	})
+}
+
+func TestConformancePlaidItemStatus(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		item := &models.PlaidItem{ID: "item-1", InstitutionName: "Bank", Platform: models.PlatformM1Finance, EncryptedAccessToken: "sealed"}
+		if err := s.CreateOrUpdatePlaidItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := s.GetPlaidItem(ctx, "item-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status != models.PlaidItemStatusHealthy || stored.LastSyncedAt != nil {
+			t.Fatalf("new item status %q, last synced %v; want healthy and never synced", stored.Status, stored.LastSyncedAt)
+		}
+
+		synced := testTime(0)
+		if err := s.SetPlaidItemStatus(ctx, "item-1", models.PlaidItemStatusHealthy, &synced); err != nil {
+			t.Fatal(err)
+		}
+		// A failure keeps the time of the last successful sync
+		if err := s.SetPlaidItemStatus(ctx, "item-1", models.PlaidItemStatusLoginRequired, nil); err != nil {
+			t.Fatal(err)
+		}
+		stored, err = s.GetPlaidItem(ctx, "item-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status != models.PlaidItemStatusLoginRequired || stored.LastSyncedAt == nil || !stored.LastSyncedAt.Equal(synced) {
+			t.Fatalf("failed item status %q, last synced %v; want login_required, last synced %s", stored.Status, stored.LastSyncedAt, synced)
+		}
+
+		// Linking it again makes it healthy
+		if err := s.CreateOrUpdatePlaidItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		stored, err = s.GetPlaidItem(ctx, "item-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status != models.PlaidItemStatusHealthy || stored.LastSyncedAt == nil {
+			t.Fatalf("re-linked item status %q, last synced %v; want healthy, keeping its last sync", stored.Status, stored.LastSyncedAt)
+		}
+
+		if err := s.SetPlaidItemStatus(ctx, "missing", models.PlaidItemStatusHealthy, nil); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("SetPlaidItemStatus of a missing item: %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// ListOptions pages a listing. A zero Limit means "no limit".
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// window returns the slice bounds of this page within total results
+func (o ListOptions) window(total int) (start, end int) {
+	start = o.Offset
+	if start > total {
+		start = total
+	}
+	end = total
+	if o.Limit > 0 && start+o.Limit < end {
+		end = start + o.Limit
+	}
+	return start, end
+}
+
+// sqlClause appends LIMIT/OFFSET placeholders for this page to args and returns the clause
+func (o ListOptions) sqlClause(args []interface{}) (string, []interface{}) {
+	clause := ""
+	if o.Limit > 0 {
+		args = append(args, o.Limit)
+		clause += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if o.Offset > 0 {
+		args = append(args, o.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return clause, args
+}
+
+// InvestmentFilter narrows an investment listing. The zero value lists active holdings,
+// dust included.
+type InvestmentFilter struct {
+	// IncludeInactive adds the holdings a sync no longer reports
+	IncludeInactive bool
+	// ExcludeDust leaves out the holdings flagged as dust by a sync
+	ExcludeDust bool
+}
+
+// matches reports whether the filter lists inv
+func (f InvestmentFilter) matches(inv *models.Investment) bool {
+	return (inv.Active || f.IncludeInactive) && !(inv.IsDust && f.ExcludeDust)
+}
+
+// sqlWhere returns the conditions to append to an investment query's WHERE clause
+func (f InvestmentFilter) sqlWhere() string {
+	clause := ""
+	if !f.IncludeInactive {
+		clause += " AND active"
+	}
+	if f.ExcludeDust {
+		clause += " AND NOT is_dust"
+	}
+	return clause
+}
+
+// TransactionFilter narrows a transaction listing. Zero values mean "no constraint".
+type TransactionFilter struct {
+	Platform  models.Platform
+	AccountID string
+	Symbol    string
+	Type      models.TransactionType
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+	ListOptions
+}
+
+// ExecutionFilter narrows a workflow execution listing. Zero values mean "no constraint".
+type ExecutionFilter struct {
+	Status         models.WorkflowExecutionStatus
+	SourceID       string
+	VideoID        string
+	CompletedAfter time.Time // exclusive
+	Limit          int
+	Sort           SortOption
+}
+
+// sqlWhere returns the WHERE clause selecting userID's executions that meet this filter's
+// constraints, which ignore Limit and Sort, along with its arguments
+func (f ExecutionFilter) sqlWhere(userID string) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{userID}
+	addCondition := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	if f.Status != "" {
+		addCondition("status = $%d", f.Status)
+	}
+	if f.SourceID != "" {
+		addCondition("source_id = $%d", f.SourceID)
+	}
+	if f.VideoID != "" {
+		addCondition("video_id = $%d", f.VideoID)
+	}
+	if !f.CompletedAfter.IsZero() {
+		addCondition("completed_at > $%d", f.CompletedAfter.UTC())
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// matches reports whether an execution meets this filter's constraints
+func (f ExecutionFilter) matches(e *models.WorkflowExecution) bool {
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if f.SourceID != "" && e.SourceID != f.SourceID {
+		return false
+	}
+	if f.VideoID != "" && e.VideoID != f.VideoID {
+		return false
+	}
+	if !f.CompletedAfter.IsZero() && !e.CompletedAt.After(f.CompletedAfter) {
+		return false
+	}
+	return true
+}
+
+// executionKeysetOrder orders executions for cursor pagination. Both keys descend so that one
+// row comparison finds the executions after a cursor.
+const executionKeysetOrder = " ORDER BY created_at DESC, id DESC"
+
+var errSortedExecutionPage = errors.New("cursor pagination cannot be combined with a sort")
+
+// executionCreatedAt returns the creation time to store for a new execution: its own, or now
+// if it has none. It is truncated to whole seconds, which is what a cursor holds, so the stores
+// write it themselves rather than leave it to the database clock.
+func executionCreatedAt(e *models.WorkflowExecution) time.Time {
+	if e.CreatedAt.IsZero() {
+		return models.Now()
+	}
+	return models.NormalizeTime(e.CreatedAt)
+}
+
+// executionCursor is the position of the last execution on a page. Its time is read back from
+// the store, and matches the stored value exactly because every store writes whole-second
+// creation times; see executionCreatedAt.
+type executionCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// cursorAfter returns the cursor for the page after e
+func cursorAfter(e *models.WorkflowExecution) string {
+	raw := e.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + e.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseExecutionCursor decodes a cursor returned by cursorAfter
+func parseExecutionCursor(cursor string) (executionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return executionCursor{}, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return executionCursor{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return executionCursor{}, ErrInvalidCursor
+	}
+	return executionCursor{CreatedAt: t.UTC(), ID: id}, nil
+}
+
+// before reports whether e comes after this cursor in executionKeysetOrder
+func (c executionCursor) before(e *models.WorkflowExecution) bool {
+	if !e.CreatedAt.Equal(c.CreatedAt) {
+		return e.CreatedAt.Before(c.CreatedAt)
+	}
+	return e.ID < c.ID
+}
+
+// sqlKeysetWhere is sqlWhere narrowed to the executions after cursor, if it is not empty
+func (f ExecutionFilter) sqlKeysetWhere(cursor, userID string) (string, []interface{}, error) {
+	if f.Sort.Field != "" {
+		return "", nil, errSortedExecutionPage
+	}
+	where, args := f.sqlWhere(userID)
+	if cursor == "" {
+		return where, args, nil
+	}
+	after, err := parseExecutionCursor(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, after.CreatedAt, after.ID)
+	condition := fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	return where + " AND " + condition, args, nil
+}
+
+// sqlKeysetLimit returns the LIMIT clause for a page, which fetches one extra row so that
+// nextPage can tell whether another page follows
+func (f ExecutionFilter) sqlKeysetLimit(args []interface{}) (string, []interface{}) {
+	if f.Limit <= 0 {
+		return "", args
+	}
+	args = append(args, f.Limit+1)
+	return fmt.Sprintf(" LIMIT $%d", len(args)), args
+}
+
+// nextPage trims the extra row fetched for a page and returns the cursor for the next one
+func (f ExecutionFilter) nextPage(executions []*models.WorkflowExecution) ([]*models.WorkflowExecution, string) {
+	if f.Limit <= 0 || len(executions) <= f.Limit {
+		return executions, ""
+	}
+	executions = executions[:f.Limit]
+	return executions, cursorAfter(executions[len(executions)-1])
+}
@@ -1,66 +1,255 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"0xnetworth/backend/internal/models"
 )
 
-// Store defines the interface for data storage operations
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidCursor is returned for a pagination cursor that the store did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PoolStats describes a store's database connection pool
+type PoolStats struct {
+	AcquiredConns int `json:"acquired_conns"` // Connections in use by queries
+	IdleConns     int `json:"idle_conns"`
+	TotalConns    int `json:"total_conns"`
+	MaxConns      int `json:"max_conns"` // 0 means unlimited
+}
+
+// PoolStatsProvider is implemented by stores backed by a connection pool
+type PoolStatsProvider interface {
+	PoolStats() PoolStats
+}
+
+// Store defines the interface for data storage operations.
+// Financial, sync and workflow operations are scoped to the store's user; call ForUser to
+// obtain a view for a specific user.
+// Lookups by ID and deletes return ErrNotFound when the record does not exist, or belongs to
+// another user. Record IDs are unique across users: writing an ID another user owns changes
+// nothing, and upserts that report a result report UpsertSkipped.
+// Data operations take the caller's context and stop early once it is cancelled.
 type Store interface {
+	// User scoping
+	ForUser(userID string) Store
+	UserID() string
+
+	// WithTransaction runs fn against a view of the store whose writes are committed together
+	// when fn returns nil and discarded when it returns an error. fn must use only tx; the
+	// in-memory store blocks other callers until fn returns.
+	WithTransaction(ctx context.Context, fn func(tx Store) error) error
+	// DeleteRecords deletes every record of kind that the store's user owns, returning how many
+	// were deleted.
+	DeleteRecords(ctx context.Context, kind RecordKind) (int, error)
+
+	// HealthCheck reports whether the store can serve requests
+	HealthCheck(ctx context.Context) error
+
+	// User operations
+	GetUserByTokenHash(ctx context.Context, tokenHash string) (*models.User, error)
+	CreateOrUpdateUser(ctx context.Context, user *models.User) error
+	// ListUserIDs returns the ID of every user in order, whichever user the store is scoped to
+	ListUserIDs(ctx context.Context) ([]string, error)
+
 	// Portfolio operations
-	GetAllPortfolios() []*models.Portfolio
-	GetPortfoliosByPlatform(platform models.Platform) []*models.Portfolio
-	GetPortfolioByID(id string) (*models.Portfolio, bool)
-	CreateOrUpdatePortfolio(portfolio *models.Portfolio)
-	DeletePortfolio(id string) bool
-
-	// Investment operations
-	GetAllInvestments() []*models.Investment
-	GetInvestmentsByAccount(accountID string) []*models.Investment
-	GetInvestmentsByPlatform(platform models.Platform) []*models.Investment
-	CreateOrUpdateInvestment(investment *models.Investment)
-	DeleteInvestment(id string) bool
+	// GetAllPortfolios returns a page of portfolios in portfolio order unless sorted otherwise
+	// (see PortfolioSortFields)
+	GetAllPortfolios(ctx context.Context, opts ListOptions, order SortOption) ([]*models.Portfolio, int, error)
+	CountPortfolios(ctx context.Context) (int, error)
+	GetPortfoliosByPlatform(ctx context.Context, platform models.Platform) ([]*models.Portfolio, error)
+	GetPortfolioByID(ctx context.Context, id string) (*models.Portfolio, error)
+	// GetPortfolioValues returns the value and number of active holdings of every portfolio, in
+	// portfolio order. Portfolios without holdings are included with a value of 0.
+	GetPortfolioValues(ctx context.Context) ([]*models.PortfolioValue, error)
+	// CreateOrUpdatePortfolio reports whether the portfolio was created, updated or left unchanged
+	CreateOrUpdatePortfolio(ctx context.Context, portfolio *models.Portfolio) (UpsertResult, error)
+	UpdatePortfolioMetadata(ctx context.Context, id string, update models.PortfolioMetadataUpdate) (*models.Portfolio, error)
+	// DeletePortfolio also deletes the portfolio's transactions, so none are left pointing at a missing account
+	DeletePortfolio(ctx context.Context, id string) error
+
+	// Account operations; listings are ordered by platform, currency and ID
+	GetAllAccounts(ctx context.Context) ([]*models.Account, error)
+	GetAccountsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Account, error)
+	GetAccountByID(ctx context.Context, id string) (*models.Account, error)
+	CreateOrUpdateAccount(ctx context.Context, account *models.Account) (UpsertResult, error)
+	DeleteAccount(ctx context.Context, id string) error
+
+	// Investment operations. Listings return the investments filter matches (see
+	// InvestmentFilter), and writing an investment marks it active again.
+	// GetAllInvestments returns a page of investments, sorted by order if it names a field (see
+	// InvestmentSortFields)
+	GetAllInvestments(ctx context.Context, opts ListOptions, order SortOption, filter InvestmentFilter) ([]*models.Investment, int, error)
+	// CountInvestments counts active investments, only those on platform unless it is empty
+	CountInvestments(ctx context.Context, platform models.Platform) (int, error)
+	GetInvestmentsByAccount(ctx context.Context, accountID string, filter InvestmentFilter) ([]*models.Investment, error)
+	GetInvestmentsByPlatform(ctx context.Context, platform models.Platform, filter InvestmentFilter) ([]*models.Investment, error)
+	// GetInvestmentsBySymbol returns investments in symbol on any account or platform, matching case-insensitively
+	GetInvestmentsBySymbol(ctx context.Context, symbol string, filter InvestmentFilter) ([]*models.Investment, error)
+	CreateOrUpdateInvestment(ctx context.Context, investment *models.Investment) error
+	// CreateOrUpdateInvestments writes all investments or none, counting how many were created,
+	// updated, left unchanged or skipped
+	CreateOrUpdateInvestments(ctx context.Context, investments []*models.Investment) (UpsertCounts, error)
+	// DeactivateMissingInvestments marks the active investments on platform held in accountIDs
+	// whose IDs are not in keepIDs as inactive as of at, returning how many were deactivated.
+	// Pass only accounts whose holdings were fully synced.
+	DeactivateMissingInvestments(ctx context.Context, platform models.Platform, accountIDs, keepIDs []string, at time.Time) (int, error)
+	// DeactivateInvestments marks the active investments with the given IDs as inactive as of at,
+	// returning how many were deactivated
+	DeactivateInvestments(ctx context.Context, ids []string, at time.Time) (int, error)
+	// UpdateInvestmentPrice reprices the active investment with the given ID as of at, valuing
+	// it at its stored quantity, or returns ErrNotFound if there is none. Only the price, value,
+	// unrealized gain and stale flag change, so a concurrent sync's writes are never undone.
+	UpdateInvestmentPrice(ctx context.Context, id string, price float64, at time.Time) error
+	DeleteInvestment(ctx context.Context, id string) error
 
 	// NetWorth operations
-	GetNetWorth() *models.NetWorth
-	UpdateNetWorth(networth *models.NetWorth)
-	RecalculateNetWorth() *models.NetWorth
+	GetNetWorth(ctx context.Context) (*models.NetWorth, error)
+	UpdateNetWorth(ctx context.Context, networth *models.NetWorth) error
+	RecalculateNetWorth(ctx context.Context) (*models.NetWorth, error)
+
+	// Net worth history operations
+	SaveNetWorthSnapshot(ctx context.Context, networth *models.NetWorth) error
+	// GetNetWorthSnapshots returns snapshots taken in [from, to), oldest first; zero bounds are open
+	GetNetWorthSnapshots(ctx context.Context, from, to time.Time) ([]*models.NetWorthSnapshot, error)
+	// SaveInvestmentHistory records points in the per-symbol history, all or none. A point
+	// replaces any stored for the same platform, symbol and timestamp.
+	SaveInvestmentHistory(ctx context.Context, points []*models.InvestmentHistoryPoint) error
+	// GetInvestmentHistory returns the points for symbol on every platform recorded in [from, to),
+	// oldest first; zero bounds are open and symbol matches case-insensitively
+	GetInvestmentHistory(ctx context.Context, symbol string, from, to time.Time) ([]*models.InvestmentHistoryPoint, error)
+
+	// Transaction operations
+	CreateOrUpdateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactionsByAccount(ctx context.Context, accountID string) ([]*models.Transaction, error)
+	GetTransactionsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Transaction, error)
+	// GetTransactionsBetween returns transactions in [from, to), newest first; zero bounds are open
+	GetTransactionsBetween(ctx context.Context, from, to time.Time) ([]*models.Transaction, error)
+	ListTransactions(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, int, error)
+	GetTransactionSummary(ctx context.Context, year int) (*models.TransactionSummary, error)
 
 	// Sync metadata operations
-	GetLastSyncTime() time.Time
-	SetLastSyncTime(t time.Time)
+	// GetLastSyncTime returns when platform last synced successfully, or the zero time if never
+	GetLastSyncTime(ctx context.Context, platform models.Platform) (time.Time, error)
+	// GetSyncRecord returns the latest sync attempt on platform
+	GetSyncRecord(ctx context.Context, platform models.Platform) (*models.SyncRecord, error)
+	// RecordSyncResult records a sync attempt on platform made at at, with the records it wrote.
+	// A failed attempt stores errMsg and keeps the time of the last successful sync.
+	RecordSyncResult(ctx context.Context, platform models.Platform, status models.SyncStatus, errMsg string, counts models.SyncCounts, at time.Time) error
+
+	// Plaid item operations. Items carry their access token encrypted; the store never sees it
+	// in the clear. GetPlaidItems lists items oldest first.
+	GetPlaidItems(ctx context.Context) ([]*models.PlaidItem, error)
+	GetPlaidItem(ctx context.Context, id string) (*models.PlaidItem, error)
+	CreateOrUpdatePlaidItem(ctx context.Context, item *models.PlaidItem) error
+	// SetPlaidItemAccounts records the accounts an item's sync stored
+	SetPlaidItemAccounts(ctx context.Context, id string, accountIDs []string) error
+	// SetPlaidItemStatus records whether an item can be synced. syncedAt, if not nil, is when
+	// it last synced successfully; otherwise the stored time is kept.
+	SetPlaidItemStatus(ctx context.Context, id string, status models.PlaidItemStatus, syncedAt *time.Time) error
+	DeletePlaidItem(ctx context.Context, id string) error
+	// GetPlaidItemUserID returns the user who linked an item, whichever user the store is
+	// scoped to, for Plaid webhooks, which name the item but not its user
+	GetPlaidItemUserID(ctx context.Context, id string) (string, error)
 
 	// YouTube Source operations
-	GetAllYouTubeSources() []*models.YouTubeSource
-	GetYouTubeSourceByID(id string) (*models.YouTubeSource, bool)
-	CreateOrUpdateYouTubeSource(source *models.YouTubeSource)
-	DeleteYouTubeSource(id string) bool
+	GetAllYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error)
+	// GetEnabledYouTubeSources returns the sources that are enabled, newest first
+	GetEnabledYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error)
+	// GetYouTubeSourcesByType returns the sources of a type, newest first
+	GetYouTubeSourcesByType(ctx context.Context, sourceType models.YouTubeSourceType) ([]*models.YouTubeSource, error)
+	GetYouTubeSourceByID(ctx context.Context, id string) (*models.YouTubeSource, error)
+	CreateOrUpdateYouTubeSource(ctx context.Context, source *models.YouTubeSource) error
+	DeleteYouTubeSource(ctx context.Context, id string) error
+
+	// YouTube quota operations. Usage is shared by every user, by quota day (YYYY-MM-DD).
+	// GetYouTubeQuotaUsage returns the units used on day, 0 if none were recorded
+	GetYouTubeQuotaUsage(ctx context.Context, day string) (int, error)
+	// AddYouTubeQuotaUsage adds units to the usage of day and returns the new total
+	AddYouTubeQuotaUsage(ctx context.Context, day string, units int) (int, error)
 
 	// Video Transcript operations
-	CreateOrUpdateTranscript(transcript *models.VideoTranscript)
-	GetTranscriptByID(id string) (*models.VideoTranscript, bool)
-	GetTranscriptsByVideoID(videoID string) []*models.VideoTranscript
+	CreateOrUpdateTranscript(ctx context.Context, transcript *models.VideoTranscript) error
+	GetTranscriptByID(ctx context.Context, id string) (*models.VideoTranscript, error)
+	// GetTranscriptsByVideoID returns every transcript stored for a video, newest first
+	GetTranscriptsByVideoID(ctx context.Context, videoID string) ([]*models.VideoTranscript, error)
+	// GetLatestTranscriptByVideoID returns the newest transcript stored for a video
+	GetLatestTranscriptByVideoID(ctx context.Context, videoID string) (*models.VideoTranscript, error)
+	// GetTranscriptsBySourceID returns up to limit transcripts for a source, newest first; a limit of zero or less returns all
+	GetTranscriptsBySourceID(ctx context.Context, sourceID string, limit int) ([]*models.VideoTranscript, error)
+	// ListTranscripts returns a page of all transcripts ordered by ID
+	ListTranscripts(ctx context.Context, opts ListOptions) ([]*models.VideoTranscript, error)
+	// SearchTranscripts returns up to limit transcripts whose title or text contains every word
+	// of query, ignoring case, best matches first; a limit of zero or less returns all.
+	// PostgreSQL matches stemmed words, so "indicators" also finds "indicator".
+	SearchTranscripts(ctx context.Context, query string, limit int) ([]*models.TranscriptSearchResult, error)
+
+	// PruneTranscriptsOlderThan prunes transcripts created before cutoff, except those referenced by
+	// an execution completed at or after cutoff, and returns how many it pruned. With keepMetadata
+	// only the text is cleared, so the transcript still resolves; otherwise the transcript is
+	// deleted along with its analyses and recommendations, and executions lose their references.
+	PruneTranscriptsOlderThan(ctx context.Context, cutoff time.Time, keepMetadata bool) (int, error)
 
 	// Market Analysis operations
-	CreateOrUpdateMarketAnalysis(analysis *models.MarketAnalysis)
-	GetMarketAnalysisByID(id string) (*models.MarketAnalysis, bool)
-	GetMarketAnalysesByTranscriptID(transcriptID string) []*models.MarketAnalysis
+	CreateOrUpdateMarketAnalysis(ctx context.Context, analysis *models.MarketAnalysis) error
+	GetMarketAnalysisByID(ctx context.Context, id string) (*models.MarketAnalysis, error)
+	GetMarketAnalysesByTranscriptID(ctx context.Context, transcriptID string) ([]*models.MarketAnalysis, error)
+	// ListMarketAnalyses returns a page of all market analyses ordered by ID
+	ListMarketAnalyses(ctx context.Context, opts ListOptions) ([]*models.MarketAnalysis, error)
 
 	// Recommendation operations
-	CreateOrUpdateRecommendation(recommendation *models.Recommendation)
-	GetRecommendationByID(id string) (*models.Recommendation, bool)
-	GetRecommendationsByAnalysisID(analysisID string) []*models.Recommendation
+	CreateOrUpdateRecommendation(ctx context.Context, recommendation *models.Recommendation) error
+	GetRecommendationByID(ctx context.Context, id string) (*models.Recommendation, error)
+	GetRecommendationsByAnalysisID(ctx context.Context, analysisID string) ([]*models.Recommendation, error)
+	// ListRecommendations returns a page of all recommendations ordered by ID
+	ListRecommendations(ctx context.Context, opts ListOptions) ([]*models.Recommendation, error)
 
 	// Workflow Execution operations
-	CreateOrUpdateWorkflowExecution(execution *models.WorkflowExecution)
-	GetWorkflowExecutionByID(id string) (*models.WorkflowExecution, bool)
-	GetAllWorkflowExecutions() []*models.WorkflowExecution
-	GetWorkflowExecutionsBySourceID(sourceID string) []*models.WorkflowExecution
-	GetWorkflowExecutionsByVideoID(videoID string) []*models.WorkflowExecution
-	
+	CreateOrUpdateWorkflowExecution(ctx context.Context, execution *models.WorkflowExecution) error
+	// TryClaimVideoExecution creates execution, which must have a VideoID, as the one processing
+	// its video, unless another execution is processing the video or has completed it. It returns
+	// the ID of the execution holding the video and whether execution claimed it. Of concurrent
+	// callers claiming the same video exactly one succeeds. Saving an execution as failed
+	// releases its claim.
+	TryClaimVideoExecution(ctx context.Context, execution *models.WorkflowExecution) (string, bool, error)
+	GetWorkflowExecutionByID(ctx context.Context, id string) (*models.WorkflowExecution, error)
+	GetAllWorkflowExecutions(ctx context.Context) ([]*models.WorkflowExecution, error)
+	ListWorkflowExecutions(ctx context.Context, filter ExecutionFilter) ([]*models.WorkflowExecution, error)
+	// PageWorkflowExecutions returns up to filter.Limit executions matching filter, newest first,
+	// starting after cursor (the first page if it is empty). nextCursor is empty on the last page.
+	// The page is found with keyset predicates rather than an offset, so it cannot be sorted.
+	PageWorkflowExecutions(ctx context.Context, filter ExecutionFilter, cursor string) (executions []*models.WorkflowExecution, nextCursor string, err error)
+	// CountWorkflowExecutions counts the executions matching filter, ignoring its Limit
+	CountWorkflowExecutions(ctx context.Context, filter ExecutionFilter) (int, error)
+	// GetCompletedWorkflowExecutionsSince returns completed executions with a recommendation that
+	// completed after since, newest first. A zero since or limit means no bound.
+	GetCompletedWorkflowExecutionsSince(ctx context.Context, since time.Time, limit int) ([]*models.WorkflowExecution, error)
+	GetWorkflowExecutionsBySourceID(ctx context.Context, sourceID string) ([]*models.WorkflowExecution, error)
+	GetWorkflowExecutionsByVideoID(ctx context.Context, videoID string) ([]*models.WorkflowExecution, error)
+	// DeleteWorkflowExecution deletes an execution and drops its ID from aggregated recommendations.
+	// With cascade it also deletes the execution's transcript, analysis and recommendation,
+	// except any that another execution still references.
+	DeleteWorkflowExecution(ctx context.Context, id string, cascade bool) error
+	// PruneWorkflowExecutions deletes executions with status that started before olderThan (a zero
+	// olderThan means no bound), except the keep most recent of each source, and returns how many
+	// it deleted. Their artifacts are deleted too unless another execution references them.
+	PruneWorkflowExecutions(ctx context.Context, status models.WorkflowExecutionStatus, olderThan time.Time, keep int) (int, error)
+	// AddWorkflowExecutionEvent appends an event to an execution's event log. The execution
+	// must exist; its events are deleted with it.
+	AddWorkflowExecutionEvent(ctx context.Context, event *models.WorkflowExecutionEvent) error
+	// GetWorkflowExecutionEvents returns an execution's events, oldest first
+	GetWorkflowExecutionEvents(ctx context.Context, executionID string) ([]*models.WorkflowExecutionEvent, error)
+	// GetRecommendationSummaryData returns executions completed after since that have a stored
+	// recommendation, joined with that recommendation and their analysis, newest first
+	GetRecommendationSummaryData(ctx context.Context, since time.Time) ([]*models.RecommendationSummaryRow, error)
+
 	// Aggregated Recommendation operations
-	GetLatestAggregatedRecommendation() (*models.AggregatedRecommendation, bool)
-	CreateOrUpdateAggregatedRecommendation(rec *models.AggregatedRecommendation) error
+	GetAggregatedRecommendations(ctx context.Context, limit int) ([]*models.AggregatedRecommendation, error)
+	GetLatestAggregatedRecommendation(ctx context.Context) (*models.AggregatedRecommendation, error)
+	CreateOrUpdateAggregatedRecommendation(ctx context.Context, rec *models.AggregatedRecommendation) error
 }
 
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// seedOwnerData stores one record of each financial kind for the default user
+func seedOwnerData(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.CreateOrUpdatePortfolio(ctx, &models.Portfolio{ID: "p1", Platform: models.PlatformCoinbase, Name: "Owner"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateOrUpdateAccount(ctx, &models.Account{ID: "a1", Platform: models.PlatformCoinbase, Name: "Owner BTC", Currency: "BTC", Available: 1, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+		{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60000, Price: 60000, Currency: "USD"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateOrUpdateTransaction(ctx, &models.Transaction{
+		ID: "tx1", AccountID: "a1", Platform: models.PlatformCoinbase, Type: models.TransactionTypeBuy,
+		Amount: 60000, Currency: "USD", Timestamp: testTime(0),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateOrUpdatePlaidItem(ctx, &models.PlaidItem{ID: "item-1", InstitutionName: "Bank", Platform: models.PlatformCoinbase, EncryptedAccessToken: "sealed"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RecalculateNetWorth(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveNetWorthSnapshot(ctx, &models.NetWorth{TotalValue: 60000, Currency: "USD", LastCalculated: testTime(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecordSyncResult(ctx, models.PlatformCoinbase, models.SyncStatusSuccess, "", models.SyncCounts{Portfolios: 1}, testTime(0)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// forOtherUser provisions a second user and returns the store scoped to them
+func forOtherUser(t *testing.T, s Store) Store {
+	t.Helper()
+	if err := s.CreateOrUpdateUser(context.Background(), &models.User{ID: "alice", Name: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	return s.ForUser("alice")
+}
+
+func TestIsolationReadsSeeOnlyOwnData(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		seedOwnerData(t, s)
+		other := forOtherUser(t, s)
+
+		if portfolios, total, err := other.GetAllPortfolios(ctx, ListOptions{}, SortOption{}); err != nil || len(portfolios) != 0 || total != 0 {
+			t.Fatalf("other user's portfolios = %d (total %d), %v; want none", len(portfolios), total, err)
+		}
+		if _, err := other.GetPortfolioByID(ctx, "p1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's GetPortfolioByID: %v, want ErrNotFound", err)
+		}
+		if accounts, err := other.GetAllAccounts(ctx); err != nil || len(accounts) != 0 {
+			t.Fatalf("other user's accounts = %d, %v; want none", len(accounts), err)
+		}
+		if investments, _, err := other.GetAllInvestments(ctx, ListOptions{}, SortOption{}, InvestmentFilter{IncludeInactive: true}); err != nil || len(investments) != 0 {
+			t.Fatalf("other user's investments = %d, %v; want none", len(investments), err)
+		}
+		if investments, err := other.GetInvestmentsBySymbol(ctx, "BTC", InvestmentFilter{}); err != nil || len(investments) != 0 {
+			t.Fatalf("other user's BTC holdings = %d, %v; want none", len(investments), err)
+		}
+		if transactions, total, err := other.ListTransactions(ctx, TransactionFilter{}); err != nil || len(transactions) != 0 || total != 0 {
+			t.Fatalf("other user's transactions = %d (total %d), %v; want none", len(transactions), total, err)
+		}
+		if items, err := other.GetPlaidItems(ctx); err != nil || len(items) != 0 {
+			t.Fatalf("other user's Plaid items = %d, %v; want none", len(items), err)
+		}
+		if snapshots, err := other.GetNetWorthSnapshots(ctx, testTime(-1), testTime(1)); err != nil || len(snapshots) != 0 {
+			t.Fatalf("other user's snapshots = %d, %v; want none", len(snapshots), err)
+		}
+		if _, err := other.GetSyncRecord(ctx, models.PlatformCoinbase); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's GetSyncRecord: %v, want ErrNotFound", err)
+		}
+		networth, err := other.RecalculateNetWorth(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if networth.TotalValue != 0 || networth.AccountCount != 0 {
+			t.Fatalf("other user's net worth %v over %d accounts, want 0", networth.TotalValue, networth.AccountCount)
+		}
+
+		// Plaid webhooks name only the item, so its owner is found from any user's view
+		if userID, err := other.GetPlaidItemUserID(ctx, "item-1"); err != nil || userID != models.DefaultUserID {
+			t.Fatalf("GetPlaidItemUserID = %q, %v; want the default user", userID, err)
+		}
+	})
+}
+
+func TestIsolationWritesToAnotherUsersIDs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		seedOwnerData(t, s)
+		other := forOtherUser(t, s)
+
+		if result, err := other.CreateOrUpdatePortfolio(ctx, &models.Portfolio{ID: "p1", Platform: models.PlatformCoinbase, Name: "Taken"}); err != nil || result != UpsertSkipped {
+			t.Fatalf("portfolio write to another user's ID = %s, %v; want skipped", result, err)
+		}
+		if result, err := other.CreateOrUpdateAccount(ctx, &models.Account{ID: "a1", Platform: models.PlatformCoinbase, Name: "Taken", Currency: "BTC"}); err != nil || result != UpsertSkipped {
+			t.Fatalf("account write to another user's ID = %s, %v; want skipped", result, err)
+		}
+		counts, err := other.CreateOrUpdateInvestments(ctx, []*models.Investment{
+			{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 99, Value: 1, Price: 1, Currency: "USD"},
+			{ID: "i2", AccountID: "a2", Platform: models.PlatformCoinbase, Symbol: "ETH", Quantity: 1, Value: 3000, Price: 3000, Currency: "USD"},
+		})
+		if err != nil || counts != (UpsertCounts{Created: 1, Skipped: 1}) {
+			t.Fatalf("investment batch over another user's ID = %+v, %v; want 1 created and 1 skipped", counts, err)
+		}
+		if err := other.CreateOrUpdateInvestment(ctx, &models.Investment{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 99, Currency: "USD"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := other.CreateOrUpdateTransaction(ctx, &models.Transaction{
+			ID: "tx1", AccountID: "a1", Platform: models.PlatformCoinbase, Type: models.TransactionTypeSell,
+			Amount: 1, Currency: "USD", Timestamp: testTime(0),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := other.CreateOrUpdatePlaidItem(ctx, &models.PlaidItem{ID: "item-1", InstitutionName: "Bank", Platform: models.PlatformCoinbase, EncryptedAccessToken: "other"}); err == nil {
+			t.Fatal("linking another user's Plaid item succeeded")
+		}
+
+		// Nothing the other user wrote reached the owner's records, nor shows up for them
+		if p, err := s.GetPortfolioByID(ctx, "p1"); err != nil || p.Name != "Owner" {
+			t.Fatalf("owner's portfolio after other user's write = %+v, %v", p, err)
+		}
+		if a, err := s.GetAccountByID(ctx, "a1"); err != nil || a.Name != "Owner BTC" {
+			t.Fatalf("owner's account after other user's write = %+v, %v", a, err)
+		}
+		if investments, err := s.GetInvestmentsBySymbol(ctx, "BTC", InvestmentFilter{}); err != nil || len(investments) != 1 || investments[0].Quantity != 1 {
+			t.Fatalf("owner's BTC holdings after other user's write = %+v, %v", investments, err)
+		}
+		if transactions, _, err := s.ListTransactions(ctx, TransactionFilter{}); err != nil || len(transactions) != 1 || transactions[0].Type != models.TransactionTypeBuy {
+			t.Fatalf("owner's transactions after other user's write = %+v, %v", transactions, err)
+		}
+		if _, err := other.GetPortfolioByID(ctx, "p1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's GetPortfolioByID after skipped write: %v, want ErrNotFound", err)
+		}
+		if investments, err := other.GetInvestmentsBySymbol(ctx, "BTC", InvestmentFilter{}); err != nil || len(investments) != 0 {
+			t.Fatalf("other user's BTC holdings after skipped write = %d, %v; want none", len(investments), err)
+		}
+		if transactions, _, err := other.ListTransactions(ctx, TransactionFilter{}); err != nil || len(transactions) != 0 {
+			t.Fatalf("other user's transactions after skipped write = %d, %v; want none", len(transactions), err)
+		}
+	})
+}
+
+func TestIsolationChangesToAnotherUsersRecords(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		seedOwnerData(t, s)
+		other := forOtherUser(t, s)
+
+		custodian := "Other"
+		if _, err := other.UpdatePortfolioMetadata(ctx, "p1", models.PortfolioMetadataUpdate{Custodian: &custodian}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("UpdatePortfolioMetadata of another user's portfolio: %v, want ErrNotFound", err)
+		}
+		if n, err := other.DeactivateInvestments(ctx, []string{"i1"}, testTime(0)); err != nil || n != 0 {
+			t.Fatalf("DeactivateInvestments of another user's holding = %d, %v; want 0", n, err)
+		}
+		if err := other.SetPlaidItemAccounts(ctx, "item-1", []string{"a1"}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("SetPlaidItemAccounts of another user's item: %v, want ErrNotFound", err)
+		}
+		if err := other.SetPlaidItemStatus(ctx, "item-1", models.PlaidItemStatusLoginRequired, nil); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("SetPlaidItemStatus of another user's item: %v, want ErrNotFound", err)
+		}
+		for name, del := range map[string]func() error{
+			"portfolio":  func() error { return other.DeletePortfolio(ctx, "p1") },
+			"account":    func() error { return other.DeleteAccount(ctx, "a1") },
+			"investment": func() error { return other.DeleteInvestment(ctx, "i1") },
+			"plaid item": func() error { return other.DeletePlaidItem(ctx, "item-1") },
+		} {
+			if err := del(); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("deleting another user's %s: %v, want ErrNotFound", name, err)
+			}
+		}
+
+		// Deleting every record of a kind only deletes the user's own
+		for _, kind := range []RecordKind{RecordPortfolios, RecordAccounts, RecordInvestments, RecordTransactions, RecordNetWorthSnapshots} {
+			if n, err := other.DeleteRecords(ctx, kind); err != nil || n != 0 {
+				t.Fatalf("other user's DeleteRecords(%s) = %d, %v; want 0", kind, n, err)
+			}
+		}
+
+		networth, err := s.RecalculateNetWorth(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if networth.TotalValue != 60000 {
+			t.Fatalf("owner's net worth %v after other user's changes, want 60000", networth.TotalValue)
+		}
+		if _, err := s.GetPlaidItem(ctx, "item-1"); err != nil {
+			t.Fatalf("owner's Plaid item after other user's changes: %v", err)
+		}
+	})
+}
+
+func TestIsolationWorkflowRecords(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "src-1", Type: models.YouTubeSourceTypeChannel, Name: "Owner's channel", URL: "https://www.youtube.com/@owner", Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		owned := &models.WorkflowExecution{ID: "e1", Status: models.WorkflowStatusProcessing, VideoID: "v1", VideoURL: "https://youtu.be/v1", SourceID: "src-1"}
+		if _, claimed, err := s.TryClaimVideoExecution(ctx, owned); err != nil || !claimed {
+			t.Fatalf("owner's claim = %v, %v; want claimed", claimed, err)
+		}
+		other := forOtherUser(t, s)
+
+		if sources, err := other.GetAllYouTubeSources(ctx); err != nil || len(sources) != 0 {
+			t.Fatalf("other user's YouTube sources = %d, %v; want none", len(sources), err)
+		}
+		if sources, err := other.GetEnabledYouTubeSources(ctx); err != nil || len(sources) != 0 {
+			t.Fatalf("other user's enabled YouTube sources = %d, %v; want none", len(sources), err)
+		}
+		if _, err := other.GetYouTubeSourceByID(ctx, "src-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's GetYouTubeSourceByID: %v, want ErrNotFound", err)
+		}
+		if executions, err := other.GetAllWorkflowExecutions(ctx); err != nil || len(executions) != 0 {
+			t.Fatalf("other user's workflow executions = %d, %v; want none", len(executions), err)
+		}
+		if _, err := other.GetWorkflowExecutionByID(ctx, "e1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's GetWorkflowExecutionByID: %v, want ErrNotFound", err)
+		}
+		if err := other.AddWorkflowExecutionEvent(ctx, &models.WorkflowExecutionEvent{ExecutionID: "e1", Type: models.WorkflowEventFailed, Timestamp: testTime(0)}); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("other user's event on another user's execution: %v, want ErrNotFound", err)
+		}
+		if err := other.DeleteYouTubeSource(ctx, "src-1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleting another user's YouTube source: %v, want ErrNotFound", err)
+		}
+		if err := other.DeleteWorkflowExecution(ctx, "e1", true); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("deleting another user's workflow execution: %v, want ErrNotFound", err)
+		}
+		for _, kind := range []RecordKind{RecordYouTubeSources, RecordWorkflowExecutions} {
+			if n, err := other.DeleteRecords(ctx, kind); err != nil || n != 0 {
+				t.Fatalf("other user's DeleteRecords(%s) = %d, %v; want 0", kind, n, err)
+			}
+		}
+
+		// Writes to the owner's IDs leave the owner's records untouched
+		if err := other.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "src-1", Type: models.YouTubeSourceTypeChannel, Name: "Taken", URL: "https://www.youtube.com/@other"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := other.CreateOrUpdateWorkflowExecution(ctx, &models.WorkflowExecution{ID: "e1", Status: models.WorkflowStatusFailed, VideoID: "v1", VideoURL: "https://youtu.be/v1"}); err != nil {
+			t.Fatal(err)
+		}
+		if source, err := s.GetYouTubeSourceByID(ctx, "src-1"); err != nil || source.Name != "Owner's channel" {
+			t.Fatalf("owner's source after other user's write = %+v, %v", source, err)
+		}
+		if execution, err := s.GetWorkflowExecutionByID(ctx, "e1"); err != nil || execution.Status != models.WorkflowStatusProcessing {
+			t.Fatalf("owner's execution after other user's write = %+v, %v", execution, err)
+		}
+
+		// Video claims are per user, so the other user can process the same video
+		holder, claimed, err := other.TryClaimVideoExecution(ctx, &models.WorkflowExecution{ID: "e2", Status: models.WorkflowStatusProcessing, VideoID: "v1", VideoURL: "https://youtu.be/v1"})
+		if err != nil || !claimed || holder != "e2" {
+			t.Fatalf("other user's claim on the owner's video = %q, %v, %v; want e2 claimed", holder, claimed, err)
+		}
+		if executions, err := s.GetWorkflowExecutionsByVideoID(ctx, "v1"); err != nil || len(executions) != 1 || executions[0].ID != "e1" {
+			t.Fatalf("owner's executions of v1 = %v, %v; want only e1", executions, err)
+		}
+	})
+}
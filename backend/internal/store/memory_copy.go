@@ -0,0 +1,210 @@
+package store
+
+import (
+	"maps"
+	"slices"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// The memory store copies records on the way in and out, so callers never share a struct
+// with the store's maps and can change what they get back without holding s.mu.
+
+// cloneAll copies each record in items in place and returns items
+func cloneAll[T any](items []*T, clone func(*T) *T) []*T {
+	for i, item := range items {
+		items[i] = clone(item)
+	}
+	return items
+}
+
+// clone returns a deep copy of the state with its own lock and no backing file. Callers
+// must hold s.mu.
+func (s *memoryState) clone() *memoryState {
+	c := &memoryState{
+		tenants:      make(map[string]*memoryTenant, len(s.tenants)),
+		users:        cloneMap(s.users, cloneUser),
+		youtubeQuota: maps.Clone(s.youtubeQuota),
+	}
+	for userID, tenant := range s.tenants {
+		t := &memoryTenant{
+			portfolios:        cloneMap(tenant.portfolios, clonePortfolio),
+			accounts:          cloneMap(tenant.accounts, cloneAccount),
+			investments:       cloneMap(tenant.investments, cloneInvestment),
+			transactions:      cloneMap(tenant.transactions, cloneTransaction),
+			networth:          cloneNetWorth(tenant.networth),
+			snapshots:         cloneAll(slices.Clone(tenant.snapshots), cloneSnapshot),
+			syncs:             cloneMap(tenant.syncs, cloneSyncRecord),
+			plaidItems:        cloneMap(tenant.plaidItems, clonePlaidItem),
+			investmentHistory: make(map[string][]*models.InvestmentHistoryPoint, len(tenant.investmentHistory)),
+			youtubeSources:    cloneMap(tenant.youtubeSources, cloneYouTubeSource),
+			transcripts:       cloneMap(tenant.transcripts, cloneTranscript),
+			marketAnalyses:    cloneMap(tenant.marketAnalyses, cloneMarketAnalysis),
+			recommendations:   cloneMap(tenant.recommendations, cloneRecommendation),
+			executions:        cloneMap(tenant.executions, cloneWorkflowExecution),
+			executionEvents:   make(map[string][]*models.WorkflowExecutionEvent, len(tenant.executionEvents)),
+			aggregatedRecs:    cloneAll(slices.Clone(tenant.aggregatedRecs), cloneAggregatedRecommendation),
+		}
+		for symbol, history := range tenant.investmentHistory {
+			t.investmentHistory[symbol] = cloneAll(slices.Clone(history), cloneInvestmentHistoryPoint)
+		}
+		for executionID, events := range tenant.executionEvents {
+			t.executionEvents[executionID] = cloneAll(slices.Clone(events), cloneWorkflowExecutionEvent)
+		}
+		c.tenants[userID] = t
+	}
+	return c
+}
+
+// replaceWith takes the data from other, keeping this state's lock and backing file.
+// Callers must hold s.mu.
+func (s *memoryState) replaceWith(other *memoryState) {
+	s.tenants = other.tenants
+	s.users = other.users
+	s.youtubeQuota = other.youtubeQuota
+}
+
+// cloneMap copies each record of a map into a new map
+func cloneMap[K comparable, T any](records map[K]*T, clone func(*T) *T) map[K]*T {
+	c := make(map[K]*T, len(records))
+	for id, record := range records {
+		c[id] = clone(record)
+	}
+	return c
+}
+
+// pageByID returns copies of the page of records selected by opts, ordered by map key
+func pageByID[T any](records map[string]*T, opts ListOptions, clone func(*T) *T) []*T {
+	ids := slices.Sorted(maps.Keys(records))
+	start, end := opts.window(len(ids))
+	page := make([]*T, 0, end-start)
+	for _, id := range ids[start:end] {
+		page = append(page, clone(records[id]))
+	}
+	return page
+}
+
+// clonePtr returns a pointer to a copy of *p, or nil if p is nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func cloneUser(u *models.User) *models.User {
+	c := *u
+	return &c
+}
+
+func clonePortfolio(p *models.Portfolio) *models.Portfolio {
+	c := *p
+	c.DisplayOrder = clonePtr(p.DisplayOrder)
+	return &c
+}
+
+func clonePlaidItem(item *models.PlaidItem) *models.PlaidItem {
+	c := *item
+	c.AccountIDs = slices.Clone(item.AccountIDs)
+	c.LastSyncedAt = clonePtr(item.LastSyncedAt)
+	return &c
+}
+
+func cloneAccount(a *models.Account) *models.Account {
+	c := *a
+	return &c
+}
+
+func cloneInvestment(inv *models.Investment) *models.Investment {
+	c := *inv
+	c.DeactivatedAt = clonePtr(inv.DeactivatedAt)
+	c.NativeValue = clonePtr(inv.NativeValue)
+	c.CostBasis = clonePtr(inv.CostBasis)
+	c.AverageBuyPrice = clonePtr(inv.AverageBuyPrice)
+	c.FirstAcquiredAt = clonePtr(inv.FirstAcquiredAt)
+	c.UnrealizedGain = clonePtr(inv.UnrealizedGain)
+	c.UnrealizedPLPercent = clonePtr(inv.UnrealizedPLPercent)
+	c.PriceChange24hPct = clonePtr(inv.PriceChange24hPct)
+	c.ValueChange24h = clonePtr(inv.ValueChange24h)
+	return &c
+}
+
+func cloneNetWorth(n *models.NetWorth) *models.NetWorth {
+	c := *n
+	c.ByPlatform = maps.Clone(n.ByPlatform)
+	c.ByAssetType = maps.Clone(n.ByAssetType)
+	c.ByTaxTreatment = maps.Clone(n.ByTaxTreatment)
+	c.Unconverted = maps.Clone(n.Unconverted)
+	c.PriceChange24h = clonePtr(n.PriceChange24h)
+	return &c
+}
+
+func cloneSnapshot(n *models.NetWorthSnapshot) *models.NetWorthSnapshot {
+	c := *n
+	c.ByPlatform = maps.Clone(n.ByPlatform)
+	c.ByAssetType = maps.Clone(n.ByAssetType)
+	c.ByTaxTreatment = maps.Clone(n.ByTaxTreatment)
+	return &c
+}
+
+func cloneInvestmentHistoryPoint(p *models.InvestmentHistoryPoint) *models.InvestmentHistoryPoint {
+	c := *p
+	return &c
+}
+
+func cloneSyncRecord(r *models.SyncRecord) *models.SyncRecord {
+	c := *r
+	c.LastAttempt = clonePtr(r.LastAttempt)
+	c.LastSync = clonePtr(r.LastSync)
+	return &c
+}
+
+func cloneTransaction(tx *models.Transaction) *models.Transaction {
+	c := *tx
+	return &c
+}
+
+func cloneYouTubeSource(src *models.YouTubeSource) *models.YouTubeSource {
+	c := *src
+	c.TitleIncludes = slices.Clone(src.TitleIncludes)
+	c.TitleExcludes = slices.Clone(src.TitleExcludes)
+	return &c
+}
+
+func cloneTranscript(t *models.VideoTranscript) *models.VideoTranscript {
+	c := *t
+	c.Duration = clonePtr(t.Duration)
+	return &c
+}
+
+func cloneMarketAnalysis(a *models.MarketAnalysis) *models.MarketAnalysis {
+	c := *a
+	c.Trends = slices.Clone(a.Trends)
+	c.RiskFactors = slices.Clone(a.RiskFactors)
+	return &c
+}
+
+func cloneRecommendation(r *models.Recommendation) *models.Recommendation {
+	c := *r
+	c.SuggestedActions = slices.Clone(r.SuggestedActions)
+	return &c
+}
+
+func cloneWorkflowExecution(e *models.WorkflowExecution) *models.WorkflowExecution {
+	c := *e
+	return &c
+}
+
+func cloneWorkflowExecutionEvent(e *models.WorkflowExecutionEvent) *models.WorkflowExecutionEvent {
+	c := *e
+	return &c
+}
+
+func cloneAggregatedRecommendation(r *models.AggregatedRecommendation) *models.AggregatedRecommendation {
+	c := *r
+	c.SuggestedActions = slices.Clone(r.SuggestedActions)
+	c.KeyInsights = slices.Clone(r.KeyInsights)
+	c.ExecutionIDs = slices.Clone(r.ExecutionIDs)
+	return &c
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"0xnetworth/backend/internal/models"
+)
+
+func TestMemoryStoreCopiesRecords(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	order := 1
+	portfolio := &models.Portfolio{ID: "p1", Platform: models.PlatformCoinbase, Name: "Main", DisplayOrder: &order}
+	if _, err := s.CreateOrUpdatePortfolio(ctx, portfolio); err != nil {
+		t.Fatal(err)
+	}
+	source := &models.YouTubeSource{ID: "s1", Type: models.YouTubeSourceTypeChannel, Name: "Channel", TitleIncludes: []string{"crypto"}}
+	if err := s.CreateOrUpdateYouTubeSource(ctx, source); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing what was written does not reach the store
+	portfolio.Name = "Changed"
+	*portfolio.DisplayOrder = 9
+	source.TitleIncludes[0] = "changed"
+
+	// Nor does changing what was read
+	read, err := s.GetPortfolioByID(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read.Name = "Changed"
+	*read.DisplayOrder = 9
+	readSource, err := s.GetYouTubeSourceByID(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readSource.Enabled = true
+	readSource.TitleIncludes[0] = "changed"
+	sources, err := s.GetAllYouTubeSources(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources[0].Name = "Changed"
+
+	stored, err := s.GetPortfolioByID(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Main" || *stored.DisplayOrder != 1 {
+		t.Errorf("stored portfolio = %s with order %d, want Main with order 1", stored.Name, *stored.DisplayOrder)
+	}
+	storedSource, err := s.GetYouTubeSourceByID(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storedSource.Name != "Channel" || storedSource.Enabled || storedSource.TitleIncludes[0] != "crypto" {
+		t.Errorf("stored source = %+v, want it as written", storedSource)
+	}
+}
+
+// TestMemoryStoreConcurrentReadModifyWrite has handlers and the scheduler read, change and
+// write back the same records at once. Run with -race, it fails if any of them share a struct
+// with the store.
+func TestMemoryStoreConcurrentReadModifyWrite(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	if err := s.CreateOrUpdateYouTubeSource(ctx, &models.YouTubeSource{ID: "s1", Type: models.YouTubeSourceTypeChannel, Name: "Channel"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateOrUpdateInvestments(ctx, []*models.Investment{
+		{ID: "i1", AccountID: "a1", Platform: models.PlatformCoinbase, Symbol: "BTC", Quantity: 1, Value: 60, Price: 60, Currency: "USD"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				source, err := s.GetYouTubeSourceByID(ctx, "s1")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				source.LastProcessed = testTime(0)
+				source.Name = fmt.Sprintf("Channel %d", g)
+				if err := s.CreateOrUpdateYouTubeSource(ctx, source); err != nil {
+					t.Error(err)
+					return
+				}
+
+				investments, _, err := s.GetAllInvestments(ctx, ListOptions{}, SortOption{}, InvestmentFilter{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for _, investment := range investments {
+					investment.Price++
+					investment.Value = investment.Price * investment.Quantity
+				}
+				if _, err := s.CreateOrUpdateInvestments(ctx, investments); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := s.RecalculateNetWorth(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+				networth, err := s.GetNetWorth(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				networth.ByPlatform[models.PlatformM1Finance] = float64(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	networth, err := s.GetNetWorth(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := networth.ByPlatform[models.PlatformM1Finance]; ok {
+		t.Errorf("net worth by platform = %v, changed through a returned copy", networth.ByPlatform)
+	}
+}
@@ -0,0 +1,316 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// memoryFileInterval is the minimum time between writes of a file-backed memory store
+const memoryFileInterval = 3 * time.Second
+
+// memoryFile saves a memory store's state to a JSON file in the background. Mutations mark
+// the state dirty; the writer waits memoryFileInterval so a burst of changes is saved once.
+type memoryFile struct {
+	path      string
+	dirty     chan struct{}
+	closed    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// memoryFileContents is the on-disk form of memoryState. Users are not saved: their token
+// hashes are never serialized, and API_USERS provisions them again at startup.
+type memoryFileContents struct {
+	Tenants      map[string]*memoryTenantFile `json:"tenants"`
+	YouTubeQuota map[string]int               `json:"youtube_quota,omitempty"`
+	// Files written before workflow data was kept per user hold it here; it loads into the
+	// default user
+	memoryWorkflowFile
+}
+
+// memoryWorkflowFile is the on-disk form of a tenant's workflow data
+type memoryWorkflowFile struct {
+	YouTubeSources            map[string]*models.YouTubeSource            `json:"youtube_sources,omitempty"`
+	Transcripts               map[string]*models.VideoTranscript          `json:"transcripts,omitempty"`
+	MarketAnalyses            map[string]*models.MarketAnalysis           `json:"market_analyses,omitempty"`
+	Recommendations           map[string]*models.Recommendation           `json:"recommendations,omitempty"`
+	Executions                map[string]*models.WorkflowExecution        `json:"executions,omitempty"`
+	ExecutionEvents           map[string][]*models.WorkflowExecutionEvent `json:"execution_events,omitempty"`
+	AggregatedRecommendations []*models.AggregatedRecommendation          `json:"aggregated_recommendations,omitempty"`
+}
+
+// memoryTenantFile is the on-disk form of memoryTenant
+type memoryTenantFile struct {
+	Portfolios   map[string]*models.Portfolio           `json:"portfolios"`
+	Accounts     map[string]*models.Account             `json:"accounts"`
+	Investments  map[string]*models.Investment          `json:"investments"`
+	Transactions map[string]*models.Transaction         `json:"transactions"`
+	NetWorth     *models.NetWorth                       `json:"networth,omitempty"`
+	Snapshots    []*models.NetWorthSnapshot             `json:"snapshots"`
+	Syncs        map[models.Platform]*models.SyncRecord `json:"syncs,omitempty"`
+	PlaidItems   map[string]*plaidItemFile              `json:"plaid_items,omitempty"`
+	// InvestmentHistory is keyed by uppercase symbol
+	InvestmentHistory map[string][]*models.InvestmentHistoryPoint `json:"investment_history,omitempty"`
+	// Files written before sync attempts were recorded hold only successful sync times:
+	// LastSyncs per platform, or before that LastSync for Coinbase
+	LastSyncs map[models.Platform]time.Time `json:"last_syncs,omitempty"`
+	LastSync  time.Time                     `json:"last_sync,omitzero"`
+	memoryWorkflowFile
+}
+
+// plaidItemFile is the on-disk form of a PlaidItem, which keeps the encrypted access token the
+// API leaves out
+type plaidItemFile struct {
+	*models.PlaidItem
+	EncryptedAccessToken string `json:"encrypted_access_token"`
+}
+
+// NewFileStore creates an in-memory store that is loaded from path at startup and written
+// back to it after changes. A missing or unreadable file logs a warning and starts empty;
+// an unreadable file is first moved aside to path.corrupt so it is not overwritten.
+// Call Close before exiting to save changes made within the last write interval.
+func NewFileStore(path string) *MemoryStore {
+	s := NewStore().(*MemoryStore)
+	if err := s.loadFile(path); err != nil {
+		log.Printf("Warning: %v; starting with an empty store", err)
+	}
+
+	s.file = &memoryFile{
+		path:   path,
+		dirty:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.fileWriter()
+	return s
+}
+
+// Close stops the background writer and saves the current state. It is a no-op for a store
+// without a file.
+func (s *MemoryStore) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	var err error
+	s.file.closeOnce.Do(func() {
+		close(s.file.closed)
+		<-s.file.done
+		err = s.saveFile()
+	})
+	return err
+}
+
+// changed marks the state as needing a write. Mutating methods call it while holding s.mu.
+func (s *memoryState) changed() {
+	if s.file == nil {
+		return
+	}
+	select {
+	case s.file.dirty <- struct{}{}:
+	default:
+		// A write is already pending and will include this change
+	}
+}
+
+// fileWriter saves the state after changes, at most once per memoryFileInterval
+func (s *MemoryStore) fileWriter() {
+	defer close(s.file.done)
+	for {
+		select {
+		case <-s.file.dirty:
+		case <-s.file.closed:
+			return
+		}
+
+		select {
+		case <-time.After(memoryFileInterval):
+		case <-s.file.closed:
+			// Close saves the final state
+			return
+		}
+
+		if err := s.saveFile(); err != nil {
+			log.Printf("Error saving store file: %v", err)
+		}
+	}
+}
+
+// saveFile writes the state to a temporary file and renames it over the store file, so a
+// crash mid-write leaves the previous contents intact
+func (s *MemoryStore) saveFile() error {
+	s.mu.RLock()
+	data, err := json.Marshal(s.fileContents())
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.file.path), filepath.Base(s.file.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.file.path); err != nil {
+		return fmt.Errorf("failed to replace store file: %w", err)
+	}
+	return nil
+}
+
+// fileContents copies the state into its on-disk form. Callers must hold s.mu.
+func (s *MemoryStore) fileContents() *memoryFileContents {
+	contents := &memoryFileContents{
+		Tenants:      make(map[string]*memoryTenantFile, len(s.tenants)),
+		YouTubeQuota: s.youtubeQuota,
+	}
+	for userID, tenant := range s.tenants {
+		contents.Tenants[userID] = &memoryTenantFile{
+			Portfolios:        tenant.portfolios,
+			Accounts:          tenant.accounts,
+			Investments:       tenant.investments,
+			Transactions:      tenant.transactions,
+			NetWorth:          tenant.networth,
+			Snapshots:         tenant.snapshots,
+			Syncs:             tenant.syncs,
+			InvestmentHistory: tenant.investmentHistory,
+			memoryWorkflowFile: memoryWorkflowFile{
+				YouTubeSources:            tenant.youtubeSources,
+				Transcripts:               tenant.transcripts,
+				MarketAnalyses:            tenant.marketAnalyses,
+				Recommendations:           tenant.recommendations,
+				Executions:                tenant.executions,
+				ExecutionEvents:           tenant.executionEvents,
+				AggregatedRecommendations: tenant.aggregatedRecs,
+			},
+		}
+		if len(tenant.plaidItems) > 0 {
+			items := make(map[string]*plaidItemFile, len(tenant.plaidItems))
+			for id, item := range tenant.plaidItems {
+				items[id] = &plaidItemFile{PlaidItem: item, EncryptedAccessToken: item.EncryptedAccessToken}
+			}
+			contents.Tenants[userID].PlaidItems = items
+		}
+	}
+	return contents
+}
+
+// loadFile replaces the state with the contents of path. The state is left untouched on error.
+func (s *MemoryStore) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("store file %s does not exist", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read store file %s: %w", path, err)
+	}
+
+	var contents memoryFileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		if renameErr := os.Rename(path, path+".corrupt"); renameErr != nil {
+			return fmt.Errorf("store file %s is corrupt (%v) and could not be moved aside: %w", path, err, renameErr)
+		}
+		return fmt.Errorf("store file %s is corrupt, moved it to %s.corrupt: %w", path, path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, saved := range contents.Tenants {
+		if saved == nil {
+			continue
+		}
+		tenant := newMemoryTenant()
+		copyEntries(tenant.portfolios, saved.Portfolios)
+		copyEntries(tenant.accounts, saved.Accounts)
+		copyEntries(tenant.investments, saved.Investments)
+		for _, investment := range tenant.investments {
+			// Files written before holdings could be deactivated have no active flag
+			if !investment.Active && investment.DeactivatedAt == nil {
+				investment.Active = true
+			}
+		}
+		copyEntries(tenant.transactions, saved.Transactions)
+		if saved.NetWorth != nil {
+			tenant.networth = saved.NetWorth
+		}
+		tenant.snapshots = saved.Snapshots
+		if saved.LastSyncs == nil && !saved.LastSync.IsZero() {
+			saved.LastSyncs = map[models.Platform]time.Time{models.PlatformCoinbase: saved.LastSync}
+		}
+		for platform, lastSync := range saved.LastSyncs {
+			tenant.syncs[platform] = &models.SyncRecord{
+				Platform:    platform,
+				Status:      models.SyncStatusSuccess,
+				LastAttempt: &lastSync,
+				LastSync:    &lastSync,
+			}
+		}
+		copyEntries(tenant.syncs, saved.Syncs)
+		for id, saved := range saved.PlaidItems {
+			if saved == nil || saved.PlaidItem == nil {
+				continue
+			}
+			saved.PlaidItem.EncryptedAccessToken = saved.EncryptedAccessToken
+			// Files written before items had a status hold healthy ones
+			if saved.PlaidItem.Status == "" {
+				saved.PlaidItem.Status = models.PlaidItemStatusHealthy
+			}
+			tenant.plaidItems[id] = saved.PlaidItem
+		}
+		for symbol, history := range saved.InvestmentHistory {
+			tenant.investmentHistory[symbol] = history
+		}
+		saved.memoryWorkflowFile.loadInto(tenant)
+		s.tenants[userID] = tenant
+	}
+	contents.memoryWorkflowFile.loadInto(s.tenants[models.DefaultUserID])
+	maps.Copy(s.youtubeQuota, contents.YouTubeQuota)
+
+	log.Printf("Loaded store file %s (%d users)", path, len(contents.Tenants))
+	return nil
+}
+
+// loadInto copies the saved workflow data into tenant
+func (f *memoryWorkflowFile) loadInto(tenant *memoryTenant) {
+	copyEntries(tenant.youtubeSources, f.YouTubeSources)
+	copyEntries(tenant.transcripts, f.Transcripts)
+	copyEntries(tenant.marketAnalyses, f.MarketAnalyses)
+	copyEntries(tenant.recommendations, f.Recommendations)
+	copyEntries(tenant.executions, f.Executions)
+	for executionID, events := range f.ExecutionEvents {
+		if _, exists := tenant.executions[executionID]; exists {
+			tenant.executionEvents[executionID] = slices.DeleteFunc(events, func(e *models.WorkflowExecutionEvent) bool { return e == nil })
+		}
+	}
+	tenant.aggregatedRecs = append(tenant.aggregatedRecs, slices.DeleteFunc(f.AggregatedRecommendations, func(r *models.AggregatedRecommendation) bool { return r == nil })...)
+}
+
+// copyEntries copies the non-nil entries of src into dst
+func copyEntries[K comparable, T any](dst, src map[K]*T) {
+	for id, value := range src {
+		if value != nil {
+			dst[id] = value
+		}
+	}
+}
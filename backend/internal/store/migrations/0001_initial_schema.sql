@@ -1,5 +1,19 @@
 -- 0xNetworth Database Schema
--- PostgreSQL schema for persisting investment data, workflow executions, and analysis results
+-- PostgreSQL schema for persisting investment data, workflow executions, and analysis results.
+-- This baseline is written to be re-runnable so it also applies cleanly to databases created
+-- before migrations were tracked. Later changes belong in new numbered files.
+
+-- Users table
+-- Financial data is scoped per user. The bootstrap 'default' user owns all rows that
+-- existed before multi-user support and serves every request when no API tokens are configured.
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE, -- SHA-256 hex digest of the user's API token
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO users (id, name) VALUES ('default', 'Default user') ON CONFLICT (id) DO NOTHING;
 
 -- Portfolios table
 CREATE TABLE IF NOT EXISTS portfolios (
@@ -8,9 +22,18 @@ CREATE TABLE IF NOT EXISTS portfolios (
     name VARCHAR(255) NOT NULL,
     type VARCHAR(100),
     last_synced TIMESTAMP,
+    tax_treatment VARCHAR(50),
+    custodian VARCHAR(255),
+    display_order INTEGER,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+-- User-managed metadata; sync upserts never clear these
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS tax_treatment VARCHAR(50);
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS custodian VARCHAR(255);
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS display_order INTEGER;
+-- Existing rows are assigned to the bootstrap user via the column default
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
 
 -- Investments table
 CREATE TABLE IF NOT EXISTS investments (
@@ -24,15 +47,44 @@ CREATE TABLE IF NOT EXISTS investments (
     price DOUBLE PRECISION NOT NULL,
     currency VARCHAR(10) NOT NULL DEFAULT 'USD',
     asset_type VARCHAR(50),
+    cost_basis DOUBLE PRECISION,
+    average_buy_price DOUBLE PRECISION,
+    first_acquired_at TIMESTAMP,
+    unrealized_gain DOUBLE PRECISION,
     last_updated TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+-- Cost basis columns are nullable: NULL means unknown, which is distinct from zero
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS cost_basis DOUBLE PRECISION;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS average_buy_price DOUBLE PRECISION;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS first_acquired_at TIMESTAMP;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS unrealized_gain DOUBLE PRECISION;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+
+-- Transactions table
+CREATE TABLE IF NOT EXISTS transactions (
+    id VARCHAR(255) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    platform VARCHAR(50) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    symbol VARCHAR(50),
+    quantity DOUBLE PRECISION,
+    amount DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT 'USD',
+    fee DOUBLE PRECISION,
+    timestamp TIMESTAMP NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_timestamp ON transactions(timestamp DESC);
 
 -- Sync metadata table
 CREATE TABLE IF NOT EXISTS sync_metadata (
     id VARCHAR(255) PRIMARY KEY,
-    platform VARCHAR(50) NOT NULL UNIQUE,
+    platform VARCHAR(50) NOT NULL,
     last_sync_time TIMESTAMP,
     sync_status VARCHAR(50),
     error_message TEXT,
@@ -40,6 +92,11 @@ CREATE TABLE IF NOT EXISTS sync_metadata (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+ALTER TABLE sync_metadata ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+-- Sync state is tracked per user and platform (replaces the original platform-only constraint)
+ALTER TABLE sync_metadata DROP CONSTRAINT IF EXISTS sync_metadata_platform_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_metadata_user_platform ON sync_metadata(user_id, platform);
+
 -- YouTube sources table
 CREATE TABLE IF NOT EXISTS youtube_sources (
     id VARCHAR(255) PRIMARY KEY,
@@ -112,6 +169,7 @@ CREATE TABLE IF NOT EXISTS workflow_executions (
 );
 
 -- Aggregated recommendations table
+-- One row per generation so the history of consolidated advice is preserved
 CREATE TABLE IF NOT EXISTS aggregated_recommendations (
     id VARCHAR(255) PRIMARY KEY,
     action VARCHAR(100) NOT NULL,
@@ -120,21 +178,25 @@ CREATE TABLE IF NOT EXISTS aggregated_recommendations (
     summary TEXT NOT NULL,
     key_insights JSONB,
     execution_ids JSONB NOT NULL, -- Array of execution IDs used to generate this recommendation
+    window_days INTEGER NOT NULL DEFAULT 0,
+    source_count INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
+ALTER TABLE aggregated_recommendations ADD COLUMN IF NOT EXISTS window_days INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE aggregated_recommendations ADD COLUMN IF NOT EXISTS source_count INTEGER NOT NULL DEFAULT 0;
 CREATE INDEX IF NOT EXISTS idx_aggregated_recommendations_created_at ON aggregated_recommendations(created_at DESC);
-CREATE TRIGGER update_aggregated_recommendations_updated_at
-    BEFORE UPDATE ON aggregated_recommendations
-    FOR EACH ROW
-    EXECUTE FUNCTION update_updated_at_column();
 
 -- Create indexes for common queries
 CREATE INDEX IF NOT EXISTS idx_investments_account_id ON investments(account_id);
 CREATE INDEX IF NOT EXISTS idx_investments_platform ON investments(platform);
 CREATE INDEX IF NOT EXISTS idx_portfolios_platform ON portfolios(platform);
+CREATE INDEX IF NOT EXISTS idx_portfolios_user_id ON portfolios(user_id);
+CREATE INDEX IF NOT EXISTS idx_investments_user_id ON investments(user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
 CREATE INDEX IF NOT EXISTS idx_workflow_executions_status ON workflow_executions(status);
 CREATE INDEX IF NOT EXISTS idx_workflow_executions_source_id ON workflow_executions(source_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_completed_at ON workflow_executions(completed_at);
 CREATE INDEX IF NOT EXISTS idx_video_transcripts_video_id ON video_transcripts(video_id);
 CREATE INDEX IF NOT EXISTS idx_video_transcripts_source_id ON video_transcripts(source_id);
 CREATE INDEX IF NOT EXISTS idx_market_analyses_transcript_id ON market_analyses(transcript_id);
@@ -150,15 +212,26 @@ END;
 $$ language 'plpgsql';
 
 -- Create triggers to automatically update updated_at
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_portfolios_updated_at ON portfolios;
 CREATE TRIGGER update_portfolios_updated_at BEFORE UPDATE ON portfolios
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_investments_updated_at ON investments;
 CREATE TRIGGER update_investments_updated_at BEFORE UPDATE ON investments
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_sync_metadata_updated_at ON sync_metadata;
 CREATE TRIGGER update_sync_metadata_updated_at BEFORE UPDATE ON sync_metadata
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_youtube_sources_updated_at ON youtube_sources;
 CREATE TRIGGER update_youtube_sources_updated_at BEFORE UPDATE ON youtube_sources
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_aggregated_recommendations_updated_at ON aggregated_recommendations;
+CREATE TRIGGER update_aggregated_recommendations_updated_at BEFORE UPDATE ON aggregated_recommendations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,12 @@
+-- Net worth history, one row per user per minute
+CREATE TABLE IF NOT EXISTS networth_snapshots (
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    taken_at TIMESTAMP NOT NULL, -- UTC, truncated to the minute
+    total_value DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    by_platform JSONB NOT NULL,
+    by_asset_type JSONB NOT NULL,
+    by_tax_treatment JSONB NOT NULL,
+    account_count INTEGER NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
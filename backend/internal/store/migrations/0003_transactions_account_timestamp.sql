@@ -0,0 +1,2 @@
+-- Serves per-account transaction listings, which are filtered by account and ordered by time
+CREATE INDEX IF NOT EXISTS idx_transactions_account_timestamp ON transactions(account_id, timestamp DESC);
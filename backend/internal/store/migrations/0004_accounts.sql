@@ -0,0 +1,17 @@
+-- Platform accounts: one balance per currency, such as a Coinbase USD or BTC wallet
+CREATE TABLE IF NOT EXISTS accounts (
+    id VARCHAR(255) PRIMARY KEY,
+    platform VARCHAR(50) NOT NULL,
+    portfolio_id VARCHAR(255),
+    name VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    type VARCHAR(50),
+    available_balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    hold DOUBLE PRECISION NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_synced TIMESTAMP,
+    user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_accounts_user_platform ON accounts(user_id, platform);
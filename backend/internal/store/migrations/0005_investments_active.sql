@@ -0,0 +1,4 @@
+-- Holdings a sync no longer reports are deactivated rather than deleted, so they stay
+-- available for auditing but drop out of net worth
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
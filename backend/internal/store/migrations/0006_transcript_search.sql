@@ -0,0 +1,8 @@
+-- Full-text search over transcripts. Titles are weighted above the transcript text so
+-- videos named after the search terms rank first.
+ALTER TABLE video_transcripts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(video_title, '')), 'A') ||
+        setweight(to_tsvector('english', text), 'B')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_video_transcripts_search ON video_transcripts USING GIN (search_vector);
@@ -0,0 +1,5 @@
+-- Sync metadata records failed attempts and how much each sync wrote, not only the last success
+ALTER TABLE sync_metadata ADD COLUMN IF NOT EXISTS last_attempt_time TIMESTAMP;
+ALTER TABLE sync_metadata ADD COLUMN IF NOT EXISTS portfolios_synced INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sync_metadata ADD COLUMN IF NOT EXISTS accounts_synced INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sync_metadata ADD COLUMN IF NOT EXISTS investments_synced INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Step-level event log of workflow executions, deleted with the execution
+CREATE TABLE IF NOT EXISTS workflow_execution_events (
+    id BIGSERIAL PRIMARY KEY,
+    execution_id VARCHAR(255) NOT NULL REFERENCES workflow_executions(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_events_execution_id ON workflow_execution_events(execution_id, id);
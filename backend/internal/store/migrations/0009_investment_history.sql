@@ -0,0 +1,12 @@
+-- Per-symbol position history, one row per user, platform and symbol per minute
+CREATE TABLE IF NOT EXISTS investment_history (
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    platform VARCHAR(50) NOT NULL,
+    symbol VARCHAR(50) NOT NULL, -- uppercase
+    taken_at TIMESTAMP NOT NULL, -- UTC, truncated to the minute
+    quantity DOUBLE PRECISION NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    PRIMARY KEY (user_id, symbol, platform, taken_at)
+);
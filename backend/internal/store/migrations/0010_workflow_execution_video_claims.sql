@@ -0,0 +1,6 @@
+-- The execution processing a video, or that completed it, claims the video so concurrent
+-- triggers cannot both process it. Failed executions release their claim.
+ALTER TABLE workflow_executions ADD COLUMN IF NOT EXISTS claimed_video_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_executions_claimed_video_id
+    ON workflow_executions(claimed_video_id) WHERE claimed_video_id IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Serves cursor pagination of workflow executions, newest first
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_created_at_id ON workflow_executions(created_at DESC, id DESC);
@@ -0,0 +1,4 @@
+-- A holding reported in another currency keeps that value and currency here, alongside its
+-- value converted into the reporting currency
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS native_value DOUBLE PRECISION;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS native_currency VARCHAR(10);
@@ -0,0 +1,3 @@
+-- Units locked in staking or an earn program are synced as their own holdings, flagged so they
+-- can be told apart from the liquid holding of the same asset
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS staked BOOLEAN NOT NULL DEFAULT FALSE;
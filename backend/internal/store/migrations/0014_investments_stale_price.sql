@@ -0,0 +1,3 @@
+-- A holding a sync could not price is kept at its last known price and flagged, so its value
+-- is known to be approximate
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS stale_price BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- A holding worth less than the sync's dust threshold is flagged, so listings can hide it while
+-- it still counts towards net worth
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS is_dust BOOLEAN NOT NULL DEFAULT FALSE;
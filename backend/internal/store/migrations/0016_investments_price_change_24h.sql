@@ -0,0 +1,4 @@
+-- The 24 hour price move of a holding as of its last sync and the value it accounts for; NULL
+-- when the platform did not report it
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS price_change_24h_pct DOUBLE PRECISION;
+ALTER TABLE investments ADD COLUMN IF NOT EXISTS value_change_24h DOUBLE PRECISION;
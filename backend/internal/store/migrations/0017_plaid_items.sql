@@ -0,0 +1,13 @@
+-- Institution logins linked through Plaid. The access token is encrypted by the server before
+-- it is stored.
+CREATE TABLE IF NOT EXISTS plaid_items (
+    id VARCHAR(255) PRIMARY KEY, -- Plaid's item_id
+    institution_id VARCHAR(255),
+    institution_name VARCHAR(255) NOT NULL DEFAULT '',
+    platform VARCHAR(50) NOT NULL,
+    encrypted_access_token TEXT NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_plaid_items_user ON plaid_items(user_id);
@@ -0,0 +1,3 @@
+-- The accounts each Plaid item's last sync stored, as a JSON array of account IDs, so unlinking
+-- the item can find its data
+ALTER TABLE plaid_items ADD COLUMN IF NOT EXISTS account_ids JSONB NOT NULL DEFAULT '[]';
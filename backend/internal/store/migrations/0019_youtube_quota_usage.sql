@@ -0,0 +1,7 @@
+-- Estimated YouTube Data API quota used per quota day. Google resets the quota at midnight
+-- Pacific time, so day is the date in America/Los_Angeles.
+CREATE TABLE IF NOT EXISTS youtube_quota_usage (
+    day VARCHAR(10) PRIMARY KEY, -- YYYY-MM-DD
+    units INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- Per-source title filters: JSON arrays of patterns a video's title must match one of
+-- (title_includes, when not empty) and none of (title_excludes) for the video to be processed,
+-- and whether the videos they leave out are recorded as skipped executions
+ALTER TABLE youtube_sources ADD COLUMN IF NOT EXISTS title_includes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE youtube_sources ADD COLUMN IF NOT EXISTS title_excludes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE youtube_sources ADD COLUMN IF NOT EXISTS record_skipped BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- Executions created before the server wrote created_at itself got CURRENT_TIMESTAMP, which
+-- keeps microseconds. Cursors hold whole seconds, so truncate them to match.
+UPDATE workflow_executions SET created_at = date_trunc('second', created_at)
+WHERE created_at <> date_trunc('second', created_at);
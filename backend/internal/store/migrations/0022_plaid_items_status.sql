@@ -0,0 +1,4 @@
+-- Whether each Plaid item can be synced, and when it last synced. An item whose institution
+-- needs the user to log in again is login_required until it is re-linked.
+ALTER TABLE plaid_items ADD COLUMN IF NOT EXISTS status VARCHAR(50) NOT NULL DEFAULT 'healthy';
+ALTER TABLE plaid_items ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP;
@@ -0,0 +1,22 @@
+-- YouTube sources and workflow records belong to the user who created them, like financial
+-- data. Existing rows were created before users existed and go to the default user.
+ALTER TABLE youtube_sources ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE video_transcripts ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE market_analyses ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE recommendations ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE workflow_executions ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE workflow_execution_events ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+ALTER TABLE aggregated_recommendations ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES users(id);
+
+CREATE INDEX IF NOT EXISTS idx_youtube_sources_user_id ON youtube_sources(user_id);
+CREATE INDEX IF NOT EXISTS idx_video_transcripts_user_video_id ON video_transcripts(user_id, video_id);
+CREATE INDEX IF NOT EXISTS idx_market_analyses_user_id ON market_analyses(user_id);
+CREATE INDEX IF NOT EXISTS idx_recommendations_user_id ON recommendations(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_user_created_at_id ON workflow_executions(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_events_user_id ON workflow_execution_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_aggregated_recommendations_user_created_at ON aggregated_recommendations(user_id, created_at DESC);
+
+-- Each user processes a video once, so the claim on it is per user
+DROP INDEX IF EXISTS idx_workflow_executions_claimed_video_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_executions_user_claimed_video_id
+    ON workflow_executions(user_id, claimed_video_id) WHERE claimed_video_id IS NOT NULL;
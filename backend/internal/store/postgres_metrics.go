@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultSlowQuery is the latency above which a query is logged; DB_SLOW_QUERY_MS overrides it
+const defaultSlowQuery = 500 * time.Millisecond
+
+// queryTableRe finds the table a statement reads or writes
+var queryTableRe = regexp.MustCompile(`(?is)^\s*(?:insert\s+into|update|delete\s+from)\s+(\w+)|\bfrom\s+(\w+)`)
+
+// queryTracer times every statement run through the pool, so each store method is covered
+// without timing code of its own. Statements are recorded in metrics.Default under a name
+// derived from their SQL, such as "postgres select investments", and statements slower than
+// slowQuery are logged.
+type queryTracer struct {
+	registry  *metrics.Registry
+	slowQuery time.Duration // 0 disables the slow query log
+	names     sync.Map      // SQL text -> operation name
+}
+
+// queryTraceKey is the context key under which a statement's trace is passed from start to end
+type queryTraceKey struct{}
+
+// queryTrace is a statement in progress
+type queryTrace struct {
+	name  string
+	sql   string
+	start time.Time
+}
+
+// newQueryTracer creates a tracer recording into metrics.Default, reading the slow query
+// threshold from DB_SLOW_QUERY_MS
+func newQueryTracer() *queryTracer {
+	slowQuery := defaultSlowQuery
+	if ms := getEnvInt("DB_SLOW_QUERY_MS", -1); ms >= 0 {
+		slowQuery = time.Duration(ms) * time.Millisecond
+	}
+	return &queryTracer{registry: metrics.Default, slowQuery: slowQuery}
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{name: t.operationName(data.SQL), sql: data.SQL, start: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer. For Query it runs when the rows are closed, so
+// the duration includes reading the results.
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.finish(ctx, data.Err)
+}
+
+// TraceBatchStart implements pgx.BatchTracer. A batch is recorded as one operation named
+// after its first statement.
+func (t *queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	sql := ""
+	if data.Batch != nil && len(data.Batch.QueuedQueries) > 0 {
+		sql = data.Batch.QueuedQueries[0].SQL
+	}
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{name: "batch " + t.operationName(sql), sql: sql, start: time.Now()})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer; statements in a batch are timed together
+func (t *queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+// TraceBatchEnd implements pgx.BatchTracer
+func (t *queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.finish(ctx, data.Err)
+}
+
+// finish records the statement traced in ctx
+func (t *queryTracer) finish(ctx context.Context, err error) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+	duration := time.Since(trace.start)
+	t.registry.Observe("postgres "+trace.name, duration, err)
+	if t.slowQuery > 0 && duration > t.slowQuery {
+		log.Printf("Slow query (%s) took %v: %s", trace.name, duration.Round(time.Millisecond), strings.Join(strings.Fields(trace.sql), " "))
+	}
+}
+
+// operationName derives a short, low-cardinality name from a statement: its verb and the
+// table it reads or writes
+func (t *queryTracer) operationName(sql string) string {
+	if name, ok := t.names.Load(sql); ok {
+		return name.(string)
+	}
+
+	name := "unknown"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		name = strings.ToLower(fields[0])
+	}
+	if match := queryTableRe.FindStringSubmatch(sql); match != nil {
+		name += " " + strings.ToLower(match[1]+match[2])
+	}
+	t.names.Store(sql, name)
+	return name
+}
@@ -4,14 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"0xnetworth/backend/internal/currency"
 	"0xnetworth/backend/internal/models"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -23,14 +29,39 @@ const (
 	defaultMinConns = 5
 )
 
-// PostgresStore is a PostgreSQL-backed store implementation
+// PostgresStore is a PostgreSQL-backed store implementation.
+// Financial data is scoped to userID; use ForUser to obtain a view for another user.
 type PostgresStore struct {
 	pool    *pgxpool.Pool
+	db      pgxQuerier // the pool, or the transaction inside WithTransaction
 	timeout time.Duration
+	userID  string
 }
 
-// NewPostgresStore creates a new PostgreSQL store
-func NewPostgresStore(connString string) (*PostgresStore, error) {
+// pgxQuerier runs queries on a pool or inside a transaction; Begin on a transaction
+// starts a savepoint
+type pgxQuerier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Fail the build if PostgresStore drifts from the interfaces it implements
+var _ Store = (*PostgresStore)(nil)
+var _ PoolStatsProvider = (*PostgresStore)(nil)
+
+// Startup connection retry defaults; see connect
+const (
+	defaultConnectRetries = 5
+	defaultConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+	connectPingTimeout    = 5 * time.Second
+)
+
+// NewPostgresStore creates a new PostgreSQL store, waiting for the database to accept
+// connections. Cancelling ctx abandons the wait.
+func NewPostgresStore(ctx context.Context, connString string) (*PostgresStore, error) {
 	// Parse connection string and configure pool
 	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
@@ -51,16 +82,35 @@ func NewPostgresStore(connString string) (*PostgresStore, error) {
 	idleTimeout := getEnvDuration("DB_IDLE_TIMEOUT", 30*time.Minute)
 	config.MaxConnIdleTime = idleTimeout
 
+	// pgbouncer in transaction pooling mode cannot keep prepared statements across
+	// transactions; DB_QUERY_EXEC_MODE=simple_protocol or exec avoids preparing them
+	if mode := os.Getenv("DB_QUERY_EXEC_MODE"); mode != "" {
+		execMode, err := parseQueryExecMode(mode)
+		if err != nil {
+			return nil, err
+		}
+		config.ConnConfig.DefaultQueryExecMode = execMode
+	}
+	if val := os.Getenv("DB_STATEMENT_CACHE_CAPACITY"); val != "" {
+		capacity, err := strconv.Atoi(val)
+		if err != nil || capacity < 0 {
+			return nil, fmt.Errorf("invalid DB_STATEMENT_CACHE_CAPACITY %q: must be a non-negative integer", val)
+		}
+		// Each mode uses one of the two caches, so the setting sizes both
+		config.ConnConfig.StatementCacheCapacity = capacity
+		config.ConnConfig.DescriptionCacheCapacity = capacity
+	}
+
+	// Time every query; see queryTracer
+	config.ConnConfig.Tracer = newQueryTracer()
+
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
-
-	// Test the connection with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := pool.Ping(ctx); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if err := connect(ctx, pool, connectionTarget(config)); err != nil {
+		pool.Close()
+		return nil, err
 	}
 
 	// Get query timeout from environment
@@ -68,10 +118,73 @@ func NewPostgresStore(connString string) (*PostgresStore, error) {
 
 	return &PostgresStore{
 		pool:    pool,
+		db:      pool,
 		timeout: queryTimeout,
+		userID:  models.DefaultUserID,
 	}, nil
 }
 
+// connect pings the database until it answers. A failed ping is retried DB_CONNECT_RETRIES
+// times, waiting DB_CONNECT_BACKOFF before the first retry and doubling the wait after each
+// one up to maxConnectBackoff, so the server can start alongside a database that is still
+// booting. It returns early with ctx's error once ctx is cancelled.
+func connect(ctx context.Context, pool *pgxpool.Pool, target string) error {
+	retries := max(getEnvInt("DB_CONNECT_RETRIES", defaultConnectRetries), 0)
+	backoff := getEnvDuration("DB_CONNECT_BACKOFF", defaultConnectBackoff)
+	for attempt := 1; ; attempt++ {
+		log.Printf("Connecting to PostgreSQL at %s (attempt %d of %d)", target, attempt, retries+1)
+		pingCtx, cancel := context.WithTimeout(ctx, connectPingTimeout)
+		err := pool.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("stopped waiting for database: %w", ctx.Err())
+		}
+		if attempt > retries {
+			return fmt.Errorf("failed to ping database after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("PostgreSQL at %s is not ready, retrying in %s: %v", target, backoff, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// connectionTarget describes the database a config connects to for logging, leaving out the
+// user name and password
+func connectionTarget(config *pgxpool.Config) string {
+	return fmt.Sprintf("%s:%d/%s", config.ConnConfig.Host, config.ConnConfig.Port, config.ConnConfig.Database)
+}
+
+// queryExecModes maps DB_QUERY_EXEC_MODE values to pgx query exec modes
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// parseQueryExecMode returns the pgx query exec mode named by a DB_QUERY_EXEC_MODE value.
+// Unknown names are an error rather than a silent fallback to the default mode.
+func parseQueryExecMode(name string) (pgx.QueryExecMode, error) {
+	if mode, ok := queryExecModes[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return mode, nil
+	}
+	valid := make([]string, 0, len(queryExecModes))
+	for key := range queryExecModes {
+		valid = append(valid, key)
+	}
+	sort.Strings(valid)
+	return 0, fmt.Errorf("invalid DB_QUERY_EXEC_MODE %q: must be one of %s", name, strings.Join(valid, ", "))
+}
+
 // getEnvInt gets an integer from environment variable or returns default
 func getEnvInt(key string, defaultValue int) int {
 	if val := os.Getenv(key); val != "" {
@@ -92,9 +205,11 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
-// getContext returns a context with timeout for database operations
-func (s *PostgresStore) getContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), s.timeout)
+// getContext derives the context for a database operation from the caller's context,
+// so cancelled requests abort their queries. The configured query timeout is a ceiling
+// applied on top of any deadline the caller already set.
+func (s *PostgresStore) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.timeout)
 }
 
 // Close closes the database connection pool
@@ -102,459 +217,1646 @@ func (s *PostgresStore) Close() {
 	s.pool.Close()
 }
 
-// InitSchema executes the schema SQL to create tables
-func (s *PostgresStore) InitSchema(schemaSQL string) error {
-	ctx, cancel := s.getContext()
+// HealthCheck pings the database through the connection pool
+func (s *PostgresStore) HealthCheck(ctx context.Context) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	_, err := s.pool.Exec(ctx, schemaSQL)
-	return err
+	return s.pool.Ping(ctx)
 }
 
-// Helper functions for timestamp conversion
-func parseTimestamp(ts sql.NullTime) string {
-	if ts.Valid {
-		return ts.Time.UTC().Format(time.RFC3339)
+// PoolStats returns the current state of the connection pool
+func (s *PostgresStore) PoolStats() PoolStats {
+	stat := s.pool.Stat()
+	return PoolStats{
+		AcquiredConns: int(stat.AcquiredConns()),
+		IdleConns:     int(stat.IdleConns()),
+		TotalConns:    int(stat.TotalConns()),
+		MaxConns:      int(stat.MaxConns()),
 	}
-	return ""
 }
 
-func parseTimestampPtr(ts sql.NullTime) *string {
-	if ts.Valid {
-		formatted := ts.Time.UTC().Format(time.RFC3339)
-		return &formatted
-	}
-	return nil
+// ForUser returns a view of the store whose queries are scoped to userID.
+// The view shares the connection pool and must not be closed separately.
+func (s *PostgresStore) ForUser(userID string) Store {
+	scoped := *s
+	scoped.userID = userID
+	return &scoped
 }
 
-func parseIntPtr(i sql.NullInt64) *int {
-	if i.Valid {
-		val := int(i.Int64)
-		return &val
+// WithTransaction runs fn against a view of the store that runs every query in one database
+// transaction, committing it if fn returns nil. Inside another transaction it uses a savepoint.
+func (s *PostgresStore) WithTransaction(ctx context.Context, fn func(tx Store) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	scoped := *s
+	scoped.db = tx
+	if err := fn(&scoped); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
-// Portfolio operations
-
-// GetAllPortfolios returns all portfolios
-func (s *PostgresStore) GetAllPortfolios() []*models.Portfolio {
-	ctx, cancel := s.getContext()
+// DeleteRecords deletes every record of kind
+func (s *PostgresStore) DeleteRecords(ctx context.Context, kind RecordKind) (int, error) {
+	query, args, err := deleteRecordsSQL(kind, s.userID)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, platform, name, type, last_synced, created_at, updated_at FROM portfolios ORDER BY created_at DESC")
+	result, err := s.db.Exec(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to get all portfolios: %v", err)
-		return []*models.Portfolio{}
+		return 0, fmt.Errorf("failed to delete %s: %w", kind, err)
 	}
-	defer rows.Close()
+	return int(result.RowsAffected()), nil
+}
 
-	portfolios := make([]*models.Portfolio, 0)
-	for rows.Next() {
-		var p models.Portfolio
-		var lastSynced, createdAt, updatedAt sql.NullTime
-		var portfolioType sql.NullString
+// UserID returns the user this store is scoped to
+func (s *PostgresStore) UserID() string {
+	return s.userID
+}
 
-		err := rows.Scan(&p.ID, &p.Platform, &p.Name, &portfolioType, &lastSynced, &createdAt, &updatedAt)
-		if err != nil {
-			continue
-		}
+// User operations
 
-		if portfolioType.Valid {
-			p.Type = portfolioType.String
-		}
-		p.LastSynced = parseTimestamp(lastSynced)
+// GetUserByTokenHash returns the user owning the given API token hash
+func (s *PostgresStore) GetUserByTokenHash(ctx context.Context, tokenHash string) (*models.User, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var user models.User
+	var name, hash sql.NullString
+	var createdAt sql.NullTime
 
-		portfolios = append(portfolios, &p)
+	err := s.db.QueryRow(ctx,
+		"SELECT id, name, token_hash, created_at FROM users WHERE token_hash = $1",
+		tokenHash).Scan(&user.ID, &name, &hash, &createdAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to look up user by token: %w", err)
 	}
 
-	return portfolios
+	if name.Valid {
+		user.Name = name.String
+	}
+	if hash.Valid {
+		user.TokenHash = hash.String
+	}
+	user.CreatedAt = parseTimestamp(createdAt)
+	return &user, nil
 }
 
-// GetPortfoliosByPlatform returns portfolios for a specific platform
-func (s *PostgresStore) GetPortfoliosByPlatform(platform models.Platform) []*models.Portfolio {
-	ctx, cancel := s.getContext()
+// CreateOrUpdateUser creates or updates a user and its API token hash
+func (s *PostgresStore) CreateOrUpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, platform, name, type, last_synced, created_at, updated_at FROM portfolios WHERE platform = $1 ORDER BY created_at DESC",
-		platform)
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO users (id, name, token_hash, created_at, updated_at)
+		 VALUES ($1, $2, NULLIF($3, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 name = EXCLUDED.name,
+		 token_hash = EXCLUDED.token_hash,
+		 updated_at = CURRENT_TIMESTAMP`,
+		user.ID, user.Name, user.TokenHash)
 	if err != nil {
-		log.Printf("Failed to get portfolios by platform %s: %v", platform, err)
-		return []*models.Portfolio{}
+		return fmt.Errorf("failed to save user %s: %w", user.ID, err)
 	}
-	defer rows.Close()
+	return nil
+}
 
-	portfolios := make([]*models.Portfolio, 0)
+// ListUserIDs returns the ID of every user in order, across users
+func (s *PostgresStore) ListUserIDs(ctx context.Context) ([]string, error) {
+	ids, err := s.selectIDs(ctx, "SELECT id FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return ids, nil
+}
+
+// migrateAssetTypes rewrites free-form asset_type values to their canonical form.
+// Rows that are already canonical are untouched, so this is cheap to run on every start.
+func (s *PostgresStore) migrateAssetTypes(ctx context.Context) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, "SELECT DISTINCT COALESCE(asset_type, '') FROM investments")
+	if err != nil {
+		return fmt.Errorf("failed to read asset types: %w", err)
+	}
+	existing := make([]string, 0)
 	for rows.Next() {
-		var p models.Portfolio
-		var lastSynced, createdAt, updatedAt sql.NullTime
-		var portfolioType sql.NullString
+		var assetType string
+		if err := rows.Scan(&assetType); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan asset type: %w", err)
+		}
+		existing = append(existing, assetType)
+	}
+	rows.Close()
 
-		err := rows.Scan(&p.ID, &p.Platform, &p.Name, &portfolioType, &lastSynced, &createdAt, &updatedAt)
-		if err != nil {
+	for _, assetType := range existing {
+		canonical, _ := models.NormalizeAssetType(assetType)
+		if string(canonical) == assetType {
 			continue
 		}
-
-		if portfolioType.Valid {
-			p.Type = portfolioType.String
+		tag, err := s.db.Exec(ctx,
+			"UPDATE investments SET asset_type = $1 WHERE COALESCE(asset_type, '') = $2",
+			canonical, assetType)
+		if err != nil {
+			return fmt.Errorf("failed to normalize asset type %q: %w", assetType, err)
 		}
-		p.LastSynced = parseTimestamp(lastSynced)
-
-		portfolios = append(portfolios, &p)
+		log.Printf("Normalized asset type %q to %q on %d investments", assetType, canonical, tag.RowsAffected())
 	}
-
-	return portfolios
+	return nil
 }
 
-// GetPortfolioByID returns a portfolio by ID
-func (s *PostgresStore) GetPortfolioByID(id string) (*models.Portfolio, bool) {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	var p models.Portfolio
-	var lastSynced, createdAt, updatedAt sql.NullTime
-	var portfolioType sql.NullString
+// rowScanner is a single result row; both pgx and database/sql rows satisfy it,
+// so the scan helpers below are shared with SQLiteStore
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, platform, name, type, last_synced, created_at, updated_at FROM portfolios WHERE id = $1",
-		id).Scan(&p.ID, &p.Platform, &p.Name, &portfolioType, &lastSynced, &createdAt, &updatedAt)
+// Helper functions for timestamp conversion
+func parseTimestamp(ts sql.NullTime) time.Time {
+	if ts.Valid {
+		return models.NormalizeTime(ts.Time)
+	}
+	return time.Time{}
+}
 
-	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get portfolio %s: %v", id, err)
-		}
-		return nil, false
+func parseTimestampPtr(ts sql.NullTime) *time.Time {
+	if ts.Valid {
+		t := models.NormalizeTime(ts.Time)
+		return &t
 	}
+	return nil
+}
 
-	if portfolioType.Valid {
-		p.Type = portfolioType.String
+// nullableTime converts a zero time to NULL for query arguments
+func nullableTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
 	}
-	p.LastSynced = parseTimestamp(lastSynced)
+	return t.UTC()
+}
 
-	return &p, true
+func parseFloatPtr(f sql.NullFloat64) *float64 {
+	if f.Valid {
+		val := f.Float64
+		return &val
+	}
+	return nil
 }
 
-// CreateOrUpdatePortfolio creates or updates a portfolio
-func (s *PostgresStore) CreateOrUpdatePortfolio(portfolio *models.Portfolio) {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	var lastSynced interface{}
-	if portfolio.LastSynced != "" {
-		t, err := time.Parse(time.RFC3339, portfolio.LastSynced)
-		if err == nil {
-			lastSynced = t
-		}
+func parseIntPtr(i sql.NullInt64) *int {
+	if i.Valid {
+		val := int(i.Int64)
+		return &val
 	}
+	return nil
+}
 
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO portfolios (id, platform, name, type, last_synced, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		 ON CONFLICT (id) DO UPDATE SET
-		 platform = EXCLUDED.platform,
-		 name = EXCLUDED.name,
-		 type = EXCLUDED.type,
-		 last_synced = EXCLUDED.last_synced,
-		 updated_at = CURRENT_TIMESTAMP`,
-		portfolio.ID, portfolio.Platform, portfolio.Name, portfolio.Type, lastSynced)
+// Portfolio operations
+
+// portfolioColumns is the column list shared by all portfolio SELECT queries (see scanPortfolio)
+const portfolioColumns = "id, platform, name, type, last_synced, tax_treatment, custodian, display_order, created_at, updated_at"
+
+// portfolioOrder sorts portfolios by user-controlled display order, then name
+const portfolioOrder = " ORDER BY display_order ASC NULLS LAST, LOWER(name) ASC, id ASC"
+
+// scanPortfolio scans a row selected with portfolioColumns into a Portfolio
+func scanPortfolio(row rowScanner) (*models.Portfolio, error) {
+	var p models.Portfolio
+	var lastSynced, createdAt, updatedAt sql.NullTime
+	var portfolioType, taxTreatment, custodian sql.NullString
+	var displayOrder sql.NullInt64
 
+	err := row.Scan(&p.ID, &p.Platform, &p.Name, &portfolioType, &lastSynced, &taxTreatment, &custodian, &displayOrder, &createdAt, &updatedAt)
 	if err != nil {
-		log.Printf("Failed to create/update portfolio %s: %v", portfolio.ID, err)
+		return nil, err
 	}
-}
 
-// DeletePortfolio deletes a portfolio by ID
-func (s *PostgresStore) DeletePortfolio(id string) bool {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	result, err := s.pool.Exec(ctx, "DELETE FROM portfolios WHERE id = $1", id)
-	if err != nil {
-		log.Printf("Failed to delete portfolio %s: %v", id, err)
-		return false
+	if portfolioType.Valid {
+		p.Type = portfolioType.String
 	}
-	return result.RowsAffected() > 0
+	if taxTreatment.Valid {
+		p.TaxTreatment = models.TaxTreatment(taxTreatment.String)
+	}
+	if custodian.Valid {
+		p.Custodian = custodian.String
+	}
+	p.DisplayOrder = parseIntPtr(displayOrder)
+	p.LastSynced = parseTimestamp(lastSynced)
+
+	return &p, nil
 }
 
-// Investment operations
+// count runs a COUNT(*) query and returns the result
+func (s *PostgresStore) count(ctx context.Context, query string, args ...interface{}) (int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var total int
+	err := s.db.QueryRow(ctx, query, args...).Scan(&total)
+	return total, err
+}
 
-// GetAllInvestments returns all investments
-func (s *PostgresStore) GetAllInvestments() []*models.Investment {
-	ctx, cancel := s.getContext()
+// queryPortfolios runs a portfolio SELECT and scans every row
+func (s *PostgresStore) queryPortfolios(ctx context.Context, query string, args ...interface{}) ([]*models.Portfolio, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, account_id, platform, symbol, name, quantity, value, price, currency, asset_type, last_updated, created_at, updated_at FROM investments ORDER BY created_at DESC")
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to get all investments: %v", err)
-		return []*models.Investment{}
+		return nil, err
 	}
 	defer rows.Close()
 
-	investments := make([]*models.Investment, 0)
+	portfolios := make([]*models.Portfolio, 0)
 	for rows.Next() {
-		var inv models.Investment
-		var lastUpdated, createdAt, updatedAt sql.NullTime
-		var name, assetType sql.NullString
-
-		err := rows.Scan(&inv.ID, &inv.AccountID, &inv.Platform, &inv.Symbol, &name, &inv.Quantity, &inv.Value, &inv.Price, &inv.Currency, &assetType, &lastUpdated, &createdAt, &updatedAt)
+		p, err := scanPortfolio(rows)
 		if err != nil {
-			continue
-		}
-
-		if name.Valid {
-			inv.Name = name.String
-		}
-		if assetType.Valid {
-			inv.AssetType = assetType.String
+			return nil, err
 		}
-		inv.LastUpdated = parseTimestamp(lastUpdated)
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, rows.Err()
+}
 
-		investments = append(investments, &inv)
+// GetAllPortfolios returns a page of portfolios along with the total number of portfolios
+func (s *PostgresStore) GetAllPortfolios(ctx context.Context, opts ListOptions, order SortOption) ([]*models.Portfolio, int, error) {
+	orderBy, err := portfolioSortFields.orderBy(order, portfolioOrder)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.CountPortfolios(ctx)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return investments
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	portfolios, err := s.queryPortfolios(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE user_id = $1"+orderBy+page,
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get all portfolios: %w", err)
+	}
+	return portfolios, total, nil
 }
 
-// GetInvestmentsByAccount returns investments for a specific account
-func (s *PostgresStore) GetInvestmentsByAccount(accountID string) []*models.Investment {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, account_id, platform, symbol, name, quantity, value, price, currency, asset_type, last_updated, created_at, updated_at FROM investments WHERE account_id = $1 ORDER BY created_at DESC",
-		accountID)
+// CountPortfolios returns the number of portfolios
+func (s *PostgresStore) CountPortfolios(ctx context.Context) (int, error) {
+	count, err := s.count(ctx, "SELECT COUNT(*) FROM portfolios WHERE user_id = $1", s.userID)
 	if err != nil {
-		log.Printf("Failed to get investments by account %s: %v", accountID, err)
-		return []*models.Investment{}
+		return 0, fmt.Errorf("failed to count portfolios: %w", err)
 	}
-	defer rows.Close()
-
-	investments := make([]*models.Investment, 0)
-	for rows.Next() {
-		var inv models.Investment
-		var lastUpdated, createdAt, updatedAt sql.NullTime
-		var name, assetType sql.NullString
+	return count, nil
+}
 
-		err := rows.Scan(&inv.ID, &inv.AccountID, &inv.Platform, &inv.Symbol, &name, &inv.Quantity, &inv.Value, &inv.Price, &inv.Currency, &assetType, &lastUpdated, &createdAt, &updatedAt)
-		if err != nil {
-			continue
-		}
+// GetPortfoliosByPlatform returns portfolios for a specific platform
+func (s *PostgresStore) GetPortfoliosByPlatform(ctx context.Context, platform models.Platform) ([]*models.Portfolio, error) {
+	portfolios, err := s.queryPortfolios(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE user_id = $1 AND platform = $2"+portfolioOrder,
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolios by platform %s: %w", platform, err)
+	}
+	return portfolios, nil
+}
 
-		if name.Valid {
-			inv.Name = name.String
-		}
-		if assetType.Valid {
-			inv.AssetType = assetType.String
+// GetPortfolioByID returns a portfolio by ID
+func (s *PostgresStore) GetPortfolioByID(ctx context.Context, id string) (*models.Portfolio, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	p, err := scanPortfolio(s.db.QueryRow(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		inv.LastUpdated = parseTimestamp(lastUpdated)
-
-		investments = append(investments, &inv)
+		return nil, fmt.Errorf("failed to get portfolio %s: %w", id, err)
 	}
+	return p, nil
+}
 
-	return investments
+// portfolioValuesQuery sums the active holdings of each of a user's portfolios, keeping
+// portfolios without any
+const portfolioValuesQuery = `SELECT p.id, p.name, p.platform, COUNT(i.id), COALESCE(SUM(i.value), 0)
+		 FROM portfolios p
+		 LEFT JOIN investments i ON i.account_id = p.id AND i.user_id = p.user_id AND i.active
+		 WHERE p.user_id = $1
+		 GROUP BY p.id, p.name, p.platform, p.display_order
+		 ORDER BY p.display_order ASC NULLS LAST, LOWER(p.name) ASC, p.id ASC`
+
+// scanPortfolioValue scans a row selected with portfolioValuesQuery
+func scanPortfolioValue(row rowScanner) (*models.PortfolioValue, error) {
+	var v models.PortfolioValue
+	if err := row.Scan(&v.PortfolioID, &v.Name, &v.Platform, &v.HoldingCount, &v.Value); err != nil {
+		return nil, err
+	}
+	return &v, nil
 }
 
-// GetInvestmentsByPlatform returns investments for a specific platform
-func (s *PostgresStore) GetInvestmentsByPlatform(platform models.Platform) []*models.Investment {
-	ctx, cancel := s.getContext()
+// GetPortfolioValues returns the value and number of active holdings of every portfolio
+func (s *PostgresStore) GetPortfolioValues(ctx context.Context) ([]*models.PortfolioValue, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, account_id, platform, symbol, name, quantity, value, price, currency, asset_type, last_updated, created_at, updated_at FROM investments WHERE platform = $1 ORDER BY created_at DESC",
-		platform)
+	rows, err := s.db.Query(ctx, portfolioValuesQuery, s.userID)
 	if err != nil {
-		log.Printf("Failed to get investments by platform %s: %v", platform, err)
-		return []*models.Investment{}
+		return nil, fmt.Errorf("failed to get portfolio values: %w", err)
 	}
 	defer rows.Close()
 
-	investments := make([]*models.Investment, 0)
+	values := make([]*models.PortfolioValue, 0)
 	for rows.Next() {
-		var inv models.Investment
-		var lastUpdated, createdAt, updatedAt sql.NullTime
-		var name, assetType sql.NullString
-
-		err := rows.Scan(&inv.ID, &inv.AccountID, &inv.Platform, &inv.Symbol, &name, &inv.Quantity, &inv.Value, &inv.Price, &inv.Currency, &assetType, &lastUpdated, &createdAt, &updatedAt)
+		value, err := scanPortfolioValue(rows)
 		if err != nil {
-			continue
-		}
-
-		if name.Valid {
-			inv.Name = name.String
-		}
-		if assetType.Valid {
-			inv.AssetType = assetType.String
+			return nil, fmt.Errorf("failed to scan portfolio value: %w", err)
 		}
-		inv.LastUpdated = parseTimestamp(lastUpdated)
-
-		investments = append(investments, &inv)
+		values = append(values, value)
 	}
-
-	return investments
+	return values, rows.Err()
 }
 
-// CreateOrUpdateInvestment creates or updates an investment
-func (s *PostgresStore) CreateOrUpdateInvestment(investment *models.Investment) {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	var lastUpdated interface{}
-	if investment.LastUpdated != "" {
-		t, err := time.Parse(time.RFC3339, investment.LastUpdated)
-		if err == nil {
-			lastUpdated = t
-		}
+// CreateOrUpdatePortfolio creates or updates a portfolio.
+// User-managed metadata is only overwritten when the incoming portfolio sets it, so syncs preserve it.
+func (s *PostgresStore) CreateOrUpdatePortfolio(ctx context.Context, portfolio *models.Portfolio) (UpsertResult, error) {
+	existing, err := s.GetPortfolioByID(ctx, portfolio.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
 	}
 
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO investments (id, account_id, platform, symbol, name, quantity, value, price, currency, asset_type, last_updated, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	lastSynced := nullableTime(portfolio.LastSynced)
+
+	result, err := s.db.Exec(ctx,
+		`INSERT INTO portfolios (id, platform, name, type, last_synced, tax_treatment, custodian, display_order, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		 ON CONFLICT (id) DO UPDATE SET
-		 account_id = EXCLUDED.account_id,
 		 platform = EXCLUDED.platform,
-		 symbol = EXCLUDED.symbol,
 		 name = EXCLUDED.name,
-		 quantity = EXCLUDED.quantity,
-		 value = EXCLUDED.value,
-		 price = EXCLUDED.price,
-		 currency = EXCLUDED.currency,
-		 asset_type = EXCLUDED.asset_type,
-		 last_updated = EXCLUDED.last_updated,
-		 updated_at = CURRENT_TIMESTAMP`,
-		investment.ID, investment.AccountID, investment.Platform, investment.Symbol, investment.Name,
-		investment.Quantity, investment.Value, investment.Price, investment.Currency, investment.AssetType, lastUpdated)
+		 type = EXCLUDED.type,
+		 last_synced = EXCLUDED.last_synced,
+		 tax_treatment = COALESCE(EXCLUDED.tax_treatment, portfolios.tax_treatment),
+		 custodian = COALESCE(EXCLUDED.custodian, portfolios.custodian),
+		 display_order = COALESCE(EXCLUDED.display_order, portfolios.display_order),
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE portfolios.user_id = EXCLUDED.user_id`,
+		portfolio.ID, portfolio.Platform, portfolio.Name, portfolio.Type, lastSynced,
+		string(portfolio.TaxTreatment), portfolio.Custodian, portfolio.DisplayOrder, s.userID)
 
 	if err != nil {
-		log.Printf("Failed to create/update investment %s: %v", investment.ID, err)
+		return 0, fmt.Errorf("failed to create/update portfolio %s: %w", portfolio.ID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return UpsertSkipped, nil
 	}
+	return upsertResult(existing, portfolio, samePortfolio), nil
 }
 
-// DeleteInvestment deletes an investment by ID
-func (s *PostgresStore) DeleteInvestment(id string) bool {
-	ctx, cancel := s.getContext()
+// UpdatePortfolioMetadata applies a partial metadata update to an existing portfolio
+func (s *PostgresStore) UpdatePortfolioMetadata(ctx context.Context, id string, update models.PortfolioMetadataUpdate) (*models.Portfolio, error) {
+	portfolio, err := s.GetPortfolioByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update.Apply(portfolio)
+
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	result, err := s.pool.Exec(ctx, "DELETE FROM investments WHERE id = $1", id)
+	_, err = s.db.Exec(ctx,
+		`UPDATE portfolios
+		 SET tax_treatment = NULLIF($2, ''), custodian = NULLIF($3, ''), display_order = $4, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND user_id = $5`,
+		id, string(portfolio.TaxTreatment), portfolio.Custodian, portfolio.DisplayOrder, s.userID)
 	if err != nil {
-		log.Printf("Failed to delete investment %s: %v", id, err)
-		return false
+		return nil, fmt.Errorf("failed to update metadata for portfolio %s: %w", id, err)
 	}
-	return result.RowsAffected() > 0
+	return portfolio, nil
 }
 
-// NetWorth operations
+// DeletePortfolio deletes a portfolio by ID
+func (s *PostgresStore) DeletePortfolio(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio %s: %w", id, err)
+	}
+	defer tx.Rollback(ctx)
 
-// GetNetWorth returns the current net worth (calculated on the fly)
-func (s *PostgresStore) GetNetWorth() *models.NetWorth {
-	return s.RecalculateNetWorth()
+	result, err := tx.Exec(ctx, "DELETE FROM portfolios WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio %s: %w", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	// Transactions have no foreign key to portfolios, so cascade by hand
+	if _, err := tx.Exec(ctx, "DELETE FROM transactions WHERE account_id = $1 AND user_id = $2", id, s.userID); err != nil {
+		return fmt.Errorf("failed to delete transactions for portfolio %s: %w", id, err)
+	}
+	return tx.Commit(ctx)
 }
 
-// UpdateNetWorth updates the net worth calculation (no-op for PostgresStore, always recalculates)
-func (s *PostgresStore) UpdateNetWorth(networth *models.NetWorth) {
-	// No-op: NetWorth is always calculated from investments
-}
+// Account operations
 
-// RecalculateNetWorth recalculates net worth from current accounts and investments
-func (s *PostgresStore) RecalculateNetWorth() *models.NetWorth {
-	networth := &models.NetWorth{
-		ByPlatform:    make(map[models.Platform]float64),
-		ByAssetType:    make(map[string]float64),
-		Currency:       "USD",
-		LastCalculated: time.Now().UTC().Format(time.RFC3339),
+// accountColumns is the column list shared by all account SELECT queries (see scanAccount)
+const accountColumns = "id, platform, portfolio_id, name, currency, type, available_balance, hold, active, last_synced"
+
+// accountOrder sorts accounts by platform, currency and ID
+const accountOrder = " ORDER BY platform, currency, id"
+
+// accountUpsertSQL inserts or updates one account; its arguments are the account fields in
+// accountColumns order followed by the user ID
+const accountUpsertSQL = `INSERT INTO accounts (id, platform, portfolio_id, name, currency, type, available_balance, hold, active, last_synced, user_id, created_at, updated_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, $8, $9, $10, $11, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 platform = EXCLUDED.platform,
+		 portfolio_id = EXCLUDED.portfolio_id,
+		 name = EXCLUDED.name,
+		 currency = EXCLUDED.currency,
+		 type = EXCLUDED.type,
+		 available_balance = EXCLUDED.available_balance,
+		 hold = EXCLUDED.hold,
+		 active = EXCLUDED.active,
+		 last_synced = EXCLUDED.last_synced,
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE accounts.user_id = EXCLUDED.user_id`
+
+// scanAccount scans a row selected with accountColumns into an Account
+func scanAccount(row rowScanner) (*models.Account, error) {
+	var a models.Account
+	var portfolioID, accountType sql.NullString
+	var lastSynced sql.NullTime
+
+	err := row.Scan(&a.ID, &a.Platform, &portfolioID, &a.Name, &a.Currency, &accountType, &a.Available, &a.Hold, &a.Active, &lastSynced)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get total value and breakdowns by platform and asset type
-	ctx, cancel := s.getContext()
+	a.PortfolioID = portfolioID.String
+	a.Type = accountType.String
+	a.LastSynced = parseTimestamp(lastSynced)
+	return &a, nil
+}
+
+// queryAccounts runs an account SELECT and scans every row
+func (s *PostgresStore) queryAccounts(ctx context.Context, query string, args ...interface{}) ([]*models.Account, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		`SELECT platform, asset_type, SUM(value) as total_value
-		 FROM investments
-		 GROUP BY platform, asset_type`)
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to calculate net worth: %v", err)
-		return networth
+		return nil, err
 	}
 	defer rows.Close()
 
-	var totalValue float64
+	accounts := make([]*models.Account, 0)
 	for rows.Next() {
-		var platform models.Platform
-		var assetType sql.NullString
-		var value float64
-
-		err := rows.Scan(&platform, &assetType, &value)
+		a, err := scanAccount(rows)
 		if err != nil {
-			continue
-		}
-
-		totalValue += value
-		networth.ByPlatform[platform] += value
-
-		if assetType.Valid {
-			networth.ByAssetType[assetType.String] += value
+			return nil, err
 		}
+		accounts = append(accounts, a)
 	}
+	return accounts, rows.Err()
+}
 
-	networth.TotalValue = totalValue
-
-	// Get portfolio count
-	var count int
-	err = s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM portfolios").Scan(&count)
+// GetAllAccounts returns all accounts
+func (s *PostgresStore) GetAllAccounts(ctx context.Context) ([]*models.Account, error) {
+	accounts, err := s.queryAccounts(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE user_id = $1"+accountOrder, s.userID)
 	if err != nil {
-		log.Printf("Failed to get portfolio count: %v", err)
-	} else {
-		networth.AccountCount = count
+		return nil, fmt.Errorf("failed to get all accounts: %w", err)
 	}
-
-	return networth
+	return accounts, nil
 }
 
-// Sync metadata operations
+// GetAccountsByPlatform returns accounts for a specific platform
+func (s *PostgresStore) GetAccountsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Account, error) {
+	accounts, err := s.queryAccounts(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 AND platform = $2"+accountOrder,
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts by platform %s: %w", platform, err)
+	}
+	return accounts, nil
+}
 
-// GetLastSyncTime returns the last sync time
-func (s *PostgresStore) GetLastSyncTime() time.Time {
-	ctx, cancel := s.getContext()
+// GetAccountByID returns an account by ID
+func (s *PostgresStore) GetAccountByID(ctx context.Context, id string) (*models.Account, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	var lastSync sql.NullTime
-	err := s.pool.QueryRow(ctx,
-		"SELECT last_sync_time FROM sync_metadata WHERE platform = $1 ORDER BY updated_at DESC LIMIT 1",
-		models.PlatformCoinbase).Scan(&lastSync)
-
+	a, err := scanAccount(s.db.QueryRow(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE id = $1 AND user_id = $2", id, s.userID))
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get last sync time: %v", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return time.Time{}
+		return nil, fmt.Errorf("failed to get account %s: %w", id, err)
+	}
+	return a, nil
+}
+
+// CreateOrUpdateAccount creates or updates an account
+func (s *PostgresStore) CreateOrUpdateAccount(ctx context.Context, account *models.Account) (UpsertResult, error) {
+	existing, err := s.GetAccountByID(ctx, account.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	account.Currency = normalizeCurrency(account.Currency)
+	result, err := s.db.Exec(ctx, accountUpsertSQL,
+		account.ID, account.Platform, account.PortfolioID, account.Name, account.Currency, account.Type,
+		account.Available, account.Hold, account.Active, nullableTime(account.LastSynced), s.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create/update account %s: %w", account.ID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return UpsertSkipped, nil
+	}
+	return upsertResult(existing, account, sameAccount), nil
+}
+
+// DeleteAccount deletes an account by ID
+func (s *PostgresStore) DeleteAccount(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, "DELETE FROM accounts WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete account %s: %w", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Investment operations
+
+// investmentColumns is the column list shared by all investment SELECT queries (see scanInvestment)
+const investmentColumns = "id, account_id, platform, symbol, name, quantity, value, price, currency, native_value, native_currency, asset_type, staked, stale_price, is_dust, price_change_24h_pct, value_change_24h, cost_basis, average_buy_price, first_acquired_at, unrealized_gain, last_updated, active, deactivated_at, created_at, updated_at"
+
+// scanInvestment scans a row selected with investmentColumns into an Investment
+func scanInvestment(row rowScanner) (*models.Investment, error) {
+	var inv models.Investment
+	var lastUpdated, firstAcquiredAt, deactivatedAt, createdAt, updatedAt sql.NullTime
+	var name, nativeCurrency, assetType sql.NullString
+	var nativeValue, priceChange, valueChange, costBasis, averageBuyPrice, unrealizedGain sql.NullFloat64
+
+	err := row.Scan(&inv.ID, &inv.AccountID, &inv.Platform, &inv.Symbol, &name, &inv.Quantity, &inv.Value, &inv.Price, &inv.Currency, &nativeValue, &nativeCurrency, &assetType, &inv.Staked, &inv.StalePrice, &inv.IsDust,
+		&priceChange, &valueChange, &costBasis, &averageBuyPrice, &firstAcquiredAt, &unrealizedGain, &lastUpdated, &inv.Active, &deactivatedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if name.Valid {
+		inv.Name = name.String
+	}
+	if assetType.Valid {
+		inv.AssetType = models.AssetType(assetType.String)
+	}
+	inv.NativeValue = parseFloatPtr(nativeValue)
+	inv.NativeCurrency = nativeCurrency.String
+	inv.PriceChange24hPct = parseFloatPtr(priceChange)
+	inv.ValueChange24h = parseFloatPtr(valueChange)
+	inv.CostBasis = parseFloatPtr(costBasis)
+	inv.AverageBuyPrice = parseFloatPtr(averageBuyPrice)
+	inv.FirstAcquiredAt = parseTimestampPtr(firstAcquiredAt)
+	inv.UnrealizedGain = parseFloatPtr(unrealizedGain)
+	inv.LastUpdated = parseTimestamp(lastUpdated)
+	inv.DeactivatedAt = parseTimestampPtr(deactivatedAt)
+
+	return &inv, nil
+}
+
+// queryInvestments runs an investment SELECT and scans every row
+func (s *PostgresStore) queryInvestments(ctx context.Context, query string, args ...interface{}) ([]*models.Investment, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	investments := make([]*models.Investment, 0)
+	for rows.Next() {
+		inv, err := scanInvestment(rows)
+		if err != nil {
+			return nil, err
+		}
+		investments = append(investments, inv)
+	}
+
+	return investments, rows.Err()
+}
+
+// GetAllInvestments returns a page of investments along with the total number of investments
+func (s *PostgresStore) GetAllInvestments(ctx context.Context, opts ListOptions, order SortOption, filter InvestmentFilter) ([]*models.Investment, int, error) {
+	orderBy, err := investmentSortFields.orderBy(order, " ORDER BY created_at DESC, id")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.count(ctx, "SELECT COUNT(*) FROM investments WHERE user_id = $1"+filter.sqlWhere(), s.userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count investments: %w", err)
+	}
+
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1"+filter.sqlWhere()+orderBy+page,
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get all investments: %w", err)
+	}
+	return investments, total, nil
+}
+
+// CountInvestments returns the number of active investments, on platform if it is not empty
+func (s *PostgresStore) CountInvestments(ctx context.Context, platform models.Platform) (int, error) {
+	query := "SELECT COUNT(*) FROM investments WHERE user_id = $1 AND active"
+	args := []interface{}{s.userID}
+	if platform != "" {
+		query += " AND platform = $2"
+		args = append(args, platform)
+	}
+	count, err := s.count(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count investments: %w", err)
+	}
+	return count, nil
+}
+
+// GetInvestmentsByAccount returns investments for a specific account
+func (s *PostgresStore) GetInvestmentsByAccount(ctx context.Context, accountID string, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND account_id = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by account %s: %w", accountID, err)
+	}
+	return investments, nil
+}
+
+// GetInvestmentsByPlatform returns investments for a specific platform
+func (s *PostgresStore) GetInvestmentsByPlatform(ctx context.Context, platform models.Platform, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND platform = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by platform %s: %w", platform, err)
+	}
+	return investments, nil
+}
+
+// GetInvestmentsBySymbol returns investments in a symbol across all accounts and platforms
+func (s *PostgresStore) GetInvestmentsBySymbol(ctx context.Context, symbol string, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND UPPER(symbol) = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, strings.ToUpper(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by symbol %s: %w", symbol, err)
+	}
+	return investments, nil
+}
+
+// investmentUpsertSQL inserts or updates one investment as active; see investmentUpsertArgs.
+// Cost basis fields are computed rather than synced, so a NULL never overwrites a stored value.
+const investmentUpsertSQL = `INSERT INTO investments (id, account_id, platform, symbol, name, quantity, value, price, currency, native_value, native_currency, asset_type, staked, stale_price, is_dust, price_change_24h_pct, value_change_24h,
+		 cost_basis, average_buy_price, first_acquired_at, unrealized_gain, last_updated, active, deactivated_at, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, TRUE, NULL, $23, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 account_id = EXCLUDED.account_id,
+		 platform = EXCLUDED.platform,
+		 symbol = EXCLUDED.symbol,
+		 name = EXCLUDED.name,
+		 quantity = EXCLUDED.quantity,
+		 value = EXCLUDED.value,
+		 price = EXCLUDED.price,
+		 currency = EXCLUDED.currency,
+		 native_value = EXCLUDED.native_value,
+		 native_currency = EXCLUDED.native_currency,
+		 asset_type = EXCLUDED.asset_type,
+		 staked = EXCLUDED.staked,
+		 stale_price = EXCLUDED.stale_price,
+		 is_dust = EXCLUDED.is_dust,
+		 price_change_24h_pct = EXCLUDED.price_change_24h_pct,
+		 value_change_24h = EXCLUDED.value_change_24h,
+		 cost_basis = COALESCE(EXCLUDED.cost_basis, investments.cost_basis),
+		 average_buy_price = COALESCE(EXCLUDED.average_buy_price, investments.average_buy_price),
+		 first_acquired_at = COALESCE(EXCLUDED.first_acquired_at, investments.first_acquired_at),
+		 unrealized_gain = COALESCE(EXCLUDED.unrealized_gain, investments.unrealized_gain),
+		 last_updated = EXCLUDED.last_updated,
+		 active = TRUE,
+		 deactivated_at = NULL,
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE investments.user_id = EXCLUDED.user_id`
+
+// investmentUpsertArgs normalizes the investment in place and returns the arguments for
+// investmentUpsertSQL
+func investmentUpsertArgs(investment *models.Investment, userID string) []interface{} {
+	investment.AssetType, _ = models.NormalizeAssetType(string(investment.AssetType))
+	investment.Currency = normalizeCurrency(investment.Currency)
+	investment.NativeCurrency = normalizeCurrency(investment.NativeCurrency)
+	investment.Active = true
+	investment.DeactivatedAt = nil
+	var firstAcquiredAt interface{}
+	if investment.FirstAcquiredAt != nil {
+		firstAcquiredAt = nullableTime(*investment.FirstAcquiredAt)
+	}
+	var nativeCurrency interface{}
+	if investment.NativeCurrency != "" {
+		nativeCurrency = investment.NativeCurrency
+	}
+	return []interface{}{
+		investment.ID, investment.AccountID, investment.Platform, investment.Symbol, investment.Name,
+		investment.Quantity, investment.Value, investment.Price, investment.Currency, investment.NativeValue, nativeCurrency, investment.AssetType, investment.Staked, investment.StalePrice, investment.IsDust,
+		investment.PriceChange24hPct, investment.ValueChange24h,
+		investment.CostBasis, investment.AverageBuyPrice, firstAcquiredAt, investment.UnrealizedGain,
+		nullableTime(investment.LastUpdated), userID,
+	}
+}
+
+// CreateOrUpdateInvestment creates or updates an investment.
+// Cost basis fields are computed rather than synced, so a nil value never overwrites a stored one.
+func (s *PostgresStore) CreateOrUpdateInvestment(ctx context.Context, investment *models.Investment) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	if _, err := s.db.Exec(ctx, investmentUpsertSQL, investmentUpsertArgs(investment, s.userID)...); err != nil {
+		return fmt.Errorf("failed to create/update investment %s: %w", investment.ID, err)
+	}
+	return nil
+}
+
+// CreateOrUpdateInvestments upserts investments in one transaction, sending every statement
+// in a single batch. It counts how many were created, updated, left unchanged or skipped.
+func (s *PostgresStore) CreateOrUpdateInvestments(ctx context.Context, investments []*models.Investment) (UpsertCounts, error) {
+	var counts UpsertCounts
+	if len(investments) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]string, len(investments))
+	for i, investment := range investments {
+		ids[i] = investment.ID
+	}
+	existing, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND id = ANY($2)", s.userID, ids)
+	if err != nil {
+		return counts, fmt.Errorf("failed to load existing investments: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin investment batch: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, investment := range investments {
+		batch.Queue(investmentUpsertSQL, investmentUpsertArgs(investment, s.userID)...)
+	}
+	results := tx.SendBatch(ctx, batch)
+	written := make([]bool, len(investments))
+	for i, investment := range investments {
+		tag, err := results.Exec()
+		if err != nil {
+			results.Close()
+			return counts, fmt.Errorf("failed to create/update investment %s: %w", investment.ID, err)
+		}
+		written[i] = tag.RowsAffected() > 0
+	}
+	if err := results.Close(); err != nil {
+		return counts, fmt.Errorf("failed to create/update investments: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return counts, fmt.Errorf("failed to commit investment batch: %w", err)
+	}
+	return countInvestmentUpserts(existing, investments, written), nil
+}
+
+// DeactivateMissingInvestments marks active investments on platform in accountIDs that are
+// not in keepIDs as inactive
+func (s *PostgresStore) DeactivateMissingInvestments(ctx context.Context, platform models.Platform, accountIDs, keepIDs []string, at time.Time) (int, error) {
+	if len(accountIDs) == 0 {
+		return 0, nil
+	}
+	if keepIDs == nil {
+		// A NULL array would match nothing rather than everything
+		keepIDs = []string{}
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx,
+		`UPDATE investments SET active = FALSE, deactivated_at = $5, updated_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND platform = $2 AND active AND account_id = ANY($3) AND NOT (id = ANY($4))`,
+		s.userID, platform, accountIDs, keepIDs, at.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate missing %s investments: %w", platform, err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// DeactivateInvestments marks the active investments with the given IDs as inactive
+func (s *PostgresStore) DeactivateInvestments(ctx context.Context, ids []string, at time.Time) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx,
+		`UPDATE investments SET active = FALSE, deactivated_at = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND active AND id = ANY($2)`,
+		s.userID, ids, at.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate investments: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+// investmentPriceSQL reprices one active investment at its stored quantity; the unrealized
+// gain is only recomputed when a cost basis is known
+const investmentPriceSQL = `UPDATE investments SET price = $1, value = $1 * quantity,
+		 unrealized_gain = CASE WHEN cost_basis IS NULL THEN unrealized_gain ELSE $1 * quantity - cost_basis END,
+		 stale_price = FALSE, last_updated = $2, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $3 AND user_id = $4 AND active`
+
+// UpdateInvestmentPrice reprices an active investment at its stored quantity
+func (s *PostgresStore) UpdateInvestmentPrice(ctx context.Context, id string, price float64, at time.Time) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, investmentPriceSQL, price, at.UTC(), id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to update the price of investment %s: %w", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteInvestment deletes an investment by ID
+func (s *PostgresStore) DeleteInvestment(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, "DELETE FROM investments WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete investment %s: %w", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NetWorth operations
+
+// GetNetWorth returns the current net worth (calculated on the fly)
+func (s *PostgresStore) GetNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	return s.RecalculateNetWorth(ctx)
+}
+
+// UpdateNetWorth updates the net worth calculation (no-op for PostgresStore, always recalculates)
+func (s *PostgresStore) UpdateNetWorth(ctx context.Context, networth *models.NetWorth) error {
+	// No-op: NetWorth is always calculated from investments
+	return nil
+}
+
+// networthQuery sums the active investments of user $1 by platform, asset type, tax treatment and
+// currency, with the value change of their 24 hour price moves, NULL where none is known
+const networthQuery = `SELECT i.platform, i.asset_type, p.tax_treatment, UPPER(i.currency), SUM(i.value) as total_value, SUM(i.value_change_24h)
+	 FROM investments i
+	 LEFT JOIN portfolios p ON p.id = i.account_id AND p.user_id = i.user_id
+	 WHERE i.user_id = $1 AND i.active
+	 GROUP BY i.platform, i.asset_type, p.tax_treatment, UPPER(i.currency)`
+
+// RecalculateNetWorth recalculates net worth from current accounts and investments
+func (s *PostgresStore) RecalculateNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	networth := &models.NetWorth{
+		ByPlatform:    make(map[models.Platform]float64),
+		ByAssetType:    make(map[models.AssetType]float64),
+		ByTaxTreatment: make(map[models.TaxTreatment]float64),
+		Currency:       currency.Reporting,
+		LastCalculated: models.Now(),
+	}
+
+	// Get total value and breakdowns by platform, asset type and currency
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx, networthQuery, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate net worth: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var platform models.Platform
+		var assetType, taxTreatment, code sql.NullString
+		var value float64
+		var priceChange sql.NullFloat64
+
+		err := rows.Scan(&platform, &assetType, &taxTreatment, &code, &value, &priceChange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan net worth row: %w", err)
+		}
+		networth.AddHolding(platform, assetType.String, models.TaxTreatment(taxTreatment.String), code.String, value)
+		if priceChange.Valid {
+			networth.AddPriceChange(code.String, priceChange.Float64)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to calculate net worth: %w", err)
+	}
+
+	// Get portfolio count
+	var count int
+	err = s.db.QueryRow(ctx, "SELECT COUNT(*) FROM portfolios WHERE user_id = $1", s.userID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio count: %w", err)
+	}
+	networth.AccountCount = count
+
+	return networth, nil
+}
+
+// Net worth history operations
+
+// networthSnapshotColumns is the column list scanned by scanNetWorthSnapshot
+const networthSnapshotColumns = "taken_at, total_value, currency, by_platform, by_asset_type, by_tax_treatment, account_count"
+
+// scanNetWorthSnapshot scans a row selected with networthSnapshotColumns, decoding the breakdowns
+func scanNetWorthSnapshot(row rowScanner) (*models.NetWorthSnapshot, error) {
+	var snapshot models.NetWorthSnapshot
+	var takenAt sql.NullTime
+	var byPlatformJSON, byAssetTypeJSON, byTaxTreatmentJSON []byte
+
+	err := row.Scan(&takenAt, &snapshot.TotalValue, &snapshot.Currency, &byPlatformJSON, &byAssetTypeJSON, &byTaxTreatmentJSON, &snapshot.AccountCount)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Timestamp = parseTimestamp(takenAt)
+	if err := json.Unmarshal(byPlatformJSON, &snapshot.ByPlatform); err != nil {
+		return nil, fmt.Errorf("failed to decode platform breakdown: %w", err)
+	}
+	if err := json.Unmarshal(byAssetTypeJSON, &snapshot.ByAssetType); err != nil {
+		return nil, fmt.Errorf("failed to decode asset type breakdown: %w", err)
+	}
+	if err := json.Unmarshal(byTaxTreatmentJSON, &snapshot.ByTaxTreatment); err != nil {
+		return nil, fmt.Errorf("failed to decode tax treatment breakdown: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// snapshotBreakdowns marshals a snapshot's breakdown maps for storage
+func snapshotBreakdowns(snapshot *models.NetWorthSnapshot) (byPlatform, byAssetType, byTaxTreatment []byte, err error) {
+	if byPlatform, err = json.Marshal(snapshot.ByPlatform); err != nil {
+		return nil, nil, nil, err
+	}
+	if byAssetType, err = json.Marshal(snapshot.ByAssetType); err != nil {
+		return nil, nil, nil, err
+	}
+	if byTaxTreatment, err = json.Marshal(snapshot.ByTaxTreatment); err != nil {
+		return nil, nil, nil, err
+	}
+	return byPlatform, byAssetType, byTaxTreatment, nil
+}
+
+// networthSnapshotUpsertSQL stores a snapshot, replacing any taken in the same minute
+const networthSnapshotUpsertSQL = `INSERT INTO networth_snapshots (user_id, taken_at, total_value, currency, by_platform, by_asset_type, by_tax_treatment, account_count)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (user_id, taken_at) DO UPDATE SET
+		 total_value = EXCLUDED.total_value,
+		 currency = EXCLUDED.currency,
+		 by_platform = EXCLUDED.by_platform,
+		 by_asset_type = EXCLUDED.by_asset_type,
+		 by_tax_treatment = EXCLUDED.by_tax_treatment,
+		 account_count = EXCLUDED.account_count`
+
+// snapshotRangeQuery builds the SELECT for a user's snapshots in [from, to), oldest first
+func snapshotRangeQuery(userID string, from, to time.Time) (string, []interface{}) {
+	query := "SELECT " + networthSnapshotColumns + " FROM networth_snapshots WHERE user_id = $1"
+	args := []interface{}{userID}
+	if !from.IsZero() {
+		args = append(args, from.UTC())
+		query += fmt.Sprintf(" AND taken_at >= $%d", len(args))
+	}
+	if !to.IsZero() {
+		args = append(args, to.UTC())
+		query += fmt.Sprintf(" AND taken_at < $%d", len(args))
+	}
+	return query + " ORDER BY taken_at ASC", args
+}
+
+// SaveNetWorthSnapshot records a calculated net worth in the history
+func (s *PostgresStore) SaveNetWorthSnapshot(ctx context.Context, networth *models.NetWorth) error {
+	snapshot := models.NewNetWorthSnapshot(networth)
+	byPlatform, byAssetType, byTaxTreatment, err := snapshotBreakdowns(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode net worth snapshot: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err = s.db.Exec(ctx, networthSnapshotUpsertSQL,
+		s.userID, snapshot.Timestamp, snapshot.TotalValue, snapshot.Currency, byPlatform, byAssetType, byTaxTreatment, snapshot.AccountCount)
+	if err != nil {
+		return fmt.Errorf("failed to save net worth snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetNetWorthSnapshots returns snapshots taken in [from, to), oldest first
+func (s *PostgresStore) GetNetWorthSnapshots(ctx context.Context, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	query, args := snapshotRangeQuery(s.userID, from, to)
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get net worth snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*models.NetWorthSnapshot, 0)
+	for rows.Next() {
+		snapshot, err := scanNetWorthSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan net worth snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// Investment history operations
+
+// investmentHistoryColumns is the column list scanned by scanInvestmentHistoryPoint
+const investmentHistoryColumns = "taken_at, platform, symbol, quantity, price, value, currency"
+
+// scanInvestmentHistoryPoint scans a row selected with investmentHistoryColumns
+func scanInvestmentHistoryPoint(row rowScanner) (*models.InvestmentHistoryPoint, error) {
+	var point models.InvestmentHistoryPoint
+	var takenAt sql.NullTime
+	if err := row.Scan(&takenAt, &point.Platform, &point.Symbol, &point.Quantity, &point.Price, &point.Value, &point.Currency); err != nil {
+		return nil, err
+	}
+	point.Timestamp = parseTimestamp(takenAt)
+	return &point, nil
+}
+
+// investmentHistoryUpsertSQL stores a history point, replacing any for the same platform and
+// symbol in the same minute; see investmentHistoryUpsertArgs
+const investmentHistoryUpsertSQL = `INSERT INTO investment_history (user_id, platform, symbol, taken_at, quantity, price, value, currency)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (user_id, symbol, platform, taken_at) DO UPDATE SET
+		 quantity = EXCLUDED.quantity,
+		 price = EXCLUDED.price,
+		 value = EXCLUDED.value,
+		 currency = EXCLUDED.currency`
+
+// investmentHistoryUpsertArgs returns the arguments of investmentHistoryUpsertSQL for point
+func investmentHistoryUpsertArgs(point *models.InvestmentHistoryPoint, userID string) []interface{} {
+	return []interface{}{
+		userID, point.Platform, strings.ToUpper(point.Symbol), point.Timestamp.UTC().Truncate(time.Minute),
+		point.Quantity, point.Price, point.Value, point.Currency,
+	}
+}
+
+// investmentHistoryRangeQuery builds the SELECT for a user's history of symbol in [from, to),
+// oldest first
+func investmentHistoryRangeQuery(userID, symbol string, from, to time.Time) (string, []interface{}) {
+	query := "SELECT " + investmentHistoryColumns + " FROM investment_history WHERE user_id = $1 AND symbol = $2"
+	args := []interface{}{userID, strings.ToUpper(symbol)}
+	if !from.IsZero() {
+		args = append(args, from.UTC())
+		query += fmt.Sprintf(" AND taken_at >= $%d", len(args))
+	}
+	if !to.IsZero() {
+		args = append(args, to.UTC())
+		query += fmt.Sprintf(" AND taken_at < $%d", len(args))
+	}
+	return query + " ORDER BY taken_at ASC, platform ASC", args
+}
+
+// SaveInvestmentHistory records points in the per-symbol history as one batch
+func (s *PostgresStore) SaveInvestmentHistory(ctx context.Context, points []*models.InvestmentHistoryPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin investment history batch: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, point := range points {
+		batch.Queue(investmentHistoryUpsertSQL, investmentHistoryUpsertArgs(point, s.userID)...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to save investment history: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit investment history batch: %w", err)
+	}
+	return nil
+}
+
+// GetInvestmentHistory returns the points for symbol recorded in [from, to), oldest first
+func (s *PostgresStore) GetInvestmentHistory(ctx context.Context, symbol string, from, to time.Time) ([]*models.InvestmentHistoryPoint, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	query, args := investmentHistoryRangeQuery(s.userID, symbol, from, to)
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investment history: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]*models.InvestmentHistoryPoint, 0)
+	for rows.Next() {
+		point, err := scanInvestmentHistoryPoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan investment history: %w", err)
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// Transaction operations
+
+// transactionUpsertSQL inserts or updates one transaction; see transactionUpsertArgs
+const transactionUpsertSQL = `INSERT INTO transactions (id, account_id, platform, type, symbol, quantity, amount, currency, fee, timestamp, description, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 account_id = EXCLUDED.account_id,
+		 platform = EXCLUDED.platform,
+		 type = EXCLUDED.type,
+		 symbol = EXCLUDED.symbol,
+		 quantity = EXCLUDED.quantity,
+		 amount = EXCLUDED.amount,
+		 currency = EXCLUDED.currency,
+		 fee = EXCLUDED.fee,
+		 timestamp = EXCLUDED.timestamp,
+		 description = EXCLUDED.description
+		 WHERE transactions.user_id = EXCLUDED.user_id`
+
+// transactionUpsertArgs normalizes the transaction in place and returns the arguments for
+// transactionUpsertSQL
+func transactionUpsertArgs(transaction *models.Transaction, userID string) []interface{} {
+	transaction.Currency = normalizeCurrency(transaction.Currency)
+	transaction.Timestamp = transaction.Timestamp.UTC()
+	return []interface{}{
+		transaction.ID, transaction.AccountID, transaction.Platform, transaction.Type, transaction.Symbol,
+		transaction.Quantity, transaction.Amount, transaction.Currency, transaction.Fee,
+		nullableTime(transaction.Timestamp), transaction.Description, userID,
+	}
+}
+
+// CreateOrUpdateTransaction creates or updates a transaction
+func (s *PostgresStore) CreateOrUpdateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if !transaction.Type.IsValid() {
+		return fmt.Errorf("invalid transaction type %q", transaction.Type)
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	if _, err := s.db.Exec(ctx, transactionUpsertSQL, transactionUpsertArgs(transaction, s.userID)...); err != nil {
+		return fmt.Errorf("failed to create/update transaction %s: %w", transaction.ID, err)
+	}
+	return nil
+}
+
+// GetTransactionsByAccount returns every transaction for an account, newest first
+func (s *PostgresStore) GetTransactionsByAccount(ctx context.Context, accountID string) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{AccountID: accountID})
+	return transactions, err
+}
+
+// GetTransactionsByPlatform returns every transaction for a platform, newest first
+func (s *PostgresStore) GetTransactionsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{Platform: platform})
+	return transactions, err
+}
+
+// GetTransactionsBetween returns transactions in [from, to), newest first
+func (s *PostgresStore) GetTransactionsBetween(ctx context.Context, from, to time.Time) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{From: from, To: to})
+	return transactions, err
+}
+
+// ListTransactions returns transactions matching the filter, newest first, along with the
+// total number of matches before pagination
+func (s *PostgresStore) ListTransactions(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	addCondition("user_id = $%d", s.userID)
+	if filter.Platform != "" {
+		addCondition("platform = $%d", filter.Platform)
+	}
+	if filter.AccountID != "" {
+		addCondition("account_id = $%d", filter.AccountID)
+	}
+	if filter.Symbol != "" {
+		addCondition("UPPER(symbol) = $%d", strings.ToUpper(filter.Symbol))
+	}
+	if filter.Type != "" {
+		addCondition("type = $%d", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		addCondition("timestamp >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("timestamp < $%d", filter.To.UTC())
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM transactions"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	page, args := filter.sqlClause(args)
+	query := "SELECT id, account_id, platform, type, symbol, quantity, amount, currency, fee, timestamp, description FROM transactions" +
+		where + " ORDER BY timestamp DESC, id" + page
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]*models.Transaction, 0)
+	for rows.Next() {
+		var tx models.Transaction
+		var symbol, description sql.NullString
+		var quantity, fee sql.NullFloat64
+		var timestamp sql.NullTime
+
+		err := rows.Scan(&tx.ID, &tx.AccountID, &tx.Platform, &tx.Type, &symbol, &quantity, &tx.Amount, &tx.Currency, &fee, &timestamp, &description)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transaction row: %w", err)
+		}
+
+		if symbol.Valid {
+			tx.Symbol = symbol.String
+		}
+		if quantity.Valid {
+			tx.Quantity = quantity.Float64
+		}
+		if fee.Valid {
+			tx.Fee = fee.Float64
+		}
+		if description.Valid {
+			tx.Description = description.String
+		}
+		tx.Timestamp = parseTimestamp(timestamp)
+
+		transactions = append(transactions, &tx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return transactions, total, nil
+}
+
+// GetTransactionSummary totals a calendar year of transactions by type per currency
+func (s *PostgresStore) GetTransactionSummary(ctx context.Context, year int) (*models.TransactionSummary, error) {
+	summary := &models.TransactionSummary{
+		Year:       year,
+		ByCurrency: make(map[string]*models.TransactionTotals),
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := s.db.Query(ctx,
+		`SELECT currency, type, COALESCE(SUM(amount), 0), COALESCE(SUM(fee), 0)
+		 FROM transactions
+		 WHERE user_id = $1 AND timestamp >= $2 AND timestamp < $3
+		 GROUP BY currency, type`,
+		s.userID, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions for %d: %w", year, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+		var txType models.TransactionType
+		var amount, fee float64
+		if err := rows.Scan(&currency, &txType, &amount, &fee); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction summary row: %w", err)
+		}
+		totals, exists := summary.ByCurrency[currency]
+		if !exists {
+			totals = &models.TransactionTotals{}
+			summary.ByCurrency[currency] = totals
+		}
+		totals.Add(txType, amount, fee)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions for %d: %w", year, err)
+	}
+
+	return summary, nil
+}
+
+// Sync metadata operations
+
+// GetLastSyncTime returns when platform last synced
+func (s *PostgresStore) GetLastSyncTime(ctx context.Context, platform models.Platform) (time.Time, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var lastSync sql.NullTime
+	err := s.db.QueryRow(ctx,
+		"SELECT last_sync_time FROM sync_metadata WHERE user_id = $1 AND platform = $2 ORDER BY updated_at DESC LIMIT 1",
+		s.userID, platform).Scan(&lastSync)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get last sync time: %w", err)
 	}
 
 	if !lastSync.Valid {
-		return time.Time{}
+		return time.Time{}, nil
 	}
 
-	return lastSync.Time
+	return lastSync.Time, nil
 }
 
-// SetLastSyncTime sets the last sync time
-func (s *PostgresStore) SetLastSyncTime(t time.Time) {
-	ctx, cancel := s.getContext()
+// GetSyncRecord returns the latest sync attempt on platform
+func (s *PostgresStore) GetSyncRecord(ctx context.Context, platform models.Platform) (*models.SyncRecord, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO sync_metadata (id, platform, last_sync_time, sync_status, created_at, updated_at)
-		 VALUES ($1, $2, $3, 'success', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		 ON CONFLICT (platform) DO UPDATE SET
-		 last_sync_time = EXCLUDED.last_sync_time,
-		 sync_status = 'success',
+	record, err := scanSyncRecord(s.db.QueryRow(ctx,
+		"SELECT "+syncRecordColumns+" FROM sync_metadata WHERE user_id = $1 AND platform = $2",
+		s.userID, platform))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync record for %s: %w", platform, err)
+	}
+	return record, nil
+}
+
+// RecordSyncResult records a sync attempt on platform
+func (s *PostgresStore) RecordSyncResult(ctx context.Context, platform models.Platform, status models.SyncStatus, errMsg string, counts models.SyncCounts, at time.Time) error {
+	// A failed attempt passes no sync time, so the last successful one is kept
+	var lastSync interface{}
+	if status == models.SyncStatusSuccess {
+		lastSync = at.UTC()
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO sync_metadata (id, user_id, platform, last_sync_time, last_attempt_time, sync_status, error_message,
+		   portfolios_synced, accounts_synced, investments_synced, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (user_id, platform) DO UPDATE SET
+		 last_sync_time = COALESCE(EXCLUDED.last_sync_time, sync_metadata.last_sync_time),
+		 last_attempt_time = EXCLUDED.last_attempt_time,
+		 sync_status = EXCLUDED.sync_status,
+		 error_message = EXCLUDED.error_message,
+		 portfolios_synced = EXCLUDED.portfolios_synced,
+		 accounts_synced = EXCLUDED.accounts_synced,
+		 investments_synced = EXCLUDED.investments_synced,
 		 updated_at = CURRENT_TIMESTAMP`,
-		fmt.Sprintf("sync-%s", models.PlatformCoinbase), models.PlatformCoinbase, t)
+		fmt.Sprintf("sync-%s-%s", s.userID, platform), s.userID, platform, lastSync, at.UTC(), status, errMsg,
+		counts.Portfolios, counts.Accounts, counts.Investments)
+	if err != nil {
+		return fmt.Errorf("failed to record sync result: %w", err)
+	}
+	return nil
+}
+
+// Plaid item operations
+
+// plaidItemColumns is the column list scanned by scanPlaidItem
+const plaidItemColumns = "id, institution_id, institution_name, platform, encrypted_access_token, account_ids, status, last_synced_at, created_at, updated_at"
+
+// plaidItemUpsertSQL inserts or updates one Plaid item; its arguments are the item fields in
+// plaidItemColumns order up to the token, followed by the user ID. An item is only updated by
+// the user who linked it, and linking it again makes it healthy.
+const plaidItemUpsertSQL = `INSERT INTO plaid_items (id, institution_id, institution_name, platform, encrypted_access_token, user_id, created_at, updated_at)
+		 VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 institution_id = EXCLUDED.institution_id,
+		 institution_name = EXCLUDED.institution_name,
+		 platform = EXCLUDED.platform,
+		 encrypted_access_token = EXCLUDED.encrypted_access_token,
+		 status = 'healthy',
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE plaid_items.user_id = EXCLUDED.user_id`
+
+// plaidItemAccountsSQL sets the account IDs of a Plaid item: $1 the JSON array, $2 the item ID
+// and $3 the user ID
+const plaidItemAccountsSQL = "UPDATE plaid_items SET account_ids = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_id = $3"
+
+// plaidItemStatusSQL sets the status of a Plaid item: $1 the status, $2 the time it last synced
+// or NULL to keep the stored one, $3 the item ID and $4 the user ID
+const plaidItemStatusSQL = `UPDATE plaid_items SET status = $1, last_synced_at = COALESCE($2, last_synced_at),
+		 updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND user_id = $4`
+
+// plaidItemSyncedAt is the $2 argument of plaidItemStatusSQL for syncedAt
+func plaidItemSyncedAt(syncedAt *time.Time) any {
+	if syncedAt == nil {
+		return nil
+	}
+	return syncedAt.UTC()
+}
+
+// plaidItemAccountsJSON encodes account IDs for plaidItemAccountsSQL
+func plaidItemAccountsJSON(accountIDs []string) (string, error) {
+	return stringListJSON(accountIDs)
+}
+
+// stringListJSON encodes values as a JSON array for a column defaulting to '[]', so nil is []
+// rather than null
+func stringListJSON(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
+
+// scanPlaidItem scans a row selected with plaidItemColumns into a PlaidItem
+func scanPlaidItem(row rowScanner) (*models.PlaidItem, error) {
+	var item models.PlaidItem
+	var institutionID sql.NullString
+	var accountIDs []byte
+	var lastSyncedAt, createdAt, updatedAt sql.NullTime
+	err := row.Scan(&item.ID, &institutionID, &item.InstitutionName, &item.Platform, &item.EncryptedAccessToken, &accountIDs, &item.Status, &lastSyncedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	item.InstitutionID = institutionID.String
+	if err := json.Unmarshal(accountIDs, &item.AccountIDs); err != nil {
+		return nil, fmt.Errorf("invalid account IDs of plaid item %s: %w", item.ID, err)
+	}
+	if lastSyncedAt.Valid {
+		synced := parseTimestamp(lastSyncedAt)
+		item.LastSyncedAt = &synced
+	}
+	item.CreatedAt = parseTimestamp(createdAt)
+	item.UpdatedAt = parseTimestamp(updatedAt)
+	return &item, nil
+}
+
+// GetPlaidItems returns the user's Plaid items, oldest first
+func (s *PostgresStore) GetPlaidItems(ctx context.Context) ([]*models.PlaidItem, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx,
+		"SELECT "+plaidItemColumns+" FROM plaid_items WHERE user_id = $1 ORDER BY created_at, id", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plaid items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.PlaidItem, 0)
+	for rows.Next() {
+		item, err := scanPlaidItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plaid item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetPlaidItem returns a Plaid item by ID
+func (s *PostgresStore) GetPlaidItem(ctx context.Context, id string) (*models.PlaidItem, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	item, err := scanPlaidItem(s.db.QueryRow(ctx,
+		"SELECT "+plaidItemColumns+" FROM plaid_items WHERE id = $1 AND user_id = $2", id, s.userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plaid item %s: %w", id, err)
+	}
+	return item, nil
+}
+
+// CreateOrUpdatePlaidItem creates or updates a Plaid item
+func (s *PostgresStore) CreateOrUpdatePlaidItem(ctx context.Context, item *models.PlaidItem) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tag, err := s.db.Exec(ctx, plaidItemUpsertSQL,
+		item.ID, item.InstitutionID, item.InstitutionName, item.Platform, item.EncryptedAccessToken, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to create/update plaid item %s: %w", item.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("plaid item %s is linked by another user", item.ID)
+	}
+	return nil
+}
+
+// SetPlaidItemAccounts records the accounts a Plaid item's sync stored
+func (s *PostgresStore) SetPlaidItemAccounts(ctx context.Context, id string, accountIDs []string) error {
+	encoded, err := plaidItemAccountsJSON(accountIDs)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tag, err := s.db.Exec(ctx, plaidItemAccountsSQL, encoded, id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to set the accounts of plaid item %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetPlaidItemStatus records whether a Plaid item can be synced, and when it last synced
+func (s *PostgresStore) SetPlaidItemStatus(ctx context.Context, id string, status models.PlaidItemStatus, syncedAt *time.Time) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tag, err := s.db.Exec(ctx, plaidItemStatusSQL, status, plaidItemSyncedAt(syncedAt), id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to set the status of plaid item %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePlaidItem deletes a Plaid item, and with it the stored access token
+func (s *PostgresStore) DeletePlaidItem(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tag, err := s.db.Exec(ctx, "DELETE FROM plaid_items WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete plaid item %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
 
+// GetPlaidItemUserID returns the user who linked a Plaid item, across users
+func (s *PostgresStore) GetPlaidItemUserID(ctx context.Context, id string) (string, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var userID string
+	err := s.db.QueryRow(ctx, "SELECT user_id FROM plaid_items WHERE id = $1", id).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
 	if err != nil {
-		log.Printf("Failed to set last sync time: %v", err)
+		return "", fmt.Errorf("failed to get the user of plaid item %s: %w", id, err)
 	}
+	return userID, nil
 }
 
 // YouTube Source operations
 
 // GetAllYouTubeSources returns all YouTube sources
-func (s *PostgresStore) GetAllYouTubeSources() []*models.YouTubeSource {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetAllYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, type, url, name, channel_id, playlist_id, enabled, schedule, last_processed, created_at, updated_at FROM youtube_sources ORDER BY created_at DESC")
+	rows, err := s.db.Query(ctx,
+		"SELECT id, type, url, name, channel_id, playlist_id, enabled, schedule, last_processed, created_at, updated_at FROM youtube_sources WHERE user_id = $1 ORDER BY created_at DESC",
+		s.userID)
 	if err != nil {
-		log.Printf("Failed to get all YouTube sources: %v", err)
-		return []*models.YouTubeSource{}
+		return nil, fmt.Errorf("failed to get all YouTube sources: %w", err)
 	}
 	defer rows.Close()
 
@@ -566,7 +1868,7 @@ func (s *PostgresStore) GetAllYouTubeSources() []*models.YouTubeSource {
 
 		err := rows.Scan(&src.ID, &src.Type, &src.URL, &src.Name, &channelID, &playlistID, &src.Enabled, &schedule, &lastProcessed, &createdAt, &updatedAt)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to scan YouTube source row: %w", err)
 		}
 
 		if channelID.Valid {
@@ -583,26 +1885,65 @@ func (s *PostgresStore) GetAllYouTubeSources() []*models.YouTubeSource {
 		sources = append(sources, &src)
 	}
 
-	return sources
+	return sources, rows.Err()
+}
+
+// queryYouTubeSources runs a YouTube source SELECT and scans every row
+func (s *PostgresStore) queryYouTubeSources(ctx context.Context, query string, args ...interface{}) ([]*models.YouTubeSource, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := make([]*models.YouTubeSource, 0)
+	for rows.Next() {
+		src, err := scanYouTubeSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan YouTube source row: %w", err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// GetEnabledYouTubeSources returns the sources that are enabled, newest first
+func (s *PostgresStore) GetEnabledYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	sources, err := s.queryYouTubeSources(ctx, enabledYouTubeSourcesQuery, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enabled YouTube sources: %w", err)
+	}
+	return sources, nil
+}
+
+// GetYouTubeSourcesByType returns the sources of a type, newest first
+func (s *PostgresStore) GetYouTubeSourcesByType(ctx context.Context, sourceType models.YouTubeSourceType) ([]*models.YouTubeSource, error) {
+	sources, err := s.queryYouTubeSources(ctx, youtubeSourcesByTypeQuery, s.userID, sourceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s YouTube sources: %w", sourceType, err)
+	}
+	return sources, nil
 }
 
 // GetYouTubeSourceByID returns a YouTube source by ID
-func (s *PostgresStore) GetYouTubeSourceByID(id string) (*models.YouTubeSource, bool) {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetYouTubeSourceByID(ctx context.Context, id string) (*models.YouTubeSource, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	var src models.YouTubeSource
 	var channelID, playlistID, schedule sql.NullString
 	var lastProcessed, createdAt, updatedAt sql.NullTime
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, type, url, name, channel_id, playlist_id, enabled, schedule, last_processed, created_at, updated_at FROM youtube_sources WHERE id = $1",
-		id).Scan(&src.ID, &src.Type, &src.URL, &src.Name, &channelID, &playlistID, &src.Enabled, &schedule, &lastProcessed, &createdAt, &updatedAt)
+	err := s.db.QueryRow(ctx,
+		"SELECT id, type, url, name, channel_id, playlist_id, enabled, schedule, last_processed, created_at, updated_at FROM youtube_sources WHERE id = $1 AND user_id = $2",
+		id, s.userID).Scan(&src.ID, &src.Type, &src.URL, &src.Name, &channelID, &playlistID, &src.Enabled, &schedule, &lastProcessed, &createdAt, &updatedAt)
 
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get YouTube source %s: %v", id, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return nil, false
+		return nil, fmt.Errorf("failed to get YouTube source %s: %w", id, err)
 	}
 
 	if channelID.Valid {
@@ -616,99 +1957,116 @@ func (s *PostgresStore) GetYouTubeSourceByID(id string) (*models.YouTubeSource,
 	}
 	src.LastProcessed = parseTimestamp(lastProcessed)
 
-	return &src, true
+	return &src, nil
 }
 
 // CreateOrUpdateYouTubeSource creates or updates a YouTube source
-func (s *PostgresStore) CreateOrUpdateYouTubeSource(source *models.YouTubeSource) {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) CreateOrUpdateYouTubeSource(ctx context.Context, source *models.YouTubeSource) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	var lastProcessed interface{}
-	if source.LastProcessed != "" {
-		t, err := time.Parse(time.RFC3339, source.LastProcessed)
-		if err == nil {
-			lastProcessed = t
-		}
+	args, err := youtubeSourceArgs(source, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to encode YouTube source %s: %w", source.ID, err)
 	}
-
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO youtube_sources (id, type, url, name, channel_id, playlist_id, enabled, schedule, last_processed, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		 ON CONFLICT (id) DO UPDATE SET
-		 type = EXCLUDED.type,
-		 url = EXCLUDED.url,
-		 name = EXCLUDED.name,
-		 channel_id = EXCLUDED.channel_id,
-		 playlist_id = EXCLUDED.playlist_id,
-		 enabled = EXCLUDED.enabled,
-		 schedule = EXCLUDED.schedule,
-		 last_processed = EXCLUDED.last_processed,
-		 updated_at = CURRENT_TIMESTAMP`,
-		source.ID, source.Type, source.URL, source.Name, source.ChannelID, source.PlaylistID, source.Enabled, source.Schedule, lastProcessed)
-
+	_, err = s.db.Exec(ctx, youtubeSourceUpsertSQL, args...)
 	if err != nil {
-		log.Printf("Failed to create/update YouTube source %s: %v", source.ID, err)
+		return fmt.Errorf("failed to create/update YouTube source %s: %w", source.ID, err)
 	}
+	return nil
 }
 
 // DeleteYouTubeSource deletes a YouTube source by ID
-func (s *PostgresStore) DeleteYouTubeSource(id string) bool {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) DeleteYouTubeSource(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, "DELETE FROM youtube_sources WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete YouTube source %s: %w", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// YouTube quota operations
+
+// GetYouTubeQuotaUsage returns the units used on day, 0 if none were recorded
+func (s *PostgresStore) GetYouTubeQuotaUsage(ctx context.Context, day string) (int, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	result, err := s.pool.Exec(ctx, "DELETE FROM youtube_sources WHERE id = $1", id)
+	var units int
+	err := s.db.QueryRow(ctx, "SELECT units FROM youtube_quota_usage WHERE day = $1", day).Scan(&units)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
 	if err != nil {
-		log.Printf("Failed to delete YouTube source %s: %v", id, err)
-		return false
+		return 0, fmt.Errorf("failed to get YouTube quota usage of %s: %w", day, err)
+	}
+	return units, nil
+}
+
+// AddYouTubeQuotaUsage adds units to the usage of day and returns the new total
+func (s *PostgresStore) AddYouTubeQuotaUsage(ctx context.Context, day string, units int) (int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var total int
+	if err := s.db.QueryRow(ctx, youtubeQuotaAddSQL, day, units).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to add YouTube quota usage of %s: %w", day, err)
 	}
-	return result.RowsAffected() > 0
+	return total, nil
 }
 
 // Video Transcript operations
 
-// CreateOrUpdateTranscript creates or updates a video transcript
-func (s *PostgresStore) CreateOrUpdateTranscript(transcript *models.VideoTranscript) {
-	ctx, cancel := s.getContext()
+// CreateOrUpdateTranscript creates or updates a video transcript. A transcript another user
+// owns is left untouched.
+func (s *PostgresStore) CreateOrUpdateTranscript(ctx context.Context, transcript *models.VideoTranscript) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	var duration interface{}
 	if transcript.Duration != nil {
 		duration = *transcript.Duration
 	}
 
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO video_transcripts (id, video_id, video_title, video_url, text, duration, source_id, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP)
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO video_transcripts (id, video_id, video_title, video_url, text, duration, source_id, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, CURRENT_TIMESTAMP))
 		 ON CONFLICT (id) DO UPDATE SET
 		 video_id = EXCLUDED.video_id,
 		 video_title = EXCLUDED.video_title,
 		 video_url = EXCLUDED.video_url,
 		 text = EXCLUDED.text,
 		 duration = EXCLUDED.duration,
-		 source_id = EXCLUDED.source_id`,
-		transcript.ID, transcript.VideoID, transcript.VideoTitle, transcript.VideoURL, transcript.Text, duration, transcript.SourceID)
+		 source_id = EXCLUDED.source_id
+		 WHERE video_transcripts.user_id = EXCLUDED.user_id`,
+		transcript.ID, transcript.VideoID, transcript.VideoTitle, transcript.VideoURL, transcript.Text, duration, transcript.SourceID,
+		s.userID, nullableTime(transcript.CreatedAt))
 
 	if err != nil {
-		log.Printf("Failed to create/update transcript %s: %v", transcript.ID, err)
+		return fmt.Errorf("failed to create/update transcript %s: %w", transcript.ID, err)
 	}
+	return nil
 }
 
 // GetTranscriptByID returns a transcript by ID
-func (s *PostgresStore) GetTranscriptByID(id string) (*models.VideoTranscript, bool) {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetTranscriptByID(ctx context.Context, id string) (*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	var t models.VideoTranscript
 	var duration sql.NullInt64
 	var sourceID sql.NullString
 	var createdAt sql.NullTime
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, video_id, video_title, video_url, text, duration, source_id, created_at FROM video_transcripts WHERE id = $1",
-		id).Scan(&t.ID, &t.VideoID, &t.VideoTitle, &t.VideoURL, &t.Text, &duration, &sourceID, &createdAt)
+	err := s.db.QueryRow(ctx,
+		"SELECT id, video_id, video_title, video_url, text, duration, source_id, created_at FROM video_transcripts WHERE id = $1 AND user_id = $2",
+		id, s.userID).Scan(&t.ID, &t.VideoID, &t.VideoTitle, &t.VideoURL, &t.Text, &duration, &sourceID, &createdAt)
 
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get transcript %s: %v", id, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return nil, false
+		return nil, fmt.Errorf("failed to get transcript %s: %w", id, err)
 	}
 
 	t.Duration = parseIntPtr(duration)
@@ -717,19 +2075,32 @@ func (s *PostgresStore) GetTranscriptByID(id string) (*models.VideoTranscript, b
 	}
 	t.CreatedAt = parseTimestamp(createdAt)
 
-	return &t, true
+	return &t, nil
+}
+
+// GetLatestTranscriptByVideoID returns the newest transcript stored for a video
+func (s *PostgresStore) GetLatestTranscriptByVideoID(ctx context.Context, videoID string) (*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	t, err := scanTranscript(s.db.QueryRow(ctx, latestTranscriptByVideoIDQuery, videoID, s.userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest transcript of video %s: %w", videoID, err)
+	}
+	return t, nil
 }
 
 // GetTranscriptsByVideoID returns transcripts for a specific video ID
-func (s *PostgresStore) GetTranscriptsByVideoID(videoID string) []*models.VideoTranscript {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetTranscriptsByVideoID(ctx context.Context, videoID string) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, video_id, video_title, video_url, text, duration, source_id, created_at FROM video_transcripts WHERE video_id = $1 ORDER BY created_at DESC",
-		videoID)
+	rows, err := s.db.Query(ctx,
+		"SELECT id, video_id, video_title, video_url, text, duration, source_id, created_at FROM video_transcripts WHERE video_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		videoID, s.userID)
 	if err != nil {
-		log.Printf("Failed to get transcripts by video ID %s: %v", videoID, err)
-		return []*models.VideoTranscript{}
+		return nil, fmt.Errorf("failed to get transcripts by video ID %s: %w", videoID, err)
 	}
 	defer rows.Close()
 
@@ -742,8 +2113,7 @@ func (s *PostgresStore) GetTranscriptsByVideoID(videoID string) []*models.VideoT
 
 		err := rows.Scan(&t.ID, &t.VideoID, &t.VideoTitle, &t.VideoURL, &t.Text, &duration, &sourceID, &createdAt)
 		if err != nil {
-			log.Printf("Failed to scan transcript row: %v", err)
-			continue
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
 		}
 
 		t.Duration = parseIntPtr(duration)
@@ -755,14 +2125,101 @@ func (s *PostgresStore) GetTranscriptsByVideoID(videoID string) []*models.VideoT
 		transcripts = append(transcripts, &t)
 	}
 
-	return transcripts
+	return transcripts, rows.Err()
+}
+
+// GetTranscriptsBySourceID returns up to limit transcripts for a source, newest first
+func (s *PostgresStore) GetTranscriptsBySourceID(ctx context.Context, sourceID string, limit int) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := ListOptions{Limit: limit}.sqlClause([]interface{}{sourceID, s.userID})
+	rows, err := s.db.Query(ctx,
+		"SELECT "+transcriptColumns+" FROM video_transcripts WHERE source_id = $1 AND user_id = $2 ORDER BY created_at DESC, id"+page,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcripts by source ID %s: %w", sourceID, err)
+	}
+	defer rows.Close()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for rows.Next() {
+		t, err := scanTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+// ListTranscripts returns a page of all transcripts ordered by ID
+func (s *PostgresStore) ListTranscripts(ctx context.Context, opts ListOptions) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.db.Query(ctx, "SELECT "+transcriptColumns+" FROM video_transcripts WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.VideoTranscript, 0)
+	for rows.Next() {
+		item, err := scanTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SearchTranscripts returns the transcripts matching query as a full-text search over their
+// title and text, best ranked first
+func (s *PostgresStore) SearchTranscripts(ctx context.Context, query string, limit int) ([]*models.TranscriptSearchResult, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := ListOptions{Limit: limit}.sqlClause([]interface{}{query, s.userID})
+	rows, err := s.db.Query(ctx,
+		"SELECT "+transcriptColumns+", "+sourceNameColumn+` FROM video_transcripts
+		 WHERE user_id = $2 AND search_vector @@ websearch_to_tsquery('english', $1)
+		 ORDER BY ts_rank(search_vector, websearch_to_tsquery('english', $1)) DESC, created_at DESC, id`+page,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	terms := searchTerms(query)
+	results := make([]*models.TranscriptSearchResult, 0)
+	for rows.Next() {
+		var sourceName sql.NullString
+		t, err := scanTranscript(trailingScanner{rows, []interface{}{&sourceName}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		results = append(results, newSearchResult(t, sourceName.String, terms))
+	}
+	return results, rows.Err()
+}
+
+// PruneTranscriptsOlderThan clears the text of, or deletes, transcripts created before cutoff
+func (s *PostgresStore) PruneTranscriptsOlderThan(ctx context.Context, cutoff time.Time, keepMetadata bool) (int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, pruneTranscriptsSQL(keepMetadata), cutoff.UTC(), s.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune transcripts: %w", err)
+	}
+	return int(result.RowsAffected()), nil
 }
 
 // Market Analysis operations
 
-// CreateOrUpdateMarketAnalysis creates or updates a market analysis
-func (s *PostgresStore) CreateOrUpdateMarketAnalysis(analysis *models.MarketAnalysis) {
-	ctx, cancel := s.getContext()
+// CreateOrUpdateMarketAnalysis creates or updates a market analysis. An analysis another user
+// owns is left untouched.
+func (s *PostgresStore) CreateOrUpdateMarketAnalysis(ctx context.Context, analysis *models.MarketAnalysis) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	trendsJSON, err := json.Marshal(analysis.Trends)
 	if err != nil {
@@ -775,39 +2232,42 @@ func (s *PostgresStore) CreateOrUpdateMarketAnalysis(analysis *models.MarketAnal
 		riskFactorsJSON = []byte("[]")
 	}
 
-	_, err = s.pool.Exec(ctx,
-		`INSERT INTO market_analyses (id, transcript_id, conditions, trends, risk_factors, summary, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO market_analyses (id, transcript_id, conditions, trends, risk_factors, summary, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, CURRENT_TIMESTAMP))
 		 ON CONFLICT (id) DO UPDATE SET
 		 transcript_id = EXCLUDED.transcript_id,
 		 conditions = EXCLUDED.conditions,
 		 trends = EXCLUDED.trends,
 		 risk_factors = EXCLUDED.risk_factors,
-		 summary = EXCLUDED.summary`,
-		analysis.ID, analysis.TranscriptID, analysis.Conditions, trendsJSON, riskFactorsJSON, analysis.Summary)
+		 summary = EXCLUDED.summary
+		 WHERE market_analyses.user_id = EXCLUDED.user_id`,
+		analysis.ID, analysis.TranscriptID, analysis.Conditions, trendsJSON, riskFactorsJSON, analysis.Summary,
+		s.userID, nullableTime(analysis.CreatedAt))
 
 	if err != nil {
-		log.Printf("Failed to create/update market analysis %s: %v", analysis.ID, err)
+		return fmt.Errorf("failed to create/update market analysis %s: %w", analysis.ID, err)
 	}
+	return nil
 }
 
 // GetMarketAnalysisByID returns a market analysis by ID
-func (s *PostgresStore) GetMarketAnalysisByID(id string) (*models.MarketAnalysis, bool) {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetMarketAnalysisByID(ctx context.Context, id string) (*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	var a models.MarketAnalysis
 	var trendsJSON, riskFactorsJSON []byte
 	var createdAt sql.NullTime
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, transcript_id, conditions, trends, risk_factors, summary, created_at FROM market_analyses WHERE id = $1",
-		id).Scan(&a.ID, &a.TranscriptID, &a.Conditions, &trendsJSON, &riskFactorsJSON, &a.Summary, &createdAt)
+	err := s.db.QueryRow(ctx,
+		"SELECT id, transcript_id, conditions, trends, risk_factors, summary, created_at FROM market_analyses WHERE id = $1 AND user_id = $2",
+		id, s.userID).Scan(&a.ID, &a.TranscriptID, &a.Conditions, &trendsJSON, &riskFactorsJSON, &a.Summary, &createdAt)
 
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get market analysis %s: %v", id, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return nil, false
+		return nil, fmt.Errorf("failed to get market analysis %s: %w", id, err)
 	}
 
 	if err := json.Unmarshal(trendsJSON, &a.Trends); err != nil {
@@ -820,19 +2280,18 @@ func (s *PostgresStore) GetMarketAnalysisByID(id string) (*models.MarketAnalysis
 	}
 	a.CreatedAt = parseTimestamp(createdAt)
 
-	return &a, true
+	return &a, nil
 }
 
 // GetMarketAnalysesByTranscriptID returns market analyses for a specific transcript ID
-func (s *PostgresStore) GetMarketAnalysesByTranscriptID(transcriptID string) []*models.MarketAnalysis {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetMarketAnalysesByTranscriptID(ctx context.Context, transcriptID string) ([]*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, transcript_id, conditions, trends, risk_factors, summary, created_at FROM market_analyses WHERE transcript_id = $1 ORDER BY created_at DESC",
-		transcriptID)
+	rows, err := s.db.Query(ctx,
+		"SELECT id, transcript_id, conditions, trends, risk_factors, summary, created_at FROM market_analyses WHERE transcript_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		transcriptID, s.userID)
 	if err != nil {
-		log.Printf("Failed to get market analyses by transcript ID %s: %v", transcriptID, err)
-		return []*models.MarketAnalysis{}
+		return nil, fmt.Errorf("failed to get market analyses by transcript ID %s: %w", transcriptID, err)
 	}
 	defer rows.Close()
 
@@ -844,8 +2303,7 @@ func (s *PostgresStore) GetMarketAnalysesByTranscriptID(transcriptID string) []*
 
 		err := rows.Scan(&a.ID, &a.TranscriptID, &a.Conditions, &trendsJSON, &riskFactorsJSON, &a.Summary, &createdAt)
 		if err != nil {
-			log.Printf("Failed to scan market analysis row: %v", err)
-			continue
+			return nil, fmt.Errorf("failed to scan market analysis row: %w", err)
 		}
 
 		if err := json.Unmarshal(trendsJSON, &a.Trends); err != nil {
@@ -861,14 +2319,37 @@ func (s *PostgresStore) GetMarketAnalysesByTranscriptID(transcriptID string) []*
 		analyses = append(analyses, &a)
 	}
 
-	return analyses
+	return analyses, rows.Err()
+}
+
+// ListMarketAnalyses returns a page of all market analyses ordered by ID
+func (s *PostgresStore) ListMarketAnalyses(ctx context.Context, opts ListOptions) ([]*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.db.Query(ctx, "SELECT "+marketAnalysisColumns+" FROM market_analyses WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list market analyses: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.MarketAnalysis, 0)
+	for rows.Next() {
+		item, err := scanMarketAnalysis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market analysis row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
 }
 
 // Recommendation operations
 
-// CreateOrUpdateRecommendation creates or updates a recommendation
-func (s *PostgresStore) CreateOrUpdateRecommendation(recommendation *models.Recommendation) {
-	ctx, cancel := s.getContext()
+// CreateOrUpdateRecommendation creates or updates a recommendation. A recommendation another
+// user owns is left untouched.
+func (s *PostgresStore) CreateOrUpdateRecommendation(ctx context.Context, recommendation *models.Recommendation) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	suggestedActionsJSON, err := json.Marshal(recommendation.SuggestedActions)
 	if err != nil {
@@ -876,40 +2357,43 @@ func (s *PostgresStore) CreateOrUpdateRecommendation(recommendation *models.Reco
 		suggestedActionsJSON = []byte("[]")
 	}
 
-	_, err = s.pool.Exec(ctx,
-		`INSERT INTO recommendations (id, analysis_id, action, confidence, suggested_actions, summary, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO recommendations (id, analysis_id, action, confidence, suggested_actions, summary, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, CURRENT_TIMESTAMP))
 		 ON CONFLICT (id) DO UPDATE SET
 		 analysis_id = EXCLUDED.analysis_id,
 		 action = EXCLUDED.action,
 		 confidence = EXCLUDED.confidence,
 		 suggested_actions = EXCLUDED.suggested_actions,
-		 summary = EXCLUDED.summary`,
-		recommendation.ID, recommendation.AnalysisID, recommendation.Action, recommendation.Confidence, suggestedActionsJSON, recommendation.Summary)
+		 summary = EXCLUDED.summary
+		 WHERE recommendations.user_id = EXCLUDED.user_id`,
+		recommendation.ID, recommendation.AnalysisID, recommendation.Action, recommendation.Confidence, suggestedActionsJSON, recommendation.Summary,
+		s.userID, nullableTime(recommendation.CreatedAt))
 
 	if err != nil {
-		log.Printf("Failed to create/update recommendation %s: %v", recommendation.ID, err)
+		return fmt.Errorf("failed to create/update recommendation %s: %w", recommendation.ID, err)
 	}
+	return nil
 }
 
 // GetRecommendationByID returns a recommendation by ID
-func (s *PostgresStore) GetRecommendationByID(id string) (*models.Recommendation, bool) {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetRecommendationByID(ctx context.Context, id string) (*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
 	var r models.Recommendation
 	var suggestedActionsJSON []byte
 	var summary sql.NullString
 	var createdAt sql.NullTime
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, analysis_id, action, confidence, suggested_actions, summary, created_at FROM recommendations WHERE id = $1",
-		id).Scan(&r.ID, &r.AnalysisID, &r.Action, &r.Confidence, &suggestedActionsJSON, &summary, &createdAt)
+	err := s.db.QueryRow(ctx,
+		"SELECT id, analysis_id, action, confidence, suggested_actions, summary, created_at FROM recommendations WHERE id = $1 AND user_id = $2",
+		id, s.userID).Scan(&r.ID, &r.AnalysisID, &r.Action, &r.Confidence, &suggestedActionsJSON, &summary, &createdAt)
 
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get recommendation %s: %v", id, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		return nil, false
+		return nil, fmt.Errorf("failed to get recommendation %s: %w", id, err)
 	}
 
 	if err := json.Unmarshal(suggestedActionsJSON, &r.SuggestedActions); err != nil {
@@ -921,19 +2405,18 @@ func (s *PostgresStore) GetRecommendationByID(id string) (*models.Recommendation
 	}
 	r.CreatedAt = parseTimestamp(createdAt)
 
-	return &r, true
+	return &r, nil
 }
 
 // GetRecommendationsByAnalysisID returns recommendations for a specific analysis ID
-func (s *PostgresStore) GetRecommendationsByAnalysisID(analysisID string) []*models.Recommendation {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetRecommendationsByAnalysisID(ctx context.Context, analysisID string) ([]*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, analysis_id, action, confidence, suggested_actions, summary, created_at FROM recommendations WHERE analysis_id = $1 ORDER BY created_at DESC",
-		analysisID)
+	rows, err := s.db.Query(ctx,
+		"SELECT id, analysis_id, action, confidence, suggested_actions, summary, created_at FROM recommendations WHERE analysis_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		analysisID, s.userID)
 	if err != nil {
-		log.Printf("Failed to get recommendations by analysis ID %s: %v", analysisID, err)
-		return []*models.Recommendation{}
+		return nil, fmt.Errorf("failed to get recommendations by analysis ID %s: %w", analysisID, err)
 	}
 	defer rows.Close()
 
@@ -946,8 +2429,7 @@ func (s *PostgresStore) GetRecommendationsByAnalysisID(analysisID string) []*mod
 
 		err := rows.Scan(&r.ID, &r.AnalysisID, &r.Action, &r.Confidence, &suggestedActionsJSON, &summary, &createdAt)
 		if err != nil {
-			log.Printf("Failed to scan recommendation row: %v", err)
-			continue
+			return nil, fmt.Errorf("failed to scan recommendation row: %w", err)
 		}
 
 		if err := json.Unmarshal(suggestedActionsJSON, &r.SuggestedActions); err != nil {
@@ -962,32 +2444,44 @@ func (s *PostgresStore) GetRecommendationsByAnalysisID(analysisID string) []*mod
 		recommendations = append(recommendations, &r)
 	}
 
-	return recommendations
+	return recommendations, rows.Err()
 }
 
-// Workflow Execution operations
-
-// CreateOrUpdateWorkflowExecution creates or updates a workflow execution
-func (s *PostgresStore) CreateOrUpdateWorkflowExecution(execution *models.WorkflowExecution) {
-	var startedAt, completedAt interface{}
-	if execution.StartedAt != "" {
-		t, err := time.Parse(time.RFC3339, execution.StartedAt)
-		if err == nil {
-			startedAt = t
-		}
+// ListRecommendations returns a page of all recommendations ordered by ID
+func (s *PostgresStore) ListRecommendations(ctx context.Context, opts ListOptions) ([]*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.db.Query(ctx, "SELECT "+recommendationColumns+" FROM recommendations WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recommendations: %w", err)
 	}
-	if execution.CompletedAt != "" {
-		t, err := time.Parse(time.RFC3339, execution.CompletedAt)
-		if err == nil {
-			completedAt = t
+	defer rows.Close()
+
+	items := make([]*models.Recommendation, 0)
+	for rows.Next() {
+		item, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation row: %w", err)
 		}
+		items = append(items, item)
 	}
+	return items, rows.Err()
+}
+
+// Workflow Execution operations
+
+// CreateOrUpdateWorkflowExecution creates or updates a workflow execution. An execution another
+// user owns is left untouched.
+func (s *PostgresStore) CreateOrUpdateWorkflowExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	startedAt := nullableTime(execution.StartedAt)
+	completedAt := nullableTime(execution.CompletedAt)
 
-	ctx, cancel := s.getContext()
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	_, err := s.pool.Exec(ctx,
-		`INSERT INTO workflow_executions (id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CURRENT_TIMESTAMP, $11, $12)
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO workflow_executions (id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at, user_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $13, $11, $12, $14)
 		 ON CONFLICT (id) DO UPDATE SET
 		 status = EXCLUDED.status,
 		 video_id = EXCLUDED.video_id,
@@ -999,33 +2493,60 @@ func (s *PostgresStore) CreateOrUpdateWorkflowExecution(execution *models.Workfl
 		 recommendation_id = EXCLUDED.recommendation_id,
 		 error = EXCLUDED.error,
 		 started_at = EXCLUDED.started_at,
-		 completed_at = EXCLUDED.completed_at`,
+		 completed_at = EXCLUDED.completed_at,
+		 claimed_video_id = CASE WHEN EXCLUDED.status = 'failed' THEN NULL ELSE workflow_executions.claimed_video_id END
+		 WHERE workflow_executions.user_id = EXCLUDED.user_id`,
 		execution.ID, execution.Status, execution.VideoID, execution.VideoURL, execution.VideoTitle,
 		execution.SourceID, execution.TranscriptID, execution.AnalysisID, execution.RecommendationID,
-		execution.Error, startedAt, completedAt)
+		execution.Error, startedAt, completedAt, executionCreatedAt(execution), s.userID)
 
 	if err != nil {
-		log.Printf("Failed to create/update workflow execution %s: %v", execution.ID, err)
+		return fmt.Errorf("failed to create/update workflow execution %s: %w", execution.ID, err)
 	}
+	return nil
 }
 
-// GetWorkflowExecutionByID returns a workflow execution by ID
-func (s *PostgresStore) GetWorkflowExecutionByID(id string) (*models.WorkflowExecution, bool) {
-	ctx, cancel := s.getContext()
+// TryClaimVideoExecution saves execution as the one processing its video unless another
+// execution holds the video
+func (s *PostgresStore) TryClaimVideoExecution(ctx context.Context, execution *models.WorkflowExecution) (string, bool, error) {
+	if execution.VideoID == "" {
+		return "", false, fmt.Errorf("execution %s has no video ID to claim", execution.ID)
+	}
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		var holderID string
+		err := s.db.QueryRow(ctx, videoClaimHolderQuery, execution.VideoID, s.userID).Scan(&holderID)
+		if err == nil {
+			return holderID, false, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", false, fmt.Errorf("failed to look up claim on video %s: %w", execution.VideoID, err)
+		}
+
+		tag, err := s.db.Exec(ctx, claimVideoSQL, claimVideoArgs(execution, s.userID)...)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to claim video %s: %w", execution.VideoID, err)
+		}
+		if tag.RowsAffected() > 0 {
+			return execution.ID, true, nil
+		}
+	}
+	return "", false, fmt.Errorf("failed to claim video %s: its claim kept changing", execution.VideoID)
+}
+
+// workflowExecutionColumns is the column list scanned by scanWorkflowExecution
+const workflowExecutionColumns = "id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at"
+
+// scanWorkflowExecution scans a row selected with workflowExecutionColumns
+func scanWorkflowExecution(row rowScanner) (*models.WorkflowExecution, error) {
 	var e models.WorkflowExecution
 	var videoTitle, videoID, sourceID, transcriptID, analysisID, recommendationID, errorMsg sql.NullString
 	var createdAt, startedAt, completedAt sql.NullTime
 
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at FROM workflow_executions WHERE id = $1",
-		id).Scan(&e.ID, &e.Status, &videoID, &e.VideoURL, &videoTitle, &sourceID, &transcriptID, &analysisID, &recommendationID, &errorMsg, &createdAt, &startedAt, &completedAt)
-
+	err := row.Scan(&e.ID, &e.Status, &videoID, &e.VideoURL, &videoTitle, &sourceID, &transcriptID, &analysisID, &recommendationID, &errorMsg, &createdAt, &startedAt, &completedAt)
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get workflow execution %s: %v", id, err)
-		}
-		return nil, false
+		return nil, err
 	}
 
 	if videoID.Valid {
@@ -1053,243 +2574,415 @@ func (s *PostgresStore) GetWorkflowExecutionByID(id string) (*models.WorkflowExe
 	e.StartedAt = parseTimestamp(startedAt)
 	e.CompletedAt = parseTimestamp(completedAt)
 
-	return &e, true
+	return &e, nil
 }
 
-// GetAllWorkflowExecutions returns all workflow executions
-func (s *PostgresStore) GetAllWorkflowExecutions() []*models.WorkflowExecution {
-	ctx, cancel := s.getContext()
+// queryWorkflowExecutions runs a workflow execution SELECT and scans every row
+func (s *PostgresStore) queryWorkflowExecutions(ctx context.Context, query string, args ...interface{}) ([]*models.WorkflowExecution, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at FROM workflow_executions ORDER BY created_at DESC")
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to get all workflow executions: %v", err)
-		return []*models.WorkflowExecution{}
+		return nil, err
 	}
 	defer rows.Close()
 
 	executions := make([]*models.WorkflowExecution, 0)
 	for rows.Next() {
-		var e models.WorkflowExecution
-		var videoTitle, videoID, sourceID, transcriptID, analysisID, recommendationID, errorMsg sql.NullString
-		var createdAt, startedAt, completedAt sql.NullTime
-
-		err := rows.Scan(&e.ID, &e.Status, &videoID, &e.VideoURL, &videoTitle, &sourceID, &transcriptID, &analysisID, &recommendationID, &errorMsg, &createdAt, &startedAt, &completedAt)
+		e, err := scanWorkflowExecution(rows)
 		if err != nil {
-			log.Printf("Failed to scan workflow execution row: %v", err)
-			continue
+			return nil, err
 		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
 
-		if videoID.Valid {
-			e.VideoID = videoID.String
-		}
-		if videoTitle.Valid {
-			e.VideoTitle = videoTitle.String
-		}
-		if sourceID.Valid {
-			e.SourceID = sourceID.String
-		}
-		if transcriptID.Valid {
-			e.TranscriptID = transcriptID.String
-		}
-		if analysisID.Valid {
-			e.AnalysisID = analysisID.String
-		}
-		if recommendationID.Valid {
-			e.RecommendationID = recommendationID.String
-		}
-		if errorMsg.Valid {
-			e.Error = errorMsg.String
+// GetWorkflowExecutionByID returns a workflow execution by ID
+func (s *PostgresStore) GetWorkflowExecutionByID(ctx context.Context, id string) (*models.WorkflowExecution, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	e, err := scanWorkflowExecution(s.db.QueryRow(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
 		}
-		e.CreatedAt = parseTimestamp(createdAt)
-		e.StartedAt = parseTimestamp(startedAt)
-		e.CompletedAt = parseTimestamp(completedAt)
+		return nil, fmt.Errorf("failed to get workflow execution %s: %w", id, err)
+	}
+	return e, nil
+}
+
+// GetAllWorkflowExecutions returns all workflow executions
+func (s *PostgresStore) GetAllWorkflowExecutions(ctx context.Context) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE user_id = $1 ORDER BY created_at DESC", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all workflow executions: %w", err)
+	}
+	return executions, nil
+}
+
+// ListWorkflowExecutions returns workflow executions matching the filter, newest first unless
+// the filter sorts them otherwise
+func (s *PostgresStore) ListWorkflowExecutions(ctx context.Context, filter ExecutionFilter) ([]*models.WorkflowExecution, error) {
+	orderBy, err := executionSortFields.orderBy(filter.Sort, " ORDER BY created_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	where, args := filter.sqlWhere(s.userID)
+	query := "SELECT " + workflowExecutionColumns + " FROM workflow_executions" + where + orderBy
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	executions, err := s.queryWorkflowExecutions(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow executions: %w", err)
+	}
+	return executions, nil
+}
+
+// PageWorkflowExecutions returns a page of executions matching filter, newest first, after cursor
+func (s *PostgresStore) PageWorkflowExecutions(ctx context.Context, filter ExecutionFilter, cursor string) ([]*models.WorkflowExecution, string, error) {
+	where, args, err := filter.sqlKeysetWhere(cursor, s.userID)
+	if err != nil {
+		return nil, "", err
+	}
+	limit, args := filter.sqlKeysetLimit(args)
+
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions"+where+executionKeysetOrder+limit,
+		args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to page workflow executions: %w", err)
+	}
+	executions, next := filter.nextPage(executions)
+	return executions, next, nil
+}
+
+// CountWorkflowExecutions returns the number of executions matching filter, ignoring its Limit
+func (s *PostgresStore) CountWorkflowExecutions(ctx context.Context, filter ExecutionFilter) (int, error) {
+	where, args := filter.sqlWhere(s.userID)
+	count, err := s.count(ctx, "SELECT COUNT(*) FROM workflow_executions"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workflow executions: %w", err)
+	}
+	return count, nil
+}
 
-		executions = append(executions, &e)
+// GetCompletedWorkflowExecutionsSince returns completed executions with a recommendation that
+// completed after since, newest first
+func (s *PostgresStore) GetCompletedWorkflowExecutionsSince(ctx context.Context, since time.Time, limit int) ([]*models.WorkflowExecution, error) {
+	query := "SELECT " + workflowExecutionColumns + ` FROM workflow_executions
+		 WHERE user_id = $1 AND status = $2 AND recommendation_id IS NOT NULL AND recommendation_id <> '' AND completed_at IS NOT NULL`
+	args := []interface{}{s.userID, models.WorkflowStatusCompleted}
+	if !since.IsZero() {
+		args = append(args, since.UTC())
+		query += fmt.Sprintf(" AND completed_at > $%d", len(args))
+	}
+	query += " ORDER BY completed_at DESC, id"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
 
-	return executions
+	executions, err := s.queryWorkflowExecutions(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions completed since %s: %w", since.Format(time.RFC3339), err)
+	}
+	return executions, nil
 }
 
 // GetWorkflowExecutionsBySourceID returns workflow executions for a specific source ID
-func (s *PostgresStore) GetWorkflowExecutionsBySourceID(sourceID string) []*models.WorkflowExecution {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) GetWorkflowExecutionsBySourceID(ctx context.Context, sourceID string) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE source_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		sourceID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions by source ID %s: %w", sourceID, err)
+	}
+	return executions, nil
+}
+
+// GetWorkflowExecutionsByVideoID returns workflow executions for a specific video ID
+func (s *PostgresStore) GetWorkflowExecutionsByVideoID(ctx context.Context, videoID string) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE video_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		videoID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions by video ID %s: %w", videoID, err)
+	}
+	return executions, nil
+}
+
+// deleteWorkflowExecutionSQL deletes execution $1 of user $2 and returns the artifacts it linked to
+const deleteWorkflowExecutionSQL = "DELETE FROM workflow_executions WHERE id = $1 AND user_id = $2 RETURNING transcript_id, analysis_id, recommendation_id"
+
+// executionArtifactDelete deletes one workflow artifact by ID ($1) and user ($2)
+type executionArtifactDelete struct {
+	query string
+	id    sql.NullString
+}
+
+// executionArtifactDeletes returns the statements that remove a deleted execution's
+// recommendation, analysis and transcript. Artifacts another execution still references are kept.
+func executionArtifactDeletes(transcriptID, analysisID, recommendationID sql.NullString) []executionArtifactDelete {
+	return []executionArtifactDelete{
+		{"DELETE FROM recommendations WHERE id = $1 AND user_id = $2 AND NOT EXISTS (SELECT 1 FROM workflow_executions WHERE recommendation_id = $1)", recommendationID},
+		{"DELETE FROM market_analyses WHERE id = $1 AND user_id = $2 AND NOT EXISTS (SELECT 1 FROM workflow_executions WHERE analysis_id = $1)", analysisID},
+		{"DELETE FROM video_transcripts WHERE id = $1 AND user_id = $2 AND NOT EXISTS (SELECT 1 FROM workflow_executions WHERE transcript_id = $1)", transcriptID},
+	}
+}
+
+// DeleteWorkflowExecution deletes an execution in one transaction, optionally with its
+// artifacts, and drops its ID from aggregated recommendations
+func (s *PostgresStore) DeleteWorkflowExecution(ctx context.Context, id string, cascade bool) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete workflow execution %s: %w", id, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var transcriptID, analysisID, recommendationID sql.NullString
+	err = tx.QueryRow(ctx, deleteWorkflowExecutionSQL, id, s.userID).Scan(&transcriptID, &analysisID, &recommendationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete workflow execution %s: %w", id, err)
+	}
+
+	if cascade {
+		for _, d := range executionArtifactDeletes(transcriptID, analysisID, recommendationID) {
+			if !d.id.Valid {
+				continue
+			}
+			if _, err := tx.Exec(ctx, d.query, d.id.String, s.userID); err != nil {
+				return fmt.Errorf("failed to delete artifacts of workflow execution %s: %w", id, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE aggregated_recommendations SET execution_ids = execution_ids - $1::text WHERE user_id = $2 AND execution_ids ? $1::text",
+		id, s.userID); err != nil {
+		return fmt.Errorf("failed to unlink workflow execution %s from aggregated recommendations: %w", id, err)
+	}
+	return tx.Commit(ctx)
+}
+
+// PruneWorkflowExecutions deletes old executions with status, keeping the newest of each source
+func (s *PostgresStore) PruneWorkflowExecutions(ctx context.Context, status models.WorkflowExecutionStatus, olderThan time.Time, keep int) (int, error) {
+	query, args := prunableWorkflowExecutionsQuery(status, olderThan, keep, s.userID)
+	ids, err := s.selectIDs(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select workflow executions to prune: %w", err)
+	}
+	return deleteWorkflowExecutions(ctx, s, ids)
+}
+
+// selectIDs runs a query selecting a single ID column
+func (s *PostgresStore) selectIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at FROM workflow_executions WHERE source_id = $1 ORDER BY created_at DESC",
-		sourceID)
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to get workflow executions by source ID %s: %v", sourceID, err)
-		return []*models.WorkflowExecution{}
+		return nil, err
 	}
 	defer rows.Close()
 
-	executions := make([]*models.WorkflowExecution, 0)
+	ids := make([]string, 0)
 	for rows.Next() {
-		var e models.WorkflowExecution
-		var videoTitle, videoID, sourceIDVal, transcriptID, analysisID, recommendationID, errorMsg sql.NullString
-		var startedAt, completedAt sql.NullTime
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AddWorkflowExecutionEvent appends an event to an execution's event log
+func (s *PostgresStore) AddWorkflowExecutionEvent(ctx context.Context, event *models.WorkflowExecutionEvent) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.db.Exec(ctx, addWorkflowExecutionEventSQL,
+		event.ExecutionID, event.Type, event.Detail, event.Timestamp.UTC(), s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to add %s event to workflow execution %s: %w", event.Type, event.ExecutionID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetWorkflowExecutionEvents returns an execution's events, oldest first
+func (s *PostgresStore) GetWorkflowExecutionEvents(ctx context.Context, executionID string) ([]*models.WorkflowExecutionEvent, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx, workflowExecutionEventsQuery, executionID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events of workflow execution %s: %w", executionID, err)
+	}
+	defer rows.Close()
 
-		err := rows.Scan(&e.ID, &e.Status, &videoID, &e.VideoURL, &videoTitle, &sourceIDVal, &transcriptID, &analysisID, &recommendationID, &errorMsg, &e.CreatedAt, &startedAt, &completedAt)
+	events := make([]*models.WorkflowExecutionEvent, 0)
+	for rows.Next() {
+		e, err := scanWorkflowExecutionEvent(rows)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to scan workflow execution event: %w", err)
 		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
 
-		if videoID.Valid {
-			e.VideoID = videoID.String
-		}
-		if videoTitle.Valid {
-			e.VideoTitle = videoTitle.String
-		}
-		if sourceIDVal.Valid {
-			e.SourceID = sourceIDVal.String
-		}
-		if transcriptID.Valid {
-			e.TranscriptID = transcriptID.String
-		}
-		if analysisID.Valid {
-			e.AnalysisID = analysisID.String
-		}
-		if recommendationID.Valid {
-			e.RecommendationID = recommendationID.String
-		}
-		if errorMsg.Valid {
-			e.Error = errorMsg.String
-		}
-		e.StartedAt = parseTimestamp(startedAt)
-		e.CompletedAt = parseTimestamp(completedAt)
+// recommendationSummaryQuery joins completed executions with their recommendation and analysis
+const recommendationSummaryQuery = `SELECT e.id, e.video_id, e.video_title, e.completed_at, r.action, r.confidence, a.conditions
+		 FROM workflow_executions e
+		 JOIN recommendations r ON r.id = e.recommendation_id AND r.user_id = e.user_id
+		 LEFT JOIN market_analyses a ON a.id = e.analysis_id AND a.user_id = e.user_id
+		 WHERE e.status = $1 AND e.completed_at > $2 AND e.user_id = $3
+		 ORDER BY e.completed_at DESC, e.id`
+
+// scanRecommendationSummaryRow scans a row selected by recommendationSummaryQuery
+func scanRecommendationSummaryRow(row rowScanner) (*models.RecommendationSummaryRow, error) {
+	var r models.RecommendationSummaryRow
+	var videoID, videoTitle, condition sql.NullString
+	var completedAt sql.NullTime
+
+	if err := row.Scan(&r.ExecutionID, &videoID, &videoTitle, &completedAt, &r.Action, &r.Confidence, &condition); err != nil {
+		return nil, err
+	}
+	r.VideoID = videoID.String
+	r.VideoTitle = videoTitle.String
+	r.CompletedAt = parseTimestamp(completedAt)
+	r.Condition = condition.String
+	r.HasAnalysis = condition.Valid
+	return &r, nil
+}
 
-		executions = append(executions, &e)
+// GetRecommendationSummaryData returns executions completed after since that have a stored
+// recommendation, in one query rather than a lookup per execution
+func (s *PostgresStore) GetRecommendationSummaryData(ctx context.Context, since time.Time) ([]*models.RecommendationSummaryRow, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.db.Query(ctx, recommendationSummaryQuery, models.WorkflowStatusCompleted, since.UTC(), s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendation summary data: %w", err)
 	}
+	defer rows.Close()
 
-	return executions
+	summaryRows := make([]*models.RecommendationSummaryRow, 0)
+	for rows.Next() {
+		r, err := scanRecommendationSummaryRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation summary row: %w", err)
+		}
+		summaryRows = append(summaryRows, r)
+	}
+	return summaryRows, rows.Err()
 }
 
-// GetWorkflowExecutionsByVideoID returns workflow executions for a specific video ID
-func (s *PostgresStore) GetWorkflowExecutionsByVideoID(videoID string) []*models.WorkflowExecution {
-	ctx, cancel := s.getContext()
+// Aggregated Recommendation operations
+
+// GetAggregatedRecommendations returns stored aggregated recommendations, newest first.
+// A limit of 0 or less returns the full history.
+func (s *PostgresStore) GetAggregatedRecommendations(ctx context.Context, limit int) ([]*models.AggregatedRecommendation, error) {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	rows, err := s.pool.Query(ctx,
-		"SELECT id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at FROM workflow_executions WHERE video_id = $1 ORDER BY created_at DESC",
-		videoID)
+
+	query := "SELECT id, action, confidence, suggested_actions, summary, key_insights, execution_ids, window_days, source_count, created_at FROM aggregated_recommendations WHERE user_id = $1 ORDER BY created_at DESC"
+	args := []interface{}{s.userID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
 	if err != nil {
-		log.Printf("Failed to get workflow executions by video ID %s: %v", videoID, err)
-		return []*models.WorkflowExecution{}
+		return nil, fmt.Errorf("failed to get aggregated recommendations: %w", err)
 	}
 	defer rows.Close()
 
-	executions := make([]*models.WorkflowExecution, 0)
+	recs := make([]*models.AggregatedRecommendation, 0)
 	for rows.Next() {
-		var e models.WorkflowExecution
-		var videoTitle, videoIDVal, sourceIDVal, transcriptID, analysisID, recommendationID, errorMsg sql.NullString
-		var startedAt, completedAt sql.NullTime
+		var rec models.AggregatedRecommendation
+		var suggestedActionsJSON, keyInsightsJSON, executionIDsJSON []byte
+		var createdAt sql.NullTime
 
-		err := rows.Scan(&e.ID, &e.Status, &videoIDVal, &e.VideoURL, &videoTitle, &sourceIDVal, &transcriptID, &analysisID, &recommendationID, &errorMsg, &e.CreatedAt, &startedAt, &completedAt)
+		err := rows.Scan(&rec.ID, &rec.Action, &rec.Confidence, &suggestedActionsJSON, &rec.Summary, &keyInsightsJSON, &executionIDsJSON, &rec.WindowDays, &rec.SourceCount, &createdAt)
 		if err != nil {
-			log.Printf("Failed to scan workflow execution row: %v", err)
-			continue
+			return nil, fmt.Errorf("failed to scan aggregated recommendation row: %w", err)
 		}
 
-		if videoIDVal.Valid {
-			e.VideoID = videoIDVal.String
-		}
-		if videoTitle.Valid {
-			e.VideoTitle = videoTitle.String
-		}
-		if sourceIDVal.Valid {
-			e.SourceID = sourceIDVal.String
-		}
-		if transcriptID.Valid {
-			e.TranscriptID = transcriptID.String
+		// Unmarshal JSON fields
+		if err := json.Unmarshal(suggestedActionsJSON, &rec.SuggestedActions); err != nil {
+			log.Printf("Failed to unmarshal suggested actions for aggregated recommendation %s: %v", rec.ID, err)
+			rec.SuggestedActions = []models.SuggestedAction{}
 		}
-		if analysisID.Valid {
-			e.AnalysisID = analysisID.String
+		if err := json.Unmarshal(keyInsightsJSON, &rec.KeyInsights); err != nil {
+			log.Printf("Failed to unmarshal key insights for aggregated recommendation %s: %v", rec.ID, err)
+			rec.KeyInsights = []string{}
 		}
-		if recommendationID.Valid {
-			e.RecommendationID = recommendationID.String
+		if err := json.Unmarshal(executionIDsJSON, &rec.ExecutionIDs); err != nil {
+			log.Printf("Failed to unmarshal execution IDs for aggregated recommendation %s: %v", rec.ID, err)
+			rec.ExecutionIDs = []string{}
 		}
-		if errorMsg.Valid {
-			e.Error = errorMsg.String
-		}
-		e.StartedAt = parseTimestamp(startedAt)
-		e.CompletedAt = parseTimestamp(completedAt)
+		rec.GeneratedAt = parseTimestamp(createdAt)
 
-		executions = append(executions, &e)
+		recs = append(recs, &rec)
 	}
 
-	return executions
+	return recs, rows.Err()
 }
 
-
-// Aggregated Recommendation operations
-
 // GetLatestAggregatedRecommendation returns the most recent aggregated recommendation
-func (s *PostgresStore) GetLatestAggregatedRecommendation() (*models.AggregatedRecommendation, bool) {
-	ctx, cancel := s.getContext()
-	defer cancel()
-	
-	var rec models.AggregatedRecommendation
-	var suggestedActionsJSON, keyInsightsJSON, executionIDsJSON []byte
-	var createdAt, updatedAt sql.NullTime
-	
-	err := s.pool.QueryRow(ctx,
-		"SELECT id, action, confidence, suggested_actions, summary, key_insights, execution_ids, created_at, updated_at FROM aggregated_recommendations ORDER BY created_at DESC LIMIT 1",
-	).Scan(&rec.ID, &rec.Action, &rec.Confidence, &suggestedActionsJSON, &rec.Summary, &keyInsightsJSON, &executionIDsJSON, &createdAt, &updatedAt)
-	
+func (s *PostgresStore) GetLatestAggregatedRecommendation(ctx context.Context) (*models.AggregatedRecommendation, error) {
+	recs, err := s.GetAggregatedRecommendations(ctx, 1)
 	if err != nil {
-		if err != sql.ErrNoRows {
-			log.Printf("Failed to get latest aggregated recommendation: %v", err)
-		}
-		return nil, false
-	}
-	
-	// Unmarshal JSON fields
-	if err := json.Unmarshal(suggestedActionsJSON, &rec.SuggestedActions); err != nil {
-		log.Printf("Failed to unmarshal suggested actions: %v", err)
-		rec.SuggestedActions = []models.SuggestedAction{}
-	}
-	if err := json.Unmarshal(keyInsightsJSON, &rec.KeyInsights); err != nil {
-		log.Printf("Failed to unmarshal key insights: %v", err)
-		rec.KeyInsights = []string{}
+		return nil, err
 	}
-	if err := json.Unmarshal(executionIDsJSON, &rec.ExecutionIDs); err != nil {
-		log.Printf("Failed to unmarshal execution IDs: %v", err)
-		rec.ExecutionIDs = []string{}
+	if len(recs) == 0 {
+		return nil, ErrNotFound
 	}
-	
-	rec.CreatedAt = parseTimestamp(createdAt)
-	rec.UpdatedAt = parseTimestamp(updatedAt)
-	
-	return &rec, true
+	return recs[0], nil
 }
 
 // CreateOrUpdateAggregatedRecommendation creates or updates an aggregated recommendation
-func (s *PostgresStore) CreateOrUpdateAggregatedRecommendation(rec *models.AggregatedRecommendation) error {
-	ctx, cancel := s.getContext()
+func (s *PostgresStore) CreateOrUpdateAggregatedRecommendation(ctx context.Context, rec *models.AggregatedRecommendation) error {
+	ctx, cancel := s.getContext(ctx)
 	defer cancel()
-	
+
 	// Marshal JSON fields
 	suggestedActionsJSON, err := json.Marshal(rec.SuggestedActions)
 	if err != nil {
 		log.Printf("Failed to marshal suggested actions: %v", err)
 		suggestedActionsJSON = []byte("[]")
 	}
-	
+
 	keyInsightsJSON, err := json.Marshal(rec.KeyInsights)
 	if err != nil {
 		log.Printf("Failed to marshal key insights: %v", err)
 		keyInsightsJSON = []byte("[]")
 	}
-	
+
 	executionIDsJSON, err := json.Marshal(rec.ExecutionIDs)
 	if err != nil {
 		log.Printf("Failed to marshal execution IDs: %v", err)
 		return fmt.Errorf("failed to marshal execution IDs: %w", err)
 	}
-	
-	_, err = s.pool.Exec(ctx,
-		`INSERT INTO aggregated_recommendations (id, action, confidence, suggested_actions, summary, key_insights, execution_ids, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+
+	generatedAt := nullableTime(rec.GeneratedAt)
+
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO aggregated_recommendations (id, action, confidence, suggested_actions, summary, key_insights, execution_ids, window_days, source_count, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, CURRENT_TIMESTAMP), CURRENT_TIMESTAMP)
 		 ON CONFLICT (id) DO UPDATE SET
 		 action = EXCLUDED.action,
 		 confidence = EXCLUDED.confidence,
@@ -1297,13 +2990,16 @@ func (s *PostgresStore) CreateOrUpdateAggregatedRecommendation(rec *models.Aggre
 		 summary = EXCLUDED.summary,
 		 key_insights = EXCLUDED.key_insights,
 		 execution_ids = EXCLUDED.execution_ids,
-		 updated_at = CURRENT_TIMESTAMP`,
-		rec.ID, rec.Action, rec.Confidence, suggestedActionsJSON, rec.Summary, keyInsightsJSON, executionIDsJSON)
-	
+		 window_days = EXCLUDED.window_days,
+		 source_count = EXCLUDED.source_count,
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE aggregated_recommendations.user_id = EXCLUDED.user_id`,
+		rec.ID, rec.Action, rec.Confidence, suggestedActionsJSON, rec.Summary, keyInsightsJSON, executionIDsJSON, rec.WindowDays, rec.SourceCount, s.userID, generatedAt)
+
 	if err != nil {
 		log.Printf("Failed to create/update aggregated recommendation %s: %v", rec.ID, err)
 		return fmt.Errorf("failed to create/update aggregated recommendation: %w", err)
 	}
-	
+
 	return nil
 }
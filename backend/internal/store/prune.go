@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// deleteWorkflowExecutions deletes the executions in one transaction, with their artifacts
+// where no other execution references them, and returns how many were deleted. Executions
+// that were deleted concurrently are skipped.
+func deleteWorkflowExecutions(ctx context.Context, s Store, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	deleted := 0
+	err := s.WithTransaction(ctx, func(tx Store) error {
+		deleted = 0
+		for _, id := range ids {
+			err := tx.DeleteWorkflowExecution(ctx, id, true)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// pruneTranscriptsCondition selects transcripts of user $2 created before $1 that no execution
+// completed since $1 references
+const pruneTranscriptsCondition = ` WHERE user_id = $2 AND created_at < $1 AND NOT EXISTS (
+		 SELECT 1 FROM workflow_executions e WHERE e.transcript_id = video_transcripts.id AND e.completed_at >= $1)`
+
+// pruneTranscriptsSQL returns the statement that prunes user $2's transcripts older than $1. Deleting a
+// transcript cascades to its analyses and recommendations and clears executions' references.
+func pruneTranscriptsSQL(keepMetadata bool) string {
+	if keepMetadata {
+		return "UPDATE video_transcripts SET text = ''" + pruneTranscriptsCondition + " AND text <> ''"
+	}
+	return "DELETE FROM video_transcripts" + pruneTranscriptsCondition
+}
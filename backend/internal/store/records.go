@@ -0,0 +1,45 @@
+package store
+
+import "fmt"
+
+// RecordKind names a type of stored record; the names match the sections of a data export
+type RecordKind string
+
+const (
+	RecordPortfolios                RecordKind = "portfolios"
+	RecordAccounts                  RecordKind = "accounts"
+	RecordInvestments               RecordKind = "investments"
+	RecordTransactions              RecordKind = "transactions"
+	RecordNetWorthSnapshots         RecordKind = "networth_snapshots"
+	RecordYouTubeSources            RecordKind = "youtube_sources"
+	RecordTranscripts               RecordKind = "transcripts"
+	RecordMarketAnalyses            RecordKind = "market_analyses"
+	RecordRecommendations           RecordKind = "recommendations"
+	RecordWorkflowExecutions        RecordKind = "workflow_executions"
+	RecordAggregatedRecommendations RecordKind = "aggregated_recommendations"
+)
+
+// recordTables maps each kind to its table
+var recordTables = map[RecordKind]string{
+	RecordPortfolios:                "portfolios",
+	RecordAccounts:                  "accounts",
+	RecordInvestments:               "investments",
+	RecordTransactions:              "transactions",
+	RecordNetWorthSnapshots:         "networth_snapshots",
+	RecordYouTubeSources:            "youtube_sources",
+	RecordTranscripts:               "video_transcripts",
+	RecordMarketAnalyses:            "market_analyses",
+	RecordRecommendations:           "recommendations",
+	RecordWorkflowExecutions:        "workflow_executions",
+	RecordAggregatedRecommendations: "aggregated_recommendations",
+}
+
+// deleteRecordsSQL returns the statement that deletes every record of kind that userID owns,
+// along with its arguments
+func deleteRecordsSQL(kind RecordKind, userID string) (string, []interface{}, error) {
+	table, ok := recordTables[kind]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown record kind %q", kind)
+	}
+	return "DELETE FROM " + table + " WHERE user_id = $1", []interface{}{userID}, nil
+}
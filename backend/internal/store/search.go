@@ -0,0 +1,111 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// Characters of transcript text kept before and after the first match in a search snippet
+const (
+	snippetLead  = 80
+	snippetTrail = 200
+)
+
+// searchTerms splits a search query into lower-case terms. A transcript matches when every
+// term appears in its title or text.
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// matchesTerms reports whether every term appears in the title or text, ignoring case
+func matchesTerms(t *models.VideoTranscript, terms []string) bool {
+	title, text := strings.ToLower(t.VideoTitle), strings.ToLower(t.Text)
+	for _, term := range terms {
+		if !strings.Contains(title, term) && !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return len(terms) > 0
+}
+
+// newSearchResult builds the search result for a matching transcript. The snippet is taken
+// from around the first occurrence of any term in the text, or from its start when only the
+// title matched.
+func newSearchResult(t *models.VideoTranscript, sourceName string, terms []string) *models.TranscriptSearchResult {
+	text := []rune(t.Text)
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []models.TextRange
+	for _, term := range terms {
+		needle := []rune(term)
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(needle)], needle) {
+				matches = append(matches, models.TextRange{Start: i, End: i + len(needle)})
+			}
+		}
+	}
+
+	start, end := 0, min(len(text), snippetLead+snippetTrail)
+	if len(matches) > 0 {
+		first := matches[0].Start
+		for _, m := range matches[1:] {
+			first = min(first, m.Start)
+		}
+		start = max(0, first-snippetLead)
+		end = min(len(text), first+snippetTrail)
+	}
+
+	highlights := make([]models.TextRange, 0)
+	for _, m := range matches {
+		if m.Start >= start && m.End <= end {
+			highlights = append(highlights, models.TextRange{Start: m.Start - start, End: m.End - start})
+		}
+	}
+	sort.Slice(highlights, func(i, j int) bool {
+		if highlights[i].Start != highlights[j].Start {
+			return highlights[i].Start < highlights[j].Start
+		}
+		return highlights[i].End > highlights[j].End
+	})
+
+	return &models.TranscriptSearchResult{
+		TranscriptID: t.ID,
+		VideoID:      t.VideoID,
+		VideoTitle:   t.VideoTitle,
+		VideoURL:     t.VideoURL,
+		SourceID:     t.SourceID,
+		SourceName:   sourceName,
+		Snippet:      string(text[start:end]),
+		Highlights:   highlights,
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// trailingScanner scans a row whose leading columns are read by a scan helper such as
+// scanTranscript, passing the columns selected after them to extra
+type trailingScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (t trailingScanner) Scan(dest ...interface{}) error {
+	return t.row.Scan(append(dest, t.extra...)...)
+}
+
+// sourceNameColumn selects the name of a transcript's source alongside transcriptColumns
+const sourceNameColumn = "(SELECT name FROM youtube_sources WHERE youtube_sources.id = video_transcripts.source_id AND youtube_sources.user_id = video_transcripts.user_id)"
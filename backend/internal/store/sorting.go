@@ -0,0 +1,167 @@
+package store
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+
+	"0xnetworth/backend/internal/models"
+)
+
+// SortOption orders a listing by one of its sort fields (see InvestmentSortFields,
+// PortfolioSortFields and ExecutionSortFields). The zero value keeps the listing's default order.
+type SortOption struct {
+	Field string
+	Desc  bool
+}
+
+// sortField is a whitelisted sort field. Only column is ever written into SQL, so a field
+// name from a request can never reach a query.
+type sortField[T any] struct {
+	column  string
+	compare func(a, b *T) int
+	missing func(*T) bool // set for fields stored as NULL when unset, which sort last either way
+}
+
+// sortFields is the whitelist of sort fields for one kind of record. Ties sort by ID.
+type sortFields[T any] struct {
+	fields map[string]sortField[T]
+	id     func(*T) string
+}
+
+// names returns the allowed field names in alphabetical order
+func (f sortFields[T]) names() []string {
+	names := make([]string, 0, len(f.fields))
+	for name := range f.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the whitelisted field for o
+func (f sortFields[T]) lookup(o SortOption) (sortField[T], error) {
+	field, ok := f.fields[o.Field]
+	if !ok {
+		return field, fmt.Errorf("invalid sort field %q; allowed fields: %s", o.Field, strings.Join(f.names(), ", "))
+	}
+	return field, nil
+}
+
+// orderBy returns the ORDER BY clause for o, or defaultOrder if o is the zero value
+func (f sortFields[T]) orderBy(o SortOption, defaultOrder string) (string, error) {
+	if o.Field == "" {
+		return defaultOrder, nil
+	}
+	field, err := f.lookup(o)
+	if err != nil {
+		return "", err
+	}
+	clause := " ORDER BY " + field.column
+	if o.Desc {
+		clause += " DESC"
+	} else {
+		clause += " ASC"
+	}
+	if field.missing != nil {
+		clause += " NULLS LAST"
+	}
+	return clause + ", id ASC", nil
+}
+
+// sort orders records in memory the way orderBy orders them in SQL. It reports whether o
+// named a field, so that callers can fall back to their default order.
+func (f sortFields[T]) sort(records []*T, o SortOption) (bool, error) {
+	if o.Field == "" {
+		return false, nil
+	}
+	field, err := f.lookup(o)
+	if err != nil {
+		return false, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if field.missing != nil {
+			if missingA, missingB := field.missing(a), field.missing(b); missingA != missingB {
+				return missingB
+			}
+		}
+		c := field.compare(a, b)
+		if o.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return f.id(a) < f.id(b)
+	})
+	return true, nil
+}
+
+var investmentSortFields = sortFields[models.Investment]{
+	fields: map[string]sortField[models.Investment]{
+		"symbol":   {column: "symbol", compare: func(a, b *models.Investment) int { return strings.Compare(a.Symbol, b.Symbol) }},
+		"name":     {column: "LOWER(name)", compare: func(a, b *models.Investment) int { return compareFolded(a.Name, b.Name) }},
+		"platform": {column: "platform", compare: func(a, b *models.Investment) int { return strings.Compare(string(a.Platform), string(b.Platform)) }},
+		"quantity": {column: "quantity", compare: func(a, b *models.Investment) int { return cmp.Compare(a.Quantity, b.Quantity) }},
+		"price":    {column: "price", compare: func(a, b *models.Investment) int { return cmp.Compare(a.Price, b.Price) }},
+		"value":    {column: "value", compare: func(a, b *models.Investment) int { return cmp.Compare(a.Value, b.Value) }},
+		"last_updated": {
+			column:  "last_updated",
+			compare: func(a, b *models.Investment) int { return a.LastUpdated.Compare(b.LastUpdated) },
+			missing: func(inv *models.Investment) bool { return inv.LastUpdated.IsZero() },
+		},
+	},
+	id: func(inv *models.Investment) string { return inv.ID },
+}
+
+var portfolioSortFields = sortFields[models.Portfolio]{
+	fields: map[string]sortField[models.Portfolio]{
+		"name":     {column: "LOWER(name)", compare: func(a, b *models.Portfolio) int { return compareFolded(a.Name, b.Name) }},
+		"platform": {column: "platform", compare: func(a, b *models.Portfolio) int { return strings.Compare(string(a.Platform), string(b.Platform)) }},
+		"last_synced": {
+			column:  "last_synced",
+			compare: func(a, b *models.Portfolio) int { return a.LastSynced.Compare(b.LastSynced) },
+			missing: func(p *models.Portfolio) bool { return p.LastSynced.IsZero() },
+		},
+		"display_order": {
+			column:  "display_order",
+			compare: func(a, b *models.Portfolio) int { return cmp.Compare(*a.DisplayOrder, *b.DisplayOrder) },
+			missing: func(p *models.Portfolio) bool { return p.DisplayOrder == nil },
+		},
+	},
+	id: func(p *models.Portfolio) string { return p.ID },
+}
+
+var executionSortFields = sortFields[models.WorkflowExecution]{
+	fields: map[string]sortField[models.WorkflowExecution]{
+		"status":     {column: "status", compare: func(a, b *models.WorkflowExecution) int { return strings.Compare(string(a.Status), string(b.Status)) }},
+		"created_at": {column: "created_at", compare: func(a, b *models.WorkflowExecution) int { return a.CreatedAt.Compare(b.CreatedAt) }},
+		"started_at": {
+			column:  "started_at",
+			compare: func(a, b *models.WorkflowExecution) int { return a.StartedAt.Compare(b.StartedAt) },
+			missing: func(e *models.WorkflowExecution) bool { return e.StartedAt.IsZero() },
+		},
+		"completed_at": {
+			column:  "completed_at",
+			compare: func(a, b *models.WorkflowExecution) int { return a.CompletedAt.Compare(b.CompletedAt) },
+			missing: func(e *models.WorkflowExecution) bool { return e.CompletedAt.IsZero() },
+		},
+	},
+	id: func(e *models.WorkflowExecution) string { return e.ID },
+}
+
+// compareFolded compares strings case-insensitively, like ordering by LOWER() in SQL
+func compareFolded(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// InvestmentSortFields returns the fields GetAllInvestments can sort by
+func InvestmentSortFields() []string { return investmentSortFields.names() }
+
+// PortfolioSortFields returns the fields GetAllPortfolios can sort by
+func PortfolioSortFields() []string { return portfolioSortFields.names() }
+
+// ExecutionSortFields returns the fields ListWorkflowExecutions can sort by
+func ExecutionSortFields() []string { return executionSortFields.names() }
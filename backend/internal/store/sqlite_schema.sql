@@ -0,0 +1,288 @@
+-- 0xNetworth Database Schema
+-- SQLite schema for single-file deployments; mirrors the PostgreSQL migrations in migrations/.
+-- JSON columns are stored as TEXT and timestamps as UTC text, which SQLite compares correctly.
+
+-- Users table
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE, -- SHA-256 hex digest of the user's API token
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO users (id, name) VALUES ('default', 'Default user') ON CONFLICT (id) DO NOTHING;
+
+-- Portfolios table
+CREATE TABLE IF NOT EXISTS portfolios (
+    id TEXT PRIMARY KEY,
+    platform TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT,
+    last_synced TIMESTAMP,
+    tax_treatment TEXT,
+    custodian TEXT,
+    display_order INTEGER,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Accounts table: one balance per currency, such as a Coinbase USD or BTC wallet
+CREATE TABLE IF NOT EXISTS accounts (
+    id TEXT PRIMARY KEY,
+    platform TEXT NOT NULL,
+    portfolio_id TEXT,
+    name TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    type TEXT,
+    available_balance REAL NOT NULL DEFAULT 0,
+    hold REAL NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    last_synced TIMESTAMP,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Investments table
+CREATE TABLE IF NOT EXISTS investments (
+    id TEXT PRIMARY KEY,
+    account_id TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    name TEXT,
+    quantity REAL NOT NULL,
+    value REAL NOT NULL,
+    price REAL NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    native_value REAL,
+    native_currency TEXT,
+    asset_type TEXT,
+    staked BOOLEAN NOT NULL DEFAULT 0,
+    stale_price BOOLEAN NOT NULL DEFAULT 0,
+    is_dust BOOLEAN NOT NULL DEFAULT 0,
+    price_change_24h_pct REAL,
+    value_change_24h REAL,
+    cost_basis REAL,
+    average_buy_price REAL,
+    first_acquired_at TIMESTAMP,
+    unrealized_gain REAL,
+    last_updated TIMESTAMP,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    deactivated_at TIMESTAMP,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transactions table
+CREATE TABLE IF NOT EXISTS transactions (
+    id TEXT PRIMARY KEY,
+    account_id TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    type TEXT NOT NULL,
+    symbol TEXT,
+    quantity REAL,
+    amount REAL NOT NULL,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    fee REAL,
+    timestamp TIMESTAMP NOT NULL,
+    description TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sync metadata table
+CREATE TABLE IF NOT EXISTS sync_metadata (
+    id TEXT PRIMARY KEY,
+    platform TEXT NOT NULL,
+    last_sync_time TIMESTAMP,
+    last_attempt_time TIMESTAMP,
+    sync_status TEXT,
+    error_message TEXT,
+    portfolios_synced INTEGER NOT NULL DEFAULT 0,
+    accounts_synced INTEGER NOT NULL DEFAULT 0,
+    investments_synced INTEGER NOT NULL DEFAULT 0,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_metadata_user_platform ON sync_metadata(user_id, platform);
+
+-- Institution logins linked through Plaid; the access token is encrypted by the server
+CREATE TABLE IF NOT EXISTS plaid_items (
+    id TEXT PRIMARY KEY,
+    institution_id TEXT,
+    institution_name TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL,
+    encrypted_access_token TEXT NOT NULL,
+    account_ids TEXT NOT NULL DEFAULT '[]', -- JSON array
+    status TEXT NOT NULL DEFAULT 'healthy', -- login_required once it has to be re-linked
+    last_synced_at TIMESTAMP,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_plaid_items_user ON plaid_items(user_id);
+
+-- Net worth history, one row per user per minute
+CREATE TABLE IF NOT EXISTS networth_snapshots (
+    user_id TEXT NOT NULL REFERENCES users(id),
+    taken_at TIMESTAMP NOT NULL, -- UTC, truncated to the minute
+    total_value REAL NOT NULL,
+    currency TEXT NOT NULL,
+    by_platform TEXT NOT NULL, -- JSON object
+    by_asset_type TEXT NOT NULL, -- JSON object
+    by_tax_treatment TEXT NOT NULL, -- JSON object
+    account_count INTEGER NOT NULL,
+    PRIMARY KEY (user_id, taken_at)
+);
+
+-- Per-symbol position history, one row per user, platform and symbol per minute
+CREATE TABLE IF NOT EXISTS investment_history (
+    user_id TEXT NOT NULL REFERENCES users(id),
+    platform TEXT NOT NULL,
+    symbol TEXT NOT NULL, -- uppercase
+    taken_at TIMESTAMP NOT NULL, -- UTC, truncated to the minute
+    quantity REAL NOT NULL,
+    price REAL NOT NULL,
+    value REAL NOT NULL,
+    currency TEXT NOT NULL,
+    PRIMARY KEY (user_id, symbol, platform, taken_at)
+);
+
+-- YouTube sources table
+CREATE TABLE IF NOT EXISTS youtube_sources (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    url TEXT NOT NULL,
+    name TEXT NOT NULL,
+    channel_id TEXT,
+    playlist_id TEXT,
+    enabled BOOLEAN DEFAULT 1,
+    schedule TEXT,
+    title_includes TEXT NOT NULL DEFAULT '[]', -- JSON array of patterns
+    title_excludes TEXT NOT NULL DEFAULT '[]', -- JSON array of patterns
+    record_skipped BOOLEAN NOT NULL DEFAULT 0,
+    last_processed TIMESTAMP,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Estimated YouTube Data API quota used per quota day (YYYY-MM-DD, Pacific time)
+CREATE TABLE IF NOT EXISTS youtube_quota_usage (
+    day TEXT PRIMARY KEY,
+    units INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Video transcripts table
+CREATE TABLE IF NOT EXISTS video_transcripts (
+    id TEXT PRIMARY KEY,
+    video_id TEXT NOT NULL,
+    video_title TEXT,
+    video_url TEXT NOT NULL,
+    text TEXT NOT NULL,
+    duration INTEGER,
+    source_id TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Market analyses table
+CREATE TABLE IF NOT EXISTS market_analyses (
+    id TEXT PRIMARY KEY,
+    transcript_id TEXT NOT NULL,
+    conditions TEXT NOT NULL,
+    trends TEXT, -- JSON array
+    risk_factors TEXT, -- JSON array
+    summary TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (transcript_id) REFERENCES video_transcripts(id) ON DELETE CASCADE
+);
+
+-- Recommendations table
+CREATE TABLE IF NOT EXISTS recommendations (
+    id TEXT PRIMARY KEY,
+    analysis_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    confidence REAL NOT NULL CHECK (confidence >= 0.0 AND confidence <= 1.0),
+    suggested_actions TEXT, -- JSON array
+    summary TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (analysis_id) REFERENCES market_analyses(id) ON DELETE CASCADE
+);
+
+-- Workflow executions table
+CREATE TABLE IF NOT EXISTS workflow_executions (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL,
+    video_id TEXT,
+    video_url TEXT NOT NULL,
+    video_title TEXT,
+    source_id TEXT,
+    transcript_id TEXT,
+    analysis_id TEXT,
+    recommendation_id TEXT,
+    error TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    claimed_video_id TEXT, -- set while processing or once completed, see sqliteUpgradeIndexes
+    FOREIGN KEY (transcript_id) REFERENCES video_transcripts(id) ON DELETE SET NULL,
+    FOREIGN KEY (analysis_id) REFERENCES market_analyses(id) ON DELETE SET NULL,
+    FOREIGN KEY (recommendation_id) REFERENCES recommendations(id) ON DELETE SET NULL
+);
+
+-- Step-level event log of workflow executions
+CREATE TABLE IF NOT EXISTS workflow_execution_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    execution_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    detail TEXT,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (execution_id) REFERENCES workflow_executions(id) ON DELETE CASCADE
+);
+
+-- Aggregated recommendations table
+CREATE TABLE IF NOT EXISTS aggregated_recommendations (
+    id TEXT PRIMARY KEY,
+    action TEXT NOT NULL,
+    confidence REAL NOT NULL CHECK (confidence >= 0.0 AND confidence <= 1.0),
+    suggested_actions TEXT, -- JSON array
+    summary TEXT NOT NULL,
+    key_insights TEXT, -- JSON array
+    execution_ids TEXT NOT NULL, -- JSON array of execution IDs used to generate this recommendation
+    window_days INTEGER NOT NULL DEFAULT 0,
+    source_count INTEGER NOT NULL DEFAULT 0,
+    user_id TEXT NOT NULL DEFAULT 'default' REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for common queries
+CREATE INDEX IF NOT EXISTS idx_aggregated_recommendations_created_at ON aggregated_recommendations(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_id ON transactions(account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_timestamp ON transactions(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_account_timestamp ON transactions(account_id, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_investments_account_id ON investments(account_id);
+CREATE INDEX IF NOT EXISTS idx_investments_platform ON investments(platform);
+CREATE INDEX IF NOT EXISTS idx_portfolios_platform ON portfolios(platform);
+CREATE INDEX IF NOT EXISTS idx_portfolios_user_id ON portfolios(user_id);
+CREATE INDEX IF NOT EXISTS idx_accounts_user_platform ON accounts(user_id, platform);
+CREATE INDEX IF NOT EXISTS idx_investments_user_id ON investments(user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_status ON workflow_executions(status);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_source_id ON workflow_executions(source_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_completed_at ON workflow_executions(completed_at);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_created_at_id ON workflow_executions(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_events_execution_id ON workflow_execution_events(execution_id, id);
+CREATE INDEX IF NOT EXISTS idx_video_transcripts_video_id ON video_transcripts(video_id);
+CREATE INDEX IF NOT EXISTS idx_video_transcripts_source_id ON video_transcripts(source_id);
+CREATE INDEX IF NOT EXISTS idx_market_analyses_transcript_id ON market_analyses(transcript_id);
+CREATE INDEX IF NOT EXISTS idx_recommendations_analysis_id ON recommendations(analysis_id);
@@ -0,0 +1,2557 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"0xnetworth/backend/internal/currency"
+	"0xnetworth/backend/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema creates every table on first run; it is embedded so a single binary can
+// start from an empty database file
+//
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// SQLiteStore is a SQLite-backed store implementation for single-binary deployments.
+// It runs the same queries as PostgresStore wherever the two dialects agree.
+// Financial data is scoped to userID; use ForUser to obtain a view for another user.
+type SQLiteStore struct {
+	db      *sql.DB
+	tx      *sql.Tx // set inside WithTransaction
+	timeout time.Duration
+	userID  string
+}
+
+// Fail the build if SQLiteStore drifts from the interfaces it implements
+var _ Store = (*SQLiteStore)(nil)
+var _ PoolStatsProvider = (*SQLiteStore)(nil)
+
+// NewSQLiteStore opens the SQLite database at path, creating the file and schema if needed
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	// Foreign keys are off by default in SQLite. WAL lets readers proceed during a write,
+	// and the busy timeout makes concurrent writers wait rather than fail immediately.
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	s := &SQLiteStore{
+		db:      db,
+		timeout: getEnvDuration("DB_QUERY_TIMEOUT", defaultQueryTimeout),
+		userID:  models.DefaultUserID,
+	}
+
+	ctx, cancel := s.getContext(context.Background())
+	defer cancel()
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	if err := s.addMissingColumns(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade schema: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, sqliteUpgradeIndexes); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade schema: %w", err)
+	}
+
+	return s, nil
+}
+
+// sqliteAddedColumns lists columns added to tables after they were first created. The schema
+// only creates missing tables, so databases created by older versions get these added at startup.
+var sqliteAddedColumns = []struct {
+	table, column, definition string
+}{
+	{"investments", "active", "BOOLEAN NOT NULL DEFAULT 1"},
+	{"investments", "deactivated_at", "TIMESTAMP"},
+	{"investments", "native_value", "REAL"},
+	{"investments", "native_currency", "TEXT"},
+	{"investments", "staked", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"investments", "stale_price", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"investments", "is_dust", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"investments", "price_change_24h_pct", "REAL"},
+	{"investments", "value_change_24h", "REAL"},
+	{"sync_metadata", "last_attempt_time", "TIMESTAMP"},
+	{"sync_metadata", "portfolios_synced", "INTEGER NOT NULL DEFAULT 0"},
+	{"sync_metadata", "accounts_synced", "INTEGER NOT NULL DEFAULT 0"},
+	{"sync_metadata", "investments_synced", "INTEGER NOT NULL DEFAULT 0"},
+	{"workflow_executions", "claimed_video_id", "TEXT"},
+	{"plaid_items", "account_ids", "TEXT NOT NULL DEFAULT '[]'"},
+	{"plaid_items", "status", "TEXT NOT NULL DEFAULT 'healthy'"},
+	{"plaid_items", "last_synced_at", "TIMESTAMP"},
+	{"youtube_sources", "title_includes", "TEXT NOT NULL DEFAULT '[]'"},
+	{"youtube_sources", "title_excludes", "TEXT NOT NULL DEFAULT '[]'"},
+	{"youtube_sources", "record_skipped", "BOOLEAN NOT NULL DEFAULT 0"},
+	// Added columns cannot reference users while foreign keys are on, so only new databases
+	// get the constraint
+	{"youtube_sources", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"video_transcripts", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"market_analyses", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"recommendations", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"workflow_executions", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"workflow_execution_events", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+	{"aggregated_recommendations", "user_id", "TEXT NOT NULL DEFAULT 'default'"},
+}
+
+// sqliteUpgradeIndexes creates indexes on columns in sqliteAddedColumns, which only exist once
+// addMissingColumns has run. A video is claimed by the user's execution processing it, or that
+// completed it, so concurrent triggers cannot both process it; the claim used to be global.
+const sqliteUpgradeIndexes = `DROP INDEX IF EXISTS idx_workflow_executions_claimed_video_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_executions_user_claimed_video_id
+    ON workflow_executions(user_id, claimed_video_id) WHERE claimed_video_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_youtube_sources_user_id ON youtube_sources(user_id);
+CREATE INDEX IF NOT EXISTS idx_video_transcripts_user_video_id ON video_transcripts(user_id, video_id);
+CREATE INDEX IF NOT EXISTS idx_market_analyses_user_id ON market_analyses(user_id);
+CREATE INDEX IF NOT EXISTS idx_recommendations_user_id ON recommendations(user_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_executions_user_created_at_id ON workflow_executions(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_events_user_id ON workflow_execution_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_aggregated_recommendations_user_created_at ON aggregated_recommendations(user_id, created_at DESC);`
+
+// addMissingColumns adds any of sqliteAddedColumns the database does not have yet
+func (s *SQLiteStore) addMissingColumns(ctx context.Context) error {
+	for _, added := range sqliteAddedColumns {
+		var exists int
+		err := s.db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", added.table, added.column).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", added.table, err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", added.table, added.column, err)
+		}
+		log.Printf("Added column %s.%s to SQLite database", added.table, added.column)
+	}
+	return nil
+}
+
+// getContext derives the context for a database operation from the caller's context,
+// with the configured query timeout as a ceiling
+func (s *SQLiteStore) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() {
+	if err := s.db.Close(); err != nil {
+		log.Printf("Failed to close SQLite database: %v", err)
+	}
+}
+
+// HealthCheck pings the database
+func (s *SQLiteStore) HealthCheck(ctx context.Context) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	return s.db.PingContext(ctx)
+}
+
+// PoolStats returns the current state of the database handle's connection pool
+func (s *SQLiteStore) PoolStats() PoolStats {
+	stats := s.db.Stats()
+	return PoolStats{
+		AcquiredConns: stats.InUse,
+		IdleConns:     stats.Idle,
+		TotalConns:    stats.OpenConnections,
+		MaxConns:      stats.MaxOpenConnections,
+	}
+}
+
+// ForUser returns a view of the store whose queries are scoped to userID.
+// The view shares the database handle and must not be closed separately.
+func (s *SQLiteStore) ForUser(userID string) Store {
+	scoped := *s
+	scoped.userID = userID
+	return &scoped
+}
+
+// UserID returns the user this store is scoped to
+func (s *SQLiteStore) UserID() string {
+	return s.userID
+}
+
+// placeholderPattern matches PostgreSQL-style $N placeholders
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// rebind rewrites $N placeholders as SQLite's ?N, so queries (including the clauses built
+// by ListOptions.sqlClause) bind their arguments by number exactly as in PostgreSQL
+func rebind(query string) string {
+	return placeholderPattern.ReplaceAllString(query, "?$1")
+}
+
+// sqliteConn runs queries on the database or inside a transaction
+type sqliteConn interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// conn returns the transaction inside WithTransaction, otherwise the database
+func (s *SQLiteStore) conn() sqliteConn {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+func (s *SQLiteStore) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.conn().ExecContext(ctx, rebind(query), args...)
+}
+
+func (s *SQLiteStore) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn().QueryContext(ctx, rebind(query), args...)
+}
+
+func (s *SQLiteStore) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.conn().QueryRowContext(ctx, rebind(query), args...)
+}
+
+// sqliteTx is a transaction started by begin
+type sqliteTx interface {
+	sqliteConn
+	Commit() error
+	Rollback() error
+}
+
+// begin starts a transaction, or a savepoint when the store is already inside one
+func (s *SQLiteStore) begin(ctx context.Context) (sqliteTx, error) {
+	if s.tx == nil {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT nested"); err != nil {
+		return nil, err
+	}
+	return &sqliteSavepoint{Tx: s.tx}, nil
+}
+
+// sqliteSavepoint is a nested transaction; committing releases the savepoint and rolling
+// back undoes only what ran since it was taken
+type sqliteSavepoint struct {
+	*sql.Tx
+	done bool
+}
+
+func (sp *sqliteSavepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT nested")
+	return err
+}
+
+func (sp *sqliteSavepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	if _, err := sp.Tx.Exec("ROLLBACK TO SAVEPOINT nested"); err != nil {
+		return err
+	}
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT nested")
+	return err
+}
+
+// WithTransaction runs fn against a view of the store that runs every query in one database
+// transaction, committing it if fn returns nil. Inside another transaction it uses a savepoint.
+func (s *SQLiteStore) WithTransaction(ctx context.Context, fn func(tx Store) error) error {
+	if s.tx != nil {
+		sp, err := s.begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin savepoint: %w", err)
+		}
+		defer sp.Rollback()
+		if err := fn(s); err != nil {
+			return err
+		}
+		return sp.Commit()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	scoped := *s
+	scoped.tx = tx
+	if err := fn(&scoped); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteRecords deletes every record of kind
+func (s *SQLiteStore) DeleteRecords(ctx context.Context, kind RecordKind) (int, error) {
+	query, args, err := deleteRecordsSQL(kind, s.userID)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %s: %w", kind, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+// count runs a COUNT(*) query and returns the result
+func (s *SQLiteStore) count(ctx context.Context, query string, args ...interface{}) (int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var total int
+	err := s.queryRow(ctx, query, args...).Scan(&total)
+	return total, err
+}
+
+// deleteByID runs a DELETE and reports ErrNotFound when no row matched
+func (s *SQLiteStore) deleteByID(ctx context.Context, query string, args ...interface{}) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// jsonText marshals v for a JSON TEXT column, falling back to an empty array
+func jsonText(v interface{}, what, id string) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to marshal %s for %s: %v", what, id, err)
+		return "[]"
+	}
+	return string(data)
+}
+
+// User operations
+
+// GetUserByTokenHash returns the user owning the given API token hash
+func (s *SQLiteStore) GetUserByTokenHash(ctx context.Context, tokenHash string) (*models.User, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var user models.User
+	var name, hash sql.NullString
+	var createdAt sql.NullTime
+
+	err := s.queryRow(ctx,
+		"SELECT id, name, token_hash, created_at FROM users WHERE token_hash = $1",
+		tokenHash).Scan(&user.ID, &name, &hash, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to look up user by token: %w", err)
+	}
+
+	if name.Valid {
+		user.Name = name.String
+	}
+	if hash.Valid {
+		user.TokenHash = hash.String
+	}
+	user.CreatedAt = parseTimestamp(createdAt)
+	return &user, nil
+}
+
+// CreateOrUpdateUser creates or updates a user and its API token hash
+func (s *SQLiteStore) CreateOrUpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO users (id, name, token_hash, created_at, updated_at)
+		 VALUES ($1, $2, NULLIF($3, ''), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 name = EXCLUDED.name,
+		 token_hash = EXCLUDED.token_hash,
+		 updated_at = CURRENT_TIMESTAMP`,
+		user.ID, user.Name, user.TokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to save user %s: %w", user.ID, err)
+	}
+	return nil
+}
+
+// ListUserIDs returns the ID of every user in order, across users
+func (s *SQLiteStore) ListUserIDs(ctx context.Context) ([]string, error) {
+	ids, err := s.selectIDs(ctx, "SELECT id FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return ids, nil
+}
+
+// Portfolio operations
+
+// queryPortfolios runs a portfolio SELECT and scans every row
+func (s *SQLiteStore) queryPortfolios(ctx context.Context, query string, args ...interface{}) ([]*models.Portfolio, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	portfolios := make([]*models.Portfolio, 0)
+	for rows.Next() {
+		p, err := scanPortfolio(rows)
+		if err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, rows.Err()
+}
+
+// GetAllPortfolios returns a page of portfolios along with the total number of portfolios
+func (s *SQLiteStore) GetAllPortfolios(ctx context.Context, opts ListOptions, order SortOption) ([]*models.Portfolio, int, error) {
+	orderBy, err := portfolioSortFields.orderBy(order, portfolioOrder)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.CountPortfolios(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	portfolios, err := s.queryPortfolios(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE user_id = $1"+orderBy+page,
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get all portfolios: %w", err)
+	}
+	return portfolios, total, nil
+}
+
+// CountPortfolios returns the number of portfolios
+func (s *SQLiteStore) CountPortfolios(ctx context.Context) (int, error) {
+	count, err := s.count(ctx, "SELECT COUNT(*) FROM portfolios WHERE user_id = $1", s.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count portfolios: %w", err)
+	}
+	return count, nil
+}
+
+// GetPortfoliosByPlatform returns portfolios for a specific platform
+func (s *SQLiteStore) GetPortfoliosByPlatform(ctx context.Context, platform models.Platform) ([]*models.Portfolio, error) {
+	portfolios, err := s.queryPortfolios(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE user_id = $1 AND platform = $2"+portfolioOrder,
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolios by platform %s: %w", platform, err)
+	}
+	return portfolios, nil
+}
+
+// GetPortfolioByID returns a portfolio by ID
+func (s *SQLiteStore) GetPortfolioByID(ctx context.Context, id string) (*models.Portfolio, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	p, err := scanPortfolio(s.queryRow(ctx,
+		"SELECT "+portfolioColumns+" FROM portfolios WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get portfolio %s: %w", id, err)
+	}
+	return p, nil
+}
+
+// GetPortfolioValues returns the value and number of active holdings of every portfolio
+func (s *SQLiteStore) GetPortfolioValues(ctx context.Context) ([]*models.PortfolioValue, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, portfolioValuesQuery, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio values: %w", err)
+	}
+	defer rows.Close()
+
+	values := make([]*models.PortfolioValue, 0)
+	for rows.Next() {
+		value, err := scanPortfolioValue(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio value: %w", err)
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// CreateOrUpdatePortfolio creates or updates a portfolio.
+// User-managed metadata is only overwritten when the incoming portfolio sets it, so syncs preserve it.
+func (s *SQLiteStore) CreateOrUpdatePortfolio(ctx context.Context, portfolio *models.Portfolio) (UpsertResult, error) {
+	existing, err := s.GetPortfolioByID(ctx, portfolio.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	lastSynced := nullableTime(portfolio.LastSynced)
+
+	result, err := s.exec(ctx,
+		`INSERT INTO portfolios (id, platform, name, type, last_synced, tax_treatment, custodian, display_order, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 platform = EXCLUDED.platform,
+		 name = EXCLUDED.name,
+		 type = EXCLUDED.type,
+		 last_synced = EXCLUDED.last_synced,
+		 tax_treatment = COALESCE(EXCLUDED.tax_treatment, portfolios.tax_treatment),
+		 custodian = COALESCE(EXCLUDED.custodian, portfolios.custodian),
+		 display_order = COALESCE(EXCLUDED.display_order, portfolios.display_order),
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE portfolios.user_id = EXCLUDED.user_id`,
+		portfolio.ID, portfolio.Platform, portfolio.Name, portfolio.Type, lastSynced,
+		string(portfolio.TaxTreatment), portfolio.Custodian, portfolio.DisplayOrder, s.userID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to create/update portfolio %s: %w", portfolio.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return UpsertSkipped, nil
+	}
+	return upsertResult(existing, portfolio, samePortfolio), nil
+}
+
+// UpdatePortfolioMetadata applies a partial metadata update to an existing portfolio
+func (s *SQLiteStore) UpdatePortfolioMetadata(ctx context.Context, id string, update models.PortfolioMetadataUpdate) (*models.Portfolio, error) {
+	portfolio, err := s.GetPortfolioByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update.Apply(portfolio)
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err = s.exec(ctx,
+		`UPDATE portfolios
+		 SET tax_treatment = NULLIF($2, ''), custodian = NULLIF($3, ''), display_order = $4, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND user_id = $5`,
+		id, string(portfolio.TaxTreatment), portfolio.Custodian, portfolio.DisplayOrder, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update metadata for portfolio %s: %w", id, err)
+	}
+	return portfolio, nil
+}
+
+// DeletePortfolio deletes a portfolio by ID
+func (s *SQLiteStore) DeletePortfolio(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, rebind("DELETE FROM portfolios WHERE id = $1 AND user_id = $2"), id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio %s: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	// Transactions have no foreign key to portfolios, so cascade by hand
+	if _, err := tx.ExecContext(ctx, rebind("DELETE FROM transactions WHERE account_id = $1 AND user_id = $2"), id, s.userID); err != nil {
+		return fmt.Errorf("failed to delete transactions for portfolio %s: %w", id, err)
+	}
+	return tx.Commit()
+}
+
+// Account operations
+
+// queryAccounts runs an account SELECT and scans every row
+func (s *SQLiteStore) queryAccounts(ctx context.Context, query string, args ...interface{}) ([]*models.Account, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := make([]*models.Account, 0)
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// GetAllAccounts returns all accounts
+func (s *SQLiteStore) GetAllAccounts(ctx context.Context) ([]*models.Account, error) {
+	accounts, err := s.queryAccounts(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE user_id = $1"+accountOrder, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// GetAccountsByPlatform returns accounts for a specific platform
+func (s *SQLiteStore) GetAccountsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Account, error) {
+	accounts, err := s.queryAccounts(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE user_id = $1 AND platform = $2"+accountOrder,
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts by platform %s: %w", platform, err)
+	}
+	return accounts, nil
+}
+
+// GetAccountByID returns an account by ID
+func (s *SQLiteStore) GetAccountByID(ctx context.Context, id string) (*models.Account, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	a, err := scanAccount(s.queryRow(ctx,
+		"SELECT "+accountColumns+" FROM accounts WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get account %s: %w", id, err)
+	}
+	return a, nil
+}
+
+// CreateOrUpdateAccount creates or updates an account
+func (s *SQLiteStore) CreateOrUpdateAccount(ctx context.Context, account *models.Account) (UpsertResult, error) {
+	existing, err := s.GetAccountByID(ctx, account.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return 0, err
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	account.Currency = normalizeCurrency(account.Currency)
+	result, err := s.exec(ctx, accountUpsertSQL,
+		account.ID, account.Platform, account.PortfolioID, account.Name, account.Currency, account.Type,
+		account.Available, account.Hold, account.Active, nullableTime(account.LastSynced), s.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create/update account %s: %w", account.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if affected == 0 {
+		return UpsertSkipped, nil
+	}
+	return upsertResult(existing, account, sameAccount), nil
+}
+
+// DeleteAccount deletes an account by ID
+func (s *SQLiteStore) DeleteAccount(ctx context.Context, id string) error {
+	err := s.deleteByID(ctx, "DELETE FROM accounts WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to delete account %s: %w", id, err)
+	}
+	return err
+}
+
+// Investment operations
+
+// queryInvestments runs an investment SELECT and scans every row
+func (s *SQLiteStore) queryInvestments(ctx context.Context, query string, args ...interface{}) ([]*models.Investment, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	investments := make([]*models.Investment, 0)
+	for rows.Next() {
+		inv, err := scanInvestment(rows)
+		if err != nil {
+			return nil, err
+		}
+		investments = append(investments, inv)
+	}
+	return investments, rows.Err()
+}
+
+// GetAllInvestments returns a page of investments along with the total number of investments
+func (s *SQLiteStore) GetAllInvestments(ctx context.Context, opts ListOptions, order SortOption, filter InvestmentFilter) ([]*models.Investment, int, error) {
+	orderBy, err := investmentSortFields.orderBy(order, " ORDER BY created_at DESC, id")
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.count(ctx, "SELECT COUNT(*) FROM investments WHERE user_id = $1"+filter.sqlWhere(), s.userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count investments: %w", err)
+	}
+
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1"+filter.sqlWhere()+orderBy+page,
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get all investments: %w", err)
+	}
+	return investments, total, nil
+}
+
+// CountInvestments returns the number of active investments, on platform if it is not empty
+func (s *SQLiteStore) CountInvestments(ctx context.Context, platform models.Platform) (int, error) {
+	query := "SELECT COUNT(*) FROM investments WHERE user_id = $1 AND active"
+	args := []interface{}{s.userID}
+	if platform != "" {
+		query += " AND platform = $2"
+		args = append(args, platform)
+	}
+	count, err := s.count(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count investments: %w", err)
+	}
+	return count, nil
+}
+
+// GetInvestmentsByAccount returns investments for a specific account
+func (s *SQLiteStore) GetInvestmentsByAccount(ctx context.Context, accountID string, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND account_id = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by account %s: %w", accountID, err)
+	}
+	return investments, nil
+}
+
+// GetInvestmentsByPlatform returns investments for a specific platform
+func (s *SQLiteStore) GetInvestmentsByPlatform(ctx context.Context, platform models.Platform, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND platform = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, platform)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by platform %s: %w", platform, err)
+	}
+	return investments, nil
+}
+
+// GetInvestmentsBySymbol returns investments in a symbol across all accounts and platforms
+func (s *SQLiteStore) GetInvestmentsBySymbol(ctx context.Context, symbol string, filter InvestmentFilter) ([]*models.Investment, error) {
+	investments, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND UPPER(symbol) = $2"+filter.sqlWhere()+" ORDER BY created_at DESC",
+		s.userID, strings.ToUpper(symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investments by symbol %s: %w", symbol, err)
+	}
+	return investments, nil
+}
+
+// CreateOrUpdateInvestment creates or updates an investment.
+// Cost basis fields are computed rather than synced, so a nil value never overwrites a stored one.
+func (s *SQLiteStore) CreateOrUpdateInvestment(ctx context.Context, investment *models.Investment) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	if _, err := s.exec(ctx, investmentUpsertSQL, investmentUpsertArgs(investment, s.userID)...); err != nil {
+		return fmt.Errorf("failed to create/update investment %s: %w", investment.ID, err)
+	}
+	return nil
+}
+
+// CreateOrUpdateInvestments upserts investments in one transaction using a single prepared
+// statement. It counts how many were created, updated, left unchanged or skipped.
+func (s *SQLiteStore) CreateOrUpdateInvestments(ctx context.Context, investments []*models.Investment) (UpsertCounts, error) {
+	var counts UpsertCounts
+	if len(investments) == 0 {
+		return counts, nil
+	}
+
+	ids := make([]string, len(investments))
+	for i, investment := range investments {
+		ids[i] = investment.ID
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return counts, err
+	}
+	existing, err := s.queryInvestments(ctx,
+		"SELECT "+investmentColumns+" FROM investments WHERE user_id = $1 AND id IN (SELECT value FROM json_each($2))",
+		s.userID, string(idsJSON))
+	if err != nil {
+		return counts, fmt.Errorf("failed to load existing investments: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin investment batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, rebind(investmentUpsertSQL))
+	if err != nil {
+		return counts, fmt.Errorf("failed to prepare investment upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	written := make([]bool, len(investments))
+	for i, investment := range investments {
+		result, err := stmt.ExecContext(ctx, investmentUpsertArgs(investment, s.userID)...)
+		if err != nil {
+			return counts, fmt.Errorf("failed to create/update investment %s: %w", investment.ID, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return counts, err
+		}
+		written[i] = affected > 0
+	}
+
+	if err := tx.Commit(); err != nil {
+		return counts, fmt.Errorf("failed to commit investment batch: %w", err)
+	}
+	return countInvestmentUpserts(existing, investments, written), nil
+}
+
+// DeactivateMissingInvestments marks active investments on platform in accountIDs that are
+// not in keepIDs as inactive
+func (s *SQLiteStore) DeactivateMissingInvestments(ctx context.Context, platform models.Platform, accountIDs, keepIDs []string, at time.Time) (int, error) {
+	if len(accountIDs) == 0 {
+		return 0, nil
+	}
+	if keepIDs == nil {
+		// json.Marshal encodes a nil slice as null, which would match nothing rather than everything
+		keepIDs = []string{}
+	}
+	accountIDsJSON, err := json.Marshal(accountIDs)
+	if err != nil {
+		return 0, err
+	}
+	keepIDsJSON, err := json.Marshal(keepIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	// SQLite has no array parameters, so the ID lists are passed as JSON arrays
+	result, err := s.exec(ctx,
+		`UPDATE investments SET active = FALSE, deactivated_at = $5, updated_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND platform = $2 AND active
+		 AND account_id IN (SELECT value FROM json_each($3)) AND id NOT IN (SELECT value FROM json_each($4))`,
+		s.userID, platform, string(accountIDsJSON), string(keepIDsJSON), at.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate missing %s investments: %w", platform, err)
+	}
+	deactivated, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deactivated), nil
+}
+
+// DeactivateInvestments marks the active investments with the given IDs as inactive
+func (s *SQLiteStore) DeactivateInvestments(ctx context.Context, ids []string, at time.Time) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx,
+		`UPDATE investments SET active = FALSE, deactivated_at = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND active AND id IN (SELECT value FROM json_each($2))`,
+		s.userID, string(idsJSON), at.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate investments: %w", err)
+	}
+	deactivated, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(deactivated), nil
+}
+
+// UpdateInvestmentPrice reprices an active investment at its stored quantity
+func (s *SQLiteStore) UpdateInvestmentPrice(ctx context.Context, id string, price float64, at time.Time) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, investmentPriceSQL, price, at.UTC(), id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to update the price of investment %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteInvestment deletes an investment by ID
+func (s *SQLiteStore) DeleteInvestment(ctx context.Context, id string) error {
+	err := s.deleteByID(ctx, "DELETE FROM investments WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to delete investment %s: %w", id, err)
+	}
+	return err
+}
+
+// NetWorth operations
+
+// GetNetWorth returns the current net worth (calculated on the fly)
+func (s *SQLiteStore) GetNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	return s.RecalculateNetWorth(ctx)
+}
+
+// UpdateNetWorth is a no-op; net worth is always recalculated from investments
+func (s *SQLiteStore) UpdateNetWorth(ctx context.Context, networth *models.NetWorth) error {
+	return nil
+}
+
+// RecalculateNetWorth recalculates net worth from current accounts and investments
+func (s *SQLiteStore) RecalculateNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	networth := &models.NetWorth{
+		ByPlatform:     make(map[models.Platform]float64),
+		ByAssetType:    make(map[models.AssetType]float64),
+		ByTaxTreatment: make(map[models.TaxTreatment]float64),
+		Currency:       currency.Reporting,
+		LastCalculated: models.Now(),
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, networthQuery, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate net worth: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var platform models.Platform
+		var assetType, taxTreatment, code sql.NullString
+		var value float64
+		var priceChange sql.NullFloat64
+
+		if err := rows.Scan(&platform, &assetType, &taxTreatment, &code, &value, &priceChange); err != nil {
+			return nil, fmt.Errorf("failed to scan net worth row: %w", err)
+		}
+		networth.AddHolding(platform, assetType.String, models.TaxTreatment(taxTreatment.String), code.String, value)
+		if priceChange.Valid {
+			networth.AddPriceChange(code.String, priceChange.Float64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to calculate net worth: %w", err)
+	}
+
+	count, err := s.count(ctx, "SELECT COUNT(*) FROM portfolios WHERE user_id = $1", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio count: %w", err)
+	}
+	networth.AccountCount = count
+
+	return networth, nil
+}
+
+// Net worth history operations
+
+// SaveNetWorthSnapshot records a calculated net worth in the history
+func (s *SQLiteStore) SaveNetWorthSnapshot(ctx context.Context, networth *models.NetWorth) error {
+	snapshot := models.NewNetWorthSnapshot(networth)
+	byPlatform, byAssetType, byTaxTreatment, err := snapshotBreakdowns(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode net worth snapshot: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err = s.exec(ctx, networthSnapshotUpsertSQL,
+		s.userID, snapshot.Timestamp, snapshot.TotalValue, snapshot.Currency,
+		string(byPlatform), string(byAssetType), string(byTaxTreatment), snapshot.AccountCount)
+	if err != nil {
+		return fmt.Errorf("failed to save net worth snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetNetWorthSnapshots returns snapshots taken in [from, to), oldest first
+func (s *SQLiteStore) GetNetWorthSnapshots(ctx context.Context, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	query, args := snapshotRangeQuery(s.userID, from, to)
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get net worth snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*models.NetWorthSnapshot, 0)
+	for rows.Next() {
+		snapshot, err := scanNetWorthSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan net worth snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// Investment history operations
+
+// SaveInvestmentHistory records points in the per-symbol history as one batch
+func (s *SQLiteStore) SaveInvestmentHistory(ctx context.Context, points []*models.InvestmentHistoryPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin investment history batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, rebind(investmentHistoryUpsertSQL))
+	if err != nil {
+		return fmt.Errorf("failed to prepare investment history upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, point := range points {
+		if _, err := stmt.ExecContext(ctx, investmentHistoryUpsertArgs(point, s.userID)...); err != nil {
+			return fmt.Errorf("failed to save investment history for %s: %w", point.Symbol, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit investment history batch: %w", err)
+	}
+	return nil
+}
+
+// GetInvestmentHistory returns the points for symbol recorded in [from, to), oldest first
+func (s *SQLiteStore) GetInvestmentHistory(ctx context.Context, symbol string, from, to time.Time) ([]*models.InvestmentHistoryPoint, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	query, args := investmentHistoryRangeQuery(s.userID, symbol, from, to)
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get investment history: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]*models.InvestmentHistoryPoint, 0)
+	for rows.Next() {
+		point, err := scanInvestmentHistoryPoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan investment history: %w", err)
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// Transaction operations
+
+// CreateOrUpdateTransaction creates or updates a transaction
+func (s *SQLiteStore) CreateOrUpdateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if !transaction.Type.IsValid() {
+		return fmt.Errorf("invalid transaction type %q", transaction.Type)
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	if _, err := s.exec(ctx, transactionUpsertSQL, transactionUpsertArgs(transaction, s.userID)...); err != nil {
+		return fmt.Errorf("failed to create/update transaction %s: %w", transaction.ID, err)
+	}
+	return nil
+}
+
+// GetTransactionsByAccount returns every transaction for an account, newest first
+func (s *SQLiteStore) GetTransactionsByAccount(ctx context.Context, accountID string) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{AccountID: accountID})
+	return transactions, err
+}
+
+// GetTransactionsByPlatform returns every transaction for a platform, newest first
+func (s *SQLiteStore) GetTransactionsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{Platform: platform})
+	return transactions, err
+}
+
+// GetTransactionsBetween returns transactions in [from, to), newest first
+func (s *SQLiteStore) GetTransactionsBetween(ctx context.Context, from, to time.Time) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{From: from, To: to})
+	return transactions, err
+}
+
+// ListTransactions returns transactions matching the filter, newest first, along with the
+// total number of matches before pagination
+func (s *SQLiteStore) ListTransactions(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, int, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	addCondition := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+	addCondition("user_id = $%d", s.userID)
+	if filter.Platform != "" {
+		addCondition("platform = $%d", filter.Platform)
+	}
+	if filter.AccountID != "" {
+		addCondition("account_id = $%d", filter.AccountID)
+	}
+	if filter.Symbol != "" {
+		addCondition("UPPER(symbol) = $%d", strings.ToUpper(filter.Symbol))
+	}
+	if filter.Type != "" {
+		addCondition("type = $%d", filter.Type)
+	}
+	if !filter.From.IsZero() {
+		addCondition("timestamp >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("timestamp < $%d", filter.To.UTC())
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	total, err := s.count(ctx, "SELECT COUNT(*) FROM transactions"+where, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := filter.sqlClause(args)
+	rows, err := s.query(ctx,
+		"SELECT id, account_id, platform, type, symbol, quantity, amount, currency, fee, timestamp, description FROM transactions"+
+			where+" ORDER BY timestamp DESC, id"+page,
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := make([]*models.Transaction, 0)
+	for rows.Next() {
+		var tx models.Transaction
+		var symbol, description sql.NullString
+		var quantity, fee sql.NullFloat64
+		var timestamp sql.NullTime
+
+		err := rows.Scan(&tx.ID, &tx.AccountID, &tx.Platform, &tx.Type, &symbol, &quantity, &tx.Amount, &tx.Currency, &fee, &timestamp, &description)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transaction row: %w", err)
+		}
+
+		tx.Symbol = symbol.String
+		tx.Quantity = quantity.Float64
+		tx.Fee = fee.Float64
+		tx.Description = description.String
+		tx.Timestamp = parseTimestamp(timestamp)
+
+		transactions = append(transactions, &tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	return transactions, total, nil
+}
+
+// GetTransactionSummary totals a calendar year of transactions by type per currency
+func (s *SQLiteStore) GetTransactionSummary(ctx context.Context, year int) (*models.TransactionSummary, error) {
+	summary := &models.TransactionSummary{
+		Year:       year,
+		ByCurrency: make(map[string]*models.TransactionTotals),
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := s.query(ctx,
+		`SELECT currency, type, COALESCE(SUM(amount), 0), COALESCE(SUM(fee), 0)
+		 FROM transactions
+		 WHERE user_id = $1 AND timestamp >= $2 AND timestamp < $3
+		 GROUP BY currency, type`,
+		s.userID, start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions for %d: %w", year, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+		var txType models.TransactionType
+		var amount, fee float64
+		if err := rows.Scan(&currency, &txType, &amount, &fee); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction summary row: %w", err)
+		}
+		totals, exists := summary.ByCurrency[currency]
+		if !exists {
+			totals = &models.TransactionTotals{}
+			summary.ByCurrency[currency] = totals
+		}
+		totals.Add(txType, amount, fee)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarize transactions for %d: %w", year, err)
+	}
+
+	return summary, nil
+}
+
+// Sync metadata operations
+
+// GetLastSyncTime returns when platform last synced
+func (s *SQLiteStore) GetLastSyncTime(ctx context.Context, platform models.Platform) (time.Time, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var lastSync sql.NullTime
+	err := s.queryRow(ctx,
+		"SELECT last_sync_time FROM sync_metadata WHERE user_id = $1 AND platform = $2 ORDER BY updated_at DESC LIMIT 1",
+		s.userID, platform).Scan(&lastSync)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to get last sync time: %w", err)
+	}
+	return parseTimestamp(lastSync), nil
+}
+
+// syncRecordColumns is the column list scanned by scanSyncRecord
+const syncRecordColumns = "platform, sync_status, error_message, portfolios_synced, accounts_synced, investments_synced, " +
+	"last_attempt_time, last_sync_time"
+
+// scanSyncRecord scans a row selected with syncRecordColumns
+func scanSyncRecord(row rowScanner) (*models.SyncRecord, error) {
+	var r models.SyncRecord
+	var status, errMsg sql.NullString
+	var lastAttempt, lastSync sql.NullTime
+	if err := row.Scan(&r.Platform, &status, &errMsg, &r.Counts.Portfolios, &r.Counts.Accounts, &r.Counts.Investments,
+		&lastAttempt, &lastSync); err != nil {
+		return nil, err
+	}
+	r.Status = models.SyncStatus(status.String)
+	r.Error = errMsg.String
+	r.LastSync = parseTimestampPtr(lastSync)
+	r.LastAttempt = parseTimestampPtr(lastAttempt)
+	if !lastAttempt.Valid {
+		// Rows written before attempts were recorded only hold the last successful sync
+		r.LastAttempt = parseTimestampPtr(lastSync)
+	}
+	return &r, nil
+}
+
+// GetSyncRecord returns the latest sync attempt on platform
+func (s *SQLiteStore) GetSyncRecord(ctx context.Context, platform models.Platform) (*models.SyncRecord, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	record, err := scanSyncRecord(s.queryRow(ctx,
+		"SELECT "+syncRecordColumns+" FROM sync_metadata WHERE user_id = $1 AND platform = $2",
+		s.userID, platform))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync record for %s: %w", platform, err)
+	}
+	return record, nil
+}
+
+// RecordSyncResult records a sync attempt on platform
+func (s *SQLiteStore) RecordSyncResult(ctx context.Context, platform models.Platform, status models.SyncStatus, errMsg string, counts models.SyncCounts, at time.Time) error {
+	// A failed attempt passes no sync time, so the last successful one is kept
+	var lastSync interface{}
+	if status == models.SyncStatusSuccess {
+		lastSync = at.UTC()
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO sync_metadata (id, user_id, platform, last_sync_time, last_attempt_time, sync_status, error_message,
+		   portfolios_synced, accounts_synced, investments_synced, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		 ON CONFLICT (user_id, platform) DO UPDATE SET
+		 last_sync_time = COALESCE(EXCLUDED.last_sync_time, sync_metadata.last_sync_time),
+		 last_attempt_time = EXCLUDED.last_attempt_time,
+		 sync_status = EXCLUDED.sync_status,
+		 error_message = EXCLUDED.error_message,
+		 portfolios_synced = EXCLUDED.portfolios_synced,
+		 accounts_synced = EXCLUDED.accounts_synced,
+		 investments_synced = EXCLUDED.investments_synced,
+		 updated_at = CURRENT_TIMESTAMP`,
+		fmt.Sprintf("sync-%s-%s", s.userID, platform), s.userID, platform, lastSync, at.UTC(), status, errMsg,
+		counts.Portfolios, counts.Accounts, counts.Investments)
+	if err != nil {
+		return fmt.Errorf("failed to record sync result: %w", err)
+	}
+	return nil
+}
+
+// Plaid item operations
+
+// GetPlaidItems returns the user's Plaid items, oldest first
+func (s *SQLiteStore) GetPlaidItems(ctx context.Context) ([]*models.PlaidItem, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx,
+		"SELECT "+plaidItemColumns+" FROM plaid_items WHERE user_id = $1 ORDER BY created_at, id", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plaid items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.PlaidItem, 0)
+	for rows.Next() {
+		item, err := scanPlaidItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan plaid item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetPlaidItem returns a Plaid item by ID
+func (s *SQLiteStore) GetPlaidItem(ctx context.Context, id string) (*models.PlaidItem, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	item, err := scanPlaidItem(s.queryRow(ctx,
+		"SELECT "+plaidItemColumns+" FROM plaid_items WHERE id = $1 AND user_id = $2", id, s.userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plaid item %s: %w", id, err)
+	}
+	return item, nil
+}
+
+// CreateOrUpdatePlaidItem creates or updates a Plaid item
+func (s *SQLiteStore) CreateOrUpdatePlaidItem(ctx context.Context, item *models.PlaidItem) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, plaidItemUpsertSQL,
+		item.ID, item.InstitutionID, item.InstitutionName, item.Platform, item.EncryptedAccessToken, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to create/update plaid item %s: %w", item.ID, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return fmt.Errorf("plaid item %s is linked by another user", item.ID)
+	}
+	return nil
+}
+
+// SetPlaidItemAccounts records the accounts a Plaid item's sync stored
+func (s *SQLiteStore) SetPlaidItemAccounts(ctx context.Context, id string, accountIDs []string) error {
+	encoded, err := plaidItemAccountsJSON(accountIDs)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, plaidItemAccountsSQL, encoded, id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to set the accounts of plaid item %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetPlaidItemStatus records whether a Plaid item can be synced, and when it last synced
+func (s *SQLiteStore) SetPlaidItemStatus(ctx context.Context, id string, status models.PlaidItemStatus, syncedAt *time.Time) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, plaidItemStatusSQL, status, plaidItemSyncedAt(syncedAt), id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to set the status of plaid item %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePlaidItem deletes a Plaid item, and with it the stored access token
+func (s *SQLiteStore) DeletePlaidItem(ctx context.Context, id string) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, "DELETE FROM plaid_items WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete plaid item %s: %w", id, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetPlaidItemUserID returns the user who linked a Plaid item, across users
+func (s *SQLiteStore) GetPlaidItemUserID(ctx context.Context, id string) (string, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	var userID string
+	err := s.queryRow(ctx, "SELECT user_id FROM plaid_items WHERE id = $1", id).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the user of plaid item %s: %w", id, err)
+	}
+	return userID, nil
+}
+
+// YouTube Source operations
+
+// youtubeSourceColumns is the column list scanned by scanYouTubeSource
+const youtubeSourceColumns = "id, type, url, name, channel_id, playlist_id, enabled, schedule, title_includes, title_excludes, record_skipped, last_processed"
+
+// enabledYouTubeSourcesQuery selects the enabled sources of user $1, newest first
+const enabledYouTubeSourcesQuery = "SELECT " + youtubeSourceColumns + " FROM youtube_sources WHERE user_id = $1 AND enabled = TRUE ORDER BY created_at DESC"
+
+// youtubeSourcesByTypeQuery selects the sources of user $1 of type $2, newest first
+const youtubeSourcesByTypeQuery = "SELECT " + youtubeSourceColumns + " FROM youtube_sources WHERE user_id = $1 AND type = $2 ORDER BY created_at DESC"
+
+// scanYouTubeSource scans a row selected with youtubeSourceColumns
+func scanYouTubeSource(row rowScanner) (*models.YouTubeSource, error) {
+	var src models.YouTubeSource
+	var channelID, playlistID, schedule sql.NullString
+	var titleIncludes, titleExcludes []byte
+	var lastProcessed sql.NullTime
+
+	err := row.Scan(&src.ID, &src.Type, &src.URL, &src.Name, &channelID, &playlistID, &src.Enabled, &schedule,
+		&titleIncludes, &titleExcludes, &src.RecordSkipped, &lastProcessed)
+	if err != nil {
+		return nil, err
+	}
+	src.ChannelID = channelID.String
+	src.PlaylistID = playlistID.String
+	src.Schedule = schedule.String
+	if err := json.Unmarshal(titleIncludes, &src.TitleIncludes); err != nil {
+		return nil, fmt.Errorf("invalid title includes of YouTube source %s: %w", src.ID, err)
+	}
+	if err := json.Unmarshal(titleExcludes, &src.TitleExcludes); err != nil {
+		return nil, fmt.Errorf("invalid title excludes of YouTube source %s: %w", src.ID, err)
+	}
+	src.LastProcessed = parseTimestamp(lastProcessed)
+	return &src, nil
+}
+
+// youtubeSourceUpsertSQL inserts or updates a YouTube source, leaving one another user owns
+// untouched; see youtubeSourceArgs
+const youtubeSourceUpsertSQL = `INSERT INTO youtube_sources (id, type, url, name, channel_id, playlist_id, enabled, schedule, title_includes, title_excludes, record_skipped, last_processed, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE($14, CURRENT_TIMESTAMP), CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 type = EXCLUDED.type,
+		 url = EXCLUDED.url,
+		 name = EXCLUDED.name,
+		 channel_id = EXCLUDED.channel_id,
+		 playlist_id = EXCLUDED.playlist_id,
+		 enabled = EXCLUDED.enabled,
+		 schedule = EXCLUDED.schedule,
+		 title_includes = EXCLUDED.title_includes,
+		 title_excludes = EXCLUDED.title_excludes,
+		 record_skipped = EXCLUDED.record_skipped,
+		 last_processed = EXCLUDED.last_processed,
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE youtube_sources.user_id = EXCLUDED.user_id`
+
+// youtubeSourceArgs returns the arguments of youtubeSourceUpsertSQL for a source of userID,
+// with its title filters encoded as JSON arrays
+func youtubeSourceArgs(source *models.YouTubeSource, userID string) ([]interface{}, error) {
+	titleIncludes, err := stringListJSON(source.TitleIncludes)
+	if err != nil {
+		return nil, err
+	}
+	titleExcludes, err := stringListJSON(source.TitleExcludes)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		source.ID, source.Type, source.URL, source.Name, source.ChannelID, source.PlaylistID, source.Enabled, source.Schedule,
+		titleIncludes, titleExcludes, source.RecordSkipped, nullableTime(source.LastProcessed), userID, nullableTime(source.CreatedAt),
+	}, nil
+}
+
+// queryYouTubeSources runs a YouTube source SELECT and scans every row
+func (s *SQLiteStore) queryYouTubeSources(ctx context.Context, query string, args ...interface{}) ([]*models.YouTubeSource, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := make([]*models.YouTubeSource, 0)
+	for rows.Next() {
+		src, err := scanYouTubeSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan YouTube source row: %w", err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// GetAllYouTubeSources returns all YouTube sources
+func (s *SQLiteStore) GetAllYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	sources, err := s.queryYouTubeSources(ctx,
+		"SELECT "+youtubeSourceColumns+" FROM youtube_sources WHERE user_id = $1 ORDER BY created_at DESC", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all YouTube sources: %w", err)
+	}
+	return sources, nil
+}
+
+// GetEnabledYouTubeSources returns the sources that are enabled, newest first
+func (s *SQLiteStore) GetEnabledYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	sources, err := s.queryYouTubeSources(ctx, enabledYouTubeSourcesQuery, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enabled YouTube sources: %w", err)
+	}
+	return sources, nil
+}
+
+// GetYouTubeSourcesByType returns the sources of a type, newest first
+func (s *SQLiteStore) GetYouTubeSourcesByType(ctx context.Context, sourceType models.YouTubeSourceType) ([]*models.YouTubeSource, error) {
+	sources, err := s.queryYouTubeSources(ctx, youtubeSourcesByTypeQuery, s.userID, sourceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s YouTube sources: %w", sourceType, err)
+	}
+	return sources, nil
+}
+
+// GetYouTubeSourceByID returns a YouTube source by ID
+func (s *SQLiteStore) GetYouTubeSourceByID(ctx context.Context, id string) (*models.YouTubeSource, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	src, err := scanYouTubeSource(s.queryRow(ctx,
+		"SELECT "+youtubeSourceColumns+" FROM youtube_sources WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get YouTube source %s: %w", id, err)
+	}
+	return src, nil
+}
+
+// CreateOrUpdateYouTubeSource creates or updates a YouTube source
+func (s *SQLiteStore) CreateOrUpdateYouTubeSource(ctx context.Context, source *models.YouTubeSource) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	args, err := youtubeSourceArgs(source, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to encode YouTube source %s: %w", source.ID, err)
+	}
+	_, err = s.exec(ctx, youtubeSourceUpsertSQL, args...)
+	if err != nil {
+		return fmt.Errorf("failed to create/update YouTube source %s: %w", source.ID, err)
+	}
+	return nil
+}
+
+// DeleteYouTubeSource deletes a YouTube source by ID
+func (s *SQLiteStore) DeleteYouTubeSource(ctx context.Context, id string) error {
+	err := s.deleteByID(ctx, "DELETE FROM youtube_sources WHERE id = $1 AND user_id = $2", id, s.userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to delete YouTube source %s: %w", id, err)
+	}
+	return err
+}
+
+// YouTube quota operations
+
+// youtubeQuotaAddSQL adds to a day's usage, starting it if it is the day's first, and returns
+// the new total
+const youtubeQuotaAddSQL = `INSERT INTO youtube_quota_usage (day, units, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (day) DO UPDATE SET units = youtube_quota_usage.units + EXCLUDED.units, updated_at = CURRENT_TIMESTAMP
+		RETURNING units`
+
+// GetYouTubeQuotaUsage returns the units used on day, 0 if none were recorded
+func (s *SQLiteStore) GetYouTubeQuotaUsage(ctx context.Context, day string) (int, error) {
+	var units int
+	err := s.queryRow(ctx, "SELECT units FROM youtube_quota_usage WHERE day = $1", day).Scan(&units)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get YouTube quota usage of %s: %w", day, err)
+	}
+	return units, nil
+}
+
+// AddYouTubeQuotaUsage adds units to the usage of day and returns the new total
+func (s *SQLiteStore) AddYouTubeQuotaUsage(ctx context.Context, day string, units int) (int, error) {
+	var total int
+	if err := s.queryRow(ctx, youtubeQuotaAddSQL, day, units).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to add YouTube quota usage of %s: %w", day, err)
+	}
+	return total, nil
+}
+
+// Video Transcript operations
+
+// transcriptColumns is the column list scanned by scanTranscript
+const transcriptColumns = "id, video_id, video_title, video_url, text, duration, source_id, created_at"
+
+// scanTranscript scans a row selected with transcriptColumns
+func scanTranscript(row rowScanner) (*models.VideoTranscript, error) {
+	var t models.VideoTranscript
+	var videoTitle, sourceID sql.NullString
+	var duration sql.NullInt64
+	var createdAt sql.NullTime
+
+	if err := row.Scan(&t.ID, &t.VideoID, &videoTitle, &t.VideoURL, &t.Text, &duration, &sourceID, &createdAt); err != nil {
+		return nil, err
+	}
+	t.VideoTitle = videoTitle.String
+	t.Duration = parseIntPtr(duration)
+	t.SourceID = sourceID.String
+	t.CreatedAt = parseTimestamp(createdAt)
+	return &t, nil
+}
+
+// CreateOrUpdateTranscript creates or updates a video transcript. A transcript another user
+// owns is left untouched.
+func (s *SQLiteStore) CreateOrUpdateTranscript(ctx context.Context, transcript *models.VideoTranscript) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO video_transcripts (id, video_id, video_title, video_url, text, duration, source_id, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, CURRENT_TIMESTAMP))
+		 ON CONFLICT (id) DO UPDATE SET
+		 video_id = EXCLUDED.video_id,
+		 video_title = EXCLUDED.video_title,
+		 video_url = EXCLUDED.video_url,
+		 text = EXCLUDED.text,
+		 duration = EXCLUDED.duration,
+		 source_id = EXCLUDED.source_id
+		 WHERE video_transcripts.user_id = EXCLUDED.user_id`,
+		transcript.ID, transcript.VideoID, transcript.VideoTitle, transcript.VideoURL, transcript.Text, transcript.Duration, transcript.SourceID,
+		s.userID, nullableTime(transcript.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create/update transcript %s: %w", transcript.ID, err)
+	}
+	return nil
+}
+
+// GetTranscriptByID returns a transcript by ID
+func (s *SQLiteStore) GetTranscriptByID(ctx context.Context, id string) (*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	t, err := scanTranscript(s.queryRow(ctx,
+		"SELECT "+transcriptColumns+" FROM video_transcripts WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get transcript %s: %w", id, err)
+	}
+	return t, nil
+}
+
+// latestTranscriptByVideoIDQuery selects the newest transcript of video $1 stored for user $2
+const latestTranscriptByVideoIDQuery = "SELECT " + transcriptColumns + " FROM video_transcripts WHERE video_id = $1 AND user_id = $2 ORDER BY created_at DESC, id LIMIT 1"
+
+// GetLatestTranscriptByVideoID returns the newest transcript stored for a video
+func (s *SQLiteStore) GetLatestTranscriptByVideoID(ctx context.Context, videoID string) (*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	t, err := scanTranscript(s.queryRow(ctx, latestTranscriptByVideoIDQuery, videoID, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest transcript of video %s: %w", videoID, err)
+	}
+	return t, nil
+}
+
+// GetTranscriptsByVideoID returns transcripts for a specific video ID
+func (s *SQLiteStore) GetTranscriptsByVideoID(ctx context.Context, videoID string) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx,
+		"SELECT "+transcriptColumns+" FROM video_transcripts WHERE video_id = $1 AND user_id = $2 ORDER BY created_at DESC", videoID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcripts by video ID %s: %w", videoID, err)
+	}
+	defer rows.Close()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for rows.Next() {
+		t, err := scanTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+// GetTranscriptsBySourceID returns up to limit transcripts for a source, newest first
+func (s *SQLiteStore) GetTranscriptsBySourceID(ctx context.Context, sourceID string, limit int) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := ListOptions{Limit: limit}.sqlClause([]interface{}{sourceID, s.userID})
+	rows, err := s.query(ctx,
+		"SELECT "+transcriptColumns+" FROM video_transcripts WHERE source_id = $1 AND user_id = $2 ORDER BY created_at DESC, id"+page,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcripts by source ID %s: %w", sourceID, err)
+	}
+	defer rows.Close()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for rows.Next() {
+		t, err := scanTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+// ListTranscripts returns a page of all transcripts ordered by ID
+func (s *SQLiteStore) ListTranscripts(ctx context.Context, opts ListOptions) ([]*models.VideoTranscript, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.query(ctx, "SELECT "+transcriptColumns+" FROM video_transcripts WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.VideoTranscript, 0)
+	for rows.Next() {
+		item, err := scanTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SearchTranscripts returns the transcripts containing every word of query, newest first.
+// Matching ignores case for ASCII letters only.
+func (s *SQLiteStore) SearchTranscripts(ctx context.Context, query string, limit int) ([]*models.TranscriptSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []*models.TranscriptSearchResult{}, nil
+	}
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{s.userID}
+	for _, term := range terms {
+		args = append(args, "%"+likeEscaper.Replace(term)+"%")
+		conditions = append(conditions, fmt.Sprintf(`(video_title LIKE $%d ESCAPE '\' OR text LIKE $%d ESCAPE '\')`, len(args), len(args)))
+	}
+	page, args := ListOptions{Limit: limit}.sqlClause(args)
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx,
+		"SELECT "+transcriptColumns+", "+sourceNameColumn+" FROM video_transcripts WHERE "+
+			strings.Join(conditions, " AND ")+" ORDER BY created_at DESC, id"+page,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*models.TranscriptSearchResult, 0)
+	for rows.Next() {
+		var sourceName sql.NullString
+		t, err := scanTranscript(trailingScanner{rows, []interface{}{&sourceName}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transcript row: %w", err)
+		}
+		results = append(results, newSearchResult(t, sourceName.String, terms))
+	}
+	return results, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term, for patterns using ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// PruneTranscriptsOlderThan clears the text of, or deletes, transcripts created before cutoff
+func (s *SQLiteStore) PruneTranscriptsOlderThan(ctx context.Context, cutoff time.Time, keepMetadata bool) (int, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, pruneTranscriptsSQL(keepMetadata), cutoff.UTC(), s.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune transcripts: %w", err)
+	}
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(pruned), nil
+}
+
+// Market Analysis operations
+
+// marketAnalysisColumns is the column list scanned by scanMarketAnalysis
+const marketAnalysisColumns = "id, transcript_id, conditions, trends, risk_factors, summary, created_at"
+
+// scanMarketAnalysis scans a row selected with marketAnalysisColumns, decoding the JSON columns
+func scanMarketAnalysis(row rowScanner) (*models.MarketAnalysis, error) {
+	var a models.MarketAnalysis
+	var trendsJSON, riskFactorsJSON []byte
+	var summary sql.NullString
+	var createdAt sql.NullTime
+
+	if err := row.Scan(&a.ID, &a.TranscriptID, &a.Conditions, &trendsJSON, &riskFactorsJSON, &summary, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(trendsJSON, &a.Trends); err != nil {
+		log.Printf("Failed to unmarshal trends for analysis %s: %v", a.ID, err)
+		a.Trends = []string{}
+	}
+	if err := json.Unmarshal(riskFactorsJSON, &a.RiskFactors); err != nil {
+		log.Printf("Failed to unmarshal risk factors for analysis %s: %v", a.ID, err)
+		a.RiskFactors = []string{}
+	}
+	a.Summary = summary.String
+	a.CreatedAt = parseTimestamp(createdAt)
+	return &a, nil
+}
+
+// CreateOrUpdateMarketAnalysis creates or updates a market analysis. An analysis another user
+// owns is left untouched.
+func (s *SQLiteStore) CreateOrUpdateMarketAnalysis(ctx context.Context, analysis *models.MarketAnalysis) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO market_analyses (id, transcript_id, conditions, trends, risk_factors, summary, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, CURRENT_TIMESTAMP))
+		 ON CONFLICT (id) DO UPDATE SET
+		 transcript_id = EXCLUDED.transcript_id,
+		 conditions = EXCLUDED.conditions,
+		 trends = EXCLUDED.trends,
+		 risk_factors = EXCLUDED.risk_factors,
+		 summary = EXCLUDED.summary
+		 WHERE market_analyses.user_id = EXCLUDED.user_id`,
+		analysis.ID, analysis.TranscriptID, analysis.Conditions,
+		jsonText(analysis.Trends, "trends", analysis.ID), jsonText(analysis.RiskFactors, "risk factors", analysis.ID), analysis.Summary,
+		s.userID, nullableTime(analysis.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create/update market analysis %s: %w", analysis.ID, err)
+	}
+	return nil
+}
+
+// GetMarketAnalysisByID returns a market analysis by ID
+func (s *SQLiteStore) GetMarketAnalysisByID(ctx context.Context, id string) (*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	a, err := scanMarketAnalysis(s.queryRow(ctx,
+		"SELECT "+marketAnalysisColumns+" FROM market_analyses WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get market analysis %s: %w", id, err)
+	}
+	return a, nil
+}
+
+// GetMarketAnalysesByTranscriptID returns market analyses for a specific transcript ID
+func (s *SQLiteStore) GetMarketAnalysesByTranscriptID(ctx context.Context, transcriptID string) ([]*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx,
+		"SELECT "+marketAnalysisColumns+" FROM market_analyses WHERE transcript_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		transcriptID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market analyses by transcript ID %s: %w", transcriptID, err)
+	}
+	defer rows.Close()
+
+	analyses := make([]*models.MarketAnalysis, 0)
+	for rows.Next() {
+		a, err := scanMarketAnalysis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market analysis row: %w", err)
+		}
+		analyses = append(analyses, a)
+	}
+	return analyses, rows.Err()
+}
+
+// ListMarketAnalyses returns a page of all market analyses ordered by ID
+func (s *SQLiteStore) ListMarketAnalyses(ctx context.Context, opts ListOptions) ([]*models.MarketAnalysis, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.query(ctx, "SELECT "+marketAnalysisColumns+" FROM market_analyses WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list market analyses: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.MarketAnalysis, 0)
+	for rows.Next() {
+		item, err := scanMarketAnalysis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market analysis row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Recommendation operations
+
+// recommendationColumns is the column list scanned by scanRecommendation
+const recommendationColumns = "id, analysis_id, action, confidence, suggested_actions, summary, created_at"
+
+// scanRecommendation scans a row selected with recommendationColumns, decoding suggested actions
+func scanRecommendation(row rowScanner) (*models.Recommendation, error) {
+	var r models.Recommendation
+	var suggestedActionsJSON []byte
+	var summary sql.NullString
+	var createdAt sql.NullTime
+
+	if err := row.Scan(&r.ID, &r.AnalysisID, &r.Action, &r.Confidence, &suggestedActionsJSON, &summary, &createdAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(suggestedActionsJSON, &r.SuggestedActions); err != nil {
+		log.Printf("Failed to unmarshal suggested actions for recommendation %s: %v", r.ID, err)
+		r.SuggestedActions = []models.SuggestedAction{}
+	}
+	r.Summary = summary.String
+	r.CreatedAt = parseTimestamp(createdAt)
+	return &r, nil
+}
+
+// CreateOrUpdateRecommendation creates or updates a recommendation. A recommendation another
+// user owns is left untouched.
+func (s *SQLiteStore) CreateOrUpdateRecommendation(ctx context.Context, recommendation *models.Recommendation) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO recommendations (id, analysis_id, action, confidence, suggested_actions, summary, user_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, CURRENT_TIMESTAMP))
+		 ON CONFLICT (id) DO UPDATE SET
+		 analysis_id = EXCLUDED.analysis_id,
+		 action = EXCLUDED.action,
+		 confidence = EXCLUDED.confidence,
+		 suggested_actions = EXCLUDED.suggested_actions,
+		 summary = EXCLUDED.summary
+		 WHERE recommendations.user_id = EXCLUDED.user_id`,
+		recommendation.ID, recommendation.AnalysisID, recommendation.Action, recommendation.Confidence,
+		jsonText(recommendation.SuggestedActions, "suggested actions", recommendation.ID), recommendation.Summary,
+		s.userID, nullableTime(recommendation.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create/update recommendation %s: %w", recommendation.ID, err)
+	}
+	return nil
+}
+
+// GetRecommendationByID returns a recommendation by ID
+func (s *SQLiteStore) GetRecommendationByID(ctx context.Context, id string) (*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	r, err := scanRecommendation(s.queryRow(ctx,
+		"SELECT "+recommendationColumns+" FROM recommendations WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get recommendation %s: %w", id, err)
+	}
+	return r, nil
+}
+
+// GetRecommendationsByAnalysisID returns recommendations for a specific analysis ID
+func (s *SQLiteStore) GetRecommendationsByAnalysisID(ctx context.Context, analysisID string) ([]*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx,
+		"SELECT "+recommendationColumns+" FROM recommendations WHERE analysis_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		analysisID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendations by analysis ID %s: %w", analysisID, err)
+	}
+	defer rows.Close()
+
+	recommendations := make([]*models.Recommendation, 0)
+	for rows.Next() {
+		r, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation row: %w", err)
+		}
+		recommendations = append(recommendations, r)
+	}
+	return recommendations, rows.Err()
+}
+
+// ListRecommendations returns a page of all recommendations ordered by ID
+func (s *SQLiteStore) ListRecommendations(ctx context.Context, opts ListOptions) ([]*models.Recommendation, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	page, args := opts.sqlClause([]interface{}{s.userID})
+	rows, err := s.query(ctx, "SELECT "+recommendationColumns+" FROM recommendations WHERE user_id = $1 ORDER BY id"+page, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recommendations: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*models.Recommendation, 0)
+	for rows.Next() {
+		item, err := scanRecommendation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Workflow Execution operations
+
+// CreateOrUpdateWorkflowExecution creates or updates a workflow execution. An execution another
+// user owns is left untouched. Unset transcript, analysis and recommendation IDs are stored as
+// NULL to satisfy the foreign keys.
+func (s *SQLiteStore) CreateOrUpdateWorkflowExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err := s.exec(ctx,
+		`INSERT INTO workflow_executions (id, status, video_id, video_url, video_title, source_id, transcript_id, analysis_id, recommendation_id, error, created_at, started_at, completed_at, user_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $13, $11, $12, $14)
+		 ON CONFLICT (id) DO UPDATE SET
+		 status = EXCLUDED.status,
+		 video_id = EXCLUDED.video_id,
+		 video_url = EXCLUDED.video_url,
+		 video_title = EXCLUDED.video_title,
+		 source_id = EXCLUDED.source_id,
+		 transcript_id = EXCLUDED.transcript_id,
+		 analysis_id = EXCLUDED.analysis_id,
+		 recommendation_id = EXCLUDED.recommendation_id,
+		 error = EXCLUDED.error,
+		 started_at = EXCLUDED.started_at,
+		 completed_at = EXCLUDED.completed_at,
+		 claimed_video_id = CASE WHEN EXCLUDED.status = 'failed' THEN NULL ELSE workflow_executions.claimed_video_id END
+		 WHERE workflow_executions.user_id = EXCLUDED.user_id`,
+		execution.ID, execution.Status, execution.VideoID, execution.VideoURL, execution.VideoTitle,
+		execution.SourceID, execution.TranscriptID, execution.AnalysisID, execution.RecommendationID,
+		execution.Error, nullableTime(execution.StartedAt), nullableTime(execution.CompletedAt), executionCreatedAt(execution),
+		s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to create/update workflow execution %s: %w", execution.ID, err)
+	}
+	return nil
+}
+
+// TryClaimVideoExecution saves execution as the one processing its video unless another
+// execution of the user holds the video
+func (s *SQLiteStore) TryClaimVideoExecution(ctx context.Context, execution *models.WorkflowExecution) (string, bool, error) {
+	if execution.VideoID == "" {
+		return "", false, fmt.Errorf("execution %s has no video ID to claim", execution.ID)
+	}
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		var holderID string
+		err := s.queryRow(ctx, videoClaimHolderQuery, execution.VideoID, s.userID).Scan(&holderID)
+		if err == nil {
+			return holderID, false, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", false, fmt.Errorf("failed to look up claim on video %s: %w", execution.VideoID, err)
+		}
+
+		result, err := s.exec(ctx, claimVideoSQL, claimVideoArgs(execution, s.userID)...)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to claim video %s: %w", execution.VideoID, err)
+		}
+		claimed, err := result.RowsAffected()
+		if err != nil {
+			return "", false, err
+		}
+		if claimed > 0 {
+			return execution.ID, true, nil
+		}
+	}
+	return "", false, fmt.Errorf("failed to claim video %s: its claim kept changing", execution.VideoID)
+}
+
+// queryWorkflowExecutions runs a workflow execution SELECT and scans every row
+func (s *SQLiteStore) queryWorkflowExecutions(ctx context.Context, query string, args ...interface{}) ([]*models.WorkflowExecution, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for rows.Next() {
+		e, err := scanWorkflowExecution(rows)
+		if err != nil {
+			return nil, err
+		}
+		executions = append(executions, e)
+	}
+	return executions, rows.Err()
+}
+
+// GetWorkflowExecutionByID returns a workflow execution by ID
+func (s *SQLiteStore) GetWorkflowExecutionByID(ctx context.Context, id string) (*models.WorkflowExecution, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	e, err := scanWorkflowExecution(s.queryRow(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE id = $1 AND user_id = $2", id, s.userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get workflow execution %s: %w", id, err)
+	}
+	return e, nil
+}
+
+// GetAllWorkflowExecutions returns all workflow executions
+func (s *SQLiteStore) GetAllWorkflowExecutions(ctx context.Context) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE user_id = $1 ORDER BY created_at DESC", s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all workflow executions: %w", err)
+	}
+	return executions, nil
+}
+
+// ListWorkflowExecutions returns workflow executions matching the filter, newest first unless
+// the filter sorts them otherwise
+func (s *SQLiteStore) ListWorkflowExecutions(ctx context.Context, filter ExecutionFilter) ([]*models.WorkflowExecution, error) {
+	orderBy, err := executionSortFields.orderBy(filter.Sort, " ORDER BY created_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	where, args := filter.sqlWhere(s.userID)
+	query := "SELECT " + workflowExecutionColumns + " FROM workflow_executions" + where + orderBy
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	executions, err := s.queryWorkflowExecutions(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow executions: %w", err)
+	}
+	return executions, nil
+}
+
+// PageWorkflowExecutions returns a page of executions matching filter, newest first, after cursor
+func (s *SQLiteStore) PageWorkflowExecutions(ctx context.Context, filter ExecutionFilter, cursor string) ([]*models.WorkflowExecution, string, error) {
+	where, args, err := filter.sqlKeysetWhere(cursor, s.userID)
+	if err != nil {
+		return nil, "", err
+	}
+	limit, args := filter.sqlKeysetLimit(args)
+
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions"+where+executionKeysetOrder+limit,
+		args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to page workflow executions: %w", err)
+	}
+	executions, next := filter.nextPage(executions)
+	return executions, next, nil
+}
+
+// CountWorkflowExecutions returns the number of executions matching filter, ignoring its Limit
+func (s *SQLiteStore) CountWorkflowExecutions(ctx context.Context, filter ExecutionFilter) (int, error) {
+	where, args := filter.sqlWhere(s.userID)
+	count, err := s.count(ctx, "SELECT COUNT(*) FROM workflow_executions"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count workflow executions: %w", err)
+	}
+	return count, nil
+}
+
+// GetCompletedWorkflowExecutionsSince returns completed executions with a recommendation that
+// completed after since, newest first
+func (s *SQLiteStore) GetCompletedWorkflowExecutionsSince(ctx context.Context, since time.Time, limit int) ([]*models.WorkflowExecution, error) {
+	query := "SELECT " + workflowExecutionColumns + ` FROM workflow_executions
+		 WHERE user_id = $1 AND status = $2 AND recommendation_id IS NOT NULL AND recommendation_id <> '' AND completed_at IS NOT NULL`
+	args := []interface{}{s.userID, models.WorkflowStatusCompleted}
+	if !since.IsZero() {
+		args = append(args, since.UTC())
+		query += fmt.Sprintf(" AND completed_at > $%d", len(args))
+	}
+	query += " ORDER BY completed_at DESC, id"
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	executions, err := s.queryWorkflowExecutions(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions completed since %s: %w", since.Format(time.RFC3339), err)
+	}
+	return executions, nil
+}
+
+// GetWorkflowExecutionsBySourceID returns workflow executions for a specific source ID
+func (s *SQLiteStore) GetWorkflowExecutionsBySourceID(ctx context.Context, sourceID string) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE source_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		sourceID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions by source ID %s: %w", sourceID, err)
+	}
+	return executions, nil
+}
+
+// GetWorkflowExecutionsByVideoID returns workflow executions for a specific video ID
+func (s *SQLiteStore) GetWorkflowExecutionsByVideoID(ctx context.Context, videoID string) ([]*models.WorkflowExecution, error) {
+	executions, err := s.queryWorkflowExecutions(ctx,
+		"SELECT "+workflowExecutionColumns+" FROM workflow_executions WHERE video_id = $1 AND user_id = $2 ORDER BY created_at DESC",
+		videoID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow executions by video ID %s: %w", videoID, err)
+	}
+	return executions, nil
+}
+
+// DeleteWorkflowExecution deletes an execution in one transaction, optionally with its
+// artifacts, and drops its ID from aggregated recommendations
+func (s *SQLiteStore) DeleteWorkflowExecution(ctx context.Context, id string, cascade bool) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	tx, err := s.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete workflow execution %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	var transcriptID, analysisID, recommendationID sql.NullString
+	err = tx.QueryRowContext(ctx, rebind(deleteWorkflowExecutionSQL), id, s.userID).Scan(&transcriptID, &analysisID, &recommendationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete workflow execution %s: %w", id, err)
+	}
+
+	if cascade {
+		for _, d := range executionArtifactDeletes(transcriptID, analysisID, recommendationID) {
+			if !d.id.Valid {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, rebind(d.query), d.id.String, s.userID); err != nil {
+				return fmt.Errorf("failed to delete artifacts of workflow execution %s: %w", id, err)
+			}
+		}
+	}
+
+	// execution_ids is a JSON array in a TEXT column; rebuild it without this ID
+	if _, err := tx.ExecContext(ctx, rebind(
+		`UPDATE aggregated_recommendations
+		 SET execution_ids = (SELECT json_group_array(value) FROM json_each(aggregated_recommendations.execution_ids) WHERE value <> $1)
+		 WHERE user_id = $2 AND EXISTS (SELECT 1 FROM json_each(aggregated_recommendations.execution_ids) WHERE value = $1)`),
+		id, s.userID); err != nil {
+		return fmt.Errorf("failed to unlink workflow execution %s from aggregated recommendations: %w", id, err)
+	}
+	return tx.Commit()
+}
+
+// prunableWorkflowExecutionsSQL ranks user $3's executions with status $1 within their source,
+// newest first, and selects those past the first $2. Callers append an age bound on started.
+const prunableWorkflowExecutionsSQL = `SELECT id FROM (
+		 SELECT id, COALESCE(started_at, created_at) AS started,
+		   ROW_NUMBER() OVER (PARTITION BY COALESCE(source_id, '') ORDER BY COALESCE(started_at, created_at) DESC, id DESC) AS recency
+		 FROM workflow_executions WHERE status = $1 AND user_id = $3
+		 ) ranked WHERE recency > $2`
+
+// prunableWorkflowExecutionsQuery returns the query selecting userID's executions to prune and
+// its arguments
+func prunableWorkflowExecutionsQuery(status models.WorkflowExecutionStatus, olderThan time.Time, keep int, userID string) (string, []interface{}) {
+	query := prunableWorkflowExecutionsSQL
+	args := []interface{}{status, max(keep, 0), userID}
+	if !olderThan.IsZero() {
+		args = append(args, olderThan.UTC())
+		query += " AND started < $4"
+	}
+	return query, args
+}
+
+// PruneWorkflowExecutions deletes old executions with status, keeping the newest of each source
+func (s *SQLiteStore) PruneWorkflowExecutions(ctx context.Context, status models.WorkflowExecutionStatus, olderThan time.Time, keep int) (int, error) {
+	query, args := prunableWorkflowExecutionsQuery(status, olderThan, keep, s.userID)
+	ids, err := s.selectIDs(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select workflow executions to prune: %w", err)
+	}
+	return deleteWorkflowExecutions(ctx, s, ids)
+}
+
+// selectIDs runs a query selecting a single ID column
+func (s *SQLiteStore) selectIDs(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// addWorkflowExecutionEventSQL appends an event ($2, $3, $4) to execution $1 of user $5,
+// inserting nothing if the user has no such execution
+const addWorkflowExecutionEventSQL = `INSERT INTO workflow_execution_events (execution_id, type, detail, created_at, user_id)
+		 SELECT id, $2, NULLIF($3, ''), $4, user_id FROM workflow_executions WHERE id = $1 AND user_id = $5`
+
+// workflowExecutionEventsQuery selects the events of execution $1 of user $2, oldest first
+const workflowExecutionEventsQuery = `SELECT execution_id, type, detail, created_at FROM workflow_execution_events
+		 WHERE execution_id = $1 AND user_id = $2 ORDER BY id`
+
+// scanWorkflowExecutionEvent scans a row selected by workflowExecutionEventsQuery
+func scanWorkflowExecutionEvent(row rowScanner) (*models.WorkflowExecutionEvent, error) {
+	var e models.WorkflowExecutionEvent
+	var detail sql.NullString
+	var createdAt sql.NullTime
+	if err := row.Scan(&e.ExecutionID, &e.Type, &detail, &createdAt); err != nil {
+		return nil, err
+	}
+	e.Detail = detail.String
+	e.Timestamp = parseTimestamp(createdAt)
+	return &e, nil
+}
+
+// AddWorkflowExecutionEvent appends an event to an execution's event log
+func (s *SQLiteStore) AddWorkflowExecutionEvent(ctx context.Context, event *models.WorkflowExecutionEvent) error {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	result, err := s.exec(ctx, addWorkflowExecutionEventSQL,
+		event.ExecutionID, event.Type, event.Detail, event.Timestamp.UTC(), s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to add %s event to workflow execution %s: %w", event.Type, event.ExecutionID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetWorkflowExecutionEvents returns an execution's events, oldest first
+func (s *SQLiteStore) GetWorkflowExecutionEvents(ctx context.Context, executionID string) ([]*models.WorkflowExecutionEvent, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, workflowExecutionEventsQuery, executionID, s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events of workflow execution %s: %w", executionID, err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.WorkflowExecutionEvent, 0)
+	for rows.Next() {
+		e, err := scanWorkflowExecutionEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workflow execution event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetRecommendationSummaryData returns executions completed after since that have a stored
+// recommendation, in one query rather than a lookup per execution
+func (s *SQLiteStore) GetRecommendationSummaryData(ctx context.Context, since time.Time) ([]*models.RecommendationSummaryRow, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	rows, err := s.query(ctx, recommendationSummaryQuery, models.WorkflowStatusCompleted, since.UTC(), s.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendation summary data: %w", err)
+	}
+	defer rows.Close()
+
+	summaryRows := make([]*models.RecommendationSummaryRow, 0)
+	for rows.Next() {
+		r, err := scanRecommendationSummaryRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation summary row: %w", err)
+		}
+		summaryRows = append(summaryRows, r)
+	}
+	return summaryRows, rows.Err()
+}
+
+// Aggregated Recommendation operations
+
+// GetAggregatedRecommendations returns stored aggregated recommendations, newest first.
+// A limit of 0 or less returns the full history.
+func (s *SQLiteStore) GetAggregatedRecommendations(ctx context.Context, limit int) ([]*models.AggregatedRecommendation, error) {
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+
+	query := "SELECT id, action, confidence, suggested_actions, summary, key_insights, execution_ids, window_days, source_count, created_at FROM aggregated_recommendations WHERE user_id = $1 ORDER BY created_at DESC"
+	args := []interface{}{s.userID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aggregated recommendations: %w", err)
+	}
+	defer rows.Close()
+
+	recs := make([]*models.AggregatedRecommendation, 0)
+	for rows.Next() {
+		var rec models.AggregatedRecommendation
+		var suggestedActionsJSON, keyInsightsJSON, executionIDsJSON []byte
+		var createdAt sql.NullTime
+
+		err := rows.Scan(&rec.ID, &rec.Action, &rec.Confidence, &suggestedActionsJSON, &rec.Summary, &keyInsightsJSON, &executionIDsJSON, &rec.WindowDays, &rec.SourceCount, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated recommendation row: %w", err)
+		}
+
+		if err := json.Unmarshal(suggestedActionsJSON, &rec.SuggestedActions); err != nil {
+			log.Printf("Failed to unmarshal suggested actions for aggregated recommendation %s: %v", rec.ID, err)
+			rec.SuggestedActions = []models.SuggestedAction{}
+		}
+		if err := json.Unmarshal(keyInsightsJSON, &rec.KeyInsights); err != nil {
+			log.Printf("Failed to unmarshal key insights for aggregated recommendation %s: %v", rec.ID, err)
+			rec.KeyInsights = []string{}
+		}
+		if err := json.Unmarshal(executionIDsJSON, &rec.ExecutionIDs); err != nil {
+			log.Printf("Failed to unmarshal execution IDs for aggregated recommendation %s: %v", rec.ID, err)
+			rec.ExecutionIDs = []string{}
+		}
+		rec.GeneratedAt = parseTimestamp(createdAt)
+
+		recs = append(recs, &rec)
+	}
+
+	return recs, rows.Err()
+}
+
+// GetLatestAggregatedRecommendation returns the most recent aggregated recommendation
+func (s *SQLiteStore) GetLatestAggregatedRecommendation(ctx context.Context) (*models.AggregatedRecommendation, error) {
+	recs, err := s.GetAggregatedRecommendations(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, ErrNotFound
+	}
+	return recs[0], nil
+}
+
+// CreateOrUpdateAggregatedRecommendation creates or updates an aggregated recommendation. One
+// another user owns is left untouched.
+func (s *SQLiteStore) CreateOrUpdateAggregatedRecommendation(ctx context.Context, rec *models.AggregatedRecommendation) error {
+	executionIDsJSON, err := json.Marshal(rec.ExecutionIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution IDs: %w", err)
+	}
+
+	ctx, cancel := s.getContext(ctx)
+	defer cancel()
+	_, err = s.exec(ctx,
+		`INSERT INTO aggregated_recommendations (id, action, confidence, suggested_actions, summary, key_insights, execution_ids, window_days, source_count, user_id, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11, CURRENT_TIMESTAMP), CURRENT_TIMESTAMP)
+		 ON CONFLICT (id) DO UPDATE SET
+		 action = EXCLUDED.action,
+		 confidence = EXCLUDED.confidence,
+		 suggested_actions = EXCLUDED.suggested_actions,
+		 summary = EXCLUDED.summary,
+		 key_insights = EXCLUDED.key_insights,
+		 execution_ids = EXCLUDED.execution_ids,
+		 window_days = EXCLUDED.window_days,
+		 source_count = EXCLUDED.source_count,
+		 updated_at = CURRENT_TIMESTAMP
+		 WHERE aggregated_recommendations.user_id = EXCLUDED.user_id`,
+		rec.ID, rec.Action, rec.Confidence, jsonText(rec.SuggestedActions, "suggested actions", rec.ID), rec.Summary,
+		jsonText(rec.KeyInsights, "key insights", rec.ID), string(executionIDsJSON), rec.WindowDays, rec.SourceCount,
+		s.userID, nullableTime(rec.GeneratedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create/update aggregated recommendation: %w", err)
+	}
+	return nil
+}
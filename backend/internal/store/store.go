@@ -1,436 +1,2213 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"0xnetworth/backend/internal/currency"
 	"0xnetworth/backend/internal/models"
 )
 
-// MemoryStore is an in-memory store for investment data
+// MemoryStore is an in-memory store for investment data.
+// Financial and workflow data is kept per user; use ForUser to obtain a view for another user.
 type MemoryStore struct {
-	mu              sync.RWMutex
-	portfolios      map[string]*models.Portfolio
-	investments     map[string]*models.Investment
-	networth        *models.NetWorth
-	lastSync        time.Time
+	*memoryState
+	userID string
+}
+
+// Fail the build if MemoryStore drifts from the Store interface
+var _ Store = (*MemoryStore)(nil)
+
+// memoryState is shared by every user view of a MemoryStore
+type memoryState struct {
+	mu           sync.RWMutex
+	tenants      map[string]*memoryTenant
+	users        map[string]*models.User
+	youtubeQuota map[string]int // units used by quota day
+	file         *memoryFile    // nil unless created by NewFileStore
+}
+
+// memoryTenant holds the financial and workflow data owned by a single user
+type memoryTenant struct {
+	portfolios   map[string]*models.Portfolio
+	accounts     map[string]*models.Account
+	investments  map[string]*models.Investment
+	transactions map[string]*models.Transaction
+	networth     *models.NetWorth
+	snapshots    []*models.NetWorthSnapshot // oldest first, at most maxMemorySnapshots
+	syncs        map[models.Platform]*models.SyncRecord // latest sync attempt per platform
+	plaidItems   map[string]*models.PlaidItem
+	// investmentHistory is keyed by uppercase symbol; each is oldest first, at most
+	// maxMemoryInvestmentHistory
+	investmentHistory map[string][]*models.InvestmentHistoryPoint
+
 	youtubeSources  map[string]*models.YouTubeSource
 	transcripts     map[string]*models.VideoTranscript
 	marketAnalyses  map[string]*models.MarketAnalysis
 	recommendations map[string]*models.Recommendation
 	executions      map[string]*models.WorkflowExecution
+	executionEvents map[string][]*models.WorkflowExecutionEvent // by execution ID, oldest first
+	aggregatedRecs  []*models.AggregatedRecommendation        // oldest first, at most maxMemoryAggregatedRecs
 }
 
-// NewStore creates a new in-memory store
-func NewStore() Store {
-	return &MemoryStore{
-		portfolios:      make(map[string]*models.Portfolio),
-		investments:     make(map[string]*models.Investment),
-		networth:        &models.NetWorth{},
-		youtubeSources:  make(map[string]*models.YouTubeSource),
-		transcripts:     make(map[string]*models.VideoTranscript),
-		marketAnalyses:  make(map[string]*models.MarketAnalysis),
-		recommendations: make(map[string]*models.Recommendation),
-		executions:      make(map[string]*models.WorkflowExecution),
+// maxMemorySnapshots bounds the net worth history kept per user; the oldest are dropped first
+const maxMemorySnapshots = 10000
+
+// maxMemoryInvestmentHistory bounds the history kept per user and symbol; the oldest points are dropped first
+const maxMemoryInvestmentHistory = 10000
+
+// maxMemoryAggregatedRecs bounds the aggregated recommendation history kept per user; the oldest are dropped first
+const maxMemoryAggregatedRecs = 50
+
+func newMemoryTenant() *memoryTenant {
+	return &memoryTenant{
+		portfolios:   make(map[string]*models.Portfolio),
+		accounts:     make(map[string]*models.Account),
+		investments:  make(map[string]*models.Investment),
+		transactions: make(map[string]*models.Transaction),
+		networth:     &models.NetWorth{},
+		syncs:        make(map[models.Platform]*models.SyncRecord),
+		plaidItems:   make(map[string]*models.PlaidItem),
+		investmentHistory: make(map[string][]*models.InvestmentHistoryPoint),
+		youtubeSources:    make(map[string]*models.YouTubeSource),
+		transcripts:       make(map[string]*models.VideoTranscript),
+		marketAnalyses:    make(map[string]*models.MarketAnalysis),
+		recommendations:   make(map[string]*models.Recommendation),
+		executions:        make(map[string]*models.WorkflowExecution),
+		executionEvents:   make(map[string][]*models.WorkflowExecutionEvent),
 	}
 }
 
-// Portfolio operations
-
-// GetAllPortfolios returns all portfolios
-func (s *MemoryStore) GetAllPortfolios() []*models.Portfolio {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// tenant returns the data for the store's user. Callers must hold s.mu; the tenant
+// is guaranteed to exist because ForUser creates it.
+func (s *MemoryStore) tenant() *memoryTenant {
+	return s.tenants[s.userID]
+}
 
-	portfolios := make([]*models.Portfolio, 0, len(s.portfolios))
-	for _, p := range s.portfolios {
-		portfolios = append(portfolios, p)
+// ownedByOtherUser reports whether a user other than the store's holds a record with id in the
+// map records returns. The SQL stores key records by ID alone and leave a record
+// another user owns untouched, so the memory store skips such writes too. Callers must hold s.mu.
+func ownedByOtherUser[T any](s *MemoryStore, id string, records func(t *memoryTenant) map[string]T) bool {
+	for userID, tenant := range s.tenants {
+		if _, exists := records(tenant)[id]; exists && userID != s.userID {
+			return true
+		}
 	}
-	return portfolios
+	return false
 }
 
-// GetPortfoliosByPlatform returns portfolios for a specific platform
-func (s *MemoryStore) GetPortfoliosByPlatform(platform models.Platform) []*models.Portfolio {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	portfolios := make([]*models.Portfolio, 0)
-	for _, p := range s.portfolios {
-		if p.Platform == platform {
-			portfolios = append(portfolios, p)
-		}
+// normalizeCurrency uppercases known currency codes so aggregation keys stay consistent.
+// Unknown codes are stored as given; callers are expected to validate at the API boundary.
+func normalizeCurrency(code string) string {
+	if normalized, err := currency.Normalize(code); err == nil {
+		return normalized
 	}
-	return portfolios
+	return code
 }
 
-// GetPortfolioByID returns a portfolio by ID
-func (s *MemoryStore) GetPortfolioByID(id string) (*models.Portfolio, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// NewStore creates a new in-memory store scoped to the default user
+func NewStore() Store {
+	state := &memoryState{
+		tenants: map[string]*memoryTenant{
+			models.DefaultUserID: newMemoryTenant(),
+		},
+		users: map[string]*models.User{
+			models.DefaultUserID: {ID: models.DefaultUserID, Name: "Default user"},
+		},
+		youtubeQuota: make(map[string]int),
+	}
+	return &MemoryStore{memoryState: state, userID: models.DefaultUserID}
+}
 
-	portfolio, exists := s.portfolios[id]
-	return portfolio, exists
+// HealthCheck always succeeds; the memory store has no connection to lose
+func (s *MemoryStore) HealthCheck(ctx context.Context) error {
+	return ctx.Err()
 }
 
-// CreateOrUpdatePortfolio creates or updates a portfolio
-func (s *MemoryStore) CreateOrUpdatePortfolio(portfolio *models.Portfolio) {
+// ForUser returns a view of the store scoped to userID
+func (s *MemoryStore) ForUser(userID string) Store {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.portfolios[portfolio.ID] = portfolio
+	if _, exists := s.tenants[userID]; !exists {
+		s.tenants[userID] = newMemoryTenant()
+	}
+	return &MemoryStore{memoryState: s.memoryState, userID: userID}
 }
 
-// DeletePortfolio deletes a portfolio by ID
-func (s *MemoryStore) DeletePortfolio(id string) bool {
+// UserID returns the user this store is scoped to
+func (s *MemoryStore) UserID() string {
+	return s.userID
+}
+
+// WithTransaction runs fn against a copy of the state and swaps the copy in if fn returns nil.
+// Other callers wait until fn returns.
+func (s *MemoryStore) WithTransaction(ctx context.Context, fn func(tx Store) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.portfolios[id]; !exists {
-		return false
+	working := s.memoryState.clone()
+	if err := fn(&MemoryStore{memoryState: working, userID: s.userID}); err != nil {
+		return err
 	}
-	delete(s.portfolios, id)
-	return true
+	s.replaceWith(working)
+	s.changed()
+	return nil
 }
 
-// Investment operations
+// DeleteRecords deletes every record of kind
+func (s *MemoryStore) DeleteRecords(ctx context.Context, kind RecordKind) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	tenant := s.tenant()
+	var deleted int
+	switch kind {
+	case RecordPortfolios:
+		deleted, tenant.portfolios = len(tenant.portfolios), make(map[string]*models.Portfolio)
+	case RecordAccounts:
+		deleted, tenant.accounts = len(tenant.accounts), make(map[string]*models.Account)
+	case RecordInvestments:
+		deleted, tenant.investments = len(tenant.investments), make(map[string]*models.Investment)
+	case RecordTransactions:
+		deleted, tenant.transactions = len(tenant.transactions), make(map[string]*models.Transaction)
+	case RecordNetWorthSnapshots:
+		deleted, tenant.snapshots = len(tenant.snapshots), nil
+	case RecordYouTubeSources:
+		deleted, tenant.youtubeSources = len(tenant.youtubeSources), make(map[string]*models.YouTubeSource)
+	case RecordTranscripts:
+		deleted, tenant.transcripts = len(tenant.transcripts), make(map[string]*models.VideoTranscript)
+	case RecordMarketAnalyses:
+		deleted, tenant.marketAnalyses = len(tenant.marketAnalyses), make(map[string]*models.MarketAnalysis)
+	case RecordRecommendations:
+		deleted, tenant.recommendations = len(tenant.recommendations), make(map[string]*models.Recommendation)
+	case RecordWorkflowExecutions:
+		deleted, tenant.executions = len(tenant.executions), make(map[string]*models.WorkflowExecution)
+		tenant.executionEvents = make(map[string][]*models.WorkflowExecutionEvent)
+	case RecordAggregatedRecommendations:
+		deleted, tenant.aggregatedRecs = len(tenant.aggregatedRecs), nil
+	default:
+		return 0, fmt.Errorf("unknown record kind %q", kind)
+	}
+	return deleted, nil
+}
+
+// User operations
 
-// GetAllInvestments returns all investments
-func (s *MemoryStore) GetAllInvestments() []*models.Investment {
+// GetUserByTokenHash returns the user owning the given API token hash
+func (s *MemoryStore) GetUserByTokenHash(ctx context.Context, tokenHash string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	investments := make([]*models.Investment, 0, len(s.investments))
-	for _, inv := range s.investments {
-		investments = append(investments, inv)
+	for _, user := range s.users {
+		if user.TokenHash != "" && user.TokenHash == tokenHash {
+			return cloneUser(user), nil
+		}
 	}
-	return investments
+	return nil, ErrNotFound
 }
 
-// GetInvestmentsByAccount returns investments for a specific account
-func (s *MemoryStore) GetInvestmentsByAccount(accountID string) []*models.Investment {
+// CreateOrUpdateUser creates or updates a user and its API token hash
+func (s *MemoryStore) CreateOrUpdateUser(ctx context.Context, user *models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	s.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+// ListUserIDs returns the ID of every user in order, across users
+func (s *MemoryStore) ListUserIDs(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	investments := make([]*models.Investment, 0)
-	for _, inv := range s.investments {
-		if inv.AccountID == accountID {
-			investments = append(investments, inv)
-		}
-	}
-	return investments
+	return slices.Sorted(maps.Keys(s.users)), nil
 }
 
-// GetInvestmentsByPlatform returns investments for a specific platform
-func (s *MemoryStore) GetInvestmentsByPlatform(platform models.Platform) []*models.Investment {
+// Portfolio operations
+
+// GetAllPortfolios returns a page of portfolios along with the total number of portfolios
+func (s *MemoryStore) GetAllPortfolios(ctx context.Context, opts ListOptions, order SortOption) ([]*models.Portfolio, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	investments := make([]*models.Investment, 0)
-	for _, inv := range s.investments {
-		if inv.Platform == platform {
-			investments = append(investments, inv)
-		}
+	portfolios := make([]*models.Portfolio, 0, len(s.tenant().portfolios))
+	for _, p := range s.tenant().portfolios {
+		portfolios = append(portfolios, p)
+	}
+	sorted, err := portfolioSortFields.sort(portfolios, order)
+	if err != nil {
+		return nil, 0, err
 	}
-	return investments
+	if !sorted {
+		sortPortfolios(portfolios)
+	}
+	start, end := opts.window(len(portfolios))
+	return cloneAll(portfolios[start:end], clonePortfolio), len(portfolios), nil
 }
 
-// CreateOrUpdateInvestment creates or updates an investment
-func (s *MemoryStore) CreateOrUpdateInvestment(investment *models.Investment) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// CountPortfolios returns the number of portfolios
+func (s *MemoryStore) CountPortfolios(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	s.investments[investment.ID] = investment
+	return len(s.tenant().portfolios), nil
 }
 
-// DeleteInvestment deletes an investment by ID
-func (s *MemoryStore) DeleteInvestment(id string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetPortfoliosByPlatform returns portfolios for a specific platform
+func (s *MemoryStore) GetPortfoliosByPlatform(ctx context.Context, platform models.Platform) ([]*models.Portfolio, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if _, exists := s.investments[id]; !exists {
-		return false
+	portfolios := make([]*models.Portfolio, 0)
+	for _, p := range s.tenant().portfolios {
+		if p.Platform == platform {
+			portfolios = append(portfolios, p)
+		}
 	}
-	delete(s.investments, id)
-	return true
+	sortPortfolios(portfolios)
+	return cloneAll(portfolios, clonePortfolio), nil
 }
 
-// NetWorth operations
-
-// GetNetWorth returns the current net worth
-func (s *MemoryStore) GetNetWorth() *models.NetWorth {
+// GetPortfolioByID returns a portfolio by ID
+func (s *MemoryStore) GetPortfolioByID(ctx context.Context, id string) (*models.Portfolio, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Return a copy to avoid race conditions
-	networth := *s.networth
-	return &networth
+	portfolio, exists := s.tenant().portfolios[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clonePortfolio(portfolio), nil
 }
 
-// UpdateNetWorth updates the net worth calculation
-func (s *MemoryStore) UpdateNetWorth(networth *models.NetWorth) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetPortfolioValues returns the value and number of active holdings of every portfolio
+func (s *MemoryStore) GetPortfolioValues(ctx context.Context) ([]*models.PortfolioValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	portfolios := make([]*models.Portfolio, 0, len(s.tenant().portfolios))
+	for _, p := range s.tenant().portfolios {
+		portfolios = append(portfolios, p)
+	}
+	sortPortfolios(portfolios)
 
-	s.networth = networth
+	values := make([]*models.PortfolioValue, len(portfolios))
+	byID := make(map[string]*models.PortfolioValue, len(portfolios))
+	for i, p := range portfolios {
+		values[i] = &models.PortfolioValue{PortfolioID: p.ID, Name: p.Name, Platform: p.Platform}
+		byID[p.ID] = values[i]
+	}
+	for _, investment := range s.tenant().investments {
+		if value, exists := byID[investment.AccountID]; exists && investment.Active {
+			value.HoldingCount++
+			value.Value += investment.Value
+		}
+	}
+	return values, nil
 }
 
-// RecalculateNetWorth recalculates net worth from current accounts and investments
-func (s *MemoryStore) RecalculateNetWorth() *models.NetWorth {
+// CreateOrUpdatePortfolio creates or updates a portfolio
+func (s *MemoryStore) CreateOrUpdatePortfolio(ctx context.Context, portfolio *models.Portfolio) (UpsertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	networth := &models.NetWorth{
-		ByPlatform:   make(map[models.Platform]float64),
-		ByAssetType:  make(map[string]float64),
-		Currency:     "USD", // Default currency
-		LastCalculated: time.Now().UTC().Format(time.RFC3339),
+	if ownedByOtherUser(s, portfolio.ID, func(t *memoryTenant) map[string]*models.Portfolio { return t.portfolios }) {
+		return UpsertSkipped, nil
+	}
+	existing, exists := s.tenant().portfolios[portfolio.ID]
+	result := upsertResult(existing, portfolio, samePortfolio)
+	if exists {
+		if portfolio.TaxTreatment == "" {
+			portfolio.TaxTreatment = existing.TaxTreatment
+		}
+		if portfolio.Custodian == "" {
+			portfolio.Custodian = existing.Custodian
+		}
+		if portfolio.DisplayOrder == nil {
+			portfolio.DisplayOrder = clonePtr(existing.DisplayOrder)
+		}
 	}
+	s.tenant().portfolios[portfolio.ID] = clonePortfolio(portfolio)
+	return result, nil
+}
 
-	// Calculate total from investments (portfolios don't have balances, only holdings)
-	totalValue := 0.0
-	for _, investment := range s.investments {
-		totalValue += investment.Value
-		networth.ByPlatform[investment.Platform] += investment.Value
-		networth.ByAssetType[investment.AssetType] += investment.Value
+// UpdatePortfolioMetadata applies a partial metadata update to an existing portfolio
+func (s *MemoryStore) UpdatePortfolioMetadata(ctx context.Context, id string, update models.PortfolioMetadataUpdate) (*models.Portfolio, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
 
-	networth.TotalValue = totalValue
-	networth.AccountCount = len(s.portfolios) // Use portfolio count instead of account count
-	s.networth = networth
-	return networth
+	existing, exists := s.tenant().portfolios[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	updated := clonePortfolio(existing)
+	update.Apply(updated)
+	s.tenant().portfolios[id] = updated
+	return clonePortfolio(updated), nil
 }
 
-// GetLastSyncTime returns the last sync time
-func (s *MemoryStore) GetLastSyncTime() time.Time {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	return s.lastSync
+// sortPortfolios orders portfolios by display order (unset last), then name, then ID
+func sortPortfolios(portfolios []*models.Portfolio) {
+	sort.SliceStable(portfolios, func(i, j int) bool {
+		a, b := portfolios[i], portfolios[j]
+		if (a.DisplayOrder == nil) != (b.DisplayOrder == nil) {
+			return a.DisplayOrder != nil
+		}
+		if a.DisplayOrder != nil && *a.DisplayOrder != *b.DisplayOrder {
+			return *a.DisplayOrder < *b.DisplayOrder
+		}
+		if nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name); nameA != nameB {
+			return nameA < nameB
+		}
+		return a.ID < b.ID
+	})
 }
 
-// SetLastSyncTime sets the last sync time
-func (s *MemoryStore) SetLastSyncTime(t time.Time) {
+// DeletePortfolio deletes a portfolio by ID
+func (s *MemoryStore) DeletePortfolio(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	s.lastSync = t
+	if _, exists := s.tenant().portfolios[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.tenant().portfolios, id)
+	for txID, tx := range s.tenant().transactions {
+		if tx.AccountID == id {
+			delete(s.tenant().transactions, txID)
+		}
+	}
+	return nil
 }
 
-// YouTube Source operations
+// Account operations
 
-// GetAllYouTubeSources returns all YouTube sources
-func (s *MemoryStore) GetAllYouTubeSources() []*models.YouTubeSource {
+// sortAccounts orders accounts by platform, currency and ID
+func sortAccounts(accounts []*models.Account) {
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.ID < b.ID
+	})
+}
+
+// GetAllAccounts returns all accounts
+func (s *MemoryStore) GetAllAccounts(ctx context.Context) ([]*models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sources := make([]*models.YouTubeSource, 0, len(s.youtubeSources))
-	for _, src := range s.youtubeSources {
-		sources = append(sources, src)
+	accounts := make([]*models.Account, 0, len(s.tenant().accounts))
+	for _, account := range s.tenant().accounts {
+		accounts = append(accounts, account)
 	}
-	return sources
+	sortAccounts(accounts)
+	return cloneAll(accounts, cloneAccount), nil
 }
 
-// GetYouTubeSourceByID returns a YouTube source by ID
-func (s *MemoryStore) GetYouTubeSourceByID(id string) (*models.YouTubeSource, bool) {
+// GetAccountsByPlatform returns accounts for a specific platform
+func (s *MemoryStore) GetAccountsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	source, exists := s.youtubeSources[id]
-	return source, exists
+	accounts := make([]*models.Account, 0)
+	for _, account := range s.tenant().accounts {
+		if account.Platform == platform {
+			accounts = append(accounts, account)
+		}
+	}
+	sortAccounts(accounts)
+	return cloneAll(accounts, cloneAccount), nil
 }
 
-// CreateOrUpdateYouTubeSource creates or updates a YouTube source
-func (s *MemoryStore) CreateOrUpdateYouTubeSource(source *models.YouTubeSource) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetAccountByID returns an account by ID
+func (s *MemoryStore) GetAccountByID(ctx context.Context, id string) (*models.Account, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	s.youtubeSources[source.ID] = source
+	account, exists := s.tenant().accounts[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneAccount(account), nil
 }
 
-// DeleteYouTubeSource deletes a YouTube source by ID
-func (s *MemoryStore) DeleteYouTubeSource(id string) bool {
+// CreateOrUpdateAccount creates or updates an account
+func (s *MemoryStore) CreateOrUpdateAccount(ctx context.Context, account *models.Account) (UpsertResult, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	if _, exists := s.youtubeSources[id]; !exists {
-		return false
+	if ownedByOtherUser(s, account.ID, func(t *memoryTenant) map[string]*models.Account { return t.accounts }) {
+		return UpsertSkipped, nil
 	}
-	delete(s.youtubeSources, id)
-	return true
+	account.Currency = normalizeCurrency(account.Currency)
+	result := upsertResult(s.tenant().accounts[account.ID], account, sameAccount)
+	s.tenant().accounts[account.ID] = cloneAccount(account)
+	return result, nil
 }
 
-// Video Transcript operations
-
-// CreateOrUpdateTranscript creates or updates a video transcript
-func (s *MemoryStore) CreateOrUpdateTranscript(transcript *models.VideoTranscript) {
+// DeleteAccount deletes an account by ID
+func (s *MemoryStore) DeleteAccount(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	s.transcripts[transcript.ID] = transcript
+	if _, exists := s.tenant().accounts[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.tenant().accounts, id)
+	return nil
 }
 
-// GetTranscriptByID returns a transcript by ID
-func (s *MemoryStore) GetTranscriptByID(id string) (*models.VideoTranscript, bool) {
+// Investment operations
+
+// GetAllInvestments returns a page of investments along with the total number of investments
+func (s *MemoryStore) GetAllInvestments(ctx context.Context, opts ListOptions, order SortOption, filter InvestmentFilter) ([]*models.Investment, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	transcript, exists := s.transcripts[id]
-	return transcript, exists
+	investments := make([]*models.Investment, 0, len(s.tenant().investments))
+	for _, inv := range s.tenant().investments {
+		if filter.matches(inv) {
+			investments = append(investments, inv)
+		}
+	}
+	sorted, err := investmentSortFields.sort(investments, order)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !sorted {
+		// Map iteration order is random; sort so pages are stable
+		sort.Slice(investments, func(i, j int) bool {
+			return investments[i].ID < investments[j].ID
+		})
+	}
+	start, end := opts.window(len(investments))
+	return cloneAll(investments[start:end], cloneInvestment), len(investments), nil
 }
 
-// GetTranscriptsByVideoID returns transcripts for a specific video ID
-func (s *MemoryStore) GetTranscriptsByVideoID(videoID string) []*models.VideoTranscript {
+// CountInvestments returns the number of active investments, on platform if it is not empty
+func (s *MemoryStore) CountInvestments(ctx context.Context, platform models.Platform) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	transcripts := make([]*models.VideoTranscript, 0)
-	for _, t := range s.transcripts {
-		if t.VideoID == videoID {
-			transcripts = append(transcripts, t)
+	count := 0
+	for _, inv := range s.tenant().investments {
+		if inv.Active && (platform == "" || inv.Platform == platform) {
+			count++
 		}
 	}
-	return transcripts
+	return count, nil
 }
 
-// Market Analysis operations
-
-// CreateOrUpdateMarketAnalysis creates or updates a market analysis
-func (s *MemoryStore) CreateOrUpdateMarketAnalysis(analysis *models.MarketAnalysis) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetInvestmentsByAccount returns investments for a specific account
+func (s *MemoryStore) GetInvestmentsByAccount(ctx context.Context, accountID string, filter InvestmentFilter) ([]*models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	s.marketAnalyses[analysis.ID] = analysis
+	investments := make([]*models.Investment, 0)
+	for _, inv := range s.tenant().investments {
+		if inv.AccountID == accountID && filter.matches(inv) {
+			investments = append(investments, inv)
+		}
+	}
+	return cloneAll(investments, cloneInvestment), nil
 }
 
-// GetMarketAnalysisByID returns a market analysis by ID
-func (s *MemoryStore) GetMarketAnalysisByID(id string) (*models.MarketAnalysis, bool) {
+// GetInvestmentsByPlatform returns investments for a specific platform
+func (s *MemoryStore) GetInvestmentsByPlatform(ctx context.Context, platform models.Platform, filter InvestmentFilter) ([]*models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	analysis, exists := s.marketAnalyses[id]
-	return analysis, exists
+	investments := make([]*models.Investment, 0)
+	for _, inv := range s.tenant().investments {
+		if inv.Platform == platform && filter.matches(inv) {
+			investments = append(investments, inv)
+		}
+	}
+	return cloneAll(investments, cloneInvestment), nil
 }
 
-// GetMarketAnalysesByTranscriptID returns market analyses for a specific transcript ID
-func (s *MemoryStore) GetMarketAnalysesByTranscriptID(transcriptID string) []*models.MarketAnalysis {
+// GetInvestmentsBySymbol returns investments in a symbol across all accounts and platforms
+func (s *MemoryStore) GetInvestmentsBySymbol(ctx context.Context, symbol string, filter InvestmentFilter) ([]*models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	analyses := make([]*models.MarketAnalysis, 0)
-	for _, a := range s.marketAnalyses {
-		if a.TranscriptID == transcriptID {
-			analyses = append(analyses, a)
+	investments := make([]*models.Investment, 0)
+	for _, inv := range s.tenant().investments {
+		if strings.EqualFold(inv.Symbol, symbol) && filter.matches(inv) {
+			investments = append(investments, inv)
 		}
 	}
-	return analyses
+	return cloneAll(investments, cloneInvestment), nil
 }
 
-// Recommendation operations
-
-// CreateOrUpdateRecommendation creates or updates a recommendation
-func (s *MemoryStore) CreateOrUpdateRecommendation(recommendation *models.Recommendation) {
+// CreateOrUpdateInvestment creates or updates an investment.
+// Cost basis fields are computed rather than synced, so a nil value never overwrites a stored one.
+func (s *MemoryStore) CreateOrUpdateInvestment(ctx context.Context, investment *models.Investment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	s.recommendations[recommendation.ID] = recommendation
+	s.upsertInvestment(investment)
+	return nil
 }
 
-// GetRecommendationByID returns a recommendation by ID
-func (s *MemoryStore) GetRecommendationByID(id string) (*models.Recommendation, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// CreateOrUpdateInvestments upserts investments under a single lock acquisition and counts
+// how many were created, updated, left unchanged or skipped
+func (s *MemoryStore) CreateOrUpdateInvestments(ctx context.Context, investments []*models.Investment) (UpsertCounts, error) {
+	var counts UpsertCounts
+	if err := ctx.Err(); err != nil {
+		return counts, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
 
-	recommendation, exists := s.recommendations[id]
-	return recommendation, exists
+	for _, investment := range investments {
+		counts.Add(s.upsertInvestment(investment))
+	}
+	return counts, nil
 }
 
-// GetRecommendationsByAnalysisID returns recommendations for a specific analysis ID
-func (s *MemoryStore) GetRecommendationsByAnalysisID(analysisID string) []*models.Recommendation {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	recommendations := make([]*models.Recommendation, 0)
-	for _, r := range s.recommendations {
-		if r.AnalysisID == analysisID {
-			recommendations = append(recommendations, r)
+// upsertInvestment stores an investment as active, keeping computed cost basis fields the
+// incoming value leaves unset. An investment another user owns is skipped. Callers must hold s.mu.
+func (s *MemoryStore) upsertInvestment(investment *models.Investment) UpsertResult {
+	if ownedByOtherUser(s, investment.ID, func(t *memoryTenant) map[string]*models.Investment { return t.investments }) {
+		return UpsertSkipped
+	}
+	investment.AssetType, _ = models.NormalizeAssetType(string(investment.AssetType))
+	investment.Currency = normalizeCurrency(investment.Currency)
+	investment.NativeCurrency = normalizeCurrency(investment.NativeCurrency)
+	existing, exists := s.tenant().investments[investment.ID]
+	result := upsertResult(existing, investment, sameInvestment)
+	investment.Active = true
+	investment.DeactivatedAt = nil
+	if exists {
+		if investment.CostBasis == nil {
+			investment.CostBasis = clonePtr(existing.CostBasis)
+		}
+		if investment.AverageBuyPrice == nil {
+			investment.AverageBuyPrice = clonePtr(existing.AverageBuyPrice)
+		}
+		if investment.FirstAcquiredAt == nil {
+			investment.FirstAcquiredAt = clonePtr(existing.FirstAcquiredAt)
+		}
+		if investment.UnrealizedGain == nil {
+			investment.UnrealizedGain = clonePtr(existing.UnrealizedGain)
 		}
 	}
-	return recommendations
+	s.tenant().investments[investment.ID] = cloneInvestment(investment)
+	return result
 }
 
-// Workflow Execution operations
-
-// CreateOrUpdateWorkflowExecution creates or updates a workflow execution
-func (s *MemoryStore) CreateOrUpdateWorkflowExecution(execution *models.WorkflowExecution) {
+// DeactivateMissingInvestments marks active investments on platform in accountIDs that are
+// not in keepIDs as inactive
+func (s *MemoryStore) DeactivateMissingInvestments(ctx context.Context, platform models.Platform, accountIDs, keepIDs []string, at time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.changed()
 
-	s.executions[execution.ID] = execution
+	deactivatedAt := at.UTC()
+	deactivated := 0
+	for _, inv := range s.tenant().investments {
+		if !inv.Active || inv.Platform != platform || !slices.Contains(accountIDs, inv.AccountID) || slices.Contains(keepIDs, inv.ID) {
+			continue
+		}
+		inv.Active = false
+		inv.DeactivatedAt = &deactivatedAt
+		deactivated++
+	}
+	return deactivated, nil
 }
 
-// GetWorkflowExecutionByID returns a workflow execution by ID
-func (s *MemoryStore) GetWorkflowExecutionByID(id string) (*models.WorkflowExecution, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	execution, exists := s.executions[id]
-	return execution, exists
+// DeactivateInvestments marks the active investments with the given IDs as inactive
+func (s *MemoryStore) DeactivateInvestments(ctx context.Context, ids []string, at time.Time) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	deactivatedAt := at.UTC()
+	deactivated := 0
+	for _, id := range ids {
+		inv, exists := s.tenant().investments[id]
+		if !exists || !inv.Active {
+			continue
+		}
+		inv.Active = false
+		inv.DeactivatedAt = &deactivatedAt
+		deactivated++
+	}
+	return deactivated, nil
 }
 
-// GetAllWorkflowExecutions returns all workflow executions
-func (s *MemoryStore) GetAllWorkflowExecutions() []*models.WorkflowExecution {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// UpdateInvestmentPrice reprices an active investment at its stored quantity
+func (s *MemoryStore) UpdateInvestmentPrice(ctx context.Context, id string, price float64, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
 
-	executions := make([]*models.WorkflowExecution, 0, len(s.executions))
-	for _, e := range s.executions {
-		executions = append(executions, e)
+	investment, exists := s.tenant().investments[id]
+	if !exists || !investment.Active {
+		return ErrNotFound
+	}
+	investment.Price = price
+	investment.Value = price * investment.Quantity
+	if investment.CostBasis != nil {
+		unrealizedGain := investment.Value - *investment.CostBasis
+		investment.UnrealizedGain = &unrealizedGain
 	}
-	return executions
+	investment.StalePrice = false
+	investment.LastUpdated = at.UTC()
+	return nil
 }
 
-// GetWorkflowExecutionsBySourceID returns workflow executions for a specific source ID
-func (s *MemoryStore) GetWorkflowExecutionsBySourceID(sourceID string) []*models.WorkflowExecution {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// DeleteInvestment deletes an investment by ID
+func (s *MemoryStore) DeleteInvestment(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
 
-	executions := make([]*models.WorkflowExecution, 0)
-	for _, e := range s.executions {
-		if e.SourceID == sourceID {
-			executions = append(executions, e)
-		}
+	if _, exists := s.tenant().investments[id]; !exists {
+		return ErrNotFound
 	}
-	return executions
+	delete(s.tenant().investments, id)
+	return nil
 }
 
-// GetWorkflowExecutionsByVideoID returns workflow executions for a specific video ID
-func (s *MemoryStore) GetWorkflowExecutionsByVideoID(videoID string) []*models.WorkflowExecution {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// NetWorth operations
 
-	executions := make([]*models.WorkflowExecution, 0)
-	for _, e := range s.executions {
-		if e.VideoID == videoID {
-			executions = append(executions, e)
-		}
+// GetNetWorth returns the current net worth
+func (s *MemoryStore) GetNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return executions
-}
-
-// GetLatestAggregatedRecommendation returns the most recent aggregated recommendation
-func (s *MemoryStore) GetLatestAggregatedRecommendation() (*models.AggregatedRecommendation, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	
-	// Memory store doesn't persist aggregated recommendations
-	// Return false to indicate not found
-	return nil, false
-}
 
-// CreateOrUpdateAggregatedRecommendation creates or updates an aggregated recommendation
-func (s *MemoryStore) CreateOrUpdateAggregatedRecommendation(rec *models.AggregatedRecommendation) error {
-	// Memory store doesn't persist aggregated recommendations
-	// This is a no-op for in-memory store
-	return nil
+	return cloneNetWorth(s.tenant().networth), nil
 }
 
+// UpdateNetWorth updates the net worth calculation
+func (s *MemoryStore) UpdateNetWorth(ctx context.Context, networth *models.NetWorth) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	s.tenant().networth = cloneNetWorth(networth)
+	return nil
+}
+
+// RecalculateNetWorth recalculates net worth from current accounts and investments
+func (s *MemoryStore) RecalculateNetWorth(ctx context.Context) (*models.NetWorth, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	networth := &models.NetWorth{
+		ByPlatform:   make(map[models.Platform]float64),
+		ByAssetType:  make(map[models.AssetType]float64),
+		ByTaxTreatment: make(map[models.TaxTreatment]float64),
+		Currency:     currency.Reporting,
+		LastCalculated: models.Now(),
+	}
+
+	// Calculate total from investments (portfolios don't have balances, only holdings)
+	for _, investment := range s.tenant().investments {
+		if !investment.Active {
+			continue
+		}
+		var taxTreatment models.TaxTreatment
+		if portfolio, exists := s.tenant().portfolios[investment.AccountID]; exists {
+			taxTreatment = portfolio.TaxTreatment
+		}
+		networth.AddHolding(investment.Platform, string(investment.AssetType), taxTreatment, investment.Currency, investment.Value)
+		if investment.ValueChange24h != nil {
+			networth.AddPriceChange(investment.Currency, *investment.ValueChange24h)
+		}
+	}
+	networth.AccountCount = len(s.tenant().portfolios) // Use portfolio count instead of account count
+	s.tenant().networth = networth
+	return cloneNetWorth(networth), nil
+}
+
+// Net worth history operations
+
+// SaveNetWorthSnapshot records a calculated net worth in the history
+func (s *MemoryStore) SaveNetWorthSnapshot(ctx context.Context, networth *models.NetWorth) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	snapshot := cloneSnapshot(models.NewNetWorthSnapshot(networth))
+	tenant := s.tenant()
+	i := sort.Search(len(tenant.snapshots), func(i int) bool {
+		return !tenant.snapshots[i].Timestamp.Before(snapshot.Timestamp)
+	})
+	if i < len(tenant.snapshots) && tenant.snapshots[i].Timestamp.Equal(snapshot.Timestamp) {
+		tenant.snapshots[i] = snapshot
+		return nil
+	}
+	tenant.snapshots = append(tenant.snapshots, nil)
+	copy(tenant.snapshots[i+1:], tenant.snapshots[i:])
+	tenant.snapshots[i] = snapshot
+	if len(tenant.snapshots) > maxMemorySnapshots {
+		tenant.snapshots = tenant.snapshots[len(tenant.snapshots)-maxMemorySnapshots:]
+	}
+	return nil
+}
+
+// GetNetWorthSnapshots returns snapshots taken in [from, to), oldest first
+func (s *MemoryStore) GetNetWorthSnapshots(ctx context.Context, from, to time.Time) ([]*models.NetWorthSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := make([]*models.NetWorthSnapshot, 0)
+	for _, snapshot := range s.tenant().snapshots {
+		if !from.IsZero() && snapshot.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !snapshot.Timestamp.Before(to) {
+			continue
+		}
+		snapshots = append(snapshots, cloneSnapshot(snapshot))
+	}
+	return snapshots, nil
+}
+
+// Investment history operations
+
+// SaveInvestmentHistory records points in the per-symbol history
+func (s *MemoryStore) SaveInvestmentHistory(ctx context.Context, points []*models.InvestmentHistoryPoint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	tenant := s.tenant()
+	for _, point := range points {
+		point = cloneInvestmentHistoryPoint(point)
+		point.Symbol = strings.ToUpper(point.Symbol)
+		point.Timestamp = point.Timestamp.UTC().Truncate(time.Minute)
+
+		history := tenant.investmentHistory[point.Symbol]
+		i := sort.Search(len(history), func(i int) bool {
+			if !history[i].Timestamp.Equal(point.Timestamp) {
+				return history[i].Timestamp.After(point.Timestamp)
+			}
+			return history[i].Platform >= point.Platform
+		})
+		if i < len(history) && history[i].Timestamp.Equal(point.Timestamp) && history[i].Platform == point.Platform {
+			history[i] = point
+			continue
+		}
+		history = slices.Insert(history, i, point)
+		if len(history) > maxMemoryInvestmentHistory {
+			history = history[len(history)-maxMemoryInvestmentHistory:]
+		}
+		tenant.investmentHistory[point.Symbol] = history
+	}
+	return nil
+}
+
+// GetInvestmentHistory returns the points for symbol recorded in [from, to), oldest first
+func (s *MemoryStore) GetInvestmentHistory(ctx context.Context, symbol string, from, to time.Time) ([]*models.InvestmentHistoryPoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	points := make([]*models.InvestmentHistoryPoint, 0)
+	for _, point := range s.tenant().investmentHistory[strings.ToUpper(symbol)] {
+		if !from.IsZero() && point.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !point.Timestamp.Before(to) {
+			continue
+		}
+		points = append(points, cloneInvestmentHistoryPoint(point))
+	}
+	return points, nil
+}
+
+// Transaction operations
+
+// CreateOrUpdateTransaction creates or updates a transaction. A transaction another user owns
+// is left as it is.
+func (s *MemoryStore) CreateOrUpdateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !transaction.Type.IsValid() {
+		return fmt.Errorf("invalid transaction type %q", transaction.Type)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, transaction.ID, func(t *memoryTenant) map[string]*models.Transaction { return t.transactions }) {
+		return nil
+	}
+	transaction.Currency = normalizeCurrency(transaction.Currency)
+	transaction.Timestamp = transaction.Timestamp.UTC()
+	s.tenant().transactions[transaction.ID] = cloneTransaction(transaction)
+	return nil
+}
+
+// GetTransactionsByAccount returns every transaction for an account, newest first
+func (s *MemoryStore) GetTransactionsByAccount(ctx context.Context, accountID string) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{AccountID: accountID})
+	return transactions, err
+}
+
+// GetTransactionsByPlatform returns every transaction for a platform, newest first
+func (s *MemoryStore) GetTransactionsByPlatform(ctx context.Context, platform models.Platform) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{Platform: platform})
+	return transactions, err
+}
+
+// GetTransactionsBetween returns transactions in [from, to), newest first
+func (s *MemoryStore) GetTransactionsBetween(ctx context.Context, from, to time.Time) ([]*models.Transaction, error) {
+	transactions, _, err := s.ListTransactions(ctx, TransactionFilter{From: from, To: to})
+	return transactions, err
+}
+
+// ListTransactions returns transactions matching the filter, newest first, along with the
+// total number of matches before pagination
+func (s *MemoryStore) ListTransactions(ctx context.Context, filter TransactionFilter) ([]*models.Transaction, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]*models.Transaction, 0)
+	for _, tx := range s.tenant().transactions {
+		if filter.Platform != "" && tx.Platform != filter.Platform {
+			continue
+		}
+		if filter.AccountID != "" && tx.AccountID != filter.AccountID {
+			continue
+		}
+		if filter.Symbol != "" && !strings.EqualFold(tx.Symbol, filter.Symbol) {
+			continue
+		}
+		if filter.Type != "" && tx.Type != filter.Type {
+			continue
+		}
+		if !filter.From.IsZero() && tx.Timestamp.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !tx.Timestamp.Before(filter.To) {
+			continue
+		}
+		matches = append(matches, tx)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Timestamp.After(matches[j].Timestamp)
+	})
+
+	total := len(matches)
+	start, end := filter.window(total)
+	return cloneAll(matches[start:end], cloneTransaction), total, nil
+}
+
+// GetTransactionSummary totals a calendar year of transactions by type per currency
+func (s *MemoryStore) GetTransactionSummary(ctx context.Context, year int) (*models.TransactionSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := &models.TransactionSummary{
+		Year:       year,
+		ByCurrency: make(map[string]*models.TransactionTotals),
+	}
+	for _, tx := range s.tenant().transactions {
+		if tx.Timestamp.UTC().Year() != year {
+			continue
+		}
+		totals, exists := summary.ByCurrency[tx.Currency]
+		if !exists {
+			totals = &models.TransactionTotals{}
+			summary.ByCurrency[tx.Currency] = totals
+		}
+		totals.Add(tx.Type, tx.Amount, tx.Fee)
+	}
+	return summary, nil
+}
+
+// GetLastSyncTime returns when platform last synced
+func (s *MemoryStore) GetLastSyncTime(ctx context.Context, platform models.Platform) (time.Time, error) {
+	if err := ctx.Err(); err != nil {
+		return time.Time{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if record, exists := s.tenant().syncs[platform]; exists && record.LastSync != nil {
+		return *record.LastSync, nil
+	}
+	return time.Time{}, nil
+}
+
+// GetSyncRecord returns the latest sync attempt on platform
+func (s *MemoryStore) GetSyncRecord(ctx context.Context, platform models.Platform) (*models.SyncRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, exists := s.tenant().syncs[platform]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneSyncRecord(record), nil
+}
+
+// RecordSyncResult records a sync attempt on platform
+func (s *MemoryStore) RecordSyncResult(ctx context.Context, platform models.Platform, status models.SyncStatus, errMsg string, counts models.SyncCounts, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	attempted := at.UTC()
+	record := &models.SyncRecord{
+		Platform:    platform,
+		Status:      status,
+		Error:       errMsg,
+		Counts:      counts,
+		LastAttempt: &attempted,
+	}
+	if status == models.SyncStatusSuccess {
+		record.LastSync = clonePtr(&attempted)
+	} else if previous, exists := s.tenant().syncs[platform]; exists {
+		record.LastSync = clonePtr(previous.LastSync)
+	}
+	s.tenant().syncs[platform] = record
+	return nil
+}
+
+// Plaid item operations
+
+// GetPlaidItems returns the user's Plaid items, oldest first
+func (s *MemoryStore) GetPlaidItems(ctx context.Context) ([]*models.PlaidItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]*models.PlaidItem, 0, len(s.tenant().plaidItems))
+	for _, item := range s.tenant().plaidItems {
+		items = append(items, clonePlaidItem(item))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+// GetPlaidItem returns a Plaid item by ID
+func (s *MemoryStore) GetPlaidItem(ctx context.Context, id string) (*models.PlaidItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, exists := s.tenant().plaidItems[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clonePlaidItem(item), nil
+}
+
+// CreateOrUpdatePlaidItem creates or updates a Plaid item. An item linked by another user is
+// not overwritten.
+func (s *MemoryStore) CreateOrUpdatePlaidItem(ctx context.Context, item *models.PlaidItem) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, item.ID, func(t *memoryTenant) map[string]*models.PlaidItem { return t.plaidItems }) {
+		return fmt.Errorf("plaid item %s is linked by another user", item.ID)
+	}
+	stored := clonePlaidItem(item)
+	now := models.Now()
+	stored.CreatedAt, stored.UpdatedAt = now, now
+	// The accounts and last sync are only set by SetPlaidItemAccounts and SetPlaidItemStatus,
+	// as in the SQL stores, and linking an item again makes it healthy
+	stored.AccountIDs = nil
+	stored.AccountCount = 0
+	stored.Status = models.PlaidItemStatusHealthy
+	stored.LastSyncedAt = nil
+	if existing, exists := s.tenant().plaidItems[item.ID]; exists {
+		stored.CreatedAt = existing.CreatedAt
+		stored.AccountIDs = existing.AccountIDs
+		stored.LastSyncedAt = existing.LastSyncedAt
+	}
+	s.tenant().plaidItems[item.ID] = stored
+	return nil
+}
+
+// SetPlaidItemAccounts records the accounts a Plaid item's sync stored
+func (s *MemoryStore) SetPlaidItemAccounts(ctx context.Context, id string, accountIDs []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	item, exists := s.tenant().plaidItems[id]
+	if !exists {
+		return ErrNotFound
+	}
+	item.AccountIDs = slices.Clone(accountIDs)
+	item.UpdatedAt = models.Now()
+	return nil
+}
+
+// SetPlaidItemStatus records whether a Plaid item can be synced, and when it last synced
+func (s *MemoryStore) SetPlaidItemStatus(ctx context.Context, id string, status models.PlaidItemStatus, syncedAt *time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	item, exists := s.tenant().plaidItems[id]
+	if !exists {
+		return ErrNotFound
+	}
+	item.Status = status
+	if syncedAt != nil {
+		synced := syncedAt.UTC()
+		item.LastSyncedAt = &synced
+	}
+	item.UpdatedAt = models.Now()
+	return nil
+}
+
+// DeletePlaidItem deletes a Plaid item, and with it the stored access token
+func (s *MemoryStore) DeletePlaidItem(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if _, exists := s.tenant().plaidItems[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.tenant().plaidItems, id)
+	return nil
+}
+
+// GetPlaidItemUserID returns the user who linked a Plaid item, across users
+func (s *MemoryStore) GetPlaidItemUserID(ctx context.Context, id string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for userID, tenant := range s.tenants {
+		if _, exists := tenant.plaidItems[id]; exists {
+			return userID, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// YouTube Source operations
+
+// GetAllYouTubeSources returns all YouTube sources
+func (s *MemoryStore) GetAllYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.youtubeSourcesWhere(func(*models.YouTubeSource) bool { return true }), nil
+}
+
+// GetEnabledYouTubeSources returns the sources that are enabled, newest first
+func (s *MemoryStore) GetEnabledYouTubeSources(ctx context.Context) ([]*models.YouTubeSource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.youtubeSourcesWhere(func(src *models.YouTubeSource) bool { return src.Enabled }), nil
+}
+
+// GetYouTubeSourcesByType returns the sources of a type, newest first
+func (s *MemoryStore) GetYouTubeSourcesByType(ctx context.Context, sourceType models.YouTubeSourceType) ([]*models.YouTubeSource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.youtubeSourcesWhere(func(src *models.YouTubeSource) bool { return src.Type == sourceType }), nil
+}
+
+// youtubeSourcesWhere returns copies of the sources matching match, newest first like the SQL
+// stores. Callers must hold s.mu.
+func (s *MemoryStore) youtubeSourcesWhere(match func(*models.YouTubeSource) bool) []*models.YouTubeSource {
+	sources := make([]*models.YouTubeSource, 0)
+	for _, src := range s.tenant().youtubeSources {
+		if match(src) {
+			sources = append(sources, src)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if !sources[i].CreatedAt.Equal(sources[j].CreatedAt) {
+			return sources[i].CreatedAt.After(sources[j].CreatedAt)
+		}
+		return sources[i].ID < sources[j].ID
+	})
+	return cloneAll(sources, cloneYouTubeSource)
+}
+
+// GetYouTubeSourceByID returns a YouTube source by ID
+func (s *MemoryStore) GetYouTubeSourceByID(ctx context.Context, id string) (*models.YouTubeSource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	source, exists := s.tenant().youtubeSources[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneYouTubeSource(source), nil
+}
+
+// CreateOrUpdateYouTubeSource creates or updates a YouTube source. A source another user owns
+// is left untouched.
+func (s *MemoryStore) CreateOrUpdateYouTubeSource(ctx context.Context, source *models.YouTubeSource) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, source.ID, func(t *memoryTenant) map[string]*models.YouTubeSource { return t.youtubeSources }) {
+		return nil
+	}
+	s.tenant().youtubeSources[source.ID] = cloneYouTubeSource(source)
+	return nil
+}
+
+// DeleteYouTubeSource deletes a YouTube source by ID
+func (s *MemoryStore) DeleteYouTubeSource(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if _, exists := s.tenant().youtubeSources[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.tenant().youtubeSources, id)
+	return nil
+}
+
+// YouTube quota operations
+
+// GetYouTubeQuotaUsage returns the units used on day, 0 if none were recorded
+func (s *MemoryStore) GetYouTubeQuotaUsage(ctx context.Context, day string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.youtubeQuota[day], nil
+}
+
+// AddYouTubeQuotaUsage adds units to the usage of day and returns the new total
+func (s *MemoryStore) AddYouTubeQuotaUsage(ctx context.Context, day string, units int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	s.youtubeQuota[day] += units
+	return s.youtubeQuota[day], nil
+}
+
+// Video Transcript operations
+
+// CreateOrUpdateTranscript creates or updates a video transcript. A transcript another user
+// owns is left untouched.
+func (s *MemoryStore) CreateOrUpdateTranscript(ctx context.Context, transcript *models.VideoTranscript) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, transcript.ID, func(t *memoryTenant) map[string]*models.VideoTranscript { return t.transcripts }) {
+		return nil
+	}
+	s.tenant().transcripts[transcript.ID] = cloneTranscript(transcript)
+	return nil
+}
+
+// GetTranscriptByID returns a transcript by ID
+func (s *MemoryStore) GetTranscriptByID(ctx context.Context, id string) (*models.VideoTranscript, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transcript, exists := s.tenant().transcripts[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneTranscript(transcript), nil
+}
+
+// GetTranscriptsByVideoID returns transcripts for a specific video ID
+func (s *MemoryStore) GetTranscriptsByVideoID(ctx context.Context, videoID string) ([]*models.VideoTranscript, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for _, t := range s.tenant().transcripts {
+		if t.VideoID == videoID {
+			transcripts = append(transcripts, t)
+		}
+	}
+	sortTranscriptsNewestFirst(transcripts)
+	return cloneAll(transcripts, cloneTranscript), nil
+}
+
+// GetLatestTranscriptByVideoID returns the newest transcript stored for a video
+func (s *MemoryStore) GetLatestTranscriptByVideoID(ctx context.Context, videoID string) (*models.VideoTranscript, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for _, t := range s.tenant().transcripts {
+		if t.VideoID == videoID {
+			transcripts = append(transcripts, t)
+		}
+	}
+	if len(transcripts) == 0 {
+		return nil, ErrNotFound
+	}
+	sortTranscriptsNewestFirst(transcripts)
+	return cloneTranscript(transcripts[0]), nil
+}
+
+// sortTranscriptsNewestFirst orders transcripts by creation time, newest first, then by ID
+func sortTranscriptsNewestFirst(transcripts []*models.VideoTranscript) {
+	sort.Slice(transcripts, func(i, j int) bool {
+		if !transcripts[i].CreatedAt.Equal(transcripts[j].CreatedAt) {
+			return transcripts[i].CreatedAt.After(transcripts[j].CreatedAt)
+		}
+		return transcripts[i].ID < transcripts[j].ID
+	})
+}
+
+// GetTranscriptsBySourceID returns up to limit transcripts for a source, newest first
+func (s *MemoryStore) GetTranscriptsBySourceID(ctx context.Context, sourceID string, limit int) ([]*models.VideoTranscript, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transcripts := make([]*models.VideoTranscript, 0)
+	for _, t := range s.tenant().transcripts {
+		if t.SourceID == sourceID {
+			transcripts = append(transcripts, t)
+		}
+	}
+	sortTranscriptsNewestFirst(transcripts)
+	if limit > 0 && len(transcripts) > limit {
+		transcripts = transcripts[:limit]
+	}
+	return cloneAll(transcripts, cloneTranscript), nil
+}
+
+// ListTranscripts returns a page of all transcripts ordered by ID
+func (s *MemoryStore) ListTranscripts(ctx context.Context, opts ListOptions) ([]*models.VideoTranscript, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return pageByID(s.tenant().transcripts, opts, cloneTranscript), nil
+}
+
+// SearchTranscripts returns the transcripts containing every word of query, newest first
+func (s *MemoryStore) SearchTranscripts(ctx context.Context, query string, limit int) ([]*models.TranscriptSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := searchTerms(query)
+	matches := make([]*models.VideoTranscript, 0)
+	for _, t := range s.tenant().transcripts {
+		if matchesTerms(t, terms) {
+			matches = append(matches, t)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	results := make([]*models.TranscriptSearchResult, len(matches))
+	for i, t := range matches {
+		var sourceName string
+		if source, exists := s.tenant().youtubeSources[t.SourceID]; exists {
+			sourceName = source.Name
+		}
+		results[i] = newSearchResult(t, sourceName, terms)
+	}
+	return results, nil
+}
+
+// PruneTranscriptsOlderThan clears the text of, or deletes, transcripts created before cutoff.
+// Deleting mirrors the SQL schema: the transcript's analyses and their recommendations go with it,
+// and executions drop their references to all three.
+func (s *MemoryStore) PruneTranscriptsOlderThan(ctx context.Context, cutoff time.Time, keepMetadata bool) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	tenant := s.tenant()
+	// Transcripts of executions completed within the retention window are kept
+	recent := make(map[string]bool)
+	for _, e := range tenant.executions {
+		if e.TranscriptID != "" && !e.CompletedAt.IsZero() && !e.CompletedAt.Before(cutoff) {
+			recent[e.TranscriptID] = true
+		}
+	}
+
+	pruned := 0
+	deleted := make(map[string]bool) // IDs of deleted transcripts, analyses and recommendations
+	for id, t := range tenant.transcripts {
+		if !t.CreatedAt.Before(cutoff) || recent[id] {
+			continue
+		}
+		if keepMetadata {
+			if t.Text == "" {
+				continue
+			}
+			t.Text = ""
+			pruned++
+			continue
+		}
+		delete(tenant.transcripts, id)
+		deleted[id] = true
+		pruned++
+		for analysisID, analysis := range tenant.marketAnalyses {
+			if analysis.TranscriptID != id {
+				continue
+			}
+			delete(tenant.marketAnalyses, analysisID)
+			deleted[analysisID] = true
+			for recID, rec := range tenant.recommendations {
+				if rec.AnalysisID == analysisID {
+					delete(tenant.recommendations, recID)
+					deleted[recID] = true
+				}
+			}
+		}
+	}
+
+	for _, e := range tenant.executions {
+		if deleted[e.TranscriptID] {
+			e.TranscriptID = ""
+		}
+		if deleted[e.AnalysisID] {
+			e.AnalysisID = ""
+		}
+		if deleted[e.RecommendationID] {
+			e.RecommendationID = ""
+		}
+	}
+	return pruned, nil
+}
+
+// Market Analysis operations
+
+// CreateOrUpdateMarketAnalysis creates or updates a market analysis. An analysis another user
+// owns is left untouched.
+func (s *MemoryStore) CreateOrUpdateMarketAnalysis(ctx context.Context, analysis *models.MarketAnalysis) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, analysis.ID, func(t *memoryTenant) map[string]*models.MarketAnalysis { return t.marketAnalyses }) {
+		return nil
+	}
+	s.tenant().marketAnalyses[analysis.ID] = cloneMarketAnalysis(analysis)
+	return nil
+}
+
+// GetMarketAnalysisByID returns a market analysis by ID
+func (s *MemoryStore) GetMarketAnalysisByID(ctx context.Context, id string) (*models.MarketAnalysis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analysis, exists := s.tenant().marketAnalyses[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneMarketAnalysis(analysis), nil
+}
+
+// GetMarketAnalysesByTranscriptID returns market analyses for a specific transcript ID, newest first
+func (s *MemoryStore) GetMarketAnalysesByTranscriptID(ctx context.Context, transcriptID string) ([]*models.MarketAnalysis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analyses := make([]*models.MarketAnalysis, 0)
+	for _, a := range s.tenant().marketAnalyses {
+		if a.TranscriptID == transcriptID {
+			analyses = append(analyses, a)
+		}
+	}
+	sort.Slice(analyses, func(i, j int) bool {
+		if !analyses[i].CreatedAt.Equal(analyses[j].CreatedAt) {
+			return analyses[i].CreatedAt.After(analyses[j].CreatedAt)
+		}
+		return analyses[i].ID < analyses[j].ID
+	})
+	return cloneAll(analyses, cloneMarketAnalysis), nil
+}
+
+// ListMarketAnalyses returns a page of all market analyses ordered by ID
+func (s *MemoryStore) ListMarketAnalyses(ctx context.Context, opts ListOptions) ([]*models.MarketAnalysis, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return pageByID(s.tenant().marketAnalyses, opts, cloneMarketAnalysis), nil
+}
+
+// Recommendation operations
+
+// CreateOrUpdateRecommendation creates or updates a recommendation. A recommendation another
+// user owns is left untouched.
+func (s *MemoryStore) CreateOrUpdateRecommendation(ctx context.Context, recommendation *models.Recommendation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, recommendation.ID, func(t *memoryTenant) map[string]*models.Recommendation { return t.recommendations }) {
+		return nil
+	}
+	s.tenant().recommendations[recommendation.ID] = cloneRecommendation(recommendation)
+	return nil
+}
+
+// GetRecommendationByID returns a recommendation by ID
+func (s *MemoryStore) GetRecommendationByID(ctx context.Context, id string) (*models.Recommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recommendation, exists := s.tenant().recommendations[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneRecommendation(recommendation), nil
+}
+
+// GetRecommendationsByAnalysisID returns recommendations for a specific analysis ID, newest first
+func (s *MemoryStore) GetRecommendationsByAnalysisID(ctx context.Context, analysisID string) ([]*models.Recommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recommendations := make([]*models.Recommendation, 0)
+	for _, r := range s.tenant().recommendations {
+		if r.AnalysisID == analysisID {
+			recommendations = append(recommendations, r)
+		}
+	}
+	sort.Slice(recommendations, func(i, j int) bool {
+		if !recommendations[i].CreatedAt.Equal(recommendations[j].CreatedAt) {
+			return recommendations[i].CreatedAt.After(recommendations[j].CreatedAt)
+		}
+		return recommendations[i].ID < recommendations[j].ID
+	})
+	return cloneAll(recommendations, cloneRecommendation), nil
+}
+
+// ListRecommendations returns a page of all recommendations ordered by ID
+func (s *MemoryStore) ListRecommendations(ctx context.Context, opts ListOptions) ([]*models.Recommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return pageByID(s.tenant().recommendations, opts, cloneRecommendation), nil
+}
+
+// Workflow Execution operations
+
+// CreateOrUpdateWorkflowExecution creates or updates a workflow execution. Like the SQL
+// stores, an update keeps the stored creation time, a new execution without one is created
+// now, and an execution another user owns is left untouched.
+func (s *MemoryStore) CreateOrUpdateWorkflowExecution(ctx context.Context, execution *models.WorkflowExecution) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if ownedByOtherUser(s, execution.ID, func(t *memoryTenant) map[string]*models.WorkflowExecution { return t.executions }) {
+		return nil
+	}
+	stored := cloneWorkflowExecution(execution)
+	if existing, ok := s.tenant().executions[execution.ID]; ok {
+		stored.CreatedAt = existing.CreatedAt
+	} else {
+		stored.CreatedAt = executionCreatedAt(execution)
+	}
+	s.tenant().executions[execution.ID] = stored
+	return nil
+}
+
+// TryClaimVideoExecution saves execution as the one processing its video unless another
+// execution holds the video. Any pending, processing or completed execution of the video
+// holds it; the newest is reported.
+func (s *MemoryStore) TryClaimVideoExecution(ctx context.Context, execution *models.WorkflowExecution) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if execution.VideoID == "" {
+		return "", false, fmt.Errorf("execution %s has no video ID to claim", execution.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ownedByOtherUser(s, execution.ID, func(t *memoryTenant) map[string]*models.WorkflowExecution { return t.executions }) {
+		return "", false, fmt.Errorf("execution %s belongs to another user", execution.ID)
+	}
+	holders := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if e.VideoID != execution.VideoID {
+			continue
+		}
+		switch e.Status {
+		case models.WorkflowStatusPending, models.WorkflowStatusProcessing, models.WorkflowStatusCompleted:
+			holders = append(holders, e)
+		}
+	}
+	if len(holders) > 0 {
+		sortExecutionsNewestFirst(holders)
+		return holders[0].ID, false, nil
+	}
+
+	defer s.changed()
+	stored := cloneWorkflowExecution(execution)
+	stored.CreatedAt = executionCreatedAt(execution)
+	s.tenant().executions[execution.ID] = stored
+	return execution.ID, true, nil
+}
+
+// GetWorkflowExecutionByID returns a workflow execution by ID
+func (s *MemoryStore) GetWorkflowExecutionByID(ctx context.Context, id string) (*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	execution, exists := s.tenant().executions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return cloneWorkflowExecution(execution), nil
+}
+
+// GetAllWorkflowExecutions returns all workflow executions, newest first
+func (s *MemoryStore) GetAllWorkflowExecutions(ctx context.Context) ([]*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0, len(s.tenant().executions))
+	for _, e := range s.tenant().executions {
+		executions = append(executions, e)
+	}
+	sortExecutionsNewestFirst(executions)
+	return cloneAll(executions, cloneWorkflowExecution), nil
+}
+
+// sortExecutionsNewestFirst orders executions by creation time, newest first, then by ID
+func sortExecutionsNewestFirst(executions []*models.WorkflowExecution) {
+	sort.Slice(executions, func(i, j int) bool {
+		if !executions[i].CreatedAt.Equal(executions[j].CreatedAt) {
+			return executions[i].CreatedAt.After(executions[j].CreatedAt)
+		}
+		return executions[i].ID < executions[j].ID
+	})
+}
+
+// ListWorkflowExecutions returns workflow executions matching the filter, newest first unless
+// the filter sorts them otherwise
+func (s *MemoryStore) ListWorkflowExecutions(ctx context.Context, filter ExecutionFilter) ([]*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if filter.matches(e) {
+			executions = append(executions, e)
+		}
+	}
+
+	sorted, err := executionSortFields.sort(executions, filter.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if !sorted {
+		sortExecutionsNewestFirst(executions)
+	}
+	if filter.Limit > 0 && len(executions) > filter.Limit {
+		executions = executions[:filter.Limit]
+	}
+	return cloneAll(executions, cloneWorkflowExecution), nil
+}
+
+// PageWorkflowExecutions returns a page of executions matching filter, newest first, after cursor
+func (s *MemoryStore) PageWorkflowExecutions(ctx context.Context, filter ExecutionFilter, cursor string) ([]*models.WorkflowExecution, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	if filter.Sort.Field != "" {
+		return nil, "", errSortedExecutionPage
+	}
+	var after *executionCursor
+	if cursor != "" {
+		parsed, err := parseExecutionCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &parsed
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if filter.matches(e) && (after == nil || after.before(e)) {
+			executions = append(executions, e)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		if !executions[i].CreatedAt.Equal(executions[j].CreatedAt) {
+			return executions[i].CreatedAt.After(executions[j].CreatedAt)
+		}
+		return executions[i].ID > executions[j].ID
+	})
+	if filter.Limit > 0 && len(executions) > filter.Limit+1 {
+		executions = executions[:filter.Limit+1]
+	}
+	executions, next := filter.nextPage(executions)
+	return cloneAll(executions, cloneWorkflowExecution), next, nil
+}
+
+// CountWorkflowExecutions returns the number of executions matching filter, ignoring its Limit
+func (s *MemoryStore) CountWorkflowExecutions(ctx context.Context, filter ExecutionFilter) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, e := range s.tenant().executions {
+		if filter.matches(e) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetCompletedWorkflowExecutionsSince returns completed executions with a recommendation that
+// completed after since, newest first
+func (s *MemoryStore) GetCompletedWorkflowExecutionsSince(ctx context.Context, since time.Time, limit int) ([]*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if e.Status == models.WorkflowStatusCompleted && e.RecommendationID != "" && e.CompletedAt.After(since) {
+			executions = append(executions, e)
+		}
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		if !executions[i].CompletedAt.Equal(executions[j].CompletedAt) {
+			return executions[i].CompletedAt.After(executions[j].CompletedAt)
+		}
+		return executions[i].ID < executions[j].ID
+	})
+	if limit > 0 && len(executions) > limit {
+		executions = executions[:limit]
+	}
+	return cloneAll(executions, cloneWorkflowExecution), nil
+}
+
+// GetWorkflowExecutionsBySourceID returns workflow executions for a specific source ID, newest first
+func (s *MemoryStore) GetWorkflowExecutionsBySourceID(ctx context.Context, sourceID string) ([]*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if e.SourceID == sourceID {
+			executions = append(executions, e)
+		}
+	}
+	sortExecutionsNewestFirst(executions)
+	return cloneAll(executions, cloneWorkflowExecution), nil
+}
+
+// GetWorkflowExecutionsByVideoID returns workflow executions for a specific video ID, newest first
+func (s *MemoryStore) GetWorkflowExecutionsByVideoID(ctx context.Context, videoID string) ([]*models.WorkflowExecution, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	executions := make([]*models.WorkflowExecution, 0)
+	for _, e := range s.tenant().executions {
+		if e.VideoID == videoID {
+			executions = append(executions, e)
+		}
+	}
+	sortExecutionsNewestFirst(executions)
+	return cloneAll(executions, cloneWorkflowExecution), nil
+}
+
+// DeleteWorkflowExecution deletes an execution under one lock, optionally with its
+// artifacts, and drops its ID from aggregated recommendations
+func (s *MemoryStore) DeleteWorkflowExecution(ctx context.Context, id string, cascade bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	tenant := s.tenant()
+	execution, exists := tenant.executions[id]
+	if !exists {
+		return ErrNotFound
+	}
+	delete(tenant.executions, id)
+	delete(tenant.executionEvents, id)
+
+	if cascade {
+		// Keep artifacts that another execution still references
+		referenced := func(matches func(e *models.WorkflowExecution) bool) bool {
+			for _, e := range tenant.executions {
+				if matches(e) {
+					return true
+				}
+			}
+			return false
+		}
+		if execution.RecommendationID != "" && !referenced(func(e *models.WorkflowExecution) bool { return e.RecommendationID == execution.RecommendationID }) {
+			delete(tenant.recommendations, execution.RecommendationID)
+		}
+		if execution.AnalysisID != "" && !referenced(func(e *models.WorkflowExecution) bool { return e.AnalysisID == execution.AnalysisID }) {
+			delete(tenant.marketAnalyses, execution.AnalysisID)
+		}
+		if execution.TranscriptID != "" && !referenced(func(e *models.WorkflowExecution) bool { return e.TranscriptID == execution.TranscriptID }) {
+			delete(tenant.transcripts, execution.TranscriptID)
+		}
+	}
+
+	for _, rec := range tenant.aggregatedRecs {
+		if !slices.Contains(rec.ExecutionIDs, id) {
+			continue
+		}
+		rec.ExecutionIDs = slices.DeleteFunc(rec.ExecutionIDs, func(executionID string) bool {
+			return executionID == id
+		})
+	}
+	return nil
+}
+
+// PruneWorkflowExecutions deletes old executions with status, keeping the newest of each source
+func (s *MemoryStore) PruneWorkflowExecutions(ctx context.Context, status models.WorkflowExecutionStatus, olderThan time.Time, keep int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	bySource := make(map[string][]*models.WorkflowExecution)
+	for _, e := range s.tenant().executions {
+		if e.Status == status {
+			bySource[e.SourceID] = append(bySource[e.SourceID], e)
+		}
+	}
+	ids := make([]string, 0)
+	for _, executions := range bySource {
+		sort.Slice(executions, func(i, j int) bool {
+			ti, tj := executionStart(executions[i]), executionStart(executions[j])
+			if !ti.Equal(tj) {
+				return ti.After(tj)
+			}
+			return executions[i].ID > executions[j].ID
+		})
+		for _, e := range executions[min(max(keep, 0), len(executions)):] {
+			if olderThan.IsZero() || executionStart(e).Before(olderThan) {
+				ids = append(ids, e.ID)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	return deleteWorkflowExecutions(ctx, s, ids)
+}
+
+// executionStart returns when an execution started, or when it was created if that is unknown
+func executionStart(e *models.WorkflowExecution) time.Time {
+	if e.StartedAt.IsZero() {
+		return e.CreatedAt
+	}
+	return e.StartedAt
+}
+
+// AddWorkflowExecutionEvent appends an event to an execution's event log
+func (s *MemoryStore) AddWorkflowExecutionEvent(ctx context.Context, event *models.WorkflowExecutionEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	if _, exists := s.tenant().executions[event.ExecutionID]; !exists {
+		return ErrNotFound
+	}
+	s.tenant().executionEvents[event.ExecutionID] = append(s.tenant().executionEvents[event.ExecutionID], cloneWorkflowExecutionEvent(event))
+	return nil
+}
+
+// GetWorkflowExecutionEvents returns an execution's events, oldest first
+func (s *MemoryStore) GetWorkflowExecutionEvents(ctx context.Context, executionID string) ([]*models.WorkflowExecutionEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return cloneAll(slices.Clone(s.tenant().executionEvents[executionID]), cloneWorkflowExecutionEvent), nil
+}
+
+// GetRecommendationSummaryData returns executions completed after since that have a stored
+// recommendation, joined with that recommendation and their analysis, newest first
+func (s *MemoryStore) GetRecommendationSummaryData(ctx context.Context, since time.Time) ([]*models.RecommendationSummaryRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenant := s.tenant()
+	rows := make([]*models.RecommendationSummaryRow, 0)
+	for _, e := range tenant.executions {
+		if e.Status != models.WorkflowStatusCompleted || !e.CompletedAt.After(since) {
+			continue
+		}
+		rec, exists := tenant.recommendations[e.RecommendationID]
+		if !exists {
+			continue
+		}
+		row := &models.RecommendationSummaryRow{
+			ExecutionID: e.ID,
+			VideoID:     e.VideoID,
+			VideoTitle:  e.VideoTitle,
+			CompletedAt: e.CompletedAt,
+			Action:      rec.Action,
+			Confidence:  rec.Confidence,
+		}
+		if analysis, exists := tenant.marketAnalyses[e.AnalysisID]; exists {
+			row.Condition = analysis.Conditions
+			row.HasAnalysis = true
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].CompletedAt.Equal(rows[j].CompletedAt) {
+			return rows[i].CompletedAt.After(rows[j].CompletedAt)
+		}
+		return rows[i].ExecutionID < rows[j].ExecutionID
+	})
+	return rows, nil
+}
+
+// GetAggregatedRecommendations returns up to limit aggregated recommendations, newest first.
+// A limit of zero or less returns the whole history.
+func (s *MemoryStore) GetAggregatedRecommendations(ctx context.Context, limit int) ([]*models.AggregatedRecommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenant := s.tenant()
+	count := len(tenant.aggregatedRecs)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	recs := make([]*models.AggregatedRecommendation, 0, count)
+	for i := len(tenant.aggregatedRecs) - 1; i >= 0 && len(recs) < count; i-- {
+		recs = append(recs, cloneAggregatedRecommendation(tenant.aggregatedRecs[i]))
+	}
+	return recs, nil
+}
+
+// GetLatestAggregatedRecommendation returns the most recent aggregated recommendation, or
+// ErrNotFound if none has been generated yet
+func (s *MemoryStore) GetLatestAggregatedRecommendation(ctx context.Context) (*models.AggregatedRecommendation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.tenant().aggregatedRecs) == 0 {
+		return nil, ErrNotFound
+	}
+	return cloneAggregatedRecommendation(s.tenant().aggregatedRecs[len(s.tenant().aggregatedRecs)-1]), nil
+}
+
+// CreateOrUpdateAggregatedRecommendation replaces the recommendation with the same ID in place,
+// or appends it as the latest one. A recommendation another user owns is left untouched.
+func (s *MemoryStore) CreateOrUpdateAggregatedRecommendation(ctx context.Context, rec *models.AggregatedRecommendation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.changed()
+
+	for userID, tenant := range s.tenants {
+		owned := slices.ContainsFunc(tenant.aggregatedRecs, func(r *models.AggregatedRecommendation) bool { return r.ID == rec.ID })
+		if owned && userID != s.userID {
+			return nil
+		}
+	}
+	if rec.GeneratedAt.IsZero() {
+		rec.GeneratedAt = models.Now()
+	}
+	tenant := s.tenant()
+	stored := cloneAggregatedRecommendation(rec)
+	for i, existing := range tenant.aggregatedRecs {
+		if existing.ID == rec.ID {
+			tenant.aggregatedRecs[i] = stored
+			return nil
+		}
+	}
+	tenant.aggregatedRecs = append(tenant.aggregatedRecs, stored)
+	if len(tenant.aggregatedRecs) > maxMemoryAggregatedRecs {
+		tenant.aggregatedRecs = tenant.aggregatedRecs[len(tenant.aggregatedRecs)-maxMemoryAggregatedRecs:]
+	}
+	return nil
+}
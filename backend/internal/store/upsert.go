@@ -0,0 +1,162 @@
+package store
+
+import "0xnetworth/backend/internal/models"
+
+// UpsertResult says what a create-or-update did to the stored record
+type UpsertResult int
+
+const (
+	// UpsertCreated means no record had the ID, so one was inserted
+	UpsertCreated UpsertResult = iota + 1
+	// UpsertUpdated means an existing record's values changed
+	UpsertUpdated
+	// UpsertUnchanged means an existing record was rewritten with the same values; only sync
+	// timestamps moved
+	UpsertUnchanged
+	// UpsertSkipped means the ID belongs to another user, so nothing was written
+	UpsertSkipped
+)
+
+// String returns the result's name
+func (r UpsertResult) String() string {
+	switch r {
+	case UpsertCreated:
+		return "created"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertUnchanged:
+		return "unchanged"
+	case UpsertSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// UpsertCounts tallies the results of a series of upserts
+type UpsertCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped,omitempty"`
+}
+
+// Add counts one result
+func (c *UpsertCounts) Add(result UpsertResult) {
+	switch result {
+	case UpsertCreated:
+		c.Created++
+	case UpsertUpdated:
+		c.Updated++
+	case UpsertUnchanged:
+		c.Unchanged++
+	case UpsertSkipped:
+		c.Skipped++
+	}
+}
+
+// Merge adds other's counts to c
+func (c *UpsertCounts) Merge(other UpsertCounts) {
+	c.Created += other.Created
+	c.Updated += other.Updated
+	c.Unchanged += other.Unchanged
+	c.Skipped += other.Skipped
+}
+
+// Written returns how many records were written
+func (c UpsertCounts) Written() int {
+	return c.Created + c.Updated + c.Unchanged
+}
+
+// upsertResult classifies writing next over prev, the record stored before the write or nil
+// if there was none
+func upsertResult[T any](prev, next *T, same func(prev, next *T) bool) UpsertResult {
+	switch {
+	case prev == nil:
+		return UpsertCreated
+	case same(prev, next):
+		return UpsertUnchanged
+	default:
+		return UpsertUpdated
+	}
+}
+
+// countInvestmentUpserts classifies a batch of investment upserts from the investments stored
+// before it. written reports, per investment, whether the upsert wrote a row; it writes none
+// for an ID owned by another user.
+func countInvestmentUpserts(existing, investments []*models.Investment, written []bool) UpsertCounts {
+	prev := make(map[string]*models.Investment, len(existing))
+	for _, inv := range existing {
+		prev[inv.ID] = inv
+	}
+	var counts UpsertCounts
+	for i, investment := range investments {
+		if !written[i] {
+			counts.Add(UpsertSkipped)
+			continue
+		}
+		counts.Add(upsertResult(prev[investment.ID], investment, sameInvestment))
+		// A later duplicate of the ID compares against this write
+		prev[investment.ID] = investment
+	}
+	return counts
+}
+
+// samePortfolio reports whether upserting next over prev leaves its values as they were.
+// The sync time is ignored, as is metadata that next leaves unset and the upsert preserves.
+func samePortfolio(prev, next *models.Portfolio) bool {
+	return prev.Platform == next.Platform &&
+		prev.Name == next.Name &&
+		prev.Type == next.Type &&
+		(next.TaxTreatment == "" || next.TaxTreatment == prev.TaxTreatment) &&
+		(next.Custodian == "" || next.Custodian == prev.Custodian) &&
+		(next.DisplayOrder == nil || sameValue(prev.DisplayOrder, next.DisplayOrder))
+}
+
+// sameAccount reports whether upserting next over prev leaves its values as they were,
+// ignoring the sync time
+func sameAccount(prev, next *models.Account) bool {
+	return prev.Platform == next.Platform &&
+		prev.PortfolioID == next.PortfolioID &&
+		prev.Name == next.Name &&
+		prev.Currency == next.Currency &&
+		prev.Type == next.Type &&
+		prev.Available == next.Available &&
+		prev.Hold == next.Hold &&
+		prev.Active == next.Active
+}
+
+// sameInvestment reports whether upserting next over prev leaves its values as they were.
+// The update time is ignored, as are cost basis fields that next leaves unset and the upsert
+// preserves. Reactivating an inactive investment is a change.
+func sameInvestment(prev, next *models.Investment) bool {
+	return prev.AccountID == next.AccountID &&
+		prev.Platform == next.Platform &&
+		prev.Symbol == next.Symbol &&
+		prev.Name == next.Name &&
+		prev.Quantity == next.Quantity &&
+		prev.Value == next.Value &&
+		prev.Price == next.Price &&
+		prev.Currency == next.Currency &&
+		sameValue(prev.NativeValue, next.NativeValue) &&
+		prev.NativeCurrency == next.NativeCurrency &&
+		prev.AssetType == next.AssetType &&
+		prev.Staked == next.Staked &&
+		prev.StalePrice == next.StalePrice &&
+		prev.IsDust == next.IsDust &&
+		sameValue(prev.PriceChange24hPct, next.PriceChange24hPct) &&
+		sameValue(prev.ValueChange24h, next.ValueChange24h) &&
+		prev.Active &&
+		(next.CostBasis == nil || sameValue(prev.CostBasis, next.CostBasis)) &&
+		(next.AverageBuyPrice == nil || sameValue(prev.AverageBuyPrice, next.AverageBuyPrice)) &&
+		(next.FirstAcquiredAt == nil || (prev.FirstAcquiredAt != nil && prev.FirstAcquiredAt.Equal(*next.FirstAcquiredAt))) &&
+		(next.UnrealizedGain == nil || sameValue(prev.UnrealizedGain, next.UnrealizedGain))
+}
+
+// sameValue reports whether two optional values are both nil or both set to the same value
+func sameValue[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
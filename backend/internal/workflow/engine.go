@@ -1,14 +1,18 @@
 package workflow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	workflowclient "0xnetworth/backend/internal/integrations/workflow"
+	"0xnetworth/backend/internal/integrations/youtube"
 	"0xnetworth/backend/internal/models"
 	"0xnetworth/backend/internal/store"
 )
@@ -17,48 +21,74 @@ import (
 type Engine struct {
 	store         store.Store
 	workflowClient *workflowclient.Client
+	// keepTranscriptHistory stores a new transcript on every run of a video instead of
+	// updating the video's existing transcript
+	keepTranscriptHistory bool
+	// youtubeClient looks up what the workflow service does not report about a video, such as
+	// its duration; nil if YOUTUBE_API_KEY is not set
+	youtubeClient *youtube.Client
 }
 
-// NewEngine creates a new workflow engine
-func NewEngine(store store.Store, workflowClient *workflowclient.Client) *Engine {
+// NewEngine creates a new workflow engine. youtubeClient is nil if YOUTUBE_API_KEY is not set.
+func NewEngine(store store.Store, workflowClient *workflowclient.Client, youtubeClient *youtube.Client) *Engine {
 	return &Engine{
-		store:          store,
-		workflowClient: workflowClient,
+		store:                 store,
+		workflowClient:        workflowClient,
+		keepTranscriptHistory: os.Getenv("WORKFLOW_KEEP_TRANSCRIPT_HISTORY") == "true",
+		youtubeClient:         youtubeClient,
 	}
 }
 
+// ForUser returns a copy of the engine that stores executions and their artifacts as userID's
+// and builds the portfolio context from their holdings. A nil engine stays nil.
+func (e *Engine) ForUser(userID string) *Engine {
+	if e == nil {
+		return nil
+	}
+	scoped := *e
+	scoped.store = e.store.ForUser(userID)
+	return &scoped
+}
+
 // ExecuteWorkflow processes a YouTube video through the agentic workflow
-func (e *Engine) ExecuteWorkflow(videoURL string, sourceID string) (*models.WorkflowExecution, error) {
+func (e *Engine) ExecuteWorkflow(ctx context.Context, videoURL string, sourceID string) (*models.WorkflowExecution, error) {
 	// Extract video ID from URL to check for duplicates
 	videoID := extractVideoIDFromURL(videoURL)
 	
-	// Check if this video has already been processed (globally, not just per-source)
-	if videoID != "" {
-		existingExecutions := e.store.GetWorkflowExecutionsByVideoID(videoID)
-		// Check if any existing execution completed successfully
-		for _, existing := range existingExecutions {
-			if existing.Status == models.WorkflowStatusCompleted {
-				log.Printf("Video %s has already been processed (execution %s). Skipping duplicate.", videoID, existing.ID)
-				return existing, fmt.Errorf("video %s has already been processed", videoID)
-			}
-		}
-	}
-	
 	// Create execution record
 	executionID := uuid.New().String()
+	now := models.Now()
 	execution := &models.WorkflowExecution{
 		ID:        executionID,
 		Status:    models.WorkflowStatusProcessing,
 		VideoURL:  videoURL,
+		VideoID:   videoID,
 		SourceID:  sourceID,
-		StartedAt: time.Now().UTC().Format(time.RFC3339),
+		CreatedAt: now,
+		StartedAt: now,
+	}
+	if videoID == "" {
+		if err := e.store.CreateOrUpdateWorkflowExecution(ctx, execution); err != nil {
+			return nil, fmt.Errorf("failed to save workflow execution: %w", err)
+		}
+	} else {
+		// Claim the video (for the user, not just per-source) so that only one of several
+		// concurrent triggers processes it; the others get the execution that did
+		holder, err := e.claimVideo(ctx, execution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save workflow execution: %w", err)
+		}
+		if holder != nil {
+			log.Printf("Video %s is already %s by execution %s. Skipping duplicate.", videoID, claimState(holder), holder.ID)
+			return holder, nil
+		}
 	}
-	e.store.CreateOrUpdateWorkflowExecution(execution)
 
 	log.Printf("Starting workflow execution %s for video: %s", executionID, videoURL)
+	e.recordEvent(ctx, executionID, models.WorkflowEventStarted, videoURL)
 
 	// Build portfolio context from current investments
-	portfolioContext := e.BuildPortfolioContext()
+	portfolioContext := e.BuildPortfolioContext(ctx)
 
 	// Call Python workflow service
 	request := workflowclient.WorkflowRequest{
@@ -70,26 +100,48 @@ func (e *Engine) ExecuteWorkflow(videoURL string, sourceID string) (*models.Work
 	if err != nil {
 		execution.Status = models.WorkflowStatusFailed
 		execution.Error = err.Error()
-		execution.CompletedAt = time.Now().UTC().Format(time.RFC3339)
-		e.store.CreateOrUpdateWorkflowExecution(execution)
+		execution.CompletedAt = models.Now()
+		if saveErr := e.store.CreateOrUpdateWorkflowExecution(context.WithoutCancel(ctx), execution); saveErr != nil {
+			log.Printf("Failed to record failure of workflow execution %s: %v", executionID, saveErr)
+		}
+		e.recordEvent(context.WithoutCancel(ctx), executionID, models.WorkflowEventFailed, err.Error())
 		return execution, fmt.Errorf("workflow service error: %w", err)
 	}
+	e.recordEvent(ctx, executionID, models.WorkflowEventServiceCalled, "")
 
-	// Store transcript
-	transcriptID := uuid.New().String()
+	// Store transcript, replacing the one stored by an earlier run of the same video
 	transcript := &models.VideoTranscript{
-		ID:          transcriptID,
+		ID:          uuid.New().String(),
 		VideoID:     response.Transcript.VideoID,
 		VideoTitle:  response.Transcript.VideoTitle,
 		VideoURL:    videoURL,
 		Text:        response.Transcript.Text,
 		Duration:    response.Transcript.Duration,
 		SourceID:    sourceID,
-		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:   models.Now(),
+	}
+	if transcript.Duration == nil && transcript.VideoID != "" {
+		transcript.Duration = e.videoDuration(ctx, transcript.VideoID)
+	}
+	if !e.keepTranscriptHistory && transcript.VideoID != "" {
+		existing, err := e.store.GetLatestTranscriptByVideoID(ctx, transcript.VideoID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return e.failExecution(ctx, execution, fmt.Errorf("failed to look up existing transcript: %w", err))
+		}
+		if err == nil {
+			transcript.ID = existing.ID
+			transcript.CreatedAt = existing.CreatedAt
+		}
+	}
+	transcriptID := transcript.ID
+	if err := e.store.CreateOrUpdateTranscript(ctx, transcript); err != nil {
+		return e.failExecution(ctx, execution, fmt.Errorf("failed to save transcript: %w", err))
 	}
-	e.store.CreateOrUpdateTranscript(transcript)
 	execution.TranscriptID = transcriptID
-	execution.VideoID = response.Transcript.VideoID
+	e.recordEvent(ctx, executionID, models.WorkflowEventTranscriptStored, transcriptID)
+	if response.Transcript.VideoID != "" {
+		execution.VideoID = response.Transcript.VideoID
+	}
 	execution.VideoTitle = response.Transcript.VideoTitle
 
 	// Store market analysis
@@ -101,10 +153,13 @@ func (e *Engine) ExecuteWorkflow(videoURL string, sourceID string) (*models.Work
 		Trends:       response.MarketAnalysis.Trends,
 		RiskFactors:  response.MarketAnalysis.RiskFactors,
 		Summary:      response.MarketAnalysis.Summary,
-		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:    models.Now(),
+	}
+	if err := e.store.CreateOrUpdateMarketAnalysis(ctx, analysis); err != nil {
+		return e.failExecution(ctx, execution, fmt.Errorf("failed to save market analysis: %w", err))
 	}
-	e.store.CreateOrUpdateMarketAnalysis(analysis)
 	execution.AnalysisID = analysisID
+	e.recordEvent(ctx, executionID, models.WorkflowEventAnalysisStored, analysisID)
 
 	// Store recommendation
 	recommendationID := uuid.New().String()
@@ -123,24 +178,155 @@ func (e *Engine) ExecuteWorkflow(videoURL string, sourceID string) (*models.Work
 		Confidence:      response.Recommendation.Confidence,
 		SuggestedActions: suggestedActions,
 		Summary:        response.Recommendation.Summary,
-		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		CreatedAt:      models.Now(),
+	}
+	if err := e.store.CreateOrUpdateRecommendation(ctx, recommendation); err != nil {
+		return e.failExecution(ctx, execution, fmt.Errorf("failed to save recommendation: %w", err))
 	}
-	e.store.CreateOrUpdateRecommendation(recommendation)
 	execution.RecommendationID = recommendationID
+	e.recordEvent(ctx, executionID, models.WorkflowEventRecommendationStored, recommendationID)
 
 	// Mark execution as completed
 	execution.Status = models.WorkflowStatusCompleted
-	execution.CompletedAt = time.Now().UTC().Format(time.RFC3339)
-	e.store.CreateOrUpdateWorkflowExecution(execution)
+	execution.CompletedAt = models.Now()
+	if err := e.store.CreateOrUpdateWorkflowExecution(ctx, execution); err != nil {
+		err = fmt.Errorf("failed to save workflow execution: %w", err)
+		e.recordEvent(context.WithoutCancel(ctx), executionID, models.WorkflowEventFailed, err.Error())
+		return execution, err
+	}
+	e.recordEvent(ctx, executionID, models.WorkflowEventCompleted, "")
 
 	log.Printf("Workflow execution %s completed successfully", executionID)
 
 	return execution, nil
 }
 
+// videoDuration looks up the duration in seconds of a video on YouTube, returning nil if it
+// cannot be found, such as without an API key or for a live stream that has not ended. A
+// failed lookup is only logged; the transcript is stored without a duration.
+func (e *Engine) videoDuration(ctx context.Context, videoID string) *int {
+	if e.youtubeClient == nil {
+		return nil
+	}
+	details, err := e.youtubeClient.GetVideoDetails(ctx, []string{videoID})
+	if err != nil {
+		log.Printf("Warning: Failed to get the duration of video %s: %v", videoID, err)
+		return nil
+	}
+	if len(details) == 0 || details[0].DurationSeconds == 0 {
+		return nil
+	}
+	return &details[0].DurationSeconds
+}
+
+// failExecution marks an execution as failed, records it along with a failed event and returns
+// the causing error. Both are written even if ctx was cancelled, so the execution is not left
+// processing and its event log shows where it stopped.
+func (e *Engine) failExecution(ctx context.Context, execution *models.WorkflowExecution, err error) (*models.WorkflowExecution, error) {
+	ctx = context.WithoutCancel(ctx)
+	execution.Status = models.WorkflowStatusFailed
+	execution.Error = err.Error()
+	execution.CompletedAt = models.Now()
+	if saveErr := e.store.CreateOrUpdateWorkflowExecution(ctx, execution); saveErr != nil {
+		log.Printf("Failed to record failure of workflow execution %s: %v", execution.ID, saveErr)
+	}
+	e.recordEvent(ctx, execution.ID, models.WorkflowEventFailed, err.Error())
+	return execution, err
+}
+
+// abandonedExecutionAge is how long an execution may hold a video's claim while processing.
+// It is well past the workflow service timeout, so an older one was interrupted, for example
+// by a restart, and will never finish.
+const abandonedExecutionAge = 30 * time.Minute
+
+// maxClaimAttempts bounds how often claimVideo retries after the execution holding a video
+// releases it
+const maxClaimAttempts = 3
+
+// claimVideo saves execution as the one processing its video. If another execution holds the
+// video it is returned instead, unless it was abandoned mid-run, in which case it is marked
+// failed to release the video and the claim is retried.
+func (e *Engine) claimVideo(ctx context.Context, execution *models.WorkflowExecution) (*models.WorkflowExecution, error) {
+	for attempt := 0; attempt < maxClaimAttempts; attempt++ {
+		holderID, claimed, err := e.store.TryClaimVideoExecution(ctx, execution)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+		holder, err := e.store.GetWorkflowExecutionByID(ctx, holderID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue // Deleted since the claim failed, so the video is free again
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case holder.Status == models.WorkflowStatusFailed:
+			continue // Failed since the claim failed, releasing the video
+		case holder.Status == models.WorkflowStatusCompleted, models.Now().Sub(holder.StartedAt) < abandonedExecutionAge:
+			return holder, nil
+		}
+		log.Printf("Execution %s has been %s on video %s since %s, marking it failed",
+			holder.ID, holder.Status, execution.VideoID, holder.StartedAt.Format(time.RFC3339))
+		e.failExecution(ctx, holder, fmt.Errorf("abandoned after %s without completing", abandonedExecutionAge))
+	}
+	return nil, fmt.Errorf("video %s is still claimed after %d attempts", execution.VideoID, maxClaimAttempts)
+}
+
+// claimState describes what the execution holding a video's claim is doing with it
+func claimState(holder *models.WorkflowExecution) string {
+	if holder.Status == models.WorkflowStatusCompleted {
+		return "processed"
+	}
+	return "being processed"
+}
+
+// RecordSkipped records that a source's title filters left a video out, as a skipped execution
+// whose skipped event gives the reason. The execution does not claim the video, so a later
+// run may still process it.
+func (e *Engine) RecordSkipped(ctx context.Context, videoURL, videoTitle, sourceID, reason string) (*models.WorkflowExecution, error) {
+	now := models.Now()
+	execution := &models.WorkflowExecution{
+		ID:          uuid.New().String(),
+		Status:      models.WorkflowStatusSkipped,
+		VideoID:     extractVideoIDFromURL(videoURL),
+		VideoURL:    videoURL,
+		VideoTitle:  videoTitle,
+		SourceID:    sourceID,
+		CreatedAt:   now,
+		StartedAt:   now,
+		CompletedAt: now,
+	}
+	if err := e.store.CreateOrUpdateWorkflowExecution(ctx, execution); err != nil {
+		return nil, fmt.Errorf("failed to save skipped execution: %w", err)
+	}
+	e.recordEvent(ctx, execution.ID, models.WorkflowEventSkipped, reason)
+	return execution, nil
+}
+
+// recordEvent appends an event to the execution's event log. The log is diagnostic, so a
+// failure to write it is logged rather than failing the execution.
+func (e *Engine) recordEvent(ctx context.Context, executionID string, eventType models.WorkflowEventType, detail string) {
+	event := &models.WorkflowExecutionEvent{
+		ExecutionID: executionID,
+		Type:        eventType,
+		Detail:      detail,
+		Timestamp:   models.Now(),
+	}
+	if err := e.store.AddWorkflowExecutionEvent(ctx, event); err != nil {
+		log.Printf("Failed to record %s event of workflow execution %s: %v", eventType, executionID, err)
+	}
+}
+
 // BuildPortfolioContext builds portfolio context from current investments
-func (e *Engine) BuildPortfolioContext() *workflowclient.PortfolioContext {
-	investments := e.store.GetAllInvestments()
+func (e *Engine) BuildPortfolioContext(ctx context.Context) *workflowclient.PortfolioContext {
+	investments, _, err := e.store.GetAllInvestments(ctx, store.ListOptions{}, store.SortOption{}, store.InvestmentFilter{})
+	if err != nil {
+		log.Printf("Failed to load investments for portfolio context: %v", err)
+		return nil
+	}
 	
 	if len(investments) == 0 {
 		return nil
@@ -197,7 +383,7 @@ func extractVideoIDFromURL(url string) string {
 }
 
 // GenerateAggregatedRecommendation generates a consolidated recommendation from the last 10 videos
-func (e *Engine) GenerateAggregatedRecommendation(executions []*models.WorkflowExecution, portfolioContext *workflowclient.PortfolioContext) (*workflowclient.AggregatedRecommendation, error) {
+func (e *Engine) GenerateAggregatedRecommendation(ctx context.Context, executions []*models.WorkflowExecution, portfolioContext *workflowclient.PortfolioContext) (*workflowclient.AggregatedRecommendation, error) {
 	if len(executions) == 0 {
 		return nil, fmt.Errorf("no workflow executions provided")
 	}
@@ -209,8 +395,11 @@ func (e *Engine) GenerateAggregatedRecommendation(executions []*models.WorkflowE
 	for _, exec := range executions {
 		// Get market analysis
 		if exec.AnalysisID != "" {
-			analysis, exists := e.store.GetMarketAnalysisByID(exec.AnalysisID)
-			if exists {
+			analysis, err := e.store.GetMarketAnalysisByID(ctx, exec.AnalysisID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return nil, fmt.Errorf("failed to load market analysis %s: %w", exec.AnalysisID, err)
+			}
+			if err == nil {
 				marketAnalyses = append(marketAnalyses, workflowclient.MarketAnalysis{
 					Conditions:  analysis.Conditions,
 					Trends:      analysis.Trends,
@@ -222,8 +411,11 @@ func (e *Engine) GenerateAggregatedRecommendation(executions []*models.WorkflowE
 		
 		// Get recommendation
 		if exec.RecommendationID != "" {
-			rec, exists := e.store.GetRecommendationByID(exec.RecommendationID)
-			if exists {
+			rec, err := e.store.GetRecommendationByID(ctx, exec.RecommendationID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return nil, fmt.Errorf("failed to load recommendation %s: %w", exec.RecommendationID, err)
+			}
+			if err == nil {
 				suggestedActions := make([]workflowclient.SuggestedAction, len(rec.SuggestedActions))
 				for i, sa := range rec.SuggestedActions {
 					suggestedActions[i] = workflowclient.SuggestedAction{
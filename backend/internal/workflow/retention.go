@@ -0,0 +1,180 @@
+package workflow
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"0xnetworth/backend/internal/models"
+	"0xnetworth/backend/internal/store"
+)
+
+// Transcript retention modes accepted in TRANSCRIPT_RETENTION_MODE
+const (
+	retentionModeTruncate = "truncate"
+	retentionModeDelete   = "delete"
+)
+
+// retentionInterval is how often the retention worker prunes old data
+const retentionInterval = 24 * time.Hour
+
+// DefaultKeepFailedExecutions is how many failed executions of each source are kept when
+// EXECUTION_KEEP_FAILED is not set
+const DefaultKeepFailedExecutions = 50
+
+// RetentionWorker periodically prunes old workflow data:
+//   - transcripts older than TRANSCRIPT_RETENTION_DAYS. By default only their text is cleared,
+//     keeping the transcript and its analysis; with TRANSCRIPT_RETENTION_MODE=delete the
+//     transcripts are deleted.
+//   - failed executions older than EXECUTION_RETENTION_DAYS, except the EXECUTION_KEEP_FAILED
+//     most recent of each source
+type RetentionWorker struct {
+	store          store.Store
+	transcriptDays int // 0 disables transcript retention
+	keepMetadata   bool
+	executionDays  int // 0 disables execution retention
+	keepFailed     int
+	stop           chan struct{}
+	done           chan struct{}
+	stopOnce       sync.Once
+}
+
+// NewRetentionWorker creates a retention worker configured from the environment. Each kind of
+// retention is disabled unless its number of days is set to a positive number.
+func NewRetentionWorker(store store.Store) *RetentionWorker {
+	w := &RetentionWorker{
+		store:        store,
+		keepMetadata: true,
+		keepFailed:   DefaultKeepFailedExecutions,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	w.transcriptDays = retentionDays("TRANSCRIPT_RETENTION_DAYS")
+	switch mode := os.Getenv("TRANSCRIPT_RETENTION_MODE"); mode {
+	case "", retentionModeTruncate:
+	case retentionModeDelete:
+		w.keepMetadata = false
+	default:
+		log.Printf("Warning: invalid TRANSCRIPT_RETENTION_MODE %q (must be %s or %s), transcript retention is disabled",
+			mode, retentionModeTruncate, retentionModeDelete)
+		w.transcriptDays = 0
+	}
+
+	w.executionDays = retentionDays("EXECUTION_RETENTION_DAYS")
+	if keep := os.Getenv("EXECUTION_KEEP_FAILED"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil || n < 0 {
+			log.Printf("Warning: invalid EXECUTION_KEEP_FAILED %q, execution retention is disabled", keep)
+			w.executionDays = 0
+		} else {
+			w.keepFailed = n
+		}
+	}
+	return w
+}
+
+// retentionDays reads a retention period in days from the environment, returning 0 (disabled)
+// if it is unset or invalid
+func retentionDays(key string) int {
+	days := os.Getenv(key)
+	if days == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: invalid %s %q, retention is disabled", key, days)
+		return 0
+	}
+	return n
+}
+
+// Start prunes old data now and then every retentionInterval until Stop is called
+func (w *RetentionWorker) Start() {
+	if w.transcriptDays == 0 && w.executionDays == 0 {
+		close(w.done)
+		return
+	}
+
+	if w.transcriptDays > 0 {
+		log.Printf("Starting transcript retention (%s after %d days)", w.modeName(), w.transcriptDays)
+	}
+	if w.executionDays > 0 {
+		log.Printf("Starting execution retention (failed executions after %d days, keeping %d per source)", w.executionDays, w.keepFailed)
+	}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+		for {
+			w.prune()
+			select {
+			case <-ticker.C:
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the worker, waiting for a run in progress to finish
+func (w *RetentionWorker) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// prune runs one retention pass over every user's records and logs what it pruned
+func (w *RetentionWorker) prune() {
+	ctx := context.Background()
+	userIDs, err := w.store.ListUserIDs(ctx)
+	if err != nil {
+		log.Printf("Retention failed to list users: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		w.pruneUser(ctx, userID)
+	}
+}
+
+// pruneUser runs one retention pass over a user's records. Executions go first, so transcripts
+// they no longer protect can be pruned in the same pass.
+func (w *RetentionWorker) pruneUser(ctx context.Context, userID string) {
+	owner := w.store.ForUser(userID)
+	if w.executionDays > 0 {
+		cutoff := models.Now().AddDate(0, 0, -w.executionDays)
+		pruned, err := owner.PruneWorkflowExecutions(ctx, models.WorkflowStatusFailed, cutoff, w.keepFailed)
+		if err != nil {
+			log.Printf("Execution retention failed for user %s: %v", userID, err)
+		} else {
+			log.Printf("Execution retention: deleted %d failed executions of user %s started before %s", pruned, userID, cutoff.Format(time.RFC3339))
+		}
+	}
+	if w.transcriptDays > 0 {
+		cutoff := models.Now().AddDate(0, 0, -w.transcriptDays)
+		pruned, err := owner.PruneTranscriptsOlderThan(ctx, cutoff, w.keepMetadata)
+		if err != nil {
+			log.Printf("Transcript retention failed for user %s: %v", userID, err)
+		} else {
+			log.Printf("Transcript retention: %s %d transcripts of user %s created before %s", w.prunedVerb(), pruned, userID, cutoff.Format(time.RFC3339))
+		}
+	}
+}
+
+func (w *RetentionWorker) modeName() string {
+	if w.keepMetadata {
+		return retentionModeTruncate
+	}
+	return retentionModeDelete
+}
+
+func (w *RetentionWorker) prunedVerb() string {
+	if w.keepMetadata {
+		return "cleared the text of"
+	}
+	return "deleted"
+}
@@ -1,6 +1,8 @@
 package workflow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -22,25 +24,24 @@ type Scheduler struct {
 	enabled     bool
 	youtubeClient *youtube.Client
 	jobEntries  map[string]cron.EntryID // Maps source ID to cron entry ID
+	// runCtx is the parent of every run's context; Stop cancels it so in-flight runs give up
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
 }
 
-// NewScheduler creates a new workflow scheduler
-func NewScheduler(store store.Store, engine *Engine) *Scheduler {
+// sourceRunTimeout bounds one run of a source: polling YouTube and processing the few videos
+// found, each of which the workflow service may take up to five minutes over
+const sourceRunTimeout = 30 * time.Minute
+
+// NewScheduler creates a new workflow scheduler. youtubeClient is nil if YOUTUBE_API_KEY is not
+// set, which disables channel polling.
+func NewScheduler(store store.Store, engine *Engine, youtubeClient *youtube.Client) *Scheduler {
 	enabled := os.Getenv("WORKFLOW_SCHEDULE_ENABLED")
 	if enabled == "" || enabled == "true" {
 		enabled = "true"
 	}
 	
-	// Initialize YouTube client if API key is provided
-	youtubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
-	var youtubeClient *youtube.Client
-	if youtubeAPIKey != "" {
-		youtubeClient = youtube.NewClient(youtubeAPIKey)
-		log.Println("YouTube API client initialized")
-	} else {
-		log.Println("Warning: YOUTUBE_API_KEY not set. Channel polling will be disabled.")
-	}
-	
+	runCtx, cancelRuns := context.WithCancel(context.Background())
 	s := &Scheduler{
 		store:        store,
 		engine:       engine,
@@ -48,6 +49,8 @@ func NewScheduler(store store.Store, engine *Engine) *Scheduler {
 		enabled:      enabled == "true",
 		youtubeClient: youtubeClient,
 		jobEntries:   make(map[string]cron.EntryID),
+		runCtx:       runCtx,
+		cancelRuns:   cancelRuns,
 	}
 	
 	if s.enabled {
@@ -69,8 +72,10 @@ func (s *Scheduler) Start() {
 	log.Println("Workflow scheduler started")
 }
 
-// Stop stops the scheduler
+// Stop stops the scheduler, cancelling runs in progress, including manually triggered ones,
+// and waiting for scheduled ones to return
 func (s *Scheduler) Stop() {
+	s.cancelRuns()
 	if !s.enabled {
 		return
 	}
@@ -81,15 +86,28 @@ func (s *Scheduler) Stop() {
 	log.Println("Workflow scheduler stopped")
 }
 
-// setupSchedules sets up cron jobs for each enabled YouTube source
+// setupSchedules sets up cron jobs for each enabled YouTube source of every user
 func (s *Scheduler) setupSchedules() {
-	sources := s.store.GetAllYouTubeSources()
+	ctx := context.Background()
+	userIDs, err := s.store.ListUserIDs(ctx)
+	if err != nil {
+		log.Printf("Error loading users for scheduling: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		s.setupUserSchedules(ctx, userID)
+	}
+}
+
+// setupUserSchedules sets up cron jobs for each enabled YouTube source of a user
+func (s *Scheduler) setupUserSchedules(ctx context.Context, userID string) {
+	sources, err := s.store.ForUser(userID).GetEnabledYouTubeSources(ctx)
+	if err != nil {
+		log.Printf("Error loading YouTube sources of user %s for scheduling: %v", userID, err)
+		return
+	}
 	
 	for _, source := range sources {
-		if !source.Enabled {
-			continue
-		}
-		
 		// Use source-specific schedule if available, otherwise use default
 		schedule := source.Schedule
 		if schedule == "" {
@@ -105,7 +123,7 @@ func (s *Scheduler) setupSchedules() {
 		
 		entryID, err := s.cron.AddFunc(schedule, func() {
 			log.Printf("Scheduled execution triggered for source: %s (%s)", source.Name, sourceID)
-			s.executeSource(sourceID, sourceURL)
+			s.runSource(userID, sourceID, sourceURL)
 		})
 		
 		if err != nil {
@@ -118,84 +136,112 @@ func (s *Scheduler) setupSchedules() {
 	}
 }
 
-// executeSource executes workflow for a YouTube source
-func (s *Scheduler) executeSource(sourceID string, sourceURL string) {
+// runSource executes workflow for a YouTube source of userID under a deadline of
+// sourceRunTimeout, cancelled early if the scheduler stops
+func (s *Scheduler) runSource(userID string, sourceID string, sourceURL string) {
+	ctx, cancel := context.WithTimeout(s.runCtx, sourceRunTimeout)
+	defer cancel()
+	s.executeSource(ctx, userID, sourceID, sourceURL)
+}
+
+// executeSource executes workflow for a YouTube source as its owner, userID, so the executions
+// and their artifacts belong to them
+func (s *Scheduler) executeSource(ctx context.Context, userID string, sourceID string, sourceURL string) {
 	log.Printf("Executing workflow for source %s: %s", sourceID, sourceURL)
+	owner := s.store.ForUser(userID)
+	engine := s.engine.ForUser(userID)
 	
-	source, exists := s.store.GetYouTubeSourceByID(sourceID)
-	if !exists {
-		log.Printf("Source %s not found", sourceID)
+	source, err := owner.GetYouTubeSourceByID(ctx, sourceID)
+	if errors.Is(err, store.ErrNotFound) {
+		log.Printf("Skipping source %s: it has been deleted", sourceID)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading source %s: %v", sourceID, err)
+		return
+	}
+	// A cron entry can outlive the source being disabled, so check again before running
+	if !source.Enabled {
+		log.Printf("Skipping source %s: it is disabled", sourceID)
 		return
 	}
 	
 	// If YouTube client is not available or source is not a channel, fall back to direct URL processing
 	if s.youtubeClient == nil || source.Type != models.YouTubeSourceTypeChannel {
 		log.Printf("Processing source URL directly (YouTube client not available or not a channel)")
-		execution, err := s.engine.ExecuteWorkflow(sourceURL, sourceID)
+		execution, err := engine.ExecuteWorkflow(ctx, sourceURL, sourceID)
 		if err != nil {
 			log.Printf("Error executing workflow for source %s: %v", sourceID, err)
 			return
 		}
 		
-		if execution.CompletedAt != "" {
+		if !execution.CompletedAt.IsZero() {
 			source.LastProcessed = execution.CompletedAt
-			s.store.CreateOrUpdateYouTubeSource(source)
+			s.saveSource(ctx, owner, source)
 		}
 		
 		log.Printf("Workflow execution completed for source %s: %s", sourceID, execution.ID)
 		return
 	}
 	
-	// Extract channel ID from URL using YouTube client
-	var channelID string
-	var err error
-	if s.youtubeClient != nil {
-		channelID, err = s.youtubeClient.ExtractChannelID(sourceURL)
+	// Use the channel ID resolved earlier, which the handlers clear when the URL changes, and
+	// otherwise extract it from the URL using YouTube client
+	channelID := source.ChannelID
+	switch {
+	case channelID != "":
+		// Resolved on an earlier run or when the source was saved
+	case s.youtubeClient != nil:
+		channelID, err = s.youtubeClient.ExtractChannelID(ctx, sourceURL)
 		if err != nil {
 			log.Printf("Could not extract channel ID from URL %s: %v", sourceURL, err)
 		}
-	} else {
+	default:
 		// Fallback to simple extraction if client not available
 		channelID = s.extractChannelIDFromURL(sourceURL)
 	}
 	
 	if channelID == "" {
-		// If we can't extract channel ID, try to use the stored channel_id
-		if source.ChannelID != "" {
-			channelID = source.ChannelID
-		} else {
-			log.Printf("Could not extract channel ID from URL %s, falling back to direct processing", sourceURL)
-			execution, err := s.engine.ExecuteWorkflow(sourceURL, sourceID)
-			if err != nil {
-				log.Printf("Error executing workflow for source %s: %v", sourceID, err)
-				return
-			}
-			if execution.CompletedAt != "" {
-				source.LastProcessed = execution.CompletedAt
-				s.store.CreateOrUpdateYouTubeSource(source)
-			}
+		log.Printf("Could not extract channel ID from URL %s, falling back to direct processing", sourceURL)
+		execution, err := engine.ExecuteWorkflow(ctx, sourceURL, sourceID)
+		if err != nil {
+			log.Printf("Error executing workflow for source %s: %v", sourceID, err)
 			return
 		}
+		if !execution.CompletedAt.IsZero() {
+			source.LastProcessed = execution.CompletedAt
+			s.saveSource(ctx, owner, source)
+		}
+		return
 	}
 	
 	// Store the resolved channel ID for future use
 	if source.ChannelID != channelID {
 		source.ChannelID = channelID
-		s.store.CreateOrUpdateYouTubeSource(source)
+		s.saveSource(ctx, owner, source)
 		log.Printf("Resolved channel ID for source %s: %s", sourceID, channelID)
 	}
 	
 	// Always fetch only the last 5 videos (most recent), regardless of last processed time
 	// This ensures we only ever process the 5 most recent videos and don't catch up on older ones
 	// Add rate limiting: wait 100ms before API call to avoid quota issues
-	// YouTube API allows 10,000 units/day, each search costs 100 units
+	// YouTube API allows 10,000 units/day; listing the channel's uploads costs 1 unit per page
+	// where a search costs 100. The client refuses requests past YOUTUBE_DAILY_QUOTA_BUDGET.
 	// This simple delay helps prevent rapid quota consumption
-	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-time.After(100 * time.Millisecond):
+	case <-ctx.Done():
+		log.Printf("Stopped polling source %s: %v", sourceID, ctx.Err())
+		return
+	}
 	
-	videos, err := s.youtubeClient.GetChannelVideos(channelID, 5, nil)
+	videos, err := s.youtubeClient.GetChannelUploads(ctx, channelID, 5, nil)
 	if err != nil {
 		// Log quota-related errors specifically
-		if apiErr, ok := err.(*youtube.APIError); ok && apiErr.StatusCode == http.StatusForbidden {
+		var quotaErr *youtube.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			log.Printf("Skipped polling channel %s: daily quota budget reached (%d of %d units), resets at %s",
+				channelID, quotaErr.Used, quotaErr.Budget, quotaErr.ResetsAt.Format(time.RFC3339))
+		} else if apiErr, ok := err.(*youtube.APIError); ok && apiErr.StatusCode == http.StatusForbidden {
 			log.Printf("YouTube API quota exceeded or invalid key for channel %s: %v", channelID, err)
 		} else {
 			log.Printf("Error fetching videos from channel %s: %v", channelID, err)
@@ -206,22 +252,37 @@ func (s *Scheduler) executeSource(sourceID string, sourceURL string) {
 	if len(videos) == 0 {
 		log.Printf("No new videos found for channel %s", channelID)
 		// Update last processed time even if no new videos
-		now := time.Now().UTC().Format(time.RFC3339)
-		source.LastProcessed = now
-		s.store.CreateOrUpdateYouTubeSource(source)
+		source.LastProcessed = models.Now()
+		s.saveSource(ctx, owner, source)
 		return
 	}
 	
 	log.Printf("Found %d videos from channel %s", len(videos), channelID)
 	
+	titleFilter, err := source.TitleFilter()
+	if err != nil {
+		log.Printf("Not processing source %s: %v", sourceID, err)
+		return
+	}
+	
 	// Get already processed video IDs for this source (optimized)
-	processedVideoIDs := s.getProcessedVideoIDs(sourceID)
+	processedVideoIDs, skippedVideoIDs, err := s.getProcessedVideoIDs(ctx, owner, sourceID)
+	if err != nil {
+		log.Printf("Error loading processed videos for source %s: %v", sourceID, err)
+		return
+	}
 	
 	// Process each new video
 	processedCount := 0
 	latestProcessedTime := source.LastProcessed
 	
 	for _, video := range videos {
+		// Leave the remaining videos to the next run once the scheduler stops
+		if ctx.Err() != nil {
+			log.Printf("Stopped processing source %s: %v", sourceID, ctx.Err())
+			break
+		}
+		
 		// Skip if already processed
 		if processedVideoIDs[video.ID] {
 			log.Printf("Skipping already processed video: %s (%s)", video.ID, video.Title)
@@ -231,8 +292,19 @@ func (s *Scheduler) executeSource(sourceID string, sourceURL string) {
 		// Build YouTube URL for the video
 		videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID)
 		
+		// Leave out videos the source's title filters reject, recording each once if asked
+		if ok, reason := titleFilter.Match(video.Title); !ok {
+			log.Printf("Skipping video %s (%s) of source %s: %s", video.ID, video.Title, sourceID, reason)
+			if source.RecordSkipped && !skippedVideoIDs[video.ID] {
+				if _, err := engine.RecordSkipped(ctx, videoURL, video.Title, sourceID, reason); err != nil {
+					log.Printf("Error recording skipped video %s: %v", video.ID, err)
+				}
+			}
+			continue
+		}
+		
 		log.Printf("Processing new video: %s (%s)", video.ID, video.Title)
-		execution, err := s.engine.ExecuteWorkflow(videoURL, sourceID)
+		execution, err := engine.ExecuteWorkflow(ctx, videoURL, sourceID)
 		if err != nil {
 			log.Printf("Error executing workflow for video %s: %v", video.ID, err)
 			continue
@@ -241,9 +313,9 @@ func (s *Scheduler) executeSource(sourceID string, sourceURL string) {
 		processedCount++
 		
 		// Update latest processed time
-		if execution.CompletedAt != "" {
+		if !execution.CompletedAt.IsZero() {
 			latestProcessedTime = execution.CompletedAt
-		} else if execution.StartedAt != "" {
+		} else if !execution.StartedAt.IsZero() {
 			latestProcessedTime = execution.StartedAt
 		}
 		
@@ -251,14 +323,23 @@ func (s *Scheduler) executeSource(sourceID string, sourceURL string) {
 	}
 	
 	// Update source last processed time
-	if latestProcessedTime != "" {
+	if !latestProcessedTime.IsZero() {
 		source.LastProcessed = latestProcessedTime
-		s.store.CreateOrUpdateYouTubeSource(source)
+		s.saveSource(ctx, owner, source)
 	}
 	
 	log.Printf("Processed %d new videos from source %s", processedCount, sourceID)
 }
 
+// saveSource persists a source's processing state to its owner's store, logging rather than
+// aborting on failure. It is written even if the run was cancelled, so the videos processed
+// before are recorded.
+func (s *Scheduler) saveSource(ctx context.Context, owner store.Store, source *models.YouTubeSource) {
+	if err := owner.CreateOrUpdateYouTubeSource(context.WithoutCancel(ctx), source); err != nil {
+		log.Printf("Error saving source %s: %v", source.ID, err)
+	}
+}
+
 // extractChannelIDFromURL extracts channel ID from YouTube URL
 func (s *Scheduler) extractChannelIDFromURL(url string) string {
 	// Pattern: https://www.youtube.com/channel/UC...
@@ -275,50 +356,64 @@ func (s *Scheduler) extractChannelIDFromURL(url string) string {
 
 // getProcessedVideoIDs returns a map of already processed video IDs for a specific source
 // This is optimized to only check executions from the same source
-func (s *Scheduler) getProcessedVideoIDs(sourceID string) map[string]bool {
-	executions := s.store.GetWorkflowExecutionsBySourceID(sourceID)
+// Videos the title filters skipped are returned apart, so they are checked against the filters
+// again rather than treated as processed.
+func (s *Scheduler) getProcessedVideoIDs(ctx context.Context, owner store.Store, sourceID string) (map[string]bool, map[string]bool, error) {
+	executions, err := owner.GetWorkflowExecutionsBySourceID(ctx, sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
 	processed := make(map[string]bool)
+	skipped := make(map[string]bool)
 	
 	for _, exec := range executions {
-		if exec.VideoID != "" {
+		if exec.VideoID == "" {
+			continue
+		}
+		if exec.Status == models.WorkflowStatusSkipped {
+			skipped[exec.VideoID] = true
+		} else {
 			processed[exec.VideoID] = true
 		}
 	}
 	
-	return processed
+	return processed, skipped, nil
 }
 
-// TriggerSourceManually triggers a workflow execution for a source immediately
-func (s *Scheduler) TriggerSourceManually(sourceID string) error {
-	source, exists := s.store.GetYouTubeSourceByID(sourceID)
-	if !exists {
+// TriggerSourceManually triggers a workflow execution for a source of userID immediately
+func (s *Scheduler) TriggerSourceManually(ctx context.Context, userID string, sourceID string) error {
+	source, err := s.store.ForUser(userID).GetYouTubeSourceByID(ctx, sourceID)
+	if errors.Is(err, store.ErrNotFound) {
 		return &SourceNotFoundError{SourceID: sourceID}
 	}
+	if err != nil {
+		return err
+	}
 	
 	if !source.Enabled {
 		return &SourceDisabledError{SourceID: sourceID}
 	}
 	
-	go s.executeSource(sourceID, source.URL)
+	// Run detached from ctx so the execution outlives the triggering request
+	go s.runSource(userID, sourceID, source.URL)
 	return nil
 }
 
-// TriggerAllSources triggers workflow execution for all enabled sources immediately
-func (s *Scheduler) TriggerAllSources() []string {
-	sources := s.store.GetAllYouTubeSources()
+// TriggerAllSources triggers workflow execution for all enabled sources of userID immediately
+func (s *Scheduler) TriggerAllSources(ctx context.Context, userID string) ([]string, error) {
+	sources, err := s.store.ForUser(userID).GetEnabledYouTubeSources(ctx)
+	if err != nil {
+		return nil, err
+	}
 	triggered := make([]string, 0)
 	
 	for _, source := range sources {
-		if !source.Enabled {
-			continue
-		}
-		
-		go s.executeSource(source.ID, source.URL)
+		go s.runSource(userID, source.ID, source.URL)
 		triggered = append(triggered, source.ID)
 		log.Printf("Manually triggered source: %s (%s)", source.Name, source.ID)
 	}
 	
-	return triggered
+	return triggered, nil
 }
 
 // SourceNotFoundError represents an error when a source is not found
@@ -339,9 +434,9 @@ func (e *SourceDisabledError) Error() string {
 	return "source is disabled: " + e.SourceID
 }
 
-// ReloadSourceSchedule reloads the cron schedule for a specific source
+// ReloadSourceSchedule reloads the cron schedule for a specific source of userID
 // This should be called when a source's schedule is updated
-func (s *Scheduler) ReloadSourceSchedule(sourceID string) error {
+func (s *Scheduler) ReloadSourceSchedule(ctx context.Context, userID string, sourceID string) error {
 	if !s.enabled {
 		return fmt.Errorf("scheduler is disabled")
 	}
@@ -354,9 +449,9 @@ func (s *Scheduler) ReloadSourceSchedule(sourceID string) error {
 	}
 	
 	// Get the source from store
-	source, exists := s.store.GetYouTubeSourceByID(sourceID)
-	if !exists {
-		return fmt.Errorf("source %s not found", sourceID)
+	source, err := s.store.ForUser(userID).GetYouTubeSourceByID(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to load source %s: %w", sourceID, err)
 	}
 	
 	// If source is disabled, don't schedule it
@@ -379,7 +474,7 @@ func (s *Scheduler) ReloadSourceSchedule(sourceID string) error {
 	
 	entryID, err := s.cron.AddFunc(schedule, func() {
 		log.Printf("Scheduled execution triggered for source: %s (%s)", source.Name, sourceID)
-		s.executeSource(sourceID, sourceURL)
+		s.runSource(userID, sourceID, sourceURL)
 	})
 	
 	if err != nil {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -59,7 +60,7 @@ func main() {
 
 	// Also test by making an actual request
 	fmt.Println("=== Testing with actual API request ===")
-	accounts, err := client.GetAccounts()
+	accounts, err := client.GetAccounts(context.Background())
 	if err != nil {
 		fmt.Printf("⚠ Request failed: %v\n", err)
 		fmt.Println()
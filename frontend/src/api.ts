@@ -18,8 +18,23 @@ import {
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api';
 
+// API token identifying the current user; only needed when the backend has API_USERS configured
+function getAPIToken(): string | undefined {
+  return localStorage.getItem('apiToken') || import.meta.env.VITE_API_TOKEN || undefined;
+}
+
+function apiFetch(input: string, init: RequestInit = {}): Promise<Response> {
+  const token = getAPIToken();
+  if (!token) {
+    return fetch(input, init);
+  }
+  const headers = new Headers(init.headers);
+  headers.set('Authorization', `Bearer ${token}`);
+  return fetch(input, { ...init, headers });
+}
+
 async function fetchAPI<T>(endpoint: string): Promise<T> {
-  const response = await fetch(`${API_BASE_URL}${endpoint}`);
+  const response = await apiFetch(`${API_BASE_URL}${endpoint}`);
   if (!response.ok) {
     throw new Error(`Failed to fetch ${endpoint}: ${response.statusText}`);
   }
@@ -27,7 +42,7 @@ async function fetchAPI<T>(endpoint: string): Promise<T> {
 }
 
 async function postAPI<T>(endpoint: string, body?: any): Promise<T> {
-  const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+  const response = await apiFetch(`${API_BASE_URL}${endpoint}`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -200,7 +215,7 @@ export async function getRecommendationsSummary(days: number = 7): Promise<Recom
 }
 
 export async function generateAggregatedRecommendation(): Promise<AggregatedRecommendation> {
-  const response = await fetch(`${API_BASE_URL}/workflow/recommendations/aggregate`, {
+  const response = await apiFetch(`${API_BASE_URL}/workflow/recommendations/aggregate`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -231,7 +246,7 @@ export async function createYouTubeSource(source: CreateYouTubeSourceRequest): P
 }
 
 export async function updateYouTubeSource(id: string, source: CreateYouTubeSourceRequest): Promise<YouTubeSource> {
-  const response = await fetch(`${API_BASE_URL}/workflow/sources/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/workflow/sources/${id}`, {
     method: 'PUT',
     headers: {
       'Content-Type': 'application/json',
@@ -246,7 +261,7 @@ export async function updateYouTubeSource(id: string, source: CreateYouTubeSourc
 }
 
 export async function deleteYouTubeSource(id: string): Promise<void> {
-  const response = await fetch(`${API_BASE_URL}/workflow/sources/${id}`, {
+  const response = await apiFetch(`${API_BASE_URL}/workflow/sources/${id}`, {
     method: 'DELETE',
   });
   if (!response.ok) {
@@ -267,7 +282,7 @@ export interface TestYouTubeSourceResponse {
 }
 
 export async function testYouTubeSource(url: string): Promise<TestYouTubeSourceResponse> {
-  const response = await fetch(`${API_BASE_URL}/workflow/sources/test`, {
+  const response = await apiFetch(`${API_BASE_URL}/workflow/sources/test`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
@@ -291,7 +306,7 @@ export interface TriggerAllSourcesResponse {
 }
 
 export async function triggerAllSources(): Promise<TriggerAllSourcesResponse> {
-  const response = await fetch(`${API_BASE_URL}/workflow/sources/trigger-all`, {
+  const response = await apiFetch(`${API_BASE_URL}/workflow/sources/trigger-all`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
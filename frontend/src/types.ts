@@ -1,11 +1,27 @@
 export type Platform = 'coinbase';
 
+export type TaxTreatment = 'taxable' | 'traditional' | 'roth' | 'other';
+
+export type AssetType =
+  | 'crypto'
+  | 'stablecoin'
+  | 'cash'
+  | 'stock'
+  | 'etf'
+  | 'bond'
+  | 'derivative'
+  | 'real_estate'
+  | 'other';
+
 export interface Portfolio {
   id: string;
   platform: Platform;
   name: string;
   type?: string;
   last_synced?: string;
+  tax_treatment?: TaxTreatment;
+  custodian?: string;
+  display_order?: number;
 }
 
 export interface Investment {
@@ -18,15 +34,21 @@ export interface Investment {
   value: number;
   price: number;
   currency: string;
-  asset_type: string;
+  asset_type: AssetType;
   last_updated?: string;
+  // Computed cost basis fields; null means unknown
+  cost_basis?: number | null;
+  average_buy_price?: number | null;
+  first_acquired_at?: string | null;
+  unrealized_gain?: number | null;
 }
 
 export interface NetWorth {
   total_value: number;
   currency: string;
   by_platform: Record<Platform, number>;
-  by_asset_type: Record<string, number>;
+  by_asset_type: Partial<Record<AssetType, number>>;
+  by_tax_treatment: Partial<Record<TaxTreatment | 'unassigned', number>>;
   account_count: number;
   last_calculated: string;
 }
@@ -39,6 +61,9 @@ export interface NetWorthBreakdown {
 
 export interface InvestmentsResponse {
   investments: Investment[];
+  total_count: number;
+  limit: number;
+  offset: number;
 }
 
 export interface PlatformInvestmentsResponse {
@@ -48,6 +73,9 @@ export interface PlatformInvestmentsResponse {
 
 export interface PortfoliosResponse {
   portfolios: Portfolio[];
+  total_count: number;
+  limit: number;
+  offset: number;
 }
 
 export interface PlatformPortfoliosResponse {